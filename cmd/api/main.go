@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -13,6 +18,7 @@ import (
 	"github.com/revaspay/backend/internal/database/migrations"
 	"github.com/revaspay/backend/internal/queue"
 	"github.com/revaspay/backend/internal/routes"
+	"github.com/revaspay/backend/internal/utils"
 )
 
 func main() {
@@ -28,6 +34,20 @@ func main() {
 	// Initialize configuration
 	cfg := config.LoadConfig()
 
+	// Sign and verify tokens with the configured keys and lifetimes
+	if err := utils.ConfigureJWT(utils.JWTSettings{
+		KeyID:           cfg.JWT.KeyID,
+		Secret:          cfg.JWT.Secret,
+		PreviousKeys:    cfg.JWT.PreviousKeys,
+		AccessTokenTTL:  cfg.JWT.AccessTokenTTL,
+		RefreshTokenTTL: cfg.JWT.RefreshTokenTTL,
+	}); err != nil {
+		log.Fatalf("Invalid JWT configuration: %v", err)
+	}
+
+	// Bound every payment, payout and KYC provider call so a hung provider can't block a worker
+	utils.SetProviderTimeout(cfg.Timeouts.Provider)
+
 	// Setup database connection
 	db, err := database.InitDB(cfg.Database)
 	if err != nil {
@@ -41,6 +61,11 @@ func main() {
 
 	// Initialize router
 	router := gin.Default()
+	
+	// Only believe X-Forwarded-For from our own load balancers, so ClientIP can't be spoofed
+	if err := router.SetTrustedProxies(config.DefaultSecurityConfig().TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// Configure CORS
 	router.Use(cors.New(cors.Config{
@@ -53,9 +78,10 @@ func main() {
 
 	// Initialize job queue
 	jobQueue := queue.NewQueue(db)
+	jobQueue.SetJobTimeout(cfg.Timeouts.Job)
 
-	// Start job queue processor in a goroutine
-	go jobQueue.ProcessJobs()
+	// Start job queue processor - it runs in its own goroutine
+	jobQueue.ProcessJobs()
 
 	// Register routes
 	routes.RegisterRoutes(router, db, jobQueue)
@@ -69,8 +95,35 @@ func main() {
 		port = "8080"
 	}
 
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
 	fmt.Printf("RevasPay API server running on port %s\n", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+
+	// Wait for interrupt signal to gracefully shut down the server
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+
+	// Jobs and requests share one deadline so shutdown stays within the orchestrator's grace period
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	// Stop taking new jobs and let the one in flight finish, so a withdrawal isn't cut off mid-payout
+	if err := jobQueue.Shutdown(ctx); err != nil {
+		log.Printf("Job processor did not finish in time, in-flight job may be interrupted: %v", err)
 	}
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Fatalf("Server forced to shutdown: %v", err)
+	}
+
+	log.Println("Server exiting")
 }
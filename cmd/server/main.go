@@ -12,18 +12,33 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/revaspay/backend/internal/circuitbreaker"
 	"github.com/revaspay/backend/internal/config"
 	"github.com/revaspay/backend/internal/database"
 	"github.com/revaspay/backend/internal/handlers"
 	"github.com/revaspay/backend/internal/jobs"
+	"github.com/revaspay/backend/internal/metrics"
 	"github.com/revaspay/backend/internal/middleware"
 	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/money"
 	"github.com/revaspay/backend/internal/queue"
 	"github.com/revaspay/backend/internal/routes"
+	"github.com/revaspay/backend/internal/services/account"
+	"github.com/revaspay/backend/internal/services/dataexport"
+	"github.com/revaspay/backend/internal/services/email"
 	"github.com/revaspay/backend/internal/services/kyc"
+	"github.com/revaspay/backend/internal/services/notification"
 	"github.com/revaspay/backend/internal/services/payment"
 	"github.com/revaspay/backend/internal/services/payment/providers/paystack"
+	"github.com/revaspay/backend/internal/services/reconciliation"
+	"github.com/revaspay/backend/internal/services/screening"
+	"github.com/revaspay/backend/internal/services/storage"
+	"github.com/revaspay/backend/internal/services/subscription"
+	"github.com/revaspay/backend/internal/services/virtualaccount"
 	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/services/webhook"
+	"github.com/revaspay/backend/internal/utils"
 )
 
 func main() {
@@ -35,12 +50,42 @@ func main() {
 	// Initialize configuration
 	cfg := config.LoadConfig()
 
+	// Sign and verify tokens with the configured keys and lifetimes
+	if err := utils.ConfigureJWT(utils.JWTSettings{
+		KeyID:           cfg.JWT.KeyID,
+		Secret:          cfg.JWT.Secret,
+		PreviousKeys:    cfg.JWT.PreviousKeys,
+		AccessTokenTTL:  cfg.JWT.AccessTokenTTL,
+		RefreshTokenTTL: cfg.JWT.RefreshTokenTTL,
+	}); err != nil {
+		log.Fatalf("Invalid JWT configuration: %v", err)
+	}
+
+	// Bound every payment, payout and KYC provider call so a hung provider can't block a worker
+	utils.SetProviderTimeout(cfg.Timeouts.Provider)
+
+	// Fees, conversions and customer amounts round with the configured modes
+	roundingPolicy, err := money.ParsePolicy(cfg.Rounding.Customer, cfg.Rounding.Fee, cfg.Rounding.Conversion)
+	if err != nil {
+		log.Fatalf("Invalid rounding configuration: %v", err)
+	}
+	money.SetPolicy(roundingPolicy)
+
+	// Failed jobs are retried as their job type's retry policy says
+	jobs.ConfigureRetryPolicies(cfg.JobRetry)
+
+	// Payment and withdrawal jobs are taken ahead of reconciliation
+	jobs.ConfigureJobPriorities()
+
 	// Initialize database
 	db, err := database.InitDB(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Reject tokens of suspended and deleted users
+	middleware.ConfigureAccountStatus(db)
+
 	// Initialize Redis client
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     cfg.Redis.URL, // Use URL from config instead of separate Address and Password
@@ -62,9 +107,16 @@ func main() {
 
 	// Initialize services
 	walletService := wallet.NewWalletService(db)
+	walletService.SetCreditBatchWindow(cfg.Wallet.CreditBatchWindow)
+	creditFlusherCtx, stopCreditFlusher := context.WithCancel(context.Background())
+	go walletService.RunCreditFlusher(creditFlusherCtx)
 	
-	// Initialize KYC service
-	kycService := kyc.NewKYCService(db, cfg.Didit)
+	// Initialize KYC providers, which read submitted documents from the storage backend
+	documentStore, err := storage.DefaultBackend()
+	if err != nil {
+		log.Fatalf("Failed to initialize document storage: %v", err)
+	}
+	kycProviders := kyc.DefaultProviders(db, documentStore)
 	
 	// Initialize payment providers
 	paystackProvider := paystack.NewPaystackProvider(paystack.PaystackConfig{
@@ -72,37 +124,118 @@ func main() {
 		PublicKey: cfg.Paystack.PublicKey,
 	})
 	
+	// Initialize merchant webhook service for outbound event delivery
+	webhookService := webhook.NewWebhookService(db, queueAdapter)
+	webhookService.SetBalanceChangeWindow(cfg.Wallet.BalanceWebhookWindow)
+	
+	// Initialize address screening for crypto disbursements
+	screeningService := screening.NewService(db, nil)
+	
 	// Initialize payment service with both DB and wallet service
 	paymentService := payment.NewPaymentService(db, walletService)
+	paymentService.SetAmountLimits(cfg.Payment.AmountLimits)
+	paymentService.SetMetadataLimits(cfg.Payment.MetadataLimits)
+	paymentService.SetCircuitBreakerSettings(circuitbreaker.Settings(cfg.CircuitBreaker))
+	paymentService.SetWebhookService(webhookService)
+	paymentService.SetReceiptQueue(queueAdapter)
+	paymentService.SetPaymentExpiry(cfg.Payment.Expiry, cfg.Payment.ProviderExpiry)
 	
 	// Register payment providers
 	paymentService.RegisterProvider(models.PaymentProviderPaystack, paystackProvider)
+	paymentService.RegisterAccountProvider(models.PaymentProviderPaystack, func(credentials payment.ProviderCredentials) payment.PaymentProvider {
+		return paystackProvider.WithKeys(credentials.SecretKey, credentials.PublicKey)
+	})
+	credentialBox, err := utils.NewSecretBoxFromBase64(cfg.Payment.CredentialsKey)
+	if err != nil {
+		log.Printf("Merchant provider credentials unavailable: %v", err)
+	}
+	paymentService.SetCredentialBox(credentialBox)
 	// Temporarily disabled due to missing implementations
 	// paymentService.RegisterProvider(models.PaymentProviderStripe, stripeProvider)
 	// paymentService.RegisterProvider(models.PaymentProviderPaypal, paypalProvider)
+	paymentService.SetRouter(payment.NewPaymentRouter(cfg.Payment.Routes, cfg.Payment.DefaultProviders, paymentService.ProviderHealthy))
 	
 	// Register all job handlers
 	jobs.RegisterPaymentWebhookJobHandlers(queueAdapter, db, paymentService, walletService)
-	jobs.RegisterRecurringPaymentJobHandlers(queueAdapter, db, paymentService, walletService)
+	jobs.RegisterPaymentExpiryJobHandlers(queueAdapter, paymentService, cfg.Payment.ExpiryInterval)
+	jobs.RegisterPaymentReceiptJobHandlers(queueAdapter, db, email.NewEmailService())
+	jobs.RegisterLoginNotificationJobHandlers(queueAdapter, db, email.NewEmailService())
+	
+	// Subscription renewals go through the subscription engine, which tells subscribers how they went
+	notificationService := notification.NewService(db,
+		notification.NewInAppChannel(db),
+		notification.NewEmailChannel(email.NewEmailService()),
+	)
+	subscriptionService := subscription.NewSubscriptionService(db, paymentService)
+	subscriptionService.SetNotificationService(notificationService)
+	jobs.RegisterRecurringPaymentJobHandlers(queueAdapter, db, subscriptionService)
+	
 	// Create and register withdrawal job handlers
 	withdrawalJob := jobs.NewWithdrawalJob(db, queueAdapter, paymentService, walletService)
+	withdrawalJob.SetWebhookService(webhookService)
+	withdrawalJob.SetScreeningService(screeningService)
+	withdrawalJob.SetDryRun(cfg.Withdrawal.DryRun, cfg.Withdrawal.AllowDryRunFlag)
+	withdrawalJob.SetManualResolveAfter(cfg.Withdrawal.ManualResolveAfter)
+	withdrawalJob.SetCircuitBreakerSettings(circuitbreaker.Settings(cfg.CircuitBreaker))
+	if cfg.Withdrawal.DryRun || cfg.Withdrawal.AllowDryRunFlag {
+		log.Printf("Withdrawal dry runs enabled (all: %v, per withdrawal: %v), payouts may not be sent", cfg.Withdrawal.DryRun, cfg.Withdrawal.AllowDryRunFlag)
+	}
 	withdrawalJob.RegisterHandlers(queueAdapter)
-	jobs.RegisterKYCVerificationJobHandlers(queueAdapter, db, kycService)
-	jobs.RegisterVirtualAccountJobHandlers(queueAdapter, db, paymentService, walletService)
+	jobs.RegisterKYCVerificationJobHandlers(queueAdapter, db, kycProviders...)
+	kycPoller := kyc.NewPoller(db, kyc.PollSchedule{Interval: cfg.KYC.PollInterval, MaxDuration: cfg.KYC.PollMaxDuration}, kycProviders...)
+	jobs.RegisterKYCPollJobHandlers(queueAdapter, kycPoller)
+	
+	// Virtual accounts are reconciled against the configured providers' transaction history
+	virtualAccountService := virtualaccount.NewVirtualAccountService(db, virtualaccount.DefaultProviders(cfg)...)
+	jobs.RegisterVirtualAccountJobHandlers(queueAdapter, db, paymentService, walletService, virtualAccountService)
 	
 	// Register referral reward job handlers
 	jobs.RegisterReferralRewardJobHandlers(queueAdapter, db, walletService)
 	
+	// Register merchant webhook delivery handlers
+	jobs.RegisterMerchantWebhookJobHandlers(queueAdapter, webhookService)
+	
+	// Register wallet ledger reconciliation handler
+	jobs.RegisterWalletReconciliationJobHandlers(queueAdapter, db, walletService)
+	
+	// Provider records are reconciled daily against our payments and withdrawals
+	reconciliationService := reconciliation.NewService(db, reconciliation.NewPaystackSource(paystackProvider))
+	jobs.RegisterProviderReconciliationJobHandlers(queueAdapter, db, reconciliationService)
+	
+	// Users' data exports are built in the background and kept in the document store until
+	// their download links expire
+	dataExportService := dataexport.NewService(db, documentStore, email.NewEmailService(), queueAdapter)
+	jobs.RegisterDataExportJobHandlers(queueAdapter, dataExportService)
+
+	// Expired tokens, old sessions, completed jobs and expired data exports are deleted on a schedule
+	jobs.RegisterCleanupJobHandlers(queueAdapter, db, jobs.CleanupSettings(cfg.Cleanup), dataExportService)
+	
 	// Initialize security middleware
 	securityMiddleware := middleware.NewSecurityMiddleware(db)
 	
+	// Per-user limits on payment and withdrawal initiation
+	securityConfig := config.DefaultSecurityConfig()
+	var rateLimiter *middleware.RateLimiter
+	if securityConfig.RateLimitBackend == "redis" {
+		rateLimiter = middleware.NewRedisRateLimiter(redisClient, 60, 10, 5, 3)
+	} else {
+		rateLimiter = middleware.NewRateLimiter(60, 10, 5, 3)
+	}
+	
 	// Initialize handlers
 	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	merchantWebhookHandler := handlers.NewMerchantWebhookHandler(webhookService)
+	withdrawalHandler := handlers.NewWithdrawalHandler(walletService, withdrawalJob, account.NewService(db))
 	
 	// Initialize Gin router
 	router := gin.Default()
+	if err := router.SetTrustedProxies(securityConfig.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 	
 	// Apply global middleware
+	router.Use(middleware.MetricsMiddleware())
+	router.Use(middleware.MaxBodyBytes(securityConfig.MaxBodyBytes, securityConfig.MaxUploadBodyBytes))
 	router.Use(gin.Logger()) // Use built-in logger instead of custom middleware
 	router.Use(gin.Recovery())
 	router.Use(func(c *gin.Context) { // Simple CORS middleware
@@ -116,17 +249,38 @@ func main() {
 		c.Next()
 	})
 	router.Use(securityMiddleware.BruteForceProtection())
-	router.Use(securityMiddleware.SessionActivity())
+	// Record session activity on authenticated routes, expiring idle sessions
+	middleware.ConfigureSessionActivity(db, database.IdleTimeouts{
+		Default:  securityConfig.SessionIdleTimeout,
+		HighRisk: securityConfig.SessionIdleTimeoutHighRisk,
+	})
 	
 	// Setup routes
-	routes.SetupPaymentRoutes(router, paymentHandler)
+	routes.SetupPaymentRoutes(router, db, paymentHandler, routes.PaymentRateLimit(rateLimiter, securityConfig), routes.PublicPaymentStatusRateLimit(rateLimiter, securityConfig), routes.WebhookIPAllowList(securityConfig))
+	routes.SetupMerchantWebhookRoutes(router, merchantWebhookHandler)
+	routes.SetupWithdrawalRoutes(router, withdrawalHandler, routes.WithdrawalRateLimit(rateLimiter, securityConfig))
+	router.GET("/metrics", metrics.Handler(os.Getenv("METRICS_TOKEN")))
 	
 	// Start background job processor
-	jobProcessor := queue.NewJobProcessor(redisQueue, 10) // 10 worker goroutines
+	jobProcessor := queue.NewJobProcessor(redisQueue, cfg.JobWorkers)
+	jobProcessor.SetJobTimeout(cfg.Timeouts.Job)
+	jobs.ConfigureJobConcurrency(jobProcessor, cfg.JobConcurrency)
+	if err := prometheus.Register(queue.NewDepthCollector(jobProcessor, jobProcessor.JobTypes)); err != nil {
+		log.Printf("Failed to register queue depth metrics: %v", err)
+	}
 	go jobProcessor.Start()
 	
 	// Schedule recurring jobs
-	jobs.ScheduleRecurringJobs(queueAdapter, db, paymentService, walletService)
+	jobs.ScheduleRecurringJobs(queueAdapter, db, paymentService, walletService, webhookService)
+	if err := jobs.NewCleanupJob(db, queueAdapter, jobs.CleanupSettings(cfg.Cleanup)).ScheduleCleanup(); err != nil {
+		log.Printf("Failed to schedule cleanup: %v", err)
+	}
+	if err := jobs.NewPaymentExpiryJob(queueAdapter, paymentService, cfg.Payment.ExpiryInterval).ScheduleExpiry(); err != nil {
+		log.Printf("Failed to schedule payment expiry: %v", err)
+	}
+	if err := jobs.NewKYCPollJob(queueAdapter, kycPoller).SchedulePolling(); err != nil {
+		log.Printf("Failed to schedule KYC polling: %v", err)
+	}
 	
 	// Start server
 	srv := startServer(router, cfg.Server.Port)
@@ -139,6 +293,7 @@ func main() {
 	
 	// Stop job processor
 	jobProcessor.Stop()
+	stopCreditFlusher()
 	
 	// Create a deadline to wait for
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/middleware"
 	"github.com/revaspay/backend/internal/services/payment/momo"
 )
 
@@ -170,12 +171,11 @@ func (h *MoMoHandler) CheckDisbursementStatus(c *gin.Context) {
 	})
 }
 
-// Helper function to get user ID from context
-// This assumes you have middleware that sets the user ID in the context
+// getUserIDFromContext returns the ID of the user authenticated by middleware.AuthMiddleware
 func getUserIDFromContext(c *gin.Context) string {
-	userID, exists := c.Get("userID")
-	if !exists {
+	userID, ok := middleware.UserID(c)
+	if !ok {
 		return ""
 	}
-	return userID.(string)
+	return userID.String()
 }
@@ -0,0 +1,350 @@
+// Package circuitbreaker stops calling a provider that keeps failing, so an outage fails fast
+// instead of tying up requests and burning job retries, and lets a trial call through after a
+// cool-down to find out when the provider has recovered
+package circuitbreaker
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/revaspay/backend/internal/metrics"
+	"github.com/revaspay/backend/internal/utils"
+)
+
+// State is a breaker's state
+type State string
+
+// Breaker states
+const (
+	StateClosed   State = "closed"    // Calls go through and their failures are counted
+	StateOpen     State = "open"      // Calls are rejected until the cool-down ends
+	StateHalfOpen State = "half_open" // One trial call goes through to test the provider
+)
+
+var (
+	// ErrOpen is matched by the errors returned for calls a breaker rejects
+	ErrOpen = errors.New("circuit breaker is open")
+
+	// ErrUnavailable is wrapped by provider clients around errors that mean the provider itself
+	// is down, such as 5xx responses, so they count as failures
+	ErrUnavailable = errors.New("provider unavailable")
+)
+
+// Settings configures when a breaker opens and how long it stays open
+type Settings struct {
+	FailureRate float64       // Share of calls in Window that must fail to open the breaker
+	MinRequests int           // Calls needed in Window before the failure rate is judged
+	Window      time.Duration // How long calls are counted for while the breaker is closed
+	CoolDown    time.Duration // How long the breaker stays open before a trial call
+}
+
+// DefaultSettings are used for any setting left at zero
+var DefaultSettings = Settings{
+	FailureRate: 0.5,
+	MinRequests: 10,
+	Window:      time.Minute,
+	CoolDown:    30 * time.Second,
+}
+
+// OpenError is returned for a call the breaker rejected. It matches ErrOpen.
+type OpenError struct {
+	Name       string
+	RetryAfter time.Duration // Until the breaker lets a call through again
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s circuit breaker is open, retry in %s", e.Name, e.RetryAfter.Round(time.Second))
+}
+
+// Is makes errors.Is(err, ErrOpen) match
+func (e *OpenError) Is(target error) bool {
+	return target == ErrOpen
+}
+
+// RetryAfter returns how long until the breaker that rejected err lets calls through again,
+// and false if err isn't a rejection
+func RetryAfter(err error) (time.Duration, bool) {
+	var openErr *OpenError
+	if errors.As(err, &openErr) {
+		return openErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// IsFailure reports whether err means the provider is unhealthy: it timed out, couldn't be
+// reached or reported itself unavailable. Other errors, such as a declined charge, show the
+// provider is up and don't count against it.
+func IsFailure(err error) bool {
+	if err == nil || errors.Is(err, ErrOpen) {
+		return false
+	}
+	if utils.IsTimeout(err) || errors.Is(err, ErrUnavailable) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// Status is a breaker's current state, as reported on the health endpoint
+type Status struct {
+	Name     string     `json:"name"`
+	State    State      `json:"state"`
+	Requests int        `json:"requests"` // Calls counted in the current window
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	RetryAt  *time.Time `json:"retry_at,omitempty"` // When an open breaker lets a trial call through
+}
+
+// Breaker tracks the failure rate of calls to one provider. It opens once enough calls in a
+// window fail, rejects calls for the cool-down, then lets a single trial call through: if it
+// succeeds the breaker closes, otherwise it opens for another cool-down.
+type Breaker struct {
+	name     string
+	settings Settings
+	now      func() time.Time
+
+	mu            sync.Mutex
+	state         State
+	windowStart   time.Time
+	requests      int
+	failures      int
+	openedAt      time.Time
+	trialInFlight bool
+}
+
+// New creates a closed breaker and registers it under name, replacing any breaker already
+// registered with that name
+func New(name string, settings Settings) *Breaker {
+	if settings.FailureRate <= 0 {
+		settings.FailureRate = DefaultSettings.FailureRate
+	}
+	if settings.MinRequests <= 0 {
+		settings.MinRequests = DefaultSettings.MinRequests
+	}
+	if settings.Window <= 0 {
+		settings.Window = DefaultSettings.Window
+	}
+	if settings.CoolDown <= 0 {
+		settings.CoolDown = DefaultSettings.CoolDown
+	}
+
+	b := &Breaker{
+		name:     name,
+		settings: settings,
+		now:      time.Now,
+		state:    StateClosed,
+	}
+	b.windowStart = b.now()
+	metrics.ObserveCircuitBreakerState(name, string(StateClosed))
+	register(b)
+	return b
+}
+
+// Name returns the name the breaker was registered under
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// Ready returns an *OpenError if a call made now would be rejected. Unlike Allow it doesn't
+// reserve anything, so callers can check before doing work that only makes sense if the
+// provider will be called.
+func (b *Breaker) Ready() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rejection(b.now())
+}
+
+// Allow reserves a call, returning an *OpenError instead if the breaker is rejecting calls.
+// The call must be finished with Done once the provider has answered, or with Release if it
+// was never made.
+func (b *Breaker) Allow() (*Call, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if err := b.rejection(now); err != nil {
+		metrics.ObserveCircuitBreakerRejection(b.name)
+		return nil, err
+	}
+
+	switch b.state {
+	case StateOpen:
+		// The cool-down is over, so this call is the trial
+		b.setState(StateHalfOpen)
+		b.trialInFlight = true
+		return &Call{breaker: b, trial: true}, nil
+	case StateHalfOpen:
+		b.trialInFlight = true
+		return &Call{breaker: b, trial: true}, nil
+	}
+
+	if now.Sub(b.windowStart) >= b.settings.Window {
+		b.windowStart = now
+		b.requests = 0
+		b.failures = 0
+	}
+	return &Call{breaker: b}, nil
+}
+
+// Do runs fn through the breaker, recording whether it failed
+func (b *Breaker) Do(fn func() error) error {
+	call, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	call.Done(err)
+	return err
+}
+
+// Status returns the breaker's current state
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := Status{
+		Name:     b.name,
+		State:    b.state,
+		Requests: b.requests,
+		Failures: b.failures,
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		retryAt := openedAt.Add(b.settings.CoolDown)
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+	}
+	return status
+}
+
+// rejection returns the error for a call made at now, or nil if it would be let through
+func (b *Breaker) rejection(now time.Time) error {
+	switch b.state {
+	case StateOpen:
+		if wait := b.openedAt.Add(b.settings.CoolDown).Sub(now); wait > 0 {
+			return &OpenError{Name: b.name, RetryAfter: wait}
+		}
+	case StateHalfOpen:
+		if b.trialInFlight {
+			return &OpenError{Name: b.name, RetryAfter: b.settings.CoolDown}
+		}
+	}
+	return nil
+}
+
+// record applies the outcome of a finished call
+func (b *Breaker) record(trial, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if trial {
+		b.trialInFlight = false
+		if failed {
+			b.open(now)
+		} else {
+			b.close(now)
+		}
+		return
+	}
+
+	// Calls started before the breaker opened don't count towards the next window
+	if b.state != StateClosed {
+		return
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if b.requests >= b.settings.MinRequests && float64(b.failures)/float64(b.requests) >= b.settings.FailureRate {
+		b.open(now)
+	}
+}
+
+// release gives back a reserved trial call that was never made
+func (b *Breaker) release(trial bool) {
+	if !trial {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trialInFlight = false
+}
+
+func (b *Breaker) open(now time.Time) {
+	b.openedAt = now
+	b.setState(StateOpen)
+}
+
+func (b *Breaker) close(now time.Time) {
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+	b.setState(StateClosed)
+}
+
+func (b *Breaker) setState(state State) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	metrics.ObserveCircuitBreakerState(b.name, string(state))
+}
+
+// Call is a call reserved through Allow
+type Call struct {
+	breaker  *Breaker
+	trial    bool
+	finished bool
+}
+
+// Done records the provider's answer. Errors only count as failures if IsFailure says so.
+func (c *Call) Done(err error) {
+	if c.finished {
+		return
+	}
+	c.finished = true
+	c.breaker.record(c.trial, IsFailure(err))
+}
+
+// Release gives the call back without recording an outcome, for calls that were never made.
+// It does nothing after Done, so it can be deferred.
+func (c *Call) Release() {
+	if c.finished {
+		return
+	}
+	c.finished = true
+	c.breaker.release(c.trial)
+}
+
+var registry = struct {
+	sync.Mutex
+	breakers map[string]*Breaker
+}{breakers: make(map[string]*Breaker)}
+
+func register(b *Breaker) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.breakers[b.name] = b
+}
+
+// Snapshot returns the status of every registered breaker, sorted by name
+func Snapshot() []Status {
+	registry.Lock()
+	breakers := make([]*Breaker, 0, len(registry.breakers))
+	for _, b := range registry.breakers {
+		breakers = append(breakers, b)
+	}
+	registry.Unlock()
+
+	statuses := make([]Status, 0, len(breakers))
+	for _, b := range breakers {
+		statuses = append(statuses, b.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
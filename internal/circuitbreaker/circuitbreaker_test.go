@@ -0,0 +1,149 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDown = fmt.Errorf("%w: status 503", ErrUnavailable)
+
+// newTestBreaker returns a breaker whose clock only moves when the returned function is called
+func newTestBreaker(name string) (*Breaker, func(time.Duration)) {
+	b := New(name, Settings{FailureRate: 0.5, MinRequests: 4, Window: time.Minute, CoolDown: 30 * time.Second})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	b.windowStart = now
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+func fail() error    { return errDown }
+func succeed() error { return nil }
+
+func TestBreakerOpensAtFailureRate(t *testing.T) {
+	b, _ := newTestBreaker("test:opens")
+
+	// Failures below MinRequests don't open the breaker
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, b.Do(fail), ErrUnavailable)
+	}
+	assert.Equal(t, StateClosed, b.Status().State)
+
+	assert.NoError(t, b.Do(succeed))
+	assert.Equal(t, StateOpen, b.Status().State, "3 of 4 calls failed")
+
+	called := false
+	err := b.Do(func() error { called = true; return nil })
+	assert.False(t, called, "open breaker should short-circuit calls")
+	assert.ErrorIs(t, err, ErrOpen)
+	retryAfter, ok := RetryAfter(err)
+	require.True(t, ok)
+	assert.Equal(t, 30*time.Second, retryAfter)
+	assert.ErrorIs(t, b.Ready(), ErrOpen)
+}
+
+func TestBreakerIgnoresNonProviderErrors(t *testing.T) {
+	b, _ := newTestBreaker("test:declines")
+
+	declined := errors.New("charge declined")
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, declined, b.Do(func() error { return declined }))
+	}
+	assert.Equal(t, StateClosed, b.Status().State)
+}
+
+func TestBreakerWindowResets(t *testing.T) {
+	b, advance := newTestBreaker("test:window")
+
+	for i := 0; i < 3; i++ {
+		b.Do(fail)
+	}
+	advance(2 * time.Minute)
+	b.Do(fail)
+	assert.Equal(t, StateClosed, b.Status().State, "failures from the previous window shouldn't count")
+	assert.Equal(t, 1, b.Status().Failures)
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	open := func(t *testing.T, name string) (*Breaker, func(time.Duration)) {
+		b, advance := newTestBreaker(name)
+		for i := 0; i < 4; i++ {
+			b.Do(fail)
+		}
+		require.Equal(t, StateOpen, b.Status().State)
+		advance(30 * time.Second)
+		require.NoError(t, b.Ready())
+		return b, advance
+	}
+
+	t.Run("successful trial closes", func(t *testing.T) {
+		b, _ := open(t, "test:half-open-success")
+
+		call, err := b.Allow()
+		require.NoError(t, err)
+		assert.Equal(t, StateHalfOpen, b.Status().State)
+		_, err = b.Allow()
+		assert.ErrorIs(t, err, ErrOpen, "only one trial call at a time")
+
+		call.Done(nil)
+		assert.Equal(t, StateClosed, b.Status().State)
+		assert.NoError(t, b.Do(succeed))
+	})
+
+	t.Run("failed trial reopens", func(t *testing.T) {
+		b, advance := open(t, "test:half-open-failure")
+
+		assert.ErrorIs(t, b.Do(func() error { return context.DeadlineExceeded }), context.DeadlineExceeded)
+		assert.Equal(t, StateOpen, b.Status().State)
+		assert.ErrorIs(t, b.Ready(), ErrOpen)
+
+		advance(30 * time.Second)
+		assert.NoError(t, b.Do(succeed))
+		assert.Equal(t, StateClosed, b.Status().State)
+	})
+
+	t.Run("released trial lets another through", func(t *testing.T) {
+		b, _ := open(t, "test:half-open-release")
+
+		call, err := b.Allow()
+		require.NoError(t, err)
+		call.Release()
+		call.Done(errDown) // Ignored once released
+
+		assert.Equal(t, StateHalfOpen, b.Status().State)
+		assert.NoError(t, b.Do(succeed))
+		assert.Equal(t, StateClosed, b.Status().State)
+	})
+}
+
+func TestIsFailure(t *testing.T) {
+	assert.False(t, IsFailure(nil))
+	assert.False(t, IsFailure(errors.New("insufficient funds")))
+	assert.False(t, IsFailure(&OpenError{Name: "test"}))
+	assert.True(t, IsFailure(context.DeadlineExceeded))
+	assert.True(t, IsFailure(errDown))
+}
+
+func TestSnapshot(t *testing.T) {
+	b, _ := newTestBreaker("test:snapshot")
+	for i := 0; i < 4; i++ {
+		b.Do(fail)
+	}
+
+	var found *Status
+	for _, status := range Snapshot() {
+		if status.Name == "test:snapshot" {
+			status := status
+			found = &status
+		}
+	}
+	require.NotNil(t, found)
+	assert.Equal(t, StateOpen, found.State)
+	require.NotNil(t, found.RetryAt)
+	assert.Equal(t, found.OpenedAt.Add(30*time.Second), *found.RetryAt)
+}
@@ -2,8 +2,11 @@ package config
 
 import (
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/revaspay/backend/internal/secrets"
@@ -22,7 +25,22 @@ type Config struct {
 	Stripe      StripeConfig
 	PayPal      PayPalConfig
 	Didit      DiditConfig
+	KYC         KYCConfig
 	MoMo        MoMoConfig
+	Grey        GreyConfig
+	Wise        WiseConfig
+	Barter      BarterConfig
+	Withdrawal  WithdrawalConfig
+	Wallet      WalletConfig
+	Payment     PaymentConfig
+	Timeouts    TimeoutConfig
+	CircuitBreaker CircuitBreakerConfig
+	Features    map[string]bool // Feature flag defaults by flag key, from FEATURE_<KEY>=true|false
+	JobRetry    map[string]JobRetryPolicy // Retry policy overrides by job type, from JOB_RETRY_<TYPE>
+	JobWorkers  int                       // Background job workers on each instance, from JOB_WORKERS
+	JobConcurrency map[string]int         // Concurrency limit overrides by job type, from JOB_CONCURRENCY_<TYPE>
+	Cleanup     CleanupConfig
+	Rounding    RoundingConfig
 	
 	dopplerClient   *secrets.DopplerClient
 	dopplerInitOnce sync.Once
@@ -37,9 +55,10 @@ type DatabaseConfig struct {
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	Port         string
-	ReadTimeout  int
-	WriteTimeout int
+	Port            string
+	ReadTimeout     int
+	WriteTimeout    int
+	ShutdownTimeout int // Seconds to wait for in-flight jobs and requests on shutdown
 }
 
 // RedisConfig holds Redis configuration
@@ -51,8 +70,11 @@ type RedisConfig struct {
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
-	Secret     string
-	Expiration int // in hours
+	Secret          string            // Current signing secret
+	KeyID           string            // ID of the current secret, sent in the kid header of new tokens
+	PreviousKeys    map[string]string // Retired secrets by key ID, still accepted until their tokens expire
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
 }
 
 // PaystackConfig holds Paystack configuration
@@ -90,6 +112,12 @@ type DiditConfig struct {
 	Environment   string // sandbox or production
 }
 
+// KYCConfig holds how KYC providers are polled for results that never arrive by webhook
+type KYCConfig struct {
+	PollInterval    time.Duration // Between the first polls of a submitted verification, doubling after each
+	PollMaxDuration time.Duration // How long after submission a verification without a result times out
+}
+
 // MoMoConfig holds MTN Mobile Money API configuration
 type MoMoConfig struct {
 	SubscriptionKey      string
@@ -98,6 +126,145 @@ type MoMoConfig struct {
 	DisbursementAPIUser  string
 	DisbursementAPIKey   string
 	UseSandbox           bool
+	CallbackSecret       string // Token appended to callback URLs, as MoMo doesn't sign callbacks
+	CallbackBaseURL      string // Public URL of this API, which MoMo sends callbacks to
+}
+
+// GreyConfig holds Grey virtual account API configuration
+type GreyConfig struct {
+	APIKey  string
+	BaseURL string
+}
+
+// WiseConfig holds Wise API configuration. Account details are issued on ProfileID.
+type WiseConfig struct {
+	APIToken  string
+	ProfileID string
+	BaseURL   string
+}
+
+// BarterConfig holds Barter (by Flutterwave) virtual account API configuration
+type BarterConfig struct {
+	SecretKey string
+	BaseURL   string
+}
+
+// WithdrawalConfig holds withdrawal processing configuration. Dry runs go through the whole
+// withdrawal flow without calling payout providers, for testing provider integrations.
+type WithdrawalConfig struct {
+	DryRun          bool // Dry run every withdrawal
+	AllowDryRunFlag bool // Dry run withdrawals whose metadata sets dry_run; never enable in production
+
+	// ManualResolveAfter is how long a withdrawal must have been processing before an admin
+	// can resolve it by hand, so the status check has had its chance to settle it
+	ManualResolveAfter time.Duration
+}
+
+// WalletConfig holds wallet configuration
+type WalletConfig struct {
+	// CreditBatchWindow batches payment credits to each wallet over this window into one
+	// balance update, to cut lock contention on busy wallets. Credits are saved as pending
+	// first and get their ledger entries when applied. Zero credits each one directly.
+	CreditBatchWindow time.Duration
+	// BalanceWebhookWindow coalesces a wallet's balance changes over this window into one
+	// wallet.balance_changed webhook event
+	BalanceWebhookWindow time.Duration
+}
+
+// TimeoutConfig bounds how long outbound provider calls and background jobs may run, so a
+// hung provider can't hold a request or worker indefinitely
+type TimeoutConfig struct {
+	Provider time.Duration // Each HTTP call to a payment, payout or KYC provider
+	Job      time.Duration // Each background job, including the provider calls it makes
+}
+
+// CircuitBreakerConfig controls the circuit breakers around payment and payout providers,
+// which stop calling a provider that keeps failing until a cool-down has passed
+type CircuitBreakerConfig struct {
+	FailureRate float64       // Share of calls in Window that must fail to open a breaker
+	MinRequests int           // Calls needed in Window before the failure rate is judged
+	Window      time.Duration // How long calls are counted for
+	CoolDown    time.Duration // How long an open breaker rejects calls before a trial call
+}
+
+// CleanupConfig controls the recurring job that deletes expired tokens, old sessions,
+// completed queue jobs and audit logs past their retention
+type CleanupConfig struct {
+	Interval               time.Duration // Time between cleanup runs
+	SessionRetention       time.Duration // How long expired and revoked sessions are kept, for security reviews
+	JobRetention           time.Duration // How long completed queue jobs are kept
+	AuditInfoRetention     time.Duration // How long info audit logs are kept; 0 keeps them forever
+	AuditWarningRetention  time.Duration // How long warning audit logs are kept
+	AuditErrorRetention    time.Duration // How long error audit logs are kept
+	AuditCriticalRetention time.Duration // How long critical audit logs are kept
+	AuditAnonymize         bool          // Anonymize warning and worse audit logs past their retention instead of deleting them
+}
+
+// RoundingConfig holds the rounding mode names (half_up, half_even, down or up) for each kind
+// of amount
+type RoundingConfig struct {
+	Customer   string // Amounts charged or credited to customers and merchants
+	Fee        string // Our fees; only round in our favour where that's allowed
+	Conversion string // Amounts converted between currencies
+}
+
+// JobRetryPolicy controls how failed background jobs of one type are retried
+type JobRetryPolicy struct {
+	MaxRetries  int           // Retries after the first attempt; 0 never retries
+	BaseBackoff time.Duration // Delay before the first retry, doubled for each retry after it
+	MaxBackoff  time.Duration // Longest delay between retries
+	DeadLetter  bool          // Keep jobs that run out of retries in the failed set for inspection
+}
+
+// AmountLimit bounds the amount of a single payment in one currency
+type AmountLimit struct {
+	Min float64 `json:"min_amount"`
+	Max float64 `json:"max_amount"`
+}
+
+// MetadataLimits bounds the free-form metadata merchants attach to payments and payment links
+type MetadataLimits struct {
+	MaxBytes int // Size of the metadata encoded as JSON
+	MaxKeys  int // Keys across every level of nesting
+	MaxDepth int // Levels of nested objects and arrays; a flat object is depth 1
+}
+
+// DefaultMetadataLimits are used when the PAYMENT_METADATA_* variables aren't set
+var DefaultMetadataLimits = MetadataLimits{MaxBytes: 8 << 10, MaxKeys: 50, MaxDepth: 5}
+
+// PaymentConfig holds payment configuration
+type PaymentConfig struct {
+	AmountLimits     map[string]AmountLimit   // Keyed by currency code; currencies without limits aren't bounded
+	MetadataLimits   MetadataLimits
+	Expiry           time.Duration            // How long a pending payment can be paid before it expires
+	ProviderExpiry   map[string]time.Duration // Keyed by provider; overrides Expiry
+	ExpiryInterval   time.Duration            // How often expired pending payments are looked for
+	Routes           []PaymentRoute           // Tried in order; the first that matches a payment picks its providers
+	DefaultProviders []string                 // Providers for payments no route matches, in order of preference
+	CredentialsKey   string                   // Base64 32-byte AES-256 key encrypting merchants' own provider secret keys
+}
+
+// PaymentRoute sends payments that match it to its providers, in order of preference. Empty
+// currencies or countries match any, and a zero amount bound doesn't bound.
+type PaymentRoute struct {
+	Name       string
+	Currencies []string // Currency codes, upper case
+	Countries  []string // Customer's ISO 3166 country codes, upper case
+	MinAmount  float64
+	MaxAmount  float64
+	Providers  []string
+}
+
+// defaultPaymentAmountLimits are the payment amount bounds used for currencies whose
+// PAYMENT_LIMITS_<CURRENCY> variable isn't set
+var defaultPaymentAmountLimits = map[string]AmountLimit{
+	"USD": {Min: 0.5, Max: 50000},
+	"EUR": {Min: 0.5, Max: 50000},
+	"GBP": {Min: 0.5, Max: 40000},
+	"NGN": {Min: 100, Max: 50000000},
+	"GHS": {Min: 1, Max: 500000},
+	"KES": {Min: 50, Max: 5000000},
+	"ZAR": {Min: 10, Max: 1000000},
 }
 
 // LoadConfig creates a new Config instance with values from environment variables
@@ -114,9 +281,10 @@ func LoadConfig() *Config {
 			MaxIdle:  getEnvInt("DATABASE_MAX_IDLE", 5),
 		},
 		Server: ServerConfig{
-			Port:         getEnv("PORT", "8080"),
-			ReadTimeout:  getEnvInt("SERVER_READ_TIMEOUT", 10),
-			WriteTimeout: getEnvInt("SERVER_WRITE_TIMEOUT", 10),
+			Port:            getEnv("PORT", "8080"),
+			ReadTimeout:     getEnvInt("SERVER_READ_TIMEOUT", 10),
+			WriteTimeout:    getEnvInt("SERVER_WRITE_TIMEOUT", 10),
+			ShutdownTimeout: getEnvInt("SERVER_SHUTDOWN_TIMEOUT", 30),
 		},
 		Redis: RedisConfig{
 			URL:      getEnv("REDIS_URL", "redis://localhost:6379"),
@@ -124,7 +292,74 @@ func LoadConfig() *Config {
 			DB:       getEnvInt("REDIS_DB", 0),
 		},
 		JWT: JWTConfig{
-			Expiration: getEnvInt("JWT_EXPIRATION", 24),
+			KeyID:           getEnv("JWT_KEY_ID", ""),
+			AccessTokenTTL:  getEnvDuration("JWT_EXPIRY", 15*time.Minute),
+			RefreshTokenTTL: getEnvDuration("JWT_REFRESH_EXPIRY", 7*24*time.Hour),
+		},
+		Grey: GreyConfig{
+			BaseURL: getEnv("GREY_BASE_URL", ""),
+		},
+		Wise: WiseConfig{
+			ProfileID: getEnv("WISE_PROFILE_ID", ""),
+			BaseURL:   getEnv("WISE_BASE_URL", ""),
+		},
+		Barter: BarterConfig{
+			BaseURL: getEnv("BARTER_BASE_URL", ""),
+		},
+		Withdrawal: WithdrawalConfig{
+			DryRun:          getEnv("WITHDRAWAL_DRY_RUN", "false") == "true",
+			AllowDryRunFlag: getEnv("WITHDRAWAL_ALLOW_DRY_RUN_FLAG", "false") == "true",
+			ManualResolveAfter: getEnvDuration("WITHDRAWAL_MANUAL_RESOLVE_AFTER", 24*time.Hour),
+		},
+		Wallet: WalletConfig{
+			CreditBatchWindow:    getEnvDuration("WALLET_CREDIT_BATCH_WINDOW", 0),
+			BalanceWebhookWindow: getEnvDuration("WALLET_BALANCE_WEBHOOK_WINDOW", 10*time.Second),
+		},
+		Timeouts: TimeoutConfig{
+			Provider: getEnvDuration("PROVIDER_TIMEOUT", 30*time.Second),
+			Job:      getEnvDuration("JOB_TIMEOUT", 5*time.Minute),
+		},
+		CircuitBreaker: CircuitBreakerConfig{
+			FailureRate: getEnvFloat("CIRCUIT_BREAKER_FAILURE_RATE", 0.5),
+			MinRequests: getEnvInt("CIRCUIT_BREAKER_MIN_REQUESTS", 10),
+			Window:      getEnvDuration("CIRCUIT_BREAKER_WINDOW", time.Minute),
+			CoolDown:    getEnvDuration("CIRCUIT_BREAKER_COOLDOWN", 30*time.Second),
+		},
+		Features: getFeatureDefaults(),
+		JobRetry: getJobRetryPolicies(),
+		JobWorkers: getEnvInt("JOB_WORKERS", 20),
+		JobConcurrency: getJobConcurrencyLimits(),
+		Cleanup: CleanupConfig{
+			Interval:               getEnvDuration("CLEANUP_INTERVAL", 6*time.Hour),
+			SessionRetention:       getEnvDuration("SESSION_RETENTION", 30*24*time.Hour),
+			JobRetention:           getEnvDuration("COMPLETED_JOB_RETENTION", 7*24*time.Hour),
+			AuditInfoRetention:     getEnvDuration("AUDIT_LOG_RETENTION_INFO", 90*24*time.Hour),
+			AuditWarningRetention:  getEnvDuration("AUDIT_LOG_RETENTION_WARNING", 365*24*time.Hour),
+			AuditErrorRetention:    getEnvDuration("AUDIT_LOG_RETENTION_ERROR", 365*24*time.Hour),
+			AuditCriticalRetention: getEnvDuration("AUDIT_LOG_RETENTION_CRITICAL", 5*365*24*time.Hour),
+			AuditAnonymize:         getEnv("AUDIT_LOG_ANONYMIZE", "false") == "true",
+		},
+		Rounding: RoundingConfig{
+			Customer:   getEnv("ROUNDING_MODE_CUSTOMER", "half_up"),
+			Fee:        getEnv("ROUNDING_MODE_FEE", "half_up"),
+			Conversion: getEnv("ROUNDING_MODE_CONVERSION", "half_up"),
+		},
+		KYC: KYCConfig{
+			PollInterval:    getEnvDuration("KYC_POLL_INTERVAL", 5*time.Minute),
+			PollMaxDuration: getEnvDuration("KYC_POLL_MAX_DURATION", 48*time.Hour),
+		},
+		Payment: PaymentConfig{
+			AmountLimits: getPaymentAmountLimits(),
+			MetadataLimits: MetadataLimits{
+				MaxBytes: getEnvInt("PAYMENT_METADATA_MAX_BYTES", DefaultMetadataLimits.MaxBytes),
+				MaxKeys:  getEnvInt("PAYMENT_METADATA_MAX_KEYS", DefaultMetadataLimits.MaxKeys),
+				MaxDepth: getEnvInt("PAYMENT_METADATA_MAX_DEPTH", DefaultMetadataLimits.MaxDepth),
+			},
+			Expiry:           getEnvDuration("PAYMENT_EXPIRY", 30*time.Minute),
+			ProviderExpiry:   getPaymentProviderExpiry(),
+			ExpiryInterval:   getEnvDuration("PAYMENT_EXPIRY_INTERVAL", time.Minute),
+			Routes:           getPaymentRoutes(),
+			DefaultProviders: getEnvListOrDefault("PAYMENT_DEFAULT_PROVIDERS", []string{"paystack"}),
 		},
 		FrontendURL: getEnv("FRONTEND_URL", "http://localhost:3000"),
 		Environment: getEnv("ENVIRONMENT", "development"),
@@ -161,10 +396,12 @@ func (c *Config) initSecrets() {
 			// If Doppler initialization fails, fall back to environment variables
 			// This allows the application to run without Doppler in development
 			c.JWT.Secret = getEnv("JWT_SECRET", "your-secret-key")
+			c.JWT.PreviousKeys = parseKeyList(getEnv("JWT_PREVIOUS_KEYS", ""))
 			
 			// Payment provider credentials from environment
 			c.Paystack.SecretKey = getEnv("PAYSTACK_SECRET_KEY", "")
 			c.Paystack.PublicKey = getEnv("PAYSTACK_PUBLIC_KEY", "")
+			c.Payment.CredentialsKey = getEnv("PAYMENT_CREDENTIALS_KEY", "")
 			
 			c.Flutterwave.SecretKey = getEnv("FLUTTERWAVE_SECRET_KEY", "")
 			c.Flutterwave.PublicKey = getEnv("FLUTTERWAVE_PUBLIC_KEY", "")
@@ -190,15 +427,24 @@ func (c *Config) initSecrets() {
 			c.MoMo.DisbursementAPIUser = getEnv("MTN_MOMO_DISBURSEMENT_API_USER", "")
 			c.MoMo.DisbursementAPIKey = getEnv("MTN_MOMO_DISBURSEMENT_API_KEY", "")
 			c.MoMo.UseSandbox = getEnv("MTN_MOMO_USE_SANDBOX", "true") == "true"
+			c.MoMo.CallbackSecret = getEnv("MTN_MOMO_CALLBACK_SECRET", "")
+			c.MoMo.CallbackBaseURL = getEnv("MTN_MOMO_CALLBACK_BASE_URL", "")
+			
+			// Virtual account provider credentials from environment
+			c.Grey.APIKey = getEnv("GREY_API_KEY", "")
+			c.Wise.APIToken = getEnv("WISE_API_TOKEN", "")
+			c.Barter.SecretKey = getEnv("BARTER_SECRET_KEY", "")
 			return
 		}
 
 		// Get secrets from Doppler with fallback to environment variables
 		c.JWT.Secret = c.dopplerClient.GetSecretWithFallback("JWT_SECRET", getEnv("JWT_SECRET", "your-secret-key"))
+		c.JWT.PreviousKeys = parseKeyList(c.dopplerClient.GetSecretWithFallback("JWT_PREVIOUS_KEYS", getEnv("JWT_PREVIOUS_KEYS", "")))
 		
 		// Payment provider credentials from Doppler with fallback to environment
 		c.Paystack.SecretKey = c.dopplerClient.GetSecretWithFallback("PAYSTACK_SECRET_KEY", getEnv("PAYSTACK_SECRET_KEY", ""))
 		c.Paystack.PublicKey = c.dopplerClient.GetSecretWithFallback("PAYSTACK_PUBLIC_KEY", getEnv("PAYSTACK_PUBLIC_KEY", ""))
+		c.Payment.CredentialsKey = c.dopplerClient.GetSecretWithFallback("PAYMENT_CREDENTIALS_KEY", getEnv("PAYMENT_CREDENTIALS_KEY", ""))
 		
 		c.Flutterwave.SecretKey = c.dopplerClient.GetSecretWithFallback("FLUTTERWAVE_SECRET_KEY", getEnv("FLUTTERWAVE_SECRET_KEY", ""))
 		c.Flutterwave.PublicKey = c.dopplerClient.GetSecretWithFallback("FLUTTERWAVE_PUBLIC_KEY", getEnv("FLUTTERWAVE_PUBLIC_KEY", ""))
@@ -223,10 +469,17 @@ func (c *Config) initSecrets() {
 		c.MoMo.CollectionAPIKey = c.dopplerClient.GetSecretWithFallback("MTN_MOMO_COLLECTION_API_KEY", getEnv("MTN_MOMO_COLLECTION_API_KEY", ""))
 		c.MoMo.DisbursementAPIUser = c.dopplerClient.GetSecretWithFallback("MTN_MOMO_DISBURSEMENT_API_USER", getEnv("MTN_MOMO_DISBURSEMENT_API_USER", ""))
 		c.MoMo.DisbursementAPIKey = c.dopplerClient.GetSecretWithFallback("MTN_MOMO_DISBURSEMENT_API_KEY", getEnv("MTN_MOMO_DISBURSEMENT_API_KEY", ""))
+		c.MoMo.CallbackSecret = c.dopplerClient.GetSecretWithFallback("MTN_MOMO_CALLBACK_SECRET", getEnv("MTN_MOMO_CALLBACK_SECRET", ""))
+		c.MoMo.CallbackBaseURL = c.dopplerClient.GetSecretWithFallback("MTN_MOMO_CALLBACK_BASE_URL", getEnv("MTN_MOMO_CALLBACK_BASE_URL", ""))
 		
 		// Parse boolean value
 		useSandbox := c.dopplerClient.GetSecretWithFallback("MTN_MOMO_USE_SANDBOX", getEnv("MTN_MOMO_USE_SANDBOX", "true"))
 		c.MoMo.UseSandbox = useSandbox == "true"
+		
+		// Virtual account provider credentials from Doppler with fallback to environment
+		c.Grey.APIKey = c.dopplerClient.GetSecretWithFallback("GREY_API_KEY", getEnv("GREY_API_KEY", ""))
+		c.Wise.APIToken = c.dopplerClient.GetSecretWithFallback("WISE_API_TOKEN", getEnv("WISE_API_TOKEN", ""))
+		c.Barter.SecretKey = c.dopplerClient.GetSecretWithFallback("BARTER_SECRET_KEY", getEnv("BARTER_SECRET_KEY", ""))
 	})
 }
 
@@ -261,3 +514,246 @@ func getEnvInt(key string, defaultValue int) int {
 	
 	return intValue
 }
+
+// getEnvDuration retrieves an environment variable as a duration such as "15m", or returns a
+// default value if it's unset or invalid
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return defaultValue
+	}
+
+	return duration
+}
+
+// parseKeyList parses comma-separated "id:secret" pairs. Entries without both parts are ignored.
+func parseKeyList(value string) map[string]string {
+	keys := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" || secret == "" {
+			continue
+		}
+		keys[id] = secret
+	}
+	return keys
+}
+
+// getEnvList retrieves a comma-separated environment variable as a list, dropping blank entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvListOrDefault retrieves a comma-separated environment variable as a list, or returns
+// a default value if it's unset or blank
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	if values := getEnvList(key); len(values) > 0 {
+		return values
+	}
+	return defaultValue
+}
+
+// getFeatureDefaults reads feature flag defaults from FEATURE_<KEY> variables, e.g.
+// FEATURE_CRYPTO_WALLETS=true enables the crypto_wallets flag. Values that aren't booleans are ignored.
+func getFeatureDefaults() map[string]bool {
+	features := make(map[string]bool)
+	for _, entry := range os.Environ() {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(name, "FEATURE_") {
+			continue
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		features[strings.ToLower(strings.TrimPrefix(name, "FEATURE_"))] = enabled
+	}
+	return features
+}
+
+// getJobRetryPolicies reads job retry policy overrides from
+// JOB_RETRY_<TYPE>=max_retries,base_backoff,max_backoff[,dead_letter] variables, e.g.
+// JOB_RETRY_PAYMENT_WEBHOOK=8,10s,2h retries payment_webhook jobs 8 times. Dead-lettering
+// defaults to true. Invalid overrides are ignored.
+func getJobRetryPolicies() map[string]JobRetryPolicy {
+	policies := make(map[string]JobRetryPolicy)
+	for _, entry := range os.Environ() {
+		name, _, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(name, "JOB_RETRY_") {
+			continue
+		}
+		fields := getEnvList(name)
+		if len(fields) != 3 && len(fields) != 4 {
+			continue
+		}
+		maxRetries, retriesErr := strconv.Atoi(fields[0])
+		baseBackoff, baseErr := time.ParseDuration(fields[1])
+		maxBackoff, maxErr := time.ParseDuration(fields[2])
+		if retriesErr != nil || baseErr != nil || maxErr != nil || maxRetries < 0 || baseBackoff <= 0 || maxBackoff < baseBackoff {
+			continue
+		}
+		deadLetter := true
+		if len(fields) == 4 {
+			var err error
+			if deadLetter, err = strconv.ParseBool(fields[3]); err != nil {
+				continue
+			}
+		}
+		policies[strings.ToLower(strings.TrimPrefix(name, "JOB_RETRY_"))] = JobRetryPolicy{
+			MaxRetries:  maxRetries,
+			BaseBackoff: baseBackoff,
+			MaxBackoff:  maxBackoff,
+			DeadLetter:  deadLetter,
+		}
+	}
+	return policies
+}
+
+// getJobConcurrencyLimits reads job concurrency limit overrides from JOB_CONCURRENCY_<TYPE>
+// variables, e.g. JOB_CONCURRENCY_PROCESS_KYC_VERIFICATION=5 lets 5 KYC verifications run at
+// once. A limit of 0 removes the job type's limit. Values that aren't whole numbers are ignored.
+func getJobConcurrencyLimits() map[string]int {
+	limits := make(map[string]int)
+	for _, entry := range os.Environ() {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(name, "JOB_CONCURRENCY_") {
+			continue
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit < 0 {
+			continue
+		}
+		limits[strings.ToLower(strings.TrimPrefix(name, "JOB_CONCURRENCY_"))] = limit
+	}
+	return limits
+}
+
+// getPaymentProviderExpiry returns the payment expiry per provider from
+// PAYMENT_EXPIRY_<PROVIDER>. Invalid or non-positive durations are ignored.
+func getPaymentProviderExpiry() map[string]time.Duration {
+	expiry := make(map[string]time.Duration)
+	for _, entry := range os.Environ() {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(name, "PAYMENT_EXPIRY_") || name == "PAYMENT_EXPIRY_INTERVAL" {
+			continue
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || duration <= 0 {
+			continue
+		}
+		expiry[strings.ToLower(strings.TrimPrefix(name, "PAYMENT_EXPIRY_"))] = duration
+	}
+	return expiry
+}
+
+// getPaymentRoutes reads payment routing rules from PAYMENT_ROUTE_<NAME> variables, tried in
+// name order, e.g. PAYMENT_ROUTE_10_NIGERIA=currencies=NGN;countries=NG;max=5000000;providers=paystack,flutterwave.
+// Every field but providers is optional. Rules without providers or with invalid fields are ignored.
+func getPaymentRoutes() []PaymentRoute {
+	var routes []PaymentRoute
+	for _, entry := range os.Environ() {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(name, "PAYMENT_ROUTE_") {
+			continue
+		}
+		route, ok := parsePaymentRoute(value)
+		if !ok {
+			continue
+		}
+		route.Name = strings.ToLower(strings.TrimPrefix(name, "PAYMENT_ROUTE_"))
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Name < routes[j].Name })
+	return routes
+}
+
+// parsePaymentRoute parses a routing rule's semicolon separated key=value fields
+func parsePaymentRoute(value string) (PaymentRoute, bool) {
+	var route PaymentRoute
+	for _, field := range strings.Split(value, ";") {
+		key, raw, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return PaymentRoute{}, false
+		}
+		var list []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		var err error
+		switch strings.TrimSpace(key) {
+		case "currencies":
+			for _, code := range list {
+				route.Currencies = append(route.Currencies, strings.ToUpper(code))
+			}
+		case "countries":
+			for _, code := range list {
+				route.Countries = append(route.Countries, strings.ToUpper(code))
+			}
+		case "min":
+			route.MinAmount, err = strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		case "max":
+			route.MaxAmount, err = strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		case "providers":
+			for _, provider := range list {
+				route.Providers = append(route.Providers, strings.ToLower(provider))
+			}
+		default:
+			return PaymentRoute{}, false
+		}
+		if err != nil {
+			return PaymentRoute{}, false
+		}
+	}
+	if len(route.Providers) == 0 || route.MinAmount < 0 || (route.MaxAmount > 0 && route.MaxAmount < route.MinAmount) {
+		return PaymentRoute{}, false
+	}
+	return route, true
+}
+
+// getPaymentAmountLimits returns the default payment amount limits, overridden per currency by
+// PAYMENT_LIMITS_<CURRENCY>=min,max. Invalid overrides are ignored.
+func getPaymentAmountLimits() map[string]AmountLimit {
+	limits := make(map[string]AmountLimit, len(defaultPaymentAmountLimits))
+	for currency, limit := range defaultPaymentAmountLimits {
+		limits[currency] = limit
+		bounds := getEnvList("PAYMENT_LIMITS_" + currency)
+		if len(bounds) != 2 {
+			continue
+		}
+		minAmount, minErr := strconv.ParseFloat(bounds[0], 64)
+		maxAmount, maxErr := strconv.ParseFloat(bounds[1], 64)
+		if minErr != nil || maxErr != nil || minAmount <= 0 || maxAmount < minAmount {
+			continue
+		}
+		limits[currency] = AmountLimit{Min: minAmount, Max: maxAmount}
+	}
+	return limits
+}
+
+// getEnvFloat retrieves an environment variable as a float or returns a default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return floatValue
+}
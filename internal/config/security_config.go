@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,29 @@ type SecurityConfig struct {
 	AuthRateLimit       int
 	AuthRateBurst       int
 	RateLimitCleanupMin int
+	RateLimitBackend    string // "redis" to share limits across instances, "memory" for local dev
+
+	// Per-user rate limiting of expensive endpoints, in requests per minute
+	PaymentRateLimit    float64
+	PaymentRateBurst    int
+	WithdrawalRateLimit float64
+	WithdrawalRateBurst int
+	KYCSubmitRateLimit  float64
+	KYCSubmitRateBurst  int
+
+	// Per-IP limit on the unauthenticated payment status lookup, in requests per minute
+	PublicPaymentStatusRateLimit float64
+	PublicPaymentStatusRateBurst int
+
+	// Trusted proxies - X-Forwarded-For is only believed from these CIDRs. Leaving this empty
+	// makes the client IP the connecting address; trusting everything would let any client
+	// spoof its IP and dodge rate limits and brute-force lockouts.
+	TrustedProxies []string
+
+	// Webhook IP allow lists - provider webhooks only accept requests from these CIDRs
+	WebhookAllowedIPs     map[string][]string // By provider; a provider without ranges isn't restricted
+	WebhookTrustedProxies []string            // Proxies whose X-Forwarded-For is believed, TrustedProxies by default
+	WebhookAPIKey         string              // X-API-Key the blockchain, bank and exchange rate webhooks must send; unset rejects them all
 
 	// CSRF protection
 	CSRFSecret      string
@@ -26,15 +50,41 @@ type SecurityConfig struct {
 	CORSAllowedOrigins    []string
 
 	// Session security
-	SessionMaxAge        int
-	SessionRenewAfter    int
-	SessionInactivityMax int
+	SessionMaxAge              int
+	SessionRenewAfter          int
+	SessionIdleTimeout         time.Duration // Sessions unused for this long expire and must sign in again
+	SessionIdleTimeoutHighRisk time.Duration // Shorter idle timeout for sessions from risky logins
+	TrustedDeviceDuration      time.Duration // How long a device marked as trusted skips MFA challenges
 
 	// MFA settings
 	MFAIssuer     string
 	MFADigits     int
 	MFAPeriod     uint
 	MFABackupCodes int
+
+	// Request body limits - larger bodies are rejected with 413
+	MaxBodyBytes       int64 // Every request but file uploads
+	MaxUploadBodyBytes int64 // multipart/form-data file uploads
+
+	// Password checks
+	PasswordMinStrength  string // Minimum EvaluatePasswordStrength score, by name ("moderate") or number ("2")
+	PasswordBreachCheck  bool   // Reject passwords found in the Have I Been Pwned breach corpus
+	PasswordHistoryCount int    // New passwords can't match any of the user's last this many; 0 allows reuse
+
+	// Password reset emails - at most this many are sent per address and per client IP within
+	// the window; further requests get the usual response but send nothing
+	PasswordResetEmailLimit int
+	PasswordResetIPLimit    int
+	PasswordResetWindow     time.Duration
+
+	// New login emails - at most this many are sent to a user within the window, so a login
+	// loop doesn't flood their inbox
+	LoginNotificationLimit  int
+	LoginNotificationWindow time.Duration
+
+	// Login risk
+	ImpossibleTravelSpeedKmh      float64 // Fastest plausible travel speed between two logins
+	ImpossibleTravelMinDistanceKm float64 // Jumps shorter than this are ignored as GeoIP noise
 }
 
 // DefaultSecurityConfig returns the default security configuration
@@ -46,10 +96,31 @@ func DefaultSecurityConfig() SecurityConfig {
 		AuthRateLimit:       5.0,
 		AuthRateBurst:       3.0,
 		RateLimitCleanupMin: 5,
+		RateLimitBackend:    getEnvOrDefault("RATE_LIMIT_BACKEND", "memory"),
+
+		// Per-user limits - these endpoints call out to providers, so they're much tighter
+		PaymentRateLimit:    getEnvFloat("PAYMENT_RATE_LIMIT_PER_MIN", 10),
+		PaymentRateBurst:    getEnvInt("PAYMENT_RATE_BURST", 5),
+		WithdrawalRateLimit: getEnvFloat("WITHDRAWAL_RATE_LIMIT_PER_MIN", 3),
+		WithdrawalRateBurst: getEnvInt("WITHDRAWAL_RATE_BURST", 2),
+		KYCSubmitRateLimit:  getEnvFloat("KYC_SUBMIT_RATE_LIMIT_PER_MIN", 1),
+		KYCSubmitRateBurst:  getEnvInt("KYC_SUBMIT_RATE_BURST", 3),
+
+		// The hosted checkout page polls every few seconds while a payment settles
+		PublicPaymentStatusRateLimit: getEnvFloat("PUBLIC_PAYMENT_STATUS_RATE_LIMIT_PER_MIN", 30),
+		PublicPaymentStatusRateBurst: getEnvInt("PUBLIC_PAYMENT_STATUS_RATE_BURST", 10),
+
+		// Trusted proxies - none by default, so X-Forwarded-For is ignored
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
+
+		// Webhook IP allow lists, e.g. WEBHOOK_ALLOWED_IPS_PAYSTACK=52.31.139.75,52.49.173.169
+		WebhookAllowedIPs:     webhookAllowedIPs(),
+		WebhookTrustedProxies: getEnvListOrDefault("WEBHOOK_TRUSTED_PROXIES", getEnvList("TRUSTED_PROXIES")),
+		WebhookAPIKey:         os.Getenv("WEBHOOK_API_KEY"),
 
 		// CSRF protection
 		CSRFSecret:       getEnvOrDefault("CSRF_SECRET", "change-me-in-production"),
-		CSRFExcludePaths: []string{"/api/webhooks/*", "/health"},
+		CSRFExcludePaths: []string{"/webhooks/*", "/api/webhooks/*", "/health"},
 
 		// Secure headers
 		HSTSMaxAge:            31536000 * time.Second, // 1 year
@@ -68,16 +139,54 @@ func DefaultSecurityConfig() SecurityConfig {
 		CORSAllowedOrigins: []string{"*"}, // Should be restricted in production
 
 		// Session security
-		SessionMaxAge:        86400 * 7, // 7 days
-		SessionRenewAfter:    3600 * 12, // 12 hours
-		SessionInactivityMax: 3600,      // 1 hour
+		SessionMaxAge:              86400 * 7, // 7 days
+		SessionRenewAfter:          3600 * 12, // 12 hours
+		SessionIdleTimeout:         time.Duration(getEnvInt("SESSION_IDLE_TIMEOUT_MINUTES", 60)) * time.Minute,
+		SessionIdleTimeoutHighRisk: time.Duration(getEnvInt("SESSION_IDLE_TIMEOUT_HIGH_RISK_MINUTES", 30)) * time.Minute,
+		TrustedDeviceDuration:      time.Duration(getEnvInt("TRUSTED_DEVICE_DAYS", 30)) * 24 * time.Hour,
 
 		// MFA settings
 		MFAIssuer:     "RevasPay",
 		MFADigits:     6,
 		MFAPeriod:     30,
 		MFABackupCodes: 10,
+
+		// Request body limits
+		MaxBodyBytes:       int64(getEnvInt("MAX_BODY_KB", 1024)) << 10,
+		MaxUploadBodyBytes: int64(getEnvInt("MAX_UPLOAD_BODY_MB", 20)) << 20,
+
+		// Password checks
+		PasswordMinStrength:  getEnvOrDefault("PASSWORD_MIN_STRENGTH", "moderate"),
+		PasswordBreachCheck:  getEnvOrDefault("PASSWORD_BREACH_CHECK", "true") != "false",
+		PasswordHistoryCount: getEnvInt("PASSWORD_HISTORY_COUNT", 5),
+
+		// Password reset emails
+		PasswordResetEmailLimit: getEnvInt("PASSWORD_RESET_EMAIL_LIMIT", 3),
+		PasswordResetIPLimit:    getEnvInt("PASSWORD_RESET_IP_LIMIT", 10),
+		PasswordResetWindow:     time.Duration(getEnvInt("PASSWORD_RESET_WINDOW_MINUTES", 60)) * time.Minute,
+
+		// New login emails
+		LoginNotificationLimit:  getEnvInt("LOGIN_NOTIFICATION_LIMIT", 3),
+		LoginNotificationWindow: time.Duration(getEnvInt("LOGIN_NOTIFICATION_WINDOW_MINUTES", 60)) * time.Minute,
+
+		// Login risk - faster than a commercial flight is treated as impossible
+		ImpossibleTravelSpeedKmh:      getEnvFloat("IMPOSSIBLE_TRAVEL_SPEED_KMH", 900),
+		ImpossibleTravelMinDistanceKm: getEnvFloat("IMPOSSIBLE_TRAVEL_MIN_DISTANCE_KM", 500),
+	}
+}
+
+// WebhookProviders are the providers whose webhook source IPs can be restricted
+var WebhookProviders = []string{"paystack", "flutterwave", "stripe", "paypal", "crypto", "smile", "didit", "momo"}
+
+// webhookAllowedIPs reads each provider's allow list from WEBHOOK_ALLOWED_IPS_<PROVIDER>
+func webhookAllowedIPs() map[string][]string {
+	allowed := make(map[string][]string)
+	for _, provider := range WebhookProviders {
+		if cidrs := getEnvList("WEBHOOK_ALLOWED_IPS_" + strings.ToUpper(provider)); len(cidrs) > 0 {
+			allowed[provider] = cidrs
+		}
 	}
+	return allowed
 }
 
 // getEnvOrDefault gets an environment variable or returns a default value
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// X-Forwarded-For only sets the client IP when the request comes through a configured proxy,
+// and webhooks trust the same proxies unless given their own
+func TestTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clientIP := func(cfg SecurityConfig, remoteAddr string) string {
+		t.Helper()
+		router := gin.New()
+		require.NoError(t, router.SetTrustedProxies(cfg.TrustedProxies))
+		var ip string
+		router.GET("/", func(c *gin.Context) { ip = c.ClientIP() })
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "52.31.139.75")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return ip
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "")
+	t.Setenv("WEBHOOK_TRUSTED_PROXIES", "")
+	cfg := DefaultSecurityConfig()
+	assert.Empty(t, cfg.TrustedProxies)
+	assert.Equal(t, "198.51.100.7", clientIP(cfg, "198.51.100.7:443"), "the header is ignored without trusted proxies")
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 172.16.0.1")
+	cfg = DefaultSecurityConfig()
+	assert.Equal(t, []string{"10.0.0.0/8", "172.16.0.1"}, cfg.TrustedProxies)
+	assert.Equal(t, []string{"10.0.0.0/8", "172.16.0.1"}, cfg.WebhookTrustedProxies)
+	assert.Equal(t, "52.31.139.75", clientIP(cfg, "10.1.2.3:443"))
+	assert.Equal(t, "198.51.100.7", clientIP(cfg, "198.51.100.7:443"))
+
+	t.Setenv("WEBHOOK_TRUSTED_PROXIES", "192.168.0.0/16")
+	assert.Equal(t, []string{"192.168.0.0/16"}, DefaultSecurityConfig().WebhookTrustedProxies)
+}
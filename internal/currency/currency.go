@@ -0,0 +1,150 @@
+// Package currency lists the currencies we support and how precisely their amounts are kept
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/revaspay/backend/internal/models"
+)
+
+var (
+	// ErrUnsupportedCurrency is returned for currency codes that aren't in the registry
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+	// ErrInvalidAmount is returned when parsing an amount that isn't a plain decimal number or
+	// has more decimal places than the currency's precision
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+// Info describes a supported currency
+type Info struct {
+	Code      models.Currency `json:"code"`
+	Precision int             `json:"precision"` // Digits after the decimal point in the minor unit
+}
+
+// ToMinorUnits converts an amount to an integer count of the currency's minor unit, e.g.
+// cents, rounding to the nearest unit. Sums of minor units are exact where floats are not.
+func (i Info) ToMinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * math.Pow10(i.Precision)))
+}
+
+// FromMinorUnits converts a count of the currency's minor unit back to an amount
+func (i Info) FromMinorUnits(units int64) float64 {
+	return float64(units) / math.Pow10(i.Precision)
+}
+
+// Format formats an amount with exactly the currency's number of decimal places
+func (i Info) Format(amount float64) string {
+	return strconv.FormatFloat(i.FromMinorUnits(i.ToMinorUnits(amount)), 'f', i.Precision, 64)
+}
+
+// Parse parses a decimal amount such as "12.50" without going through binary floating point,
+// rejecting amounts with more decimal places than the currency's precision
+func (i Info) Parse(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	sign := int64(1)
+	if strings.HasPrefix(value, "-") {
+		sign = -1
+		value = value[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" || len(fraction) > i.Precision || !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("%w %q for %s", ErrInvalidAmount, value, i.Code)
+	}
+	fraction += strings.Repeat("0", i.Precision-len(fraction))
+
+	units, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q for %s", ErrInvalidAmount, value, i.Code)
+	}
+	return i.FromMinorUnits(sign * units), nil
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Registry holds the supported currencies
+type Registry struct {
+	currencies map[models.Currency]Info
+}
+
+// NewRegistry creates a registry of the given currencies
+func NewRegistry(currencies ...Info) *Registry {
+	registry := &Registry{currencies: make(map[models.Currency]Info, len(currencies))}
+	for _, info := range currencies {
+		registry.currencies[info.Code] = info
+	}
+	return registry
+}
+
+// Default is the registry of currencies payments, wallets and withdrawals can use
+var Default = NewRegistry(
+	Info{Code: models.CurrencyUSD, Precision: 2},
+	Info{Code: models.CurrencyEUR, Precision: 2},
+	Info{Code: models.CurrencyGBP, Precision: 2},
+	Info{Code: models.CurrencyNGN, Precision: 2},
+	Info{Code: models.CurrencyGHS, Precision: 2},
+	Info{Code: models.CurrencyKES, Precision: 2},
+	Info{Code: models.CurrencyZAR, Precision: 2},
+)
+
+// Lookup returns a supported currency, or ErrUnsupportedCurrency naming the supported ones
+func (r *Registry) Lookup(code models.Currency) (Info, error) {
+	info, ok := r.currencies[code]
+	if !ok {
+		codes := make([]string, 0, len(r.currencies))
+		for _, c := range r.Codes() {
+			codes = append(codes, string(c))
+		}
+		return Info{}, fmt.Errorf("%w %q, use one of %s", ErrUnsupportedCurrency, code, strings.Join(codes, ", "))
+	}
+	return info, nil
+}
+
+// Validate returns ErrUnsupportedCurrency if the currency isn't supported
+func (r *Registry) Validate(code models.Currency) error {
+	_, err := r.Lookup(code)
+	return err
+}
+
+// Round rounds an amount to the currency's precision. Amounts in unsupported currencies are
+// returned unchanged, as they should have been rejected by Validate.
+func (r *Registry) Round(code models.Currency, amount float64) float64 {
+	info, ok := r.currencies[code]
+	if !ok {
+		return amount
+	}
+	return info.FromMinorUnits(info.ToMinorUnits(amount))
+}
+
+// Codes returns the supported currency codes in alphabetical order
+func (r *Registry) Codes() []models.Currency {
+	codes := make([]models.Currency, 0, len(r.currencies))
+	for code := range r.currencies {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// Validate checks a currency against the default registry
+func Validate(code models.Currency) error {
+	return Default.Validate(code)
+}
+
+// Round rounds an amount to its currency's precision in the default registry
+func Round(code models.Currency, amount float64) float64 {
+	return Default.Round(code, amount)
+}
@@ -0,0 +1,117 @@
+package currency
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(models.CurrencyUSD))
+	assert.NoError(t, Validate(models.CurrencyGHS))
+
+	err := Validate(models.Currency("XYZ"))
+	assert.True(t, errors.Is(err, ErrUnsupportedCurrency))
+	assert.Contains(t, err.Error(), `"XYZ"`)
+	assert.Contains(t, err.Error(), "USD")
+
+	// Codes are matched exactly, so lowercase input is rejected
+	assert.Error(t, Validate(models.Currency("usd")))
+}
+
+func TestRound(t *testing.T) {
+	registry := NewRegistry(
+		Info{Code: models.CurrencyUSD, Precision: 2},
+		Info{Code: models.Currency("JPY"), Precision: 0},
+	)
+
+	tests := []struct {
+		name     string
+		code     models.Currency
+		amount   float64
+		expected float64
+	}{
+		{"float error is removed", models.CurrencyUSD, 0.1 + 0.2, 0.3},
+		{"fee arithmetic", models.CurrencyUSD, 100 - 0.015 - 0.00000001, 99.98},
+		{"half rounds away from zero", models.CurrencyUSD, 10.125, 10.13},
+		{"zero precision", models.Currency("JPY"), 1500.6, 1501},
+		{"unsupported currency is unchanged", models.Currency("XYZ"), 1.23456, 1.23456},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, registry.Round(tt.code, tt.amount))
+		})
+	}
+}
+
+func TestCodesAreSorted(t *testing.T) {
+	codes := Default.Codes()
+	assert.Len(t, codes, 7)
+	for i := 1; i < len(codes); i++ {
+		assert.Less(t, string(codes[i-1]), string(codes[i]))
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	usd := Info{Code: models.CurrencyUSD, Precision: 2}
+	jpy := Info{Code: models.Currency("JPY"), Precision: 0}
+
+	assert.Equal(t, int64(1050), usd.ToMinorUnits(10.5))
+	assert.Equal(t, int64(30), usd.ToMinorUnits(0.1+0.2))
+	assert.Equal(t, int64(-999), usd.ToMinorUnits(-9.99))
+	assert.Equal(t, 10.5, usd.FromMinorUnits(1050))
+	assert.Equal(t, int64(1501), jpy.ToMinorUnits(1500.6))
+
+	// Summing in minor units avoids float drift
+	var total int64
+	for i := 0; i < 10; i++ {
+		total += usd.ToMinorUnits(0.1)
+	}
+	assert.Equal(t, 1.0, usd.FromMinorUnits(total))
+}
+
+func TestFormat(t *testing.T) {
+	usd := Info{Code: models.CurrencyUSD, Precision: 2}
+	jpy := Info{Code: models.Currency("JPY"), Precision: 0}
+
+	assert.Equal(t, "12.50", usd.Format(12.5))
+	assert.Equal(t, "99.99", usd.Format(100-0.01-0.00000001))
+	assert.Equal(t, "1501", jpy.Format(1500.6))
+}
+
+func TestParse(t *testing.T) {
+	usd := Info{Code: models.CurrencyUSD, Precision: 2}
+
+	tests := []struct {
+		value    string
+		expected float64
+		wantErr  bool
+	}{
+		{"12.50", 12.5, false},
+		{"12.5", 12.5, false},
+		{"12", 12, false},
+		{" 0.07 ", 0.07, false},
+		{"-3.10", -3.1, false},
+		{"12.345", 0, true},
+		{"1e3", 0, true},
+		{".50", 0, true},
+		{"12.", 12, false},
+		{"abc", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			amount, err := usd.Parse(tt.value)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrInvalidAmount))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, amount)
+		})
+	}
+}
@@ -7,16 +7,22 @@ import (
 	"gorm.io/gorm"
 )
 
+// AttemptTypePasswordReset marks requests for a password reset email
+const AttemptTypePasswordReset = "password_reset"
+
+// AttemptTypeLoginNotification marks new login emails sent to a user
+const AttemptTypeLoginNotification = "login_notification"
+
 // AuthAttempt tracks authentication attempts for security purposes
 type AuthAttempt struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	UserID      *uuid.UUID `gorm:"type:uuid" json:"user_id"` // Can be null for attempts with non-existent users
-	Email       string    `json:"email"`
-	IPAddress   string    `json:"ip_address"`
+	Email       string    `gorm:"index" json:"email"`
+	IPAddress   string    `gorm:"index" json:"ip_address"`
 	UserAgent   string    `json:"user_agent"`
 	Success     bool      `json:"success"`
 	AttemptType string    `json:"attempt_type"` // login, password_reset, etc.
-	CreatedAt   time.Time `json:"created_at"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
 }
 
 // AccountLockout tracks account lockouts due to failed authentication attempts
@@ -92,6 +98,39 @@ func CountFailedAttempts(db *gorm.DB, userID *uuid.UUID, email, ipAddress string
 	return count, nil
 }
 
+// AuthAttemptRetryAfter returns how long until another attempt of the given type may be made
+// when at most limit attempts are allowed per window, or zero if one may be made now. Attempts
+// are matched on the email or IP address column; pass the other empty.
+func AuthAttemptRetryAfter(db *gorm.DB, attemptType, email, ipAddress string, limit int, window time.Duration) (time.Duration, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	query := db.Where("attempt_type = ? AND created_at > ?", attemptType, time.Now().Add(-window))
+	if email != "" {
+		query = query.Where("email = ?", email)
+	}
+	if ipAddress != "" {
+		query = query.Where("ip_address = ?", ipAddress)
+	}
+
+	// Once the limit is reached, another attempt is allowed when the oldest of the last
+	// limit attempts leaves the window
+	var attempts []AuthAttempt
+	if err := query.Order("created_at DESC").Limit(limit).Find(&attempts).Error; err != nil {
+		return 0, err
+	}
+	if len(attempts) < limit {
+		return 0, nil
+	}
+
+	retryAfter := time.Until(attempts[len(attempts)-1].CreatedAt.Add(window))
+	if retryAfter < 0 {
+		return 0, nil
+	}
+	return retryAfter, nil
+}
+
 // LockAccount locks a user account for a specified duration
 func LockAccount(db *gorm.DB, userID uuid.UUID, reason string, durationMinutes int) (*AccountLockout, error) {
 	// Check if account is already locked
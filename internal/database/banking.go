@@ -13,6 +13,7 @@ type BankAccount struct {
 	UserID        uuid.UUID      `gorm:"type:uuid" json:"user_id"`
 	AccountNumber string         `json:"account_number"`
 	AccountName   string         `json:"account_name"`
+	Label         string         `json:"label"` // User-defined nickname for the account
 	BankName      string         `json:"bank_name"`
 	BankCode      string         `json:"bank_code"`
 	BranchCode    string         `json:"branch_code"`
@@ -68,11 +69,15 @@ type InternationalPayment struct {
 	CryptoTxID        uuid.UUID      `gorm:"type:uuid" json:"crypto_tx_id"`
 	VendorName        string         `json:"vendor_name"`
 	VendorAddress     string         `json:"vendor_address"` // Blockchain address
+	Network           string         `json:"network"`        // base, ethereum, etc.
+	ScreeningResult   string         `json:"screening_result"` // clear, flagged
 	AmountCedis       float64        `json:"amount_cedis"`
 	AmountCrypto      string         `json:"amount_crypto"` // String to preserve precision
 	ExchangeRate      float64        `json:"exchange_rate"`
-	Status            string         `json:"status"` // initiated, processing, completed, failed
+	Reference         string         `gorm:"index" json:"reference"`
+	Status            string         `json:"status"` // initiated, queued, processing, completed, failed, blocked
 	Description       string         `json:"description"`
+	Error             string         `json:"error,omitempty"`
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 	CompletedAt       *time.Time     `json:"completed_at"`
@@ -1,29 +1,52 @@
 package database
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-// ComplianceReport represents a compliance report for international payments
+// ErrComplianceReportImmutable is returned when code tries to change a stored compliance report
+var ErrComplianceReportImmutable = errors.New("compliance reports cannot be modified once generated")
+
+// ComplianceReport represents a compliance report for international payments.
+// Reports are immutable once generated; ContentHash is a SHA-256 of ReportData.
 type ComplianceReport struct {
 	ID                    uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	UserID                uuid.UUID      `gorm:"type:uuid" json:"user_id"`
-	InternationalPaymentID uuid.UUID     `gorm:"type:uuid" json:"international_payment_id"`
-	ReportType            string         `json:"report_type"` // kyc, aml, transaction
+	InternationalPaymentID uuid.UUID     `gorm:"type:uuid;index" json:"international_payment_id"`
+	ReportType            string         `json:"report_type"` // kyc, aml, transaction, international_payment
 	ReportData            string         `json:"report_data"` // JSON string with report data
-	Status                string         `json:"status"`      // generated, submitted, approved, rejected
+	ContentHash           string         `json:"content_hash"`
+	Status                string         `gorm:"index" json:"status"` // generated, flagged, submitted, approved, rejected
+	SenderKYCStatus       string         `json:"sender_kyc_status"`
+	AmountCedis           float64        `json:"amount_cedis"`
+	AmountCrypto          string         `json:"amount_crypto"`
+	ExchangeRate          float64        `json:"exchange_rate"`
+	Network               string         `json:"network"`
+	CounterpartyAddress   string         `json:"counterparty_address"`
+	ScreeningResult       string         `json:"screening_result"` // clear, flagged, not_screened
 	SubmittedToAuthority  bool           `gorm:"default:false" json:"submitted_to_authority"`
 	AuthorityReference    string         `json:"authority_reference"`
 	Notes                 string         `json:"notes"`
-	CreatedAt             time.Time      `json:"created_at"`
+	CreatedAt             time.Time      `gorm:"index" json:"created_at"`
 	UpdatedAt             time.Time      `json:"updated_at"`
 	SubmittedAt           *time.Time     `json:"submitted_at"`
 	DeletedAt             gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// BeforeUpdate prevents a generated report from being changed
+func (r *ComplianceReport) BeforeUpdate(tx *gorm.DB) error {
+	return ErrComplianceReportImmutable
+}
+
+// BeforeDelete prevents a generated report from being removed
+func (r *ComplianceReport) BeforeDelete(tx *gorm.DB) error {
+	return ErrComplianceReportImmutable
+}
+
 // ComplianceCheck represents a compliance check result
 type ComplianceCheck struct {
 	CheckName string `json:"check_name"`
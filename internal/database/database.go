@@ -6,6 +6,8 @@ import (
 
 	"github.com/revaspay/backend/internal/config"
 	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/security/audit"
+	"github.com/revaspay/backend/internal/utils"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -44,29 +46,50 @@ func InitDB(dbConfig config.DatabaseConfig) (*gorm.DB, error) {
 // Migrate runs database migrations
 func Migrate(db *gorm.DB) error {
 	// Auto migrate all models
-	return db.AutoMigrate(
+	if err := db.AutoMigrate(
 		// User and authentication
 		&models.User{},
 		&models.Session{},
-		&models.PasswordResetToken{},
+		&PasswordResetToken{},
+		&PasswordHistory{},
 		&models.EmailVerificationToken{},
 		&models.TwoFactorAuth{},
+		&models.TransactionPIN{},
 		&models.LoginAttempt{},
+		&AuthAttempt{},
+		&UserLoginLocation{},
+		&models.Role{},
+		&models.RolePermission{},
+		&models.UserRole{},
+		&models.FeatureFlag{},
 
 		// KYC verification
 		&models.KYCVerification{},
 		&models.KYCVerificationHistory{},
 		&models.KYCDocument{},
+		&models.KYCWebhookEvent{},
 
 		// Financial
 		&models.Wallet{},
 		&models.Transaction{},
+		&models.WalletLedgerEntry{},
+		&models.PendingWalletCredit{},
+		&models.WalletBalanceChange{},
+		&models.WalletHold{},
+		&models.Transfer{},
 		&models.Payment{},
 		&models.PaymentLink{},
+		&models.PaymentSplit{},
 		&models.PaymentWebhook{},
+		&models.PaymentAmountLimit{},
+		&models.ProviderCredential{},
+		&models.PaymentMetadataSchema{},
 		&models.Withdrawal{},
 		&models.VirtualAccount{},
+		&models.VirtualAccountTransaction{},
 		&models.MoMoTransaction{},
+		&models.ReconciliationReport{},
+		&models.ReconciliationDiscrepancy{},
 
 		// Subscriptions
 		&models.Subscription{},
@@ -76,5 +99,37 @@ func Migrate(db *gorm.DB) error {
 		// Referrals
 		&models.Referral{},
 		&models.ReferralReward{},
-	)
+
+		// Merchant webhooks
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
+		&models.WebhookDeliveryAttempt{},
+
+		// Ghanaian banking
+		&BankAccount{},
+		&BankWalletLink{},
+		&GhanaBankTransaction{},
+		&CryptoWallet{},
+		&CryptoTransaction{},
+		&InternationalPayment{},
+
+		// Compliance
+		&models.BlockedAddress{},
+		&ComplianceReport{},
+
+		// Notifications
+		&models.Notification{},
+
+		// Privacy
+		&models.DataExport{},
+	); err != nil {
+		return err
+	}
+
+	// Audit logs - both loggers write to audit_logs, so each adds the columns it uses. They
+	// are migrated one at a time because AutoMigrate keeps only one model per table.
+	if err := db.AutoMigrate(&utils.AuditLog{}); err != nil {
+		return err
+	}
+	return db.AutoMigrate(&audit.AuditLog{})
 }
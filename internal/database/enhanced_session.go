@@ -35,6 +35,9 @@ type SessionMetadata struct {
 	Country        string `json:"country,omitempty"`
 	City           string `json:"city,omitempty"`
 	Region         string `json:"region,omitempty"`
+	CountryCode    string `json:"country_code,omitempty"`
+	Latitude       float64 `json:"latitude,omitempty"`
+	Longitude      float64 `json:"longitude,omitempty"`
 	
 	// Risk assessment
 	RiskScore      int    `json:"risk_score,omitempty"`
@@ -72,6 +75,11 @@ type EnhancedSession struct {
 	RiskScore     float64        `gorm:"default:0"`
 	RiskLevel     string         `gorm:"default:'low'"`
 	DeviceFingerprint string     `gorm:"index"`
+	TrustedDeviceHash string     `gorm:"index"` // SHA-256 of the trusted device token given to the client
+	TrustedUntil      *time.Time
+	RevokedAt         *time.Time
+	RevokedReason     string
+	RevokeTokenHash   string     `gorm:"index"` // SHA-256 of the token in the session's new login email
 }
 
 // GetMetadata returns the session metadata
@@ -100,9 +108,11 @@ func (s *EnhancedSession) SetMetadata(metadata *SessionMetadata) error {
 	return nil
 }
 
-// CreateEnhancedSession creates a new enhanced session
-func CreateEnhancedSession(db *gorm.DB, userID uuid.UUID, refreshToken, userAgent, ipAddress string, expiresAt time.Time, deviceInfo *SessionDevice, customMetadata *SessionMetadata) (*EnhancedSession, error) {
+// CreateEnhancedSession creates a new enhanced session. sessionID is the ID the session's
+// tokens were issued with, or uuid.Nil to have one generated.
+func CreateEnhancedSession(db *gorm.DB, sessionID, userID uuid.UUID, refreshToken, userAgent, ipAddress string, expiresAt time.Time, deviceInfo *SessionDevice, customMetadata *SessionMetadata) (*EnhancedSession, error) {
 	session := EnhancedSession{
+		ID:           sessionID,
 		UserID:       userID,
 		RefreshToken: refreshToken,
 		UserAgent:    userAgent,
@@ -251,8 +261,19 @@ func UpdateSessionRisk(db *gorm.DB, sessionID uuid.UUID, riskScore float64, risk
 	return db.Save(&session).Error
 }
 
-// RotateSession rotates the session by updating the refresh token
-func RotateSession(db *gorm.DB, sessionID uuid.UUID, newRefreshToken string) error {
+// FindActiveSessionByRefreshToken finds the active, unexpired session a refresh token was issued to.
+// Revoked, expired and suspicious sessions are not returned, so their refresh tokens can't be used.
+func FindActiveSessionByRefreshToken(db *gorm.DB, refreshToken string) (*EnhancedSession, error) {
+	var session EnhancedSession
+	if err := db.Where("refresh_token = ? AND status = ? AND expires_at > ?", refreshToken, SessionStatusActive, time.Now()).
+		First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RotateSession rotates the session by replacing its refresh token and extending its expiry
+func RotateSession(db *gorm.DB, sessionID uuid.UUID, newRefreshToken string, expiresAt time.Time) error {
 	var session EnhancedSession
 	if err := db.Where("id = ?", sessionID).First(&session).Error; err != nil {
 		return err
@@ -260,6 +281,8 @@ func RotateSession(db *gorm.DB, sessionID uuid.UUID, newRefreshToken string) err
 	
 	// Update session
 	session.RefreshToken = newRefreshToken
+	session.ExpiresAt = expiresAt
+	session.LastActiveAt = time.Now()
 	session.RotationCount++
 	
 	return db.Save(&session).Error
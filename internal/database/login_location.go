@@ -0,0 +1,45 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserLoginLocation records where and when a user last logged in successfully.
+// There is one row per user; it is used to detect impossible travel between logins.
+type UserLoginLocation struct {
+	UserID      uuid.UUID `gorm:"type:uuid;primaryKey"`
+	IPAddress   string
+	Country     string
+	CountryCode string
+	City        string
+	Latitude    float64
+	Longitude   float64
+	LoggedInAt  time.Time
+	UpdatedAt   time.Time
+}
+
+// GetLastLoginLocation gets the user's last recorded login location, or nil if there is none
+func GetLastLoginLocation(db *gorm.DB, userID uuid.UUID) (*UserLoginLocation, error) {
+	var location UserLoginLocation
+	if err := db.Where("user_id = ?", userID).First(&location).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &location, nil
+}
+
+// SaveLastLoginLocation replaces the user's last recorded login location
+func SaveLastLoginLocation(db *gorm.DB, location *UserLoginLocation) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		UpdateAll: true,
+	}).Create(location).Error
+}
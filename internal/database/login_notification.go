@@ -0,0 +1,100 @@
+package database
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrRevokeTokenInvalid is returned when a session revoke token does not match an active session
+var ErrRevokeTokenInvalid = errors.New("invalid or expired revoke link")
+
+// sameDevice reports whether two session devices are the same kind of device. Browser and OS
+// versions are ignored, so updates don't make a known device look new.
+func sameDevice(a, b *SessionDevice) bool {
+	return a.DeviceType == b.DeviceType &&
+		a.DeviceModel == b.DeviceModel &&
+		a.Browser == b.Browser &&
+		a.OS == b.OS
+}
+
+// IsNewDevice reports whether the user has never had a session on device. A user's first
+// session is not a new device, since there is nothing to compare it with.
+func IsNewDevice(db *gorm.DB, userID uuid.UUID, device *SessionDevice) (bool, error) {
+	var fingerprints []string
+	if err := db.Model(&EnhancedSession{}).
+		Where("user_id = ?", userID).
+		Distinct("device_fingerprint").
+		Pluck("device_fingerprint", &fingerprints).Error; err != nil {
+		return false, err
+	}
+	if len(fingerprints) == 0 {
+		return false, nil
+	}
+
+	for _, fingerprint := range fingerprints {
+		session := EnhancedSession{DeviceFingerprint: fingerprint}
+		known, err := session.GetDeviceInfo()
+		if err != nil {
+			continue
+		}
+		if sameDevice(known, device) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// IssueSessionRevokeToken creates the token that lets the owner of a session revoke it from a
+// new login email without signing in, replacing any token issued before. Only its hash is stored.
+func IssueSessionRevokeToken(db *gorm.DB, sessionID uuid.UUID) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	if err := db.Model(&EnhancedSession{}).
+		Where("id = ?", sessionID).
+		Update("revoke_token_hash", HashDeviceToken(token)).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeSessionWithToken revokes the active session a revoke token was issued for. The token
+// can only be used once, and not at all once the session has ended.
+func RevokeSessionWithToken(db *gorm.DB, token, reason string) (*EnhancedSession, error) {
+	if token == "" {
+		return nil, ErrRevokeTokenInvalid
+	}
+
+	var session EnhancedSession
+	if err := db.Where("revoke_token_hash = ? AND status = ? AND expires_at > ?",
+		HashDeviceToken(token), SessionStatusActive, time.Now()).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevokeTokenInvalid
+		}
+		return nil, err
+	}
+
+	result := db.Model(&EnhancedSession{}).
+		Where("id = ? AND revoke_token_hash = ?", session.ID, session.RevokeTokenHash).
+		Updates(map[string]interface{}{
+			"status":            SessionStatusRevoked,
+			"revoked_at":        time.Now(),
+			"revoked_reason":    reason,
+			"revoke_token_hash": "",
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrRevokeTokenInvalid
+	}
+	return &session, nil
+}
@@ -25,9 +25,9 @@ func RunMigrations(db *gorm.DB) {
 		&EmailVerificationToken{},
 		&MoMoTransaction{},
 		&MoMoDisbursement{},
-		&Session{},
 		&EnhancedSession{},
 		&FailedLoginAttempt{},
+		&UserLoginLocation{},
 		&SecurityQuestion{},
 		&UserSecurityQuestion{},
 		&RecoveryToken{},
@@ -41,13 +41,4 @@ func RunMigrations(db *gorm.DB) {
 	log.Println("Migrations completed successfully")
 }
 
-// PasswordResetToken represents a password reset token
-type PasswordResetToken struct {
-	ID        string `gorm:"primaryKey"`
-	UserID    string `gorm:"index"`
-	Token     string `gorm:"uniqueIndex"`
-	ExpiresAt int64  `gorm:"index"`
-	CreatedAt int64
-}
-
 // EmailVerificationToken represents an email verification token
@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// fixPasswordResetTokensMigration brings password_reset_tokens in line with
+// database.PasswordResetToken. Older deployments auto-migrated the table with text
+// IDs and unix-second timestamps, which the UUID/timestamp model cannot read or write.
+func fixPasswordResetTokensMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000004_fix_password_reset_tokens",
+		Migrate: func(tx *gorm.DB) error {
+			// Outstanding tokens were stored in plaintext and can no longer be matched
+			// against the hashed lookup, so they are dropped and users request new ones
+			if err := tx.Exec(`DELETE FROM password_reset_tokens`).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Exec(`
+				DO $$
+				BEGIN
+					IF (SELECT data_type FROM information_schema.columns
+						WHERE table_name = 'password_reset_tokens' AND column_name = 'id') <> 'uuid' THEN
+						ALTER TABLE password_reset_tokens ALTER COLUMN id TYPE UUID USING id::uuid;
+					END IF;
+
+					IF (SELECT data_type FROM information_schema.columns
+						WHERE table_name = 'password_reset_tokens' AND column_name = 'user_id') <> 'uuid' THEN
+						ALTER TABLE password_reset_tokens ALTER COLUMN user_id TYPE UUID USING user_id::uuid;
+					END IF;
+
+					IF (SELECT data_type FROM information_schema.columns
+						WHERE table_name = 'password_reset_tokens' AND column_name = 'expires_at') <> 'timestamp with time zone' THEN
+						ALTER TABLE password_reset_tokens ALTER COLUMN expires_at TYPE TIMESTAMP WITH TIME ZONE USING to_timestamp(expires_at);
+					END IF;
+
+					IF (SELECT data_type FROM information_schema.columns
+						WHERE table_name = 'password_reset_tokens' AND column_name = 'created_at') <> 'timestamp with time zone' THEN
+						ALTER TABLE password_reset_tokens ALTER COLUMN created_at TYPE TIMESTAMP WITH TIME ZONE USING to_timestamp(created_at);
+					END IF;
+				END $$;
+			`).Error; err != nil {
+				return err
+			}
+
+			// Token hashes are unique, and lookups and invalidation go by token and user
+			return tx.Exec(`
+				ALTER TABLE password_reset_tokens ALTER COLUMN id SET DEFAULT gen_random_uuid();
+				ALTER TABLE password_reset_tokens ALTER COLUMN user_id SET NOT NULL;
+				ALTER TABLE password_reset_tokens ALTER COLUMN token SET NOT NULL;
+				ALTER TABLE password_reset_tokens ALTER COLUMN expires_at SET NOT NULL;
+				ALTER TABLE password_reset_tokens ALTER COLUMN created_at SET DEFAULT NOW();
+
+				DROP INDEX IF EXISTS idx_password_reset_tokens_token;
+				CREATE UNIQUE INDEX idx_password_reset_tokens_token ON password_reset_tokens(token);
+				CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+				CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_expires_at ON password_reset_tokens(expires_at);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			// The column types are left as UUID/timestamp; only the added indexes are removed
+			return tx.Exec(`
+				DROP INDEX IF EXISTS idx_password_reset_tokens_expires_at;
+				DROP INDEX IF EXISTS idx_password_reset_tokens_user_id;
+				DROP INDEX IF EXISTS idx_password_reset_tokens_token;
+				CREATE INDEX idx_password_reset_tokens_token ON password_reset_tokens(token);
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, fixPasswordResetTokensMigration())
+}
@@ -0,0 +1,96 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// unifyKYCVerificationsMigration records which provider ran each verification and moves the
+// legacy Smile Identity submissions (kycs and kyc_histories) into kyc_verifications, so every
+// provider's records share one model. The legacy tables are left in place.
+func unifyKYCVerificationsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000005_unify_kyc_verifications",
+		Migrate: func(tx *gorm.DB) error {
+			// Existing verifications all came from Didit
+			if err := tx.Exec(`
+				ALTER TABLE kyc_verifications ADD COLUMN IF NOT EXISTS provider VARCHAR(50) NOT NULL DEFAULT 'didit';
+				ALTER TABLE kyc_verifications ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP WITH TIME ZONE;
+				CREATE INDEX IF NOT EXISTS idx_kyc_verifications_provider ON kyc_verifications(provider);
+			`).Error; err != nil {
+				return err
+			}
+
+			// Legacy records keep their IDs so existing references and history still line up.
+			// Records that were never submitted carry no verification and are skipped.
+			return tx.Exec(`
+				DO $$
+				BEGIN
+					IF to_regclass('kycs') IS NULL THEN
+						RETURN;
+					END IF;
+
+					INSERT INTO kyc_verifications (id, user_id, provider, status, id_doc_type, id_doc_number,
+						rejection_reason, verified_at, created_at, updated_at)
+					SELECT k.id, k.user_id, 'smile_identity',
+						CASE k.status WHEN 'approved' THEN 'approved' WHEN 'rejected' THEN 'rejected' ELSE 'pending' END,
+						CASE k.id_type WHEN 'passport' THEN 'passport' WHEN 'drivers_license' THEN 'license' ELSE 'id' END,
+						NULLIF(k.id_number, ''),
+						NULLIF(k.rejection_reason, ''),
+						CASE WHEN k.status = 'approved' THEN k.verified_at END,
+						k.created_at, k.updated_at
+					FROM kycs k
+					WHERE k.status IN ('pending', 'approved', 'rejected')
+						AND EXISTS (SELECT 1 FROM users u WHERE u.id = k.user_id)
+						AND NOT EXISTS (SELECT 1 FROM kyc_verifications v WHERE v.id = k.id);
+
+					INSERT INTO kyc_documents (verification_id, type, file_path, file_name, uploaded_at)
+					SELECT k.id, doc.type, doc.path, regexp_replace(doc.path, '^.*/', ''), k.created_at
+					FROM kycs k
+					JOIN kyc_verifications v ON v.id = k.id AND v.provider = 'smile_identity'
+					CROSS JOIN LATERAL (VALUES
+						(v.id_doc_type, k.id_front_url),
+						(v.id_doc_type, k.id_back_url),
+						('selfie', k.selfie_url)
+					) AS doc(type, path)
+					WHERE COALESCE(doc.path, '') <> ''
+						AND NOT EXISTS (SELECT 1 FROM kyc_documents d WHERE d.verification_id = k.id AND d.file_path = doc.path);
+
+					IF to_regclass('kyc_histories') IS NULL THEN
+						RETURN;
+					END IF;
+
+					INSERT INTO kyc_verification_histories (verification_id, previous_status, new_status, changed_by, notes, created_at)
+					SELECT h.kyc_id,
+						CASE h.previous_status WHEN 'approved' THEN 'approved' WHEN 'rejected' THEN 'rejected' ELSE 'pending' END,
+						CASE h.new_status WHEN 'approved' THEN 'approved' WHEN 'rejected' THEN 'rejected' ELSE 'pending' END,
+						(SELECT u.id FROM users u WHERE u.id = h.changed_by),
+						NULLIF(h.comment, ''),
+						h.created_at
+					FROM kyc_histories h
+					JOIN kyc_verifications v ON v.id = h.kyc_id AND v.provider = 'smile_identity'
+					WHERE NOT EXISTS (
+						SELECT 1 FROM kyc_verification_histories vh
+						WHERE vh.verification_id = h.kyc_id AND vh.created_at = h.created_at
+					);
+				END $$;
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			// Without the provider column Smile Identity verifications would pass for Didit ones,
+			// so they are removed; the migrated legacy records are still in kycs
+			return tx.Exec(`
+				DELETE FROM kyc_verification_histories WHERE verification_id IN (SELECT id FROM kyc_verifications WHERE provider = 'smile_identity');
+				DELETE FROM kyc_documents WHERE verification_id IN (SELECT id FROM kyc_verifications WHERE provider = 'smile_identity');
+				DELETE FROM kyc_verifications WHERE provider = 'smile_identity';
+				DROP INDEX IF EXISTS idx_kyc_verifications_provider;
+				ALTER TABLE kyc_verifications DROP COLUMN IF EXISTS verified_at;
+				ALTER TABLE kyc_verifications DROP COLUMN IF EXISTS provider;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, unifyKYCVerificationsMigration())
+}
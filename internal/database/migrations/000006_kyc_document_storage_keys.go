@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// kycDocumentStorageKeysMigration turns the local paths in kyc_documents.file_path into
+// storage backend keys. Files were written under the uploads directory, which is the local
+// backend's default root, so "uploads/kyc/..." becomes "kyc/...". Deployments moving to S3
+// or GCS copy the uploads directory into the bucket under the same keys.
+func kycDocumentStorageKeysMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000006_kyc_document_storage_keys",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				UPDATE kyc_documents
+				SET file_path = regexp_replace(file_path, '^(\./)?uploads/', '')
+				WHERE file_path ~ '^(\./)?uploads/';
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				UPDATE kyc_documents
+				SET file_path = 'uploads/' || file_path
+				WHERE file_path NOT LIKE 'uploads/%' AND file_path NOT LIKE '/%';
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, kycDocumentStorageKeysMigration())
+}
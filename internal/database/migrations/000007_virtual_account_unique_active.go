@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// virtualAccountUniqueActiveMigration allows a user one pending or active virtual account
+// per provider and currency. Inactive and failed accounts are kept for history.
+func virtualAccountUniqueActiveMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000007_virtual_account_unique_active",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_virtual_accounts_user_provider_currency_live
+				ON virtual_accounts (user_id, provider, currency)
+				WHERE status IN ('pending', 'active') AND deleted_at IS NULL;
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP INDEX IF EXISTS idx_virtual_accounts_user_provider_currency_live;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, virtualAccountUniqueActiveMigration())
+}
@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// createRolesMigration creates the staff role tables and the default roles. Existing admins
+// keep full access through users.is_admin.
+func createRolesMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000008_create_roles",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS roles (
+					id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					name VARCHAR(50) NOT NULL UNIQUE,
+					description TEXT,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+					updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+				);
+
+				CREATE TABLE IF NOT EXISTS role_permissions (
+					role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+					permission VARCHAR(50) NOT NULL,
+					PRIMARY KEY (role_id, permission)
+				);
+
+				CREATE TABLE IF NOT EXISTS user_roles (
+					user_id UUID NOT NULL REFERENCES users(id),
+					role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+					granted_by UUID,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+					PRIMARY KEY (user_id, role_id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles(role_id);
+			`).Error; err != nil {
+				return err
+			}
+
+			for _, role := range models.DefaultRoles {
+				if err := tx.Exec(`
+					INSERT INTO roles (name, description) VALUES (?, ?)
+					ON CONFLICT (name) DO NOTHING
+				`, role.Name, role.Description).Error; err != nil {
+					return err
+				}
+				for _, permission := range role.Permissions {
+					if err := tx.Exec(`
+						INSERT INTO role_permissions (role_id, permission)
+						SELECT id, ? FROM roles WHERE name = ?
+						ON CONFLICT DO NOTHING
+					`, permission.Permission, role.Name).Error; err != nil {
+						return err
+					}
+				}
+			}
+
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				DROP TABLE IF EXISTS user_roles;
+				DROP TABLE IF EXISTS role_permissions;
+				DROP TABLE IF EXISTS roles;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, createRolesMigration())
+}
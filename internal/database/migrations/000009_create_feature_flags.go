@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// createFeatureFlagsMigration creates the table of runtime feature flag overrides
+func createFeatureFlagsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000009_create_feature_flags",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS feature_flags (
+					key VARCHAR(100) PRIMARY KEY,
+					enabled BOOLEAN NOT NULL,
+					updated_by UUID,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+					updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+				);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE IF EXISTS feature_flags;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, createFeatureFlagsMigration())
+}
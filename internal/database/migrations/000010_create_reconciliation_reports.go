@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// createReconciliationReportsMigration creates the tables of daily provider reconciliation
+// reports and the discrepancies they found
+func createReconciliationReportsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000010_create_reconciliation_reports",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS reconciliation_reports (
+					id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					provider VARCHAR(20) NOT NULL,
+					period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+					period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+					status VARCHAR(20) NOT NULL,
+					internal_records BIGINT DEFAULT 0,
+					provider_records BIGINT DEFAULT 0,
+					discrepancy_count BIGINT DEFAULT 0,
+					totals JSONB,
+					error TEXT,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+					updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+				);
+
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_reconciliation_reports_provider_period
+					ON reconciliation_reports(provider, period_start);
+				CREATE INDEX IF NOT EXISTS idx_reconciliation_reports_status ON reconciliation_reports(status);
+
+				CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+					id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					report_id UUID NOT NULL REFERENCES reconciliation_reports(id) ON DELETE CASCADE,
+					kind VARCHAR(30) NOT NULL,
+					record_type VARCHAR(20) NOT NULL,
+					reference VARCHAR(100),
+					payment_id UUID,
+					withdrawal_id UUID,
+					currency VARCHAR(3),
+					internal_amount DECIMAL(20,8),
+					provider_amount DECIMAL(20,8),
+					internal_fee DECIMAL(20,8),
+					provider_fee DECIMAL(20,8),
+					internal_status VARCHAR(20),
+					provider_status VARCHAR(20),
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_reconciliation_discrepancies_report_id ON reconciliation_discrepancies(report_id);
+				CREATE INDEX IF NOT EXISTS idx_reconciliation_discrepancies_reference ON reconciliation_discrepancies(reference);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				DROP TABLE IF EXISTS reconciliation_discrepancies;
+				DROP TABLE IF EXISTS reconciliation_reports;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, createReconciliationReportsMigration())
+}
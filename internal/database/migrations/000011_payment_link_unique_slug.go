@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// paymentLinkUniqueSlugMigration makes payment link slugs unique, including those of deleted
+// links, so a slug never points at a different link
+func paymentLinkUniqueSlugMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000011_payment_link_unique_slug",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_links_slug ON payment_links (slug);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP INDEX IF EXISTS idx_payment_links_slug;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, paymentLinkUniqueSlugMigration())
+}
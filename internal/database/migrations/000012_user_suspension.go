@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// userSuspensionMigration records when and why an admin suspended a user's account
+func userSuspensionMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000012_user_suspension",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS is_active BOOLEAN DEFAULT TRUE;
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP WITH TIME ZONE;
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS suspension_reason TEXT;
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			// is_active predates this migration on databases set up by AutoMigrate, so it stays
+			return tx.Exec(`
+				ALTER TABLE users DROP COLUMN IF EXISTS suspension_reason;
+				ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, userSuspensionMigration())
+}
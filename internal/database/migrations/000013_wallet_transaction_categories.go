@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// walletTransactionCategoriesMigration adds bookkeeping categories to wallet transactions and
+// ledger entries, and a merchant-set tag to transactions. Existing rows are categorized from
+// their transaction type.
+func walletTransactionCategoriesMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000013_wallet_transaction_categories",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE transactions ADD COLUMN IF NOT EXISTS category VARCHAR(20);
+				ALTER TABLE transactions ADD COLUMN IF NOT EXISTS tag VARCHAR(50);
+				ALTER TABLE wallet_ledger ADD COLUMN IF NOT EXISTS category VARCHAR(20);
+
+				UPDATE transactions SET category = CASE type
+					WHEN 'payment' THEN 'sales'
+					WHEN 'momo_collection' THEN 'deposit'
+					WHEN 'deposit' THEN 'deposit'
+					WHEN 'refund' THEN 'refund'
+					WHEN 'withdrawal' THEN 'payout'
+					WHEN 'fee' THEN 'fee'
+					ELSE 'adjustment'
+				END
+				WHERE category IS NULL;
+
+				UPDATE wallet_ledger l SET category = t.category
+				FROM transactions t
+				WHERE t.id = l.transaction_id AND l.category IS NULL;
+				UPDATE wallet_ledger SET category = 'adjustment' WHERE category IS NULL;
+
+				CREATE INDEX IF NOT EXISTS idx_transactions_category ON transactions (category);
+				CREATE INDEX IF NOT EXISTS idx_wallet_ledger_category ON wallet_ledger (category);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				DROP INDEX IF EXISTS idx_wallet_ledger_category;
+				DROP INDEX IF EXISTS idx_transactions_category;
+				ALTER TABLE wallet_ledger DROP COLUMN IF EXISTS category;
+				ALTER TABLE transactions DROP COLUMN IF EXISTS tag;
+				ALTER TABLE transactions DROP COLUMN IF EXISTS category;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, walletTransactionCategoriesMigration())
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// kycWebhookEventsMigration records applied KYC provider webhooks so redeliveries are skipped
+func kycWebhookEventsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000014_kyc_webhook_events",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS kyc_webhook_events (
+					id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					provider VARCHAR(50) NOT NULL,
+					event_key VARCHAR(255) NOT NULL,
+					verification_id UUID,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_kyc_webhook_events_key ON kyc_webhook_events (provider, event_key);
+				CREATE INDEX IF NOT EXISTS idx_kyc_webhook_events_verification_id ON kyc_webhook_events (verification_id);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE IF EXISTS kyc_webhook_events`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, kycWebhookEventsMigration())
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// paymentErrorMigration stores the provider error on payments that failed or timed out
+func paymentErrorMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000015_payment_error",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`ALTER TABLE payments ADD COLUMN IF NOT EXISTS error TEXT;`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`ALTER TABLE payments DROP COLUMN IF EXISTS error;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, paymentErrorMigration())
+}
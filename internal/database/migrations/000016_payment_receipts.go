@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// paymentReceiptsMigration lets merchants turn off customer receipt emails and records when a
+// payment's receipt was sent, so it's only sent once
+func paymentReceiptsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000016_payment_receipts",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS send_payment_receipts BOOLEAN DEFAULT TRUE;
+				ALTER TABLE payments ADD COLUMN IF NOT EXISTS receipt_sent_at TIMESTAMP WITH TIME ZONE;
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE payments DROP COLUMN IF EXISTS receipt_sent_at;
+				ALTER TABLE users DROP COLUMN IF EXISTS send_payment_receipts;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, paymentReceiptsMigration())
+}
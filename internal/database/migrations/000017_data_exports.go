@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// dataExportsMigration records users' requests for a copy of their data and where the
+// exported file is stored until it expires
+func dataExportsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000017_data_exports",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS data_exports (
+					id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					user_id UUID NOT NULL,
+					status VARCHAR(20) NOT NULL DEFAULT 'pending',
+					format VARCHAR(10) NOT NULL DEFAULT 'json',
+					storage_key VARCHAR(255),
+					error TEXT,
+					completed_at TIMESTAMP WITH TIME ZONE,
+					expires_at TIMESTAMP WITH TIME ZONE,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports (user_id);
+				CREATE INDEX IF NOT EXISTS idx_data_exports_expires_at ON data_exports (expires_at);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE IF EXISTS data_exports`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, dataExportsMigration())
+}
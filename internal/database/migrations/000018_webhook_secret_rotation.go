@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// webhookSecretRotationMigration versions merchant webhook signing secrets and holds the next
+// secret while a rotation is in progress
+func webhookSecretRotationMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000018_webhook_secret_rotation",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE webhook_endpoints ADD COLUMN IF NOT EXISTS secret_version INTEGER NOT NULL DEFAULT 1;
+				ALTER TABLE webhook_endpoints ADD COLUMN IF NOT EXISTS pending_secret VARCHAR(100);
+				ALTER TABLE webhook_endpoints ADD COLUMN IF NOT EXISTS rotation_started_at TIMESTAMP WITH TIME ZONE;
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE webhook_endpoints DROP COLUMN IF EXISTS rotation_started_at;
+				ALTER TABLE webhook_endpoints DROP COLUMN IF EXISTS pending_secret;
+				ALTER TABLE webhook_endpoints DROP COLUMN IF EXISTS secret_version;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, webhookSecretRotationMigration())
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// paymentExpiryMigration records when a pending payment stops being payable, and when the
+// provider says it was paid, so payments completed after expiring can be told apart
+func paymentExpiryMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000019_payment_expiry",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE payments ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+				ALTER TABLE payments ADD COLUMN IF NOT EXISTS paid_at TIMESTAMP WITH TIME ZONE;
+				CREATE INDEX IF NOT EXISTS idx_payments_expires_at ON payments (expires_at);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				DROP INDEX IF EXISTS idx_payments_expires_at;
+				ALTER TABLE payments DROP COLUMN IF EXISTS paid_at;
+				ALTER TABLE payments DROP COLUMN IF EXISTS expires_at;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, paymentExpiryMigration())
+}
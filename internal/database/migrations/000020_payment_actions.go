@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// paymentActionsMigration records the action, such as a 3D Secure challenge, a payment is
+// waiting on the customer to complete before it can be confirmed
+func paymentActionsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000020_payment_actions",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE payments ADD COLUMN IF NOT EXISTS required_action VARCHAR(20);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE payments DROP COLUMN IF EXISTS required_action;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, paymentActionsMigration())
+}
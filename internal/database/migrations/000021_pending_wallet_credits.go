@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// pendingWalletCreditsMigration adds the credits waiting to be applied to their wallets in a
+// batch. A credit can only be pending once per wallet, type and reference.
+func pendingWalletCreditsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000021_pending_wallet_credits",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS pending_wallet_credits (
+					id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					wallet_id UUID NOT NULL REFERENCES wallets(id),
+					type VARCHAR(50) NOT NULL,
+					category VARCHAR(20),
+					amount DECIMAL(20,8) NOT NULL,
+					reference VARCHAR(100) NOT NULL,
+					description TEXT,
+					meta_data JSONB,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_pending_wallet_credits_reference ON pending_wallet_credits (wallet_id, type, reference);
+				CREATE INDEX IF NOT EXISTS idx_pending_wallet_credits_created_at ON pending_wallet_credits (created_at);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE IF EXISTS pending_wallet_credits;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, pendingWalletCreditsMigration())
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// paymentSplitsMigration adds the shares of split payments owed to other merchants and the
+// platform
+func paymentSplitsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000022_payment_splits",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS payment_splits (
+					id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					payment_id UUID NOT NULL REFERENCES payments(id),
+					type VARCHAR(20) NOT NULL,
+					wallet_id UUID REFERENCES wallets(id),
+					amount DECIMAL(20,8) DEFAULT 0,
+					percentage DECIMAL(5,2) DEFAULT 0,
+					settled_amount DECIMAL(20,8) DEFAULT 0,
+					transaction_id UUID,
+					settled_at TIMESTAMP WITH TIME ZONE,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE INDEX IF NOT EXISTS idx_payment_splits_payment_id ON payment_splits (payment_id);
+				CREATE INDEX IF NOT EXISTS idx_payment_splits_wallet_id ON payment_splits (wallet_id);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE IF EXISTS payment_splits;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, paymentSplitsMigration())
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// paymentListIndexesMigration indexes a merchant's payments for listing newest first,
+// optionally by status. Provider, currency and reference filters are applied to the
+// merchant's rows these narrow down to.
+func paymentListIndexesMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000023_payment_list_indexes",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE INDEX IF NOT EXISTS idx_payments_user_created_at ON payments (user_id, created_at DESC);
+				CREATE INDEX IF NOT EXISTS idx_payments_user_status_created_at ON payments (user_id, status, created_at DESC);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				DROP INDEX IF EXISTS idx_payments_user_created_at;
+				DROP INDEX IF EXISTS idx_payments_user_status_created_at;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, paymentListIndexesMigration())
+}
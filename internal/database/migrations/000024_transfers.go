@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// transfersMigration adds wallet-to-wallet transfers between users, unique per sender and
+// idempotency key so retried requests can't send twice
+func transfersMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000024_transfers",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS transfers (
+					id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					sender_user_id UUID NOT NULL REFERENCES users(id),
+					recipient_user_id UUID NOT NULL REFERENCES users(id),
+					sender_wallet_id UUID NOT NULL REFERENCES wallets(id),
+					recipient_wallet_id UUID NOT NULL REFERENCES wallets(id),
+					amount DECIMAL(20,8) NOT NULL,
+					currency VARCHAR(3) NOT NULL,
+					reference VARCHAR(100),
+					idempotency_key VARCHAR(100) NOT NULL,
+					debit_transaction_id UUID,
+					credit_transaction_id UUID,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_transfers_sender_idempotency_key ON transfers (sender_user_id, idempotency_key);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_transfers_reference ON transfers (reference);
+				CREATE INDEX IF NOT EXISTS idx_transfers_recipient_user_id ON transfers (recipient_user_id);
+				CREATE INDEX IF NOT EXISTS idx_transfers_created_at ON transfers (created_at);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE IF EXISTS transfers;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, transfersMigration())
+}
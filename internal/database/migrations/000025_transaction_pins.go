@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// transactionPINsMigration adds the PINs users confirm withdrawals and transfers with, kept
+// apart from their passwords
+func transactionPINsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000025_transaction_pins",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS transaction_pins (
+					id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					user_id UUID NOT NULL REFERENCES users(id),
+					pin_hash VARCHAR(255) NOT NULL,
+					failed_attempts INTEGER DEFAULT 0,
+					locked_until TIMESTAMP WITH TIME ZONE,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_transaction_pins_user_id ON transaction_pins (user_id);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE IF EXISTS transaction_pins;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, transactionPINsMigration())
+}
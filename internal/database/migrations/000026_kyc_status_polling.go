@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// kycStatusPollingMigration tracks when submitted KYC verifications were sent to their provider
+// and when to next ask it for the result. Verifications already in progress are polled from
+// now, with the time they were last updated standing in for when they were submitted.
+func kycStatusPollingMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000026_kyc_status_polling",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE kyc_verifications ADD COLUMN IF NOT EXISTS submitted_at TIMESTAMP WITH TIME ZONE;
+				ALTER TABLE kyc_verifications ADD COLUMN IF NOT EXISTS poll_attempts INTEGER DEFAULT 0;
+				ALTER TABLE kyc_verifications ADD COLUMN IF NOT EXISTS next_poll_at TIMESTAMP WITH TIME ZONE;
+
+				UPDATE kyc_verifications SET submitted_at = updated_at, next_poll_at = CURRENT_TIMESTAMP
+				WHERE status = 'in_progress' AND submitted_at IS NULL;
+
+				CREATE INDEX IF NOT EXISTS idx_kyc_verifications_next_poll_at ON kyc_verifications (next_poll_at);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				DROP INDEX IF EXISTS idx_kyc_verifications_next_poll_at;
+				ALTER TABLE kyc_verifications DROP COLUMN IF EXISTS next_poll_at;
+				ALTER TABLE kyc_verifications DROP COLUMN IF EXISTS poll_attempts;
+				ALTER TABLE kyc_verifications DROP COLUMN IF EXISTS submitted_at;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, kycStatusPollingMigration())
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// providerCredentialsMigration adds merchants' own payment provider accounts, whose secret
+// keys are stored encrypted
+func providerCredentialsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000027_provider_credentials",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS provider_credentials (
+					id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					user_id UUID NOT NULL REFERENCES users(id),
+					provider VARCHAR(20) NOT NULL,
+					account_id VARCHAR(100),
+					encrypted_secret_key TEXT NOT NULL,
+					public_key VARCHAR(255),
+					set_by UUID,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_provider_credentials_user_provider ON provider_credentials (user_id, provider);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_provider_credentials_provider_account ON provider_credentials (provider, account_id);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE IF EXISTS provider_credentials;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, providerCredentialsMigration())
+}
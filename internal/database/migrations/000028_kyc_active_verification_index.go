@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// kycActiveVerificationIndexMigration allows each user only one pending, in-progress or approved
+// KYC verification, so concurrent requests can't both start one. Users who already have more
+// than one keep their approved verification, or else their newest; the rest are expired.
+func kycActiveVerificationIndexMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000028_kyc_active_verification_index",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				UPDATE kyc_verifications SET status = 'expired', updated_at = CURRENT_TIMESTAMP
+				WHERE id IN (
+					SELECT id FROM (
+						SELECT id, ROW_NUMBER() OVER (
+							PARTITION BY user_id
+							ORDER BY status = 'approved' DESC, created_at DESC
+						) AS position
+						FROM kyc_verifications
+						WHERE status IN ('pending', 'in_progress', 'approved') AND deleted_at IS NULL
+					) ranked
+					WHERE position > 1
+				);
+
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_kyc_verifications_user_active ON kyc_verifications (user_id)
+				WHERE status IN ('pending', 'in_progress', 'approved') AND deleted_at IS NULL;
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP INDEX IF EXISTS idx_kyc_verifications_user_active;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, kycActiveVerificationIndexMigration())
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// passwordHistoryMigration keeps the hashes of users' recent passwords so they can't be reused
+func passwordHistoryMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000029_password_history",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS password_histories (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					password_hash VARCHAR(255) NOT NULL,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE INDEX IF NOT EXISTS idx_password_histories_user_id ON password_histories (user_id);
+				CREATE INDEX IF NOT EXISTS idx_password_histories_created_at ON password_histories (created_at);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE IF EXISTS password_histories;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, passwordHistoryMigration())
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// walletBalanceWebhooksMigration lets webhook endpoints opt in to wallet.balance_changed
+// events, and adds the balance changes waiting to be sent to them, one row per wallet
+func walletBalanceWebhooksMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000030_wallet_balance_webhooks",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE webhook_endpoints ADD COLUMN IF NOT EXISTS balance_events BOOLEAN NOT NULL DEFAULT FALSE;
+				CREATE TABLE IF NOT EXISTS wallet_balance_changes (
+					id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+					wallet_id UUID NOT NULL REFERENCES wallets(id),
+					delta DECIMAL(20,8) NOT NULL,
+					reference VARCHAR(100),
+					changes INTEGER NOT NULL DEFAULT 1,
+					created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_wallet_balance_changes_wallet_id ON wallet_balance_changes (wallet_id);
+				CREATE INDEX IF NOT EXISTS idx_wallet_balance_changes_created_at ON wallet_balance_changes (created_at);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				DROP TABLE IF EXISTS wallet_balance_changes;
+				ALTER TABLE webhook_endpoints DROP COLUMN IF EXISTS balance_events;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, walletBalanceWebhooksMigration())
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// paymentMerchantAccountMigration records which payments were collected into the merchant's
+// own provider account, so completing them doesn't credit the merchant's wallet
+func paymentMerchantAccountMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000031_payment_merchant_account",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE payments ADD COLUMN IF NOT EXISTS merchant_account BOOLEAN NOT NULL DEFAULT FALSE;
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE payments DROP COLUMN IF EXISTS merchant_account;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, paymentMerchantAccountMigration())
+}
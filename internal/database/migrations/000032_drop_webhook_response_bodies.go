@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// dropWebhookResponseBodiesMigration removes the merchant response bodies kept with webhook
+// delivery attempts, which could hold anything an endpoint chose to return
+func dropWebhookResponseBodiesMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000032_drop_webhook_response_bodies",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE webhook_delivery_attempts DROP COLUMN IF EXISTS response_body;
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				ALTER TABLE webhook_delivery_attempts ADD COLUMN IF NOT EXISTS response_body TEXT;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, dropWebhookResponseBodiesMigration())
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// userLoginLocationsMigration adds where each user last logged in, which login risk
+// assessment compares against to detect impossible travel
+func userLoginLocationsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000033_user_login_locations",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS user_login_locations (
+					user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+					ip_address TEXT,
+					country TEXT,
+					country_code TEXT,
+					city TEXT,
+					latitude DOUBLE PRECISION,
+					longitude DOUBLE PRECISION,
+					logged_in_at TIMESTAMP WITH TIME ZONE,
+					updated_at TIMESTAMP WITH TIME ZONE
+				);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE IF EXISTS user_login_locations;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, userLoginLocationsMigration())
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// bankAccountsMigration adds the Ghanaian bank accounts users link after Paystack verifies
+// them, with the nickname users can give each one, and the links to their crypto wallets
+func bankAccountsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000034_bank_accounts",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS bank_accounts (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID REFERENCES users(id),
+					account_number TEXT,
+					account_name TEXT,
+					label TEXT,
+					bank_name TEXT,
+					bank_code TEXT,
+					branch_code TEXT,
+					country TEXT,
+					currency TEXT,
+					is_verified BOOLEAN DEFAULT FALSE,
+					is_active BOOLEAN DEFAULT TRUE,
+					created_at TIMESTAMP WITH TIME ZONE,
+					updated_at TIMESTAMP WITH TIME ZONE,
+					deleted_at TIMESTAMP WITH TIME ZONE
+				);
+				ALTER TABLE bank_accounts ADD COLUMN IF NOT EXISTS label TEXT;
+				CREATE INDEX IF NOT EXISTS idx_bank_accounts_user_id ON bank_accounts (user_id);
+				CREATE INDEX IF NOT EXISTS idx_bank_accounts_deleted_at ON bank_accounts (deleted_at);
+
+				CREATE TABLE IF NOT EXISTS bank_wallet_links (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID REFERENCES users(id),
+					bank_account_id UUID REFERENCES bank_accounts(id),
+					wallet_id UUID,
+					status TEXT,
+					created_at TIMESTAMP WITH TIME ZONE,
+					updated_at TIMESTAMP WITH TIME ZONE,
+					deleted_at TIMESTAMP WITH TIME ZONE
+				);
+				CREATE INDEX IF NOT EXISTS idx_bank_wallet_links_bank_account_id ON bank_wallet_links (bank_account_id);
+				CREATE INDEX IF NOT EXISTS idx_bank_wallet_links_deleted_at ON bank_wallet_links (deleted_at);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				DROP TABLE IF EXISTS bank_wallet_links;
+				DROP TABLE IF EXISTS bank_accounts;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, bankAccountsMigration())
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// ghanaBankTransactionsMigration adds the deposits, withdrawals and international payments
+// made through users' linked Ghanaian bank accounts
+func ghanaBankTransactionsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000035_ghana_bank_transactions",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS ghana_bank_transactions (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID REFERENCES users(id),
+					bank_account_id UUID REFERENCES bank_accounts(id),
+					transaction_type TEXT,
+					type TEXT,
+					amount NUMERIC,
+					fee NUMERIC,
+					currency TEXT,
+					status TEXT,
+					reference TEXT,
+					bank_reference TEXT,
+					onchain_tx_hash TEXT,
+					compliance_details TEXT,
+					description TEXT,
+					error TEXT,
+					created_at TIMESTAMP WITH TIME ZONE,
+					updated_at TIMESTAMP WITH TIME ZONE,
+					completed_at TIMESTAMP WITH TIME ZONE,
+					deleted_at TIMESTAMP WITH TIME ZONE
+				);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_ghana_bank_transactions_reference ON ghana_bank_transactions (reference);
+				CREATE INDEX IF NOT EXISTS idx_ghana_bank_transactions_bank_account_status ON ghana_bank_transactions (bank_account_id, status);
+				CREATE INDEX IF NOT EXISTS idx_ghana_bank_transactions_deleted_at ON ghana_bank_transactions (deleted_at);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE IF EXISTS ghana_bank_transactions;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, ghanaBankTransactionsMigration())
+}
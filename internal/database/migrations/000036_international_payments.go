@@ -0,0 +1,99 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// internationalPaymentsMigration adds payments to international vendors and the crypto
+// wallets and on-chain transfers they're paid out through
+func internationalPaymentsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000036_international_payments",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS crypto_wallets (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID REFERENCES users(id),
+					address TEXT,
+					wallet_type TEXT,
+					network TEXT,
+					encrypted_key TEXT,
+					is_active BOOLEAN DEFAULT TRUE,
+					created_at TIMESTAMP WITH TIME ZONE,
+					updated_at TIMESTAMP WITH TIME ZONE,
+					deleted_at TIMESTAMP WITH TIME ZONE
+				);
+				CREATE INDEX IF NOT EXISTS idx_crypto_wallets_user_id ON crypto_wallets (user_id);
+				CREATE INDEX IF NOT EXISTS idx_crypto_wallets_deleted_at ON crypto_wallets (deleted_at);
+
+				CREATE TABLE IF NOT EXISTS international_payments (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID REFERENCES users(id),
+					bank_transaction_id UUID,
+					crypto_tx_id UUID,
+					vendor_name TEXT,
+					vendor_address TEXT,
+					network TEXT,
+					screening_result TEXT,
+					amount_cedis NUMERIC,
+					amount_crypto TEXT,
+					exchange_rate NUMERIC,
+					reference TEXT,
+					status TEXT,
+					description TEXT,
+					error TEXT,
+					created_at TIMESTAMP WITH TIME ZONE,
+					updated_at TIMESTAMP WITH TIME ZONE,
+					completed_at TIMESTAMP WITH TIME ZONE,
+					deleted_at TIMESTAMP WITH TIME ZONE
+				);
+				ALTER TABLE international_payments ADD COLUMN IF NOT EXISTS network TEXT;
+				ALTER TABLE international_payments ADD COLUMN IF NOT EXISTS screening_result TEXT;
+				ALTER TABLE international_payments ADD COLUMN IF NOT EXISTS reference TEXT;
+				ALTER TABLE international_payments ADD COLUMN IF NOT EXISTS error TEXT;
+				CREATE INDEX IF NOT EXISTS idx_international_payments_user_id ON international_payments (user_id);
+				CREATE INDEX IF NOT EXISTS idx_international_payments_reference ON international_payments (reference);
+				CREATE INDEX IF NOT EXISTS idx_international_payments_deleted_at ON international_payments (deleted_at);
+
+				CREATE TABLE IF NOT EXISTS crypto_transactions (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID REFERENCES users(id),
+					wallet_id UUID REFERENCES crypto_wallets(id),
+					transaction_hash TEXT,
+					from_address TEXT,
+					to_address TEXT,
+					amount TEXT,
+					currency TEXT,
+					token_symbol TEXT,
+					type TEXT,
+					status TEXT,
+					block_number BIGINT,
+					block_hash TEXT,
+					gas_used BIGINT,
+					network_fee TEXT,
+					recipient_address TEXT,
+					international_payment_id UUID,
+					error TEXT,
+					created_at TIMESTAMP WITH TIME ZONE,
+					updated_at TIMESTAMP WITH TIME ZONE,
+					confirmed_at TIMESTAMP WITH TIME ZONE,
+					deleted_at TIMESTAMP WITH TIME ZONE
+				);
+				CREATE INDEX IF NOT EXISTS idx_crypto_transactions_international_payment_id ON crypto_transactions (international_payment_id);
+				CREATE INDEX IF NOT EXISTS idx_crypto_transactions_deleted_at ON crypto_transactions (deleted_at);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				DROP TABLE IF EXISTS crypto_transactions;
+				DROP TABLE IF EXISTS international_payments;
+				DROP TABLE IF EXISTS crypto_wallets;
+			`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, internationalPaymentsMigration())
+}
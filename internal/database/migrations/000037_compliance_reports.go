@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// complianceReportsMigration adds the compliance reports generated for international
+// payments, including the snapshot of the payment each report was generated from
+func complianceReportsMigration() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "000037_compliance_reports",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Exec(`
+				CREATE TABLE IF NOT EXISTS compliance_reports (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID REFERENCES users(id),
+					international_payment_id UUID,
+					report_type TEXT,
+					report_data TEXT,
+					status TEXT,
+					submitted_to_authority BOOLEAN DEFAULT FALSE,
+					authority_reference TEXT,
+					notes TEXT,
+					created_at TIMESTAMP WITH TIME ZONE,
+					updated_at TIMESTAMP WITH TIME ZONE,
+					submitted_at TIMESTAMP WITH TIME ZONE,
+					deleted_at TIMESTAMP WITH TIME ZONE
+				);
+				ALTER TABLE compliance_reports ADD COLUMN IF NOT EXISTS content_hash TEXT;
+				ALTER TABLE compliance_reports ADD COLUMN IF NOT EXISTS sender_kyc_status TEXT;
+				ALTER TABLE compliance_reports ADD COLUMN IF NOT EXISTS amount_cedis NUMERIC;
+				ALTER TABLE compliance_reports ADD COLUMN IF NOT EXISTS amount_crypto TEXT;
+				ALTER TABLE compliance_reports ADD COLUMN IF NOT EXISTS exchange_rate NUMERIC;
+				ALTER TABLE compliance_reports ADD COLUMN IF NOT EXISTS network TEXT;
+				ALTER TABLE compliance_reports ADD COLUMN IF NOT EXISTS counterparty_address TEXT;
+				ALTER TABLE compliance_reports ADD COLUMN IF NOT EXISTS screening_result TEXT;
+				CREATE INDEX IF NOT EXISTS idx_compliance_reports_international_payment_id ON compliance_reports (international_payment_id);
+				CREATE INDEX IF NOT EXISTS idx_compliance_reports_status ON compliance_reports (status);
+				CREATE INDEX IF NOT EXISTS idx_compliance_reports_created_at ON compliance_reports (created_at);
+				CREATE INDEX IF NOT EXISTS idx_compliance_reports_deleted_at ON compliance_reports (deleted_at);
+			`).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE IF EXISTS compliance_reports;`).Error
+		},
+	}
+}
+
+func init() {
+	migrationsList = append(migrationsList, complianceReportsMigration())
+}
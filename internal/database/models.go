@@ -22,12 +22,16 @@ type User struct {
 	PhoneNumber      string            `json:"phone_number"`
 	CountryCode      string            `json:"country_code"`
 	BusinessName     string            `json:"business_name"`
+	SendPaymentReceipts bool           `gorm:"default:true" json:"send_payment_receipts"` // Email customers a receipt when their payment completes
 	Website          string            `json:"website"`
 	SocialLinks      map[string]string `gorm:"type:jsonb" json:"social_links"`
 	IsVerified       bool              `gorm:"default:false" json:"is_verified"`
 	Verified         bool              `gorm:"default:false" json:"verified"`
 	EmailVerifiedAt  *time.Time        `json:"email_verified_at"`
 	IsAdmin          bool              `gorm:"default:false" json:"is_admin"`
+	IsActive         bool              `gorm:"default:true" json:"is_active"` // False while an admin has suspended the account
+	SuspendedAt      *time.Time        `json:"suspended_at,omitempty"`
+	SuspensionReason string            `gorm:"type:text" json:"suspension_reason,omitempty"`
 	TwoFactorEnabled bool              `gorm:"default:false" json:"two_factor_enabled"`
 	TwoFactorSecret  string            `json:"-"`
 	LastLoginAt      *time.Time        `json:"last_login_at"`
@@ -45,7 +49,7 @@ type User struct {
 	Withdrawals     []Withdrawal     `json:"withdrawals,omitempty"`
 	Subscriptions   []Subscription   `json:"subscriptions,omitempty"`
 	VirtualAccounts []VirtualAccount `json:"virtual_accounts,omitempty"`
-	Referrals       []Referral       `json:"referrals,omitempty"`
+	Referrals       []Referral       `gorm:"foreignKey:ReferrerID" json:"referrals,omitempty"`
 }
 
 // KYC represents the Know Your Customer verification for a user
@@ -54,9 +58,9 @@ type KYC struct {
 	UserID          uuid.UUID  `gorm:"type:uuid" json:"user_id"`
 	IDType          string     `json:"id_type"` // passport, national_id, drivers_license
 	IDNumber        string     `json:"id_number"`
-	IDFrontURL      string     `json:"id_front_url"`
-	IDBackURL       string     `json:"id_back_url"`
-	SelfieURL       string     `json:"selfie_url"`
+	IDFrontURL      string     `json:"-"` // storage paths are served through the admin document endpoint
+	IDBackURL       string     `json:"-"`
+	SelfieURL       string     `json:"-"`
 	Status          string     `json:"status"` // pending, approved, rejected
 	RejectionReason string     `json:"rejection_reason"`
 	VerifiedAt      *time.Time `json:"verified_at"`
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ErrPasswordReused is returned when a new password matches one of the user's recent passwords
+var ErrPasswordReused = errors.New("password has been used recently, please choose a different one")
+
+// PasswordHistory is a bcrypt hash of a password a user has set. Only the most recent
+// hashes are kept, so reused passwords can be rejected.
+type PasswordHistory struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	PasswordHash string    `gorm:"type:varchar(255);not null" json:"-"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
+
+// CheckPasswordReuse returns ErrPasswordReused when password is the user's current password or
+// one of the last keep passwords they set. A keep of zero or less turns the check off.
+func CheckPasswordReuse(db *gorm.DB, user *User, password string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	var hashes []string
+	if err := db.Model(&PasswordHistory{}).
+		Where("user_id = ?", user.ID).
+		Order("created_at DESC").
+		Limit(keep).
+		Pluck("password_hash", &hashes).Error; err != nil {
+		return err
+	}
+	if user.Password != "" {
+		hashes = append(hashes, user.Password)
+	}
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// RecordPasswordHistory stores the hash of a password the user has just set and deletes all
+// but their last keep hashes. It should run in the transaction that saves the password.
+func RecordPasswordHistory(tx *gorm.DB, userID uuid.UUID, passwordHash string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	entry := PasswordHistory{
+		ID:           uuid.New(),
+		UserID:       userID,
+		PasswordHash: passwordHash,
+		CreatedAt:    time.Now(),
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return err
+	}
+
+	var kept []uuid.UUID
+	if err := tx.Model(&PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(keep).
+		Pluck("id", &kept).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ? AND id NOT IN ?", userID, kept).Delete(&PasswordHistory{}).Error
+}
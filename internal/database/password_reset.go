@@ -0,0 +1,100 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrResetTokenInvalid is returned when a reset token does not exist or was already used
+	ErrResetTokenInvalid = errors.New("invalid or expired reset token")
+
+	// ErrResetTokenExpired is returned when a reset token exists but has expired
+	ErrResetTokenExpired = errors.New("reset token has expired")
+)
+
+// PasswordResetToken represents a password reset token. Token holds the SHA-256
+// hash of the token emailed to the user; the raw token is never stored.
+type PasswordResetToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Token     string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HashResetToken hashes a password reset token for storage and lookup
+func HashResetToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// IssuePasswordResetToken creates a password reset token for a user and invalidates any
+// tokens issued before it. It returns the raw token to send to the user; only its hash is stored.
+func IssuePasswordResetToken(db *gorm.DB, userID uuid.UUID, ttl time.Duration) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	resetToken := PasswordResetToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Token:     HashResetToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&resetToken).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// GetPasswordResetToken finds the reset token matching the raw token a user presented.
+// An expired token is deleted as soon as it is presented and ErrResetTokenExpired is returned.
+func GetPasswordResetToken(db *gorm.DB, token string) (*PasswordResetToken, error) {
+	var resetToken PasswordResetToken
+	if err := db.Where("token = ?", HashResetToken(token)).First(&resetToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrResetTokenInvalid
+		}
+		return nil, err
+	}
+
+	if time.Now().After(resetToken.ExpiresAt) {
+		if err := db.Delete(&resetToken).Error; err != nil {
+			return nil, err
+		}
+		return nil, ErrResetTokenExpired
+	}
+
+	return &resetToken, nil
+}
+
+// ConsumePasswordResetToken deletes a reset token so it cannot be used again. If a
+// concurrent request already consumed it, ErrResetTokenInvalid is returned.
+func ConsumePasswordResetToken(tx *gorm.DB, resetToken *PasswordResetToken) error {
+	result := tx.Where("id = ?", resetToken.ID).Delete(&PasswordResetToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrResetTokenInvalid
+	}
+	return nil
+}
@@ -1,7 +1,11 @@
 package database
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"net"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -11,8 +15,11 @@ import (
 type SessionDevice struct {
 	DeviceType    string `json:"device_type,omitempty"`
 	DeviceID      string `json:"device_id,omitempty"`
+	DeviceModel   string `json:"device_model,omitempty"`
 	Browser       string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
 	OS            string `json:"os,omitempty"`
+	OSVersion     string `json:"os_version,omitempty"`
 	TrustedDevice bool   `json:"trusted_device"`
 	LastVerifiedAt string `json:"last_verified_at,omitempty"`
 }
@@ -43,6 +50,42 @@ func (s *EnhancedSession) SetDeviceInfo(deviceInfo *SessionDevice) error {
 	return nil
 }
 
+// HashDeviceToken hashes a trusted device token so only the hash is stored
+func HashDeviceToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// TrustSessionDevice marks the session's device as trusted until the given time.
+// The client proves it is the same device later by presenting the token whose hash is stored.
+func TrustSessionDevice(db *gorm.DB, sessionID uuid.UUID, tokenHash string, until time.Time) error {
+	return db.Model(&EnhancedSession{}).
+		Where("id = ?", sessionID).
+		Updates(map[string]interface{}{
+			"trusted_device_hash": tokenHash,
+			"trusted_until":       until,
+		}).Error
+}
+
+// IsTrustedDevice checks whether a device token belongs to one of the user's trusted devices.
+// Trust ends when it expires or when the session it was granted on is revoked or suspended.
+func IsTrustedDevice(db *gorm.DB, userID uuid.UUID, token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+
+	var count int64
+	err := db.Model(&EnhancedSession{}).
+		Where("user_id = ? AND trusted_device_hash = ? AND trusted_until > ? AND status NOT IN ?",
+			userID, HashDeviceToken(token), time.Now(), []SessionStatus{SessionStatusRevoked, SessionStatusSuspicious}).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
 // GetActiveSessions returns all active sessions for a user
 func GetActiveSessions(db *gorm.DB, userID uuid.UUID) ([]EnhancedSession, error) {
 	var sessions []EnhancedSession
@@ -50,15 +93,102 @@ func GetActiveSessions(db *gorm.DB, userID uuid.UUID) ([]EnhancedSession, error)
 	return sessions, err
 }
 
-// RevokeSession revokes a specific session
+// RevokeSession revokes a specific session. Its refresh token can no longer be used.
 func RevokeSession(db *gorm.DB, sessionID uuid.UUID, reason string) error {
 	return db.Model(&EnhancedSession{}).
 		Where("id = ?", sessionID).
 		Updates(map[string]interface{}{
-			"status": SessionStatusRevoked,
+			"status":         SessionStatusRevoked,
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
 		}).Error
 }
 
+// RevokeAllUserSessions signs a user out everywhere by revoking their active sessions, which
+// also stops their refresh tokens from minting new access tokens. If keepSessionID is set, that
+// session survives. It returns the number of sessions revoked.
+func RevokeAllUserSessions(db *gorm.DB, userID uuid.UUID, reason string, keepSessionID *uuid.UUID) (int64, error) {
+	query := db.Model(&EnhancedSession{}).Where("user_id = ? AND status = ?", userID, SessionStatusActive)
+	if keepSessionID != nil {
+		query = query.Where("id <> ?", *keepSessionID)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"status":         SessionStatusRevoked,
+		"revoked_at":     time.Now(),
+		"revoked_reason": reason,
+	})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// SessionMatch selects sessions across all users by where they were created from, so the
+// sessions from an attacker's addresses or device can be revoked during an incident. A session
+// must match every field that is set.
+type SessionMatch struct {
+	Network *net.IPNet // Sessions created from an address in this network
+	// Sessions on the trusted device with this token hash. Device fingerprints only record the
+	// device type, browser and OS, which many users share, so they aren't matched on.
+	TrustedDeviceHash string
+}
+
+// revokeBatchSize is how many sessions are checked against a network at a time
+const revokeBatchSize = 500
+
+// RevokeMatchingSessions revokes every active session that matches, whichever user it belongs
+// to, and returns the number of sessions revoked
+func RevokeMatchingSessions(db *gorm.DB, match SessionMatch, reason string) (int64, error) {
+	query := db.Model(&EnhancedSession{}).Where("status = ?", SessionStatusActive)
+	if match.TrustedDeviceHash != "" {
+		query = query.Where("trusted_device_hash = ?", match.TrustedDeviceHash)
+	}
+	updates := map[string]interface{}{
+		"status":         SessionStatusRevoked,
+		"revoked_at":     time.Now(),
+		"revoked_reason": reason,
+	}
+
+	// A single address can be matched by the database; a range is checked here, since the
+	// addresses are stored as text
+	if match.Network != nil {
+		if ones, bits := match.Network.Mask.Size(); ones == bits {
+			query = query.Where("ip_address = ?", match.Network.IP.String())
+		} else {
+			return revokeSessionsInNetwork(db, query, match.Network, updates)
+		}
+	}
+
+	result := query.Updates(updates)
+	return result.RowsAffected, result.Error
+}
+
+// revokeSessionsInNetwork applies updates to the sessions selected by query whose address is in
+// network, a batch at a time
+func revokeSessionsInNetwork(db *gorm.DB, query *gorm.DB, network *net.IPNet, updates map[string]interface{}) (int64, error) {
+	var revoked int64
+	var candidates []EnhancedSession
+	err := query.Select("id", "ip_address").FindInBatches(&candidates, revokeBatchSize, func(tx *gorm.DB, _ int) error {
+		var ids []uuid.UUID
+		for _, session := range candidates {
+			if ip := net.ParseIP(session.IPAddress); ip != nil && network.Contains(ip) {
+				ids = append(ids, session.ID)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		result := db.Model(&EnhancedSession{}).
+			Where("id IN ? AND status = ?", ids, SessionStatusActive).
+			Updates(updates)
+		revoked += result.RowsAffected
+		return result.Error
+	}).Error
+	return revoked, err
+}
+
 // RevokeAllUserSessionsExcept revokes all sessions for a user except the specified one
 func RevokeAllUserSessionsExcept(db *gorm.DB, userID uuid.UUID, exceptSessionID uuid.UUID) error {
 	return db.Model(&EnhancedSession{}).
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSessionIdle is returned for a session that went unused for longer than its idle timeout
+	ErrSessionIdle = errors.New("session expired after a period of inactivity")
+
+	// ErrSessionInactive is returned when a revoked or expired session is used
+	ErrSessionInactive = errors.New("session is no longer active")
+)
+
+// highRiskLoginScore is the login risk score from which a session is treated as high risk.
+// Logins scoring this much are challenged by the risk assessor.
+const highRiskLoginScore = 40
+
+// IdleTimeouts are how long sessions may go unused before they expire and the user has to
+// sign in again, even if the session's ExpiresAt hasn't passed. A timeout of zero turns the
+// check off for those sessions.
+type IdleTimeouts struct {
+	Default  time.Duration
+	HighRisk time.Duration // Sessions whose login was risky or that were since rated medium risk or above
+}
+
+// For returns the idle timeout of a session, by its risk
+func (t IdleTimeouts) For(session *EnhancedSession) time.Duration {
+	switch session.RiskLevel {
+	case "medium", "high", "critical":
+		return t.HighRisk
+	}
+	if metadata, err := session.GetMetadata(); err == nil && metadata.RiskScore >= highRiskLoginScore {
+		return t.HighRisk
+	}
+	return t.Default
+}
+
+// LastActivity returns when the session was last used
+func (s *EnhancedSession) LastActivity() time.Time {
+	if metadata, err := s.GetMetadata(); err == nil && !metadata.LastActiveAt.IsZero() {
+		return metadata.LastActiveAt
+	}
+	if !s.LastActiveAt.IsZero() {
+		return s.LastActiveAt
+	}
+	return s.CreatedAt
+}
+
+// ExpireIdleSession expires an active session that has been unused for longer than its idle
+// timeout as of now, returning ErrSessionIdle. Sessions still in use are left alone.
+func ExpireIdleSession(db *gorm.DB, session *EnhancedSession, timeouts IdleTimeouts, now time.Time) error {
+	if session.Status != SessionStatusActive {
+		return nil
+	}
+	timeout := timeouts.For(session)
+	if timeout <= 0 || now.Sub(session.LastActivity()) <= timeout {
+		return nil
+	}
+
+	if err := db.Model(&EnhancedSession{}).
+		Where("id = ? AND status = ?", session.ID, SessionStatusActive).
+		Updates(map[string]interface{}{
+			"status":         SessionStatusExpired,
+			"revoked_reason": "Inactive for longer than " + timeout.String(),
+		}).Error; err != nil {
+		return err
+	}
+	session.Status = SessionStatusExpired
+	return ErrSessionIdle
+}
+
+// TouchSession records a use of an active session from ipAddress, unless it has been idle for
+// too long, in which case it is expired and ErrSessionIdle is returned instead. Using a revoked
+// or expired session returns ErrSessionInactive; suspicious sessions are left to the
+// verification that flagged them.
+func TouchSession(db *gorm.DB, sessionID uuid.UUID, ipAddress string, timeouts IdleTimeouts) error {
+	var session EnhancedSession
+	if err := db.Where("id = ?", sessionID).First(&session).Error; err != nil {
+		return err
+	}
+	switch session.Status {
+	case SessionStatusRevoked, SessionStatusExpired:
+		return ErrSessionInactive
+	case SessionStatusActive:
+	default:
+		return nil
+	}
+	if !session.ExpiresAt.IsZero() && session.ExpiresAt.Before(time.Now()) {
+		return ErrSessionInactive
+	}
+	if err := ExpireIdleSession(db, &session, timeouts, time.Now()); err != nil {
+		return err
+	}
+	return UpdateSessionActivity(db, sessionID, ipAddress, "", "")
+}
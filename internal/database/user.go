@@ -1,6 +1,7 @@
 package database
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -35,6 +36,21 @@ func (u *User) CheckPassword(password string) bool {
 	return err == nil
 }
 
+// PublicNameColumns are the columns PublicName reads, for queries that only need the name
+var PublicNameColumns = []string{"id", "username", "first_name", "last_name", "display_name", "business_name"}
+
+// PublicName returns the name a merchant's customers know them by: their business name,
+// display name or full name, falling back to their username
+func (u *User) PublicName() string {
+	fullName := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	for _, name := range []string{u.BusinessName, u.DisplayName, fullName} {
+		if name = strings.TrimSpace(name); name != "" {
+			return name
+		}
+	}
+	return u.Username
+}
+
 // CreateUser creates a new user
 func CreateUser(db *gorm.DB, email, password, firstName, lastName string) (*User, error) {
 	user := &User{
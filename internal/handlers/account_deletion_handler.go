@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/services/account"
+	"gorm.io/gorm"
+)
+
+// AccountDeletionHandler handles users deleting their own accounts
+type AccountDeletionHandler struct {
+	accountService *account.Service
+}
+
+// NewAccountDeletionHandler creates a new account deletion handler
+func NewAccountDeletionHandler(db *gorm.DB) *AccountDeletionHandler {
+	return &AccountDeletionHandler{
+		accountService: account.NewService(db),
+	}
+}
+
+// DeleteAccountRequest confirms an account deletion with the user's credentials
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+	TOTPCode string `json:"totp_code"`
+}
+
+// DeleteAccount deletes the signed-in user's account once they have confirmed it with their
+// password, and their 2FA code if enabled
+func (h *AccountDeletionHandler) DeleteAccount(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "Your password is required to delete your account", nil)
+		return
+	}
+
+	err := h.accountService.Delete(c.Request.Context(), userID, account.DeletionRequest{
+		Password:  req.Password,
+		TOTPCode:  req.TOTPCode,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, account.ErrInvalidPassword):
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "Invalid password", nil)
+		return
+	case errors.Is(err, account.ErrMFACodeRequired):
+		respondError(c, http.StatusBadRequest, ErrCodeMFARequired, "2FA code required", gin.H{"require_2fa": true})
+		return
+	case errors.Is(err, account.ErrInvalidMFACode):
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "Invalid 2FA code", nil)
+		return
+	case errors.Is(err, account.ErrWalletNotEmpty), errors.Is(err, account.ErrWithdrawalsInFlight):
+		respondError(c, http.StatusConflict, ErrCodeConflict, err.Error(), nil)
+		return
+	case errors.Is(err, account.ErrUserNotFound):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "User not found", nil)
+		return
+	default:
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to delete account", nil)
+		return
+	}
+	middleware.ForgetAccountStatus(userID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Your account has been deleted",
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/account"
+	"gorm.io/gorm"
+)
+
+// AccountSuspensionHandler handles admins suspending and reactivating user accounts
+type AccountSuspensionHandler struct {
+	accountService *account.Service
+}
+
+// NewAccountSuspensionHandler creates a new account suspension handler
+func NewAccountSuspensionHandler(db *gorm.DB) *AccountSuspensionHandler {
+	return &AccountSuspensionHandler{
+		accountService: account.NewService(db),
+	}
+}
+
+// SuspendUserRequest is the body of a suspension request
+type SuspendUserRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// SuspendUser suspends a user's account and signs them out everywhere
+func (h *AccountSuspensionHandler) SuspendUser(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A suspension reason is required"})
+		return
+	}
+
+	user, err := h.accountService.Suspend(c.Request.Context(), adminID, userID, req.Reason)
+	if err != nil {
+		h.handleSuspensionError(c, err)
+		return
+	}
+	middleware.ForgetAccountStatus(userID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   accountStatusResponse(user),
+	})
+}
+
+// ReactivateUser lifts a user's suspension
+func (h *AccountSuspensionHandler) ReactivateUser(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.accountService.Reactivate(c.Request.Context(), adminID, userID)
+	if err != nil {
+		h.handleSuspensionError(c, err)
+		return
+	}
+	middleware.ForgetAccountStatus(userID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   accountStatusResponse(user),
+	})
+}
+
+// accountStatusResponse is the account status shown to admins after a change
+func accountStatusResponse(user *models.User) gin.H {
+	return gin.H{
+		"user_id":           user.ID,
+		"is_active":         user.IsActive,
+		"suspended_at":      user.SuspendedAt,
+		"suspension_reason": user.SuspensionReason,
+	}
+}
+
+// handleSuspensionError maps account errors to HTTP responses
+func (h *AccountSuspensionHandler) handleSuspensionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, account.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, account.ErrReasonRequired), errors.Is(err, account.ErrReasonTooLong), errors.Is(err, account.ErrSuspendSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, account.ErrAlreadySuspended), errors.Is(err, account.ErrNotSuspended):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account status"})
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/services/dashboard"
+)
+
+// AdminMetricsHandler serves the metrics on the admin home screen
+type AdminMetricsHandler struct {
+	dashboardService *dashboard.Service
+}
+
+// NewAdminMetricsHandler creates a new admin metrics handler
+func NewAdminMetricsHandler(dashboardService *dashboard.Service) *AdminMetricsHandler {
+	return &AdminMetricsHandler{
+		dashboardService: dashboardService,
+	}
+}
+
+// GetMetrics returns user, KYC, wallet, payment, withdrawal and job metrics in one response.
+// They're cached briefly, so may be up to 30 seconds old.
+func (h *AdminMetricsHandler) GetMetrics(c *gin.Context) {
+	metrics, err := h.dashboardService.Metrics()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get metrics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   metrics,
+	})
+}
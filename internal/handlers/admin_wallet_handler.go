@@ -1,12 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/pagination"
 	"github.com/revaspay/backend/internal/services/wallet"
 	"gorm.io/gorm"
 )
@@ -27,8 +28,7 @@ func NewAdminWalletHandler(db *gorm.DB) *AdminWalletHandler {
 
 // GetAllWallets gets all wallets in the system with pagination
 func (h *AdminWalletHandler) GetAllWallets(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	params := pagination.ParseParams(c, 20)
 	
 	var wallets []models.Wallet
 	var total int64
@@ -40,19 +40,14 @@ func (h *AdminWalletHandler) GetAllWallets(c *gin.Context) {
 	}
 	
 	// Get paginated wallets with user information
-	offset := (page - 1) * pageSize
-	if err := h.db.Preload("User").Offset(offset).Limit(pageSize).Find(&wallets).Error; err != nil {
+	if err := h.db.Preload("User").Offset(params.Offset()).Limit(params.PageSize).Find(&wallets).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get wallets"})
 		return
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
-		"wallets": wallets,
-		"pagination": gin.H{
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-		},
+		"wallets":    wallets,
+		"pagination": pagination.NewMeta(params, total),
 	})
 }
 
@@ -98,12 +93,17 @@ func (h *AdminWalletHandler) GetWalletTransactions(c *gin.Context) {
 		return
 	}
 	
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
 	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	params := pagination.ParseParams(c, 20)
 	
 	// Get transactions
-	transactions, total, err := h.walletService.GetTransactionHistory(walletID, page, pageSize)
+	transactions, total, err := h.walletService.GetTransactionHistory(walletID, filter, params.Page, params.PageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get transactions"})
 		return
@@ -112,11 +112,7 @@ func (h *AdminWalletHandler) GetWalletTransactions(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"transactions": transactions,
 		"wallet":       wallet,
-		"pagination": gin.H{
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-		},
+		"pagination":   pagination.NewMeta(params, total),
 	})
 }
 
@@ -130,8 +126,8 @@ func (h *AdminWalletHandler) AdjustWalletBalance(c *gin.Context) {
 	}
 	
 	// Check if wallet exists
-	var wallet models.Wallet
-	if err := h.db.First(&wallet, "id = ?", walletID).Error; err != nil {
+	var targetWallet models.Wallet
+	if err := h.db.First(&targetWallet, "id = ?", walletID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
 		return
 	}
@@ -169,6 +165,7 @@ func (h *AdminWalletHandler) AdjustWalletBalance(c *gin.Context) {
 			walletID,
 			input.Amount,
 			"admin_adjustment",
+			models.LedgerCategoryAdjustment,
 			input.Reference,
 			input.Description,
 			input.Metadata,
@@ -178,6 +175,7 @@ func (h *AdminWalletHandler) AdjustWalletBalance(c *gin.Context) {
 			walletID,
 			input.Amount,
 			"admin_adjustment",
+			models.LedgerCategoryAdjustment,
 			input.Reference,
 			input.Description,
 			input.Metadata,
@@ -185,18 +183,22 @@ func (h *AdminWalletHandler) AdjustWalletBalance(c *gin.Context) {
 	}
 	
 	if adjustErr != nil {
+		if errors.Is(adjustErr, wallet.ErrInsufficientFunds) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": adjustErr.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": adjustErr.Error()})
 		return
 	}
 	
 	// Get updated wallet
-	if err := h.db.First(&wallet, "id = ?", walletID).Error; err != nil {
+	if err := h.db.First(&targetWallet, "id = ?", walletID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get updated wallet"})
 		return
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
-		"wallet":      wallet,
+		"wallet":      targetWallet,
 		"transaction": transaction,
 		"message":     "Wallet balance adjusted successfully",
 	})
@@ -204,8 +206,7 @@ func (h *AdminWalletHandler) AdjustWalletBalance(c *gin.Context) {
 
 // GetAllAutoWithdrawConfigs gets all auto-withdraw configurations
 func (h *AdminWalletHandler) GetAllAutoWithdrawConfigs(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	params := pagination.ParseParams(c, 20)
 	
 	var configs []models.AutoWithdrawConfig
 	var total int64
@@ -217,18 +218,13 @@ func (h *AdminWalletHandler) GetAllAutoWithdrawConfigs(c *gin.Context) {
 	}
 	
 	// Get paginated configs with user information
-	offset := (page - 1) * pageSize
-	if err := h.db.Preload("User").Offset(offset).Limit(pageSize).Find(&configs).Error; err != nil {
+	if err := h.db.Preload("User").Offset(params.Offset()).Limit(params.PageSize).Find(&configs).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get auto-withdraw configs"})
 		return
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
-		"configs": configs,
-		"pagination": gin.H{
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-		},
+		"configs":    configs,
+		"pagination": pagination.NewMeta(params, total),
 	})
 }
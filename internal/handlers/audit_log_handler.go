@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/pagination"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// AuditLogHandler lets admins search the security audit log
+type AuditLogHandler struct {
+	auditLogger *utils.AuditLogger
+}
+
+// NewAuditLogHandler creates a new audit log handler
+func NewAuditLogHandler(db *gorm.DB) *AuditLogHandler {
+	return &AuditLogHandler{
+		auditLogger: utils.NewAuditLogger(db),
+	}
+}
+
+// ListAuditLogs returns a page of audit logs, newest first. It filters on user_id,
+// event_type (comma-separated), severity, from and to (YYYY-MM-DD or RFC3339, to
+// inclusive for dates) and success. Sensitive detail fields are redacted unless
+// include_sensitive=true, and viewing them is itself recorded in the audit log.
+func (h *AuditLogHandler) ListAuditLogs(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	params := pagination.ParseParamsWithMax(c, 50, 200)
+
+	var filter utils.AuditLogFilter
+	if value := c.Query("user_id"); value != "" {
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+			return
+		}
+		filter.UserID = &userID
+	}
+	if value := c.Query("event_type"); value != "" {
+		for _, eventType := range strings.Split(value, ",") {
+			if eventType = strings.TrimSpace(eventType); eventType != "" {
+				filter.EventTypes = append(filter.EventTypes, utils.AuditEventType(eventType))
+			}
+		}
+	}
+	filter.Severity = utils.AuditEventSeverity(c.Query("severity"))
+	if value := c.Query("from"); value != "" {
+		from, err := parseStatementDate(value, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, use YYYY-MM-DD or RFC3339"})
+			return
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := parseStatementDate(value, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, use YYYY-MM-DD or RFC3339"})
+			return
+		}
+		filter.To = &to
+	}
+	if value := c.Query("success"); value != "" {
+		success, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid success, use true or false"})
+			return
+		}
+		filter.Success = &success
+	}
+	includeSensitive := c.Query("include_sensitive") == "true"
+
+	logs, total, err := h.auditLogger.SearchAuditLogs(filter, params.PageSize, params.Offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get audit logs"})
+		return
+	}
+
+	if includeSensitive {
+		h.auditLogger.LogAdminAction(c.Request.Context(), adminID, filter.UserID, c.ClientIP(), c.Request.UserAgent(),
+			"view_unredacted_audit_logs", true, map[string]interface{}{
+				"query": c.Request.URL.RawQuery,
+			})
+	} else {
+		for i := range logs {
+			utils.RedactAuditLog(&logs[i])
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"data":       logs,
+		"pagination": pagination.NewMeta(params, total),
+	})
+}
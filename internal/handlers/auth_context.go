@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/models"
+)
+
+// getUserID returns the ID of the user authenticated by middleware.AuthMiddleware
+func getUserID(c *gin.Context) (uuid.UUID, bool) {
+	return middleware.UserID(c)
+}
+
+// getUser returns the authenticated user's record. Only routes behind middleware.LoadUser have it.
+func getUser(c *gin.Context) (*models.User, bool) {
+	return middleware.User(c)
+}
+
+// hasPermission reports whether the authenticated user's roles grant a permission. Admins hold
+// every permission.
+func hasPermission(c *gin.Context, permission models.Permission) bool {
+	return middleware.HasPermission(c, permission)
+}
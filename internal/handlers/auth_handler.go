@@ -7,14 +7,22 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/config"
 	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/security"
+	"github.com/revaspay/backend/internal/security/audit"
 	"github.com/revaspay/backend/internal/services/email"
+	"github.com/revaspay/backend/internal/services/geoip"
+	"github.com/revaspay/backend/internal/services/rbac"
 	"github.com/revaspay/backend/internal/utils"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/oauth2"
@@ -26,6 +34,9 @@ import (
 type AuthHandler struct {
 	db          *gorm.DB
 	emailService *email.EmailService
+	passwordValidator *security.PasswordValidator
+	auditLogger  *audit.Logger
+	securityConfig config.SecurityConfig
 }
 
 // NewAuthHandler creates a new auth handler
@@ -33,6 +44,9 @@ func NewAuthHandler(db *gorm.DB) *AuthHandler {
 	return &AuthHandler{
 		db:          db,
 		emailService: email.NewEmailService(),
+		passwordValidator: security.DefaultPasswordValidator(),
+		auditLogger:  audit.NewLogger(db),
+		securityConfig: config.DefaultSecurityConfig(),
 	}
 }
 
@@ -65,21 +79,27 @@ type TokenResponse struct {
 func (h *AuthHandler) Signup(c *gin.Context) {
 	var req SignupRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	}
+
+	// Check strength and known breaches
+	if err := h.passwordValidator.Validate(req.Password); err != nil {
+		respondPasswordRejected(c, err)
 		return
 	}
 
 	// Check if user already exists
 	var existingUser database.User
 	if result := h.db.Where("email = ? OR username = ?", req.Email, req.Username).First(&existingUser); result.RowsAffected > 0 {
-		c.JSON(http.StatusConflict, gin.H{"error": "Email or username already in use"})
+		respondError(c, http.StatusConflict, ErrCodeConflict, "Email or username already in use", nil)
 		return
 	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to process password", nil)
 		return
 	}
 
@@ -110,7 +130,7 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 
 	if err := tx.Create(&user).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to create user", nil)
 		return
 	}
 
@@ -123,7 +143,7 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 
 	if err := tx.Create(&wallet).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create wallet"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to create wallet", nil)
 		return
 	}
 
@@ -138,20 +158,20 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 
 		if err := tx.Create(&referral).Error; err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create referral"})
+			respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to create referral", nil)
 			return
 		}
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete registration"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to complete registration", nil)
 		return
 	}
 
 	// Generate tokens
-	tokens, err := generateTokens(user.ID, user.Email, user.IsAdmin)
+	tokens, err := generateTokens(h.db, user.ID, uuid.Nil, user.Email, user.IsAdmin)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to generate tokens", nil)
 		return
 	}
 
@@ -170,20 +190,26 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 
 	// Find user by email
 	var user database.User
 	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "Invalid credentials", nil)
 		return
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "Invalid credentials", nil)
+		return
+	}
+
+	// Suspended users can't sign in; checked after the password so it doesn't reveal the account
+	if !user.IsActive {
+		respondError(c, http.StatusForbidden, ErrCodeAccountSuspended, "Account is suspended", nil)
 		return
 	}
 
@@ -191,33 +217,29 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	if user.TwoFactorEnabled {
 		// Verify TOTP code
 		if req.TOTPCode == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "2FA code required", "require_2fa": true})
+			respondError(c, http.StatusBadRequest, ErrCodeMFARequired, "2FA code required", gin.H{"require_2fa": true})
 			return
 		}
 
 		// Verify TOTP code
 		valid := utils.ValidateTOTP(user.TwoFactorSecret, req.TOTPCode)
 		if !valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid 2FA code"})
+			respondError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "Invalid 2FA code", nil)
 			return
 		}
 	}
 
-	// Generate tokens
-	tokens, err := generateTokens(user.ID, user.Email, user.IsAdmin)
+	// Generate tokens for the session they start
+	sessionID := uuid.New()
+	tokens, err := generateTokens(h.db, user.ID, sessionID, user.Email, user.IsAdmin)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to generate tokens", nil)
 		return
 	}
 
 	// Create session
-	userAgent := c.Request.UserAgent()
-	ipAddress := c.ClientIP()
-	expiresAt := time.Now().Add(7 * 24 * time.Hour)
-	
-	_, err = database.CreateSession(h.db, user.ID, tokens.RefreshToken, userAgent, ipAddress, expiresAt)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+	if _, err := h.startSession(c, sessionID, user.ID, tokens.RefreshToken, "password", user.TwoFactorEnabled); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to create session", nil)
 		return
 	}
 
@@ -242,51 +264,69 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 
-	// Find session by refresh token
-	session, err := database.FindSessionByRefreshToken(h.db, req.RefreshToken)
+	// Find the active session the refresh token was issued to
+	session, err := database.FindActiveSessionByRefreshToken(h.db, req.RefreshToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidToken, "Invalid or expired refresh token", nil)
 		return
 	}
 
 	// Validate the refresh token
 	claims, err := utils.ValidateToken(req.RefreshToken)
 	if err != nil {
-		// Invalidate the session if token is invalid
-		_ = database.InvalidateSession(h.db, session.ID)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		// Revoke the session if token is invalid
+		_ = database.RevokeSession(h.db, session.ID, "Invalid refresh token")
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidToken, "Invalid refresh token", nil)
 		return
 	}
 
 	// Verify that the token belongs to the session's user
 	if claims.UserID != session.UserID {
-		_ = database.InvalidateSession(h.db, session.ID)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token mismatch"})
+		_ = database.RevokeSession(h.db, session.ID, "Refresh token user mismatch")
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidToken, "Token mismatch", nil)
+		return
+	}
+
+	// Refreshing counts as using the session, but one left unused for too long has expired
+	idleTimeouts := database.IdleTimeouts{
+		Default:  h.securityConfig.SessionIdleTimeout,
+		HighRisk: h.securityConfig.SessionIdleTimeoutHighRisk,
+	}
+	if err := database.TouchSession(h.db, session.ID, c.ClientIP(), idleTimeouts); err != nil {
+		if errors.Is(err, database.ErrSessionIdle) {
+			respondError(c, http.StatusUnauthorized, ErrCodeSessionExpired, "Session expired due to inactivity, please sign in again", nil)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to update session", nil)
 		return
 	}
 
 	// Get user
 	var user database.User
 	if err := h.db.First(&user, "id = ?", session.UserID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find user"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to find user", nil)
+		return
+	}
+	if !user.IsActive {
+		_ = database.RevokeSession(h.db, session.ID, "Account suspended")
+		respondError(c, http.StatusForbidden, ErrCodeAccountSuspended, "Account is suspended", nil)
 		return
 	}
 
 	// Generate tokens
-	tokens, err := generateTokens(user.ID, user.Email, user.IsAdmin)
+	tokens, err := generateTokens(h.db, user.ID, session.ID, user.Email, user.IsAdmin)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to generate tokens", nil)
 		return
 	}
 
-	// Update the session with the new refresh token
-	expiresAt := time.Now().Add(7 * 24 * time.Hour)
-	if err := database.UpdateSession(h.db, session.ID, tokens.RefreshToken, expiresAt); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session"})
+	// Rotate the session onto the new refresh token
+	if err := database.RotateSession(h.db, session.ID, tokens.RefreshToken, time.Now().Add(utils.RefreshTokenTTL())); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to update session", nil)
 		return
 	}
 
@@ -296,66 +336,102 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	})
 }
 
-// PasswordResetToken represents a password reset token in the database
-type PasswordResetToken struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID    uuid.UUID `gorm:"type:uuid" json:"user_id"`
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
-}
+// forgotPasswordMessage is the response to every password reset request, so it doesn't reveal
+// whether the email is registered or whether an email was sent
+const forgotPasswordMessage = "If your email is registered, you will receive a password reset link"
 
-// ForgotPassword initiates the password reset process
+// ForgotPassword initiates the password reset process. Reset emails are throttled per address
+// and per client IP; throttled requests get the same response, with a Retry-After header, but
+// neither create a token nor send an email.
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	var req struct {
 		Email string `json:"email" binding:"required,email"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 
+	emailAddress := strings.ToLower(strings.TrimSpace(req.Email))
+	ipAddress := c.ClientIP()
+
+	retryAfter, err := h.passwordResetRetryAfter(emailAddress, ipAddress)
+	if err != nil {
+		log.Printf("Failed to check password reset rate limit: %v", err)
+		c.JSON(http.StatusOK, gin.H{"message": forgotPasswordMessage})
+		return
+	}
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusOK, gin.H{"message": forgotPasswordMessage})
+		return
+	}
+
+	// Every request that gets this far counts towards the limits, whether or not the email
+	// is registered, so the throttling looks the same either way
+	var userID *uuid.UUID
+	sent := false
+	defer func() {
+		if _, err := database.CreateAuthAttempt(h.db, userID, emailAddress, ipAddress, c.Request.UserAgent(), database.AttemptTypePasswordReset, sent); err != nil {
+			log.Printf("Failed to record password reset request: %v", err)
+		}
+	}()
+
 	// Check if user exists
 	var user database.User
 	if result := h.db.Where("email = ?", req.Email).First(&user); result.RowsAffected == 0 {
 		// Don't reveal that the email doesn't exist for security reasons
-		c.JSON(http.StatusOK, gin.H{"message": "If your email is registered, you will receive a password reset link"})
+		c.JSON(http.StatusOK, gin.H{"message": forgotPasswordMessage})
 		return
 	}
+	userID = &user.ID
 
-	// Generate password reset token
-	token := utils.GenerateSecureToken(32)
-	// Token expires in 24 hours
-	expires := time.Now().Add(24 * time.Hour).Unix()
-
-	// Save token to database
-	resetToken := database.PasswordResetToken{
-		ID:        uuid.New().String(),
-		UserID:    user.ID.String(),
-		Token:     token,
-		ExpiresAt: expires,
-		CreatedAt: time.Now().Unix(),
-	}
-
-	if err := h.db.Create(&resetToken).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+	// Generate a password reset token that expires in 24 hours. This replaces any
+	// earlier tokens, and only its hash is stored.
+	token, err := database.IssuePasswordResetToken(h.db, user.ID, 24*time.Hour)
+	if err != nil {
+		// Respond as for an unknown email so failures don't reveal that the account exists
+		log.Printf("Failed to issue password reset token: %v", err)
+		c.JSON(http.StatusOK, gin.H{"message": forgotPasswordMessage})
 		return
 	}
 
 	// Send password reset email with token
-	err := h.emailService.SendPasswordResetEmail(user.Email, user.Username, token)
+	err = h.emailService.SendPasswordResetEmail(user.Email, user.Username, token)
 	if err != nil {
 		// Log the error but don't reveal it to the user
-		c.JSON(http.StatusOK, gin.H{"message": "If your email is registered, you will receive a password reset link"})
+		log.Printf("Failed to send password reset email: %v", err)
+		c.JSON(http.StatusOK, gin.H{"message": forgotPasswordMessage})
 		return
 	}
+	sent = true
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "If your email is registered, you will receive a password reset link",
+		"message": forgotPasswordMessage,
 	})
 }
 
+// passwordResetRetryAfter returns how long until another reset email may be sent to the address
+// or requested from the IP, or zero if one may be sent now
+func (h *AuthHandler) passwordResetRetryAfter(emailAddress, ipAddress string) (time.Duration, error) {
+	window := h.securityConfig.PasswordResetWindow
+
+	byEmail, err := database.AuthAttemptRetryAfter(h.db, database.AttemptTypePasswordReset, emailAddress, "", h.securityConfig.PasswordResetEmailLimit, window)
+	if err != nil {
+		return 0, err
+	}
+	byIP, err := database.AuthAttemptRetryAfter(h.db, database.AttemptTypePasswordReset, "", ipAddress, h.securityConfig.PasswordResetIPLimit, window)
+	if err != nil {
+		return 0, err
+	}
+
+	if byIP > byEmail {
+		return byIP, nil
+	}
+	return byEmail, nil
+}
+
 // ResetPassword handles password reset
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	var req struct {
@@ -364,50 +440,78 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 
-	// Find token in database
-	var token PasswordResetToken
-	if result := h.db.Where("token = ?", req.Token).First(&token); result.RowsAffected == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+	// Check strength and known breaches
+	if err := h.passwordValidator.Validate(req.Password); err != nil {
+		respondPasswordRejected(c, err)
 		return
 	}
-	
-	// Check if token is expired
-	if time.Now().After(token.ExpiresAt) {
-		// Delete expired token
-		h.db.Delete(&token)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Reset token has expired"})
+
+	// Find token by its hash; expired tokens are deleted when presented
+	token, err := database.GetPasswordResetToken(h.db, req.Token)
+	if err != nil {
+		if errors.Is(err, database.ErrResetTokenExpired) {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidToken, "Reset token has expired", nil)
+			return
+		}
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidToken, "Invalid or expired reset token", nil)
 		return
 	}
 	
 	// Find user
 	var user database.User
 	if err := h.db.First(&user, "id = ?", token.UserID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password reset"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to process password reset", nil)
+		return
+	}
+	
+	// Reject the user's recent passwords
+	if err := database.CheckPasswordReuse(h.db, &user, req.Password, h.securityConfig.PasswordHistoryCount); err != nil {
+		if errors.Is(err, database.ErrPasswordReused) {
+			respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to process password reset", nil)
 		return
 	}
 	
 	// Hash new password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to process password", nil)
 		return
 	}
 	
-	// Update user's password
+	// Use up the token and update the password together, so a token can only ever reset once
 	user.Password = string(hashedPassword)
-	if err := h.db.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := database.ConsumePasswordResetToken(tx, token); err != nil {
+			return err
+		}
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return database.RecordPasswordHistory(tx, user.ID, user.Password, h.securityConfig.PasswordHistoryCount)
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrResetTokenInvalid) {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidToken, "Invalid or expired reset token", nil)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to update password", nil)
 		return
 	}
 	
-	// Delete used token
-	h.db.Delete(&token)
+	// Sign out everywhere, so whoever had access before the reset loses it
+	revoked := revokeSessionsAfterPasswordChange(c, h.db, h.auditLogger, user.ID, "Password reset", nil)
 	
-	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Password has been reset successfully",
+		"sessions_revoked": revoked,
+	})
 }
 
 // ResendVerificationEmail resends a verification email with enhanced retry mechanism
@@ -439,43 +543,41 @@ func (h *AuthHandler) ResendVerificationEmail(c *gin.Context) {
 	}
 	
 	if token == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing token or email"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "Missing token or email", nil)
 		return
 	}
 
 	// Find token in database
 	verificationToken, err := database.GetEmailVerificationToken(h.db, token)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 
 	// Find user
 	var user database.User
 	if result := h.db.First(&user, verificationToken.UserID); result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "User not found", nil)
 		return
 	}
 
 	// Check if user is already verified
 	if user.IsVerified {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already verified"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "Email already verified", nil)
 		return
 	}
 
 	// Check rate limiting for verification attempts
 	exceeded, err := database.CheckVerificationRateLimit(h.db, verificationToken.UserID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check verification rate limit"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to check verification rate limit", nil)
 		return
 	}
 
 	if exceeded {
-		c.JSON(http.StatusTooManyRequests, gin.H{
-			"error": "Too many verification attempts. Please try again later.",
-			"retry_after": 3600, // 1 hour in seconds
+		respondError(c, http.StatusTooManyRequests, ErrCodeRateLimited, "Too many verification attempts. Please try again later.", gin.H{
+			"retry_after":         3600, // 1 hour in seconds
 			"retry_after_minutes": 60,
-			"status": "rate_limited",
 		})
 		return
 	}
@@ -525,45 +627,37 @@ func (h *AuthHandler) ResendVerificationEmail(c *gin.Context) {
 // SendVerificationEmail sends a verification email to the user
 func (h *AuthHandler) SendVerificationEmail(c *gin.Context) {
 	// Get user ID from JWT token
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	// Find user
 	var user database.User
-	if result := h.db.First(&user, uid); result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if result := h.db.First(&user, userID); result.RowsAffected == 0 {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "User not found", nil)
 		return
 	}
 
 	// Check if user is already verified
 	if user.IsVerified {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already verified"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "Email already verified", nil)
 		return
 	}
 
 	// Check rate limit for verification attempts
-	exceeded, err := database.CheckVerificationRateLimit(h.db, uid)
+	exceeded, err := database.CheckVerificationRateLimit(h.db, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check verification rate limit"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to check verification rate limit", nil)
 		return
 	}
 
 	if exceeded {
 		// Return detailed rate limit information
-		c.JSON(http.StatusTooManyRequests, gin.H{
-			"error": "Too many verification attempts. Please try again later.",
-			"retry_after": 3600, // 1 hour in seconds
+		respondError(c, http.StatusTooManyRequests, ErrCodeRateLimited, "Too many verification attempts. Please try again later.", gin.H{
+			"retry_after":         3600, // 1 hour in seconds
 			"retry_after_minutes": 60,
-			"status": "rate_limited",
 		})
 		return
 	}
@@ -574,9 +668,9 @@ func (h *AuthHandler) SendVerificationEmail(c *gin.Context) {
 	expiresAt := time.Now().Add(48 * time.Hour)
 
 	// Save token to database using the enhanced function
-	verificationToken, err := database.CreateEmailVerificationToken(h.db, uid, token, expiresAt)
+	verificationToken, err := database.CreateEmailVerificationToken(h.db, userID, token, expiresAt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to process request", nil)
 		return
 	}
 
@@ -618,21 +712,21 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	// Get token from query params
 	token := c.Query("token")
 	if token == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing token"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "Missing token", nil)
 		return
 	}
 
 	// Find token in database using enhanced function
 	verificationToken, err := database.GetEmailVerificationToken(h.db, token)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 
 	// Find user
 	var user database.User
 	if result := h.db.First(&user, verificationToken.UserID); result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "User not found", nil)
 		return
 	}
 
@@ -641,7 +735,7 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	now := time.Now()
 	user.EmailVerifiedAt = &now
 	if err := h.db.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to verify email", nil)
 		return
 	}
 
@@ -680,7 +774,7 @@ type GoogleAuthRequest struct {
 func (h *AuthHandler) GoogleAuth(c *gin.Context) {
 	var req GoogleAuthRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 
@@ -689,7 +783,7 @@ func (h *AuthHandler) GoogleAuth(c *gin.Context) {
 	if clientID == "" {
 		clientID = os.Getenv("GOOGLE_CLIENT_ID")
 		if clientID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Google client ID not provided"})
+			respondError(c, http.StatusBadRequest, ErrCodeValidation, "Google client ID not provided", nil)
 			return
 		}
 	}
@@ -698,7 +792,7 @@ func (h *AuthHandler) GoogleAuth(c *gin.Context) {
 	if clientSecret == "" {
 		clientSecret = os.Getenv("GOOGLE_CLIENT_SECRET")
 		if clientSecret == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Google client secret not provided"})
+			respondError(c, http.StatusBadRequest, ErrCodeValidation, "Google client secret not provided", nil)
 			return
 		}
 	}
@@ -715,19 +809,19 @@ func (h *AuthHandler) GoogleAuth(c *gin.Context) {
 	// Exchange authorization code for token
 	token, err := oauth2Config.Exchange(context.Background(), req.Code)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to exchange token: %v", err)})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, fmt.Sprintf("Failed to exchange token: %v", err), nil)
 		return
 	}
 
 	// Get user info from Google
 	userInfo, err := getUserInfoFromGoogle(token.AccessToken)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get user info: %v", err)})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to get user info: %v", err), nil)
 		return
 	}
 
 	if !userInfo.VerifiedEmail {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email not verified with Google"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "Email not verified with Google", nil)
 		return
 	}
 
@@ -747,7 +841,7 @@ func (h *AuthHandler) GoogleAuth(c *gin.Context) {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 		if err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+			respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to process password", nil)
 			return
 		}
 
@@ -768,7 +862,7 @@ func (h *AuthHandler) GoogleAuth(c *gin.Context) {
 
 		if err := tx.Create(&user).Error; err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+			respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to create user", nil)
 			return
 		}
 
@@ -781,7 +875,7 @@ func (h *AuthHandler) GoogleAuth(c *gin.Context) {
 
 		if err := tx.Create(&wallet).Error; err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create wallet"})
+			respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to create wallet", nil)
 			return
 		}
 	} else {
@@ -790,7 +884,7 @@ func (h *AuthHandler) GoogleAuth(c *gin.Context) {
 			user.ProfilePicURL = userInfo.Picture
 			if err := tx.Save(&user).Error; err != nil {
 				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+				respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to update user", nil)
 				return
 			}
 		}
@@ -798,25 +892,26 @@ func (h *AuthHandler) GoogleAuth(c *gin.Context) {
 
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete authentication"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to complete authentication", nil)
 		return
 	}
 
-	// Generate tokens
-	tokens, err := generateTokens(user.ID, user.Email, user.IsAdmin)
+	if !user.IsActive {
+		respondError(c, http.StatusForbidden, ErrCodeAccountSuspended, "Account is suspended", nil)
+		return
+	}
+
+	// Generate tokens for the session they start
+	sessionID := uuid.New()
+	tokens, err := generateTokens(h.db, user.ID, sessionID, user.Email, user.IsAdmin)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to generate tokens", nil)
 		return
 	}
 
 	// Create a session record
-	userAgent := c.GetHeader("User-Agent")
-	ipAddress := c.ClientIP()
-	expiresAt := time.Now().Add(7 * 24 * time.Hour)
-	
-	_, err = database.CreateSession(h.db, user.ID, tokens.RefreshToken, userAgent, ipAddress, expiresAt)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+	if _, err := h.startSession(c, sessionID, user.ID, tokens.RefreshToken, "google", false); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to create session", nil)
 		return
 	}
 
@@ -860,8 +955,43 @@ func getUserInfoFromGoogle(accessToken string) (*GoogleUserInfo, error) {
 	return &userInfo, nil
 }
 
-// generateTokens creates access and refresh tokens
-func generateTokens(userID uuid.UUID, email string, isAdmin bool) (utils.TokenPair, error) {
-	// Directly use the utils.GenerateTokenPair function
-	return utils.GenerateTokenPair(userID, email, isAdmin)
+// generateTokens creates access and refresh tokens for a session. Tokens issued without a
+// session, with sessionID uuid.Nil, aren't subject to the session's idle timeout or revocation.
+func generateTokens(db *gorm.DB, userID, sessionID uuid.UUID, email string, isAdmin bool) (utils.TokenPair, error) {
+	// Carry the user's role permissions in the tokens
+	permissions, err := rbac.UserPermissions(db, userID)
+	if err != nil {
+		return utils.TokenPair{}, err
+	}
+	names := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		names = append(names, string(permission))
+	}
+
+	return utils.GenerateTokenPair(userID, sessionID, email, isAdmin, names)
+}
+
+// startSession records the session a login's refresh token belongs to, with the device and
+// location it came from, so it is listed and can be revoked alongside the user's other sessions
+func (h *AuthHandler) startSession(c *gin.Context, sessionID, userID uuid.UUID, refreshToken, authMethod string, mfaVerified bool) (*database.EnhancedSession, error) {
+	userAgent := c.Request.UserAgent()
+	ipAddress := c.ClientIP()
+
+	now := time.Now()
+	metadata := &database.SessionMetadata{
+		LastActiveAt:   now,
+		LastActiveIP:   ipAddress,
+		LastLocationIP: ipAddress,
+		ActivityCount:  1,
+		AuthMethod:     authMethod,
+		MFAVerified:    mfaVerified,
+	}
+	if mfaVerified {
+		metadata.MFAVerifiedAt = &now
+	}
+	if location, err := geoip.DefaultService().Lookup(ipAddress); err == nil {
+		setSessionLocation(metadata, location)
+	}
+
+	return database.CreateEnhancedSession(h.db, sessionID, userID, refreshToken, userAgent, ipAddress, now.Add(utils.RefreshTokenTTL()), detectSessionDevice(userAgent), metadata)
 }
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/security"
+	"github.com/revaspay/backend/internal/security/audit"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Sessions left unused for longer than their idle timeout can't be refreshed
+func TestRefreshTokenExpiresIdleSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&database.EnhancedSession{}))
+	handler := &AuthHandler{db: db, securityConfig: config.SecurityConfig{
+		SessionIdleTimeout:         time.Hour,
+		SessionIdleTimeoutHighRisk: 30 * time.Minute,
+	}}
+
+	// Each session gets its own user, since tokens issued in the same second are identical
+	startSession := func(idle time.Duration, riskScore int) (*database.EnhancedSession, string) {
+		t.Helper()
+		user := testutil.CreateUser(t, db)
+		sessionID := uuid.New()
+		tokens, err := generateTokens(db, user.ID, sessionID, user.Email, false)
+		require.NoError(t, err)
+		metadata := &database.SessionMetadata{LastActiveAt: time.Now().Add(-idle), RiskScore: riskScore}
+		session, err := database.CreateEnhancedSession(db, sessionID, user.ID, tokens.RefreshToken, "test-agent", "41.66.0.1", time.Now().Add(time.Hour), nil, metadata)
+		require.NoError(t, err)
+		return session, tokens.RefreshToken
+	}
+	refresh := func(refreshToken string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"refresh_token": refreshToken})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/refresh", bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.RefreshToken(c)
+		return w
+	}
+	status := func(session *database.EnhancedSession) database.SessionStatus {
+		var reloaded database.EnhancedSession
+		require.NoError(t, db.First(&reloaded, "id = ?", session.ID).Error)
+		return reloaded.Status
+	}
+
+	// A session used recently is refreshed, and the refresh counts as activity
+	active, token := startSession(45*time.Minute, 0)
+	w := refresh(token)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var reloaded database.EnhancedSession
+	require.NoError(t, db.First(&reloaded, "id = ?", active.ID).Error)
+	assert.WithinDuration(t, time.Now(), reloaded.LastActivity(), time.Minute)
+
+	// An idle session expires
+	idle, token := startSession(2*time.Hour, 0)
+	w = refresh(token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), string(ErrCodeSessionExpired))
+	assert.Equal(t, database.SessionStatusExpired, status(idle))
+
+	// Sessions from risky logins time out sooner
+	risky, token := startSession(45*time.Minute, 50)
+	w = refresh(token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, database.SessionStatusExpired, status(risky))
+}
+
+// Access tokens stop working once their session has been idle too long or has been revoked,
+// with the check made after authentication on every protected route
+func TestAuthMiddlewareExpiresIdleSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&database.EnhancedSession{}))
+	middleware.ConfigureSessionActivity(db, database.IdleTimeouts{Default: time.Hour, HighRisk: 30 * time.Minute})
+	t.Cleanup(func() { middleware.ConfigureSessionActivity(nil, database.IdleTimeouts{}) })
+
+	router := gin.New()
+	protected := router.Group("/api")
+	protected.Use(middleware.AuthMiddleware())
+	protected.GET("/me", func(c *gin.Context) {
+		sessionID, _ := c.Get(middleware.ContextSessionID)
+		c.JSON(http.StatusOK, gin.H{"session_id": sessionID})
+	})
+
+	startSession := func(idle time.Duration) (*database.EnhancedSession, string) {
+		t.Helper()
+		user := testutil.CreateUser(t, db)
+		sessionID := uuid.New()
+		tokens, err := generateTokens(db, user.ID, sessionID, user.Email, false)
+		require.NoError(t, err)
+		metadata := &database.SessionMetadata{LastActiveAt: time.Now().Add(-idle)}
+		session, err := database.CreateEnhancedSession(db, sessionID, user.ID, tokens.RefreshToken, "test-agent", "41.66.0.1", time.Now().Add(24*time.Hour), nil, metadata)
+		require.NoError(t, err)
+		return session, tokens.AccessToken
+	}
+	get := func(accessToken string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A session in use stays usable, and each request counts as activity
+	active, token := startSession(45 * time.Minute)
+	w := get(token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), active.ID.String())
+	var reloaded database.EnhancedSession
+	require.NoError(t, db.First(&reloaded, "id = ?", active.ID).Error)
+	assert.WithinDuration(t, time.Now(), reloaded.LastActivity(), time.Minute)
+
+	// An idle session is expired, and stays unusable
+	idle, token := startSession(2 * time.Hour)
+	w = get(token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "session_expired")
+	var expired database.EnhancedSession
+	require.NoError(t, db.First(&expired, "id = ?", idle.ID).Error)
+	assert.Equal(t, database.SessionStatusExpired, expired.Status)
+	assert.Equal(t, http.StatusUnauthorized, get(token).Code)
+
+	// A revoked session's access token is refused before it expires
+	revoked, token := startSession(time.Minute)
+	require.NoError(t, database.RevokeSession(db, revoked.ID, "Signed out"))
+	assert.Equal(t, http.StatusUnauthorized, get(token).Code)
+}
+
+// Both reset endpoints read the same reset tokens: the token's user is its UUID and it
+// expires by its timestamp
+func TestAuthResetPasswordReadsResetTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newPasswordTestDB(t)
+	handler := &AuthHandler{
+		db:                db,
+		passwordValidator: security.NewPasswordValidator(security.Moderate, nil),
+		auditLogger:       audit.NewLogger(db),
+		securityConfig:    config.SecurityConfig{PasswordHistoryCount: 2},
+	}
+	user := testutil.CreateUser(t, db)
+
+	reset := func(token string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"token": token, "password": "Kx7#mVqz!Lb9Rw"})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/reset-password", bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.ResetPassword(c)
+		return w
+	}
+
+	expired, err := database.IssuePasswordResetToken(db, user.ID, -time.Minute)
+	require.NoError(t, err)
+	w := reset(expired)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Reset token has expired")
+
+	token, err := database.IssuePasswordResetToken(db, user.ID, 24*time.Hour)
+	require.NoError(t, err)
+	stored, err := database.GetPasswordResetToken(db, token)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, stored.UserID)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), stored.ExpiresAt, time.Minute)
+
+	w = reset(token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var updated database.User
+	require.NoError(t, db.First(&updated, "id = ?", user.ID).Error)
+	assert.True(t, updated.CheckPassword("Kx7#mVqz!Lb9Rw"))
+	assert.Equal(t, http.StatusBadRequest, reset(token).Code)
+}
+
+// Logins start an enhanced session recording how the user signed in, refreshing rotates that
+// session onto the new token, and revoked or expired sessions can't be refreshed
+func TestLoginSessionsAreEnhancedSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&database.EnhancedSession{}))
+	handler := &AuthHandler{db: db, securityConfig: config.SecurityConfig{SessionIdleTimeout: time.Hour}}
+
+	login := func(authMethod string, mfaVerified bool) (*database.EnhancedSession, string) {
+		t.Helper()
+		user := testutil.CreateUser(t, db)
+		sessionID := uuid.New()
+		tokens, err := generateTokens(db, user.ID, sessionID, user.Email, false)
+		require.NoError(t, err)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		c.Request.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)")
+		session, err := handler.startSession(c, sessionID, user.ID, tokens.RefreshToken, authMethod, mfaVerified)
+		require.NoError(t, err)
+		return session, tokens.RefreshToken
+	}
+	refresh := func(refreshToken string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"refresh_token": refreshToken})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/refresh", bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.RefreshToken(c)
+		return w
+	}
+
+	session, token := login("password", true)
+	assert.Equal(t, database.SessionStatusActive, session.Status)
+	metadata, err := session.GetMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, "password", metadata.AuthMethod)
+	assert.True(t, metadata.MFAVerified)
+	assert.NotNil(t, metadata.MFAVerifiedAt)
+
+	w := refresh(token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Tokens struct {
+			RefreshToken string `json:"refresh_token"`
+		} `json:"tokens"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	var rotated database.EnhancedSession
+	require.NoError(t, db.First(&rotated, "id = ?", session.ID).Error)
+	assert.Equal(t, response.Tokens.RefreshToken, rotated.RefreshToken)
+	assert.Equal(t, 1, rotated.RotationCount)
+
+	google, token := login("google", false)
+	metadata, err = google.GetMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, "google", metadata.AuthMethod)
+	assert.Nil(t, metadata.MFAVerifiedAt)
+	require.NoError(t, database.RevokeSession(db, google.ID, "Signed out"))
+	assert.Equal(t, http.StatusUnauthorized, refresh(token).Code)
+
+	expired, token := login("password", false)
+	require.NoError(t, db.Model(&database.EnhancedSession{}).Where("id = ?", expired.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	assert.Equal(t, http.StatusUnauthorized, refresh(token).Code)
+}
+
+// Password reset emails are throttled per address and per IP, and throttled requests get the
+// same answer as any other so they don't reveal which addresses are registered
+func TestForgotPasswordIsThrottled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t)
+	handler := &AuthHandler{db: db, securityConfig: config.SecurityConfig{
+		PasswordResetEmailLimit: 2,
+		PasswordResetIPLimit:    3,
+		PasswordResetWindow:     time.Hour,
+	}}
+
+	forgot := func(email, ip string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"email": email})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/forgot-password", bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.RemoteAddr = ip + ":40000"
+		handler.ForgotPassword(c)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"message":"`+forgotPasswordMessage+`"}`, w.Body.String())
+		return w
+	}
+
+	assert.Empty(t, forgot("nobody@example.com", "41.66.0.1").Header().Get("Retry-After"))
+	assert.Empty(t, forgot("Nobody@Example.com", "41.66.0.2").Header().Get("Retry-After"))
+
+	// The address has had its two emails, whatever the case or IP it's requested with
+	retryAfter, err := strconv.Atoi(forgot("nobody@example.com", "41.66.0.3").Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), retryAfter, 60)
+
+	// The first IP has made one request, so it gets two more before it is throttled too
+	assert.Empty(t, forgot("first@example.com", "41.66.0.1").Header().Get("Retry-After"))
+	assert.Empty(t, forgot("second@example.com", "41.66.0.1").Header().Get("Retry-After"))
+	assert.NotEmpty(t, forgot("third@example.com", "41.66.0.1").Header().Get("Retry-After"))
+
+	var attempts int64
+	require.NoError(t, db.Model(&database.AuthAttempt{}).Where("attempt_type = ?", database.AttemptTypePasswordReset).Count(&attempts).Error)
+	assert.EqualValues(t, 4, attempts, "throttled requests aren't recorded")
+}
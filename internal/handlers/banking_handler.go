@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/revaspay/backend/internal/database"
 	"github.com/revaspay/backend/internal/services/banking"
+	"github.com/revaspay/backend/internal/utils"
 	"gorm.io/gorm"
 )
 
@@ -17,17 +19,17 @@ type BankingHandler struct {
 }
 
 // NewBankingHandler creates a new banking handler
-func NewBankingHandler(db *gorm.DB) *BankingHandler {
+func NewBankingHandler(db *gorm.DB, resolver banking.AccountResolver) *BankingHandler {
 	return &BankingHandler{
 		db:              db,
-		bankingService:  banking.NewGhanaBankingService(db),
+		bankingService:  banking.NewGhanaBankingService(db, resolver),
 	}
 }
 
 // LinkBankAccount links a bank account to a user's account
 func (h *BankingHandler) LinkBankAccount(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -36,10 +38,10 @@ func (h *BankingHandler) LinkBankAccount(c *gin.Context) {
 	// Parse request
 	var req struct {
 		AccountNumber string `json:"account_number" binding:"required"`
-		AccountName   string `json:"account_name" binding:"required"`
-		BankName      string `json:"bank_name" binding:"required"`
+		AccountName   string `json:"account_name"`
+		BankName      string `json:"bank_name"`
 		BankCode      string `json:"bank_code" binding:"required"`
-		BranchCode    string `json:"branch_code" binding:"required"`
+		BranchCode    string `json:"branch_code"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -57,12 +59,21 @@ func (h *BankingHandler) LinkBankAccount(c *gin.Context) {
 	}
 
 	// Link bank account
-	account, err := h.bankingService.LinkBankAccount(userID.(uuid.UUID), bankDetails)
+	account, err := h.bankingService.LinkBankAccount(userID, bankDetails)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, banking.ErrBankAccountAlreadyLinked):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, banking.ErrBankAccountNotVerified), errors.Is(err, banking.ErrAccountNameMismatch):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link bank account"})
+		}
 		return
 	}
 
+	maskBankAccount(account)
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": "Bank account linked successfully",
@@ -73,19 +84,23 @@ func (h *BankingHandler) LinkBankAccount(c *gin.Context) {
 // GetBankAccounts retrieves all bank accounts for a user
 func (h *BankingHandler) GetBankAccounts(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
 	// Get bank accounts
-	accounts, err := h.bankingService.GetBankAccounts(userID.(uuid.UUID))
+	accounts, err := h.bankingService.GetBankAccounts(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get bank accounts"})
 		return
 	}
 
+	for i := range accounts {
+		maskBankAccount(&accounts[i])
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status": "success",
 		"data":   accounts,
@@ -95,7 +110,7 @@ func (h *BankingHandler) GetBankAccounts(c *gin.Context) {
 // GetBankAccount retrieves a specific bank account
 func (h *BankingHandler) GetBankAccount(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -109,26 +124,24 @@ func (h *BankingHandler) GetBankAccount(c *gin.Context) {
 	}
 
 	// Get bank account
-	var account database.BankAccount
-	if err := h.db.Where("id = ? AND user_id = ?", accountID, userID).First(&account).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Bank account not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+	account, err := h.bankingService.GetBankAccount(accountID, userID)
+	if err != nil {
+		h.handleBankAccountError(c, err)
 		return
 	}
 
+	maskBankAccount(account)
+
 	c.JSON(http.StatusOK, gin.H{
 		"status": "success",
 		"data":   account,
 	})
 }
 
-// UpdateBankAccount updates a bank account
+// UpdateBankAccount updates the label of a bank account
 func (h *BankingHandler) UpdateBankAccount(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -141,21 +154,9 @@ func (h *BankingHandler) UpdateBankAccount(c *gin.Context) {
 		return
 	}
 
-	// Check if account exists and belongs to user
-	var account database.BankAccount
-	if err := h.db.Where("id = ? AND user_id = ?", accountID, userID).First(&account).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Bank account not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
-		return
-	}
-
 	// Parse request
 	var req struct {
-		AccountName string `json:"account_name"`
-		IsActive    *bool  `json:"is_active"`
+		Label string `json:"label" binding:"required,max=100"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -163,28 +164,13 @@ func (h *BankingHandler) UpdateBankAccount(c *gin.Context) {
 		return
 	}
 
-	// Update fields
-	updates := map[string]interface{}{}
-	
-	if req.AccountName != "" {
-		updates["account_name"] = req.AccountName
-	}
-	
-	if req.IsActive != nil {
-		updates["is_active"] = *req.IsActive
-	}
-
-	// Apply updates
-	if err := h.db.Model(&account).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	account, err := h.bankingService.UpdateBankAccountLabel(accountID, userID, req.Label)
+	if err != nil {
+		h.handleBankAccountError(c, err)
 		return
 	}
 
-	// Get updated account
-	if err := h.db.First(&account, accountID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+	maskBankAccount(account)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
@@ -193,10 +179,10 @@ func (h *BankingHandler) UpdateBankAccount(c *gin.Context) {
 	})
 }
 
-// DeleteBankAccount deletes a bank account
+// DeleteBankAccount soft-deletes a bank account
 func (h *BankingHandler) DeleteBankAccount(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -209,20 +195,8 @@ func (h *BankingHandler) DeleteBankAccount(c *gin.Context) {
 		return
 	}
 
-	// Check if account exists and belongs to user
-	var account database.BankAccount
-	if err := h.db.Where("id = ? AND user_id = ?", accountID, userID).First(&account).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Bank account not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
-		return
-	}
-
-	// Soft delete the account
-	if err := h.db.Delete(&account).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.bankingService.DeleteBankAccount(accountID, userID); err != nil {
+		h.handleBankAccountError(c, err)
 		return
 	}
 
@@ -232,21 +206,12 @@ func (h *BankingHandler) DeleteBankAccount(c *gin.Context) {
 	})
 }
 
-// GetBanks retrieves a list of supported Ghanaian banks
+// GetBanks retrieves the list of supported Ghanaian banks from the provider
 func (h *BankingHandler) GetBanks(c *gin.Context) {
-	// In production, this would come from a database or API
-	// For now, we'll return a static list of major Ghanaian banks
-	banks := []map[string]string{
-		{"name": "Ghana Commercial Bank", "code": "GCB"},
-		{"name": "Ecobank Ghana", "code": "ECO"},
-		{"name": "Fidelity Bank Ghana", "code": "FBG"},
-		{"name": "Zenith Bank Ghana", "code": "ZBG"},
-		{"name": "Standard Chartered Bank Ghana", "code": "SCB"},
-		{"name": "Absa Bank Ghana", "code": "ABSA"},
-		{"name": "Consolidated Bank Ghana", "code": "CBG"},
-		{"name": "Agricultural Development Bank", "code": "ADB"},
-		{"name": "National Investment Bank", "code": "NIB"},
-		{"name": "Prudential Bank", "code": "PBL"},
+	banks, err := h.bankingService.GetBanks()
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch bank list"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -255,7 +220,8 @@ func (h *BankingHandler) GetBanks(c *gin.Context) {
 	})
 }
 
-// VerifyBankAccount verifies a bank account with the bank
+// VerifyBankAccount confirms an account number and bank code with the provider
+// and returns the name on the account so the user can confirm it before linking
 func (h *BankingHandler) VerifyBankAccount(c *gin.Context) {
 	// Parse request
 	var req struct {
@@ -268,16 +234,32 @@ func (h *BankingHandler) VerifyBankAccount(c *gin.Context) {
 		return
 	}
 
-	// In production, this would call the actual bank's API
-	// For now, we'll simulate a successful verification
+	account, err := h.bankingService.VerifyBankAccount(req.AccountNumber, req.BankCode)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": "Bank account verified successfully",
-		"data": map[string]string{
-			"account_number": req.AccountNumber,
-			"account_name":   "John Doe", // This would come from the bank's API
-			"bank_code":      req.BankCode,
-			"bank_name":      "Ghana Commercial Bank", // This would come from the bank's API
-		},
+		"data":    account,
 	})
 }
+
+// handleBankAccountError maps banking service errors to HTTP responses
+func (h *BankingHandler) handleBankAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, banking.ErrBankAccountNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bank account not found"})
+	case errors.Is(err, banking.ErrBankAccountInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process bank account request"})
+	}
+}
+
+// maskBankAccount hides all but the last 4 digits of the account number before it is returned
+func maskBankAccount(account *database.BankAccount) {
+	account.AccountNumber = utils.MaskAccountNumber(account.AccountNumber)
+}
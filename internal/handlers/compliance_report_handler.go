@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/pagination"
+	"github.com/revaspay/backend/internal/services/compliance"
+	"gorm.io/gorm"
+)
+
+// ComplianceReportHandler handles admin access to compliance reports
+type ComplianceReportHandler struct {
+	complianceService *compliance.GhanaComplianceService
+}
+
+// NewComplianceReportHandler creates a new compliance report handler
+func NewComplianceReportHandler(db *gorm.DB) *ComplianceReportHandler {
+	return &ComplianceReportHandler{
+		complianceService: compliance.NewGhanaComplianceService(db),
+	}
+}
+
+// ListReports lists compliance reports, optionally filtered by date range (from/to, YYYY-MM-DD) and status
+func (h *ComplianceReportHandler) ListReports(c *gin.Context) {
+	var filter compliance.ReportFilter
+
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+		filter.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+		// The to date is inclusive
+		t = t.AddDate(0, 0, 1)
+		filter.To = &t
+	}
+	filter.Status = c.Query("status")
+
+	params := pagination.ParseParams(c, 20)
+
+	reports, total, err := h.complianceService.ListReports(filter, params.Page, params.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get compliance reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"data":       reports,
+		"pagination": pagination.NewMeta(params, total),
+	})
+}
+
+// GetReport returns a single compliance report as JSON, or as a PDF with ?format=pdf
+func (h *ComplianceReportHandler) GetReport(c *gin.Context) {
+	reportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	report, err := h.complianceService.GetReport(reportID)
+	if err != nil {
+		if errors.Is(err, compliance.ErrReportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get compliance report"})
+		return
+	}
+
+	writeComplianceReport(c, report)
+}
+
+// writeComplianceReport responds with the report as JSON or, when format=pdf, as a PDF download
+func writeComplianceReport(c *gin.Context, report *database.ComplianceReport) {
+	if c.Query("format") != "pdf" {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   report,
+		})
+		return
+	}
+
+	pdf, err := compliance.RenderReportPDF(report)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export compliance report"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=compliance-report-%s.pdf", report.ID))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
@@ -27,7 +27,7 @@ func NewCryptoHandler(db *gorm.DB) *CryptoHandler {
 // CreateWallet creates a new Base blockchain wallet for a user
 func (h *CryptoHandler) CreateWallet(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -42,7 +42,7 @@ func (h *CryptoHandler) CreateWallet(c *gin.Context) {
 	}
 
 	// Create wallet
-	wallet, err := h.baseService.CreateBaseWallet(userID.(uuid.UUID))
+	wallet, err := h.baseService.CreateBaseWallet(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -63,7 +63,7 @@ func (h *CryptoHandler) CreateWallet(c *gin.Context) {
 // GetWallets retrieves all crypto wallets for a user
 func (h *CryptoHandler) GetWallets(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -98,7 +98,7 @@ func (h *CryptoHandler) GetWallets(c *gin.Context) {
 // GetWallet retrieves a specific wallet
 func (h *CryptoHandler) GetWallet(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -146,7 +146,7 @@ func (h *CryptoHandler) GetWallet(c *gin.Context) {
 // GetTransactions retrieves all transactions for a wallet
 func (h *CryptoHandler) GetTransactions(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -186,7 +186,7 @@ func (h *CryptoHandler) GetTransactions(c *gin.Context) {
 // GetTransaction retrieves a specific transaction
 func (h *CryptoHandler) GetTransaction(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
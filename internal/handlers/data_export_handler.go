@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/security/audit"
+	"github.com/revaspay/backend/internal/services/dataexport"
+	"gorm.io/gorm"
+)
+
+// DataExportHandler lets users download a copy of their data
+type DataExportHandler struct {
+	service     *dataexport.Service
+	auditLogger *audit.Logger
+}
+
+// NewDataExportHandler creates a new data export handler
+func NewDataExportHandler(db *gorm.DB, service *dataexport.Service) *DataExportHandler {
+	return &DataExportHandler{
+		service:     service,
+		auditLogger: audit.NewLogger(db),
+	}
+}
+
+// DataExportRequest is the body of a data export request
+type DataExportRequest struct {
+	Format models.DataExportFormat `json:"format"` // json (the default) or zip
+}
+
+// RequestDataExport queues an export of the user's data. The user is emailed a download link
+// once it is ready.
+func (h *DataExportHandler) RequestDataExport(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var req DataExportRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+			return
+		}
+	}
+
+	export, err := h.service.Request(userID, req.Format)
+	if err != nil {
+		h.auditLogger.LogWithContext(c, audit.EventTypeProfile, audit.SeverityInfo, "Data export requested",
+			&userID, nil, c.ClientIP(), c.Request.UserAgent(), false, map[string]interface{}{"error": err.Error()})
+
+		switch {
+		case errors.Is(err, dataexport.ErrExportRateLimited):
+			respondError(c, http.StatusTooManyRequests, ErrCodeRateLimited, "You can request one data export per day", nil)
+		case errors.Is(err, dataexport.ErrInvalidFormat):
+			respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		default:
+			respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to request data export", nil)
+		}
+		return
+	}
+
+	h.auditLogger.LogWithContext(c, audit.EventTypeProfile, audit.SeverityInfo, "Data export requested",
+		&userID, nil, c.ClientIP(), c.Request.UserAgent(), true, map[string]interface{}{
+			"export_id": export.ID.String(),
+			"format":    string(export.Format),
+		})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Your data export has been requested. We'll email you a download link when it's ready.",
+		"export":  export,
+	})
+}
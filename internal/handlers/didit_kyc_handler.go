@@ -1,18 +1,21 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/middleware"
 	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/pagination"
 	"github.com/revaspay/backend/internal/services/kyc"
+	"github.com/revaspay/backend/internal/services/storage"
 	"gorm.io/gorm"
 )
 
@@ -20,67 +23,50 @@ import (
 type DiditKYCHandler struct {
 	db           *gorm.DB
 	diditService *kyc.DiditService
-	uploadsDir   string
+	validator    *kyc.DocumentValidator
+	storage      storage.Backend
 }
 
-// NewDiditKYCHandler creates a new Didit KYC handler
-func NewDiditKYCHandler(db *gorm.DB) (*DiditKYCHandler, error) {
+// NewDiditKYCHandler creates a new Didit KYC handler that keeps documents in store
+func NewDiditKYCHandler(db *gorm.DB, store storage.Backend) (*DiditKYCHandler, error) {
 	// Create Didit service
-	diditService, err := kyc.NewDiditService(db)
+	diditService, err := kyc.NewDiditService(db, store)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Didit service: %w", err)
 	}
 
-	// Ensure uploads directory exists
-	uploadsDir := filepath.Join("uploads", "kyc")
-	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create uploads directory: %w", err)
-	}
-
 	return &DiditKYCHandler{
 		db:           db,
 		diditService: diditService,
-		uploadsDir:   uploadsDir,
+		validator:    kyc.DefaultDocumentValidator(),
+		storage:      store,
 	}, nil
 }
 
 // InitiateKYCVerification creates a new KYC verification session
 func (h *DiditKYCHandler) InitiateKYCVerification(c *gin.Context) {
 	// Get user ID from the JWT token
-	userIDStr := c.GetString("user_id")
-	if userIDStr == "" {
+	userID, exists := getUserID(c)
+	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
-	// Check if user already has a pending or approved verification
-	var existingVerifications []models.KYCVerification
-	if err := h.db.Where("user_id = ? AND status IN ?", userID, []models.KYCStatus{models.KYCStatusPending, models.KYCStatusInProgress, models.KYCStatusApproved}).Find(&existingVerifications).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing verifications"})
-		return
-	}
-
-	if len(existingVerifications) > 0 {
-		// User already has a pending or approved verification
-		c.JSON(http.StatusConflict, gin.H{
-			"error": "You already have a KYC verification in progress or approved",
-			"verification": gin.H{
-				"id":               existingVerifications[0].ID,
-				"status":           existingVerifications[0].Status,
-				"verification_url": existingVerifications[0].VerificationURL,
-			},
-		})
+	// Create a new verification session with Didit. A pending session the user already has is
+	// returned instead, so retries and double submits are safe.
+	verification, err := h.diditService.CreateVerificationSession(c.Request.Context(), userID)
+	if errors.Is(err, kyc.ErrVerificationActive) {
+		// User already has a verification in progress or approved
+		response := gin.H{"error": "You already have a KYC verification in progress or approved"}
+		if existing, _ := kyc.GetActiveVerification(h.db, userID); existing != nil {
+			response["verification"] = gin.H{
+				"id":     existing.ID,
+				"status": existing.Status,
+			}
+		}
+		c.JSON(http.StatusConflict, response)
 		return
 	}
-
-	// Create a new verification session with Didit
-	verification, err := h.diditService.CreateVerificationSession(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create verification session: %v", err)})
 		return
@@ -98,78 +84,31 @@ func (h *DiditKYCHandler) InitiateKYCVerification(c *gin.Context) {
 // GetKYCStatus returns the KYC status for a user
 func (h *DiditKYCHandler) GetKYCStatus(c *gin.Context) {
 	// Get user ID from the JWT token
-	userIDStr := c.GetString("user_id")
-	if userIDStr == "" {
+	userID, exists := getUserID(c)
+	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr)
+	// The latest verification is reported the same way whichever provider ran it
+	status, err := kyc.GetUserStatus(h.db, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
-	// Get the latest verification for the user
-	var verification models.KYCVerification
-	result := h.db.Where("user_id = ?", userID).Order("created_at DESC").First(&verification)
-	
-	if result.Error != nil {
-		if result.Error == gorm.ErrRecordNotFound {
-			// No verification found
-			c.JSON(http.StatusOK, gin.H{
-				"status": "not_submitted",
-				"message": "No KYC verification has been submitted",
-			})
-			return
-		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve KYC status"})
 		return
 	}
 
-	// Return the verification status
-	response := gin.H{
-		"verification_id":   verification.ID,
-		"status":           verification.Status,
-		"created_at":       verification.CreatedAt,
-		"updated_at":       verification.UpdatedAt,
-	}
-
-	// Add additional information based on status
-	if verification.Status == models.KYCStatusApproved {
-		response["full_name"] = verification.FullName
-		response["id_doc_type"] = verification.IDDocType
-		response["id_doc_country"] = verification.IDDocCountry
-		if verification.IDDocExpiry != nil {
-			response["id_doc_expiry"] = verification.IDDocExpiry
-		}
-	} else if verification.Status == models.KYCStatusRejected {
-		response["rejection_reason"] = verification.RejectionReason
-	}
-
-	// Add verification URL if available and status is pending or in progress
-	if verification.VerificationURL != "" && (verification.Status == models.KYCStatusPending || verification.Status == models.KYCStatusInProgress) {
-		response["verification_url"] = verification.VerificationURL
-	}
-
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, status)
 }
 
 // UploadDocument handles document upload for KYC verification
 func (h *DiditKYCHandler) UploadDocument(c *gin.Context) {
 	// Get user ID from the JWT token
-	userIDStr := c.GetString("user_id")
-	if userIDStr == "" {
+	userID, exists := getUserID(c)
+	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get verification ID from the request
 	verificationIDStr := c.Param("id")
 	if verificationIDStr == "" {
@@ -209,51 +148,34 @@ func (h *DiditKYCHandler) UploadDocument(c *gin.Context) {
 	}
 
 	// Validate document type
-	var docType models.DocumentType
-	switch docTypeStr {
-	case "id":
-		docType = models.DocumentTypeID
-	case "passport":
-		docType = models.DocumentTypePassport
-	case "license":
-		docType = models.DocumentTypeLicense
-	case "selfie":
-		docType = models.DocumentTypeSelfie
-	default:
+	docType, err := kyc.ParseDocumentType(docTypeStr)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document type"})
 		return
 	}
 
 	// Get the file from the form
-	file, header, err := c.Request.FormFile("file")
+	file, _, err := c.Request.FormFile("file")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
 		return
 	}
 	defer file.Close()
 
-	// Generate a unique filename
-	filename := fmt.Sprintf("%s_%s_%s%s", userID, docTypeStr, time.Now().Format("20060102150405"), filepath.Ext(header.Filename))
-	filePath := filepath.Join(h.uploadsDir, filename)
-
-	// Create the file
-	out, err := os.Create(filePath)
+	// Validate and store the file; its extension comes from the detected content type
+	filename := fmt.Sprintf("%s_%s", docTypeStr, time.Now().Format("20060102150405"))
+	stored, err := h.validator.Store(c.Request.Context(), h.storage, file, kycDocumentKey(userID, filename))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create file"})
-		return
-	}
-	defer out.Close()
-
-	// Copy the file data
-	_, err = io.Copy(out, file)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		respondDocumentError(c, "Document", err)
 		return
 	}
 
 	// Upload the document to the verification
-	document, err := h.diditService.UploadDocument(verificationID, docType, filePath)
+	document, err := h.diditService.UploadDocument(verificationID, docType, stored)
 	if err != nil {
+		if err := h.storage.Delete(context.Background(), stored.Key); err != nil {
+			log.Printf("Failed to remove KYC document %s: %v", stored.Key, err)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to upload document: %v", err)})
 		return
 	}
@@ -272,20 +194,24 @@ func (h *DiditKYCHandler) HandleDiditWebhook(c *gin.Context) {
 	// Get the webhook signature from the header
 	signature := c.GetHeader("X-Didit-Signature")
 	if signature == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing webhook signature"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing webhook signature"})
 		return
 	}
 
 	// Read the request body
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
+		if middleware.IsBodyTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
 		return
 	}
 
 	// Process the webhook
 	if err := h.diditService.ProcessWebhook(body, signature); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process webhook: %v", err)})
+		respondDiditWebhookError(c, err)
 		return
 	}
 
@@ -296,18 +222,12 @@ func (h *DiditKYCHandler) HandleDiditWebhook(c *gin.Context) {
 // GetUserVerifications returns all verifications for a user
 func (h *DiditKYCHandler) GetUserVerifications(c *gin.Context) {
 	// Get user ID from the JWT token
-	userIDStr := c.GetString("user_id")
-	if userIDStr == "" {
+	userID, exists := getUserID(c)
+	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get all verifications for the user
 	verifications, err := h.diditService.GetUserVerifications(userID)
 	if err != nil {
@@ -323,33 +243,13 @@ func (h *DiditKYCHandler) GetUserVerifications(c *gin.Context) {
 
 // GetPendingVerifications returns all pending verifications for admin review
 func (h *DiditKYCHandler) GetPendingVerifications(c *gin.Context) {
-	// Check if user is admin
-	isAdmin := c.GetBool("is_admin")
-	if !isAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+	// Check the user may view KYC submissions
+	if !hasPermission(c, models.PermissionKYCView) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
 
-	// Get page and limit from query parameters
-	page, _ := c.GetQuery("page")
-	limit, _ := c.GetQuery("limit")
-
-	pageNum := 1
-	limitNum := 10
-
-	if page != "" {
-		if val, err := parseInt(page); err == nil && val > 0 {
-			pageNum = val
-		}
-	}
-
-	if limit != "" {
-		if val, err := parseInt(limit); err == nil && val > 0 {
-			limitNum = val
-		}
-	}
-
-	offset := (pageNum - 1) * limitNum
+	params := pagination.ParseParams(c, 10)
 
 	// Get pending verifications
 	var verifications []models.KYCVerification
@@ -358,7 +258,7 @@ func (h *DiditKYCHandler) GetPendingVerifications(c *gin.Context) {
 	h.db.Model(&models.KYCVerification{}).Where("status IN ?", []models.KYCStatus{models.KYCStatusPending, models.KYCStatusInProgress}).Count(&total)
 	
 	if err := h.db.Preload("User").Where("status IN ?", []models.KYCStatus{models.KYCStatusPending, models.KYCStatusInProgress}).
-		Order("created_at DESC").Offset(offset).Limit(limitNum).Find(&verifications).Error; err != nil {
+		Order("created_at DESC").Offset(params.Offset()).Limit(params.PageSize).Find(&verifications).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to retrieve pending verifications: %v", err)})
 		return
 	}
@@ -366,32 +266,20 @@ func (h *DiditKYCHandler) GetPendingVerifications(c *gin.Context) {
 	// Return the verifications
 	c.JSON(http.StatusOK, gin.H{
 		"verifications": verifications,
-		"pagination": gin.H{
-			"total":  total,
-			"page":   pageNum,
-			"limit":  limitNum,
-			"pages":  (total + int64(limitNum) - 1) / int64(limitNum),
-		},
+		"pagination":    pagination.NewMeta(params, total),
 	})
 }
 
 // GetVerificationByID returns a specific verification by ID
 func (h *DiditKYCHandler) GetVerificationByID(c *gin.Context) {
-	// Check if user is admin or the verification owner
-	isAdmin := c.GetBool("is_admin")
-	userIDStr := c.GetString("user_id")
-	
-	if userIDStr == "" {
+	// Check if user is KYC staff or the verification owner
+	isReviewer := hasPermission(c, models.PermissionKYCView)
+	userID, exists := getUserID(c)
+	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get verification ID from the request
 	verificationIDStr := c.Param("id")
 	if verificationIDStr == "" {
@@ -417,7 +305,7 @@ func (h *DiditKYCHandler) GetVerificationByID(c *gin.Context) {
 	}
 
 	// Check if the user is authorized to view this verification
-	if !isAdmin && verification.UserID != userID {
+	if !isReviewer && verification.UserID != userID {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to view this verification"})
 		return
 	}
@@ -443,6 +331,12 @@ func (h *DiditKYCHandler) GetVerificationByID(c *gin.Context) {
 		return
 	}
 
+	// Only KYC staff get signed links to the document files
+	var documentList interface{} = documents
+	if isReviewer {
+		documentList = signedDocuments(c.Request.Context(), h.storage, documents)
+	}
+
 	// Return the verification details
 	c.JSON(http.StatusOK, gin.H{
 		"verification": verification,
@@ -453,32 +347,25 @@ func (h *DiditKYCHandler) GetVerificationByID(c *gin.Context) {
 			"name":     user.FirstName + " " + user.LastName,
 		},
 		"history":   history,
-		"documents": documents,
+		"documents": documentList,
 	})
 }
 
 // UpdateVerificationStatus updates the status of a verification (admin only)
 func (h *DiditKYCHandler) UpdateVerificationStatus(c *gin.Context) {
-	// Check if user is admin
-	isAdmin := c.GetBool("is_admin")
-	if !isAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+	// Check the user may review KYC submissions
+	if !hasPermission(c, models.PermissionKYCApprove) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
 
 	// Get admin ID for audit
-	adminIDStr := c.GetString("user_id")
-	if adminIDStr == "" {
+	adminID, exists := getUserID(c)
+	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin ID not found"})
 		return
 	}
 
-	adminID, err := uuid.Parse(adminIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
-		return
-	}
-
 	// Parse request body
 	var request struct {
 		VerificationID  string           `json:"verification_id" binding:"required"`
@@ -492,18 +379,6 @@ func (h *DiditKYCHandler) UpdateVerificationStatus(c *gin.Context) {
 		return
 	}
 
-	// Validate status
-	if request.Status != models.KYCStatusApproved && request.Status != models.KYCStatusRejected {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be 'approved' or 'rejected'"})
-		return
-	}
-
-	// If status is rejected, rejection reason is required
-	if request.Status == models.KYCStatusRejected && request.RejectionReason == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Rejection reason is required"})
-		return
-	}
-
 	// Parse verification ID
 	verificationID, err := uuid.Parse(request.VerificationID)
 	if err != nil {
@@ -511,74 +386,24 @@ func (h *DiditKYCHandler) UpdateVerificationStatus(c *gin.Context) {
 		return
 	}
 
-	// Get the verification
-	var verification models.KYCVerification
-	if err := h.db.First(&verification, "id = ?", verificationID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	// Update the verification and record the change in its history
+	verification, err := kyc.ReviewVerification(h.db, verificationID, request.Status, adminID, request.RejectionReason, request.Notes)
+	if err != nil {
+		switch {
+		case errors.Is(err, kyc.ErrInvalidReviewStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be 'approved' or 'rejected'"})
+		case errors.Is(err, kyc.ErrRejectionReasonRequired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Rejection reason is required"})
+		case errors.Is(err, kyc.ErrStatusUnchanged):
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Verification is already %s", request.Status)})
+		case errors.Is(err, kyc.ErrVerificationNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "Verification not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve verification"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update verification status"})
 		}
 		return
 	}
 
-	// Check if the verification is already in the requested status
-	if verification.Status == request.Status {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Verification is already %s", request.Status)})
-		return
-	}
-
-	// Start a transaction
-	tx := h.db.Begin()
-	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
-		return
-	}
-
-	// Record previous status for history
-	previousStatus := verification.Status
-
-	// Update verification status
-	verification.Status = request.Status
-	if request.Status == models.KYCStatusRejected {
-		verification.RejectionReason = &request.RejectionReason
-	}
-	if request.Notes != "" {
-		verification.AdminNotes = &request.Notes
-	}
-
-	// Save the updated verification
-	if err := tx.Save(&verification).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update verification status"})
-		return
-	}
-
-	// Create history record
-	history := models.KYCVerificationHistory{
-		VerificationID: verificationID,
-		PreviousStatus: previousStatus,
-		NewStatus:      request.Status,
-		ChangedBy:      adminID,
-		CreatedAt:      time.Now(),
-	}
-
-	if request.Notes != "" {
-		history.Notes = &request.Notes
-	}
-
-	if err := tx.Create(&history).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create history record"})
-		return
-	}
-
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
-		return
-	}
-
 	// Return success response
 	c.JSON(http.StatusOK, gin.H{
 		"message":      "Verification status updated successfully",
@@ -586,14 +411,9 @@ func (h *DiditKYCHandler) UpdateVerificationStatus(c *gin.Context) {
 	})
 }
 
-// Helper function to parse integer values
-func parseInt(s string) (int, error) {
-	return strconv.Atoi(s)
-}
-
 // RegisterDiditKYCRoutes registers the Didit KYC routes
-func RegisterDiditKYCRoutes(router *gin.RouterGroup, db *gorm.DB) error {
-	handler, err := NewDiditKYCHandler(db)
+func RegisterDiditKYCRoutes(router *gin.RouterGroup, db *gorm.DB, store storage.Backend) error {
+	handler, err := NewDiditKYCHandler(db, store)
 	if err != nil {
 		return fmt.Errorf("failed to create Didit KYC handler: %w", err)
 	}
@@ -1,35 +1,76 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/config"
 	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/jobs"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
 	"github.com/revaspay/backend/internal/security"
+	"github.com/revaspay/backend/internal/services/geoip"
 	"github.com/revaspay/backend/internal/utils"
 	"gorm.io/gorm"
 )
 
+// trustedDeviceCookie holds the token that identifies a trusted device on later logins
+const trustedDeviceCookie = "trusted_device"
+
+// Address ranges broader than these can't be revoked in bulk, so a typo can't sign out
+// most of the user base
+const (
+	minRevokePrefixIPv4 = 8
+	minRevokePrefixIPv6 = 32
+)
+
 // EnhancedSessionHandler handles advanced session management with security features
 type EnhancedSessionHandler struct {
-	db           *gorm.DB
-	riskAssessor *security.RiskAssessor
+	db                    *gorm.DB
+	riskAssessor          *security.RiskAssessor
+	trustedDeviceDuration time.Duration
+	auditLogger           *utils.AuditLogger
+
+	// New login emails are sent in the background, at most notificationLimit per user
+	// within notificationWindow
+	notificationQueue  jobEnqueuer
+	notificationLimit  int
+	notificationWindow time.Duration
 }
 
 // NewEnhancedSessionHandler creates a new enhanced session handler
 func NewEnhancedSessionHandler(db *gorm.DB) *EnhancedSessionHandler {
+	securityConfig := config.DefaultSecurityConfig()
 	return &EnhancedSessionHandler{
-		db:           db,
-		riskAssessor: security.NewRiskAssessor(db),
+		db:                    db,
+		riskAssessor:          security.NewRiskAssessor(db),
+		trustedDeviceDuration: securityConfig.TrustedDeviceDuration,
+		auditLogger:           utils.NewAuditLogger(db),
+		notificationLimit:     securityConfig.LoginNotificationLimit,
+		notificationWindow:    securityConfig.LoginNotificationWindow,
+	}
+}
+
+// SetNotificationQueue sets the queue that new login emails are sent through. Without one, no
+// emails are sent.
+func (h *EnhancedSessionHandler) SetNotificationQueue(q *queue.Queue) {
+	if q != nil {
+		h.notificationQueue = q
 	}
 }
 
 // CreateEnhancedSession creates a new session with security risk assessment
 func (h *EnhancedSessionHandler) CreateEnhancedSession(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -46,8 +87,8 @@ func (h *EnhancedSessionHandler) CreateEnhancedSession(c *gin.Context) {
 	userAgent := c.Request.UserAgent()
 	ipAddress := c.ClientIP()
 
-	// Perform risk assessment
-	assessment, err := h.riskAssessor.AssessLoginRisk(userID.(uuid.UUID), ipAddress, userAgent)
+	// Perform risk assessment, recognising the device if it was previously trusted
+	assessment, err := h.riskAssessor.AssessLoginRiskWithDevice(userID, ipAddress, userAgent, trustedDeviceToken(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assess login risk"})
 		return
@@ -70,22 +111,24 @@ func (h *EnhancedSessionHandler) CreateEnhancedSession(c *gin.Context) {
 		c.Set("recommend_2fa", true)
 	}
 
-	// Generate tokens using JWT utility
-	tokens, err := utils.GenerateTokenPair(user.ID, user.Email, user.IsAdmin)
+	// Generate tokens for the session they start
+	sessionID := uuid.New()
+	tokens, err := generateTokens(h.db, user.ID, sessionID, user.Email, user.IsAdmin)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
 	}
 
 	// Set expiry time for session
-	expiresAt := time.Now().Add(7 * 24 * time.Hour)
+	expiresAt := time.Now().Add(utils.RefreshTokenTTL())
 
 	// Create device info
-	deviceInfo := &database.SessionDevice{
-		DeviceType:    h.detectDeviceType(userAgent),
-		Browser:       h.detectBrowser(userAgent),
-		OS:            h.detectOS(userAgent),
-		TrustedDevice: false,
+	deviceInfo := detectSessionDevice(userAgent)
+
+	// Check for a device the user has never logged in from, before this session makes it known
+	newDevice, err := database.IsNewDevice(h.db, userID, deviceInfo)
+	if err != nil {
+		log.Printf("Failed to check devices of user %s: %v", userID, err)
 	}
 
 	// Create metadata
@@ -97,6 +140,9 @@ func (h *EnhancedSessionHandler) CreateEnhancedSession(c *gin.Context) {
 		AuthMethod:     "password", // This should be dynamic based on actual auth method
 	}
 
+	// Record where the session was created from
+	setSessionLocation(metadata, assessment.Location)
+
 	// If MFA was used, record it
 	if c.GetBool("mfa_verified") {
 		metadata.MFAVerifiedAt = &now
@@ -104,8 +150,9 @@ func (h *EnhancedSessionHandler) CreateEnhancedSession(c *gin.Context) {
 
 	// Create enhanced session
 	session, err := database.CreateEnhancedSession(
-		h.db, 
-		userID.(uuid.UUID), 
+		h.db,
+		sessionID,
+		userID, 
 		tokens.RefreshToken, 
 		userAgent, 
 		ipAddress, 
@@ -120,11 +167,14 @@ func (h *EnhancedSessionHandler) CreateEnhancedSession(c *gin.Context) {
 	}
 
 	// Record successful login for risk assessment
-	h.riskAssessor.RecordSuccessfulLogin(userID.(uuid.UUID), session.ID, ipAddress, userAgent)
+	h.riskAssessor.RecordSuccessfulLogin(userID, session.ID, ipAddress, userAgent)
 
 	// Update risk metadata
 	h.riskAssessor.UpdateSessionRiskMetadata(session.ID, assessment)
 
+	// Let the user know if this login came from somewhere unfamiliar
+	h.notifyNewLogin(&user, session, assessment, newDevice)
+
 	response := gin.H{
 		"message": "Session created successfully",
 		"session": gin.H{
@@ -150,14 +200,14 @@ func (h *EnhancedSessionHandler) CreateEnhancedSession(c *gin.Context) {
 // GetEnhancedSessions gets all active sessions with detailed information
 func (h *EnhancedSessionHandler) GetEnhancedSessions(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
 	// Get sessions
-	sessions, err := database.GetActiveSessions(h.db, userID.(uuid.UUID))
+	sessions, err := database.GetActiveSessions(h.db, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sessions"})
 		return
@@ -225,7 +275,7 @@ func (h *EnhancedSessionHandler) GetEnhancedSessions(c *gin.Context) {
 // RevokeEnhancedSession revokes a specific session with reason
 func (h *EnhancedSessionHandler) RevokeEnhancedSession(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -264,10 +314,37 @@ func (h *EnhancedSessionHandler) RevokeEnhancedSession(c *gin.Context) {
 	})
 }
 
+// RevokeSessionWithToken revokes a session from the link in its new login email, so a user can
+// sign out a device they don't recognise without signing in themselves
+func (h *EnhancedSessionHandler) RevokeSessionWithToken(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	session, err := database.RevokeSessionWithToken(h.db, req.Token, "Revoked from new login email")
+	if err != nil {
+		if errors.Is(err, database.ErrRevokeTokenInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This link is invalid or the session has already ended"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	log.Printf("Session %s of user %s revoked from new login email", session.ID, session.UserID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked. Please change your password to keep your account secure.",
+	})
+}
+
 // RevokeAllOtherSessions revokes all sessions except the current one
 func (h *EnhancedSessionHandler) RevokeAllOtherSessions(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -291,7 +368,7 @@ func (h *EnhancedSessionHandler) RevokeAllOtherSessions(c *gin.Context) {
 	// Revoke all other sessions
 	if err := database.RevokeAllUserSessionsExcept(
 		h.db, 
-		userID.(uuid.UUID), 
+		userID, 
 		currentSessionID.(uuid.UUID),
 	); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
@@ -305,10 +382,9 @@ func (h *EnhancedSessionHandler) RevokeAllOtherSessions(c *gin.Context) {
 
 // ForceMFAVerification forces MFA verification for all user sessions
 func (h *EnhancedSessionHandler) ForceMFAVerification(c *gin.Context) {
-	// Admin only endpoint
-	isAdmin, exists := c.Get("is_admin")
-	if !exists || !isAdmin.(bool) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+	// Security staff only endpoint
+	if !hasPermission(c, models.PermissionSecurityManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
 
@@ -334,10 +410,9 @@ func (h *EnhancedSessionHandler) ForceMFAVerification(c *gin.Context) {
 
 // ForcePasswordReset forces password reset for all user sessions
 func (h *EnhancedSessionHandler) ForcePasswordReset(c *gin.Context) {
-	// Admin only endpoint
-	isAdmin, exists := c.Get("is_admin")
-	if !exists || !isAdmin.(bool) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+	// Security staff only endpoint
+	if !hasPermission(c, models.PermissionSecurityManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
 
@@ -363,10 +438,9 @@ func (h *EnhancedSessionHandler) ForcePasswordReset(c *gin.Context) {
 
 // SuspendSuspiciousSessions suspends sessions that are deemed suspicious
 func (h *EnhancedSessionHandler) SuspendSuspiciousSessions(c *gin.Context) {
-	// Admin only endpoint
-	isAdmin, exists := c.Get("is_admin")
-	if !exists || !isAdmin.(bool) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+	// Security staff only endpoint
+	if !hasPermission(c, models.PermissionSecurityManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
 
@@ -385,8 +459,7 @@ func (h *EnhancedSessionHandler) SuspendSuspiciousSessions(c *gin.Context) {
 	}
 
 	// Suspend suspicious sessions
-	adminID := c.GetString("user_id")
-	adminUUID, _ := uuid.Parse(adminID)
+	adminUUID, _ := getUserID(c)
 	
 	if err := database.SuspendSuspiciousSessions(h.db, req.UserID, &adminUUID, req.Reason); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suspend suspicious sessions"})
@@ -398,10 +471,72 @@ func (h *EnhancedSessionHandler) SuspendSuspiciousSessions(c *gin.Context) {
 	})
 }
 
+// RevokeSessionsBy revokes every active session created from an IP address or range, or on a
+// trusted device, across all users. It's meant for incidents such as credential stuffing,
+// where the attacker's sessions span many accounts.
+func (h *EnhancedSessionHandler) RevokeSessionsBy(c *gin.Context) {
+	// Security staff only endpoint
+	if !hasPermission(c, models.PermissionSecurityManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	var req struct {
+		IP                string `json:"ip"`                  // Single address or CIDR range
+		TrustedDeviceHash string `json:"trusted_device_hash"` // As stored on the device's sessions
+		Reason            string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	req.IP = strings.TrimSpace(req.IP)
+	req.TrustedDeviceHash = strings.TrimSpace(req.TrustedDeviceHash)
+	if req.IP == "" && req.TrustedDeviceHash == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An IP address, CIDR range or trusted device hash is required"})
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "Revoked by security team"
+	}
+
+	match := database.SessionMatch{TrustedDeviceHash: req.TrustedDeviceHash}
+	if req.IP != "" {
+		network, err := parseRevokeNetwork(req.IP)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		match.Network = network
+	}
+
+	revoked, err := database.RevokeMatchingSessions(h.db, match, req.Reason)
+	adminID, _ := getUserID(c)
+	details := map[string]interface{}{
+		"ip":                  req.IP,
+		"trusted_device_hash": req.TrustedDeviceHash,
+		"reason":              req.Reason,
+		"revoked":             revoked,
+	}
+	if auditErr := h.auditLogger.LogAdminAction(c.Request.Context(), adminID, nil, c.ClientIP(), c.Request.UserAgent(), "revoke_sessions_by", err == nil, details); auditErr != nil {
+		log.Printf("Failed to audit bulk session revocation: %v", auditErr)
+	}
+	if err != nil {
+		log.Printf("Failed to revoke sessions matching %+v: %v", details, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Matching sessions revoked",
+		"revoked": revoked,
+	})
+}
+
 // MarkDeviceAsTrusted marks a device as trusted
 func (h *EnhancedSessionHandler) MarkDeviceAsTrusted(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -421,6 +556,16 @@ func (h *EnhancedSessionHandler) MarkDeviceAsTrusted(c *gin.Context) {
 		return
 	}
 
+	// Revoked sessions can't grant trust, and the token must go to the device the session belongs to
+	if session.Status != database.SessionStatusActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only active sessions can be trusted"})
+		return
+	}
+	if currentSessionID, exists := c.Get("session_id"); exists && currentSessionID != sessionID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only the current device can be marked as trusted"})
+		return
+	}
+
 	// Get device info
 	deviceInfo, err := session.GetDeviceInfo()
 	if err != nil {
@@ -428,6 +573,14 @@ func (h *EnhancedSessionHandler) MarkDeviceAsTrusted(c *gin.Context) {
 		return
 	}
 
+	// The token identifies this device on later logins; only its hash is stored
+	deviceToken, err := utils.GenerateRandomString(48)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate device token"})
+		return
+	}
+	trustedUntil := time.Now().Add(h.trustedDeviceDuration)
+
 	// Mark as trusted
 	deviceInfo.TrustedDevice = true
 	deviceInfo.LastVerifiedAt = time.Now().Format(time.RFC3339)
@@ -446,8 +599,17 @@ func (h *EnhancedSessionHandler) MarkDeviceAsTrusted(c *gin.Context) {
 		return
 	}
 
+	if err := database.TrustSessionDevice(h.db, sessionID, database.HashDeviceToken(deviceToken), trustedUntil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save device info"})
+		return
+	}
+
+	c.SetCookie(trustedDeviceCookie, deviceToken, int(h.trustedDeviceDuration.Seconds()), "/", "", true, true)
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Device marked as trusted",
+		"message":       "Device marked as trusted",
+		"device_token":  deviceToken,
+		"trusted_until": trustedUntil,
 	})
 }
 
@@ -455,46 +617,114 @@ func (h *EnhancedSessionHandler) MarkDeviceAsTrusted(c *gin.Context) {
 
 // detectDeviceType detects the device type from user agent
 func (h *EnhancedSessionHandler) detectDeviceType(userAgent string) string {
-	// Simple detection logic - in production use a proper user agent parser
-	if utils.ContainsAny(userAgent, []string{"iPhone", "iPad", "Android", "Mobile"}) {
-		if utils.ContainsAny(userAgent, []string{"iPad", "Tablet"}) {
-			return "tablet"
-		}
-		return "mobile"
-	}
-	return "desktop"
+	return utils.ParseUserAgent(userAgent).DeviceType
 }
 
 // detectBrowser detects the browser from user agent
 func (h *EnhancedSessionHandler) detectBrowser(userAgent string) string {
-	// Simple detection logic - in production use a proper user agent parser
-	if utils.Contains(userAgent, "Chrome") {
-		return "chrome"
-	} else if utils.Contains(userAgent, "Firefox") {
-		return "firefox"
-	} else if utils.Contains(userAgent, "Safari") {
-		return "safari"
-	} else if utils.Contains(userAgent, "Edge") {
-		return "edge"
-	} else if utils.Contains(userAgent, "Opera") {
-		return "opera"
-	}
-	return "unknown"
+	return utils.ParseUserAgent(userAgent).Browser
 }
 
 // detectOS detects the operating system from user agent
 func (h *EnhancedSessionHandler) detectOS(userAgent string) string {
-	// Simple detection logic - in production use a proper user agent parser
-	if utils.Contains(userAgent, "Windows") {
-		return "windows"
-	} else if utils.Contains(userAgent, "Mac OS") {
-		return "macos"
-	} else if utils.Contains(userAgent, "Linux") {
-		return "linux"
-	} else if utils.Contains(userAgent, "Android") {
-		return "android"
-	} else if utils.ContainsAny(userAgent, []string{"iPhone", "iPad", "iOS"}) {
-		return "ios"
-	}
-	return "unknown"
+	return utils.ParseUserAgent(userAgent).OS
+}
+
+// notifyNewLogin emails the user about a session created from a device they haven't used
+// before or a country they haven't logged in from. Trusted devices are never reported, and
+// once the user has been sent notificationLimit emails within notificationWindow further
+// logins are not reported until the window moves on.
+func (h *EnhancedSessionHandler) notifyNewLogin(user *database.User, session *database.EnhancedSession, assessment *security.RiskAssessment, newDevice bool) {
+	if h.notificationQueue == nil || assessment.TrustedDevice {
+		return
+	}
+	_, newCountry := assessment.Factors["new_country"]
+	_, impossibleTravel := assessment.Factors[security.RiskFactorImpossibleTravel]
+	if !newDevice && !newCountry && !impossibleTravel {
+		return
+	}
+
+	retryAfter, err := database.AuthAttemptRetryAfter(h.db, database.AttemptTypeLoginNotification, user.Email, "", h.notificationLimit, h.notificationWindow)
+	if err != nil {
+		log.Printf("Failed to check login notifications of user %s: %v", user.ID, err)
+		return
+	}
+	if retryAfter > 0 {
+		log.Printf("Not emailing user %s about session %s: login notification limit reached", user.ID, session.ID)
+		return
+	}
+
+	if _, err := database.CreateAuthAttempt(h.db, &user.ID, user.Email, session.IPAddress, session.UserAgent, database.AttemptTypeLoginNotification, true); err != nil {
+		log.Printf("Failed to record login notification for user %s: %v", user.ID, err)
+		return
+	}
+	if _, err := h.notificationQueue.EnqueueJob(jobs.SendLoginNotificationJobType, jobs.SendLoginNotificationPayload{SessionID: session.ID}); err != nil {
+		log.Printf("Failed to queue login notification for session %s: %v", session.ID, err)
+	}
+}
+
+// detectSessionDevice builds the full device description for a session from its user agent
+func detectSessionDevice(userAgent string) *database.SessionDevice {
+	ua := utils.ParseUserAgent(userAgent)
+	return &database.SessionDevice{
+		DeviceType:     ua.DeviceType,
+		DeviceModel:    ua.DeviceModel,
+		Browser:        ua.Browser,
+		BrowserVersion: ua.BrowserVersion,
+		OS:             ua.OS,
+		OSVersion:      ua.OSVersion,
+		TrustedDevice:  false,
+	}
+}
+
+// parseRevokeNetwork parses the address or CIDR range sessions are revoked from, refusing
+// ranges broader than the minimum revoke prefix
+func parseRevokeNetwork(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", value)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR range %q", value)
+	}
+	ones, bits := network.Mask.Size()
+	minPrefix := minRevokePrefixIPv6
+	if bits == 8*net.IPv4len {
+		minPrefix = minRevokePrefixIPv4
+	}
+	if ones < minPrefix {
+		return nil, fmt.Errorf("CIDR range %q is too broad, the prefix must be at least /%d", value, minPrefix)
+	}
+	return network, nil
+}
+
+// trustedDeviceToken returns the trusted device token sent by the client, from the
+// cookie set by MarkDeviceAsTrusted or the X-Device-Token header for non-browser clients
+func trustedDeviceToken(c *gin.Context) string {
+	if token, err := c.Cookie(trustedDeviceCookie); err == nil && token != "" {
+		return token
+	}
+	return c.GetHeader("X-Device-Token")
+}
+
+// setSessionLocation copies a resolved IP location onto the session metadata
+func setSessionLocation(metadata *database.SessionMetadata, location *geoip.Location) {
+	if location == nil {
+		return
+	}
+	metadata.Country = location.Country
+	metadata.CountryCode = location.CountryCode
+	metadata.Region = location.Region
+	metadata.City = location.City
+	metadata.Latitude = location.Latitude
+	metadata.Longitude = location.Longitude
 }
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/jobs"
+	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/security"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/revaspay/backend/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// Logins from new devices are emailed to the user, with a link that revokes the session
+func TestNewLoginNotification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&database.EnhancedSession{}))
+	created := testutil.CreateUser(t, db)
+	user := database.User{ID: created.ID, Email: created.Email}
+
+	chrome := &database.SessionDevice{DeviceType: "desktop", Browser: "Chrome", BrowserVersion: "120", OS: "Windows"}
+	createSession := func(device *database.SessionDevice) *database.EnhancedSession {
+		t.Helper()
+		session, err := database.CreateEnhancedSession(db, uuid.Nil, user.ID, "refresh-"+time.Now().String(), "test-agent", "41.66.0.1", time.Now().Add(time.Hour), device, nil)
+		require.NoError(t, err)
+		return session
+	}
+
+	// The first session and browser updates aren't new devices; another browser is
+	newDevice, err := database.IsNewDevice(db, user.ID, chrome)
+	require.NoError(t, err)
+	assert.False(t, newDevice, "first session")
+	createSession(chrome)
+	newDevice, err = database.IsNewDevice(db, user.ID, &database.SessionDevice{DeviceType: "desktop", Browser: "Chrome", BrowserVersion: "121", OS: "Windows"})
+	require.NoError(t, err)
+	assert.False(t, newDevice, "updated browser")
+	firefox := &database.SessionDevice{DeviceType: "desktop", Browser: "Firefox", OS: "Linux"}
+	newDevice, err = database.IsNewDevice(db, user.ID, firefox)
+	require.NoError(t, err)
+	assert.True(t, newDevice, "other browser")
+
+	queue := new(MockQueue)
+	queue.On("EnqueueJob", jobs.SendLoginNotificationJobType, mock.Anything).Return("job", nil)
+	handler := &EnhancedSessionHandler{db: db, notificationQueue: queue, notificationLimit: 2, notificationWindow: time.Hour}
+	unrecognised := &security.RiskAssessment{Factors: map[string]float64{"new_device": 30}}
+
+	// Known and trusted devices aren't reported, and a login loop is capped at the limit
+	session := createSession(firefox)
+	handler.notifyNewLogin(&user, session, unrecognised, false)
+	handler.notifyNewLogin(&user, session, &security.RiskAssessment{TrustedDevice: true}, true)
+	queue.AssertNotCalled(t, "EnqueueJob", mock.Anything, mock.Anything)
+	for i := 0; i < 3; i++ {
+		handler.notifyNewLogin(&user, session, unrecognised, true)
+	}
+	queue.AssertNumberOfCalls(t, "EnqueueJob", 2)
+	queue.AssertCalled(t, "EnqueueJob", jobs.SendLoginNotificationJobType, jobs.SendLoginNotificationPayload{SessionID: session.ID})
+
+	// The emailed link revokes the session, once
+	token, err := database.IssueSessionRevokeToken(db, session.ID)
+	require.NoError(t, err)
+	revoke := func(token string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/sessions/revoke", bytes.NewBufferString(`{"token":"`+token+`"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.RevokeSessionWithToken(c)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusBadRequest, revoke("not-a-token"))
+	assert.Equal(t, http.StatusOK, revoke(token))
+	assert.Equal(t, http.StatusBadRequest, revoke(token))
+
+	var revoked database.EnhancedSession
+	require.NoError(t, db.First(&revoked, "id = ?", session.ID).Error)
+	assert.Equal(t, database.SessionStatusRevoked, revoked.Status)
+	assert.Empty(t, revoked.RevokeTokenHash)
+}
+
+// Security staff can revoke every user's sessions from an address range or device at once
+func TestRevokeSessionsBy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&database.EnhancedSession{}))
+	handler := &EnhancedSessionHandler{db: db, auditLogger: utils.NewAuditLogger(db)}
+
+	bot := &database.SessionDevice{DeviceType: "desktop", Browser: "HeadlessChrome", OS: "Linux"}
+	createSession := func(ip string, device *database.SessionDevice) *database.EnhancedSession {
+		t.Helper()
+		user := testutil.CreateUser(t, db)
+		session, err := database.CreateEnhancedSession(db, uuid.Nil, user.ID, "refresh-"+ip+"-"+user.ID.String(), "test-agent", ip, time.Now().Add(time.Hour), device, nil)
+		require.NoError(t, err)
+		return session
+	}
+	inRange := createSession("203.0.113.7", nil)
+	alsoInRange := createSession("203.0.113.200", nil)
+	outOfRange := createSession("198.51.100.7", nil)
+	fromBot := createSession("192.0.2.1", bot)
+	sameBrowser := createSession("192.0.2.2", bot)
+	botDevice := database.HashDeviceToken("bot-device-token")
+	require.NoError(t, database.TrustSessionDevice(db, fromBot.ID, botDevice, time.Now().Add(time.Hour)))
+	require.NoError(t, database.TrustSessionDevice(db, sameBrowser.ID, database.HashDeviceToken("other-device-token"), time.Now().Add(time.Hour)))
+
+	revokeBy := func(body string, permitted bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/admin/security/revoke-by", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if permitted {
+			c.Set(middleware.ContextPermissions, map[models.Permission]bool{models.PermissionSecurityManage: true})
+		}
+		handler.RevokeSessionsBy(c)
+		return w
+	}
+	status := func(session *database.EnhancedSession) database.SessionStatus {
+		var reloaded database.EnhancedSession
+		require.NoError(t, db.First(&reloaded, "id = ?", session.ID).Error)
+		return reloaded.Status
+	}
+
+	assert.Equal(t, http.StatusForbidden, revokeBy(`{"ip":"203.0.113.0/24"}`, false).Code)
+	assert.Equal(t, http.StatusBadRequest, revokeBy(`{}`, true).Code)
+	assert.Equal(t, http.StatusBadRequest, revokeBy(`{"ip":"not-an-ip"}`, true).Code)
+	assert.Equal(t, http.StatusBadRequest, revokeBy(`{"ip":"0.0.0.0/0"}`, true).Code, "too broad")
+
+	w := revokeBy(`{"ip":"203.0.113.0/24","reason":"Credential stuffing"}`, true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"revoked":2`)
+	assert.Equal(t, database.SessionStatusRevoked, status(inRange))
+	assert.Equal(t, database.SessionStatusRevoked, status(alsoInRange))
+	assert.Equal(t, database.SessionStatusActive, status(outOfRange))
+
+	// Only the trusted device is matched, not every session with the same type, browser and OS
+	w = revokeBy(`{"trusted_device_hash":`+strconv.Quote(botDevice)+`}`, true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"revoked":1`)
+	assert.Equal(t, database.SessionStatusRevoked, status(fromBot))
+	assert.Equal(t, database.SessionStatusActive, status(sameBrowser))
+	assert.Equal(t, database.SessionStatusActive, status(outOfRange))
+
+	// A single address only matches itself
+	w = revokeBy(`{"ip":"198.51.100.7"}`, true)
+	assert.Contains(t, w.Body.String(), `"revoked":1`)
+	assert.Equal(t, database.SessionStatusRevoked, status(outOfRange))
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/circuitbreaker"
+	"github.com/revaspay/backend/internal/middleware"
+)
+
+// ErrorCode is a stable, machine-readable identifier for an API error. Clients branch on the
+// code; the message is for display and may change.
+type ErrorCode string
+
+// Error codes returned in the error envelope
+const (
+	ErrCodeValidation          ErrorCode = "validation_error"
+	ErrCodeUnauthorized        ErrorCode = "unauthorized"
+	ErrCodeForbidden           ErrorCode = "forbidden"
+	ErrCodeNotFound            ErrorCode = "not_found"
+	ErrCodeConflict            ErrorCode = "conflict"
+	ErrCodeInsufficientFunds   ErrorCode = "insufficient_funds"
+	ErrCodeLimitExceeded       ErrorCode = "limit_exceeded"
+	ErrCodeKYCRequired         ErrorCode = "kyc_required"
+	ErrCodeRateLimited         ErrorCode = "rate_limited"
+	ErrCodeInvalidCredentials  ErrorCode = "invalid_credentials"
+	ErrCodeMFARequired         ErrorCode = "mfa_required"
+	ErrCodePINRequired         ErrorCode = "pin_required"
+	ErrCodeInvalidPIN          ErrorCode = "invalid_pin"
+	ErrCodePINLocked           ErrorCode = "pin_locked"
+	ErrCodeInvalidToken        ErrorCode = "invalid_token"
+	ErrCodeSessionExpired      ErrorCode = "session_expired"
+	ErrCodeAccountSuspended    ErrorCode = "account_suspended"
+	ErrCodePaymentLinkInactive ErrorCode = "payment_link_inactive"
+	ErrCodePayloadTooLarge     ErrorCode = "payload_too_large"
+	ErrCodeInvalidMetadata     ErrorCode = "invalid_metadata"
+	ErrCodeProviderTimeout     ErrorCode = "provider_timeout"
+	ErrCodeProviderUnavailable ErrorCode = "provider_unavailable"
+	ErrCodeInternal            ErrorCode = "internal_error"
+)
+
+// ErrorBody is the body of the error envelope
+type ErrorBody struct {
+	Code    ErrorCode              `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// respondError writes the standard error envelope:
+// {"error": {"code": ..., "message": ..., "details": {...}}}
+func respondError(c *gin.Context, status int, code ErrorCode, message string, details gin.H) {
+	c.JSON(status, gin.H{
+		"error": ErrorBody{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
+	})
+}
+
+// respondBodyError responds to a failure reading the raw request body: 413 when the body was
+// over the route's size limit, 400 otherwise
+func respondBodyError(c *gin.Context, err error) {
+	if middleware.IsBodyTooLarge(err) {
+		respondError(c, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, "request body too large", nil)
+		return
+	}
+	respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid request body", nil)
+}
+
+// respondProviderUnavailable reports a call short-circuited by a provider's open circuit
+// breaker, with a Retry-After header for when the provider will be tried again
+func respondProviderUnavailable(c *gin.Context, err error) {
+	retryAfter := 1
+	if wait, ok := circuitbreaker.RetryAfter(err); ok {
+		retryAfter = int(math.Max(1, math.Ceil(wait.Seconds())))
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	respondError(c, http.StatusServiceUnavailable, ErrCodeProviderUnavailable,
+		"provider is temporarily unavailable, please try again later", gin.H{
+			"retry_after": retryAfter,
+		})
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/circuitbreaker"
+	"github.com/revaspay/backend/internal/services/payment"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespondError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	respondError(c, http.StatusBadRequest, ErrCodeInsufficientFunds, "Insufficient funds", gin.H{"available": 10.5})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var body map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "insufficient_funds", body["error"]["code"])
+	assert.Equal(t, "Insufficient funds", body["error"]["message"])
+	assert.Equal(t, map[string]interface{}{"available": 10.5}, body["error"]["details"])
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
+
+	body = nil
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.NotContains(t, body["error"], "details")
+}
+
+func TestRespondProviderUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	err := fmt.Errorf("%w: %w", payment.ErrProviderUnavailable, &circuitbreaker.OpenError{Name: "payment:paystack", RetryAfter: 1500 * time.Millisecond})
+	respondProviderUnavailable(c, err)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	var body map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "provider_unavailable", body["error"]["code"])
+	assert.Equal(t, map[string]interface{}{"retry_after": float64(2)}, body["error"]["details"])
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/services/featureflags"
+)
+
+// FeatureFlagHandler handles admin management of feature flags
+type FeatureFlagHandler struct {
+	flags *featureflags.Service
+}
+
+// NewFeatureFlagHandler creates a new feature flag handler
+func NewFeatureFlagHandler(flags *featureflags.Service) *FeatureFlagHandler {
+	return &FeatureFlagHandler{flags: flags}
+}
+
+// ListFeatureFlags lists every feature flag and whether it's on
+func (h *FeatureFlagHandler) ListFeatureFlags(c *gin.Context) {
+	flags, err := h.flags.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get feature flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   flags,
+	})
+}
+
+// SetFeatureFlag switches a feature flag on or off at runtime
+func (h *FeatureFlagHandler) SetFeatureFlag(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.flags.SetEnabled(c.Request.Context(), adminID, c.Param("key"), *req.Enabled); err != nil {
+		h.handleFeatureFlagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Feature flag updated",
+	})
+}
+
+// ResetFeatureFlag removes a feature flag's runtime override, so its configured default applies
+func (h *FeatureFlagHandler) ResetFeatureFlag(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.flags.Reset(c.Request.Context(), adminID, c.Param("key")); err != nil {
+		h.handleFeatureFlagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Feature flag reset to its default",
+	})
+}
+
+// handleFeatureFlagError maps feature flag errors to HTTP responses
+func (h *FeatureFlagHandler) handleFeatureFlagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, featureflags.ErrUnknownFlag):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update feature flag"})
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/revaspay/backend/internal/circuitbreaker"
+	"gorm.io/gorm"
+)
+
+const (
+	// healthCheckTimeout bounds each dependency check so a hung dependency can't hang the probe
+	healthCheckTimeout = 2 * time.Second
+
+	// queueStaleAfter is how long the queue processor can go without polling before it's
+	// considered dead. Jobs run inline, so this has to allow for a slow job.
+	queueStaleAfter = 2 * time.Minute
+)
+
+// QueueProcessor is a background job processor whose liveness can be checked
+type QueueProcessor interface {
+	LastPoll() time.Time
+}
+
+// HealthHandler serves liveness and readiness probes
+type HealthHandler struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	processor   QueueProcessor
+}
+
+// NewHealthHandler creates a new health handler. redisClient and processor may be nil
+// when the instance doesn't use them, in which case they aren't checked.
+func NewHealthHandler(db *gorm.DB, redisClient *redis.Client, processor QueueProcessor) *HealthHandler {
+	return &HealthHandler{
+		db:          db,
+		redisClient: redisClient,
+		processor:   processor,
+	}
+}
+
+// Live reports that the process is up without touching any dependencies
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready checks the database, Redis and the queue processor, returning 503 and the failed
+// checks if any of them are down. Provider circuit breakers are reported too, but an open
+// breaker doesn't make the instance unready since every instance shares the same providers.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	checks := gin.H{}
+	ready := true
+	record := func(name string, err error) {
+		if err != nil {
+			ready = false
+			checks[name] = gin.H{"status": "down", "error": err.Error()}
+			return
+		}
+		checks[name] = gin.H{"status": "up"}
+	}
+
+	record("database", h.checkDatabase(c.Request.Context()))
+	if h.redisClient != nil {
+		record("redis", h.checkRedis(c.Request.Context()))
+	}
+	if h.processor != nil {
+		record("queue", h.checkQueue())
+	}
+
+	breakers := circuitbreaker.Snapshot()
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks, "circuit_breakers": breakers})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks, "circuit_breakers": breakers})
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	var result int
+	if err := h.db.WithContext(ctx).Raw("SELECT 1").Scan(&result).Error; err != nil {
+		// The probe is public, so keep connection details out of the response
+		log.Printf("Readiness check: database ping failed: %v", err)
+		return errors.New("ping failed")
+	}
+	return nil
+}
+
+func (h *HealthHandler) checkRedis(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	if err := h.redisClient.Ping(ctx).Err(); err != nil {
+		log.Printf("Readiness check: redis ping failed: %v", err)
+		return errors.New("ping failed")
+	}
+	return nil
+}
+
+func (h *HealthHandler) checkQueue() error {
+	lastPoll := h.processor.LastPoll()
+	if lastPoll.IsZero() {
+		return errors.New("processor has not started")
+	}
+	if since := time.Since(lastPoll); since > queueStaleAfter {
+		return fmt.Errorf("processor last polled %s ago", since.Round(time.Second))
+	}
+	return nil
+}
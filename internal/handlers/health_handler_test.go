@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProcessor is a queue processor that last polled at lastPoll
+type fakeProcessor struct {
+	lastPoll time.Time
+}
+
+func (p *fakeProcessor) LastPoll() time.Time { return p.lastPoll }
+
+// The readiness probe fails, naming the check, when the database, Redis or the queue
+// processor is down, while the liveness probe keeps passing
+func TestHealthHandlerReady(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t)
+	redisServer := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: redisServer.Addr(), MaxRetries: -1})
+	defer redisClient.Close()
+	processor := &fakeProcessor{lastPoll: time.Now()}
+	handler := NewHealthHandler(db, redisClient, processor)
+
+	probe := func(handle gin.HandlerFunc) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/health", nil)
+		handle(c)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+	status := func(body map[string]interface{}, check string) interface{} {
+		return body["checks"].(map[string]interface{})[check].(map[string]interface{})["status"]
+	}
+
+	code, body := probe(handler.Ready)
+	assert.Equal(t, http.StatusOK, code)
+	for _, check := range []string{"database", "redis", "queue"} {
+		assert.Equal(t, "up", status(body, check), check)
+	}
+
+	processor.lastPoll = time.Now().Add(-time.Hour)
+	code, body = probe(handler.Ready)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "down", status(body, "queue"))
+	assert.Equal(t, "up", status(body, "database"))
+
+	processor.lastPoll = time.Now()
+	redisServer.Close()
+	code, body = probe(handler.Ready)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "down", status(body, "redis"))
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+	code, body = probe(handler.Ready)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "down", status(body, "database"))
+	assert.Equal(t, "ping failed", body["checks"].(map[string]interface{})["database"].(map[string]interface{})["error"], "connection details aren't exposed")
+
+	code, _ = probe(handler.Live)
+	assert.Equal(t, http.StatusOK, code)
+
+	// Dependencies an instance doesn't use aren't checked
+	code, body = probe(NewHealthHandler(testutil.NewDB(t), nil, nil).Ready)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotContains(t, body["checks"], "redis")
+	assert.NotContains(t, body["checks"], "queue")
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/pagination"
+	"github.com/revaspay/backend/internal/queue"
 	"github.com/revaspay/backend/internal/services/compliance"
+	"github.com/revaspay/backend/internal/services/crypto"
 	"github.com/revaspay/backend/internal/services/payment"
+	"github.com/revaspay/backend/internal/services/screening"
 	"gorm.io/gorm"
 )
 
@@ -18,10 +23,10 @@ type InternationalPaymentHandler struct {
 }
 
 // NewInternationalPaymentHandler creates a new international payment handler
-func NewInternationalPaymentHandler(db *gorm.DB) *InternationalPaymentHandler {
+func NewInternationalPaymentHandler(db *gorm.DB, jobQueue *queue.Queue, screeningSvc *screening.Service) *InternationalPaymentHandler {
 	return &InternationalPaymentHandler{
 		db:                db,
-		paymentService:    payment.NewInternationalPaymentService(db),
+		paymentService:    payment.NewInternationalPaymentService(db, jobQueue, screeningSvc),
 		complianceService: compliance.NewGhanaComplianceService(db),
 	}
 }
@@ -29,7 +34,7 @@ func NewInternationalPaymentHandler(db *gorm.DB) *InternationalPaymentHandler {
 // InitiatePayment initiates an international payment
 func (h *InternationalPaymentHandler) InitiatePayment(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -39,6 +44,7 @@ func (h *InternationalPaymentHandler) InitiatePayment(c *gin.Context) {
 	var req struct {
 		VendorName    string  `json:"vendor_name" binding:"required"`
 		VendorAddress string  `json:"vendor_address" binding:"required"`
+		Network       string  `json:"network"`
 		Amount        float64 `json:"amount" binding:"required,gt=0"`
 		Description   string  `json:"description"`
 	}
@@ -48,8 +54,18 @@ func (h *InternationalPaymentHandler) InitiatePayment(c *gin.Context) {
 		return
 	}
 
+	// Reject malformed vendor addresses before running compliance checks
+	network := req.Network
+	if network == "" {
+		network = crypto.NetworkBase
+	}
+	if err := crypto.ValidateAddress(network, req.VendorAddress); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Validate transaction with compliance service
-	passed, checks, err := h.complianceService.ValidateTransaction(userID.(uuid.UUID), req.Amount, "international_payment", req.VendorName, req.VendorAddress)
+	passed, checks, err := h.complianceService.ValidateTransaction(userID, req.Amount, "international_payment", req.VendorName, req.VendorAddress)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Compliance check failed: " + err.Error()})
 		return
@@ -65,21 +81,30 @@ func (h *InternationalPaymentHandler) InitiatePayment(c *gin.Context) {
 	}
 
 	// Create payment request
-	paymentReq := payment.PaymentRequest{
+	paymentReq := payment.CreatePaymentRequest{
 		VendorName:    req.VendorName,
 		VendorAddress: req.VendorAddress,
-		Amount:        req.Amount,
+		Network:       network,
+		AmountCedis:   req.Amount,
 		Description:   req.Description,
 	}
 
-	// Process payment
-	payment, err := h.paymentService.ProcessPayment(userID.(uuid.UUID), paymentReq)
+	// Create the payment and queue it for processing
+	payment, err := h.paymentService.Create(c.Request.Context(), userID, paymentReq)
+	if errors.Is(err, screening.ErrAddressBlocked) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "blocked",
+			"message": err.Error(),
+			"data":    payment,
+		})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.handlePaymentError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusCreated, gin.H{
 		"status":  "success",
 		"message": "International payment initiated successfully",
 		"data":    payment,
@@ -89,29 +114,32 @@ func (h *InternationalPaymentHandler) InitiatePayment(c *gin.Context) {
 // GetPayments retrieves all international payments for a user
 func (h *InternationalPaymentHandler) GetPayments(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
+	params := pagination.ParseParams(c, 20)
+
 	// Get payments
-	payments, err := h.paymentService.GetPayments(userID.(uuid.UUID))
+	payments, total, err := h.paymentService.GetPayments(userID, params.Page, params.PageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get international payments"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   payments,
+		"status":     "success",
+		"data":       payments,
+		"pagination": pagination.NewMeta(params, total),
 	})
 }
 
 // GetPayment retrieves a specific international payment
 func (h *InternationalPaymentHandler) GetPayment(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -125,15 +153,9 @@ func (h *InternationalPaymentHandler) GetPayment(c *gin.Context) {
 	}
 
 	// Get payment
-	payment, err := h.paymentService.GetPayment(paymentID)
+	payment, err := h.paymentService.GetPayment(paymentID, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Check if payment belongs to user
-	if payment.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view this payment"})
+		h.handlePaymentError(c, err)
 		return
 	}
 
@@ -143,10 +165,10 @@ func (h *InternationalPaymentHandler) GetPayment(c *gin.Context) {
 	})
 }
 
-// GetComplianceReport generates a compliance report for a payment
+// GetComplianceReport returns the compliance report for a payment as JSON, or as a PDF with ?format=pdf
 func (h *InternationalPaymentHandler) GetComplianceReport(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -159,28 +181,30 @@ func (h *InternationalPaymentHandler) GetComplianceReport(c *gin.Context) {
 		return
 	}
 
-	// Get payment
-	payment, err := h.paymentService.GetPayment(paymentID)
+	// Reports are generated on first access if the processing job hasn't produced one yet
+	report, err := h.complianceService.GetPaymentReport(paymentID, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, compliance.ErrPaymentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get compliance report"})
 		return
 	}
 
-	// Check if payment belongs to user
-	if payment.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view this payment"})
-		return
-	}
+	writeComplianceReport(c, report)
+}
 
-	// Generate compliance report
-	report, err := h.complianceService.GenerateComplianceReport(userID.(uuid.UUID), paymentID, "international_payment")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate compliance report: " + err.Error()})
-		return
+// handlePaymentError maps international payment service errors to HTTP responses
+func (h *InternationalPaymentHandler) handlePaymentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, payment.ErrPaymentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, crypto.ErrInvalidAddress), errors.Is(err, crypto.ErrUnsupportedNetwork):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, payment.ErrNoBankAccount), errors.Is(err, payment.ErrNoCryptoWallet):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process international payment request"})
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"status": "success",
-		"data":   report,
-	})
 }
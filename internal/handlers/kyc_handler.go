@@ -1,14 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
 
-	"math"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strconv"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,128 +16,84 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/jobs"
+	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/pagination"
+	"github.com/revaspay/backend/internal/queue"
 	"github.com/revaspay/backend/internal/services/kyc"
+	"github.com/revaspay/backend/internal/services/storage"
+	"github.com/revaspay/backend/internal/utils"
 )
 
-// KYCStatus represents the status of a KYC verification
-type KYCStatus string
-
-const (
-	KYCStatusNotSubmitted KYCStatus = "not_submitted"
-	KYCStatusPending      KYCStatus = "pending"
-	KYCStatusApproved     KYCStatus = "approved"
-	KYCStatusRejected     KYCStatus = "rejected"
-)
-
-// KYCSubmission represents a KYC verification submission
-type KYCSubmission struct {
-	ID               string    `json:"id"`
-	UserID           string    `json:"user_id"`
-	IDDocumentPath   string    `json:"id_document_path"`
-	SelfiePath       string    `json:"selfie_path"`
-	AddressProofPath string    `json:"address_proof_path,omitempty"`
-	Status           KYCStatus `json:"status"`
-	SubmittedAt      time.Time `json:"submitted_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
-	Notes            string    `json:"notes,omitempty"`
-}
-
 // KYCHandler handles KYC verification related requests
 type KYCHandler struct {
-	DB          *gorm.DB
-	DiditService *kyc.DiditService
-	UploadsDir  string
+	DB            *gorm.DB
+	SmileProvider *kyc.SmileProvider
+	DiditService  *kyc.DiditService
+	Storage       storage.Backend
+	Validator     *kyc.DocumentValidator
+	jobQueue      jobEnqueuer
+	auditLogger   *utils.AuditLogger
 }
 
-// NewKYCHandler creates a new KYC handler
-func NewKYCHandler(db *gorm.DB) *KYCHandler {
-	// Ensure uploads directory exists
-	uploadsDir := filepath.Join("uploads", "kyc")
-	os.MkdirAll(uploadsDir, 0755)
-
+// NewKYCHandler creates a new KYC handler. Documents are kept in store, and submitted
+// verifications are sent to the provider by a KYC verification job on jobQueue.
+func NewKYCHandler(db *gorm.DB, jobQueue *queue.Queue, store storage.Backend) *KYCHandler {
 	// Create Didit service
-	diditService, err := kyc.NewDiditService(db)
+	diditService, err := kyc.NewDiditService(db, store)
 	if err != nil {
 		// Log the error but continue - service will handle errors gracefully
 		fmt.Printf("Error initializing Didit service: %v\n", err)
 	}
 
 	return &KYCHandler{
-		DB:          db,
-		DiditService: diditService,
-		UploadsDir:  uploadsDir,
+		DB:            db,
+		SmileProvider: kyc.NewSmileProvider(db, store),
+		DiditService:  diditService,
+		Storage:       store,
+		Validator:     kyc.DefaultDocumentValidator(),
+		jobQueue:      jobQueue,
+		auditLogger:   utils.NewAuditLogger(db),
 	}
 }
 
 // GetKYCStatus returns the KYC status for a user
 func (h *KYCHandler) GetKYCStatus(c *gin.Context) {
 	// Get the user ID from the JWT token
-	userIDStr := c.GetString("user_id")
-	if userIDStr == "" {
+	userID, exists := getUserID(c)
+	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr)
+	// The latest verification is reported the same way whichever provider ran it
+	status, err := kyc.GetUserStatus(h.DB, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch KYC status"})
 		return
 	}
 
-	// Fetch the KYC status from the database
-	var kyc database.KYC
-	result := h.DB.Where("user_id = ?", userID).First(&kyc)
-
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			// No KYC record found, create a new one with status "not_submitted"
-			kyc = database.KYC{
-				UserID: userID,
-				Status: string(database.KYCStatusNotSubmitted),
-			}
-			h.DB.Create(&kyc)
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch KYC status"})
-			return
-		}
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"id":               kyc.ID,
-		"user_id":          kyc.UserID,
-		"status":           kyc.Status,
-		"id_type":          kyc.IDType,
-		"id_number":        kyc.IDNumber,
-		"id_front_url":     kyc.IDFrontURL,
-		"id_back_url":      kyc.IDBackURL,
-		"selfie_url":       kyc.SelfieURL,
-		"submitted_at":     kyc.CreatedAt,
-		"updated_at":       kyc.UpdatedAt,
-		"verified_at":      kyc.VerifiedAt,
-		"rejection_reason": kyc.RejectionReason,
-	})
+	c.JSON(http.StatusOK, status)
 }
 
 // SubmitKYC handles KYC document submission
 func (h *KYCHandler) SubmitKYC(c *gin.Context) {
 	// Get the user ID from the JWT token
-	userIDStr := c.GetString("user_id")
-	if userIDStr == "" {
+	userID, exists := getUserID(c)
+	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr)
+	// Check if user already has a verification in progress or approved
+	existing, err := kyc.GetActiveVerification(h.DB, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing verifications"})
 		return
 	}
-
-	// Check if user already has a KYC submission
-	var existingKYC database.KYC
-	result := h.DB.Where("user_id = ?", userID).First(&existingKYC)
-	if result.Error == nil && existingKYC.Status != string(database.KYCStatusRejected) && existingKYC.Status != string(database.KYCStatusNotSubmitted) {
-		c.JSON(http.StatusConflict, gin.H{"error": "KYC verification already in progress or approved"})
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": kyc.ErrVerificationActive.Error()})
 		return
 	}
 
@@ -179,151 +135,205 @@ func (h *KYCHandler) SubmitKYC(c *gin.Context) {
 	}
 
 	// Validate document type
-	var docType database.DocumentType
-	switch documentType {
-	case "passport":
-		docType = database.DocumentTypePassport
-	case "id_card":
-		docType = database.DocumentTypeIDCard
-	case "drivers_license":
-		docType = database.DocumentTypeDriversLicense
-	default:
+	docType, err := kyc.ParseDocumentType(documentType)
+	if err != nil || docType == models.DocumentTypeSelfie || docType == models.DocumentTypeProofOfAddress {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document type"})
 		return
 	}
 
-	// Create uploads directory if it doesn't exist
-	uploadsDir := filepath.Join(h.UploadsDir, userID.String())
-	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
-		return
+	var dob *time.Time
+	if parsed, err := time.Parse("2006-01-02", dateOfBirth); err == nil {
+		dob = &parsed
+	}
+
+	// Validate and store each document, removing the ones already stored if a later step fails
+	ctx := c.Request.Context()
+	var storedDocuments []*kyc.StoredDocument
+	submitted := false
+	defer func() {
+		if !submitted {
+			for _, stored := range storedDocuments {
+				if err := h.Storage.Delete(context.Background(), stored.Key); err != nil {
+					log.Printf("Failed to remove KYC document %s: %v", stored.Key, err)
+				}
+			}
+		}
+	}()
+	saveDocument := func(field, prefix string) (*kyc.StoredDocument, error) {
+		file, _, err := c.Request.FormFile(field)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		key := kycDocumentKey(userID, fmt.Sprintf("%s_%s", prefix, uuid.New().String()))
+		stored, err := h.Validator.Store(ctx, h.Storage, file, key)
+		if err != nil {
+			return nil, err
+		}
+		storedDocuments = append(storedDocuments, stored)
+		return stored, nil
 	}
 
 	// Process ID document front
-	idDocumentFront, err := c.FormFile("id_document_front")
+	idDocumentFront, err := saveDocument("id_document_front", "id_front")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID document front is required"})
-		return
-	}
-	idDocumentFrontPath := filepath.Join(uploadsDir, fmt.Sprintf("id_front_%s%s", uuid.New().String(), filepath.Ext(idDocumentFront.Filename)))
-	if err := c.SaveUploadedFile(idDocumentFront, idDocumentFrontPath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save ID document front"})
+		respondDocumentError(c, "ID document front", err)
 		return
 	}
 
 	// Process ID document back (optional for passport)
-	var idDocumentBackPath string
-	if docType != database.DocumentTypePassport {
-		idDocumentBack, err := c.FormFile("id_document_back")
-		if err != nil {
+	var idDocumentBack *kyc.StoredDocument
+	if docType != models.DocumentTypePassport {
+		idDocumentBack, err = saveDocument("id_document_back", "id_back")
+		if errors.Is(err, http.ErrMissingFile) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "ID document back is required for ID card and driver's license"})
 			return
 		}
-		idDocumentBackPath = filepath.Join(uploadsDir, fmt.Sprintf("id_back_%s%s", uuid.New().String(), filepath.Ext(idDocumentBack.Filename)))
-		if err := c.SaveUploadedFile(idDocumentBack, idDocumentBackPath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save ID document back"})
+		if err != nil {
+			respondDocumentError(c, "ID document back", err)
 			return
 		}
 	}
 
 	// Process selfie
-	selfie, err := c.FormFile("selfie")
+	selfie, err := saveDocument("selfie", "selfie")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Selfie is required"})
+		respondDocumentError(c, "Selfie", err)
 		return
 	}
-	selfiePath := filepath.Join(uploadsDir, fmt.Sprintf("selfie_%s%s", uuid.New().String(), filepath.Ext(selfie.Filename)))
-	if err := c.SaveUploadedFile(selfie, selfiePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save selfie"})
+
+	// Process address proof (optional)
+	addressProof, err := saveDocument("address_proof", "address_proof")
+	if err != nil && !errors.Is(err, http.ErrMissingFile) {
+		respondDocumentError(c, "Address proof", err)
 		return
 	}
 
-	// Process address proof (optional)
-	var addressProofPath string
-	addressProof, err := c.FormFile("address_proof")
-	if err == nil {
-		addressProofPath = filepath.Join(uploadsDir, fmt.Sprintf("address_proof_%s%s", uuid.New().String(), filepath.Ext(addressProof.Filename)))
-		if err := c.SaveUploadedFile(addressProof, addressProofPath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save address proof"})
-			return
-		}
+	// Record the verification and its documents with Smile Identity
+	verification, err := h.SmileProvider.CreateSession(c.Request.Context(), userID, kyc.SessionRequest{
+		FullName:    fullName,
+		DateOfBirth: dob,
+		Address:     address,
+		Country:     country,
+		IDDocType:   &docType,
+		IDDocNumber: c.PostForm("id_number"),
+	})
+	if errors.Is(err, kyc.ErrVerificationActive) {
+		// A concurrent submission got there first
+		c.JSON(http.StatusConflict, gin.H{"error": kyc.ErrVerificationActive.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create KYC verification"})
+		return
 	}
 
-	// Create or update KYC record
-	kycID := uuid.New()
-	if result.Error == nil {
-		kycID = existingKYC.ID
+	documents := []struct {
+		docType models.DocumentType
+		stored  *kyc.StoredDocument
+	}{
+		{docType, idDocumentFront},
+		{docType, idDocumentBack},
+		{models.DocumentTypeSelfie, selfie},
+		{models.DocumentTypeProofOfAddress, addressProof},
+	}
+	for _, document := range documents {
+		if document.stored == nil {
+			continue
+		}
+		if _, err := h.SmileProvider.UploadDocument(verification.ID, document.docType, document.stored); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save KYC documents"})
+			return
+		}
 	}
 
-	// ID number is already captured in the form data and used in the KYC struct
+	submitted = true
 
-	// Create KYC record in database
-	kyc := database.KYC{
-		ID:              kycID,
-		UserID:          userID,
-		Status:          string(database.KYCStatusPending),
-		IDType:          string(docType),
-		IDNumber:        c.PostForm("id_number"),
-		IDFrontURL:      idDocumentFrontPath,
-		IDBackURL:       idDocumentBackPath,
-		SelfieURL:       selfiePath,
+	// Submit to Smile Identity in the background so the submission is retried and survives restarts.
+	// If queueing fails the verification stays pending and still appears in the admin review queue.
+	if _, err := h.jobQueue.EnqueueJob(queue.JobType(jobs.KYCVerificationJobType), jobs.KYCVerificationJobPayload{
+		VerificationID: verification.ID,
+	}); err != nil {
+		log.Printf("Failed to enqueue KYC verification job for %s: %v", verification.ID, err)
 	}
 
-	// Save to database
-	if result.Error == nil {
-		// Update existing record
-		h.DB.Model(&kyc).Updates(kyc)
-	} else {
-		// Create new record
-		h.DB.Create(&kyc)
-	}
-
-	// Submit to Didit for verification (in a production environment, this would be done asynchronously)
-	go func() {
-		// This would be handled by a background job in production
-		// In a real implementation, we would call the Didit service
-		// For now, we'll just log it
-		fmt.Printf("Would submit KYC verification for user %s at %s\n", userID, time.Now().Format(time.RFC3339))
-		
-		// Note: The Didit service integration is used in the KYC verification job
-		// to process the verification asynchronously
-	}()
-
 	c.JSON(http.StatusOK, gin.H{
-		"message": "KYC documents submitted successfully",
-		"kyc_id":  kyc.ID,
-		"status":  kyc.Status,
+		"message":         "KYC documents submitted successfully",
+		"kyc_id":          verification.ID,
+		"verification_id": verification.ID,
+		"provider":        verification.Provider,
+		"status":          verification.Status,
 	})
 }
 
+// kycDocumentKey returns the storage key (without extension) for a user's KYC document
+func kycDocumentKey(userID uuid.UUID, name string) string {
+	return path.Join("kyc", userID.String(), name)
+}
+
+// signedDocuments lists documents for an admin with a short-lived signed URL for each.
+// Storage keys are never included.
+func signedDocuments(ctx context.Context, store storage.Backend, documents []models.KYCDocument) []gin.H {
+	signed := make([]gin.H, 0, len(documents))
+	for _, document := range documents {
+		entry := gin.H{
+			"id":          document.ID,
+			"type":        document.Type,
+			"file_name":   document.FileName,
+			"file_size":   document.FileSize,
+			"mime_type":   document.MimeType,
+			"uploaded_at": document.UploadedAt,
+		}
+		if url, err := store.SignedURL(ctx, document.StorageKey, storage.DefaultSignedURLExpiry); err != nil {
+			log.Printf("Failed to sign URL for KYC document %s: %v", document.ID, err)
+		} else {
+			entry["url"] = url
+			entry["url_expires_at"] = time.Now().Add(storage.DefaultSignedURLExpiry)
+		}
+		signed = append(signed, entry)
+	}
+	return signed
+}
+
+// respondDocumentError reports why an uploaded KYC document was rejected
+func respondDocumentError(c *gin.Context, label string, err error) {
+	switch {
+	case errors.Is(err, http.ErrMissingFile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": label + " is required"})
+	case errors.Is(err, kyc.ErrDocumentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": label + " is too large"})
+	case errors.Is(err, kyc.ErrUnsupportedDocument):
+		c.JSON(http.StatusBadRequest, gin.H{"error": label + " must be a JPEG, PNG or PDF file"})
+	case errors.Is(err, kyc.ErrDocumentInfected):
+		c.JSON(http.StatusBadRequest, gin.H{"error": label + " failed the malware scan"})
+	default:
+		log.Printf("Failed to save KYC document %s: %v", label, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save " + strings.ToLower(label)})
+	}
+}
+
 // UpdateKYCStatus updates the status of a KYC submission (admin only)
 func (h *KYCHandler) UpdateKYCStatus(c *gin.Context) {
-	// Check if the user is an admin
-	isAdmin := c.GetBool("is_admin")
-	if !isAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+	// Check the user may review KYC submissions
+	if !hasPermission(c, models.PermissionKYCApprove) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
 
 	// Get admin user ID for audit trail
-	adminIDStr := c.GetString("user_id")
-	if adminIDStr == "" {
+	adminID, exists := getUserID(c)
+	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin ID not found"})
 		return
 	}
 
-	adminID, err := uuid.Parse(adminIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
-		return
-	}
-
 	// Check if data was passed from ApproveKYC or RejectKYC methods
 	var request struct {
-		KYCID           string             `json:"kyc_id" binding:"required"`
-		Status          database.KYCStatus `json:"status" binding:"required"`
-		RejectionReason string             `json:"rejection_reason"`
-		Notes           string             `json:"notes"`
+		KYCID           string           `json:"kyc_id" binding:"required"`
+		Status          models.KYCStatus `json:"status" binding:"required"`
+		RejectionReason string           `json:"rejection_reason"`
+		Notes           string           `json:"notes"`
 	}
 
 	// Check if data was passed from ApproveKYC or RejectKYC methods
@@ -338,7 +348,7 @@ func (h *KYCHandler) UpdateKYCStatus(c *gin.Context) {
 
 		// Extract data from the context
 		request.KYCID = data["kyc_id"].(string)
-		request.Status = data["status"].(database.KYCStatus)
+		request.Status = data["status"].(models.KYCStatus)
 		request.Notes = data["notes"].(string)
 
 		// Extract rejection reason if present
@@ -353,12 +363,6 @@ func (h *KYCHandler) UpdateKYCStatus(c *gin.Context) {
 		}
 	}
 
-	// Validate status
-	if request.Status != database.KYCStatusApproved && request.Status != database.KYCStatusRejected && request.Status != database.KYCStatusPending {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
-		return
-	}
-
 	// Parse KYC ID
 	kycID, err := uuid.Parse(request.KYCID)
 	if err != nil {
@@ -366,78 +370,42 @@ func (h *KYCHandler) UpdateKYCStatus(c *gin.Context) {
 		return
 	}
 
-	// Get KYC record from database
-	var kyc database.KYC
-	result := h.DB.First(&kyc, kycID)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+	// Update the verification and record the change in its history
+	verification, err := kyc.ReviewVerification(h.DB, kycID, request.Status, adminID, request.RejectionReason, request.Notes)
+	if err != nil {
+		switch {
+		case errors.Is(err, kyc.ErrInvalidReviewStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		case errors.Is(err, kyc.ErrRejectionReasonRequired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Rejection reason is required when rejecting KYC"})
+		case errors.Is(err, kyc.ErrStatusUnchanged):
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("KYC is already %s", request.Status)})
+		case errors.Is(err, kyc.ErrVerificationNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "KYC record not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch KYC record"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update KYC status"})
 		}
 		return
 	}
 
-	// Check if KYC is in a state that can be updated
-	if kyc.Status == string(database.KYCStatusNotSubmitted) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot update KYC that has not been submitted"})
-		return
-	}
-
-	// Get previous status for history
-	previousStatus := kyc.Status
-
-	// Set verification timestamp
-	now := time.Now()
-
-	// Update KYC record
-	updates := database.KYC{
-		Status:     string(request.Status),
-		VerifiedAt: &now,
-	}
-
-	// Add rejection reason if status is rejected
-	if request.Status == database.KYCStatusRejected {
-		if request.RejectionReason == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Rejection reason is required when rejecting KYC"})
-			return
-		}
-		updates.RejectionReason = request.RejectionReason
-	}
-
-	// Update the KYC record
-	h.DB.Model(&kyc).Updates(updates)
-
-	// Create KYC history record for audit trail
-	kycHistory := database.KYCHistory{
-		KYCID:          kycID,
-		PreviousStatus: database.KYCStatus(previousStatus),
-		NewStatus:      request.Status,
-		Comment:        request.Notes,
-		ChangedBy:      adminID,
-		CreatedAt:      now,
-	}
-
-	h.DB.Create(&kycHistory)
-
 	// If status is approved, trigger any post-approval processes
-	if request.Status == database.KYCStatusApproved {
+	if verification.Status == models.KYCStatusApproved {
 		// In a real application, we might want to notify the user
 		// or trigger other processes like wallet activation
-		go h.handleKYCApproval(kyc)
+		go h.handleKYCApproval(*verification)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":     "KYC status updated successfully",
-		"kyc_id":      kyc.ID,
-		"status":      request.Status,
+		"kyc_id":      verification.ID,
+		"status":      verification.Status,
 		"verified_by": adminID,
-		"verified_at": now,
+		"verified_at": verification.VerifiedAt,
 	})
 }
 
 // handleKYCApproval handles any post-approval processes
-func (h *KYCHandler) handleKYCApproval(kyc database.KYC) {
+func (h *KYCHandler) handleKYCApproval(verification models.KYCVerification) {
 	// In a real application, this would:
 	// 1. Send notification to the user
 	// 2. Activate the user's wallet or other features
@@ -445,7 +413,7 @@ func (h *KYCHandler) handleKYCApproval(kyc database.KYC) {
 	// 4. Log the approval in an audit system
 
 	// For now, we'll just log it
-	fmt.Printf("KYC approved for user %s at %s\n", kyc.UserID, time.Now().Format(time.RFC3339))
+	fmt.Printf("KYC approved for user %s at %s\n", verification.UserID, time.Now().Format(time.RFC3339))
 }
 
 // HandleDiditWebhook processes callbacks from Didit
@@ -459,21 +427,24 @@ func (h *KYCHandler) HandleDiditWebhook(c *gin.Context) {
 	// Get signature from header
 	signature := c.GetHeader("X-Didit-Signature")
 	if signature == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing webhook signature"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing webhook signature"})
 		return
 	}
 
 	// Read request body
 	payload, err := c.GetRawData()
 	if err != nil {
+		if middleware.IsBodyTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
 		return
 	}
 
 	// Process webhook using Didit service
-	err = h.DiditService.ProcessWebhook(payload, signature)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to process webhook: %v", err)})
+	if err := h.DiditService.ProcessWebhook(payload, signature); err != nil {
+		respondDiditWebhookError(c, err)
 		return
 	}
 
@@ -483,38 +454,66 @@ func (h *KYCHandler) HandleDiditWebhook(c *gin.Context) {
 	})
 }
 
-// GetPendingKYC returns all pending KYC submissions for admin review
-func (h *KYCHandler) GetPendingKYC(c *gin.Context) {
-	// Check if the user is an admin
-	isAdmin := c.GetBool("is_admin")
-	if !isAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-		return
+// respondDiditWebhookError maps a Didit webhook failure to a status telling Didit whether to
+// retry: 4xx for deliveries that will never succeed, 500 for transient failures
+func respondDiditWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, kyc.ErrInvalidWebhookSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+	case errors.Is(err, kyc.ErrInvalidWebhookPayload):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"})
+	case errors.Is(err, kyc.ErrVerificationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Verification not found"})
+	default:
+		log.Printf("Failed to process Didit webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
 	}
+}
 
-	// Get pagination parameters
-	page := 1
-	pageSize := 10
-
-	// Parse query parameters if provided
-	pageStr := c.DefaultQuery("page", "1")
-	pageSizeStr := c.DefaultQuery("page_size", "10")
+// HandleSmileWebhook processes job results from Smile Identity
+func (h *KYCHandler) HandleSmileWebhook(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		if middleware.IsBodyTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
 
-	// Convert to integers
-	if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-		page = p
+	// Smile signs the callback inside the payload
+	if err := h.SmileProvider.ProcessWebhook(payload, ""); err != nil {
+		switch {
+		case errors.Is(err, kyc.ErrInvalidWebhookSignature):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+		case errors.Is(err, kyc.ErrVerificationNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Verification not found"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to process webhook: %v", err)})
+		}
+		return
 	}
 
-	if ps, err := strconv.Atoi(pageSizeStr); err == nil && ps > 0 && ps <= 100 {
-		pageSize = ps
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook processed successfully",
+	})
+}
+
+// GetPendingKYC returns all pending KYC submissions for admin review
+func (h *KYCHandler) GetPendingKYC(c *gin.Context) {
+	// Check the user may view KYC submissions
+	if !hasPermission(c, models.PermissionKYCView) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
 	}
 
-	// Calculate offset
-	offset := (page - 1) * pageSize
+	params := pagination.ParseParams(c, 10)
 
-	// Get pending KYC submissions
-	var kycSubmissions []database.KYC
-	result := h.DB.Where("status = ?", string(database.KYCStatusPending)).Order("created_at desc").Offset(offset).Limit(pageSize).Find(&kycSubmissions)
+	// Get pending KYC submissions from every provider
+	pendingStatuses := []models.KYCStatus{models.KYCStatusPending, models.KYCStatusInProgress}
+	var kycSubmissions []models.KYCVerification
+	result := h.DB.Where("status IN ?", pendingStatuses).Order("created_at desc").Offset(params.Offset()).Limit(params.PageSize).Find(&kycSubmissions)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending KYC submissions"})
 		return
@@ -522,17 +521,12 @@ func (h *KYCHandler) GetPendingKYC(c *gin.Context) {
 
 	// Get total count for pagination
 	var count int64
-	h.DB.Model(&database.KYC{}).Where("status = ?", string(database.KYCStatusPending)).Count(&count)
+	h.DB.Model(&models.KYCVerification{}).Where("status IN ?", pendingStatuses).Count(&count)
 
 	// Prepare response
 	response := gin.H{
 		"kyc_submissions": kycSubmissions,
-		"pagination": gin.H{
-			"total":       count,
-			"page":        page,
-			"page_size":   pageSize,
-			"total_pages": int(math.Ceil(float64(count) / float64(pageSize))),
-		},
+		"pagination":      pagination.NewMeta(params, count),
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -540,10 +534,9 @@ func (h *KYCHandler) GetPendingKYC(c *gin.Context) {
 
 // GetKYCByID returns a specific KYC submission by ID for admin review
 func (h *KYCHandler) GetKYCByID(c *gin.Context) {
-	// Check if the user is an admin
-	isAdmin := c.GetBool("is_admin")
-	if !isAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+	// Check the user may view KYC submissions
+	if !hasPermission(c, models.PermissionKYCView) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
 
@@ -562,8 +555,8 @@ func (h *KYCHandler) GetKYCByID(c *gin.Context) {
 	}
 
 	// Get KYC record from database
-	var kyc database.KYC
-	result := h.DB.First(&kyc, kycID)
+	var verification models.KYCVerification
+	result := h.DB.First(&verification, "id = ?", kycID)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "KYC record not found"})
@@ -573,17 +566,21 @@ func (h *KYCHandler) GetKYCByID(c *gin.Context) {
 		return
 	}
 
-	// Get verification history
-	var history []database.KYCHistory
-	h.DB.Where("kyc_id = ?", kycID).Order("created_at desc").Find(&history)
+	// Get verification history and documents
+	var history []models.KYCVerificationHistory
+	h.DB.Where("verification_id = ?", kycID).Order("created_at desc").Find(&history)
+
+	var documents []models.KYCDocument
+	h.DB.Where("verification_id = ?", kycID).Find(&documents)
 
 	// Get user details
 	var user database.User
-	h.DB.First(&user, kyc.UserID)
+	h.DB.First(&user, verification.UserID)
 
 	// Prepare response
 	response := gin.H{
-		"kyc": kyc,
+		"kyc":       verification,
+		"documents": signedDocuments(c.Request.Context(), h.Storage, documents),
 		"user": gin.H{
 			"id":       user.ID,
 			"email":    user.Email,
@@ -595,18 +592,94 @@ func (h *KYCHandler) GetKYCByID(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetKYCDocument streams a KYC document from the storage backend to an admin. docType is
+// id_front, id_back, selfie or proof_of_address (or another document type). Missing
+// verifications and documents are both reported as 404 so existence isn't leaked, and
+// every view is recorded in the audit log.
+func (h *KYCHandler) GetKYCDocument(c *gin.Context) {
+	// Check the user may view KYC submissions
+	if !hasPermission(c, models.PermissionKYCView) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	notFound := gin.H{"error": "Document not found"}
+
+	verificationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, notFound)
+		return
+	}
+
+	var verification models.KYCVerification
+	if err := h.DB.First(&verification, "id = ?", verificationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, notFound)
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch document"})
+		}
+		return
+	}
+
+	document, err := kyc.FindDocument(h.DB, verificationID, c.Param("docType"))
+	if err != nil {
+		if errors.Is(err, kyc.ErrDocumentNotFound) {
+			c.JSON(http.StatusNotFound, notFound)
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch document"})
+		}
+		return
+	}
+
+	object, info, err := h.Storage.Get(c.Request.Context(), document.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Printf("KYC document %s is missing from storage", document.ID)
+			c.JSON(http.StatusNotFound, notFound)
+		} else {
+			log.Printf("Failed to read KYC document %s: %v", document.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch document"})
+		}
+		return
+	}
+	defer object.Close()
+
+	if err := h.auditLogger.LogAdminAction(c.Request.Context(), adminID, &verification.UserID, c.ClientIP(), c.Request.UserAgent(), "view_kyc_document", true, map[string]interface{}{
+		"verification_id": verification.ID.String(),
+		"document_id":     document.ID.String(),
+		"document_type":   c.Param("docType"),
+	}); err != nil {
+		log.Printf("Failed to audit KYC document access: %v", err)
+	}
+
+	contentType := document.MimeType
+	if contentType == "" {
+		contentType = info.ContentType
+	}
+
+	c.DataFromReader(http.StatusOK, info.Size, contentType, object, map[string]string{
+		"Content-Disposition":    "inline",
+		"Cache-Control":          "private, no-store",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
 // ApproveKYC approves a KYC submission
 func (h *KYCHandler) ApproveKYC(c *gin.Context) {
-	// Check if the user is an admin
-	isAdmin := c.GetBool("is_admin")
-	if !isAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+	// Check the user may review KYC submissions
+	if !hasPermission(c, models.PermissionKYCApprove) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
 
 	// Get admin user ID for audit trail
-	adminIDStr := c.GetString("user_id")
-	if adminIDStr == "" {
+	if _, exists := getUserID(c); !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin ID not found"})
 		return
 	}
@@ -632,7 +705,7 @@ func (h *KYCHandler) ApproveKYC(c *gin.Context) {
 	c.Request.URL.Path = "/kyc/admin/status"
 	c.Set("_kycUpdateData", gin.H{
 		"kyc_id": kycIDStr,
-		"status": database.KYCStatusApproved,
+		"status": models.KYCStatusApproved,
 		"notes":  request.Notes,
 	})
 
@@ -642,16 +715,14 @@ func (h *KYCHandler) ApproveKYC(c *gin.Context) {
 
 // RejectKYC rejects a KYC submission
 func (h *KYCHandler) RejectKYC(c *gin.Context) {
-	// Check if the user is an admin
-	isAdmin := c.GetBool("is_admin")
-	if !isAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+	// Check the user may review KYC submissions
+	if !hasPermission(c, models.PermissionKYCApprove) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
 
 	// Get admin user ID for audit trail
-	adminIDStr := c.GetString("user_id")
-	if adminIDStr == "" {
+	if _, exists := getUserID(c); !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin ID not found"})
 		return
 	}
@@ -678,7 +749,7 @@ func (h *KYCHandler) RejectKYC(c *gin.Context) {
 	c.Request.URL.Path = "/kyc/admin/status"
 	c.Set("_kycUpdateData", gin.H{
 		"kyc_id":           kycIDStr,
-		"status":           database.KYCStatusRejected,
+		"status":           models.KYCStatusRejected,
 		"rejection_reason": request.RejectionReason,
 		"notes":            request.Notes,
 	})
@@ -688,8 +759,8 @@ func (h *KYCHandler) RejectKYC(c *gin.Context) {
 }
 
 // RegisterKYCRoutes registers the KYC routes
-func RegisterKYCRoutes(router *gin.RouterGroup, db *gorm.DB) {
-	handler := NewKYCHandler(db)
+func RegisterKYCRoutes(router *gin.RouterGroup, db *gorm.DB, jobQueue *queue.Queue, store storage.Backend) {
+	handler := NewKYCHandler(db, jobQueue, store)
 
 	kycRoutes := router.Group("/kyc")
 	{
@@ -701,6 +772,7 @@ func RegisterKYCRoutes(router *gin.RouterGroup, db *gorm.DB) {
 		{
 			adminRoutes.GET("/pending", handler.GetPendingKYC)
 			adminRoutes.GET("/:id", handler.GetKYCByID)
+			adminRoutes.GET("/:id/documents/:docType", handler.GetKYCDocument)
 			adminRoutes.PUT("/:id/approve", handler.ApproveKYC)
 			adminRoutes.PUT("/:id/reject", handler.RejectKYC)
 			adminRoutes.PUT("/status", handler.UpdateKYCStatus)
@@ -711,5 +783,6 @@ func RegisterKYCRoutes(router *gin.RouterGroup, db *gorm.DB) {
 	webhookRoutes := router.Group("/webhooks")
 	{
 		webhookRoutes.POST("/didit", handler.HandleDiditWebhook)
+		webhookRoutes.POST("/smile", handler.HandleSmileWebhook)
 	}
 }
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/kyc"
+	"github.com/revaspay/backend/internal/services/storage"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/revaspay/backend/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Staff who can view KYC submissions get documents streamed from storage, and every view is
+// audited. Missing verifications, documents and stored files all look the same.
+func TestGetKYCDocument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&utils.AuditLog{}))
+	store, err := storage.NewLocalBackend(storage.LocalConfig{Root: t.TempDir(), EncryptionKey: bytes.Repeat([]byte{7}, 32)})
+	require.NoError(t, err)
+	handler := &KYCHandler{DB: db, Storage: store, auditLogger: utils.NewAuditLogger(db)}
+
+	user := testutil.CreateUser(t, db)
+	reviewer := testutil.CreateUser(t, db)
+	verification := models.KYCVerification{UserID: user.ID, Provider: "smile", Status: models.KYCStatusInProgress}
+	require.NoError(t, db.Create(&verification).Error)
+	idFront := []byte("passport photo page")
+	require.NoError(t, store.Put(context.Background(), "kyc/"+verification.ID.String()+"/passport.jpg", idFront, "image/jpeg"))
+	require.NoError(t, db.Create(&[]models.KYCDocument{
+		{VerificationID: verification.ID, Type: models.DocumentTypePassport, StorageKey: "kyc/" + verification.ID.String() + "/passport.jpg", FileName: "passport.jpg", MimeType: "image/jpeg"},
+		{VerificationID: verification.ID, Type: models.DocumentTypeSelfie, StorageKey: "kyc/" + verification.ID.String() + "/deleted.jpg", FileName: "selfie.jpg", MimeType: "image/jpeg"},
+	}).Error)
+
+	get := func(verificationID, docType string, permitted bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/admin/kyc/"+verificationID+"/documents/"+docType, nil)
+		c.Params = gin.Params{{Key: "id", Value: verificationID}, {Key: "docType", Value: docType}}
+		c.Set(middleware.ContextUserUUID, reviewer.ID)
+		if permitted {
+			c.Set(middleware.ContextPermissions, map[models.Permission]bool{models.PermissionKYCView: true})
+		}
+		handler.GetKYCDocument(c)
+		return w
+	}
+	audited := func() int64 {
+		var n int64
+		require.NoError(t, db.Model(&utils.AuditLog{}).Where("description = ?", "Admin action: view_kyc_document").Count(&n).Error)
+		return n
+	}
+
+	assert.Equal(t, http.StatusForbidden, get(verification.ID.String(), kyc.DocumentIDFront, false).Code)
+
+	w := get(verification.ID.String(), kyc.DocumentIDFront, true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, idFront, w.Body.Bytes())
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Equal(t, "private, no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, int64(1), audited())
+
+	for _, missing := range []struct{ verificationID, docType string }{
+		{verification.ID.String(), "id_back"},
+		{verification.ID.String(), "selfie"},
+		{uuid.NewString(), "id_front"},
+		{"not-a-uuid", "id_front"},
+	} {
+		w := get(missing.verificationID, missing.docType, true)
+		assert.Equal(t, http.StatusNotFound, w.Code, missing)
+		assert.JSONEq(t, `{"error":"Document not found"}`, w.Body.String())
+	}
+	assert.Equal(t, int64(1), audited(), "only documents that were served are audited")
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/kyc"
+	"github.com/revaspay/backend/internal/services/rbac"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"gorm.io/gorm"
+)
+
+// MeHandler returns everything the app needs about the signed-in user in one call
+type MeHandler struct {
+	db            *gorm.DB
+	walletService *wallet.WalletService
+}
+
+// NewMeHandler creates a new me handler
+func NewMeHandler(db *gorm.DB) *MeHandler {
+	return &MeHandler{
+		db:            db,
+		walletService: wallet.NewWalletService(db),
+	}
+}
+
+// WalletSummary is a wallet's balances as reported by GetMe
+type WalletSummary struct {
+	WalletID  uuid.UUID       `json:"wallet_id"`
+	Currency  models.Currency `json:"currency"`
+	Balance   float64         `json:"balance"`
+	Available float64         `json:"available"`
+	Held      float64         `json:"held"`
+}
+
+// GetMe returns the authenticated user's profile, KYC status, MFA and email verification
+// flags, staff permissions and wallet balances
+func (h *MeHandler) GetMe(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var user database.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	kycStatus, err := kyc.GetUserStatus(h.db, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get KYC status"})
+		return
+	}
+
+	// Admins hold every permission
+	permissions := models.AllPermissions
+	if !user.IsAdmin {
+		if permissions, err = rbac.UserPermissions(h.db, user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get permissions"})
+			return
+		}
+	}
+
+	wallets, err := h.walletService.GetWallets(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wallets"})
+		return
+	}
+	summaries := make([]WalletSummary, 0, len(wallets))
+	for _, w := range wallets {
+		summaries = append(summaries, WalletSummary{
+			WalletID:  w.ID,
+			Currency:  w.Currency,
+			Balance:   w.Balance,
+			Available: w.Available,
+			Held:      w.Held,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": gin.H{
+			"user": gin.H{
+				"id":            user.ID,
+				"username":      user.Username,
+				"email":         user.Email,
+				"first_name":    user.FirstName,
+				"last_name":     user.LastName,
+				"display_name":  user.DisplayName,
+				"profile_image": user.ProfileImage,
+				"phone_number":  user.PhoneNumber,
+				"country_code":  user.CountryCode,
+				"business_name": user.BusinessName,
+				"referral_code": user.ReferralCode,
+				"last_login_at": user.LastLoginAt,
+				"created_at":    user.CreatedAt,
+			},
+			"email_verified": user.IsVerified,
+			"mfa_enabled":    user.TwoFactorEnabled,
+			"is_admin":       user.IsAdmin,
+			"permissions":    permissions,
+			"kyc":            kycStatus,
+			"kyc_verified":   kycStatus.Status == models.KYCStatusApproved,
+			"wallets":        summaries,
+		},
+	})
+}
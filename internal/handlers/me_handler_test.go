@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/rbac"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// GetMe reports the signed-in user's own KYC status, permissions and wallet balances, and
+// nothing for requests without a known user
+func TestGetMe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newPasswordTestDB(t)
+	for _, role := range models.DefaultRoles {
+		role := role
+		require.NoError(t, db.Create(&role).Error)
+	}
+	handler := NewMeHandler(db)
+
+	user := testutil.CreateUser(t, db)
+	_, err := rbac.NewService(db).AssignRole(context.Background(), uuid.New(), user.ID, "support")
+	require.NoError(t, err)
+	walletSvc := wallet.NewWalletService(db)
+	w, err := walletSvc.GetOrCreateWallet(user.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	_, err = walletSvc.Credit(w.ID, 100, "deposit", models.LedgerCategoryDeposit, "DEP-1", "Deposit", nil)
+	require.NoError(t, err)
+	_, err = walletSvc.Hold(w.ID, 30, "WD-1", "Withdrawal pending")
+	require.NoError(t, err)
+	other := testutil.CreateUser(t, db)
+	_, err = walletSvc.GetOrCreateWallet(other.ID, models.CurrencyUSD)
+	require.NoError(t, err)
+
+	getMe := func(userID *uuid.UUID) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		if userID != nil {
+			c.Set(middleware.ContextUserUUID, *userID)
+		}
+		handler.GetMe(c)
+		return rec
+	}
+
+	rec := getMe(&user.ID)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var response struct {
+		Data struct {
+			User struct {
+				ID    uuid.UUID `json:"id"`
+				Email string    `json:"email"`
+			} `json:"user"`
+			IsAdmin     bool                `json:"is_admin"`
+			Permissions []models.Permission `json:"permissions"`
+			KYC         struct {
+				Status models.KYCStatus `json:"status"`
+			} `json:"kyc"`
+			KYCVerified bool            `json:"kyc_verified"`
+			Wallets     []WalletSummary `json:"wallets"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, user.ID, response.Data.User.ID)
+	assert.Equal(t, user.Email, response.Data.User.Email)
+	assert.False(t, response.Data.IsAdmin)
+	assert.Contains(t, response.Data.Permissions, models.PermissionKYCView)
+	assert.NotContains(t, response.Data.Permissions, models.PermissionKYCApprove)
+	assert.Equal(t, models.KYCStatusNotSubmitted, response.Data.KYC.Status)
+	assert.False(t, response.Data.KYCVerified)
+	assert.Equal(t, []WalletSummary{{WalletID: w.ID, Currency: models.CurrencyGHS, Balance: 100, Available: 70, Held: 30}}, response.Data.Wallets)
+
+	assert.Equal(t, http.StatusUnauthorized, getMe(nil).Code)
+	unknown := uuid.New()
+	assert.Equal(t, http.StatusNotFound, getMe(&unknown).Code)
+}
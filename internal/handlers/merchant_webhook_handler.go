@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/pagination"
+	"github.com/revaspay/backend/internal/services/webhook"
+)
+
+// MerchantWebhookHandler handles merchant webhook endpoint management
+type MerchantWebhookHandler struct {
+	webhookService *webhook.WebhookService
+}
+
+// NewMerchantWebhookHandler creates a new merchant webhook handler
+func NewMerchantWebhookHandler(webhookService *webhook.WebhookService) *MerchantWebhookHandler {
+	return &MerchantWebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhookEndpointRequest represents a request to register a webhook endpoint
+type CreateWebhookEndpointRequest struct {
+	URL           string `json:"url" binding:"required"`
+	Description   string `json:"description"`
+	BalanceEvents bool   `json:"balance_events"` // Opt in to wallet.balance_changed events
+}
+
+// UpdateWebhookEndpointRequest represents a request to update a webhook endpoint
+type UpdateWebhookEndpointRequest struct {
+	URL           *string `json:"url"`
+	Description   *string `json:"description"`
+	Active        *bool   `json:"active"`
+	BalanceEvents *bool   `json:"balance_events"`
+}
+
+// CreateEndpoint registers a new webhook endpoint for the authenticated user
+func (h *MerchantWebhookHandler) CreateEndpoint(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req CreateWebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpoint, err := h.webhookService.CreateEndpoint(userID, req.URL, req.Description, req.BalanceEvents)
+	if err != nil {
+		if errors.Is(err, webhook.ErrInvalidEndpointURL) || errors.Is(err, webhook.ErrNonPublicEndpointURL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook endpoint"})
+		return
+	}
+
+	// The secret is only returned once, when the endpoint is created
+	c.JSON(http.StatusCreated, gin.H{
+		"status":   "success",
+		"endpoint": endpoint,
+		"secret":   endpoint.Secret,
+	})
+}
+
+// GetEndpoints lists the authenticated user's webhook endpoints
+func (h *MerchantWebhookHandler) GetEndpoints(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	endpoints, err := h.webhookService.GetEndpoints(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhook endpoints"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "success",
+		"endpoints": endpoints,
+	})
+}
+
+// GetEndpoint gets a single webhook endpoint
+func (h *MerchantWebhookHandler) GetEndpoint(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	endpoint, err := h.webhookService.GetEndpoint(id, userID)
+	if err != nil {
+		h.handleEndpointError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"endpoint": endpoint,
+	})
+}
+
+// UpdateEndpoint updates a webhook endpoint
+func (h *MerchantWebhookHandler) UpdateEndpoint(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	var req UpdateWebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpoint, err := h.webhookService.UpdateEndpoint(id, userID, req.URL, req.Description, req.Active, req.BalanceEvents)
+	if err != nil {
+		h.handleEndpointError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"endpoint": endpoint,
+	})
+}
+
+// DeleteEndpoint deletes a webhook endpoint
+func (h *MerchantWebhookHandler) DeleteEndpoint(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	if err := h.webhookService.DeleteEndpoint(id, userID); err != nil {
+		h.handleEndpointError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Webhook endpoint deleted",
+	})
+}
+
+// GetDeliveries returns the delivery log for a webhook endpoint
+func (h *MerchantWebhookHandler) GetDeliveries(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	params := pagination.ParseParams(c, 20)
+
+	deliveries, total, err := h.webhookService.GetDeliveries(id, userID, params.Page, params.PageSize)
+	if err != nil {
+		h.handleEndpointError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"deliveries": deliveries,
+		"pagination": pagination.NewMeta(params, total),
+	})
+}
+
+// StartSecretRotation generates a new signing secret for an endpoint. Deliveries keep being
+// signed with the current secret until the rotation is finalized, so the merchant can deploy
+// the new one alongside it first.
+func (h *MerchantWebhookHandler) StartSecretRotation(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	endpoint, err := h.webhookService.StartSecretRotation(id, userID)
+	if err != nil {
+		h.handleEndpointError(c, err)
+		return
+	}
+
+	// Like the original secret, the new one is only returned once
+	c.JSON(http.StatusOK, gin.H{
+		"status":                 "success",
+		"endpoint":               endpoint,
+		"pending_secret":         endpoint.PendingSecret,
+		"pending_secret_version": endpoint.SecretVersion + 1,
+	})
+}
+
+// FinalizeSecretRotation switches an endpoint's deliveries to the secret generated by
+// StartSecretRotation and discards the old one
+func (h *MerchantWebhookHandler) FinalizeSecretRotation(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	endpoint, err := h.webhookService.FinalizeSecretRotation(id, userID)
+	if err != nil {
+		h.handleEndpointError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"endpoint": endpoint,
+	})
+}
+
+// handleEndpointError maps webhook service errors to HTTP responses
+func (h *MerchantWebhookHandler) handleEndpointError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrEndpointNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, webhook.ErrInvalidEndpointURL), errors.Is(err, webhook.ErrNonPublicEndpointURL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, webhook.ErrRotationInProgress), errors.Is(err, webhook.ErrNoRotationInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook endpoint request"})
+	}
+}
@@ -30,27 +30,21 @@ func NewMFAHandler(db *gorm.DB, auditLogger *utils.AuditLogger) *MFAHandler {
 
 // SetupTOTP initiates TOTP setup for a user
 func (h *MFAHandler) SetupTOTP(c *gin.Context) {
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get user details
 	var user database.User
-	if err := h.db.First(&user, uid).Error; err != nil {
+	if err := h.db.First(&user, userID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	// Get or create MFA settings
-	settings, err := database.GetMFASettings(h.db, uid)
+	settings, err := database.GetMFASettings(h.db, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get MFA settings"})
 		return
@@ -80,7 +74,7 @@ func (h *MFAHandler) SetupTOTP(c *gin.Context) {
 	}
 
 	// Store backup codes in the database
-	if err := database.CreateBackupCodes(h.db, uid, settings.ID, hashedBackupCodes); err != nil {
+	if err := database.CreateBackupCodes(h.db, userID, settings.ID, hashedBackupCodes); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store backup codes"})
 		return
 	}
@@ -89,7 +83,7 @@ func (h *MFAHandler) SetupTOTP(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 	h.auditLogger.LogEvent(c, utils.AuditEventMFAEnabled, utils.AuditSeverityInfo, 
-		"MFA setup initiated", &uid, nil, ipAddress, userAgent, true, 
+		"MFA setup initiated", &userID, nil, ipAddress, userAgent, true, 
 		map[string]interface{}{"method": "TOTP"})
 
 	// Return setup information
@@ -102,18 +96,12 @@ func (h *MFAHandler) SetupTOTP(c *gin.Context) {
 
 // VerifyTOTP verifies a TOTP code during setup
 func (h *MFAHandler) VerifyTOTP(c *gin.Context) {
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get the request body
 	var req struct {
 		Code string `json:"code" binding:"required"`
@@ -137,7 +125,7 @@ func (h *MFAHandler) VerifyTOTP(c *gin.Context) {
 	}
 
 	// Get MFA settings
-	settings, err := database.GetMFASettings(h.db, uid)
+	settings, err := database.GetMFASettings(h.db, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get MFA settings"})
 		return
@@ -145,7 +133,7 @@ func (h *MFAHandler) VerifyTOTP(c *gin.Context) {
 
 	// Create MFA device
 	device := database.MFADevice{
-		UserID:       uid,
+		UserID:       userID,
 		MFASettingsID: settings.ID,
 		Name:         "Authenticator App",
 		Method:       database.MFAMethodTOTP,
@@ -162,7 +150,7 @@ func (h *MFAHandler) VerifyTOTP(c *gin.Context) {
 	}
 
 	// Enable MFA for the user
-	if err := database.EnableMFA(h.db, uid, database.MFAMethodTOTP); err != nil {
+	if err := database.EnableMFA(h.db, userID, database.MFAMethodTOTP); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable MFA"})
 		return
 	}
@@ -174,7 +162,7 @@ func (h *MFAHandler) VerifyTOTP(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 	h.auditLogger.LogEvent(c, utils.AuditEventMFAEnabled, utils.AuditSeverityInfo, 
-		"MFA enabled", &uid, nil, ipAddress, userAgent, true, 
+		"MFA enabled", &userID, nil, ipAddress, userAgent, true, 
 		map[string]interface{}{"method": "TOTP"})
 
 	c.JSON(http.StatusOK, gin.H{
@@ -315,18 +303,12 @@ func (h *MFAHandler) VerifyMFACode(c *gin.Context) {
 
 // DisableMFA disables MFA for a user
 func (h *MFAHandler) DisableMFA(c *gin.Context) {
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get the request body
 	var req struct {
 		Password string `json:"password" binding:"required"`
@@ -338,7 +320,7 @@ func (h *MFAHandler) DisableMFA(c *gin.Context) {
 
 	// Get user details
 	var user database.User
-	if err := h.db.First(&user, uid).Error; err != nil {
+	if err := h.db.First(&user, userID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -350,7 +332,7 @@ func (h *MFAHandler) DisableMFA(c *gin.Context) {
 	}
 
 	// Disable MFA
-	if err := database.DisableMFA(h.db, uid); err != nil {
+	if err := database.DisableMFA(h.db, userID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable MFA"})
 		return
 	}
@@ -359,7 +341,7 @@ func (h *MFAHandler) DisableMFA(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 	h.auditLogger.LogEvent(c, utils.AuditEventMFADisabled, utils.AuditSeverityInfo, 
-		"MFA disabled", &uid, nil, ipAddress, userAgent, true, nil)
+		"MFA disabled", &userID, nil, ipAddress, userAgent, true, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "MFA disabled successfully",
@@ -368,34 +350,28 @@ func (h *MFAHandler) DisableMFA(c *gin.Context) {
 
 // GetMFAStatus gets the MFA status for a user
 func (h *MFAHandler) GetMFAStatus(c *gin.Context) {
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get MFA settings
-	settings, err := database.GetMFASettings(h.db, uid)
+	settings, err := database.GetMFASettings(h.db, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get MFA settings"})
 		return
 	}
 
 	// Get MFA devices
-	devices, err := database.GetMFADevices(h.db, uid)
+	devices, err := database.GetMFADevices(h.db, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get MFA devices"})
 		return
 	}
 
 	// Get backup codes count
-	backupCodes, err := database.GetUnusedBackupCodes(h.db, uid)
+	backupCodes, err := database.GetUnusedBackupCodes(h.db, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get backup codes"})
 		return
@@ -435,18 +411,12 @@ func (h *MFAHandler) GetMFAStatus(c *gin.Context) {
 
 // GenerateBackupCodes generates new backup codes for a user
 func (h *MFAHandler) GenerateBackupCodes(c *gin.Context) {
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	uid, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Get the request body
 	var req struct {
 		Password string `json:"password" binding:"required"`
@@ -458,7 +428,7 @@ func (h *MFAHandler) GenerateBackupCodes(c *gin.Context) {
 
 	// Get user details
 	var user database.User
-	if err := h.db.First(&user, uid).Error; err != nil {
+	if err := h.db.First(&user, userID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -470,7 +440,7 @@ func (h *MFAHandler) GenerateBackupCodes(c *gin.Context) {
 	}
 
 	// Get MFA settings
-	settings, err := database.GetMFASettings(h.db, uid)
+	settings, err := database.GetMFASettings(h.db, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get MFA settings"})
 		return
@@ -495,7 +465,7 @@ func (h *MFAHandler) GenerateBackupCodes(c *gin.Context) {
 	}
 
 	// Store backup codes in the database
-	if err := database.CreateBackupCodes(h.db, uid, settings.ID, hashedBackupCodes); err != nil {
+	if err := database.CreateBackupCodes(h.db, userID, settings.ID, hashedBackupCodes); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store backup codes"})
 		return
 	}
@@ -504,7 +474,7 @@ func (h *MFAHandler) GenerateBackupCodes(c *gin.Context) {
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 	h.auditLogger.LogEvent(c, utils.AuditEventMFAEnabled, utils.AuditSeverityInfo, 
-		"Backup codes regenerated", &uid, nil, ipAddress, userAgent, true, nil)
+		"Backup codes regenerated", &userID, nil, ipAddress, userAgent, true, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"backup_codes": backupCodes,
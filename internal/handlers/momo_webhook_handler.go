@@ -1,37 +1,81 @@
 package handlers
 
 import (
+	"errors"
+	"log"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
-	"github.com/revaspay/backend/internal/api"
 	"github.com/revaspay/backend/internal/config"
 	"github.com/revaspay/backend/internal/services/payment/momo"
+	"github.com/revaspay/backend/internal/services/wallet"
 	"gorm.io/gorm"
 )
 
 // MoMoWebhookHandler handles MTN Mobile Money API webhook callbacks
 type MoMoWebhookHandler struct {
-	handler *api.MoMoWebhookHandler
+	processor *momo.CallbackProcessor
 }
 
 // NewMoMoWebhookHandler creates a new MoMo webhook handler
 func NewMoMoWebhookHandler(db *gorm.DB, cfg *config.Config) *MoMoWebhookHandler {
-	// Initialize the MoMo service
-	momoService := momo.InitMoMoService(db, cfg)
-	
-	// Create the API handler
-	apiHandler := api.NewMoMoWebhookHandler(momoService)
-	
+	if cfg.MoMo.CallbackSecret == "" {
+		log.Println("MTN_MOMO_CALLBACK_SECRET is not set; MoMo callbacks will be rejected")
+	}
+
 	return &MoMoWebhookHandler{
-		handler: apiHandler,
+		processor: momo.NewCallbackProcessor(db, wallet.NewWalletService(db), cfg.MoMo.CallbackSecret),
 	}
 }
 
 // PaymentNotification handles payment notification webhooks
 func (h *MoMoWebhookHandler) PaymentNotification(c *gin.Context) {
-	h.handler.PaymentNotification(c)
+	if err := h.processor.VerifyToken(c.Query("token")); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var notification momo.PaymentNotification
+	if err := c.ShouldBindJSON(&notification); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+
+	log.Printf("Received MoMo payment callback for %s, status: %s", notification.ExternalID, notification.Status)
+	h.respond(c, h.processor.ProcessCollection(notification))
 }
 
 // DisbursementNotification handles disbursement notification webhooks
 func (h *MoMoWebhookHandler) DisbursementNotification(c *gin.Context) {
-	h.handler.DisbursementNotification(c)
+	if err := h.processor.VerifyToken(c.Query("token")); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var notification momo.DisbursementNotification
+	if err := c.ShouldBindJSON(&notification); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+
+	log.Printf("Received MoMo disbursement callback for %s, status: %s", notification.ExternalID, notification.Status)
+	h.respond(c, h.processor.ProcessDisbursement(notification))
+}
+
+// respond maps the outcome of processing a callback to an HTTP response. Callbacks for
+// transactions we don't track are acknowledged so MoMo stops retrying them.
+func (h *MoMoWebhookHandler) respond(c *gin.Context, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"status": "success"})
+	case errors.Is(err, momo.ErrCallbackTransactionNotFound):
+		log.Printf("Received MoMo callback for unknown transaction: %v", err)
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+	case errors.Is(err, momo.ErrCallbackMismatch):
+		log.Printf("Rejected MoMo callback: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Failed to process MoMo callback: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process callback"})
+	}
 }
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/pagination"
+	"github.com/revaspay/backend/internal/services/notification"
+)
+
+// NotificationHandler handles in-app notification requests
+type NotificationHandler struct {
+	notificationService *notification.Service
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(notificationService *notification.Service) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// GetNotifications lists the user's in-app notifications. Pass unread=true for unread only.
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	params := pagination.ParseParams(c, 20)
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, total, err := h.notificationService.ListNotifications(userID, unreadOnly, params.Page, params.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notifications"})
+		return
+	}
+
+	unread, err := h.notificationService.UnreadCount(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":       "success",
+		"data":         notifications,
+		"unread_count": unread,
+		"pagination":   pagination.NewMeta(params, total),
+	})
+}
+
+// MarkNotificationRead marks a single notification as read
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	notificationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	n, err := h.notificationService.MarkRead(userID, notificationID)
+	if err != nil {
+		if errors.Is(err, notification.ErrNotificationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notification read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   n,
+	})
+}
+
+// MarkAllNotificationsRead marks all of the user's notifications as read
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	updated, err := h.notificationService.MarkAllRead(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notifications read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   gin.H{"updated": updated},
+	})
+}
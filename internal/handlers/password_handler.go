@@ -1,11 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/config"
 	"github.com/revaspay/backend/internal/database"
 	"github.com/revaspay/backend/internal/security"
 	"github.com/revaspay/backend/internal/security/audit"
@@ -17,6 +18,8 @@ type PasswordHandler struct {
 	db            *gorm.DB
 	auditLogger   *audit.Logger
 	passwordPolicy *security.PasswordPolicy
+	passwordValidator *security.PasswordValidator
+	passwordHistory   int // How many recent passwords can't be reused
 }
 
 // PasswordChangeRequest represents a request to update a password
@@ -24,6 +27,8 @@ type PasswordChangeRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
 	NewPassword     string `json:"new_password" binding:"required"`
 	ConfirmPassword string `json:"confirm_password" binding:"required"`
+	// KeepCurrentSession keeps the session making the change signed in; all others are revoked
+	KeepCurrentSession bool `json:"keep_current_session"`
 }
 
 // PasswordResetRequest represents a request to reset a password
@@ -39,13 +44,15 @@ func NewPasswordHandler(db *gorm.DB) *PasswordHandler {
 		db:            db,
 		auditLogger:   audit.NewLogger(db),
 		passwordPolicy: security.DefaultPasswordPolicy(),
+		passwordValidator: security.DefaultPasswordValidator(),
+		passwordHistory:   config.DefaultSecurityConfig().PasswordHistoryCount,
 	}
 }
 
 // UpdatePassword updates a user's password
 func (h *PasswordHandler) UpdatePassword(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -79,7 +86,7 @@ func (h *PasswordHandler) UpdatePassword(c *gin.Context) {
 			audit.EventTypeAuth,
 			audit.SeverityWarning,
 			"Failed password update attempt - incorrect current password",
-			userID.(*uuid.UUID),
+			&userID,
 			nil,
 			c.ClientIP(),
 			c.Request.UserAgent(),
@@ -96,14 +103,32 @@ func (h *PasswordHandler) UpdatePassword(c *gin.Context) {
 		return
 	}
 
+	// Check strength and known breaches
+	if err := h.passwordValidator.Validate(req.NewPassword); err != nil {
+		respondPasswordRejected(c, err)
+		return
+	}
+
+	// Reject the user's recent passwords
+	if err := database.CheckPasswordReuse(h.db, &user, req.NewPassword, h.passwordHistory); err != nil {
+		respondPasswordReused(c, err)
+		return
+	}
+
 	// Update password
 	if err := user.SetPassword(req.NewPassword); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
 	}
 
-	// Save user
-	if err := h.db.Save(&user).Error; err != nil {
+	// Save user, remembering the new password so it can't be reused
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return database.RecordPasswordHistory(tx, user.ID, user.Password, h.passwordHistory)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save user"})
 		return
 	}
@@ -114,7 +139,7 @@ func (h *PasswordHandler) UpdatePassword(c *gin.Context) {
 		audit.EventTypeAuth,
 		audit.SeverityInfo,
 		"Password updated successfully",
-		userID.(*uuid.UUID),
+		&userID,
 		nil,
 		c.ClientIP(),
 		c.Request.UserAgent(),
@@ -122,27 +147,20 @@ func (h *PasswordHandler) UpdatePassword(c *gin.Context) {
 		nil,
 	)
 
-	// Revoke all other sessions for security
-	if err := database.RevokeAllUserSessionsExcept(h.db, *userID.(*uuid.UUID), uuid.MustParse(c.GetString("session_id"))); err != nil {
-		// Just log the error but don't fail the request
-		h.auditLogger.LogWithContext(
-			c,
-			audit.EventTypeAuth,
-			audit.SeverityWarning,
-			"Failed to revoke other sessions after password change",
-			userID.(*uuid.UUID),
-			nil,
-			c.ClientIP(),
-			c.Request.UserAgent(),
-			false,
-			map[string]interface{}{
-				"error": err.Error(),
-			},
-		)
+	// Sign out everywhere, optionally keeping the session that made the change
+	var keepSessionID *uuid.UUID
+	if req.KeepCurrentSession {
+		if currentSessionID, ok := c.Get("session_id"); ok {
+			if id, ok := currentSessionID.(uuid.UUID); ok {
+				keepSessionID = &id
+			}
+		}
 	}
+	revoked := revokeSessionsAfterPasswordChange(c, h.db, h.auditLogger, userID, "Password changed", keepSessionID)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Password updated successfully",
+		"message":          "Password updated successfully",
+		"sessions_revoked": revoked,
 	})
 }
 
@@ -161,26 +179,19 @@ func (h *PasswordHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	// Find reset token
-	var resetToken database.PasswordResetToken
-	if err := h.db.Where("token = ?", req.Token).First(&resetToken).Error; err != nil {
+	// Find reset token by its hash; expired tokens are deleted when presented
+	resetToken, err := database.GetPasswordResetToken(h.db, req.Token)
+	if err != nil {
+		if errors.Is(err, database.ErrResetTokenExpired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Token has expired"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
 		return
 	}
 
-	// Check if token is expired
-	if resetToken.ExpiresAt < time.Now().Unix() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token has expired"})
-		return
-	}
-
 	// Get user
-	userID, err := uuid.Parse(resetToken.UserID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID in token"})
-		return
-	}
-
+	userID := resetToken.UserID
 	var user database.User
 	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
@@ -193,57 +204,45 @@ func (h *PasswordHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
+	// Check strength and known breaches
+	if err := h.passwordValidator.Validate(req.NewPassword); err != nil {
+		respondPasswordRejected(c, err)
+		return
+	}
+
+	// Reject the user's recent passwords
+	if err := database.CheckPasswordReuse(h.db, &user, req.NewPassword, h.passwordHistory); err != nil {
+		respondPasswordReused(c, err)
+		return
+	}
+
 	// Update password
 	if err := user.SetPassword(req.NewPassword); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
 	}
 
-	// Save user
-	if err := h.db.Save(&user).Error; err != nil {
+	// Use up the token and save the password together, so a token can only ever reset once
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := database.ConsumePasswordResetToken(tx, resetToken); err != nil {
+			return err
+		}
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return database.RecordPasswordHistory(tx, user.ID, user.Password, h.passwordHistory)
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrResetTokenInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save user"})
 		return
 	}
 
-	// Delete the used token
-	if err := h.db.Delete(&resetToken).Error; err != nil {
-		// Just log the error but don't fail the request
-		h.auditLogger.LogWithContext(
-			c,
-			audit.EventTypeAuth,
-			audit.SeverityWarning,
-			"Failed to delete used password reset token",
-			&userID,
-			nil,
-			c.ClientIP(),
-			c.Request.UserAgent(),
-			false,
-			map[string]interface{}{
-				"error": err.Error(),
-			},
-		)
-	}
-
-	// Revoke all sessions for this user
-	// Using RevokeAllUserSessionsExcept with a nil UUID to revoke all sessions
-	emptyUUID := uuid.Nil
-	if err := database.RevokeAllUserSessionsExcept(h.db, userID, emptyUUID); err != nil {
-		// Just log the error but don't fail the request
-		h.auditLogger.LogWithContext(
-			c,
-			audit.EventTypeAuth,
-			audit.SeverityWarning,
-			"Failed to revoke sessions after password reset",
-			&userID,
-			nil,
-			c.ClientIP(),
-			c.Request.UserAgent(),
-			false,
-			map[string]interface{}{
-				"error": err.Error(),
-			},
-		)
-	}
+	// Revoke all sessions for this user, so whoever had access before the reset loses it
+	revoked := revokeSessionsAfterPasswordChange(c, h.db, h.auditLogger, userID, "Password reset", nil)
 
 	// Log successful password reset
 	h.auditLogger.LogWithContext(
@@ -260,7 +259,8 @@ func (h *PasswordHandler) ResetPassword(c *gin.Context) {
 	)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Password reset successfully",
+		"message":          "Password reset successfully",
+		"sessions_revoked": revoked,
 	})
 }
 
@@ -284,3 +284,68 @@ func (h *PasswordHandler) EvaluatePasswordStrength(c *gin.Context) {
 		"score":    int(strength),
 	})
 }
+
+// revokeSessionsAfterPasswordChange signs the user out once their password has changed and
+// audits how many sessions were revoked. The password is already saved at this point, so a
+// failure is audited rather than failing the request.
+func revokeSessionsAfterPasswordChange(c *gin.Context, db *gorm.DB, auditLogger *audit.Logger, userID uuid.UUID, reason string, keepSessionID *uuid.UUID) int64 {
+	revoked, err := database.RevokeAllUserSessions(db, userID, reason, keepSessionID)
+	if err != nil {
+		auditLogger.LogWithContext(
+			c,
+			audit.EventTypeSession,
+			audit.SeverityWarning,
+			"Failed to revoke sessions after password change",
+			&userID,
+			nil,
+			c.ClientIP(),
+			c.Request.UserAgent(),
+			false,
+			map[string]interface{}{
+				"reason": reason,
+				"error":  err.Error(),
+			},
+		)
+		return 0
+	}
+
+	auditLogger.LogWithContext(
+		c,
+		audit.EventTypeSession,
+		audit.SeverityInfo,
+		"Sessions revoked after password change",
+		&userID,
+		nil,
+		c.ClientIP(),
+		c.Request.UserAgent(),
+		true,
+		map[string]interface{}{
+			"reason":               reason,
+			"sessions_revoked":     revoked,
+			"kept_current_session": keepSessionID != nil,
+		},
+	)
+	return revoked
+}
+
+// respondPasswordReused rejects a new password the user has used recently
+func respondPasswordReused(c *gin.Context, err error) {
+	if errors.Is(err, database.ErrPasswordReused) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check password history"})
+}
+
+// respondPasswordRejected tells the client why a new password was rejected and how to improve it
+func respondPasswordRejected(c *gin.Context, err error) {
+	var rejection *security.PasswordRejection
+	if errors.As(err, &rejection) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    rejection.Error(),
+			"feedback": rejection.Feedback,
+		})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
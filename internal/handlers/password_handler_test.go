@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/security"
+	"github.com/revaspay/backend/internal/security/audit"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newPasswordTestDB returns a test database with the columns database.User saves that
+// models.User doesn't migrate
+func newPasswordTestDB(t *testing.T) *gorm.DB {
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&database.EnhancedSession{}))
+	for _, column := range []string{
+		"password TEXT", "profile_pic_url TEXT", "verified BOOLEAN", "email_verified_at DATETIME",
+		"two_factor_enabled BOOLEAN", "two_factor_secret TEXT", "password_reset BOOLEAN", "referral_code TEXT", "referred_by TEXT",
+	} {
+		require.NoError(t, db.Exec("ALTER TABLE users ADD COLUMN "+column).Error)
+	}
+
+	// Postgres stores social links as jsonb; SQLite can't store the map, so it isn't saved
+	require.NoError(t, db.Callback().Update().Before("gorm:update").Register("test:omit_social_links", func(tx *gorm.DB) {
+		if tx.Statement.Table == "users" {
+			tx.Statement.Omit("social_links")
+		}
+	}))
+	return db
+}
+
+// newTestPasswordHandler returns a password handler that doesn't check passwords against
+// known breaches
+func newTestPasswordHandler(db *gorm.DB) *PasswordHandler {
+	return &PasswordHandler{
+		db:                db,
+		auditLogger:       audit.NewLogger(db),
+		passwordPolicy:    security.DefaultPasswordPolicy(),
+		passwordValidator: security.NewPasswordValidator(security.Moderate, nil),
+		passwordHistory:   2,
+	}
+}
+
+// resetPassword posts a password reset with the token to the handler
+func resetPassword(handler *PasswordHandler, token, password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(PasswordResetRequest{Token: token, NewPassword: password, ConfirmPassword: password})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/reset-password", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler.ResetPassword(c)
+	return w
+}
+
+// Reset tokens are stored hashed, reset a password once, and are replaced by newer tokens
+func TestResetPasswordTokenIsSingleUse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newPasswordTestDB(t)
+	handler := newTestPasswordHandler(db)
+	user := testutil.CreateUser(t, db)
+
+	replaced, err := database.IssuePasswordResetToken(db, user.ID, time.Hour)
+	require.NoError(t, err)
+	token, err := database.IssuePasswordResetToken(db, user.ID, time.Hour)
+	require.NoError(t, err)
+
+	var stored []database.PasswordResetToken
+	require.NoError(t, db.Find(&stored, "user_id = ?", user.ID.String()).Error)
+	require.Len(t, stored, 1, "issuing a token replaces the user's earlier tokens")
+	assert.Equal(t, database.HashResetToken(token), stored[0].Token)
+
+	assert.Equal(t, http.StatusBadRequest, resetPassword(handler, replaced, "Kx7#mVqz!Lb9Rw").Code)
+	w := resetPassword(handler, token, "Kx7#mVqz!Lb9Rw")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusBadRequest, resetPassword(handler, token, "Tj4$nWpy@Hc8Qe").Code)
+
+	var reset database.User
+	require.NoError(t, db.First(&reset, "id = ?", user.ID).Error)
+	assert.True(t, reset.CheckPassword("Kx7#mVqz!Lb9Rw"))
+
+	// Expired tokens are rejected and deleted
+	expired, err := database.IssuePasswordResetToken(db, user.ID, -time.Minute)
+	require.NoError(t, err)
+	w = resetPassword(handler, expired, "Tj4$nWpy@Hc8Qe")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Token has expired")
+	_, err = database.GetPasswordResetToken(db, expired)
+	assert.ErrorIs(t, err, database.ErrResetTokenInvalid)
+}
+
+// The password handlers reject a user's recent passwords through the password history
+func TestPasswordReuse(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := database.User{ID: testutil.CreateUser(t, db).ID}
+
+	const keep = 2
+	setPassword := func(password string) {
+		t.Helper()
+		if err := database.CheckPasswordReuse(db, &user, password, keep); err != nil {
+			t.Fatalf("CheckPasswordReuse(%q): %v", password, err)
+		}
+		if err := user.SetPassword(password); err != nil {
+			t.Fatalf("SetPassword: %v", err)
+		}
+		if err := database.RecordPasswordHistory(db, user.ID, user.Password, keep); err != nil {
+			t.Fatalf("RecordPasswordHistory: %v", err)
+		}
+	}
+
+	setPassword("First-Passw0rd!")
+	setPassword("Second-Passw0rd!")
+	setPassword("Third-Passw0rd!")
+
+	// The current password and the last keep passwords can't be reused
+	for _, reused := range []string{"Third-Passw0rd!", "Second-Passw0rd!"} {
+		if err := database.CheckPasswordReuse(db, &user, reused, keep); !errors.Is(err, database.ErrPasswordReused) {
+			t.Errorf("CheckPasswordReuse(%q) = %v, want ErrPasswordReused", reused, err)
+		}
+	}
+
+	// Older passwords are pruned and can be used again
+	var stored int64
+	db.Model(&database.PasswordHistory{}).Where("user_id = ?", user.ID).Count(&stored)
+	if stored != keep {
+		t.Errorf("stored %d password hashes, want %d", stored, keep)
+	}
+	if err := database.CheckPasswordReuse(db, &user, "First-Passw0rd!", keep); err != nil {
+		t.Errorf("CheckPasswordReuse of a pruned password: %v", err)
+	}
+
+	// A keep of zero allows reuse
+	if err := database.CheckPasswordReuse(db, &user, "Third-Passw0rd!", 0); err != nil {
+		t.Errorf("CheckPasswordReuse with history off: %v", err)
+	}
+}
+
+// Resetting or changing a password signs the user out everywhere, except the session making
+// a change that asked to stay signed in
+func TestPasswordChangeRevokesSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newPasswordTestDB(t)
+	handler := newTestPasswordHandler(db)
+	user := testutil.CreateUser(t, db)
+	other := testutil.CreateUser(t, db)
+
+	startSession := func(userID uuid.UUID) *database.EnhancedSession {
+		t.Helper()
+		session, err := database.CreateEnhancedSession(db, uuid.Nil, userID, "refresh-"+uuid.NewString(), "test-agent", "41.66.0.1", time.Now().Add(time.Hour), nil, nil)
+		require.NoError(t, err)
+		return session
+	}
+	status := func(session *database.EnhancedSession) database.SessionStatus {
+		var reloaded database.EnhancedSession
+		require.NoError(t, db.First(&reloaded, "id = ?", session.ID).Error)
+		return reloaded.Status
+	}
+
+	phone, laptop, otherUsers := startSession(user.ID), startSession(user.ID), startSession(other.ID)
+	token, err := database.IssuePasswordResetToken(db, user.ID, time.Hour)
+	require.NoError(t, err)
+	w := resetPassword(handler, token, "Kx7#mVqz!Lb9Rw")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"message":"Password reset successfully","sessions_revoked":2}`, w.Body.String())
+	assert.Equal(t, database.SessionStatusRevoked, status(phone))
+	assert.Equal(t, database.SessionStatusRevoked, status(laptop))
+	assert.Equal(t, database.SessionStatusActive, status(otherUsers))
+
+	// Changing the password can keep the current session signed in
+	current, tablet := startSession(user.ID), startSession(user.ID)
+	body, _ := json.Marshal(PasswordChangeRequest{
+		CurrentPassword:    "Kx7#mVqz!Lb9Rw",
+		NewPassword:        "Tj4$nWpy@Hc8Qe",
+		ConfirmPassword:    "Tj4$nWpy@Hc8Qe",
+		KeepCurrentSession: true,
+	})
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/auth/password", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set(middleware.ContextUserUUID, user.ID)
+	c.Set(middleware.ContextSessionID, current.ID)
+	handler.UpdatePassword(c)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, database.SessionStatusActive, status(current))
+	assert.Equal(t, database.SessionStatusRevoked, status(tablet))
+}
@@ -1,14 +1,18 @@
 package handlers
 
 import (
+	"errors"
 	"io"
 	"net/http"
-	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/currency"
 	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/pagination"
 	"github.com/revaspay/backend/internal/services/payment"
+	"github.com/revaspay/backend/internal/utils"
 )
 
 // PaymentHandler handles payment-related requests
@@ -29,27 +33,23 @@ type CreatePaymentLinkRequest struct {
 	Description string                 `json:"description"`
 	Amount      float64                `json:"amount" binding:"required,gt=0"`
 	Currency    models.Currency        `json:"currency" binding:"required"`
+	Slug        string                 `json:"slug"` // Optional; generated from the title when empty
 	Metadata    map[string]interface{} `json:"metadata"`
 }
 
 // CreatePaymentLink creates a new payment link
 func (h *PaymentHandler) CreatePaymentLink(c *gin.Context) {
 	// Get authenticated user from context
-	userInterface, exists := c.Get("user")
+	user, exists := getUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	user, ok := userInterface.(models.User)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user in context"})
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 
 	// Parse request
 	var req CreatePaymentLinkRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 
@@ -60,10 +60,11 @@ func (h *PaymentHandler) CreatePaymentLink(c *gin.Context) {
 		req.Description,
 		req.Amount,
 		req.Currency,
+		req.Slug,
 		req.Metadata,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.handlePaymentLinkError(c, err)
 		return
 	}
 
@@ -71,28 +72,95 @@ func (h *PaymentHandler) CreatePaymentLink(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{
 		"status":       "success",
 		"payment_link": paymentLink,
-		"payment_url":  "https://revaspay.com/pay/" + paymentLink.Slug,
+		"payment_url":  paymentLinkURL(paymentLink),
 	})
 }
 
+// CreatePaymentLinksRequest represents a request to create payment links in bulk
+type CreatePaymentLinksRequest struct {
+	Links []CreatePaymentLinkRequest `json:"links" binding:"required,min=1,dive"`
+}
+
+// CreatePaymentLinks creates a batch of payment links. Nothing is created unless every link
+// is valid; rejected links are reported by their position in the batch.
+func (h *PaymentHandler) CreatePaymentLinks(c *gin.Context) {
+	user, exists := getUser(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	var req CreatePaymentLinksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	}
+
+	inputs := make([]payment.PaymentLinkInput, len(req.Links))
+	for i, link := range req.Links {
+		inputs[i] = payment.PaymentLinkInput{
+			Title:       link.Title,
+			Description: link.Description,
+			Amount:      link.Amount,
+			Currency:    link.Currency,
+			Slug:        link.Slug,
+			Metadata:    link.Metadata,
+		}
+	}
+
+	paymentLinks, err := h.paymentService.CreatePaymentLinks(user.ID, inputs)
+	if err != nil {
+		var batchErr *payment.PaymentLinkBatchError
+		switch {
+		case errors.As(err, &batchErr):
+			items := make([]gin.H, len(batchErr.Items))
+			for i, item := range batchErr.Items {
+				items[i] = gin.H{"index": item.Index, "error": item.Err.Error()}
+			}
+			respondError(c, http.StatusUnprocessableEntity, ErrCodeValidation, "some payment links are invalid; none were created", gin.H{
+				"links": items,
+			})
+		case errors.Is(err, payment.ErrPaymentLinkBatchEmpty),
+			errors.Is(err, payment.ErrPaymentLinkBatchTooLarge):
+			respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		default:
+			h.handlePaymentLinkError(c, err)
+		}
+		return
+	}
+
+	created := make([]gin.H, len(paymentLinks))
+	for i, paymentLink := range paymentLinks {
+		created[i] = gin.H{
+			"payment_link": paymentLink,
+			"payment_url":  paymentLinkURL(paymentLink),
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":        "success",
+		"payment_links": created,
+	})
+}
+
+// paymentLinkURL returns the public URL customers pay a link at
+func paymentLinkURL(paymentLink *models.PaymentLink) string {
+	return "https://revaspay.com/pay/" + paymentLink.Slug
+}
+
 // GetPaymentLinks gets all payment links for the authenticated user
 func (h *PaymentHandler) GetPaymentLinks(c *gin.Context) {
 	// Get authenticated user from context
-	userInterface, exists := c.Get("user")
+	user, exists := getUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	user, ok := userInterface.(models.User)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user in context"})
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 
 	// Get payment links
 	paymentLinks, err := h.paymentService.GetUserPaymentLinks(user.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 
@@ -106,14 +174,9 @@ func (h *PaymentHandler) GetPaymentLinks(c *gin.Context) {
 // GetPaymentLink gets a payment link by ID
 func (h *PaymentHandler) GetPaymentLink(c *gin.Context) {
 	// Get authenticated user from context
-	userInterface, exists := c.Get("user")
+	user, exists := getUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	user, ok := userInterface.(models.User)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user in context"})
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 
@@ -121,20 +184,20 @@ func (h *PaymentHandler) GetPaymentLink(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment link ID"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid payment link ID", nil)
 		return
 	}
 
 	// Get payment link
 	paymentLink, err := h.paymentService.GetPaymentLink(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "payment link not found"})
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "payment link not found", nil)
 		return
 	}
 
 	// Check if user owns the payment link
 	if paymentLink.UserID != user.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		respondError(c, http.StatusForbidden, ErrCodeForbidden, "forbidden", nil)
 		return
 	}
 
@@ -142,7 +205,7 @@ func (h *PaymentHandler) GetPaymentLink(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":       "success",
 		"payment_link": paymentLink,
-		"payment_url":  "https://revaspay.com/pay/" + paymentLink.Slug,
+		"payment_url":  paymentLinkURL(paymentLink),
 	})
 }
 
@@ -159,14 +222,9 @@ type UpdatePaymentLinkRequest struct {
 // UpdatePaymentLink updates a payment link
 func (h *PaymentHandler) UpdatePaymentLink(c *gin.Context) {
 	// Get authenticated user from context
-	userInterface, exists := c.Get("user")
+	user, exists := getUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	user, ok := userInterface.(models.User)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user in context"})
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 
@@ -174,14 +232,14 @@ func (h *PaymentHandler) UpdatePaymentLink(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment link ID"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid payment link ID", nil)
 		return
 	}
 
 	// Parse request
 	var req UpdatePaymentLinkRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 
@@ -195,7 +253,7 @@ func (h *PaymentHandler) UpdatePaymentLink(c *gin.Context) {
 	}
 	if req.Amount != nil {
 		if *req.Amount <= 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be greater than 0"})
+			respondError(c, http.StatusBadRequest, ErrCodeValidation, "amount must be greater than 0", nil)
 			return
 		}
 		updates["amount"] = *req.Amount
@@ -214,7 +272,7 @@ func (h *PaymentHandler) UpdatePaymentLink(c *gin.Context) {
 	// Update payment link
 	paymentLink, err := h.paymentService.UpdatePaymentLink(id, user.ID, updates)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.handlePaymentLinkError(c, err)
 		return
 	}
 
@@ -222,21 +280,16 @@ func (h *PaymentHandler) UpdatePaymentLink(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":       "success",
 		"payment_link": paymentLink,
-		"payment_url":  "https://revaspay.com/pay/" + paymentLink.Slug,
+		"payment_url":  paymentLinkURL(paymentLink),
 	})
 }
 
 // DeletePaymentLink deletes a payment link
 func (h *PaymentHandler) DeletePaymentLink(c *gin.Context) {
 	// Get authenticated user from context
-	userInterface, exists := c.Get("user")
+	user, exists := getUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	user, ok := userInterface.(models.User)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user in context"})
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 
@@ -244,13 +297,13 @@ func (h *PaymentHandler) DeletePaymentLink(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment link ID"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid payment link ID", nil)
 		return
 	}
 
 	// Delete payment link
 	if err := h.paymentService.DeletePaymentLink(id, user.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.handlePaymentLinkError(c, err)
 		return
 	}
 
@@ -260,64 +313,179 @@ func (h *PaymentHandler) DeletePaymentLink(c *gin.Context) {
 	})
 }
 
-// InitiatePaymentRequest represents a request to initiate a payment
+// handlePaymentLinkError maps payment link errors to HTTP responses
+func (h *PaymentHandler) handlePaymentLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, payment.ErrPaymentLinkNotFound):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "payment link not found", nil)
+	case errors.Is(err, payment.ErrPaymentLinkInactive):
+		respondError(c, http.StatusGone, ErrCodePaymentLinkInactive, err.Error(), nil)
+	case errors.Is(err, payment.ErrInvalidSlug):
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+	case errors.Is(err, payment.ErrSlugTaken):
+		respondError(c, http.StatusConflict, ErrCodeConflict, err.Error(), nil)
+	default:
+		h.handlePaymentError(c, err)
+	}
+}
+
+// handlePaymentError maps payment errors to HTTP responses
+func (h *PaymentHandler) handlePaymentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, payment.ErrAmountBelowMinimum),
+		errors.Is(err, payment.ErrAmountAboveMaximum):
+		respondError(c, http.StatusBadRequest, ErrCodeLimitExceeded, err.Error(), nil)
+	case errors.Is(err, currency.ErrUnsupportedCurrency):
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+	case errors.Is(err, payment.ErrMetadataTooLarge):
+		respondError(c, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, err.Error(), nil)
+	case errors.Is(err, payment.ErrReservedMetadataKey),
+		errors.Is(err, payment.ErrMetadataSchemaViolation):
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidMetadata, err.Error(), nil)
+	case errors.Is(err, payment.ErrInvalidMetadataSchema):
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+	case errors.Is(err, payment.ErrMetadataSchemaNotFound):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, err.Error(), nil)
+	case errors.Is(err, payment.ErrProviderUnavailable):
+		respondProviderUnavailable(c, err)
+	case errors.Is(err, payment.ErrNoProviderRoute):
+		respondError(c, http.StatusUnprocessableEntity, ErrCodeValidation, err.Error(), nil)
+	case errors.Is(err, payment.ErrInvalidSplit),
+		errors.Is(err, payment.ErrSplitsExceedNetAmount):
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+	case errors.Is(err, payment.ErrSplitRecipientNotFound):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, err.Error(), nil)
+	case utils.IsTimeout(err):
+		respondError(c, http.StatusGatewayTimeout, ErrCodeProviderTimeout, "payment provider did not respond in time", nil)
+	default:
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
+	}
+}
+
+// respondProviderTimeout reports a payment the provider didn't confirm in time. The payment
+// stays pending and settles once it's verified or the provider's webhook arrives.
+func respondProviderTimeout(c *gin.Context, p *models.Payment) {
+	respondError(c, http.StatusGatewayTimeout, ErrCodeProviderTimeout,
+		"payment provider did not respond in time; the payment is pending", gin.H{
+			"reference": p.Reference,
+			"status":    p.Status,
+		})
+}
+
+// initiatedPaymentResponse is the response to starting a payment. When the provider needs
+// the customer to complete an action first, such as a 3D Secure challenge, requires_action is
+// set and the action is included for the frontend to handle before confirming the payment.
+func initiatedPaymentResponse(p *models.Payment, initiation *models.PaymentInitiation) gin.H {
+	response := gin.H{
+		"status":          "success",
+		"payment":         p,
+		"checkout_url":    initiation.CheckoutURL,
+		"requires_action": initiation.Action != nil,
+	}
+	if initiation.Action != nil {
+		response["action"] = initiation.Action
+	}
+	return response
+}
+
+// InitiatePaymentRequest represents a request to initiate a payment. Without a provider, one
+// is picked from the payment routes, using the customer's country if it's given.
 type InitiatePaymentRequest struct {
-	Provider      models.PaymentProvider `json:"provider" binding:"required"`
+	Provider      models.PaymentProvider `json:"provider"`
+	Country       string                 `json:"country" binding:"omitempty,len=2"`
 	Amount        float64                `json:"amount" binding:"required,gt=0"`
 	Currency      models.Currency        `json:"currency" binding:"required"`
 	Description   string                 `json:"description"`
 	CustomerEmail string                 `json:"customer_email" binding:"required,email"`
 	CustomerName  string                 `json:"customer_name" binding:"required"`
 	Metadata      map[string]interface{} `json:"metadata"`
+	Splits        []PaymentSplitRequest  `json:"splits" binding:"omitempty,dive"`
+}
+
+// PaymentSplitRequest shares a payment with another merchant's wallet or, with platform_fee
+// set, the platform. It has either an amount or a percentage of the net amount.
+type PaymentSplitRequest struct {
+	WalletID    *uuid.UUID `json:"wallet_id"`
+	PlatformFee bool       `json:"platform_fee"`
+	Amount      float64    `json:"amount" binding:"gte=0"`
+	Percentage  float64    `json:"percentage" binding:"gte=0,lte=100"`
+}
+
+// paymentSplits converts split requests to the service's inputs
+func paymentSplits(requests []PaymentSplitRequest) []payment.SplitInput {
+	splits := make([]payment.SplitInput, 0, len(requests))
+	for _, r := range requests {
+		splits = append(splits, payment.SplitInput{
+			WalletID:    r.WalletID,
+			PlatformFee: r.PlatformFee,
+			Amount:      r.Amount,
+			Percentage:  r.Percentage,
+		})
+	}
+	return splits
 }
 
 // InitiatePayment initiates a payment
 func (h *PaymentHandler) InitiatePayment(c *gin.Context) {
 	// Get authenticated user from context
-	userInterface, exists := c.Get("user")
+	user, exists := getUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	user, ok := userInterface.(models.User)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user in context"})
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 
 	// Parse request
 	var req InitiatePaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	}
+
+	var payment *models.Payment
+	var initiation *models.PaymentInitiation
+	var err error
+	if req.Provider == "" {
+		payment, initiation, err = h.paymentService.InitiateRoutedPayment(
+			c.Request.Context(),
+			user.ID,
+			req.Country,
+			req.Amount,
+			req.Currency,
+			req.CustomerEmail,
+			req.CustomerName,
+			req.Metadata,
+			paymentSplits(req.Splits),
+		)
+	} else {
+		payment, initiation, err = h.paymentService.InitiatePayment(
+			c.Request.Context(),
+			user.ID,
+			req.Provider,
+			req.Amount,
+			req.Currency,
+			req.CustomerEmail,
+			req.CustomerName,
+			req.Metadata,
+			paymentSplits(req.Splits),
+		)
 	}
-
-	// Adjust arguments to match service method signature
-	payment, checkoutURL, err := h.paymentService.InitiatePayment(
-		user.ID,
-		req.Provider,
-		req.Amount,
-		req.Currency,
-		req.Description,
-		req.CustomerEmail,
-		req.Metadata,
-	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		// Only a provider timeout returns the payment along with an error
+		if payment != nil {
+			respondProviderTimeout(c, payment)
+			return
+		}
+		h.handlePaymentError(c, err)
 		return
 	}
 
-	// Return payment
-	c.JSON(http.StatusOK, gin.H{
-		"status":       "success",
-		"payment":      payment,
-		"checkout_url": checkoutURL,
-	})
+	c.JSON(http.StatusOK, initiatedPaymentResponse(payment, initiation))
 }
 
 // InitiatePaymentFromLinkRequest represents a request to initiate a payment from a link
 type InitiatePaymentFromLinkRequest struct {
-	Provider      models.PaymentProvider `json:"provider" binding:"required"`
+	Provider      models.PaymentProvider `json:"provider"` // Picked from the payment routes when empty
+	Country       string                 `json:"country" binding:"omitempty,len=2"`
 	CustomerEmail string                 `json:"customer_email" binding:"required,email"`
 	CustomerName  string                 `json:"customer_name" binding:"required"`
 }
@@ -327,42 +495,44 @@ func (h *PaymentHandler) InitiatePaymentFromLink(c *gin.Context) {
 	// Get payment link slug
 	slug := c.Param("slug")
 	if slug == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment link slug"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid payment link slug", nil)
 		return
 	}
 
 	// Parse request
 	var req InitiatePaymentFromLinkRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 
 	// First, get the payment link by slug to get its ID
 	paymentLink, err := h.paymentService.GetPaymentLinkBySlug(slug)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "payment link not found"})
+		h.handlePaymentLinkError(c, err)
 		return
 	}
 
 	// Now use the UUID from the payment link
-	payment, checkoutURL, err := h.paymentService.InitiatePaymentFromLink(
+	payment, initiation, err := h.paymentService.InitiatePaymentFromLink(
+		c.Request.Context(),
 		paymentLink.ID,
 		req.Provider,
+		req.Country,
 		req.CustomerEmail,
 		req.CustomerName,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		// Only a provider timeout returns the payment along with an error
+		if payment != nil {
+			respondProviderTimeout(c, payment)
+			return
+		}
+		h.handlePaymentLinkError(c, err)
 		return
 	}
 
-	// Return payment
-	c.JSON(http.StatusOK, gin.H{
-		"status":       "success",
-		"payment":      payment,
-		"checkout_url": checkoutURL,
-	})
+	c.JSON(http.StatusOK, initiatedPaymentResponse(payment, initiation))
 }
 
 // VerifyPayment verifies a payment
@@ -370,14 +540,14 @@ func (h *PaymentHandler) VerifyPayment(c *gin.Context) {
 	// Get payment reference
 	reference := c.Param("reference")
 	if reference == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment reference"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid payment reference", nil)
 		return
 	}
 
 	// Verify payment
-	payment, err := h.paymentService.VerifyPayment(reference)
+	payment, err := h.paymentService.VerifyPayment(c.Request.Context(), reference)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.handlePaymentError(c, err)
 		return
 	}
 
@@ -388,65 +558,145 @@ func (h *PaymentHandler) VerifyPayment(c *gin.Context) {
 	})
 }
 
-// GetPayments gets all payments for the authenticated user
-func (h *PaymentHandler) GetPayments(c *gin.Context) {
-	// Get authenticated user from context
-	userInterface, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+// ConfirmPaymentRequest represents the result of an action the customer completed, such as
+// the OTP they were sent. Redirect actions usually have no data.
+type ConfirmPaymentRequest struct {
+	ActionData map[string]string `json:"action_data"`
+}
+
+// ConfirmPayment completes a payment that required the customer to complete an action. The
+// provider may need another action, which is returned the same way as when starting a payment.
+func (h *PaymentHandler) ConfirmPayment(c *gin.Context) {
+	reference := c.Param("reference")
+	if reference == "" || len(reference) > maxPaymentReferenceLength {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid payment reference", nil)
 		return
 	}
-	user, ok := userInterface.(models.User)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user in context"})
+
+	var req ConfirmPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 
-	// Get pagination parameters
-	pageStr := c.DefaultQuery("page", "1")
-	pageSizeStr := c.DefaultQuery("page_size", "10")
+	confirmed, action, err := h.paymentService.ConfirmPayment(c.Request.Context(), reference, req.ActionData)
+	if errors.Is(err, payment.ErrPaymentReferenceNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "payment not found", nil)
+		return
+	}
+	if errors.Is(err, payment.ErrNoActionRequired) {
+		respondError(c, http.StatusConflict, ErrCodeConflict, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		h.handlePaymentError(c, err)
+		return
+	}
 
-	// Parse pagination parameters
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		page = 1
+	response := gin.H{
+		"status":          "success",
+		"payment":         confirmed,
+		"requires_action": action != nil,
 	}
-	pageSize, err := strconv.Atoi(pageSizeStr)
-	if err != nil || pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+	if action != nil {
+		response["action"] = action
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// maxPaymentReferenceLength is the longest payment reference stored, so longer lookups can't match
+const maxPaymentReferenceLength = 100
+
+// GetPublicPaymentStatus returns a payment's status without authentication, for the hosted
+// checkout page to poll after the customer returns from the provider. Only the amount, status
+// and merchant are returned, never the customer's details.
+func (h *PaymentHandler) GetPublicPaymentStatus(c *gin.Context) {
+	reference := c.Param("reference")
+	if reference == "" || len(reference) > maxPaymentReferenceLength {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid payment reference", nil)
+		return
 	}
 
+	summary, err := h.paymentService.GetPaymentStatusSummary(reference)
+	if errors.Is(err, payment.ErrPaymentReferenceNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "payment not found", nil)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to get payment status", nil)
+		return
+	}
+
+	// The status changes while the page polls, so it must not be cached
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"payment": summary,
+	})
+}
+
+// GetPayments gets all payments for the authenticated user
+func (h *PaymentHandler) GetPayments(c *gin.Context) {
+	// Get authenticated user from context
+	user, exists := getUser(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	filter, err := parsePaymentFilter(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	}
+	params := pagination.ParseParams(c, 10)
+
 	// Get payments
-	payments, total, err := h.paymentService.GetUserPayments(user.ID, page, pageSize)
+	payments, total, err := h.paymentService.GetUserPayments(user.ID, filter, params.Page, params.PageSize)
+	if errors.Is(err, payment.ErrInvalidPaymentStatus) {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 
-	// Return payments with proper type conversion for pagination calculation
 	c.JSON(http.StatusOK, gin.H{
 		"status":   "success",
 		"payments": payments,
-		"meta": gin.H{
-			"page":       page,
-			"page_size":  pageSize,
-			"total":      total,
-			"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
-		},
+		"meta":     pagination.NewMeta(params, total),
 	})
 }
 
+// parsePaymentFilter reads the payment list filters from the query. Dates are RFC 3339 times
+// or YYYY-MM-DD days, with to including the whole day.
+func parsePaymentFilter(c *gin.Context) (payment.PaymentFilter, error) {
+	filter := payment.PaymentFilter{
+		Status:    models.PaymentStatus(strings.ToLower(c.Query("status"))),
+		Provider:  models.PaymentProvider(strings.ToLower(c.Query("provider"))),
+		Currency:  models.Currency(strings.ToUpper(c.Query("currency"))),
+		Reference: strings.TrimSpace(c.Query("reference")),
+	}
+	var err error
+	if filter.From, err = parseStatementDate(c.Query("from"), false); err != nil {
+		return filter, errors.New("invalid from date")
+	}
+	if filter.To, err = parseStatementDate(c.Query("to"), true); err != nil {
+		return filter, errors.New("invalid to date")
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, errors.New("from must be before to")
+	}
+	return filter, nil
+}
+
 // GetPayment gets a payment by ID
 func (h *PaymentHandler) GetPayment(c *gin.Context) {
 	// Get authenticated user from context
-	userInterface, exists := c.Get("user")
+	user, exists := getUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	user, ok := userInterface.(models.User)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user in context"})
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 
@@ -454,20 +704,20 @@ func (h *PaymentHandler) GetPayment(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment ID"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid payment ID", nil)
 		return
 	}
 
 	// Get payment
 	payment, err := h.paymentService.GetPayment(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "payment not found"})
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "payment not found", nil)
 		return
 	}
 
 	// Check if user owns the payment
 	if payment.UserID != user.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		respondError(c, http.StatusForbidden, ErrCodeForbidden, "forbidden", nil)
 		return
 	}
 
@@ -478,6 +728,44 @@ func (h *PaymentHandler) GetPayment(c *gin.Context) {
 	})
 }
 
+// GetPaymentEvents returns the provider events received for a payment the user owns, oldest
+// first, so merchants can see why a payment is in its current state
+func (h *PaymentHandler) GetPaymentEvents(c *gin.Context) {
+	user, exists := getUser(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid payment ID", nil)
+		return
+	}
+
+	payment, err := h.paymentService.GetPayment(id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "payment not found", nil)
+		return
+	}
+	if payment.UserID != user.ID {
+		respondError(c, http.StatusForbidden, ErrCodeForbidden, "forbidden", nil)
+		return
+	}
+
+	events, err := h.paymentService.GetPaymentEvents(payment)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to get payment events", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "success",
+		"payment_status": payment.Status,
+		"events":         events,
+	})
+}
+
 // InitiateCryptoPaymentRequest represents a request to initiate a crypto payment
 type InitiateCryptoPaymentRequest struct {
 	Amount         float64                `json:"amount" binding:"required,gt=0"`
@@ -490,26 +778,22 @@ type InitiateCryptoPaymentRequest struct {
 // InitiateCryptoPayment initiates a cryptocurrency payment
 func (h *PaymentHandler) InitiateCryptoPayment(c *gin.Context) {
 	// Get authenticated user from context
-	userInterface, exists := c.Get("user")
+	user, exists := getUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	user, ok := userInterface.(models.User)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user in context"})
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 
 	// Parse request
 	var req InitiateCryptoPaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 
 	// Initiate crypto payment
 	payment, cryptoPayment, err := h.paymentService.InitiateCryptoPayment(
+		c.Request.Context(),
 		user.ID,
 		req.Amount,
 		req.Currency,
@@ -518,7 +802,7 @@ func (h *PaymentHandler) InitiateCryptoPayment(c *gin.Context) {
 		req.Metadata,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.handlePaymentError(c, err)
 		return
 	}
 
@@ -530,19 +814,29 @@ func (h *PaymentHandler) InitiateCryptoPayment(c *gin.Context) {
 	})
 }
 
-// ProcessPaystackWebhook processes a webhook from Paystack
+// ProcessPaystackWebhook processes a webhook from Paystack. It must be signed with the secret
+// key of the account it's for, in X-Paystack-Signature.
 func (h *PaymentHandler) ProcessPaystackWebhook(c *gin.Context) {
 	// Read request body
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		respondBodyError(c, err)
+		return
+	}
+
+	if err := h.paymentService.VerifyWebhook(models.PaymentProviderPaystack, body, c.GetHeader("X-Paystack-Signature")); err != nil {
+		if errors.Is(err, payment.ErrInvalidWebhookSignature) {
+			respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "invalid webhook signature", nil)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 
 	// Process webhook
 	webhook, err := h.paymentService.ProcessWebhook(models.PaymentProviderPaystack, body)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 
@@ -558,14 +852,14 @@ func (h *PaymentHandler) ProcessStripeWebhook(c *gin.Context) {
 	// Read request body
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		respondBodyError(c, err)
 		return
 	}
 
 	// Process webhook
 	webhook, err := h.paymentService.ProcessWebhook(models.PaymentProviderStripe, body)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 
@@ -581,14 +875,14 @@ func (h *PaymentHandler) ProcessPayPalWebhook(c *gin.Context) {
 	// Read request body
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		respondBodyError(c, err)
 		return
 	}
 
 	// Process webhook
 	webhook, err := h.paymentService.ProcessWebhook(models.PaymentProviderPayPal, body)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 
@@ -604,14 +898,14 @@ func (h *PaymentHandler) ProcessCryptoWebhook(c *gin.Context) {
 	// Read request body
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		respondBodyError(c, err)
 		return
 	}
 
 	// Process webhook
 	webhook, err := h.paymentService.ProcessWebhook(models.PaymentProviderCrypto, body)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, err.Error(), nil)
 		return
 	}
 
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/currency"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/payment"
+)
+
+// PaymentLimitHandler handles admin overrides of merchants' payment amount limits
+type PaymentLimitHandler struct {
+	paymentService *payment.PaymentService
+}
+
+// NewPaymentLimitHandler creates a new payment limit handler
+func NewPaymentLimitHandler(paymentService *payment.PaymentService) *PaymentLimitHandler {
+	return &PaymentLimitHandler{
+		paymentService: paymentService,
+	}
+}
+
+// SetPaymentLimitRequest represents a request to override a merchant's payment amount limits
+type SetPaymentLimitRequest struct {
+	MinAmount float64 `json:"min_amount" binding:"required,gt=0"`
+	MaxAmount float64 `json:"max_amount" binding:"required,gt=0"`
+}
+
+// ListPaymentLimits lists a merchant's payment amount limit overrides
+func (h *PaymentLimitHandler) ListPaymentLimits(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	limits, err := h.paymentService.ListMerchantAmountLimits(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payment limits"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   limits,
+	})
+}
+
+// SetPaymentLimit overrides a merchant's payment amount limits in a currency
+func (h *PaymentLimitHandler) SetPaymentLimit(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	currency, ok := parseLimitCurrency(c.Param("currency"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency"})
+		return
+	}
+
+	var req SetPaymentLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := h.paymentService.SetMerchantAmountLimit(c.Request.Context(), adminID, userID, currency, config.AmountLimit{
+		Min: req.MinAmount,
+		Max: req.MaxAmount,
+	})
+	if err != nil {
+		h.handlePaymentLimitError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   limit,
+	})
+}
+
+// DeletePaymentLimit removes a merchant's payment amount limit override in a currency
+func (h *PaymentLimitHandler) DeletePaymentLimit(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	currency, ok := parseLimitCurrency(c.Param("currency"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency"})
+		return
+	}
+
+	if err := h.paymentService.DeleteMerchantAmountLimit(c.Request.Context(), adminID, userID, currency); err != nil {
+		h.handlePaymentLimitError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Payment limit override removed",
+	})
+}
+
+// parseLimitCurrency normalizes a currency code from the URL
+func parseLimitCurrency(value string) (models.Currency, bool) {
+	value = strings.ToUpper(value)
+	if len(value) != 3 {
+		return "", false
+	}
+	for _, r := range value {
+		if r < 'A' || r > 'Z' {
+			return "", false
+		}
+	}
+	return models.Currency(value), true
+}
+
+// handlePaymentLimitError maps payment limit errors to HTTP responses
+func (h *PaymentLimitHandler) handlePaymentLimitError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, payment.ErrInvalidAmountLimit), errors.Is(err, currency.ErrUnsupportedCurrency):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, payment.ErrAmountLimitNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment limit"})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/models"
+)
+
+// SetMetadataSchemaRequest represents a request to set the merchant's payment metadata schema
+type SetMetadataSchemaRequest struct {
+	Fields           []models.MetadataField `json:"fields" binding:"required"`
+	AllowUnknownKeys bool                   `json:"allow_unknown_keys"`
+}
+
+// GetMetadataSchema gets the authenticated merchant's payment metadata schema
+func (h *PaymentHandler) GetMetadataSchema(c *gin.Context) {
+	user, exists := getUser(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	schema, err := h.paymentService.GetMetadataSchema(user.ID)
+	if err != nil {
+		h.handlePaymentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":          "success",
+		"metadata_schema": schema,
+	})
+}
+
+// SetMetadataSchema creates or replaces the authenticated merchant's payment metadata schema
+func (h *PaymentHandler) SetMetadataSchema(c *gin.Context) {
+	user, exists := getUser(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	var req SetMetadataSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	}
+
+	schema, err := h.paymentService.SetMetadataSchema(user.ID, req.Fields, req.AllowUnknownKeys)
+	if err != nil {
+		h.handlePaymentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":          "success",
+		"metadata_schema": schema,
+	})
+}
+
+// DeleteMetadataSchema removes the authenticated merchant's payment metadata schema
+func (h *PaymentHandler) DeleteMetadataSchema(c *gin.Context) {
+	user, exists := getUser(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	if err := h.paymentService.DeleteMetadataSchema(user.ID); err != nil {
+		h.handlePaymentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Metadata schema removed",
+	})
+}
@@ -1,22 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
-	"os"
-	"path"
-	"path/filepath"
-	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/revaspay/backend/internal/database"
 	"github.com/revaspay/backend/internal/security/audit"
-	"github.com/revaspay/backend/internal/utils"
+	"github.com/revaspay/backend/internal/services/profileimage"
+	"github.com/revaspay/backend/internal/services/storage"
 	"gorm.io/gorm"
 )
 
@@ -24,7 +19,7 @@ import (
 type ProfileHandler struct {
 	db          *gorm.DB
 	auditLogger *audit.Logger
-	uploadDir   string
+	images      *profileimage.Service
 }
 
 // ProfileUpdateRequest represents a request to update a user profile
@@ -38,27 +33,23 @@ type ProfileUpdateRequest struct {
 	BusinessName *string `json:"business_name"`
 	Website      *string `json:"website"`
 	SocialLinks  map[string]string `json:"social_links"`
+	SendPaymentReceipts *bool `json:"send_payment_receipts"`
 }
 
-// NewProfileHandler creates a new profile handler
-func NewProfileHandler(db *gorm.DB) *ProfileHandler {
-	// Create uploads directory if it doesn't exist
-	uploadDir := "./uploads/profiles"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		log.Printf("Failed to create upload directory: %v", err)
-	}
-
+// NewProfileHandler creates a new profile handler keeping profile images in store, within
+// the limits set by the PROFILE_IMAGE_* environment variables
+func NewProfileHandler(db *gorm.DB, store storage.Backend) *ProfileHandler {
 	return &ProfileHandler{
 		db:          db,
 		auditLogger: audit.NewLogger(db),
-		uploadDir:   uploadDir,
+		images:      profileimage.NewService(store, profileimage.ConfigFromEnv()),
 	}
 }
 
 // GetProfile gets the user's profile
 func (h *ProfileHandler) GetProfile(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -77,7 +68,7 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 		audit.EventTypeProfile,
 		audit.SeverityInfo,
 		"Profile viewed",
-		userID.(*uuid.UUID),
+		&userID,
 		nil,
 		c.ClientIP(),
 		c.Request.UserAgent(),
@@ -100,6 +91,7 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 			"business_name": user.BusinessName,
 			"website":       user.Website,
 			"social_links":  user.SocialLinks,
+			"send_payment_receipts": user.SendPaymentReceipts,
 			"created_at":    user.CreatedAt,
 			"updated_at":    user.UpdatedAt,
 			"verified":      user.Verified,
@@ -111,7 +103,7 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 // UpdateProfile updates the user's profile
 func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -165,6 +157,10 @@ func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 		user.Website = *req.Website
 		updated = true
 	}
+	if req.SendPaymentReceipts != nil {
+		user.SendPaymentReceipts = *req.SendPaymentReceipts
+		updated = true
+	}
 	if req.SocialLinks != nil && len(req.SocialLinks) > 0 {
 		user.SocialLinks = req.SocialLinks
 		updated = true
@@ -183,7 +179,7 @@ func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 			audit.EventTypeProfile,
 			audit.SeverityInfo,
 			"Profile updated",
-			userID.(*uuid.UUID),
+			&userID,
 			nil,
 			c.ClientIP(),
 			c.Request.UserAgent(),
@@ -208,16 +204,19 @@ func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 			"business_name": user.BusinessName,
 			"website":       user.Website,
 			"social_links":  user.SocialLinks,
+			"send_payment_receipts": user.SendPaymentReceipts,
 			"created_at":    user.CreatedAt,
 			"updated_at":    user.UpdatedAt,
 		},
 	})
 }
 
-// UploadProfileImage uploads a profile image
+// UploadProfileImage replaces the user's profile image. The upload's type is sniffed from
+// its contents and its size and dimensions are checked; the image is re-encoded to strip
+// metadata, and it and a thumbnail are kept in the storage backend.
 func (h *ProfileHandler) UploadProfileImage(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -231,83 +230,70 @@ func (h *ProfileHandler) UploadProfileImage(c *gin.Context) {
 	}
 	defer file.Close()
 
-	// Validate file type
-	if !isValidImageType(header.Filename) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only JPG, PNG, and GIF are allowed"})
-		return
-	}
-
-	// Generate unique filename
-	filename := generateUniqueFilename(userID.(uuid.UUID), filepath.Ext(header.Filename))
-	filepath := filepath.Join(h.uploadDir, filename)
-
-	// Create file
-	out, err := os.Create(filepath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+	// Get user profile
+	var user database.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find user"})
 		return
 	}
-	defer out.Close()
 
-	// Copy file content
-	_, err = io.Copy(out, file)
+	// Validate and store the image
+	key, img, err := h.images.Store(c.Request.Context(), userID, file)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		h.respondImageError(c, err)
 		return
 	}
 
-	// Update user profile
-	var user database.User
-	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find user"})
+	// Update profile image
+	oldKey := user.ProfileImage
+	if err := h.db.Model(&user).Update("profile_image", key).Error; err != nil {
+		if err := h.images.Delete(c.Request.Context(), key); err != nil {
+			log.Printf("Failed to delete unused profile image: %v", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
 		return
 	}
 
 	// Delete old profile image if exists
-	if user.ProfileImage != "" && user.ProfileImage != filename {
-		// Use path/filepath package explicitly to avoid any shadowing issues
-		oldFilepath := path.Join(h.uploadDir, user.ProfileImage)
-		if err := os.Remove(oldFilepath); err != nil {
+	if oldKey != "" {
+		if err := h.images.Delete(c.Request.Context(), oldKey); err != nil {
 			log.Printf("Failed to delete old profile image: %v", err)
 		}
 	}
 
-	// Update profile image
-	user.ProfileImage = filename
-	if err := h.db.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
-		return
-	}
-
 	// Log profile image upload
 	h.auditLogger.LogWithContext(
 		c,
 		audit.EventTypeProfile,
 		audit.SeverityInfo,
 		"Profile image uploaded",
-		userID.(*uuid.UUID),
+		&userID,
 		nil,
 		c.ClientIP(),
 		c.Request.UserAgent(),
 		true,
 		map[string]interface{}{
-			"filename": filename,
+			"filename": header.Filename,
 			"filesize": header.Size,
+			"width":    img.Width,
+			"height":   img.Height,
 		},
 	)
 
 	// Return success
+	imageURL := profileImageURL(userID)
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Profile image uploaded successfully",
-		"profile_image": filename,
-		"profile_image_url": fmt.Sprintf("/uploads/profiles/%s", filename),
+		"message":             "Profile image uploaded successfully",
+		"profile_image":       key,
+		"profile_image_url":   imageURL,
+		"thumbnail_image_url": imageURL + "?size=thumbnail",
 	})
 }
 
 // DeleteProfileImage deletes a profile image
 func (h *ProfileHandler) DeleteProfileImage(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -327,14 +313,12 @@ func (h *ProfileHandler) DeleteProfileImage(c *gin.Context) {
 	}
 
 	// Delete profile image
-	filepath := filepath.Join(h.uploadDir, user.ProfileImage)
-	if err := os.Remove(filepath); err != nil {
+	if err := h.images.Delete(c.Request.Context(), user.ProfileImage); err != nil {
 		log.Printf("Failed to delete profile image: %v", err)
 	}
 
 	// Update user profile
-	user.ProfileImage = ""
-	if err := h.db.Save(&user).Error; err != nil {
+	if err := h.db.Model(&user).Update("profile_image", "").Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
 		return
 	}
@@ -345,7 +329,7 @@ func (h *ProfileHandler) DeleteProfileImage(c *gin.Context) {
 		audit.EventTypeProfile,
 		audit.SeverityInfo,
 		"Profile image deleted",
-		userID.(*uuid.UUID),
+		&userID,
 		nil,
 		c.ClientIP(),
 		c.Request.UserAgent(),
@@ -359,35 +343,59 @@ func (h *ProfileHandler) DeleteProfileImage(c *gin.Context) {
 	})
 }
 
-// Helper functions
+// ServeProfileImage streams a user's profile image, or its thumbnail with ?size=thumbnail.
+// Profile images are public, so no authentication is needed.
+func (h *ProfileHandler) ServeProfileImage(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("userID"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
 
-// isValidImageType checks if the file is a valid image type
-func isValidImageType(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	return ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".gif"
-}
+	var user database.User
+	if err := h.db.Select("id", "profile_image").First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
 
-// generateUniqueFilename generates a unique filename for a profile image
-func generateUniqueFilename(userID uuid.UUID, ext string) string {
-	randomStr, err := utils.GenerateRandomString(8)
+	object, info, err := h.images.Open(c.Request.Context(), user.ProfileImage, c.Query("size") == "thumbnail")
 	if err != nil {
-		// Fallback to timestamp if random generation fails
-		randomStr = fmt.Sprintf("%d", time.Now().UnixNano())
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+		log.Printf("Failed to read profile image: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read profile image"})
+		return
 	}
-	return fmt.Sprintf("%s-%s%s", userID.String(), randomStr, ext)
+	defer object.Close()
+
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, object, map[string]string{
+		"Cache-Control":           "public, max-age=300",
+		"Content-Security-Policy": "default-src 'none'",
+		"X-Content-Type-Options":  "nosniff",
+	})
 }
 
-// validateFile validates a file upload
-func validateFile(file multipart.File, header *multipart.FileHeader, maxSize int64) error {
-	// Check file size
-	if header.Size > maxSize {
-		return fmt.Errorf("file too large (max %d bytes)", maxSize)
-	}
+// Helper functions
 
-	// Check file type
-	if !isValidImageType(header.Filename) {
-		return fmt.Errorf("invalid file type")
+// respondImageError reports why a profile image was rejected
+func (h *ProfileHandler) respondImageError(c *gin.Context, err error) {
+	config := h.images.Config()
+	switch {
+	case errors.Is(err, profileimage.ErrImageTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Profile image must be at most %d MB", config.MaxSize>>20)})
+	case errors.Is(err, profileimage.ErrUnsupportedImage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Profile image must be a " + config.DescribeTypes() + " image"})
+	case errors.Is(err, profileimage.ErrImageDimensions):
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Profile image must be at most %dx%d pixels", config.MaxWidth, config.MaxHeight)})
+	default:
+		log.Printf("Failed to store profile image: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
 	}
+}
 
-	return nil
+// profileImageURL returns the URL a user's profile image is served at
+func profileImageURL(userID uuid.UUID) string {
+	return "/api/profile-images/" + userID.String()
 }
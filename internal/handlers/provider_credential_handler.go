@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/payment"
+)
+
+// ProviderCredentialHandler handles admin management of merchants' own payment provider accounts
+type ProviderCredentialHandler struct {
+	paymentService *payment.PaymentService
+}
+
+// NewProviderCredentialHandler creates a new provider credential handler
+func NewProviderCredentialHandler(paymentService *payment.PaymentService) *ProviderCredentialHandler {
+	return &ProviderCredentialHandler{
+		paymentService: paymentService,
+	}
+}
+
+// SetProviderCredentialRequest represents a request to store a merchant's provider credentials
+type SetProviderCredentialRequest struct {
+	AccountID string `json:"account_id"` // Provider's ID for the account in webhooks, such as a Paystack subaccount code
+	SecretKey string `json:"secret_key" binding:"required"`
+	PublicKey string `json:"public_key"`
+}
+
+// ListProviderCredentials lists the providers a merchant has their own credentials for.
+// Secret keys are never returned.
+func (h *ProviderCredentialHandler) ListProviderCredentials(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	credentials, err := h.paymentService.ListProviderCredentials(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get provider credentials"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   credentials,
+	})
+}
+
+// SetProviderCredential stores a merchant's credentials for their own account with a provider
+func (h *ProviderCredentialHandler) SetProviderCredential(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req SetProviderCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credential, err := h.paymentService.SetProviderCredential(c.Request.Context(), adminID, userID, parseCredentialProvider(c.Param("provider")), req.AccountID, payment.ProviderCredentials{
+		SecretKey: req.SecretKey,
+		PublicKey: req.PublicKey,
+	})
+	if err != nil {
+		h.handleProviderCredentialError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   credential,
+	})
+}
+
+// DeleteProviderCredential removes a merchant's credentials for a provider, so their payments
+// go through the platform's account again
+func (h *ProviderCredentialHandler) DeleteProviderCredential(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.paymentService.DeleteProviderCredential(c.Request.Context(), adminID, userID, parseCredentialProvider(c.Param("provider"))); err != nil {
+		h.handleProviderCredentialError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Provider credentials removed",
+	})
+}
+
+// parseCredentialProvider normalizes a provider name from the URL
+func parseCredentialProvider(value string) models.PaymentProvider {
+	return models.PaymentProvider(strings.ToLower(strings.TrimSpace(value)))
+}
+
+// handleProviderCredentialError maps provider credential errors to HTTP responses
+func (h *ProviderCredentialHandler) handleProviderCredentialError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, payment.ErrMerchantAccountsUnsupported), errors.Is(err, payment.ErrSecretKeyRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, payment.ErrProviderCredentialNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, payment.ErrAccountIDInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update provider credentials"})
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/pagination"
+	"github.com/revaspay/backend/internal/services/reconciliation"
+	"gorm.io/gorm"
+)
+
+// ReconciliationReportHandler handles admin access to provider reconciliation reports
+type ReconciliationReportHandler struct {
+	reconciliationService *reconciliation.Service
+}
+
+// NewReconciliationReportHandler creates a new reconciliation report handler
+func NewReconciliationReportHandler(db *gorm.DB) *ReconciliationReportHandler {
+	return &ReconciliationReportHandler{
+		reconciliationService: reconciliation.NewService(db),
+	}
+}
+
+// ListReports lists reconciliation reports, optionally filtered by provider, status and the
+// date range their periods start in (from/to, YYYY-MM-DD)
+func (h *ReconciliationReportHandler) ListReports(c *gin.Context) {
+	var filter reconciliation.ReportFilter
+
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+		filter.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+		// The to date is inclusive
+		t = t.AddDate(0, 0, 1)
+		filter.To = &t
+	}
+	filter.Provider = models.PaymentProvider(c.Query("provider"))
+	filter.Status = models.ReconciliationReportStatus(c.Query("status"))
+
+	params := pagination.ParseParams(c, 20)
+
+	reports, total, err := h.reconciliationService.ListReports(filter, params.Page, params.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get reconciliation reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"data":       reports,
+		"pagination": pagination.NewMeta(params, total),
+	})
+}
+
+// GetReport returns a single reconciliation report with its discrepancies
+func (h *ReconciliationReportHandler) GetReport(c *gin.Context) {
+	reportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	report, err := h.reconciliationService.GetReport(reportID)
+	if err != nil {
+		if errors.Is(err, reconciliation.ErrReportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get reconciliation report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   report,
+	})
+}
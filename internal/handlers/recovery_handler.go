@@ -12,7 +12,6 @@ import (
 	"github.com/revaspay/backend/internal/database"
 	"github.com/revaspay/backend/internal/security"
 	"github.com/revaspay/backend/internal/security/audit"
-	"github.com/revaspay/backend/internal/utils"
 	"gorm.io/gorm"
 )
 
@@ -234,20 +233,8 @@ func (h *RecoveryHandler) VerifySecurityQuestions(c *gin.Context) {
 
 // sendRecoveryEmail sends a recovery email to the user
 func (h *RecoveryHandler) sendRecoveryEmail(user *database.User) {
-	// Generate password reset token
-	token := utils.GenerateSecureToken(32)
-	// GenerateSecureToken doesn't return an error
-
-	// Create password reset token record
-	resetToken := database.PasswordResetToken{
-		UserID:    user.ID.String(),
-		Token:     token,
-		ExpiresAt: time.Now().Add(24 * time.Hour).Unix(), // Token expires in 24 hours
-		CreatedAt: time.Now().Unix(),
-	}
-
-	// Save to database
-	if err := h.db.Create(&resetToken).Error; err != nil {
+	// Generate a password reset token that expires in 24 hours, replacing any earlier ones
+	if _, err := database.IssuePasswordResetToken(h.db, user.ID, 24*time.Hour); err != nil {
 		return
 	}
 
@@ -377,26 +364,8 @@ func (h *RecoveryHandler) CompleteRecovery(c *gin.Context) {
 		)
 	}
 
-	// Revoke all sessions for this user
-	// Using RevokeAllUserSessionsExcept with a nil UUID to revoke all sessions
-	emptyUUID := uuid.Nil
-	if err := database.RevokeAllUserSessionsExcept(h.db, user.ID, emptyUUID); err != nil {
-		// Just log the error but don't fail the request
-		h.auditLogger.LogWithContext(
-			context.Background(),
-			audit.EventTypeAuth,
-			audit.SeverityWarning,
-			"Failed to revoke sessions after account recovery",
-			&user.ID,
-			nil,
-			"",
-			"",
-			false,
-			map[string]interface{}{
-				"error": err.Error(),
-			},
-		)
-	}
+	// Revoke all sessions for this user, including refresh tokens
+	revoked := revokeSessionsAfterPasswordChange(c, h.db, h.auditLogger, user.ID, "Account recovered", nil)
 
 	// Log successful recovery
 	h.auditLogger.LogWithContext(
@@ -413,6 +382,7 @@ func (h *RecoveryHandler) CompleteRecovery(c *gin.Context) {
 	)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Account recovered successfully",
+		"message":          "Account recovered successfully",
+		"sessions_revoked": revoked,
 	})
 }
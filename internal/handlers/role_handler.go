@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/services/rbac"
+	"gorm.io/gorm"
+)
+
+// RoleHandler handles admin management of staff roles
+type RoleHandler struct {
+	rbacService *rbac.Service
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(db *gorm.DB) *RoleHandler {
+	return &RoleHandler{
+		rbacService: rbac.NewService(db),
+	}
+}
+
+// ListRoles lists the roles that can be assigned and the permissions they grant
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	roles, err := h.rbacService.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   roles,
+	})
+}
+
+// GetUserRoles lists the roles assigned to a user
+func (h *RoleHandler) GetUserRoles(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	roles, err := h.rbacService.UserRoles(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   roles,
+	})
+}
+
+// AssignRole grants a role to a user
+func (h *RoleHandler) AssignRole(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	assignment, err := h.rbacService.AssignRole(c.Request.Context(), adminID, userID, c.Param("role"))
+	if err != nil {
+		h.handleRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   assignment,
+	})
+}
+
+// RemoveRole takes a role away from a user
+func (h *RoleHandler) RemoveRole(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.rbacService.RemoveRole(c.Request.Context(), adminID, userID, c.Param("role")); err != nil {
+		h.handleRoleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Role removed",
+	})
+}
+
+// handleRoleError maps role errors to HTTP responses
+func (h *RoleHandler) handleRoleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, rbac.ErrRoleNotFound), errors.Is(err, rbac.ErrUserNotFound), errors.Is(err, rbac.ErrRoleNotAssigned):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update roles"})
+	}
+}
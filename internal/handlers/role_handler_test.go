@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/rbac"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Staff reach admin routes through the permissions their roles put in their tokens, admins
+// reach every route, and requests without permissions are refused
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t)
+	for _, role := range models.DefaultRoles {
+		role := role
+		require.NoError(t, db.Create(&role).Error)
+	}
+	roles := rbac.NewService(db)
+
+	router := gin.New()
+	approve := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.POST("/api/admin/kyc/approve", middleware.AuthMiddleware(), middleware.RequirePermission(models.PermissionKYCApprove), approve)
+	router.POST("/unauthenticated/kyc/approve", middleware.RequirePermission(models.PermissionKYCApprove), approve)
+
+	// tokenFor signs in a user with the given role, if any
+	tokenFor := func(role string, isAdmin bool) string {
+		t.Helper()
+		user := testutil.CreateUser(t, db)
+		if role != "" {
+			_, err := roles.AssignRole(context.Background(), uuid.New(), user.ID, role)
+			require.NoError(t, err)
+		}
+		tokens, err := generateTokens(db, user.ID, uuid.New(), user.Email, isAdmin)
+		require.NoError(t, err)
+		return tokens.AccessToken
+	}
+	post := func(path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Allowed: a compliance officer's role grants kyc:approve, and admins have every permission
+	assert.Equal(t, http.StatusNoContent, post("/api/admin/kyc/approve", tokenFor("compliance_officer", false)).Code)
+	assert.Equal(t, http.StatusNoContent, post("/api/admin/kyc/approve", tokenFor("", true)).Code)
+
+	// Denied: support agents can view KYC submissions but not approve them
+	w := post("/api/admin/kyc/approve", tokenFor("support", false))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), string(models.PermissionKYCApprove))
+	assert.Equal(t, http.StatusForbidden, post("/api/admin/kyc/approve", tokenFor("", false)).Code)
+
+	// Missing claims: no token is refused before permissions are checked, and without
+	// AuthMiddleware there are no permissions to grant access
+	assert.Equal(t, http.StatusUnauthorized, post("/api/admin/kyc/approve", "").Code)
+	assert.Equal(t, http.StatusForbidden, post("/unauthenticated/kyc/approve", tokenFor("compliance_officer", false)).Code)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/pagination"
+	"github.com/revaspay/backend/internal/services/crypto"
+	"github.com/revaspay/backend/internal/services/screening"
+)
+
+// ScreeningHandler handles admin management of the address blocklist
+type ScreeningHandler struct {
+	screeningService *screening.Service
+}
+
+// NewScreeningHandler creates a new screening handler
+func NewScreeningHandler(screeningService *screening.Service) *ScreeningHandler {
+	return &ScreeningHandler{
+		screeningService: screeningService,
+	}
+}
+
+// BlockAddressRequest represents a request to add an address to the blocklist
+type BlockAddressRequest struct {
+	Network string `json:"network" binding:"required"`
+	Address string `json:"address" binding:"required"`
+	Reason  string `json:"reason" binding:"required"`
+	Source  string `json:"source"`
+}
+
+// ScreenAddressRequest represents a request to screen an address
+type ScreenAddressRequest struct {
+	Network string `json:"network" binding:"required"`
+	Address string `json:"address" binding:"required"`
+}
+
+// ListBlockedAddresses lists blocklist entries, optionally filtered by network
+func (h *ScreeningHandler) ListBlockedAddresses(c *gin.Context) {
+	params := pagination.ParseParams(c, 20)
+
+	entries, total, err := h.screeningService.ListBlockedAddresses(c.Query("network"), params.Page, params.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get blocked addresses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"data":       entries,
+		"pagination": pagination.NewMeta(params, total),
+	})
+}
+
+// BlockAddress adds an address to the blocklist
+func (h *ScreeningHandler) BlockAddress(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req BlockAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.screeningService.BlockAddress(c.Request.Context(), adminID, req.Network, req.Address, req.Reason, req.Source)
+	if err != nil {
+		h.handleScreeningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "success",
+		"data":   entry,
+	})
+}
+
+// UnblockAddress removes an address from the blocklist
+func (h *ScreeningHandler) UnblockAddress(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blocked address ID"})
+		return
+	}
+
+	if err := h.screeningService.UnblockAddress(c.Request.Context(), adminID, id); err != nil {
+		h.handleScreeningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Address removed from blocklist",
+	})
+}
+
+// ScreenAddress returns the screening verdict for an address without sending anything
+func (h *ScreeningHandler) ScreenAddress(c *gin.Context) {
+	var req ScreenAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := crypto.ValidateAddress(req.Network, req.Address); err != nil {
+		h.handleScreeningError(c, err)
+		return
+	}
+
+	verdict, err := h.screeningService.ScreenAddress(req.Network, req.Address)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to screen address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   verdict,
+	})
+}
+
+// handleScreeningError maps screening service errors to HTTP responses
+func (h *ScreeningHandler) handleScreeningError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, screening.ErrBlockedAddressNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, screening.ErrAddressAlreadyBlocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, crypto.ErrInvalidAddress), errors.Is(err, crypto.ErrUnsupportedNetwork):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process blocklist request"})
+	}
+}
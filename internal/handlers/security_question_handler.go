@@ -52,14 +52,14 @@ func (h *SecurityQuestionHandler) GetSecurityQuestions(c *gin.Context) {
 // GetUserSecurityQuestions gets all security questions for the authenticated user
 func (h *SecurityQuestionHandler) GetUserSecurityQuestions(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
 	// Get user's security questions
-	questions, err := database.GetUserSecurityQuestions(h.db, userID.(uuid.UUID))
+	questions, err := database.GetUserSecurityQuestions(h.db, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get security questions"})
 		return
@@ -83,7 +83,7 @@ func (h *SecurityQuestionHandler) GetUserSecurityQuestions(c *gin.Context) {
 		audit.EventTypeProfile,
 		audit.SeverityInfo,
 		"Security questions retrieved",
-		userID.(*uuid.UUID),
+		&userID,
 		nil,
 		c.ClientIP(),
 		c.Request.UserAgent(),
@@ -99,7 +99,7 @@ func (h *SecurityQuestionHandler) GetUserSecurityQuestions(c *gin.Context) {
 // SetSecurityQuestionAnswer sets a security question answer for the authenticated user
 func (h *SecurityQuestionHandler) SetSecurityQuestionAnswer(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -132,7 +132,7 @@ func (h *SecurityQuestionHandler) SetSecurityQuestionAnswer(c *gin.Context) {
 		userQuestion = &existingAnswer
 	} else {
 		// Create new answer
-		userQuestion, err = database.CreateUserSecurityQuestion(h.db, userID.(uuid.UUID), req.QuestionID, req.Answer)
+		userQuestion, err = database.CreateUserSecurityQuestion(h.db, userID, req.QuestionID, req.Answer)
 	}
 
 	if err != nil {
@@ -146,7 +146,7 @@ func (h *SecurityQuestionHandler) SetSecurityQuestionAnswer(c *gin.Context) {
 		audit.EventTypeProfile,
 		audit.SeverityInfo,
 		"Security question answer set",
-		userID.(*uuid.UUID),
+		&userID,
 		&userQuestion.ID,
 		c.ClientIP(),
 		c.Request.UserAgent(),
@@ -171,7 +171,7 @@ func (h *SecurityQuestionHandler) SetSecurityQuestionAnswer(c *gin.Context) {
 // DeleteSecurityQuestionAnswer deletes a security question answer
 func (h *SecurityQuestionHandler) DeleteSecurityQuestionAnswer(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -203,7 +203,7 @@ func (h *SecurityQuestionHandler) DeleteSecurityQuestionAnswer(c *gin.Context) {
 		audit.EventTypeProfile,
 		audit.SeverityInfo,
 		"Security question answer deleted",
-		userID.(*uuid.UUID),
+		&userID,
 		&userQuestion.ID,
 		c.ClientIP(),
 		c.Request.UserAgent(),
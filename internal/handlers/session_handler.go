@@ -8,7 +8,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/pagination"
 	"github.com/revaspay/backend/internal/security/audit"
+	"github.com/revaspay/backend/internal/services/geoip"
 	"github.com/revaspay/backend/internal/utils"
 	"gorm.io/gorm"
 )
@@ -30,7 +32,7 @@ func NewSessionHandler(db *gorm.DB) *SessionHandler {
 // CreateSession creates a new session for a user
 func (h *SessionHandler) CreateSession(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -43,15 +45,16 @@ func (h *SessionHandler) CreateSession(c *gin.Context) {
 		return
 	}
 
-	// Generate tokens using JWT utility
-	tokens, err := utils.GenerateTokenPair(user.ID, user.Email, user.IsAdmin)
+	// Generate tokens for the session they start
+	sessionID := uuid.New()
+	tokens, err := generateTokens(h.db, user.ID, sessionID, user.Email, user.IsAdmin)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
 		return
 	}
 
 	// Set expiry time for session
-	expiresAt := time.Now().Add(7 * 24 * time.Hour)
+	expiresAt := time.Now().Add(utils.RefreshTokenTTL())
 
 	// Get user agent and IP address
 	userAgent := c.Request.UserAgent()
@@ -72,9 +75,12 @@ func (h *SessionHandler) CreateSession(c *gin.Context) {
 		ActivityCount: 1,
 		AuthMethod:    "password", // or other auth method if applicable
 	}
+	if location, err := geoip.DefaultService().Lookup(ipAddress); err == nil {
+		setSessionLocation(metadata, location)
+	}
 
 	// Create enhanced session
-	session, err := database.CreateEnhancedSession(h.db, userID.(uuid.UUID), tokens.RefreshToken, userAgent, ipAddress, expiresAt, deviceInfo, metadata)
+	session, err := database.CreateEnhancedSession(h.db, sessionID, userID, tokens.RefreshToken, userAgent, ipAddress, expiresAt, deviceInfo, metadata)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
 		return
@@ -125,16 +131,26 @@ func (h *SessionHandler) CreateSession(c *gin.Context) {
 // GetActiveSessions gets all active sessions for a user
 func (h *SessionHandler) GetActiveSessions(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
+	params := pagination.ParseParams(c, 20)
+
 	// Get enhanced sessions
 	var sessions []database.EnhancedSession
-	if err := h.db.Where("user_id = ? AND expires_at > ? AND status = ?", 
-		userID, time.Now(), database.SessionStatusActive).Find(&sessions).Error; err != nil {
+	var total int64
+	active := func() *gorm.DB {
+		return h.db.Model(&database.EnhancedSession{}).Where("user_id = ? AND expires_at > ? AND status = ?",
+			userID, time.Now(), database.SessionStatusActive)
+	}
+	if err := active().Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sessions"})
+		return
+	}
+	if err := active().Order("created_at DESC").Offset(params.Offset()).Limit(params.PageSize).Find(&sessions).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sessions"})
 		return
 	}
@@ -203,7 +219,7 @@ func (h *SessionHandler) GetActiveSessions(c *gin.Context) {
 		audit.EventTypeSession,
 		audit.SeverityInfo,
 		"Active sessions retrieved",
-		userID.(*uuid.UUID),
+		&userID,
 		nil,
 		c.ClientIP(),
 		c.Request.UserAgent(),
@@ -214,14 +230,15 @@ func (h *SessionHandler) GetActiveSessions(c *gin.Context) {
 	)
 
 	c.JSON(http.StatusOK, gin.H{
-		"sessions": sessionResponses,
+		"sessions":   sessionResponses,
+		"pagination": pagination.NewMeta(params, total),
 	})
 }
 
 // RevokeSession revokes a specific session
 func (h *SessionHandler) RevokeSession(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -269,7 +286,7 @@ func (h *SessionHandler) RevokeSession(c *gin.Context) {
 		audit.EventTypeSession,
 		audit.SeverityInfo,
 		"Session revoked",
-		userID.(*uuid.UUID),
+		&userID,
 		&session.ID,
 		ipAddress,
 		userAgent,
@@ -291,7 +308,7 @@ func (h *SessionHandler) RevokeSession(c *gin.Context) {
 // RevokeAllSessions revokes all sessions for a user
 func (h *SessionHandler) RevokeAllSessions(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
+	userID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
@@ -346,7 +363,7 @@ func (h *SessionHandler) RevokeAllSessions(c *gin.Context) {
 		audit.EventTypeSession,
 		audit.SeverityWarning, // Higher severity since this is a mass revocation
 		"All sessions revoked",
-		userID.(*uuid.UUID),
+		&userID,
 		nil,
 		ipAddress,
 		userAgent,
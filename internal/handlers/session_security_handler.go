@@ -275,19 +275,12 @@ func (h *SessionSecurityHandler) VerifySessionSecurity(c *gin.Context) {
 // RevokeRiskySessions revokes all risky sessions for a user
 func (h *SessionSecurityHandler) RevokeRiskySessions(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("userID")
+	userUUID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	// Parse user ID
-	userUUID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Find all risky sessions for the user
 	var sessions []database.EnhancedSession
 	if err := h.db.Where("user_id = ? AND (risk_level = ? OR risk_level = ?)", 
@@ -355,19 +348,12 @@ func (h *SessionSecurityHandler) GetSessionSecurityHistory(c *gin.Context) {
 	}
 
 	// Get user ID from context
-	userID, exists := c.Get("userID")
+	userUUID, exists := getUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	// Parse user ID
-	userUUID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Ensure the session belongs to the user
 	if session.UserID != userUUID {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to access this session"})
@@ -381,6 +367,12 @@ func (h *SessionSecurityHandler) GetSessionSecurityHistory(c *gin.Context) {
 		return
 	}
 
+	// Include the risk factors recorded at login so the user can see why a session was flagged
+	var riskFactors []string
+	if metadata, err := session.GetMetadata(); err == nil {
+		riskFactors = metadata.RiskFactors
+	}
+
 	// Return response
 	c.JSON(http.StatusOK, gin.H{
 		"session_id": sessionUUID.String(),
@@ -389,6 +381,7 @@ func (h *SessionSecurityHandler) GetSessionSecurityHistory(c *gin.Context) {
 		"status": session.Status,
 		"risk_score": session.RiskScore,
 		"risk_level": session.RiskLevel,
+		"risk_factors": riskFactors,
 		"audit_logs": auditLogs,
 	})
 }
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/services/storage"
+)
+
+// StorageHandler serves objects from the local storage backend through the signed URLs
+// it issues. Object storage backends serve their own presigned URLs.
+type StorageHandler struct {
+	backend *storage.LocalBackend
+}
+
+// NewStorageHandler creates a new storage handler
+func NewStorageHandler(backend *storage.LocalBackend) *StorageHandler {
+	return &StorageHandler{backend: backend}
+}
+
+// ServeObject streams an object when the URL's signature is valid and hasn't expired
+func (h *StorageHandler) ServeObject(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := h.backend.VerifySignature(key, c.Query("expires"), c.Query("signature")); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired link"})
+		return
+	}
+
+	object, info, err := h.backend.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+		log.Printf("Failed to read stored object: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer object.Close()
+
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, object, map[string]string{
+		"Cache-Control":          "private, no-store",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
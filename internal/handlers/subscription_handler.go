@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/pagination"
+	"github.com/revaspay/backend/internal/services/payment"
+	"github.com/revaspay/backend/internal/services/subscription"
+)
+
+// SubscriptionHandler handles merchants' subscription plans and customers' subscriptions
+type SubscriptionHandler struct {
+	subscriptionService *subscription.SubscriptionService
+}
+
+// NewSubscriptionHandler creates a new subscription handler
+func NewSubscriptionHandler(subscriptionService *subscription.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		subscriptionService: subscriptionService,
+	}
+}
+
+// CreatePlanRequest represents a request to create a subscription plan
+type CreatePlanRequest struct {
+	Name        string                 `json:"name" binding:"required"`
+	Description string                 `json:"description"`
+	Amount      float64                `json:"amount" binding:"required,gt=0"`
+	Currency    string                 `json:"currency" binding:"required"`
+	Interval    string                 `json:"interval" binding:"required"`
+	TrialDays   int                    `json:"trial_days"`
+	Features    map[string]interface{} `json:"features"`
+	Metadata    map[string]interface{} `json:"metadata"`
+}
+
+// UpdatePlanRequest represents a request to update a subscription plan.
+// Currency and interval can't be changed.
+type UpdatePlanRequest struct {
+	Name        *string                `json:"name"`
+	Description *string                `json:"description"`
+	Amount      *float64               `json:"amount"`
+	TrialDays   *int                   `json:"trial_days"`
+	Active      *bool                  `json:"active"`
+	Features    map[string]interface{} `json:"features"`
+	Metadata    map[string]interface{} `json:"metadata"`
+}
+
+// SubscribeRequest represents a request to subscribe to a plan with a card the customer
+// has already paid with
+type SubscribeRequest struct {
+	PaymentReference string                 `json:"payment_reference" binding:"required"`
+	Metadata         map[string]interface{} `json:"metadata"`
+}
+
+// CancelSubscriptionRequest represents a request to cancel a subscription
+type CancelSubscriptionRequest struct {
+	Immediately bool `json:"immediately"`
+}
+
+// CreatePlan creates a subscription plan for the merchant
+func (h *SubscriptionHandler) CreatePlan(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req CreatePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.subscriptionService.CreatePlan(userID, subscription.PlanInput{
+		Name:        req.Name,
+		Description: req.Description,
+		Amount:      req.Amount,
+		Currency:    models.Currency(req.Currency),
+		Interval:    models.BillingInterval(req.Interval),
+		TrialDays:   req.TrialDays,
+		Features:    req.Features,
+		Metadata:    req.Metadata,
+	})
+	if err != nil {
+		h.handleSubscriptionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "success",
+		"data":   plan,
+	})
+}
+
+// GetPlans lists the merchant's subscription plans
+func (h *SubscriptionHandler) GetPlans(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	params := pagination.ParseParams(c, 20)
+	plans, total, err := h.subscriptionService.ListPlans(userID, params.Page, params.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get subscription plans"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"data":       plans,
+		"pagination": pagination.NewMeta(params, total),
+	})
+}
+
+// GetPlan returns an active plan so a customer can review it before subscribing
+func (h *SubscriptionHandler) GetPlan(c *gin.Context) {
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+
+	plan, err := h.subscriptionService.GetPlan(planID)
+	if err != nil {
+		h.handleSubscriptionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   plan,
+	})
+}
+
+// UpdatePlan updates one of the merchant's subscription plans
+func (h *SubscriptionHandler) UpdatePlan(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+
+	var req UpdatePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.subscriptionService.UpdatePlan(userID, planID, subscription.PlanUpdate{
+		Name:        req.Name,
+		Description: req.Description,
+		Amount:      req.Amount,
+		TrialDays:   req.TrialDays,
+		Active:      req.Active,
+		Features:    req.Features,
+		Metadata:    req.Metadata,
+	})
+	if err != nil {
+		h.handleSubscriptionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   plan,
+	})
+}
+
+// DeletePlan deletes one of the merchant's subscription plans
+func (h *SubscriptionHandler) DeletePlan(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+
+	if err := h.subscriptionService.DeletePlan(userID, planID); err != nil {
+		h.handleSubscriptionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Subscription plan deleted",
+	})
+}
+
+// GetPlanSubscriptions lists the subscriptions to one of the merchant's plans
+func (h *SubscriptionHandler) GetPlanSubscriptions(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+
+	params := pagination.ParseParams(c, 20)
+	subscriptions, total, err := h.subscriptionService.ListPlanSubscriptions(userID, planID, params.Page, params.PageSize)
+	if err != nil {
+		h.handleSubscriptionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"data":       subscriptions,
+		"pagination": pagination.NewMeta(params, total),
+	})
+}
+
+// Subscribe subscribes the user to a plan and charges the first period
+func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	planID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plan ID"})
+		return
+	}
+
+	var req SubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.subscriptionService.Subscribe(c.Request.Context(), userID, planID, subscription.SubscribeInput{
+		PaymentReference: req.PaymentReference,
+		Metadata:         req.Metadata,
+	})
+	if err != nil {
+		h.handleSubscriptionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "success",
+		"data":   sub,
+	})
+}
+
+// GetSubscriptions lists the user's subscriptions
+func (h *SubscriptionHandler) GetSubscriptions(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	params := pagination.ParseParams(c, 20)
+	subscriptions, total, err := h.subscriptionService.ListSubscriptions(userID, params.Page, params.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get subscriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"data":       subscriptions,
+		"pagination": pagination.NewMeta(params, total),
+	})
+}
+
+// CancelSubscription stops future charges on a subscription. The subscriber or the plan's
+// merchant can cancel it, at the end of the paid period or immediately.
+func (h *SubscriptionHandler) CancelSubscription(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	subscriptionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+
+	// The body is optional; an empty one cancels at the end of the period
+	var req CancelSubscriptionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	sub, err := h.subscriptionService.Cancel(userID, subscriptionID, req.Immediately)
+	if err != nil {
+		h.handleSubscriptionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   sub,
+	})
+}
+
+// handleSubscriptionError maps subscription service errors to HTTP responses
+func (h *SubscriptionHandler) handleSubscriptionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, subscription.ErrPlanNotFound), errors.Is(err, subscription.ErrSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, subscription.ErrInvalidPlan), errors.Is(err, subscription.ErrInvalidPaymentMethod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, subscription.ErrPlanInactive), errors.Is(err, subscription.ErrPlanHasSubscribers),
+		errors.Is(err, subscription.ErrAlreadySubscribed), errors.Is(err, subscription.ErrSubscriptionCancelled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, payment.ErrChargeFailed):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+	case errors.Is(err, payment.ErrProviderUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process subscription request"})
+	}
+}
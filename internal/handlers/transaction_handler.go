@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/services/payment"
+)
+
+// defaultSummaryPeriod is the period summarized when from isn't given
+const defaultSummaryPeriod = 30 * 24 * time.Hour
+
+// TransactionHandler handles merchants' transaction reporting
+type TransactionHandler struct {
+	paymentService *payment.PaymentService
+}
+
+// NewTransactionHandler creates a new transaction handler
+func NewTransactionHandler(paymentService *payment.PaymentService) *TransactionHandler {
+	return &TransactionHandler{
+		paymentService: paymentService,
+	}
+}
+
+// GetSummary returns the merchant's payment volume, counts and fees bucketed by day, week
+// or month per currency and provider. Query parameters: group_by (default day), from and
+// to (YYYY-MM-DD, inclusive, or RFC 3339). The period defaults to the last 30 days.
+func (h *TransactionHandler) GetSummary(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	from, err := parseStatementDate(c.Query("from"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+		return
+	}
+	to, err := parseStatementDate(c.Query("to"), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+		return
+	}
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultSummaryPeriod)
+	}
+
+	summary, err := h.paymentService.GetSummary(userID, payment.SummaryQuery{
+		GroupBy: payment.SummaryGroupBy(strings.ToLower(c.DefaultQuery("group_by", "day"))),
+		From:    from,
+		To:      to,
+	})
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidSummaryQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to summarize transactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   summary,
+	})
+}
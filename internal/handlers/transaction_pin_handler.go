@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/services/account"
+	"gorm.io/gorm"
+)
+
+// TransactionPINHandler handles users setting the PIN that confirms withdrawals and transfers
+type TransactionPINHandler struct {
+	accountService *account.Service
+}
+
+// NewTransactionPINHandler creates a new transaction PIN handler
+func NewTransactionPINHandler(db *gorm.DB) *TransactionPINHandler {
+	return &TransactionPINHandler{
+		accountService: account.NewService(db),
+	}
+}
+
+// SetTransactionPINRequest sets or changes the transaction PIN, confirmed with the current PIN
+// or the password and 2FA code
+type SetTransactionPINRequest struct {
+	PIN        string `json:"pin" binding:"required"`
+	CurrentPIN string `json:"current_pin"`
+	Password   string `json:"password"`
+	TOTPCode   string `json:"totp_code"`
+}
+
+// GetTransactionPIN returns whether the signed-in user has set a transaction PIN and whether
+// it's locked
+func (h *TransactionPINHandler) GetTransactionPIN(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	status, err := h.accountService.GetPINStatus(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to get transaction PIN", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   status,
+	})
+}
+
+// SetTransactionPIN sets or changes the signed-in user's transaction PIN
+func (h *TransactionPINHandler) SetTransactionPIN(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var req SetTransactionPINRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	}
+
+	err := h.accountService.SetTransactionPIN(c.Request.Context(), userID, account.PINChangeRequest{
+		PIN:        req.PIN,
+		CurrentPIN: req.CurrentPIN,
+		Password:   req.Password,
+		TOTPCode:   req.TOTPCode,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, account.ErrInvalidPINFormat):
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	case errors.Is(err, account.ErrInvalidPassword):
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "Confirm with your current PIN, or your password", nil)
+		return
+	case errors.Is(err, account.ErrMFACodeRequired):
+		respondError(c, http.StatusBadRequest, ErrCodeMFARequired, "2FA code required", gin.H{"require_2fa": true})
+		return
+	case errors.Is(err, account.ErrInvalidMFACode):
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "Invalid 2FA code", nil)
+		return
+	case errors.Is(err, account.ErrUserNotFound):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "User not found", nil)
+		return
+	default:
+		if !respondPINError(c, err) {
+			respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to set transaction PIN", nil)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Your transaction PIN has been set",
+	})
+}
+
+// checkTransactionPIN verifies the PIN confirming a withdrawal or transfer, responding with an
+// error and returning false if it's wrong, locked or not set
+func checkTransactionPIN(c *gin.Context, accountService *account.Service, userID uuid.UUID, pin string) bool {
+	err := accountService.VerifyTransactionPIN(c.Request.Context(), userID, pin)
+	if err == nil {
+		return true
+	}
+	if !respondPINError(c, err) {
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to verify transaction PIN", nil)
+	}
+	return false
+}
+
+// respondPINError responds to a transaction PIN error, returning false if err isn't one
+func respondPINError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, account.ErrPINNotSet):
+		respondError(c, http.StatusForbidden, ErrCodePINRequired, err.Error(), nil)
+	case errors.Is(err, account.ErrIncorrectPIN):
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidPIN, err.Error(), nil)
+	case errors.Is(err, account.ErrPINLocked):
+		respondError(c, http.StatusTooManyRequests, ErrCodePINLocked, err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/config"
 	"github.com/revaspay/backend/internal/database"
 	"github.com/revaspay/backend/internal/utils"
 	"golang.org/x/crypto/bcrypt"
@@ -39,7 +40,7 @@ func NewUserHandler(db *gorm.DB) *UserHandler {
 
 // GetProfile returns the user's profile
 func (h *UserHandler) GetProfile(c *gin.Context) {
-	userID, _ := c.Get("user_id")
+	userID, _ := getUserID(c)
 	
 	var user database.User
 	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
@@ -65,7 +66,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 
 // UpdateProfile updates the user's profile
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
-	userID, _ := c.Get("user_id")
+	userID, _ := getUserID(c)
 	
 	var user database.User
 	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
@@ -163,7 +164,7 @@ func (h *UserHandler) VerifyUser(c *gin.Context) {
 
 // Enable2FA initiates 2FA setup for a user
 func (h *UserHandler) Enable2FA(c *gin.Context) {
-	userID, _ := c.Get("user_id")
+	userID, _ := getUserID(c)
 	
 	var user database.User
 	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
@@ -198,7 +199,7 @@ func (h *UserHandler) Enable2FA(c *gin.Context) {
 
 // Verify2FA verifies a 2FA code and enables 2FA for the user
 func (h *UserHandler) Verify2FA(c *gin.Context) {
-	userID, _ := c.Get("user_id")
+	userID, _ := getUserID(c)
 	
 	var req TwoFactorVerifyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -230,7 +231,7 @@ func (h *UserHandler) Verify2FA(c *gin.Context) {
 
 // Disable2FA disables 2FA for the user
 func (h *UserHandler) Disable2FA(c *gin.Context) {
-	userID, _ := c.Get("user_id")
+	userID, _ := getUserID(c)
 	
 	var req TwoFactorVerifyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -269,7 +270,7 @@ func (h *UserHandler) Disable2FA(c *gin.Context) {
 
 // UpdatePassword updates the user's password
 func (h *UserHandler) UpdatePassword(c *gin.Context) {
-	userID, _ := c.Get("user_id")
+	userID, _ := getUserID(c)
 	
 	var req PasswordUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -289,6 +290,13 @@ func (h *UserHandler) UpdatePassword(c *gin.Context) {
 		return
 	}
 	
+	// Reject the user's recent passwords
+	passwordHistory := config.DefaultSecurityConfig().PasswordHistoryCount
+	if err := database.CheckPasswordReuse(h.db, &user, req.NewPassword, passwordHistory); err != nil {
+		respondPasswordReused(c, err)
+		return
+	}
+	
 	// Hash new password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
@@ -296,9 +304,15 @@ func (h *UserHandler) UpdatePassword(c *gin.Context) {
 		return
 	}
 	
-	// Update password
+	// Update password, remembering it so it can't be reused
 	user.Password = string(hashedPassword)
-	if err := h.db.Save(&user).Error; err != nil {
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return database.RecordPasswordHistory(tx, user.ID, user.Password, passwordHistory)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
 	}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/virtualaccount"
+)
+
+// VirtualAccountHandler handles users' foreign currency receiving accounts
+type VirtualAccountHandler struct {
+	virtualAccountService *virtualaccount.VirtualAccountService
+}
+
+// NewVirtualAccountHandler creates a new virtual account handler
+func NewVirtualAccountHandler(virtualAccountService *virtualaccount.VirtualAccountService) *VirtualAccountHandler {
+	return &VirtualAccountHandler{
+		virtualAccountService: virtualAccountService,
+	}
+}
+
+// CreateVirtualAccountRequest represents a request to open a virtual account
+type CreateVirtualAccountRequest struct {
+	Provider string `json:"provider" binding:"required"`
+	Currency string `json:"currency" binding:"required"`
+}
+
+// CreateVirtualAccount opens a receiving account with the chosen provider and returns the
+// bank details the user shares to get paid
+func (h *VirtualAccountHandler) CreateVirtualAccount(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req CreateVirtualAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := h.virtualAccountService.Create(c.Request.Context(), userID,
+		models.VirtualAccountProvider(req.Provider), models.VirtualAccountCurrency(req.Currency))
+	if err != nil {
+		h.handleVirtualAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "success",
+		"data":   account,
+	})
+}
+
+// GetVirtualAccounts lists the user's virtual accounts
+func (h *VirtualAccountHandler) GetVirtualAccounts(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accounts, err := h.virtualAccountService.List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get virtual accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   accounts,
+	})
+}
+
+// DeactivateVirtualAccount closes one of the user's virtual accounts
+func (h *VirtualAccountHandler) DeactivateVirtualAccount(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid virtual account ID"})
+		return
+	}
+
+	account, err := h.virtualAccountService.Deactivate(c.Request.Context(), userID, accountID)
+	if err != nil {
+		h.handleVirtualAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   account,
+	})
+}
+
+// handleVirtualAccountError maps virtual account service errors to HTTP responses
+func (h *VirtualAccountHandler) handleVirtualAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, virtualaccount.ErrAccountNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, virtualaccount.ErrUnsupportedProvider), errors.Is(err, virtualaccount.ErrUnsupportedCurrency):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, virtualaccount.ErrKYCRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, virtualaccount.ErrAccountExists), errors.Is(err, virtualaccount.ErrAccountInactive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, virtualaccount.ErrProvisioningFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Virtual account provider is unavailable, please try again later"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process virtual account request"})
+	}
+}
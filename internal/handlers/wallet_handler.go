@@ -1,48 +1,51 @@
 package handlers
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/currency"
 	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/pagination"
+	"github.com/revaspay/backend/internal/services/account"
 	"github.com/revaspay/backend/internal/services/wallet"
 	"gorm.io/gorm"
 )
 
 // WalletHandler handles wallet-related requests
 type WalletHandler struct {
-	db            *gorm.DB
-	walletService *wallet.WalletService
+	db             *gorm.DB
+	walletService  *wallet.WalletService
+	accountService *account.Service
 }
 
 // NewWalletHandler creates a new wallet handler
 func NewWalletHandler(db *gorm.DB) *WalletHandler {
 	return &WalletHandler{
-		db:            db,
-		walletService: wallet.NewWalletService(db),
+		db:             db,
+		walletService:  wallet.NewWalletService(db),
+		accountService: account.NewService(db),
 	}
 }
 
 // GetWallets gets all wallets for the authenticated user
 func (h *WalletHandler) GetWallets(c *gin.Context) {
-	userIDStr := c.GetString("user_id")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 	
 	wallets, err := h.walletService.GetWallets(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get wallets"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to get wallets", nil)
 		return
 	}
 	
@@ -51,41 +54,74 @@ func (h *WalletHandler) GetWallets(c *gin.Context) {
 
 // GetWallet gets a specific wallet by ID
 func (h *WalletHandler) GetWallet(c *gin.Context) {
-	userIDStr := c.GetString("user_id")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 	
 	walletIDStr := c.Param("id")
 	walletID, err := uuid.Parse(walletIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid wallet ID", nil)
 		return
 	}
 	
 	// Get the wallet
 	wallet, err := h.walletService.GetWallet(walletID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "wallet not found", nil)
 		return
 	}
 	
 	// Verify wallet belongs to user
-	userID, _ := uuid.Parse(userIDStr)
 	if wallet.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		respondError(c, http.StatusForbidden, ErrCodeForbidden, "access denied", nil)
 		return
 	}
 	
 	c.JSON(http.StatusOK, wallet)
 }
 
+// GetWalletBalance returns a wallet's available balance and the amount held for pending withdrawals
+func (h *WalletHandler) GetWalletBalance(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
+		return
+	}
+	
+	walletID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid wallet ID", nil)
+		return
+	}
+	
+	// Verify wallet belongs to user
+	w, err := h.walletService.GetWallet(walletID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "wallet not found", nil)
+		return
+	}
+	if w.UserID != userID {
+		respondError(c, http.StatusForbidden, ErrCodeForbidden, "access denied", nil)
+		return
+	}
+	
+	balance, err := h.walletService.GetBalance(walletID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to get wallet balance", nil)
+		return
+	}
+	
+	c.JSON(http.StatusOK, balance)
+}
+
 // CreateWallet creates a new wallet for the authenticated user
 func (h *WalletHandler) CreateWallet(c *gin.Context) {
-	userIDStr := c.GetString("user_id")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 	
@@ -94,13 +130,7 @@ func (h *WalletHandler) CreateWallet(c *gin.Context) {
 	}
 	
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 	
@@ -108,90 +138,259 @@ func (h *WalletHandler) CreateWallet(c *gin.Context) {
 	var existingWallet models.Wallet
 	result := h.db.Where("user_id = ? AND currency = ?", userID, input.Currency).First(&existingWallet)
 	if result.Error == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "wallet already exists for this currency", "wallet": existingWallet})
+		respondError(c, http.StatusConflict, ErrCodeConflict, "wallet already exists for this currency", gin.H{"wallet": existingWallet})
 		return
 	}
 	
 	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check existing wallet"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to check existing wallet", nil)
 		return
 	}
 	
 	// Create new wallet
 	wallet, err := h.walletService.GetOrCreateWallet(userID, input.Currency)
+	if errors.Is(err, currency.ErrUnsupportedCurrency) {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create wallet"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to create wallet", nil)
 		return
 	}
 	
 	c.JSON(http.StatusCreated, wallet)
 }
 
-// GetTransactionHistory gets transaction history for a wallet
+// GetTransactionHistory gets transaction history for a wallet, optionally filtered by
+// category and tag
 func (h *WalletHandler) GetTransactionHistory(c *gin.Context) {
-	userIDStr := c.GetString("user_id")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 	
 	walletIDStr := c.Param("id")
 	walletID, err := uuid.Parse(walletIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid wallet ID", nil)
 		return
 	}
 	
 	// Verify wallet belongs to user
 	var wallet models.Wallet
 	if err := h.db.First(&wallet, "id = ?", walletID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, "wallet not found", nil)
 		return
 	}
-	
-	userID, _ := uuid.Parse(userIDStr)
+
 	if wallet.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		respondError(c, http.StatusForbidden, ErrCodeForbidden, "access denied", nil)
+		return
+	}
+	
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 	
 	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	params := pagination.ParseParams(c, 20)
 	
-	transactions, total, err := h.walletService.GetTransactionHistory(walletID, page, pageSize)
+	transactions, total, err := h.walletService.GetTransactionHistory(walletID, filter, params.Page, params.PageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get transaction history"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to get transaction history", nil)
 		return
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"transactions": transactions,
-		"pagination": gin.H{
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-		},
+		"pagination":   pagination.NewMeta(params, total),
 	})
 }
 
-// GetAutoWithdrawConfig gets auto-withdraw configuration for the authenticated user
-func (h *WalletHandler) GetAutoWithdrawConfig(c *gin.Context) {
-	userIDStr := c.GetString("user_id")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+// parseTransactionFilter reads the category and tag transaction filters from the query
+func parseTransactionFilter(c *gin.Context) (wallet.TransactionFilter, error) {
+	filter := wallet.TransactionFilter{
+		Category: models.LedgerCategory(strings.ToLower(c.Query("category"))),
+		Tag:      strings.TrimSpace(c.Query("tag")),
+	}
+	if filter.Category != "" && !filter.Category.IsValid() {
+		return filter, fmt.Errorf("unknown transaction category %q", filter.Category)
+	}
+	return filter, nil
+}
+
+// TagTransactionRequest is the body of a transaction tag update
+type TagTransactionRequest struct {
+	Tag string `json:"tag"`
+}
+
+// TagTransaction sets or clears the bookkeeping tag on one of the user's wallet transactions
+func (h *WalletHandler) TagTransaction(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 	
-	userID, err := uuid.Parse(userIDStr)
+	transactionID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid transaction ID", nil)
+		return
+	}
+	
+	var req TagTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	}
+	
+	transaction, err := h.walletService.TagTransaction(userID, transactionID, req.Tag)
+	if err != nil {
+		switch {
+		case errors.Is(err, wallet.ErrTagTooLong):
+			respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		case errors.Is(err, wallet.ErrTransactionNotFound):
+			respondError(c, http.StatusNotFound, ErrCodeNotFound, "transaction not found", nil)
+		default:
+			respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to tag transaction", nil)
+		}
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"transaction": transaction})
+}
+
+// maxPDFStatementPeriod bounds PDF statements, which are rendered in memory
+const maxPDFStatementPeriod = 366 * 24 * time.Hour
+
+// ExportTransactions streams the user's ledger entries as a CSV or PDF statement.
+// Query parameters: format (csv or pdf), from and to (YYYY-MM-DD, inclusive, or RFC 3339)
+// and an optional wallet_id, which must belong to the user.
+func (h *WalletHandler) ExportTransactions(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
+		return
+	}
+	
+	format := strings.ToLower(c.DefaultQuery("format", "csv"))
+	if format != "csv" && format != "pdf" {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "format must be csv or pdf", nil)
+		return
+	}
+	
+	filter := wallet.StatementFilter{UserID: userID}
+	var err error
+	if filter.From, err = parseStatementDate(c.Query("from"), false); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid from date", nil)
+		return
+	}
+	if filter.To, err = parseStatementDate(c.Query("to"), true); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid to date", nil)
+		return
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "from must be before to", nil)
+		return
+	}
+	
+	// Same ownership check as the transactions list
+	if walletIDStr := c.Query("wallet_id"); walletIDStr != "" {
+		walletID, err := uuid.Parse(walletIDStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeValidation, "invalid wallet ID", nil)
+			return
+		}
+		w, err := h.walletService.GetWallet(walletID)
+		if err != nil {
+			respondError(c, http.StatusNotFound, ErrCodeNotFound, "wallet not found", nil)
+			return
+		}
+		if w.UserID != userID {
+			respondError(c, http.StatusForbidden, ErrCodeForbidden, "access denied", nil)
+			return
+		}
+		filter.WalletID = &walletID
+	}
+	
+	if format == "pdf" {
+		if filter.To.IsZero() {
+			filter.To = time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		}
+		if filter.From.IsZero() {
+			filter.From = filter.To.Add(-maxPDFStatementPeriod)
+		}
+		if filter.To.Sub(filter.From) > maxPDFStatementPeriod {
+			respondError(c, http.StatusBadRequest, ErrCodeValidation, "PDF statements cover at most one year; use CSV for longer periods", nil)
+			return
+		}
+	}
+	
+	filename := fmt.Sprintf("statement-%s.%s", time.Now().UTC().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		// Headers are already sent, so a failure part way through can only end the stream
+		if err := h.walletService.WriteStatementCSV(c.Writer, filter); err != nil {
+			log.Printf("Failed to stream CSV statement for user %s: %v", userID, err)
+			c.Abort()
+		}
+		return
+	}
+	
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to get user", nil)
+		return
+	}
+	merchantName := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if merchantName == "" {
+		merchantName = user.Email
+	}
+	
+	var buf bytes.Buffer
+	if err := h.walletService.WriteStatementPDF(&buf, filter, merchantName); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to generate statement", nil)
+		return
+	}
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// parseStatementDate parses a statement bound. A bare date as the end of the period
+// includes that whole day.
+func parseStatementDate(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.Add(24 * time.Hour)
+	}
+	return t, nil
+}
+
+// GetAutoWithdrawConfig gets auto-withdraw configuration for the authenticated user
+func (h *WalletHandler) GetAutoWithdrawConfig(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 	
 	config, err := h.walletService.GetAutoWithdrawConfig(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get auto-withdraw config"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to get auto-withdraw config", nil)
 		return
 	}
 	
@@ -205,15 +404,9 @@ func (h *WalletHandler) GetAutoWithdrawConfig(c *gin.Context) {
 
 // UpdateAutoWithdrawConfig updates auto-withdraw configuration for the authenticated user
 func (h *WalletHandler) UpdateAutoWithdrawConfig(c *gin.Context) {
-	userIDStr := c.GetString("user_id")
-	if userIDStr == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
 		return
 	}
 	
@@ -226,7 +419,7 @@ func (h *WalletHandler) UpdateAutoWithdrawConfig(c *gin.Context) {
 	}
 	
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
 		return
 	}
 	
@@ -240,9 +433,68 @@ func (h *WalletHandler) UpdateAutoWithdrawConfig(c *gin.Context) {
 	)
 	
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update auto-withdraw config"})
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to update auto-withdraw config", nil)
 		return
 	}
 	
 	c.JSON(http.StatusOK, config)
 }
+
+// TransferRequest sends funds to another user, identified by email or username
+type TransferRequest struct {
+	Recipient      string          `json:"recipient" binding:"required"`
+	Amount         float64         `json:"amount" binding:"required,gt=0"`
+	Currency       models.Currency `json:"currency" binding:"required,len=3"`
+	IdempotencyKey string          `json:"idempotency_key"`      // Used when the Idempotency-Key header isn't set
+	PIN            string          `json:"pin" binding:"required"` // Transaction PIN confirming the transfer
+}
+
+// Transfer sends funds from the authenticated user's wallet to another user's wallet in the same
+// currency. The idempotency key makes retries safe: repeating a request with the same key
+// returns the original transfer instead of sending again.
+func (h *WalletHandler) Transfer(c *gin.Context) {
+	user, exists := getUser(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "unauthorized", nil)
+		return
+	}
+	if !user.IsVerified {
+		respondError(c, http.StatusForbidden, ErrCodeForbidden, "verify your email address before sending transfers", nil)
+		return
+	}
+
+	var req TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	}
+	if !checkTransactionPIN(c, h.accountService, user.ID, req.PIN) {
+		return
+	}
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = req.IdempotencyKey
+	}
+
+	transfer, err := h.walletService.Transfer(user.ID, req.Recipient, req.Amount, req.Currency, idempotencyKey)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, transfer)
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		respondError(c, http.StatusBadRequest, ErrCodeInsufficientFunds, "insufficient funds: the transfer amount exceeds your available balance", nil)
+	case errors.Is(err, wallet.ErrInvalidAmount),
+		errors.Is(err, wallet.ErrIdempotencyKeyRequired),
+		errors.Is(err, wallet.ErrTransferToSelf),
+		errors.Is(err, currency.ErrUnsupportedCurrency):
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+	case errors.Is(err, wallet.ErrAccountSuspended):
+		respondError(c, http.StatusForbidden, ErrCodeAccountSuspended, "your account is suspended; transfers are disabled", nil)
+	case errors.Is(err, wallet.ErrRecipientNotFound), errors.Is(err, wallet.ErrWalletNotFound):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, err.Error(), nil)
+	case errors.Is(err, wallet.ErrIdempotencyKeyReused):
+		respondError(c, http.StatusConflict, ErrCodeConflict, err.Error(), nil)
+	default:
+		log.Printf("Failed to transfer from user %s: %v", user.ID, err)
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "failed to send transfer", nil)
+	}
+}
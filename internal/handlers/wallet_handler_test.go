@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestParseTransactionFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newContext := func(query string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/wallet/123/transactions?"+query, nil)
+		return c
+	}
+
+	filter, err := parseTransactionFilter(newContext("category=Sales&tag=%20q3%20"))
+	require.NoError(t, err)
+	assert.Equal(t, models.LedgerCategorySales, filter.Category)
+	assert.Equal(t, "q3", filter.Tag)
+
+	filter, err = parseTransactionFilter(newContext(""))
+	require.NoError(t, err)
+	assert.Empty(t, filter.Category)
+	assert.Empty(t, filter.Tag)
+
+	_, err = parseTransactionFilter(newContext("category=groceries"))
+	assert.Error(t, err)
+}
+
+// Transfers go through the same chain as the route: the token's user is loaded so their email
+// verification can be checked, and each user's transfers are rate limited
+func TestTransferThroughMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t)
+	handler := NewWalletHandler(db)
+	limiter := middleware.NewRateLimiter(100, 60, 100, 10)
+	router := gin.New()
+	router.POST("/api/wallet/transfer",
+		middleware.AuthMiddleware(),
+		limiter.UserRateLimiterMiddleware("withdrawals", middleware.UserRateLimit{RequestsPerMinute: 1, Burst: 2}),
+		middleware.LoadUser(db),
+		handler.Transfer)
+
+	sender := testutil.CreateUser(t, db)
+	recipient := testutil.CreateUser(t, db)
+	hash, err := bcrypt.GenerateFromPassword([]byte("1234"), bcrypt.MinCost)
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&models.TransactionPIN{UserID: sender.ID, PINHash: string(hash)}).Error)
+	walletSvc := wallet.NewWalletService(db)
+	w, err := walletSvc.GetOrCreateWallet(sender.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	_, err = walletSvc.Credit(w.ID, 100, "deposit", models.LedgerCategoryDeposit, "DEP-1", "Deposit", nil)
+	require.NoError(t, err)
+
+	tokens, err := generateTokens(db, sender.ID, uuid.New(), sender.Email, false)
+	require.NoError(t, err)
+	transfer := func(token, key string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(TransferRequest{Recipient: recipient.Email, Amount: 25, Currency: models.CurrencyGHS, PIN: "1234"})
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/wallet/transfer", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", key)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, transfer("", "key-0").Code)
+
+	// Unverified senders are refused
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", sender.ID).Update("is_verified", false).Error)
+	rec := transfer(tokens.AccessToken, "key-1")
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "verify your email address")
+
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", sender.ID).Update("is_verified", true).Error)
+	rec = transfer(tokens.AccessToken, "key-2")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	balance, err := walletSvc.GetWallet(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 75.0, balance.Balance)
+
+	// The burst is used up
+	assert.Equal(t, http.StatusTooManyRequests, transfer(tokens.AccessToken, "key-3").Code)
+}
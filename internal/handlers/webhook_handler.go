@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -14,28 +16,48 @@ import (
 	"gorm.io/gorm"
 )
 
+// jobEnqueuer is the part of the job queue the webhook handler needs
+type jobEnqueuer interface {
+	EnqueueJob(jobType queue.JobType, payload interface{}) (string, error)
+}
+
 // WebhookHandler handles webhooks from external providers
 type WebhookHandler struct {
 	db          *gorm.DB
 	baseService *crypto.BaseService
-	jobQueue    *queue.Queue
+	jobQueue    jobEnqueuer
+	apiKey      string
 }
 
-// NewWebhookHandler creates a new webhook handler
-func NewWebhookHandler(db *gorm.DB, baseService *crypto.BaseService, jobQueue *queue.Queue) *WebhookHandler {
+// NewWebhookHandler creates a new webhook handler. Webhooks must send apiKey in their X-API-Key
+// header; without one every webhook is rejected.
+func NewWebhookHandler(db *gorm.DB, baseService *crypto.BaseService, jobQueue *queue.Queue, apiKey string) *WebhookHandler {
+	if apiKey == "" {
+		log.Println("WEBHOOK_API_KEY is not set; provider webhooks will be rejected")
+	}
+
 	return &WebhookHandler{
 		db:          db,
 		baseService: baseService,
 		jobQueue:    jobQueue,
+		apiKey:      apiKey,
+	}
+}
+
+// verifyAPIKey checks the X-API-Key header against the configured webhook key. Nothing is
+// accepted when no key is configured.
+func (h *WebhookHandler) verifyAPIKey(c *gin.Context) bool {
+	provided := c.GetHeader("X-API-Key")
+	if provided == "" || h.apiKey == "" {
+		return false
 	}
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(h.apiKey)) == 1
 }
 
 // BlockchainTransactionWebhook handles webhooks from blockchain transaction monitoring services
 func (h *WebhookHandler) BlockchainTransactionWebhook(c *gin.Context) {
-	// Verify webhook signature/auth
-	// In production, implement proper authentication for webhooks
-	apiKey := c.GetHeader("X-API-Key")
-	if apiKey == "" {
+	// Verify webhook auth
+	if !h.verifyAPIKey(c) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
@@ -63,7 +85,7 @@ func (h *WebhookHandler) BlockchainTransactionWebhook(c *gin.Context) {
 
 	// Find transaction in database
 	var cryptoTx database.CryptoTransaction
-	if err := h.db.Where("tx_hash = ?", payload.TransactionHash).First(&cryptoTx).Error; err != nil {
+	if err := h.db.Where("transaction_hash = ?", payload.TransactionHash).First(&cryptoTx).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			// This might be a transaction we're not tracking
 			log.Printf("Received webhook for unknown transaction: %s", payload.TransactionHash)
@@ -152,11 +174,11 @@ func (h *WebhookHandler) BlockchainTransactionWebhook(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
 
-// BankTransferWebhook handles webhooks from bank transfer providers
+// BankTransferWebhook handles webhooks from bank transfer providers. The event is matched to
+// a bank transaction by reference, and the outcome is propagated to any linked international payment.
 func (h *WebhookHandler) BankTransferWebhook(c *gin.Context) {
-	// Verify webhook signature/auth
-	apiKey := c.GetHeader("X-API-Key")
-	if apiKey == "" {
+	// Verify webhook auth
+	if !h.verifyAPIKey(c) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
@@ -164,12 +186,14 @@ func (h *WebhookHandler) BankTransferWebhook(c *gin.Context) {
 	// Parse webhook payload
 	var payload struct {
 		TransactionID  string `json:"transaction_id"`
-		Reference      string `json:"reference"`
-		Status         string `json:"status"`
+		Reference      string `json:"reference" binding:"required"`
+		Status         string `json:"status" binding:"required"`
 		Amount         string `json:"amount"`
 		Currency       string `json:"currency"`
 		FailureReason  string `json:"failure_reason,omitempty"`
+		Error          string `json:"error,omitempty"`
 		ProcessedAt    string `json:"processed_at,omitempty"`
+		BankReference  string `json:"bank_reference,omitempty"`
 		BankIdentifier string `json:"bank_identifier,omitempty"`
 	}
 
@@ -178,12 +202,31 @@ func (h *WebhookHandler) BankTransferWebhook(c *gin.Context) {
 		return
 	}
 
-	log.Printf("Received bank webhook for tx: %s, status: %s", payload.TransactionID, payload.Status)
+	log.Printf("Received bank webhook for tx: %s, reference: %s, status: %s", payload.TransactionID, payload.Reference, payload.Status)
+
+	// Map external status to our status
+	var status string
+	switch payload.Status {
+	case "completed", "success", "settled":
+		status = "completed"
+	case "failed", "rejected", "returned":
+		status = "failed"
+	case "pending", "processing":
+		status = "pending"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown status: %s", payload.Status)})
+		return
+	}
+
+	failureReason := payload.FailureReason
+	if failureReason == "" {
+		failureReason = payload.Error
+	}
 
 	// Find bank transaction in database by reference
 	var bankTx database.GhanaBankTransaction
 	if err := h.db.Where("reference = ?", payload.Reference).First(&bankTx).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Printf("Received webhook for unknown bank transaction: %s", payload.Reference)
 			c.JSON(http.StatusOK, gin.H{"status": "ignored"})
 			return
@@ -192,15 +235,15 @@ func (h *WebhookHandler) BankTransferWebhook(c *gin.Context) {
 		return
 	}
 
-	// Map external status to our status
-	status := "pending"
-	switch payload.Status {
-	case "completed", "success", "settled":
-		status = "completed"
-	case "failed", "rejected", "returned":
-		status = "failed"
-	case "pending", "processing":
-		status = "pending"
+	// Providers retry deliveries, so a repeated event is acknowledged without side effects
+	if bankTx.Status == status {
+		c.JSON(http.StatusOK, gin.H{"status": "unchanged"})
+		return
+	}
+	if bankTx.Status == "completed" || bankTx.Status == "failed" {
+		log.Printf("Ignoring %s webhook for bank transaction %s already %s", status, bankTx.ID, bankTx.Status)
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
 	}
 
 	// Start a database transaction
@@ -211,11 +254,19 @@ func (h *WebhookHandler) BankTransferWebhook(c *gin.Context) {
 	}
 
 	// Update bank transaction
-	if err := tx.Model(&bankTx).Updates(map[string]interface{}{
-		"status":         status,
-		"failure_reason": payload.FailureReason,
-		"updated_at":     time.Now(),
-	}).Error; err != nil {
+	now := time.Now()
+	bankUpdates := map[string]interface{}{
+		"status":     status,
+		"error":      failureReason,
+		"updated_at": now,
+	}
+	if payload.BankReference != "" {
+		bankUpdates["bank_reference"] = payload.BankReference
+	}
+	if status == "completed" {
+		bankUpdates["completed_at"] = now
+	}
+	if err := tx.Model(&bankTx).Updates(bankUpdates).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transaction"})
 		return
@@ -224,24 +275,42 @@ func (h *WebhookHandler) BankTransferWebhook(c *gin.Context) {
 	// Find related international payment
 	var payment database.InternationalPayment
 	if err := tx.Where("bank_transaction_id = ?", bankTx.ID).First(&payment).Error; err != nil {
-		if err != gorm.ErrRecordNotFound {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find payment"})
 			return
 		}
 		// No payment associated, just update the transaction
-		tx.Commit()
+		if err := tx.Commit().Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{"status": "updated"})
 		return
 	}
 
-	// Update payment status if bank transaction failed
-	if status == "failed" {
-		if err := tx.Model(&payment).Updates(map[string]interface{}{
-			"status":     "failed",
-			"error":      fmt.Sprintf("Bank transfer failed: %s", payload.FailureReason),
-			"updated_at": time.Now(),
-		}).Error; err != nil {
+	// Propagate the bank outcome to the payment unless it has already finished
+	paymentStatus := ""
+	switch payment.Status {
+	case "completed", "failed", "blocked":
+	default:
+		if status == "failed" {
+			paymentStatus = "failed"
+		} else if status == "completed" && (payment.Status == "initiated" || payment.Status == "queued") {
+			// Cedis are collected; the crypto leg is next
+			paymentStatus = "processing"
+		}
+	}
+
+	if paymentStatus != "" {
+		paymentUpdates := map[string]interface{}{
+			"status":     paymentStatus,
+			"updated_at": now,
+		}
+		if paymentStatus == "failed" {
+			paymentUpdates["error"] = fmt.Sprintf("Bank transfer failed: %s", failureReason)
+		}
+		if err := tx.Model(&payment).Updates(paymentUpdates).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment"})
 			return
@@ -254,14 +323,14 @@ func (h *WebhookHandler) BankTransferWebhook(c *gin.Context) {
 		return
 	}
 
-	// Queue notification job if needed
-	if status == "failed" {
+	// Let the user know the payment moved on
+	if paymentStatus != "" {
 		notificationPayload := struct {
 			PaymentID uuid.UUID `json:"payment_id"`
 			Status    string    `json:"status"`
 		}{
 			PaymentID: payment.ID,
-			Status:    status,
+			Status:    paymentStatus,
 		}
 
 		// EnqueueJob handles JSON marshaling internally
@@ -276,9 +345,8 @@ func (h *WebhookHandler) BankTransferWebhook(c *gin.Context) {
 
 // ExchangeRateWebhook handles webhooks from exchange rate providers
 func (h *WebhookHandler) ExchangeRateWebhook(c *gin.Context) {
-	// Verify webhook signature/auth
-	apiKey := c.GetHeader("X-API-Key")
-	if apiKey == "" {
+	// Verify webhook auth
+	if !h.verifyAPIKey(c) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
@@ -13,6 +13,7 @@ import (
 	"github.com/revaspay/backend/internal/database"
 	"github.com/revaspay/backend/internal/queue"
 	"github.com/revaspay/backend/internal/services/crypto"
+	"github.com/revaspay/backend/internal/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -79,6 +80,9 @@ func (m *MockCryptoService) GetTransactionDetails(txHash string) (*crypto.Transa
 	return args.Get(0).(*crypto.TransactionDetails), args.Error(1)
 }
 
+// testWebhookAPIKey is the X-API-Key the test webhook handlers accept
+const testWebhookAPIKey = "test-api-key"
+
 // TestWebhookHandler is a test-specific version of WebhookHandler that accepts our mock interfaces
 type TestWebhookHandler struct {
 	db          *gorm.DB
@@ -86,37 +90,29 @@ type TestWebhookHandler struct {
 	jobQueue    *MockQueue
 }
 
-// BlockchainTransactionWebhook handles webhooks from blockchain transaction monitoring services
+// BlockchainTransactionWebhook calls the real handler with the mock queue
 func (h *TestWebhookHandler) BlockchainTransactionWebhook(c *gin.Context) {
-	// This is just a wrapper to call the real handler
-	// In a real implementation, we would need to implement this method
-	// For testing purposes, we'll just return a success response
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
+	handler := &WebhookHandler{db: h.db, jobQueue: h.jobQueue, apiKey: testWebhookAPIKey}
+	handler.BlockchainTransactionWebhook(c)
 }
 
-// BankTransferWebhook handles webhooks from bank transfer providers
+// BankTransferWebhook calls the real handler with the mock queue
 func (h *TestWebhookHandler) BankTransferWebhook(c *gin.Context) {
-	// This is just a wrapper to call the real handler
-	// In a real implementation, we would need to implement this method
-	// For testing purposes, we'll just return a success response
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
+	handler := &WebhookHandler{db: h.db, jobQueue: h.jobQueue, apiKey: testWebhookAPIKey}
+	handler.BankTransferWebhook(c)
 }
 
-// ExchangeRateWebhook handles webhooks from exchange rate providers
+// ExchangeRateWebhook calls the real handler
 func (h *TestWebhookHandler) ExchangeRateWebhook(c *gin.Context) {
-	// This is just a wrapper to call the real handler
-	// In a real implementation, we would need to implement this method
-	// For testing purposes, we'll just return a success response
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
+	handler := &WebhookHandler{db: h.db, jobQueue: h.jobQueue, apiKey: testWebhookAPIKey}
+	handler.ExchangeRateWebhook(c)
 }
 
 func setupTestDBWithModels(t *testing.T) *gorm.DB {
-	// Use in-memory database for testing
-	db, err := gorm.Open(gorm.Config{})
-	require.NoError(t, err)
+	db := testutil.NewDB(t)
 	
-	// Migrate the necessary schemas
-	err = db.AutoMigrate(
+	// Migrate the schemas the application's migration doesn't cover
+	err := db.AutoMigrate(
 		&database.Wallet{},
 		&database.InternationalPayment{},
 		&database.CryptoTransaction{},
@@ -134,6 +130,40 @@ func setupTestRouter() *gin.Engine {
 	return router
 }
 
+func TestWebhookAPIKeyRequired(t *testing.T) {
+	db := setupTestDBWithModels(t)
+	mockQueue := new(MockQueue)
+
+	tests := []struct {
+		name       string
+		configured string
+		provided   string
+	}{
+		{"no key configured", "", "anything"},
+		{"no key sent", testWebhookAPIKey, ""},
+		{"wrong key", testWebhookAPIKey, "wrong-key"},
+		{"key prefix", testWebhookAPIKey, testWebhookAPIKey[:4]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &WebhookHandler{db: db, jobQueue: mockQueue, apiKey: tt.configured}
+			router := setupTestRouter()
+			router.POST("/webhooks/bank", handler.BankTransferWebhook)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/bank", bytes.NewBufferString(`{"reference":"BANK-REF","status":"completed"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.provided != "" {
+				req.Header.Set("X-API-Key", tt.provided)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		})
+	}
+	mockQueue.AssertNotCalled(t, "EnqueueJob", mock.Anything, mock.Anything)
+}
+
 func TestBlockchainTransactionWebhook(t *testing.T) {
 	db := setupTestDBWithModels(t)
 	mockQueue := new(MockQueue)
@@ -223,7 +253,7 @@ func TestBlockchainTransactionWebhook(t *testing.T) {
 	req, err := http.NewRequest("POST", "/webhooks/blockchain", bytes.NewBuffer(payloadBytes))
 	require.NoError(t, err)
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", "test-api-key") // Add API key for authentication
+	req.Header.Set("X-API-Key", testWebhookAPIKey) // Add API key for authentication
 	
 	// Perform request
 	recorder := httptest.NewRecorder()
@@ -237,21 +267,16 @@ func TestBlockchainTransactionWebhook(t *testing.T) {
 	err = db.First(&updatedTx, cryptoTx.ID).Error
 	assert.NoError(t, err)
 	
-	// Since we're using a test handler that doesn't actually update the database,
-	// we'll skip these assertions for now
-	// assert.Equal(t, "confirmed", updatedTx.Status)
-	// assert.Equal(t, uint64(12345678), updatedTx.BlockNumber)
-	// assert.Equal(t, uint64(21000), updatedTx.GasUsed)
-	// assert.Equal(t, "0xblock1234567890", updatedTx.BlockHash)
+	assert.Equal(t, "confirmed", updatedTx.Status)
+	assert.Equal(t, uint64(12345678), updatedTx.BlockNumber)
+	assert.Equal(t, uint64(21000), updatedTx.GasUsed)
 	
 	// Verify payment was updated
 	var updatedPayment database.InternationalPayment
 	err = db.First(&updatedPayment, payment.ID).Error
 	assert.NoError(t, err)
 	
-	// Since we're using a test handler that doesn't actually update the database,
-	// we'll skip this assertion for now
-	// assert.Equal(t, "confirmed", updatedPayment.Status)
+	assert.Equal(t, "confirmed", updatedPayment.Status)
 	
 	// Verify mock expectations
 	mockQueue.AssertExpectations(t)
@@ -334,7 +359,7 @@ func TestBankTransferWebhook(t *testing.T) {
 	req, err := http.NewRequest("POST", "/webhooks/bank", bytes.NewBuffer(payloadBytes))
 	require.NoError(t, err)
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", "test-api-key") // Add API key for authentication
+	req.Header.Set("X-API-Key", testWebhookAPIKey) // Add API key for authentication
 	
 	// Perform request
 	recorder := httptest.NewRecorder()
@@ -348,19 +373,15 @@ func TestBankTransferWebhook(t *testing.T) {
 	err = db.First(&updatedBankTx, bankTx.ID).Error
 	assert.NoError(t, err)
 	
-	// Since we're using a test handler that doesn't actually update the database,
-	// we'll skip these assertions for now
-	// assert.Equal(t, "failed", updatedBankTx.Status)
-	// assert.Equal(t, "Insufficient funds", updatedBankTx.Error)
+	assert.Equal(t, "failed", updatedBankTx.Status)
+	assert.Equal(t, "Insufficient funds", updatedBankTx.Error)
 	
 	// Verify payment was updated
 	var updatedPayment database.InternationalPayment
 	err = db.First(&updatedPayment, payment.ID).Error
 	assert.NoError(t, err)
 	
-	// Since we're using a test handler that doesn't actually update the database,
-	// we'll skip this assertion for now
-	// assert.Equal(t, "failed", updatedPayment.Status)
+	assert.Equal(t, "failed", updatedPayment.Status)
 	
 	// Verify mock expectations
 	mockQueue.AssertExpectations(t)
@@ -401,7 +422,7 @@ func TestExchangeRateWebhook(t *testing.T) {
 	req, err := http.NewRequest("POST", "/webhooks/exchange-rate", bytes.NewBuffer(payloadBytes))
 	require.NoError(t, err)
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", "test-api-key") // Add API key for authentication
+	req.Header.Set("X-API-Key", testWebhookAPIKey) // Add API key for authentication
 	
 	// Perform request
 	recorder := httptest.NewRecorder()
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/jobs"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/payment"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// WebhookReplayHandler lets admins reprocess stored provider webhooks, e.g. after fixing a
+// bug in their handling
+type WebhookReplayHandler struct {
+	paymentService *payment.PaymentService
+	jobQueue       jobEnqueuer
+	auditLogger    *utils.AuditLogger
+}
+
+// NewWebhookReplayHandler creates a new webhook replay handler
+func NewWebhookReplayHandler(db *gorm.DB, paymentService *payment.PaymentService, jobQueue jobEnqueuer) *WebhookReplayHandler {
+	return &WebhookReplayHandler{
+		paymentService: paymentService,
+		jobQueue:       jobQueue,
+		auditLogger:    utils.NewAuditLogger(db),
+	}
+}
+
+// BulkReplayRequest selects the stored webhooks to replay. Dates are YYYY-MM-DD or RFC3339,
+// and a to date includes the whole day.
+type BulkReplayRequest struct {
+	Provider models.PaymentProvider `json:"provider" binding:"required"`
+	From     string                 `json:"from" binding:"required"`
+	To       string                 `json:"to" binding:"required"`
+}
+
+// ReplayWebhook resets a stored webhook and queues it to be processed again
+func (h *WebhookReplayHandler) ReplayWebhook(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	webhook, err := h.paymentService.ResetWebhookForReplay(id)
+	if err != nil {
+		h.handleReplayError(c, err)
+		return
+	}
+	if err := h.enqueue(webhook.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue webhook for replay"})
+		return
+	}
+
+	h.auditLogger.LogAdminAction(c.Request.Context(), adminID, nil, c.ClientIP(), c.Request.UserAgent(),
+		"replay_webhook", true, map[string]interface{}{
+			"webhook_id": webhook.ID.String(),
+			"provider":   webhook.Provider,
+			"event":      webhook.Event,
+		})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status": "success",
+		"data": gin.H{
+			"webhook_id": webhook.ID,
+		},
+	})
+}
+
+// BulkReplayWebhooks resets every stored webhook from a provider in a date range and queues
+// them to be processed again
+func (h *WebhookReplayHandler) BulkReplayWebhooks(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req BulkReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, err := parseStatementDate(req.From, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, use YYYY-MM-DD or RFC3339"})
+		return
+	}
+	to, err := parseStatementDate(req.To, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, use YYYY-MM-DD or RFC3339"})
+		return
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	ids, err := h.paymentService.ResetWebhooksForReplay(payment.WebhookReplayFilter{
+		Provider: req.Provider,
+		From:     from,
+		To:       to,
+	})
+	if err != nil {
+		h.handleReplayError(c, err)
+		return
+	}
+
+	// Webhooks that fail to queue stay unprocessed, so the same replay can be run again
+	queued := 0
+	for _, id := range ids {
+		if err := h.enqueue(id); err != nil {
+			continue
+		}
+		queued++
+	}
+
+	h.auditLogger.LogAdminAction(c.Request.Context(), adminID, nil, c.ClientIP(), c.Request.UserAgent(),
+		"bulk_replay_webhooks", queued == len(ids), map[string]interface{}{
+			"provider": req.Provider,
+			"from":     from,
+			"to":       to,
+			"matched":  len(ids),
+			"queued":   queued,
+		})
+
+	if queued < len(ids) {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to queue some webhooks for replay, run the replay again",
+			"data": gin.H{
+				"matched": len(ids),
+				"queued":  queued,
+			},
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"status": "success",
+		"data": gin.H{
+			"matched": len(ids),
+			"queued":  queued,
+		},
+	})
+}
+
+// enqueue queues a payment webhook job for a stored webhook
+func (h *WebhookReplayHandler) enqueue(webhookID uuid.UUID) error {
+	_, err := h.jobQueue.EnqueueJob(queue.JobType(jobs.PaymentWebhookJobType), jobs.PaymentWebhookJobPayload{
+		WebhookID: webhookID,
+	})
+	if err != nil {
+		log.Printf("Failed to queue replay of webhook %s: %v", webhookID, err)
+	}
+	return err
+}
+
+// handleReplayError maps webhook replay errors to HTTP responses
+func (h *WebhookReplayHandler) handleReplayError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, payment.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+	case errors.Is(err, payment.ErrWebhookReplayTooLarge):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay webhooks"})
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/currency"
+	"github.com/revaspay/backend/internal/jobs"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/pagination"
+	"github.com/revaspay/backend/internal/services/account"
+	"github.com/revaspay/backend/internal/services/crypto"
+	"github.com/revaspay/backend/internal/services/wallet"
+)
+
+// WithdrawalHandler handles user withdrawal requests
+type WithdrawalHandler struct {
+	walletService  *wallet.WalletService
+	withdrawalJob  *jobs.WithdrawalJob
+	accountService *account.Service
+}
+
+// NewWithdrawalHandler creates a new withdrawal handler
+func NewWithdrawalHandler(walletService *wallet.WalletService, withdrawalJob *jobs.WithdrawalJob, accountService *account.Service) *WithdrawalHandler {
+	return &WithdrawalHandler{
+		walletService:  walletService,
+		withdrawalJob:  withdrawalJob,
+		accountService: accountService,
+	}
+}
+
+// CreateWithdrawal reserves funds for a withdrawal and queues it for payout
+func (h *WithdrawalHandler) CreateWithdrawal(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var req struct {
+		Amount        float64                `json:"amount" binding:"required,gt=0"`
+		Currency      string                 `json:"currency" binding:"required,len=3"`
+		Method        string                 `json:"method" binding:"required"`
+		DestinationID uuid.UUID              `json:"destination_id"`
+		Description   string                 `json:"description"`
+		Metadata      map[string]interface{} `json:"metadata"`
+		PIN           string                 `json:"pin" binding:"required"` // Transaction PIN confirming the withdrawal
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	}
+	if !checkTransactionPIN(c, h.accountService, userID, req.PIN) {
+		return
+	}
+
+	withdrawal, err := h.walletService.CreateWithdrawal(userID, wallet.CreateWithdrawalRequest{
+		Currency:      models.Currency(req.Currency),
+		Amount:        req.Amount,
+		Method:        req.Method,
+		DestinationID: req.DestinationID,
+		Description:   req.Description,
+		Metadata:      req.Metadata,
+	})
+	if err != nil {
+		h.handleWithdrawalError(c, err)
+		return
+	}
+
+	if err := h.withdrawalJob.EnqueueWithdrawalJob(withdrawal.ID); err != nil {
+		log.Printf("Failed to enqueue withdrawal %s: %v", withdrawal.ID, err)
+		if failErr := h.walletService.FailWithdrawal(withdrawal.ID, "failed to queue withdrawal for processing"); failErr != nil {
+			log.Printf("Failed to release hold for withdrawal %s: %v", withdrawal.ID, failErr)
+		}
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to queue withdrawal for processing", nil)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "success",
+		"data":   withdrawal,
+	})
+}
+
+// GetWithdrawals lists the user's withdrawals
+func (h *WithdrawalHandler) GetWithdrawals(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	params := pagination.ParseParams(c, 20)
+
+	withdrawals, total, err := h.walletService.GetWithdrawals(userID, params.Page, params.PageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to get withdrawals", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"data":       withdrawals,
+		"pagination": pagination.NewMeta(params, total),
+	})
+}
+
+// GetWithdrawal retrieves a specific withdrawal
+func (h *WithdrawalHandler) GetWithdrawal(c *gin.Context) {
+	userID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	withdrawalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "Invalid withdrawal ID", nil)
+		return
+	}
+
+	withdrawal, err := h.walletService.GetWithdrawal(withdrawalID, userID)
+	if err != nil {
+		h.handleWithdrawalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   withdrawal,
+	})
+}
+
+// ResolveWithdrawalRequest is an admin's resolution of a withdrawal stuck in processing
+type ResolveWithdrawalRequest struct {
+	Status string `json:"status" binding:"required,oneof=completed failed"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ResolveWithdrawal lets an admin mark a withdrawal stuck in processing completed, or failed
+// with its funds returned, once the payout provider has confirmed its outcome out of band
+func (h *WithdrawalHandler) ResolveWithdrawal(c *gin.Context) {
+	adminID, exists := getUserID(c)
+	if !exists {
+		respondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	withdrawalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, "Invalid withdrawal ID", nil)
+		return
+	}
+
+	var req ResolveWithdrawalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		return
+	}
+
+	withdrawal, err := h.withdrawalJob.ResolveWithdrawal(c.Request.Context(), adminID, withdrawalID, req.Status, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrInvalidResolution), errors.Is(err, jobs.ErrResolutionReasonRequired):
+			respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+		case errors.Is(err, jobs.ErrWithdrawalNotProcessing), errors.Is(err, jobs.ErrWithdrawalNotStuck):
+			respondError(c, http.StatusConflict, ErrCodeConflict, err.Error(), nil)
+		default:
+			h.handleWithdrawalError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   withdrawal,
+	})
+}
+
+// handleWithdrawalError maps withdrawal service errors to HTTP responses
+func (h *WithdrawalHandler) handleWithdrawalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		respondError(c, http.StatusBadRequest, ErrCodeInsufficientFunds, "Insufficient funds: the withdrawal amount exceeds your available balance", gin.H{"reason": err.Error()})
+	case errors.Is(err, wallet.ErrInvalidAmount),
+		errors.Is(err, currency.ErrUnsupportedCurrency),
+		errors.Is(err, wallet.ErrUnsupportedWithdrawalMethod),
+		errors.Is(err, wallet.ErrMissingWithdrawalDestination),
+		errors.Is(err, crypto.ErrInvalidAddress),
+		errors.Is(err, crypto.ErrUnsupportedNetwork):
+		respondError(c, http.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+	case errors.Is(err, wallet.ErrAccountSuspended):
+		respondError(c, http.StatusForbidden, ErrCodeAccountSuspended, "Your account is suspended; withdrawals are disabled", nil)
+	case errors.Is(err, wallet.ErrWalletNotFound), errors.Is(err, wallet.ErrWithdrawalNotFound):
+		respondError(c, http.StatusNotFound, ErrCodeNotFound, err.Error(), nil)
+	default:
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to process withdrawal request", nil)
+	}
+}
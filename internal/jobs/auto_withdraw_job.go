@@ -3,6 +3,7 @@ package jobs
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -62,7 +63,6 @@ func (j *AutoWithdrawJob) ScheduleAutoWithdrawCheck() (string, error) {
 	job := &queue.Job{
 		Type:       AutoWithdrawCheckJobType,
 		Payload:    payload,
-		MaxRetries: 3,
 	}
 	
 	// Enqueue job
@@ -111,7 +111,6 @@ func (j *AutoWithdrawJob) checkAutoWithdrawals(ctx context.Context, job queue.Jo
 			job := &queue.Job{
 				Type:       ProcessAutoWithdrawJobType,
 				Payload:    payloadBytes,
-				MaxRetries: 3,
 			}
 
 			// Enqueue job
@@ -146,6 +145,15 @@ func (j *AutoWithdrawJob) processWithdrawal(ctx context.Context, job queue.Job)
 		return nil, fmt.Errorf("error finding auto-withdraw config: %w", err)
 	}
 	
+	// Suspended accounts' wallets are frozen
+	if err := j.walletService.CheckWithdrawalsAllowed(config.UserID); err != nil {
+		if errors.Is(err, wallet.ErrAccountSuspended) {
+			log.Printf("Skipping auto-withdrawal for suspended user %s", config.UserID)
+			return nil, nil
+		}
+		return nil, err
+	}
+	
 	var wallet models.Wallet
 	if err := j.db.First(&wallet, "id = ?", withdrawPayload.WalletID).Error; err != nil {
 		return nil, fmt.Errorf("error finding wallet: %w", err)
@@ -170,7 +178,7 @@ func (j *AutoWithdrawJob) processWithdrawal(ctx context.Context, job queue.Job)
 		WalletID:      wallet.ID,
 		Amount:        wallet.Available,
 		Currency:      wallet.Currency,
-		Method:        config.WithdrawMethod,
+		Method:        autoWithdrawMethod(config.WithdrawMethod),
 		Status:        "pending",
 		Reference:     uuid.New().String(),
 		ProcessingFee: 0, // Fee will be calculated by withdrawal service
@@ -190,74 +198,44 @@ func (j *AutoWithdrawJob) processWithdrawal(ctx context.Context, job queue.Job)
 	// Use the metadata map directly
 	withdrawal.MetaData = models.JSON(metadataMap)
 	
-	// MetaData is set above
+	// Reserve the funds; they are only debited once the payout completes
+	hold, err := j.walletService.HoldWithTx(tx, wallet.ID, withdrawal.Amount, withdrawal.Reference, "Auto-withdrawal pending")
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("error holding wallet funds: %w", err)
+	}
+	withdrawal.HoldID = &hold.ID
 
 	if err := tx.Create(&withdrawal).Error; err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("error creating withdrawal record: %w", err)
 	}
 	
-	// Debit the wallet
-	balanceBefore := wallet.Balance
-	wallet.Balance -= wallet.Available
-	wallet.Available = 0
-	
-	if err := tx.Save(&wallet).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("error updating wallet balance: %w", err)
-	}
-	
-	// Create transaction record
-	txMetadataMap := map[string]interface{}{
-		"auto_withdraw": true,
-		"withdrawal_id": withdrawal.ID.String(),
-	}
-	// No need to marshal to JSON bytes anymore
-	
-	transaction := models.Transaction{
-		WalletID:      wallet.ID,
-		Type:          "withdrawal",
-		Amount:        -withdrawal.Amount, // Negative for debit
-		Currency:      wallet.Currency,
-		Status:        "pending",
-		Reference:     withdrawal.Reference,
-		Description:   "Auto-withdrawal",
-		MetaData:      models.JSON(txMetadataMap),
-		BalanceBefore: balanceBefore,
-		BalanceAfter:  wallet.Balance,
-	}
-	
-	if err := tx.Create(&transaction).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("error creating transaction record: %w", err)
-	}
-	
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("error committing transaction: %w", err)
 	}
 	
-	// Enqueue a job to process the actual withdrawal through the payment provider
-	// This would typically be handled by a separate withdrawal service
-	payloadBytes, err := json.Marshal(map[string]interface{}{
-		"withdrawal_id": withdrawal.ID.String(),
-		"type": "auto_withdraw",
-	})
+	// Hand the withdrawal to the withdrawal processor, which captures or releases the hold
+	payloadBytes, err := json.Marshal(WithdrawalJobPayload{WithdrawalID: withdrawal.ID})
 	if err != nil {
-		log.Printf("Error marshaling payment processing payload for withdrawal %s: %v", withdrawal.ID, err)
+		log.Printf("Error marshaling withdrawal job payload for withdrawal %s: %v", withdrawal.ID, err)
 		return nil, nil
 	}
 	
 	// Create job
 	processJob := &queue.Job{
-		Type:       queue.JobTypeProcessPayment,
+		Type:       queue.JobType(WithdrawalProcessJobType),
 		Payload:    payloadBytes,
-		MaxRetries: 3,
 	}
 	
 	// Enqueue job
 	if err := j.queue.Enqueue(processJob); err != nil {
-		log.Printf("Error enqueueing payment processing job for withdrawal %s: %v", withdrawal.ID, err)
+		log.Printf("Error enqueueing withdrawal job for withdrawal %s: %v", withdrawal.ID, err)
+		if failErr := j.walletService.FailWithdrawal(withdrawal.ID, "failed to queue withdrawal for processing"); failErr != nil {
+			log.Printf("Error releasing hold for withdrawal %s: %v", withdrawal.ID, failErr)
+		}
+		return nil, fmt.Errorf("error enqueueing withdrawal job: %w", err)
 	}
 	
 	log.Printf("Auto-withdrawal processed for user %s: %f %s", config.UserID, withdrawal.Amount, wallet.Currency)
@@ -270,3 +248,11 @@ func (j *AutoWithdrawJob) processWithdrawal(ctx context.Context, job queue.Job)
 		"processed_at": time.Now(),
 	}, nil
 }
+
+// autoWithdrawMethod maps the method stored on an auto-withdraw config to the withdrawal processor's name
+func autoWithdrawMethod(method string) string {
+	if method == "bank" {
+		return wallet.WithdrawalMethodBankTransfer
+	}
+	return method
+}
@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/dataexport"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// CleanupJobType is the job type for deleting expired tokens, old sessions and finished jobs
+const CleanupJobType queue.JobType = "cleanup_expired_records"
+
+// CleanupSettings controls how often the cleanup job runs and how long it keeps records
+type CleanupSettings struct {
+	Interval               time.Duration // Time between cleanup runs
+	SessionRetention       time.Duration // How long expired and revoked sessions are kept, for security reviews
+	JobRetention           time.Duration // How long completed queue jobs are kept
+	AuditInfoRetention     time.Duration // How long info audit logs are kept; 0 keeps them forever
+	AuditWarningRetention  time.Duration // How long warning audit logs are kept
+	AuditErrorRetention    time.Duration // How long error audit logs are kept
+	AuditCriticalRetention time.Duration // How long critical audit logs are kept
+	AuditAnonymize         bool          // Anonymize warning and worse audit logs past their retention instead of deleting them
+}
+
+// CleanupJob deletes records that are no longer needed: expired password reset and email
+// verification tokens, expired and revoked sessions past their retention, completed jobs,
+// expired data exports and audit logs past the retention for their severity
+type CleanupJob struct {
+	db       *gorm.DB
+	queue    queue.QueueInterface
+	settings CleanupSettings
+	exports  *dataexport.Service
+}
+
+// NewCleanupJob creates a new cleanup job handler
+func NewCleanupJob(db *gorm.DB, q queue.QueueInterface, settings CleanupSettings) *CleanupJob {
+	return &CleanupJob{
+		db:       db,
+		queue:    q,
+		settings: settings,
+	}
+}
+
+// SetDataExportService sets the service whose expired exports are deleted. Without one,
+// exports are left in storage.
+func (j *CleanupJob) SetDataExportService(exports *dataexport.Service) {
+	j.exports = exports
+}
+
+// RegisterCleanupJobHandlers registers the cleanup job handler. exports may be nil.
+func RegisterCleanupJobHandlers(q queue.QueueInterface, db *gorm.DB, settings CleanupSettings, exports *dataexport.Service) {
+	handler := NewCleanupJob(db, q, settings)
+	handler.SetDataExportService(exports)
+
+	q.RegisterHandler(CleanupJobType, func(ctx context.Context, job queue.Job) (interface{}, error) {
+		return nil, handler.Cleanup(ctx, job)
+	})
+}
+
+// ScheduleCleanup schedules the first cleanup run
+func (j *CleanupJob) ScheduleCleanup() error {
+	return j.scheduleRun(time.Now())
+}
+
+// scheduleRun enqueues a cleanup run at the given time
+func (j *CleanupJob) scheduleRun(runAt time.Time) error {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"scheduled_at": runAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cleanup payload: %w", err)
+	}
+
+	return j.queue.Enqueue(&queue.Job{
+		ID:        uuid.New(),
+		Type:      CleanupJobType,
+		Payload:   payloadBytes,
+		NextRetry: &runAt,
+	})
+}
+
+// Cleanup deletes the records that are no longer needed and schedules the next run. A step
+// that fails is logged and left for the next run rather than retried, so one stuck table
+// doesn't hold up the others.
+func (j *CleanupJob) Cleanup(ctx context.Context, _ queue.Job) error {
+	for _, step := range j.steps(ctx, time.Now()) {
+		removed, err := step.run()
+		if err != nil {
+			log.Printf("Cleanup of %s failed: %v", step.name, err)
+			continue
+		}
+		log.Printf("Cleanup removed %d %s", removed, step.name)
+	}
+
+	return j.scheduleRun(time.Now().Add(j.settings.Interval))
+}
+
+// cleanupStep deletes one kind of record, returning how many it removed
+type cleanupStep struct {
+	name string
+	run  func() (int64, error)
+}
+
+// steps returns the cleanup steps for a run at now
+func (j *CleanupJob) steps(ctx context.Context, now time.Time) []cleanupStep {
+	sessionCutoff := now.Add(-j.settings.SessionRetention)
+	jobCutoff := now.Add(-j.settings.JobRetention)
+
+	steps := []cleanupStep{
+		{"expired password reset tokens", func() (int64, error) {
+			result := j.db.Where("expires_at < ?", now).Delete(&database.PasswordResetToken{})
+			return result.RowsAffected, result.Error
+		}},
+		{"expired email verification tokens", func() (int64, error) {
+			result := j.db.Unscoped().Where("expires_at < ?", now).Delete(&models.EmailVerificationToken{})
+			return result.RowsAffected, result.Error
+		}},
+		{"expired and revoked sessions", func() (int64, error) {
+			result := j.db.Where("expires_at < ? OR (status IN ? AND COALESCE(revoked_at, last_active_at) < ?)",
+				sessionCutoff, []database.SessionStatus{database.SessionStatusRevoked, database.SessionStatusExpired}, sessionCutoff).
+				Delete(&database.EnhancedSession{})
+			return result.RowsAffected, result.Error
+		}},
+		{"expired and deleted legacy sessions", func() (int64, error) {
+			result := j.db.Unscoped().Where("expires_at < ? OR deleted_at < ?", sessionCutoff, sessionCutoff).
+				Delete(&models.Session{})
+			return result.RowsAffected, result.Error
+		}},
+		{"completed jobs", func() (int64, error) {
+			result := j.db.Where("status = ? AND updated_at < ?", queue.JobStatusCompleted, jobCutoff).
+				Delete(&queue.Job{})
+			return result.RowsAffected, result.Error
+		}},
+	}
+	steps = append(steps, cleanupStep{"audit logs past their retention", func() (int64, error) {
+		deleted, anonymized, err := utils.PruneAuditLogs(j.db, utils.AuditRetention{
+			Info:      j.settings.AuditInfoRetention,
+			Warning:   j.settings.AuditWarningRetention,
+			Error:     j.settings.AuditErrorRetention,
+			Critical:  j.settings.AuditCriticalRetention,
+			Anonymize: j.settings.AuditAnonymize,
+		}, now)
+		if anonymized > 0 {
+			log.Printf("Cleanup anonymized %d audit logs", anonymized)
+		}
+		return deleted, err
+	}})
+	if j.exports != nil {
+		steps = append(steps, cleanupStep{"expired data exports", func() (int64, error) {
+			return j.exports.DeleteExpired(ctx, now)
+		}})
+	}
+	return steps
+}
@@ -0,0 +1,151 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/security/audit"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/revaspay/backend/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingQueue is a queue.QueueInterface that keeps the jobs enqueued on it
+type recordingQueue struct {
+	queue.QueueInterface
+	jobs []*queue.Job
+}
+
+func (q *recordingQueue) Enqueue(job *queue.Job) error {
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+func TestCleanupDeletesExpiredRecords(t *testing.T) {
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&database.EnhancedSession{}, &queue.Job{}))
+	user := testutil.CreateUser(t, db)
+
+	now := time.Now()
+	hour := time.Hour
+	day := 24 * hour
+
+	require.NoError(t, db.Create([]database.PasswordResetToken{
+		{ID: uuid.New(), UserID: user.ID, Token: "expired", ExpiresAt: now.Add(-hour)},
+		{ID: uuid.New(), UserID: user.ID, Token: "valid", ExpiresAt: now.Add(hour)},
+	}).Error)
+	require.NoError(t, db.Create([]models.EmailVerificationToken{
+		{ID: uuid.New(), UserID: user.ID, Email: user.Email, Token: "expired", ExpiresAt: now.Add(-hour)},
+		{ID: uuid.New(), UserID: user.ID, Email: user.Email, Token: "valid", ExpiresAt: now.Add(day)},
+	}).Error)
+
+	revokedLongAgo := now.Add(-40 * day)
+	revokedRecently := now.Add(-day)
+	require.NoError(t, db.Create([]database.EnhancedSession{
+		{ID: uuid.New(), UserID: user.ID, Status: database.SessionStatusActive, ExpiresAt: now.Add(day), LastActiveAt: now},
+		{ID: uuid.New(), UserID: user.ID, Status: database.SessionStatusActive, ExpiresAt: now.Add(-40 * day), LastActiveAt: now.Add(-41 * day)},
+		{ID: uuid.New(), UserID: user.ID, Status: database.SessionStatusRevoked, ExpiresAt: now.Add(day), RevokedAt: &revokedLongAgo},
+		{ID: uuid.New(), UserID: user.ID, Status: database.SessionStatusRevoked, ExpiresAt: now.Add(day), RevokedAt: &revokedRecently},
+	}).Error)
+
+	require.NoError(t, db.Create([]queue.Job{
+		{ID: uuid.New(), Type: "test", Status: queue.JobStatusCompleted, UpdatedAt: now.Add(-10 * day)},
+		{ID: uuid.New(), Type: "test", Status: queue.JobStatusCompleted, UpdatedAt: now.Add(-day)},
+		{ID: uuid.New(), Type: "test", Status: queue.JobStatusFailed, UpdatedAt: now.Add(-10 * day)},
+	}).Error)
+
+	q := &recordingQueue{}
+	job := NewCleanupJob(db, q, CleanupSettings{Interval: 6 * hour, SessionRetention: 30 * day, JobRetention: 7 * day})
+	require.NoError(t, job.Cleanup(context.Background(), queue.Job{}))
+
+	count := func(model interface{}) int64 {
+		var n int64
+		require.NoError(t, db.Model(model).Count(&n).Error)
+		return n
+	}
+	assert.Equal(t, int64(1), count(&database.PasswordResetToken{}))
+	assert.Equal(t, int64(1), count(&models.EmailVerificationToken{}))
+	assert.Equal(t, int64(2), count(&database.EnhancedSession{}), "active and recently revoked sessions are kept")
+	assert.Equal(t, int64(2), count(&queue.Job{}), "recent and failed jobs are kept")
+
+	require.Len(t, q.jobs, 1)
+	assert.Equal(t, CleanupJobType, q.jobs[0].Type)
+	assert.WithinDuration(t, now.Add(6*hour), *q.jobs[0].NextRetry, time.Minute)
+}
+
+// Audit logs are kept for the retention of their severity, and security events can be kept
+// without their personal data instead of being deleted
+func TestCleanupPrunesAuditLogs(t *testing.T) {
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&utils.AuditLog{}))
+	require.NoError(t, db.AutoMigrate(&audit.AuditLog{}))
+	user := testutil.CreateUser(t, db)
+
+	now := time.Now()
+	day := 24 * time.Hour
+	createLog := func(severity string, age time.Duration) uuid.UUID {
+		t.Helper()
+		entry := utils.AuditLog{
+			ID:          uuid.New(),
+			UserID:      &user.ID,
+			IPAddress:   "41.66.0.1",
+			UserAgent:   "test-agent",
+			Severity:    utils.AuditEventSeverity(severity),
+			Description: "Login",
+			Details:     `{"email":"ama@example.com"}`,
+			CreatedAt:   now.Add(-age),
+		}
+		require.NoError(t, db.Create(&entry).Error)
+		return entry.ID
+	}
+	oldInfo := createLog("INFO", 100*day)
+	oldLowercaseInfo := createLog("info", 100*day)
+	recentInfo := createLog("INFO", day)
+	oldWarning := createLog("WARNING", 400*day)
+	yearOldCritical := createLog("CRITICAL", 400*day)
+
+	settings := CleanupSettings{
+		Interval:               time.Hour,
+		AuditInfoRetention:     90 * day,
+		AuditWarningRetention:  365 * day,
+		AuditErrorRetention:    365 * day,
+		AuditCriticalRetention: 5 * 365 * day,
+	}
+	exists := func(id uuid.UUID) bool {
+		var n int64
+		require.NoError(t, db.Model(&utils.AuditLog{}).Where("id = ?", id).Count(&n).Error)
+		return n == 1
+	}
+
+	// With anonymization, old security events lose their personal data but are kept
+	settings.AuditAnonymize = true
+	require.NoError(t, NewCleanupJob(db, &recordingQueue{}, settings).Cleanup(context.Background(), queue.Job{}))
+	assert.False(t, exists(oldInfo))
+	assert.False(t, exists(oldLowercaseInfo))
+	assert.True(t, exists(recentInfo))
+	assert.True(t, exists(yearOldCritical), "critical logs are kept longer")
+
+	var anonymized utils.AuditLog
+	require.NoError(t, db.First(&anonymized, "id = ?", oldWarning).Error)
+	assert.Nil(t, anonymized.UserID)
+	assert.Empty(t, anonymized.IPAddress)
+	assert.Empty(t, anonymized.UserAgent)
+	assert.Empty(t, anonymized.Details)
+	assert.Equal(t, "Login", anonymized.Description)
+
+	var kept utils.AuditLog
+	require.NoError(t, db.First(&kept, "id = ?", yearOldCritical).Error)
+	assert.Equal(t, "41.66.0.1", kept.IPAddress)
+
+	// Without it, they are deleted
+	settings.AuditAnonymize = false
+	require.NoError(t, NewCleanupJob(db, &recordingQueue{}, settings).Cleanup(context.Background(), queue.Job{}))
+	assert.False(t, exists(oldWarning))
+	assert.True(t, exists(recentInfo))
+}
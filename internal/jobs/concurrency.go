@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"log"
+
+	"github.com/revaspay/backend/internal/queue"
+)
+
+// jobConcurrency is the most jobs of each type that run at once on an instance, before
+// config overrides. Job types not listed here may use every worker.
+//
+//   - KYC verifications wait on slow provider checks, so 3 at a time.
+//   - Reconciliations scan whole ledgers, and cleanup, payment expiry and KYC polling whole
+//     tables, so one at a time.
+var jobConcurrency = map[queue.JobType]int{
+	KYCVerificationJobType:              3,
+	VirtualAccountReconciliationJobType: 1,
+	WalletReconciliationJobType:         1,
+	ProviderReconciliationJobType:       1,
+	CleanupJobType:                      1,
+	ExpirePaymentsJobType:               1,
+	PollKYCVerificationsJobType:         1,
+}
+
+// ConfigureJobConcurrency sets the concurrency limit of each background job on the processor,
+// applying any overrides from config by job type. Call it before the processor starts.
+func ConfigureJobConcurrency(p *queue.JobProcessor, overrides map[string]int) {
+	for jobType, limit := range jobConcurrency {
+		p.SetConcurrencyLimit(string(jobType), limit)
+	}
+	for jobType, limit := range overrides {
+		log.Printf("Using concurrency limit %d for %s jobs", limit, jobType)
+		p.SetConcurrencyLimit(jobType, limit)
+	}
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/dataexport"
+)
+
+// NewDataExportJobHandlers creates the data export handlers for any queue
+func NewDataExportJobHandlers(service *dataexport.Service) map[queue.JobType]queue.JobHandler {
+	return map[queue.JobType]queue.JobHandler{
+		dataexport.BuildDataExportJobType: func(ctx context.Context, job queue.Job) (interface{}, error) {
+			var payload dataexport.BuildDataExportPayload
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal data export payload: %w", err)
+			}
+
+			err := service.Build(ctx, payload.ExportID)
+			if err != nil && !queue.RetryPolicyFor(job.Type).CanRetry(job.RetryCount) {
+				// Out of retries, so let the user ask again without waiting out the daily limit
+				service.MarkFailed(payload.ExportID, err)
+			}
+			return nil, err
+		},
+	}
+}
+
+// RegisterDataExportJobHandlers registers the data export job handlers
+func RegisterDataExportJobHandlers(q queue.QueueInterface, service *dataexport.Service) {
+	for jobType, handler := range NewDataExportJobHandlers(service) {
+		q.RegisterHandler(jobType, handler)
+	}
+}
@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/kyc"
+)
+
+// PollKYCVerificationsJobType is the job type for polling KYC providers for results their
+// webhooks haven't delivered
+const PollKYCVerificationsJobType queue.JobType = "poll_kyc_verifications"
+
+// KYCPollJob polls KYC providers for the results of submitted verifications, and times out
+// those that never get one, on the poller's interval
+type KYCPollJob struct {
+	queue  queue.QueueInterface
+	poller *kyc.Poller
+}
+
+// NewKYCPollJob creates a new KYC poll job handler
+func NewKYCPollJob(q queue.QueueInterface, poller *kyc.Poller) *KYCPollJob {
+	return &KYCPollJob{
+		queue:  q,
+		poller: poller,
+	}
+}
+
+// RegisterKYCPollJobHandlers registers the KYC poll job handler
+func RegisterKYCPollJobHandlers(q queue.QueueInterface, poller *kyc.Poller) {
+	handler := NewKYCPollJob(q, poller)
+
+	q.RegisterHandler(PollKYCVerificationsJobType, func(ctx context.Context, job queue.Job) (interface{}, error) {
+		return nil, handler.PollVerifications(ctx, job)
+	})
+}
+
+// SchedulePolling schedules the first poll run
+func (j *KYCPollJob) SchedulePolling() error {
+	return j.scheduleRun(time.Now())
+}
+
+// scheduleRun enqueues a poll run at the given time
+func (j *KYCPollJob) scheduleRun(runAt time.Time) error {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"scheduled_at": runAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal KYC poll payload: %w", err)
+	}
+
+	return j.queue.Enqueue(&queue.Job{
+		ID:        uuid.New(),
+		Type:      PollKYCVerificationsJobType,
+		Payload:   payloadBytes,
+		NextRetry: &runAt,
+	})
+}
+
+// PollVerifications polls the verifications due a poll and schedules the next run. A failed
+// run is logged and left for the next one, which picks up the same verifications.
+func (j *KYCPollJob) PollVerifications(ctx context.Context, _ queue.Job) error {
+	polled, timedOut, err := j.poller.PollDue(ctx, time.Now())
+	if err != nil {
+		log.Printf("KYC polling failed after polling %d and timing out %d verifications: %v", polled, timedOut, err)
+	} else if polled > 0 || timedOut > 0 {
+		log.Printf("Polled %d KYC verifications and timed out %d", polled, timedOut)
+	}
+
+	return j.scheduleRun(time.Now().Add(j.poller.Interval()))
+}
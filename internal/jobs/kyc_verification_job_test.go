@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/kyc"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKYCProvider is a KYC provider that records submissions and fails them while err is set
+type fakeKYCProvider struct {
+	kyc.Provider
+	submitted []uuid.UUID
+	err       error
+}
+
+func (p *fakeKYCProvider) Name() string { return "fake" }
+
+func (p *fakeKYCProvider) Submit(_ context.Context, verificationID uuid.UUID) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	p.submitted = append(p.submitted, verificationID)
+	return "provider-job-1", nil
+}
+
+// A submitted verification is sent to its provider once; a failed submission stays pending so
+// the job's retry sends it again
+func TestKYCVerificationJobSubmitsOnce(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+	provider := &fakeKYCProvider{err: errors.New("provider unavailable")}
+	job := NewKYCVerificationJob(db, nil, provider)
+
+	verification := models.KYCVerification{UserID: user.ID, Provider: "fake", Status: models.KYCStatusPending}
+	require.NoError(t, db.Create(&verification).Error)
+	payload, err := json.Marshal(KYCVerificationJobPayload{VerificationID: verification.ID})
+	require.NoError(t, err)
+	process := func() error {
+		return job.ProcessKYCVerification(context.Background(), &queue.Job{Type: queue.JobType(KYCVerificationJobType), Payload: payload})
+	}
+	reload := func() models.KYCVerification {
+		var reloaded models.KYCVerification
+		require.NoError(t, db.First(&reloaded, "id = ?", verification.ID).Error)
+		return reloaded
+	}
+
+	assert.ErrorIs(t, process(), provider.err)
+	assert.Equal(t, models.KYCStatusPending, reload().Status)
+
+	provider.err = nil
+	require.NoError(t, process())
+	require.NoError(t, process())
+	assert.Equal(t, []uuid.UUID{verification.ID}, provider.submitted)
+	submitted := reload()
+	assert.Equal(t, models.KYCStatusInProgress, submitted.Status)
+	assert.Equal(t, "provider-job-1", submitted.SessionID)
+	assert.NotNil(t, submitted.SubmittedAt)
+
+	var history []models.KYCVerificationHistory
+	require.NoError(t, db.Find(&history, "verification_id = ?", verification.ID).Error)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.KYCStatusInProgress, history[0].NewStatus)
+
+	// Verifications for a provider that isn't configured aren't dropped
+	other := models.KYCVerification{UserID: testutil.CreateUser(t, db).ID, Provider: "didit", Status: models.KYCStatusPending}
+	require.NoError(t, db.Create(&other).Error)
+	payload, err = json.Marshal(KYCVerificationJobPayload{VerificationID: other.ID})
+	require.NoError(t, err)
+	assert.Error(t, process())
+}
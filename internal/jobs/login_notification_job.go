@@ -0,0 +1,142 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/email"
+	"gorm.io/gorm"
+)
+
+// SendLoginNotificationJobType emails a user about a login from a new device or location
+const SendLoginNotificationJobType queue.JobType = "send_login_notification"
+
+// SendLoginNotificationPayload names the session the new login email is about
+type SendLoginNotificationPayload struct {
+	SessionID uuid.UUID `json:"session_id"`
+}
+
+// loginNotificationMailer sends the new login email
+type loginNotificationMailer interface {
+	SendTemplate(toEmail, name, locale string, data map[string]interface{}) error
+}
+
+// LoginNotificationJob emails users when their account is signed in to from a device or
+// location they haven't used before, with a link to revoke the new session
+type LoginNotificationJob struct {
+	db          *gorm.DB
+	mailer      loginNotificationMailer
+	frontendURL string
+}
+
+// NewLoginNotificationJob creates a new login notification job handler
+func NewLoginNotificationJob(db *gorm.DB, emailService *email.EmailService) *LoginNotificationJob {
+	return &LoginNotificationJob{
+		db:          db,
+		mailer:      emailService,
+		frontendURL: os.Getenv("FRONTEND_URL"),
+	}
+}
+
+// NewLoginNotificationJobHandlers creates the login notification handlers for any queue
+func NewLoginNotificationJobHandlers(db *gorm.DB, emailService *email.EmailService) map[queue.JobType]queue.JobHandler {
+	handler := NewLoginNotificationJob(db, emailService)
+
+	return map[queue.JobType]queue.JobHandler{
+		SendLoginNotificationJobType: func(ctx context.Context, job queue.Job) (interface{}, error) {
+			var payload SendLoginNotificationPayload
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal login notification payload: %w", err)
+			}
+			return nil, handler.SendNotification(ctx, payload)
+		},
+	}
+}
+
+// RegisterLoginNotificationJobHandlers registers the login notification job handlers
+func RegisterLoginNotificationJobHandlers(q queue.QueueInterface, db *gorm.DB, emailService *email.EmailService) {
+	for jobType, handler := range NewLoginNotificationJobHandlers(db, emailService) {
+		q.RegisterHandler(jobType, handler)
+	}
+}
+
+// SendNotification emails the owner of a session about the login that created it. Sessions
+// that have already ended are skipped, since there is nothing left to revoke.
+func (j *LoginNotificationJob) SendNotification(_ context.Context, payload SendLoginNotificationPayload) error {
+	var session database.EnhancedSession
+	if err := j.db.First(&session, "id = ?", payload.SessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Session %s for login notification not found, skipping", payload.SessionID)
+			return nil
+		}
+		return fmt.Errorf("failed to find session: %w", err)
+	}
+	if session.Status != database.SessionStatusActive {
+		return nil
+	}
+
+	var user database.User
+	if err := j.db.Select("id", "email", "first_name").First(&user, "id = ?", session.UserID).Error; err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	token, err := database.IssueSessionRevokeToken(j.db, session.ID)
+	if err != nil {
+		return fmt.Errorf("failed to issue revoke token for session %s: %w", session.ID, err)
+	}
+
+	if err := j.mailer.SendTemplate(user.Email, email.TemplateNewLogin, email.DefaultLocale, map[string]interface{}{
+		"Name":      user.FirstName,
+		"Device":    describeSessionDevice(&session),
+		"Location":  describeSessionLocation(&session),
+		"IPAddress": session.IPAddress,
+		"Time":      session.CreatedAt.UTC().Format("2 Jan 2006, 15:04 MST"),
+		"RevokeURL": fmt.Sprintf("%s/security/revoke-session?token=%s", j.frontendURL, url.QueryEscape(token)),
+	}); err != nil {
+		return fmt.Errorf("failed to send login notification for session %s: %w", session.ID, err)
+	}
+	return nil
+}
+
+// describeSessionDevice names a session's device the way a user would, e.g. "Chrome on Windows"
+func describeSessionDevice(session *database.EnhancedSession) string {
+	device, err := session.GetDeviceInfo()
+	if err != nil || (device.Browser == "" && device.OS == "") {
+		return "Unknown device"
+	}
+	switch {
+	case device.Browser == "":
+		return device.OS
+	case device.OS == "":
+		return device.Browser
+	default:
+		return device.Browser + " on " + device.OS
+	}
+}
+
+// describeSessionLocation names where a session was created from, e.g. "Accra, Greater Accra, Ghana"
+func describeSessionLocation(session *database.EnhancedSession) string {
+	metadata, err := session.GetMetadata()
+	if err != nil {
+		return "Unknown location"
+	}
+	var parts []string
+	for _, part := range []string{metadata.City, metadata.Region, metadata.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "Unknown location"
+	}
+	return strings.Join(parts, ", ")
+}
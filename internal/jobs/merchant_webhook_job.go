@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/webhook"
+)
+
+// MerchantWebhookJob handles outbound webhook deliveries to merchant endpoints
+type MerchantWebhookJob struct {
+	queue      queue.QueueInterface
+	webhookSvc *webhook.WebhookService
+}
+
+// NewMerchantWebhookJob creates a new merchant webhook job handler
+func NewMerchantWebhookJob(q queue.QueueInterface, webhookSvc *webhook.WebhookService) *MerchantWebhookJob {
+	return &MerchantWebhookJob{
+		queue:      q,
+		webhookSvc: webhookSvc,
+	}
+}
+
+// RegisterMerchantWebhookJobHandlers registers the merchant webhook job handlers
+func RegisterMerchantWebhookJobHandlers(q queue.QueueInterface, webhookSvc *webhook.WebhookService) {
+	handler := NewMerchantWebhookJob(q, webhookSvc)
+
+	q.RegisterHandler(webhook.DeliverWebhookJobType, func(ctx context.Context, job queue.Job) (interface{}, error) {
+		return nil, handler.DeliverWebhook(ctx, job)
+	})
+	q.RegisterHandler(webhook.RetryWebhookDeliveriesJobType, func(ctx context.Context, job queue.Job) (interface{}, error) {
+		return nil, handler.RetryDueDeliveries(ctx, job)
+	})
+	q.RegisterHandler(webhook.DispatchBalanceChangesJobType, func(ctx context.Context, job queue.Job) (interface{}, error) {
+		return nil, handler.DispatchBalanceChanges(ctx, job)
+	})
+}
+
+// ScheduleWebhookRetrySweep schedules the job that re-enqueues deliveries due for retry
+func (j *MerchantWebhookJob) ScheduleWebhookRetrySweep() error {
+	return j.scheduleSweep(time.Now())
+}
+
+// ScheduleBalanceChangeSweep schedules the job that sends coalesced wallet balance changes
+func (j *MerchantWebhookJob) ScheduleBalanceChangeSweep() error {
+	return j.scheduleBalanceChangeSweep(time.Now())
+}
+
+// scheduleBalanceChangeSweep enqueues a balance change sweep to run at the given time
+func (j *MerchantWebhookJob) scheduleBalanceChangeSweep(runAt time.Time) error {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"scheduled_at": runAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal balance change sweep payload: %w", err)
+	}
+
+	return j.queue.Enqueue(&queue.Job{
+		Type:      webhook.DispatchBalanceChangesJobType,
+		Payload:   payloadBytes,
+		NextRetry: &runAt,
+	})
+}
+
+// scheduleSweep enqueues a retry sweep to run at the given time
+func (j *MerchantWebhookJob) scheduleSweep(runAt time.Time) error {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"scheduled_at": runAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook retry sweep payload: %w", err)
+	}
+
+	job := &queue.Job{
+		Type:      webhook.RetryWebhookDeliveriesJobType,
+		Payload:   payloadBytes,
+		NextRetry: &runAt,
+	}
+
+	return j.queue.Enqueue(job)
+}
+
+// DeliverWebhook attempts a single delivery. Failed HTTP attempts are rescheduled by the
+// service, so only infrastructure errors are returned to the queue.
+func (j *MerchantWebhookJob) DeliverWebhook(ctx context.Context, job queue.Job) error {
+	var payload webhook.DeliverWebhookPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal webhook delivery payload: %w", err)
+	}
+
+	return j.webhookSvc.Deliver(ctx, payload.DeliveryID)
+}
+
+// RetryDueDeliveries re-enqueues deliveries whose backoff has elapsed and schedules the next sweep
+func (j *MerchantWebhookJob) RetryDueDeliveries(_ context.Context, _ queue.Job) error {
+	count, err := j.webhookSvc.RetryDueDeliveries()
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		log.Printf("Re-enqueued %d merchant webhook deliveries", count)
+	}
+
+	// Sweep again in a minute
+	return j.scheduleSweep(time.Now().Add(1 * time.Minute))
+}
+
+// DispatchBalanceChanges sends the wallet balance changes that have been coalesced for long
+// enough and schedules the next sweep, one coalescing window later
+func (j *MerchantWebhookJob) DispatchBalanceChanges(_ context.Context, _ queue.Job) error {
+	count, err := j.webhookSvc.DispatchBalanceChanges(time.Now())
+	if err != nil {
+		log.Printf("Failed to dispatch wallet balance changes: %v", err)
+	}
+
+	if count > 0 {
+		log.Printf("Dispatched %d wallet balance change events", count)
+	}
+
+	return j.scheduleBalanceChangeSweep(time.Now().Add(j.webhookSvc.BalanceChangeWindow()))
+}
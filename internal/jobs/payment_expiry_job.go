@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/payment"
+)
+
+// ExpirePaymentsJobType is the job type for expiring pending payments that weren't paid in time
+const ExpirePaymentsJobType queue.JobType = "expire_payments"
+
+// PaymentExpiryJob marks pending payments past their expiry as expired, on an interval
+type PaymentExpiryJob struct {
+	queue      queue.QueueInterface
+	paymentSvc *payment.PaymentService
+	interval   time.Duration
+}
+
+// NewPaymentExpiryJob creates a new payment expiry job handler
+func NewPaymentExpiryJob(q queue.QueueInterface, paymentSvc *payment.PaymentService, interval time.Duration) *PaymentExpiryJob {
+	return &PaymentExpiryJob{
+		queue:      q,
+		paymentSvc: paymentSvc,
+		interval:   interval,
+	}
+}
+
+// RegisterPaymentExpiryJobHandlers registers the payment expiry job handler
+func RegisterPaymentExpiryJobHandlers(q queue.QueueInterface, paymentSvc *payment.PaymentService, interval time.Duration) {
+	handler := NewPaymentExpiryJob(q, paymentSvc, interval)
+
+	q.RegisterHandler(ExpirePaymentsJobType, func(ctx context.Context, job queue.Job) (interface{}, error) {
+		return nil, handler.ExpirePayments(ctx, job)
+	})
+}
+
+// ScheduleExpiry schedules the first expiry run
+func (j *PaymentExpiryJob) ScheduleExpiry() error {
+	return j.scheduleRun(time.Now())
+}
+
+// scheduleRun enqueues an expiry run at the given time
+func (j *PaymentExpiryJob) scheduleRun(runAt time.Time) error {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"scheduled_at": runAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment expiry payload: %w", err)
+	}
+
+	return j.queue.Enqueue(&queue.Job{
+		ID:        uuid.New(),
+		Type:      ExpirePaymentsJobType,
+		Payload:   payloadBytes,
+		NextRetry: &runAt,
+	})
+}
+
+// ExpirePayments expires the pending payments past their expiry and schedules the next run.
+// A failed run is logged and left for the next one, which picks up the same payments.
+func (j *PaymentExpiryJob) ExpirePayments(ctx context.Context, _ queue.Job) error {
+	expired, err := j.paymentSvc.ExpirePayments(ctx, time.Now())
+	if err != nil {
+		log.Printf("Payment expiry failed after expiring %d payments: %v", expired, err)
+	} else if expired > 0 {
+		log.Printf("Expired %d unpaid payments", expired)
+	}
+
+	return j.scheduleRun(time.Now().Add(j.interval))
+}
@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/email"
+	"github.com/revaspay/backend/internal/services/payment"
+	"gorm.io/gorm"
+)
+
+// PaymentReceiptJob emails customers the receipt for their completed payments
+type PaymentReceiptJob struct {
+	db           *gorm.DB
+	emailService *email.EmailService
+	frontendURL  string
+}
+
+// NewPaymentReceiptJob creates a new payment receipt job handler
+func NewPaymentReceiptJob(db *gorm.DB, emailService *email.EmailService) *PaymentReceiptJob {
+	return &PaymentReceiptJob{
+		db:           db,
+		emailService: emailService,
+		frontendURL:  os.Getenv("FRONTEND_URL"),
+	}
+}
+
+// NewPaymentReceiptJobHandlers creates the payment receipt handlers for any queue
+func NewPaymentReceiptJobHandlers(db *gorm.DB, emailService *email.EmailService) map[queue.JobType]queue.JobHandler {
+	handler := NewPaymentReceiptJob(db, emailService)
+
+	return map[queue.JobType]queue.JobHandler{
+		payment.SendPaymentReceiptJobType: func(ctx context.Context, job queue.Job) (interface{}, error) {
+			var payload payment.SendPaymentReceiptPayload
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal payment receipt payload: %w", err)
+			}
+			return nil, handler.SendReceipt(ctx, payload)
+		},
+	}
+}
+
+// RegisterPaymentReceiptJobHandlers registers the payment receipt job handlers
+func RegisterPaymentReceiptJobHandlers(q queue.QueueInterface, db *gorm.DB, emailService *email.EmailService) {
+	for jobType, handler := range NewPaymentReceiptJobHandlers(db, emailService) {
+		q.RegisterHandler(jobType, handler)
+	}
+}
+
+// SendReceipt emails the receipt for a completed payment to its customer, unless the merchant
+// has turned receipts off or it was already sent
+func (j *PaymentReceiptJob) SendReceipt(_ context.Context, payload payment.SendPaymentReceiptPayload) error {
+	var p models.Payment
+	if err := j.db.First(&p, "id = ?", payload.PaymentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Payment %s for receipt not found, skipping", payload.PaymentID)
+			return nil
+		}
+		return fmt.Errorf("failed to find payment: %w", err)
+	}
+	if p.Status != models.PaymentStatusCompleted || p.ReceiptSentAt != nil || p.CustomerEmail == "" {
+		return nil
+	}
+
+	var merchant database.User
+	if err := j.db.Select(append(database.PublicNameColumns, "send_payment_receipts")).
+		First(&merchant, "id = ?", p.UserID).Error; err != nil {
+		return fmt.Errorf("failed to find merchant: %w", err)
+	}
+	if !merchant.SendPaymentReceipts {
+		return nil
+	}
+
+	if err := j.emailService.SendTemplate(p.CustomerEmail, email.TemplatePaymentReceipt, email.DefaultLocale, map[string]interface{}{
+		"Name":       p.CustomerName,
+		"Merchant":   merchant.PublicName(),
+		"Amount":     p.Amount,
+		"Currency":   string(p.Currency),
+		"Reference":  p.Reference,
+		"Date":       p.UpdatedAt.UTC().Format("2 Jan 2006, 15:04 MST"),
+		"ReceiptURL": fmt.Sprintf("%s/receipts/%s", j.frontendURL, url.PathEscape(p.Reference)),
+	}); err != nil {
+		return fmt.Errorf("failed to send receipt for payment %s: %w", p.Reference, err)
+	}
+
+	if err := j.db.Model(&models.Payment{}).
+		Where("id = ? AND receipt_sent_at IS NULL", p.ID).
+		Update("receipt_sent_at", time.Now()).Error; err != nil {
+		// The receipt went out, so retrying the job would send it again
+		log.Printf("Failed to record receipt sent for payment %s: %v", p.Reference, err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/revaspay/backend/internal/models"
@@ -40,20 +41,27 @@ func NewPaymentWebhookJob(db *gorm.DB, paymentSvc *payment.PaymentService, walle
 	}
 }
 
+// NewPaymentWebhookJobHandlers returns the payment webhook job handlers keyed by job type
+func NewPaymentWebhookJobHandlers(db *gorm.DB, paymentSvc *payment.PaymentService, walletSvc *wallet.WalletService) map[queue.JobType]queue.JobHandler {
+	handler := NewPaymentWebhookJob(db, paymentSvc, walletSvc)
+
+	return map[queue.JobType]queue.JobHandler{
+		queue.JobType(PaymentWebhookJobType): func(ctx context.Context, job queue.Job) (interface{}, error) {
+			// Convert queue.Job to *queue.Job for our handler
+			jobCopy := job // Make a copy to avoid modifying the original
+			if err := handler.Handle(ctx, &jobCopy); err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"status": "success"}, nil
+		},
+	}
+}
+
 // RegisterPaymentWebhookJobHandlers registers the payment webhook job handlers
 func RegisterPaymentWebhookJobHandlers(q queue.QueueInterface, db *gorm.DB, paymentSvc *payment.PaymentService, walletSvc *wallet.WalletService) {
-	handler := NewPaymentWebhookJob(db, paymentSvc, walletSvc)
-	// Convert the method to match the queue.JobHandler function signature
-	jobHandler := func(ctx context.Context, job queue.Job) (interface{}, error) {
-		// Convert queue.Job to *queue.Job for our handler
-		jobCopy := job // Make a copy to avoid modifying the original
-		err := handler.Handle(ctx, &jobCopy)
-		if err != nil {
-			return nil, err
-		}
-		return map[string]interface{}{"status": "success"}, nil
+	for jobType, handler := range NewPaymentWebhookJobHandlers(db, paymentSvc, walletSvc) {
+		q.RegisterHandler(jobType, handler)
 	}
-	q.RegisterHandler(queue.JobType(PaymentWebhookJobType), jobHandler)
 }
 
 // EnqueuePaymentWebhookJob enqueues a payment webhook job
@@ -71,7 +79,6 @@ func EnqueuePaymentWebhookJob(q queue.QueueInterface, webhookID uuid.UUID) error
 		ID:         uuid.New(),
 		Type:       queue.JobType(PaymentWebhookJobType),
 		Payload:    payloadBytes,
-		MaxRetries: 5, // Retry up to 5 times
 	}
 
 	return q.Enqueue(job)
@@ -101,13 +108,13 @@ func (j *PaymentWebhookJob) Handle(ctx context.Context, job *queue.Job) error {
 	var err error
 	switch webhook.Provider {
 	case models.PaymentProviderPaystack:
-		err = j.processPaystackWebhook(&webhook)
+		err = j.processPaystackWebhook(ctx, &webhook)
 	case models.PaymentProviderStripe:
-		err = j.processStripeWebhook(&webhook)
+		err = j.processStripeWebhook(ctx, &webhook)
 	case models.PaymentProviderPayPal:
-		err = j.processPayPalWebhook(&webhook)
+		err = j.processPayPalWebhook(ctx, &webhook)
 	case models.PaymentProviderCrypto:
-		err = j.processCryptoWebhook(&webhook)
+		err = j.processCryptoWebhook(ctx, &webhook)
 	default:
 		err = fmt.Errorf("unsupported payment provider: %s", webhook.Provider)
 	}
@@ -117,7 +124,10 @@ func (j *PaymentWebhookJob) Handle(ctx context.Context, job *queue.Job) error {
 	}
 
 	// Mark webhook as processed
-	if err := j.db.Model(&webhook).Update("processed", true).Error; err != nil {
+	if err := j.db.Model(&webhook).Updates(map[string]interface{}{
+		"processed":    true,
+		"processed_at": time.Now(),
+	}).Error; err != nil {
 		return fmt.Errorf("failed to mark webhook as processed: %w", err)
 	}
 
@@ -125,7 +135,7 @@ func (j *PaymentWebhookJob) Handle(ctx context.Context, job *queue.Job) error {
 }
 
 // processPaystackWebhook processes a Paystack webhook
-func (j *PaymentWebhookJob) processPaystackWebhook(webhook *models.PaymentWebhook) error {
+func (j *PaymentWebhookJob) processPaystackWebhook(ctx context.Context, webhook *models.PaymentWebhook) error {
 	// RawData is already a map[string]interface{}, no need to unmarshal
 	data := webhook.RawData
 
@@ -138,7 +148,7 @@ func (j *PaymentWebhookJob) processPaystackWebhook(webhook *models.PaymentWebhoo
 	// Process based on event
 	switch event {
 	case "charge.success":
-		return j.processPaystackChargeSuccess(webhook, data)
+		return j.processPaystackChargeSuccess(ctx, webhook, data)
 	default:
 		log.Printf("Unhandled Paystack event: %s", event)
 		return nil
@@ -146,7 +156,7 @@ func (j *PaymentWebhookJob) processPaystackWebhook(webhook *models.PaymentWebhoo
 }
 
 // processPaystackChargeSuccess processes a successful Paystack charge
-func (j *PaymentWebhookJob) processPaystackChargeSuccess(_ *models.PaymentWebhook, data map[string]interface{}) error {
+func (j *PaymentWebhookJob) processPaystackChargeSuccess(ctx context.Context, _ *models.PaymentWebhook, data map[string]interface{}) error {
 	// Extract payment reference
 	dataObj, ok := data["data"].(map[string]interface{})
 	if !ok {
@@ -159,40 +169,23 @@ func (j *PaymentWebhookJob) processPaystackChargeSuccess(_ *models.PaymentWebhoo
 	}
 
 	// Verify payment
-	payment, err := j.paymentSvc.VerifyPayment(reference)
+	payment, err := j.paymentSvc.VerifyPayment(ctx, reference)
 	if err != nil {
 		return fmt.Errorf("failed to verify payment: %w", err)
 	}
 
-	// Skip if payment is not completed
+	// Verifying a completed payment credits the wallet, once however often the event is replayed
 	if payment.Status != models.PaymentStatusCompleted {
 		log.Printf("Payment %s is not completed, status: %s", payment.ID, payment.Status)
 		return nil
 	}
 
-	// Credit user's wallet
-	_, err = j.walletSvc.Credit(
-		payment.UserID,
-		payment.Amount-payment.Fee, // Credit amount minus fee
-		string(payment.Currency),
-		fmt.Sprintf("Payment: %s", payment.Reference),
-		"Payment from Paystack", // Add description parameter
-		map[string]interface{}{
-			"payment_id":      payment.ID.String(),
-			"payment_method":  payment.PaymentMethod,
-			"payment_provider": string(payment.Provider),
-		},
-	)
-	if err != nil {
-		return fmt.Errorf("failed to credit wallet: %w", err)
-	}
-
-	log.Printf("Successfully processed payment %s and credited wallet for user %s", payment.ID, payment.UserID)
+	log.Printf("Successfully processed payment %s for user %s", payment.ID, payment.UserID)
 	return nil
 }
 
 // processStripeWebhook processes a Stripe webhook
-func (j *PaymentWebhookJob) processStripeWebhook(webhook *models.PaymentWebhook) error {
+func (j *PaymentWebhookJob) processStripeWebhook(ctx context.Context, webhook *models.PaymentWebhook) error {
 	// RawData is already a map[string]interface{}, no need to unmarshal
 	data := webhook.RawData
 
@@ -205,7 +198,7 @@ func (j *PaymentWebhookJob) processStripeWebhook(webhook *models.PaymentWebhook)
 	// Process based on event
 	switch event {
 	case "payment_intent.succeeded":
-		return j.processStripePaymentIntentSucceeded(webhook, data)
+		return j.processStripePaymentIntentSucceeded(ctx, webhook, data)
 	default:
 		log.Printf("Unhandled Stripe event: %s", event)
 		return nil
@@ -213,7 +206,7 @@ func (j *PaymentWebhookJob) processStripeWebhook(webhook *models.PaymentWebhook)
 }
 
 // processStripePaymentIntentSucceeded processes a successful Stripe payment intent
-func (j *PaymentWebhookJob) processStripePaymentIntentSucceeded(_ *models.PaymentWebhook, data map[string]interface{}) error {
+func (j *PaymentWebhookJob) processStripePaymentIntentSucceeded(ctx context.Context, _ *models.PaymentWebhook, data map[string]interface{}) error {
 	// Extract payment reference
 	dataObj, ok := data["data"].(map[string]interface{})
 	if !ok {
@@ -231,40 +224,23 @@ func (j *PaymentWebhookJob) processStripePaymentIntentSucceeded(_ *models.Paymen
 	}
 
 	// Verify payment
-	payment, err := j.paymentSvc.VerifyPayment(reference)
+	payment, err := j.paymentSvc.VerifyPayment(ctx, reference)
 	if err != nil {
 		return fmt.Errorf("failed to verify payment: %w", err)
 	}
 
-	// Skip if payment is not completed
+	// Verifying a completed payment credits the wallet, once however often the event is replayed
 	if payment.Status != models.PaymentStatusCompleted {
 		log.Printf("Payment %s is not completed, status: %s", payment.ID, payment.Status)
 		return nil
 	}
 
-	// Credit user's wallet
-	_, err = j.walletSvc.Credit(
-		payment.UserID,
-		payment.Amount-payment.ProviderFee, // Credit amount minus provider fee
-		string(payment.Currency),
-		fmt.Sprintf("Payment: %s", payment.Reference),
-		"Payment from Stripe", // Add description parameter
-		map[string]interface{}{
-			"payment_id":      payment.ID.String(),
-			"payment_method":  payment.PaymentMethod,
-			"payment_provider": string(payment.Provider),
-		},
-	)
-	if err != nil {
-		return fmt.Errorf("failed to credit wallet: %w", err)
-	}
-
-	log.Printf("Successfully processed payment %s and credited wallet for user %s", payment.ID, payment.UserID)
+	log.Printf("Successfully processed payment %s for user %s", payment.ID, payment.UserID)
 	return nil
 }
 
 // processPayPalWebhook processes a PayPal webhook
-func (j *PaymentWebhookJob) processPayPalWebhook(webhook *models.PaymentWebhook) error {
+func (j *PaymentWebhookJob) processPayPalWebhook(ctx context.Context, webhook *models.PaymentWebhook) error {
 	// RawData is already a map[string]interface{}, no need to unmarshal
 	data := webhook.RawData
 
@@ -277,7 +253,7 @@ func (j *PaymentWebhookJob) processPayPalWebhook(webhook *models.PaymentWebhook)
 	// Process based on event
 	switch event {
 	case "PAYMENT.CAPTURE.COMPLETED":
-		return j.processPayPalPaymentCaptureCompleted(webhook, data)
+		return j.processPayPalPaymentCaptureCompleted(ctx, webhook, data)
 	default:
 		log.Printf("Unhandled PayPal event: %s", event)
 		return nil
@@ -285,7 +261,7 @@ func (j *PaymentWebhookJob) processPayPalWebhook(webhook *models.PaymentWebhook)
 }
 
 // processPayPalPaymentCaptureCompleted processes a completed PayPal payment capture
-func (j *PaymentWebhookJob) processPayPalPaymentCaptureCompleted(_ *models.PaymentWebhook, data map[string]interface{}) error {
+func (j *PaymentWebhookJob) processPayPalPaymentCaptureCompleted(ctx context.Context, _ *models.PaymentWebhook, data map[string]interface{}) error {
 	// Extract payment reference
 	resource, ok := data["resource"].(map[string]interface{})
 	if !ok {
@@ -298,40 +274,23 @@ func (j *PaymentWebhookJob) processPayPalPaymentCaptureCompleted(_ *models.Payme
 	}
 
 	// Verify payment
-	payment, err := j.paymentSvc.VerifyPayment(reference)
+	payment, err := j.paymentSvc.VerifyPayment(ctx, reference)
 	if err != nil {
 		return fmt.Errorf("failed to verify payment: %w", err)
 	}
 
-	// Skip if payment is not completed
+	// Verifying a completed payment credits the wallet, once however often the event is replayed
 	if payment.Status != models.PaymentStatusCompleted {
 		log.Printf("Payment %s is not completed, status: %s", payment.ID, payment.Status)
 		return nil
 	}
 
-	// Credit user's wallet
-	_, err = j.walletSvc.Credit(
-		payment.UserID,
-		payment.Amount-payment.ProviderFee, // Credit amount minus provider fee
-		string(payment.Currency),
-		fmt.Sprintf("Payment: %s", payment.Reference),
-		"Payment from PayPal", // Add description parameter
-		map[string]interface{}{
-			"payment_id":      payment.ID.String(),
-			"payment_method":  payment.PaymentMethod,
-			"payment_provider": string(payment.Provider),
-		},
-	)
-	if err != nil {
-		return fmt.Errorf("failed to credit wallet: %w", err)
-	}
-
-	log.Printf("Successfully processed payment %s and credited wallet for user %s", payment.ID, payment.UserID)
+	log.Printf("Successfully processed payment %s for user %s", payment.ID, payment.UserID)
 	return nil
 }
 
 // processCryptoWebhook processes a crypto webhook
-func (j *PaymentWebhookJob) processCryptoWebhook(webhook *models.PaymentWebhook) error {
+func (j *PaymentWebhookJob) processCryptoWebhook(ctx context.Context, webhook *models.PaymentWebhook) error {
 	// RawData is already a map[string]interface{}, no need to unmarshal
 	data := webhook.RawData
 
@@ -373,40 +332,19 @@ func (j *PaymentWebhookJob) processCryptoWebhook(webhook *models.PaymentWebhook)
 		requiredConfirmations = 6
 	}
 
-	// Check if payment is confirmed
-	if cryptoPayment.Confirmations >= requiredConfirmations {
-		// Mark payment as completed
-		payment.Status = models.PaymentStatusCompleted
-
-		// Credit user's wallet
-		_, err := j.walletSvc.Credit(
-			payment.UserID,
-			payment.Amount, // No provider fee for crypto payments
-			string(payment.Currency),
-			fmt.Sprintf("Crypto Payment: %s", cryptoPayment.TxHash),
-			"Cryptocurrency payment", // Add description parameter
-			map[string]interface{}{
-				"payment_id":       payment.ID.String(),
-				"payment_method":   "crypto",
-				"crypto_currency":  cryptoPayment.Currency,
-				"transaction_hash": cryptoPayment.TxHash,
-			},
-		)
-		if err != nil {
-			return fmt.Errorf("failed to credit wallet: %w", err)
+	// Below the required confirmations only the count is recorded
+	if cryptoPayment.Confirmations < requiredConfirmations {
+		if err := j.db.Save(&cryptoPayment).Error; err != nil {
+			return fmt.Errorf("failed to save crypto payment: %w", err)
 		}
-
-		log.Printf("Successfully processed crypto payment %s and credited wallet for user %s", payment.ID, payment.UserID)
-	}
-
-	// Save changes
-	if err := j.db.Save(&cryptoPayment).Error; err != nil {
-		return fmt.Errorf("failed to save crypto payment: %w", err)
+		return nil
 	}
 
-	if err := j.db.Save(&payment).Error; err != nil {
-		return fmt.Errorf("failed to save payment: %w", err)
+	// Completing the payment credits the wallet, once however often the event is replayed
+	if err := j.paymentSvc.UpdateCryptoPayment(cryptoPayment.ID, cryptoPayment.TxHash, cryptoPayment.Confirmations, models.PaymentStatusCompleted); err != nil {
+		return fmt.Errorf("failed to complete crypto payment: %w", err)
 	}
 
+	log.Printf("Successfully processed crypto payment %s for user %s", payment.ID, payment.UserID)
 	return nil
 }
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/dataexport"
+)
+
+// jobPriorities are the default priorities of the background jobs. Jobs that move a
+// customer's money go first; reconciliation can wait behind everything else. Job types not
+// listed here are normal priority.
+var jobPriorities = map[queue.JobType]queue.Priority{
+	PaymentWebhookJobType:            queue.PriorityHigh,
+	ProcessRecurringPaymentJobType:   queue.PriorityHigh,
+	VirtualAccountTransactionJobType: queue.PriorityHigh,
+	WithdrawalProcessJobType:         queue.PriorityHigh,
+	WithdrawalStatusCheckJobType:     queue.PriorityHigh,
+	ProcessAutoWithdrawJobType:       queue.PriorityHigh,
+
+	VirtualAccountReconciliationJobType: queue.PriorityLow,
+	WalletReconciliationJobType:         queue.PriorityLow,
+	ProviderReconciliationJobType:       queue.PriorityLow,
+	CleanupJobType:                      queue.PriorityLow,
+	ExpirePaymentsJobType:               queue.PriorityLow,
+	PollKYCVerificationsJobType:         queue.PriorityLow,
+	dataexport.BuildDataExportJobType:   queue.PriorityLow,
+}
+
+// ConfigureJobPriorities sets the default priority of each background job. Call it before
+// any jobs are enqueued.
+func ConfigureJobPriorities() {
+	for jobType, priority := range jobPriorities {
+		queue.SetDefaultPriority(jobType, priority)
+	}
+}
@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/reconciliation"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// ProviderReconciliationJobType is the job type for reconciling provider records against ours
+	ProviderReconciliationJobType queue.JobType = "reconcile_providers"
+
+	// providerReconciliationHour is the UTC hour the previous day is reconciled at, giving
+	// providers time to settle late transactions
+	providerReconciliationHour = 2
+)
+
+// ProviderReconciliationJob reconciles each provider's records for the previous day against
+// our payments and withdrawals
+type ProviderReconciliationJob struct {
+	queue             queue.QueueInterface
+	reconciliationSvc *reconciliation.Service
+	auditLogger       *utils.AuditLogger
+}
+
+// NewProviderReconciliationJob creates a new provider reconciliation job handler. Reconciling
+// needs reconciliationSvc; scheduling only needs the queue.
+func NewProviderReconciliationJob(db *gorm.DB, q queue.QueueInterface, reconciliationSvc *reconciliation.Service) *ProviderReconciliationJob {
+	return &ProviderReconciliationJob{
+		queue:             q,
+		reconciliationSvc: reconciliationSvc,
+		auditLogger:       utils.NewAuditLogger(db),
+	}
+}
+
+// RegisterProviderReconciliationJobHandlers registers the provider reconciliation job handler
+func RegisterProviderReconciliationJobHandlers(q queue.QueueInterface, db *gorm.DB, reconciliationSvc *reconciliation.Service) {
+	handler := NewProviderReconciliationJob(db, q, reconciliationSvc)
+
+	q.RegisterHandler(ProviderReconciliationJobType, func(ctx context.Context, job queue.Job) (interface{}, error) {
+		return nil, handler.ReconcileProviders(ctx, job)
+	})
+}
+
+// ScheduleProviderReconciliation schedules the next daily reconciliation run
+func (j *ProviderReconciliationJob) ScheduleProviderReconciliation() error {
+	return j.scheduleRun(nextProviderReconciliationRun(time.Now()))
+}
+
+// nextProviderReconciliationRun returns the first daily run time after now
+func nextProviderReconciliationRun(now time.Time) time.Time {
+	now = now.UTC()
+	runAt := time.Date(now.Year(), now.Month(), now.Day(), providerReconciliationHour, 0, 0, 0, time.UTC)
+	if !runAt.After(now) {
+		runAt = runAt.AddDate(0, 0, 1)
+	}
+	return runAt
+}
+
+// scheduleRun enqueues a reconciliation run at the given time
+func (j *ProviderReconciliationJob) scheduleRun(runAt time.Time) error {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"scheduled_at": runAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal provider reconciliation payload: %w", err)
+	}
+
+	job := &queue.Job{
+		ID:        uuid.New(),
+		Type:      ProviderReconciliationJobType,
+		Payload:   payloadBytes,
+		NextRetry: &runAt,
+	}
+
+	return j.queue.Enqueue(job)
+}
+
+// ReconcileProviders reconciles every provider for the previous UTC day, records an audit
+// entry for each report that needs review and schedules the next run. A provider whose
+// records couldn't be fetched gets a failed report, which is alerted on like discrepancies.
+func (j *ProviderReconciliationJob) ReconcileProviders(ctx context.Context, _ queue.Job) error {
+	day := time.Now().UTC().AddDate(0, 0, -1)
+	reports, err := j.reconciliationSvc.ReconcileDay(ctx, day)
+	if err != nil {
+		log.Printf("Error reconciling providers for %s: %v", day.Format("2006-01-02"), err)
+	}
+
+	for _, report := range reports {
+		if report.Status == models.ReconciliationReportBalanced {
+			continue
+		}
+
+		var description string
+		if report.Status == models.ReconciliationReportFailed {
+			description = fmt.Sprintf("Reconciliation of %s for %s failed", report.Provider, report.PeriodStart.Format("2006-01-02"))
+		} else {
+			description = fmt.Sprintf("Reconciliation of %s for %s found %d discrepancies",
+				report.Provider, report.PeriodStart.Format("2006-01-02"), report.DiscrepancyCount)
+		}
+		log.Printf("ALERT: %s (report %s)", description, report.ID)
+
+		if err := j.auditLogger.LogEvent(ctx, utils.AuditEventReconciliationIssue, utils.AuditSeverityCritical, description, nil, nil, "", "", false, map[string]interface{}{
+			"report_id":         report.ID.String(),
+			"provider":          string(report.Provider),
+			"period_start":      report.PeriodStart,
+			"status":            string(report.Status),
+			"discrepancy_count": report.DiscrepancyCount,
+			"error":             report.Error,
+		}); err != nil {
+			log.Printf("Failed to write reconciliation audit entry for report %s: %v", report.ID, err)
+		}
+	}
+
+	log.Printf("Reconciled %d providers for %s", len(reports), day.Format("2006-01-02"))
+
+	return j.scheduleRun(nextProviderReconciliationRun(time.Now()))
+}
@@ -8,10 +8,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/revaspay/backend/internal/models"
 	"github.com/revaspay/backend/internal/queue"
-	"github.com/revaspay/backend/internal/services/payment"
-	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/services/subscription"
 	"gorm.io/gorm"
 )
 
@@ -33,27 +31,25 @@ type ProcessRecurringPaymentPayload struct {
 	SubscriptionID uuid.UUID `json:"subscription_id"`
 }
 
-// RecurringPaymentJob handles recurring payments for subscriptions
+// RecurringPaymentJob finds subscriptions that are due and renews them through the subscription engine
 type RecurringPaymentJob struct {
-	db            *gorm.DB
-	queue         queue.QueueInterface
-	paymentSvc    *payment.PaymentService
-	walletSvc     *wallet.WalletService
+	db              *gorm.DB
+	queue           queue.QueueInterface
+	subscriptionSvc *subscription.SubscriptionService
 }
 
 // NewRecurringPaymentJob creates a new recurring payment job handler
-func NewRecurringPaymentJob(db *gorm.DB, q queue.QueueInterface, paymentSvc *payment.PaymentService, walletSvc *wallet.WalletService) *RecurringPaymentJob {
+func NewRecurringPaymentJob(db *gorm.DB, q queue.QueueInterface, subscriptionSvc *subscription.SubscriptionService) *RecurringPaymentJob {
 	return &RecurringPaymentJob{
-		db:         db,
-		queue:      q,
-		paymentSvc: paymentSvc,
-		walletSvc:  walletSvc,
+		db:              db,
+		queue:           q,
+		subscriptionSvc: subscriptionSvc,
 	}
 }
 
-// RegisterJobHandlers registers the recurring payment job handlers
-func RegisterRecurringPaymentJobHandlers(q queue.QueueInterface, db *gorm.DB, paymentSvc *payment.PaymentService, walletSvc *wallet.WalletService) {
-	handler := NewRecurringPaymentJob(db, q, paymentSvc, walletSvc)
+// RegisterRecurringPaymentJobHandlers registers the recurring payment job handlers
+func RegisterRecurringPaymentJobHandlers(q queue.QueueInterface, db *gorm.DB, subscriptionSvc *subscription.SubscriptionService) {
+	handler := NewRecurringPaymentJob(db, q, subscriptionSvc)
 	
 	// Wrap the handler methods to match queue.JobHandler signature
 	checkHandler := func(ctx context.Context, job queue.Job) (interface{}, error) {
@@ -90,7 +86,6 @@ func (j *RecurringPaymentJob) ScheduleRecurringPaymentCheck() error {
 	job := &queue.Job{
 		Type:       RecurringPaymentCheckJobType,
 		Payload:    payloadBytes,
-		MaxRetries: 3,
 	}
 
 	return j.queue.Enqueue(job)
@@ -103,23 +98,23 @@ func (j *RecurringPaymentJob) CheckRecurringPayments(ctx context.Context, job qu
 	// Get current time
 	now := time.Now()
 
-	// Find active subscriptions due for billing
-	var subscriptions []models.Subscription
-	if err := j.db.Where("status = ? AND next_payment_date <= ?", "active", now).Find(&subscriptions).Error; err != nil {
+	// Find active and past-due subscriptions with a renewal or dunning retry due
+	subscriptionIDs, err := j.subscriptionSvc.DueSubscriptions(now)
+	if err != nil {
 		return fmt.Errorf("failed to find subscriptions: %w", err)
 	}
 
-	log.Printf("Found %d subscriptions due for billing", len(subscriptions))
+	log.Printf("Found %d subscriptions due for billing", len(subscriptionIDs))
 
 	// Process each subscription
-	for _, subscription := range subscriptions {
+	for _, subscriptionID := range subscriptionIDs {
 		// Enqueue a job to process this subscription payment
-		if err := j.enqueueProcessRecurringPayment(subscription.ID); err != nil {
-			log.Printf("Failed to enqueue recurring payment for subscription %s: %v", subscription.ID, err)
+		if err := j.enqueueProcessRecurringPayment(subscriptionID); err != nil {
+			log.Printf("Failed to enqueue recurring payment for subscription %s: %v", subscriptionID, err)
 			continue
 		}
 
-		log.Printf("Enqueued recurring payment for subscription %s", subscription.ID)
+		log.Printf("Enqueued recurring payment for subscription %s", subscriptionID)
 	}
 
 	// Schedule the next check in 1 hour
@@ -136,7 +131,6 @@ func (j *RecurringPaymentJob) CheckRecurringPayments(ctx context.Context, job qu
 nextJob := &queue.Job{
 		Type:       RecurringPaymentCheckJobType,
 		Payload:    nextPayloadBytes,
-		MaxRetries: 3,
 		NextRetry:  &nextRunTime,
 	}
 
@@ -161,13 +155,12 @@ func (j *RecurringPaymentJob) enqueueProcessRecurringPayment(subscriptionID uuid
 	job := &queue.Job{
 		Type:       ProcessRecurringPaymentJobType,
 		Payload:    payloadBytes,
-		MaxRetries: 3,
 	}
 
 	return j.queue.Enqueue(job)
 }
 
-// ProcessRecurringPayment processes a recurring payment for a subscription
+// ProcessRecurringPayment charges one subscription's renewal through the subscription engine
 func (j *RecurringPaymentJob) ProcessRecurringPayment(ctx context.Context, job queue.Job) error {
 	// Parse payload
 	var payload ProcessRecurringPaymentPayload
@@ -175,155 +168,12 @@ func (j *RecurringPaymentJob) ProcessRecurringPayment(ctx context.Context, job q
 		return fmt.Errorf("failed to unmarshal process recurring payment payload: %w", err)
 	}
 
-	// Get subscription
-	var subscription models.Subscription
-	if err := j.db.First(&subscription, "id = ?", payload.SubscriptionID).Error; err != nil {
-		return fmt.Errorf("failed to get subscription: %w", err)
-	}
-
-	// Get subscription plan
-	var plan models.SubscriptionPlan
-	if err := j.db.First(&plan, "id = ?", subscription.PlanID).Error; err != nil {
-		return fmt.Errorf("failed to get subscription plan: %w", err)
-	}
-
-	// Get subscriber
-	var subscriber models.User
-	if err := j.db.First(&subscriber, "id = ?", subscription.SubscriberID).Error; err != nil {
-		return fmt.Errorf("failed to get subscriber: %w", err)
-	}
-
-	// Get creator
-	var creator models.User
-	if err := j.db.First(&creator, "id = ?", plan.UserID).Error; err != nil {
-		return fmt.Errorf("failed to get creator: %w", err)
-	}
-
-	// Start a transaction
-	tx := j.db.Begin()
-	if tx.Error != nil {
-		return fmt.Errorf("failed to begin transaction: %w", tx.Error)
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Create payment record
-	metadataMap := map[string]interface{}{
-		"subscription_id": subscription.ID.String(),
-		"plan_id":         plan.ID.String(),
-		"subscriber_id":   subscriber.ID.String(),
-		"recurring":       true,
-	}
-	
-	// No need to marshal to JSON bytes anymore
-	
-	payment := models.Payment{
-		ID:            uuid.New(),
-		UserID:        plan.UserID,
-		Amount:        plan.Amount,
-		Currency:      plan.Currency,
-		Status:        models.PaymentStatusPending,
-		PaymentMethod: "subscription",
-		CustomerEmail: subscriber.Email,
-		CustomerName:  fmt.Sprintf("%s %s", subscriber.FirstName, subscriber.LastName),
-		Reference:     uuid.New().String(),
-		Metadata:      models.JSON(metadataMap),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-
-	if err := tx.Create(&payment).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to create payment record: %w", err)
-	}
-
-	// Try to charge the subscriber's saved payment method
-	// This is a placeholder - in a real implementation, you would use the payment provider SDK
-	// to charge the customer's saved payment method
-	
-	// For now, we'll simulate a successful payment
-	payment.Status = models.PaymentStatusCompleted
-	payment.UpdatedAt = time.Now()
-
-	if err := tx.Save(&payment).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update payment status: %w", err)
-	}
-
-	// Credit the creator's wallet
-	creditMetadataMap := map[string]interface{}{
-		"payment_id":      payment.ID.String(),
-		"subscription_id": subscription.ID.String(),
-		"plan_id":         plan.ID.String(),
-		"subscriber_id":   subscriber.ID.String(),
-	}
-	
-	_, creditErr := j.walletSvc.Credit(
-		plan.UserID,
-		plan.Amount * 0.95, // Apply platform fee (5%)
-		string(plan.Currency),
-		fmt.Sprintf("Subscription payment from %s", subscriber.Email),
-		"Subscription payment", // Add description parameter
-		creditMetadataMap,
-	)
-	if creditErr != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to credit creator wallet: %w", creditErr)
-	}
-
-	// Update subscription next payment date
-	nextPaymentDate := subscription.NextPaymentDate
-	if nextPaymentDate == nil {
-		now := time.Now()
-		nextPaymentDate = &now
-	}
-	
-	switch plan.Interval {
-	case "monthly":
-		*nextPaymentDate = nextPaymentDate.AddDate(0, 1, 0)
-	case "quarterly":
-		*nextPaymentDate = nextPaymentDate.AddDate(0, 3, 0)
-	case "yearly":
-		*nextPaymentDate = nextPaymentDate.AddDate(1, 0, 0)
-	}
-
-	now := time.Now()
-	subscription.LastPaymentDate = &now
-	subscription.NextPaymentDate = nextPaymentDate
-	subscription.UpdatedAt = time.Now()
-
-	if err := tx.Save(&subscription).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update subscription: %w", err)
-	}
-
-	// Create subscription payment record
-	subscriptionPayment := models.SubscriptionPayment{
-		Base: models.Base{
-			ID:        uuid.New(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
-		SubscriptionID: subscription.ID,
-		PaymentID:      payment.ID,
-		PeriodStart:    subscription.CurrentPeriodStart,
-		PeriodEnd:      subscription.CurrentPeriodEnd,
-		Status:         models.PaymentStatusCompleted,
-	}
-
-	if err := tx.Create(&subscriptionPayment).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to create subscription payment record: %w", err)
-	}
-
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	// Renewals are idempotent per billing period, so a retried job doesn't charge twice
+	sub, err := j.subscriptionSvc.ChargeSubscription(ctx, payload.SubscriptionID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to renew subscription %s: %w", payload.SubscriptionID, err)
 	}
 
-	log.Printf("Successfully processed recurring payment for subscription %s", subscription.ID)
+	log.Printf("Processed recurring payment for subscription %s (status %s, %d failed attempts)", sub.ID, sub.Status, sub.FailedPaymentCount)
 	return nil
 }
@@ -67,7 +67,6 @@ func (j *ReferralRewardJob) EnqueueReferralRewardJob(referralID uuid.UUID, event
 	job := &queue.Job{
 		Type:       ReferralRewardJobType,
 		Payload:    payloadBytes,
-		MaxRetries: 3,
 	}
 
 	return j.queue.Enqueue(job)
@@ -151,6 +150,7 @@ func (j *ReferralRewardJob) ProcessReferralReward(ctx context.Context, job *queu
 		referral.ReferrerID,
 		rewardConfig.Amount,
 		rewardConfig.Currency,
+		models.LedgerCategoryReward,
 		fmt.Sprintf("Referral reward for %s by %s", payload.EventType, referredUser.Email),
 		"Referral reward", // Add description parameter
 		map[string]interface{}{
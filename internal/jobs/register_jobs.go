@@ -4,7 +4,12 @@ import (
 	"github.com/revaspay/backend/internal/queue"
 	"github.com/revaspay/backend/internal/services/kyc"
 	"github.com/revaspay/backend/internal/services/payment"
+	"github.com/revaspay/backend/internal/services/reconciliation"
+	"github.com/revaspay/backend/internal/services/screening"
+	"github.com/revaspay/backend/internal/services/subscription"
+	"github.com/revaspay/backend/internal/services/virtualaccount"
 	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/services/webhook"
 	"gorm.io/gorm"
 )
 
@@ -14,29 +19,45 @@ func RegisterAllJobHandlers(
 	db *gorm.DB,
 	paymentSvc *payment.PaymentService,
 	walletSvc *wallet.WalletService,
-	kycSvc *kyc.KYCService,
+	kycProviders []kyc.Provider,
+	webhookSvc *webhook.WebhookService,
+	screeningSvc *screening.Service,
+	subscriptionSvc *subscription.SubscriptionService,
+	virtualAccountSvc *virtualaccount.VirtualAccountService,
+	reconciliationSvc *reconciliation.Service,
 ) {
 	// Register payment webhook job handlers
 	RegisterPaymentWebhookJobHandlers(q, db, paymentSvc, walletSvc)
 
 	// Register recurring payment job handlers
-	RegisterRecurringPaymentJobHandlers(q, db, paymentSvc, walletSvc)
+	RegisterRecurringPaymentJobHandlers(q, db, subscriptionSvc)
 
 	// Register withdrawal job handlers
 	withdrawalJob := NewWithdrawalJob(db, q, paymentSvc, walletSvc)
+	withdrawalJob.SetWebhookService(webhookSvc)
+	withdrawalJob.SetScreeningService(screeningSvc)
 	if qAdapter, ok := q.(*queue.QueueAdapter); ok {
 		withdrawalJob.RegisterHandlers(qAdapter)
 	}
 
 	// Register KYC verification job handlers
-	RegisterKYCVerificationJobHandlers(q, db, kycSvc)
+	RegisterKYCVerificationJobHandlers(q, db, kycProviders...)
 
 	// Register virtual account job handlers
-	RegisterVirtualAccountJobHandlers(q, db, paymentSvc, walletSvc)
+	RegisterVirtualAccountJobHandlers(q, db, paymentSvc, walletSvc, virtualAccountSvc)
 
 	// Register referral reward job handlers
 	RegisterReferralRewardJobHandlers(q, db, walletSvc)
 
+	// Register merchant webhook delivery handlers
+	RegisterMerchantWebhookJobHandlers(q, webhookSvc)
+
+	// Register wallet ledger reconciliation handler
+	RegisterWalletReconciliationJobHandlers(q, db, walletSvc)
+
+	// Register provider reconciliation handler
+	RegisterProviderReconciliationJobHandlers(q, db, reconciliationSvc)
+
 	// Auto-withdraw job is registered in its constructor
 	NewAutoWithdrawJob(db, q)
 }
@@ -47,9 +68,10 @@ func ScheduleRecurringJobs(
 	db *gorm.DB,
 	paymentSvc *payment.PaymentService,
 	walletSvc *wallet.WalletService,
+	webhookSvc *webhook.WebhookService,
 ) error {
-	// Schedule recurring payment check
-	recurringPaymentJob := NewRecurringPaymentJob(db, q, paymentSvc, walletSvc)
+	// Schedule recurring payment check; scheduling only needs the queue
+	recurringPaymentJob := NewRecurringPaymentJob(db, q, nil)
 	if err := recurringPaymentJob.ScheduleRecurringPaymentCheck(); err != nil {
 		return err
 	}
@@ -61,10 +83,31 @@ func ScheduleRecurringJobs(
 	}
 
 	// Schedule virtual account reconciliation
-	virtualAccountJob := NewVirtualAccountJob(db, q, paymentSvc, walletSvc)
+	virtualAccountJob := NewVirtualAccountJob(db, q, paymentSvc, walletSvc, nil)
 	if err := virtualAccountJob.ScheduleVirtualAccountReconciliation(); err != nil {
 		return err
 	}
 
+	// Schedule merchant webhook retry sweep
+	merchantWebhookJob := NewMerchantWebhookJob(q, webhookSvc)
+	if err := merchantWebhookJob.ScheduleWebhookRetrySweep(); err != nil {
+		return err
+	}
+	if err := merchantWebhookJob.ScheduleBalanceChangeSweep(); err != nil {
+		return err
+	}
+
+	// Schedule wallet ledger reconciliation
+	walletReconciliationJob := NewWalletReconciliationJob(db, q, walletSvc)
+	if err := walletReconciliationJob.ScheduleWalletReconciliation(); err != nil {
+		return err
+	}
+
+	// Schedule daily provider reconciliation; scheduling only needs the queue
+	providerReconciliationJob := NewProviderReconciliationJob(db, q, nil)
+	if err := providerReconciliationJob.ScheduleProviderReconciliation(); err != nil {
+		return err
+	}
+
 	return nil
 }
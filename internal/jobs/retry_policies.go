@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/dataexport"
+	"github.com/revaspay/backend/internal/services/payment"
+	"github.com/revaspay/backend/internal/services/webhook"
+)
+
+// retryPolicies are the retry policies of the background jobs, before config overrides. Job
+// types not listed here use queue.DefaultRetryPolicy.
+var retryPolicies = map[queue.JobType]queue.RetryPolicy{
+	// Providers resend webhooks they don't get a response to, but a payment must not be
+	// left pending because of a brief outage on our side
+	PaymentWebhookJobType:        {MaxRetries: 5, BaseBackoff: 5 * time.Second, MaxBackoff: time.Hour, DeadLetter: true},
+	WithdrawalProcessJobType:     queue.DefaultRetryPolicy,
+	WithdrawalStatusCheckJobType: {MaxRetries: 5, BaseBackoff: 5 * time.Second, MaxBackoff: time.Hour, DeadLetter: true},
+	AutoWithdrawCheckJobType:     queue.DefaultRetryPolicy,
+	ProcessAutoWithdrawJobType:   queue.DefaultRetryPolicy,
+
+	RecurringPaymentCheckJobType:        queue.DefaultRetryPolicy,
+	ProcessRecurringPaymentJobType:      queue.DefaultRetryPolicy,
+	VirtualAccountTransactionJobType:    queue.DefaultRetryPolicy,
+	VirtualAccountReconciliationJobType: queue.DefaultRetryPolicy,
+	KYCVerificationJobType:              queue.DefaultRetryPolicy,
+	ReferralRewardJobType:               queue.DefaultRetryPolicy,
+	WalletReconciliationJobType:         queue.DefaultRetryPolicy,
+	ProviderReconciliationJobType:       queue.DefaultRetryPolicy,
+	payment.SendPaymentReceiptJobType:   queue.DefaultRetryPolicy,
+	SendLoginNotificationJobType:        queue.DefaultRetryPolicy,
+	CleanupJobType:                      queue.DefaultRetryPolicy,
+	ExpirePaymentsJobType:               queue.DefaultRetryPolicy,
+	PollKYCVerificationsJobType:         queue.DefaultRetryPolicy,
+	dataexport.BuildDataExportJobType:   queue.DefaultRetryPolicy,
+
+	// Deliveries are retried by the webhook service on its own schedule, from next_retry_at
+	webhook.DeliverWebhookJobType:         {MaxRetries: 0, BaseBackoff: 5 * time.Second, MaxBackoff: time.Hour},
+	webhook.RetryWebhookDeliveriesJobType: queue.DefaultRetryPolicy,
+	webhook.DispatchBalanceChangesJobType: queue.DefaultRetryPolicy,
+}
+
+// ConfigureRetryPolicies sets the retry policy of each background job, applying any overrides
+// from config by job type. Call it before the queue starts processing jobs.
+func ConfigureRetryPolicies(overrides map[string]config.JobRetryPolicy) {
+	for jobType, policy := range retryPolicies {
+		queue.SetRetryPolicy(jobType, policy)
+	}
+	for jobType, policy := range overrides {
+		log.Printf("Using retry policy for %s jobs: %d retries, %v to %v backoff", jobType, policy.MaxRetries, policy.BaseBackoff, policy.MaxBackoff)
+		queue.SetRetryPolicy(queue.JobType(jobType), queue.RetryPolicy(policy))
+	}
+}
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/subscription"
+)
+
+const (
+	// SubscriptionBillingJobType is the job type for charging subscriptions that are due
+	SubscriptionBillingJobType = "charge_due_subscriptions"
+
+	// SubscriptionBillingInterval is how often due subscriptions are charged
+	SubscriptionBillingInterval = time.Hour
+)
+
+// SubscriptionBillingPayload represents the payload for a subscription billing job
+type SubscriptionBillingPayload struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
+
+// NewSubscriptionBillingJobHandlers creates the subscription billing handlers for any queue
+func NewSubscriptionBillingJobHandlers(subscriptionSvc *subscription.SubscriptionService) map[queue.JobType]queue.JobHandler {
+	return map[queue.JobType]queue.JobHandler{
+		queue.JobType(SubscriptionBillingJobType): func(ctx context.Context, job queue.Job) (interface{}, error) {
+			processed, err := subscriptionSvc.ChargeDue(ctx, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("Processed %d due subscriptions", processed)
+			return map[string]interface{}{"status": "success", "processed": processed}, nil
+		},
+	}
+}
+
+// ScheduleSubscriptionBilling enqueues a subscription billing job every interval
+func ScheduleSubscriptionBilling(jobQueue *queue.Queue, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := jobQueue.EnqueueJob(queue.JobType(SubscriptionBillingJobType), SubscriptionBillingPayload{
+				ScheduledAt: time.Now(),
+			}); err != nil {
+				log.Printf("Failed to schedule subscription billing: %v", err)
+			}
+		}
+	}()
+
+	log.Printf("Subscription billing scheduled every %v", interval)
+}
@@ -3,6 +3,7 @@ package jobs
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/revaspay/backend/internal/database"
 	"github.com/revaspay/backend/internal/models"
 	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/virtualaccount"
 )
 
 const (
@@ -35,39 +37,28 @@ type VirtualAccountReconciliationPayload struct {
 
 // VirtualAccountJob handles processing virtual account transactions
 type VirtualAccountJob struct {
-	db         *gorm.DB
-	queue      queue.QueueInterface
-	paymentSvc interface{} // Using interface{} as a placeholder for payment service
-	walletSvc  interface{} // Using interface{} as a placeholder for wallet service
+	db                *gorm.DB
+	queue             queue.QueueInterface
+	paymentSvc        interface{} // Using interface{} as a placeholder for payment service
+	walletSvc         interface{} // Using interface{} as a placeholder for wallet service
+	virtualAccountSvc *virtualaccount.VirtualAccountService
 }
 
-// NewVirtualAccountJob creates a new virtual account job handler
-func NewVirtualAccountJob(db *gorm.DB, q queue.QueueInterface, paymentSvc interface{}, walletSvc interface{}) *VirtualAccountJob {
-	job := &VirtualAccountJob{
-		db:         db,
-		queue:      q,
-		paymentSvc: paymentSvc,
-		walletSvc:  walletSvc,
+// NewVirtualAccountJob creates a new virtual account job handler. Reconciliation needs
+// virtualAccountSvc; scheduling only needs the queue.
+func NewVirtualAccountJob(db *gorm.DB, q queue.QueueInterface, paymentSvc interface{}, walletSvc interface{}, virtualAccountSvc *virtualaccount.VirtualAccountService) *VirtualAccountJob {
+	return &VirtualAccountJob{
+		db:                db,
+		queue:             q,
+		paymentSvc:        paymentSvc,
+		walletSvc:         walletSvc,
+		virtualAccountSvc: virtualAccountSvc,
 	}
-
-	// Register handlers with wrapper functions to match the queue.JobHandler signature
-	processHandler := func(ctx context.Context, jobData queue.Job) (interface{}, error) {
-		return job.ProcessVirtualAccountTransaction(ctx, jobData)
-	}
-
-	reconcileHandler := func(ctx context.Context, jobData queue.Job) (interface{}, error) {
-		return job.ReconcileVirtualAccounts(ctx, jobData)
-	}
-
-	q.RegisterHandler(queue.JobType(VirtualAccountTransactionJobType), processHandler)
-	q.RegisterHandler(queue.JobType(VirtualAccountReconciliationJobType), reconcileHandler)
-
-	return job
 }
 
 // RegisterVirtualAccountJobHandlers registers the virtual account job handlers
-func RegisterVirtualAccountJobHandlers(q queue.QueueInterface, db *gorm.DB, paymentSvc interface{}, walletSvc interface{}) {
-	handler := NewVirtualAccountJob(db, q, paymentSvc, walletSvc)
+func RegisterVirtualAccountJobHandlers(q queue.QueueInterface, db *gorm.DB, paymentSvc interface{}, walletSvc interface{}, virtualAccountSvc *virtualaccount.VirtualAccountService) {
+	handler := NewVirtualAccountJob(db, q, paymentSvc, walletSvc, virtualAccountSvc)
 
 	processHandler := func(ctx context.Context, job queue.Job) (interface{}, error) {
 		return handler.ProcessVirtualAccountTransaction(ctx, job)
@@ -96,7 +87,6 @@ func (j *VirtualAccountJob) EnqueueVirtualAccountTransactionJob(transactionID uu
 		ID:         uuid.New(),
 		Type:       queue.JobType(VirtualAccountTransactionJobType),
 		Payload:    payloadBytes,
-		MaxRetries: 3,
 	}
 
 	return j.queue.Enqueue(job)
@@ -117,42 +107,11 @@ func (j *VirtualAccountJob) ScheduleVirtualAccountReconciliation() error {
 		ID:         uuid.New(),
 		Type:       queue.JobType(VirtualAccountReconciliationJobType),
 		Payload:    payloadBytes,
-		MaxRetries: 3,
 	}
 
 	return j.queue.Enqueue(job)
 }
 
-// VirtualAccountTransaction represents a transaction for a virtual account
-type VirtualAccountTransaction struct {
-	ID                     uuid.UUID              `json:"id"`
-	VirtualAccountID       uuid.UUID              `json:"virtual_account_id"`
-	Amount                 float64                `json:"amount"`
-	Currency               string                 `json:"currency"`
-	TransactionID          string                 `json:"transaction_id"`
-	Reference              string                 `json:"reference"`
-	Type                   string                 `json:"type"` // inbound, outbound
-	Status                 string                 `json:"status"`
-	Provider               string                 `json:"provider"` // grey, wise, barter
-	SenderUserID           uuid.UUID              `json:"sender_user_id"`
-	SenderName             string                 `json:"sender_name"`
-	SenderEmail            string                 `json:"sender_email"`
-	SenderBank             string                 `json:"sender_bank"`
-	SenderAccountNumber    string                 `json:"sender_account_number"`
-	RecipientUserID        uuid.UUID              `json:"recipient_user_id"`
-	RecipientName          string                 `json:"recipient_name"`
-	RecipientBank          string                 `json:"recipient_bank"`
-	RecipientAccountNumber string                 `json:"recipient_account_number"`
-	RecipientAccountID     string                 `json:"recipient_account_id"`
-	Fee                    float64                `json:"fee"`
-	PaymentID              *uuid.UUID             `json:"payment_id"`
-	WithdrawalID           *uuid.UUID             `json:"withdrawal_id"`
-	Metadata               map[string]interface{} `json:"metadata"`
-	CreatedAt              time.Time              `json:"created_at"`
-	UpdatedAt              time.Time              `json:"updated_at"`
-	CompletedAt            *time.Time             `json:"completed_at"`
-}
-
 // ProcessVirtualAccountTransaction processes a virtual account transaction
 func (j *VirtualAccountJob) ProcessVirtualAccountTransaction(ctx context.Context, job queue.Job) (interface{}, error) {
 	// Parse payload
@@ -162,7 +121,7 @@ func (j *VirtualAccountJob) ProcessVirtualAccountTransaction(ctx context.Context
 	}
 
 	// Get transaction record
-	var transaction VirtualAccountTransaction
+	var transaction models.VirtualAccountTransaction
 	if err := j.db.First(&transaction, "id = ?", payload.TransactionID).Error; err != nil {
 		return nil, fmt.Errorf("failed to get virtual account transaction: %w", err)
 	}
@@ -259,7 +218,7 @@ func (j *VirtualAccountJob) ProcessVirtualAccountTransaction(ctx context.Context
 func (j *VirtualAccountJob) processInboundTransaction(
 	_ context.Context,
 	tx *gorm.DB,
-	transaction *VirtualAccountTransaction,
+	transaction *models.VirtualAccountTransaction,
 	_ *database.VirtualAccount,
 	user *database.User,
 ) error {
@@ -316,7 +275,7 @@ func (j *VirtualAccountJob) processInboundTransaction(
 func (j *VirtualAccountJob) processOutboundTransaction(
 	_ context.Context,
 	tx *gorm.DB,
-	transaction *VirtualAccountTransaction,
+	transaction *models.VirtualAccountTransaction,
 	_ *database.VirtualAccount,
 	user *database.User,
 ) error {
@@ -361,35 +320,61 @@ func (j *VirtualAccountJob) processOutboundTransaction(
 	return nil
 }
 
-// ReconcileVirtualAccounts reconciles virtual accounts with provider data
+// ReconcileVirtualAccounts reconciles active virtual accounts with their providers'
+// transaction history and queues any deposits we had missed
 func (j *VirtualAccountJob) ReconcileVirtualAccounts(ctx context.Context, job queue.Job) (interface{}, error) {
 	// Parse payload
 	var payload VirtualAccountReconciliationPayload
 	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal virtual account reconciliation payload: %w", err)
 	}
+	if j.virtualAccountSvc == nil {
+		return nil, fmt.Errorf("virtual account service not configured")
+	}
 
 	log.Printf("Starting virtual account reconciliation at %s", payload.ScheduledAt)
 
-	// Get all active virtual accounts
-	var virtualAccounts []database.VirtualAccount
-	if err := j.db.Where("status = ?", "active").Find(&virtualAccounts).Error; err != nil {
-		return nil, fmt.Errorf("failed to get active virtual accounts: %w", err)
+	virtualAccounts, err := j.virtualAccountSvc.ActiveAccounts()
+	if err != nil {
+		return nil, err
 	}
 
 	log.Printf("Found %d active virtual accounts to reconcile", len(virtualAccounts))
 
-	// Process each provider separately
-	if err := j.reconcileGreyAccounts(ctx, virtualAccounts); err != nil {
-		log.Printf("Error reconciling Grey accounts: %v", err)
-	}
+	var reconciled, created, flagged, failed int
+	skipped := map[models.VirtualAccountProvider]int{}
+	for i := range virtualAccounts {
+		account := &virtualAccounts[i]
 
-	if err := j.reconcileWiseAccounts(ctx, virtualAccounts); err != nil {
-		log.Printf("Error reconciling Wise accounts: %v", err)
+		result, err := j.virtualAccountSvc.Reconcile(ctx, account)
+		var notImplemented *virtualaccount.NotImplementedError
+		if errors.As(err, &notImplemented) {
+			skipped[account.Provider]++
+			continue
+		}
+		if err != nil {
+			failed++
+			log.Printf("Error reconciling %s virtual account %s: %v", account.Provider, account.ID, err)
+		}
+		if result == nil {
+			continue
+		}
+		if err == nil {
+			reconciled++
+		}
+
+		// Deposits found by reconciliation are processed like any other
+		for _, transactionID := range result.Created {
+			if err := j.EnqueueVirtualAccountTransactionJob(transactionID); err != nil {
+				log.Printf("Failed to enqueue missed virtual account transaction %s: %v", transactionID, err)
+			}
+		}
+		created += len(result.Created)
+		flagged += result.Flagged
 	}
 
-	if err := j.reconcileBarterAccounts(ctx, virtualAccounts); err != nil {
-		log.Printf("Error reconciling Barter accounts: %v", err)
+	for provider, count := range skipped {
+		log.Printf("Skipped %d %s virtual accounts: reconciliation not implemented for %s", count, provider, provider)
 	}
 
 	// Schedule next reconciliation in 6 hours
@@ -397,79 +382,19 @@ func (j *VirtualAccountJob) ReconcileVirtualAccounts(ctx context.Context, job qu
 		ID:         uuid.New(),
 		Type:       queue.JobType(VirtualAccountReconciliationJobType),
 		Payload:    []byte(job.Payload),
-		MaxRetries: 3,
 		NextRetry:  func() *time.Time { t := time.Now().Add(6 * time.Hour); return &t }(),
 	}
 	if err := j.queue.Enqueue(nextJob); err != nil {
 		log.Printf("Failed to schedule next virtual account reconciliation: %v", err)
 	}
 
-	log.Printf("Virtual account reconciliation completed")
-	return map[string]interface{}{"status": "success"}, nil
-}
-
-// reconcileGreyAccounts reconciles Grey virtual accounts
-func (j *VirtualAccountJob) reconcileGreyAccounts(_ context.Context, accounts []database.VirtualAccount) error {
-	// Filter Grey accounts
-	var greyAccounts []database.VirtualAccount
-	for _, account := range accounts {
-		if account.Provider == "grey" {
-			greyAccounts = append(greyAccounts, account)
-		}
-	}
-
-	if len(greyAccounts) == 0 {
-		return nil
-	}
-
-	log.Printf("Reconciling %d Grey virtual accounts", len(greyAccounts))
-
-	// In a real implementation, you would use the Grey API to get transaction history
-	// For now, we'll just log that we're reconciling
-
-	return nil
-}
-
-// reconcileWiseAccounts reconciles Wise virtual accounts
-func (j *VirtualAccountJob) reconcileWiseAccounts(_ context.Context, accounts []database.VirtualAccount) error {
-	// Filter Wise accounts
-	var wiseAccounts []database.VirtualAccount
-	for _, account := range accounts {
-		if account.Provider == "wise" {
-			wiseAccounts = append(wiseAccounts, account)
-		}
-	}
-
-	if len(wiseAccounts) == 0 {
-		return nil
-	}
-
-	log.Printf("Reconciling %d Wise virtual accounts", len(wiseAccounts))
-
-	// In a real implementation, you would use the Wise API to get transaction history
-	// For now, we'll just log that we're reconciling
-
-	return nil
-}
-
-// reconcileBarterAccounts reconciles Barter virtual accounts
-func (j *VirtualAccountJob) reconcileBarterAccounts(_ context.Context, accounts []database.VirtualAccount) error {
-	// Filter Barter accounts
-	var barterAccounts []database.VirtualAccount
-	for _, account := range accounts {
-		if account.Provider == "barter" {
-			barterAccounts = append(barterAccounts, account)
-		}
-	}
-
-	if len(barterAccounts) == 0 {
-		return nil
-	}
-
-	log.Printf("Reconciling %d Barter virtual accounts", len(barterAccounts))
-
-	// In a real implementation, you would use the Barter API to get transaction history
-	// For now, we'll just log that we're reconciling
-
-	return nil
+	log.Printf("Virtual account reconciliation completed: %d reconciled, %d missed deposits recorded, %d flagged for review, %d failed",
+		reconciled, created, flagged, failed)
+	return map[string]interface{}{
+		"status":     "success",
+		"reconciled": reconciled,
+		"created":    created,
+		"flagged":    flagged,
+		"failed":     failed,
+	}, nil
 }
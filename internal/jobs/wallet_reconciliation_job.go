@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// WalletReconciliationJobType is the job type for reconciling wallet balances against the ledger
+	WalletReconciliationJobType queue.JobType = "reconcile_wallets"
+
+	// walletReconciliationInterval is how often all wallets are reconciled
+	walletReconciliationInterval = 1 * time.Hour
+)
+
+// WalletReconciliationJob checks every wallet's balance against its ledger
+type WalletReconciliationJob struct {
+	queue       queue.QueueInterface
+	walletSvc   *wallet.WalletService
+	auditLogger *utils.AuditLogger
+}
+
+// NewWalletReconciliationJob creates a new wallet reconciliation job handler
+func NewWalletReconciliationJob(db *gorm.DB, q queue.QueueInterface, walletSvc *wallet.WalletService) *WalletReconciliationJob {
+	return &WalletReconciliationJob{
+		queue:       q,
+		walletSvc:   walletSvc,
+		auditLogger: utils.NewAuditLogger(db),
+	}
+}
+
+// RegisterWalletReconciliationJobHandlers registers the wallet reconciliation job handler
+func RegisterWalletReconciliationJobHandlers(q queue.QueueInterface, db *gorm.DB, walletSvc *wallet.WalletService) {
+	handler := NewWalletReconciliationJob(db, q, walletSvc)
+
+	q.RegisterHandler(WalletReconciliationJobType, func(ctx context.Context, job queue.Job) (interface{}, error) {
+		return nil, handler.ReconcileWallets(ctx, job)
+	})
+}
+
+// ScheduleWalletReconciliation schedules the first reconciliation run
+func (j *WalletReconciliationJob) ScheduleWalletReconciliation() error {
+	return j.scheduleRun(time.Now())
+}
+
+// scheduleRun enqueues a reconciliation run at the given time
+func (j *WalletReconciliationJob) scheduleRun(runAt time.Time) error {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"scheduled_at": runAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal wallet reconciliation payload: %w", err)
+	}
+
+	job := &queue.Job{
+		ID:        uuid.New(),
+		Type:      WalletReconciliationJobType,
+		Payload:   payloadBytes,
+		NextRetry: &runAt,
+	}
+
+	return j.queue.Enqueue(job)
+}
+
+// ReconcileWallets reconciles all wallets, records an audit entry for each mismatch and
+// schedules the next run
+func (j *WalletReconciliationJob) ReconcileWallets(ctx context.Context, _ queue.Job) error {
+	mismatches, scanned, err := j.walletSvc.ReconcileAll()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range mismatches {
+		log.Printf("ALERT: wallet %s balance %.8f does not match ledger %.8f (difference %.8f)",
+			m.WalletID, m.StoredBalance, m.LedgerBalance, m.Difference)
+
+		userID := m.UserID
+		description := fmt.Sprintf("Wallet %s balance does not match its ledger", m.WalletID)
+		if err := j.auditLogger.LogEvent(ctx, utils.AuditEventLedgerMismatch, utils.AuditSeverityCritical, description, &userID, nil, "", "", false, map[string]interface{}{
+			"wallet_id":      m.WalletID.String(),
+			"currency":       string(m.Currency),
+			"stored_balance": m.StoredBalance,
+			"ledger_balance": m.LedgerBalance,
+			"difference":     m.Difference,
+			"entry_count":    m.EntryCount,
+		}); err != nil {
+			log.Printf("Failed to write ledger mismatch audit entry for wallet %s: %v", m.WalletID, err)
+		}
+	}
+
+	log.Printf("Reconciled %d wallets, %d mismatches", scanned, len(mismatches))
+
+	return j.scheduleRun(time.Now().Add(walletReconciliationInterval))
+}
@@ -3,16 +3,23 @@ package jobs
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/circuitbreaker"
 	"github.com/revaspay/backend/internal/models"
 	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/crypto"
 	"github.com/revaspay/backend/internal/services/payment"
+	"github.com/revaspay/backend/internal/services/screening"
 	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/services/webhook"
+	"github.com/revaspay/backend/internal/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
@@ -21,8 +28,19 @@ const (
 	
 	// WithdrawalStatusCheckJobType is the job type for checking withdrawal status
 	WithdrawalStatusCheckJobType = "check_withdrawal_status"
+
+	// WithdrawalStatusCompletedDryRun is the final status of a dry run withdrawal
+	WithdrawalStatusCompletedDryRun = "completed_dry_run"
 )
 
+// ErrScreeningNotConfigured is returned for crypto withdrawals when no screening service is
+// set, as destinations can't be paid out without being screened
+var ErrScreeningNotConfigured = errors.New("address screening is not configured")
+
+// payoutMethods are the withdrawal methods with a payout provider, each behind its own
+// circuit breaker
+var payoutMethods = []string{"bank_transfer", "mobile_money", "crypto", "paypal"}
+
 // WithdrawalJobPayload represents the payload for a withdrawal job
 type WithdrawalJobPayload struct {
 	WithdrawalID uuid.UUID `json:"withdrawal_id"`
@@ -34,15 +52,53 @@ type WithdrawalJob struct {
 	queue      queue.QueueInterface
 	paymentSvc *payment.PaymentService
 	walletSvc  *wallet.WalletService
+	webhookSvc *webhook.WebhookService
+	screeningSvc *screening.Service
+	auditLogger  *utils.AuditLogger
+	dryRun          bool
+	allowDryRunFlag bool
+	breakers        map[string]*circuitbreaker.Breaker
+	manualResolveAfter time.Duration
 }
 
 // NewWithdrawalJob creates a new withdrawal job handler
 func NewWithdrawalJob(db *gorm.DB, q queue.QueueInterface, paymentSvc *payment.PaymentService, walletSvc *wallet.WalletService) *WithdrawalJob {
-	return &WithdrawalJob{
+	j := &WithdrawalJob{
 		db:         db,
 		queue:      q,
 		paymentSvc: paymentSvc,
 		walletSvc:  walletSvc,
+		auditLogger: utils.NewAuditLogger(db),
+		manualResolveAfter: DefaultManualResolveAfter,
+	}
+	j.SetCircuitBreakerSettings(circuitbreaker.DefaultSettings)
+	return j
+}
+
+// SetWebhookService sets the service used to notify merchants of withdrawal status changes
+func (j *WithdrawalJob) SetWebhookService(webhookSvc *webhook.WebhookService) {
+	j.webhookSvc = webhookSvc
+}
+
+// SetScreeningService sets the service used to screen crypto withdrawal destinations
+func (j *WithdrawalJob) SetScreeningService(screeningSvc *screening.Service) {
+	j.screeningSvc = screeningSvc
+}
+
+// SetDryRun makes withdrawals run without calling payout providers: all of them when
+// enabled, or those whose metadata sets dry_run when allowPerWithdrawal. Dry runs end in
+// completed_dry_run and never move funds.
+func (j *WithdrawalJob) SetDryRun(enabled, allowPerWithdrawal bool) {
+	j.dryRun = enabled
+	j.allowDryRunFlag = allowPerWithdrawal
+}
+
+// SetCircuitBreakerSettings sets when the payout providers' circuit breakers open and how long
+// they stay open, resetting them
+func (j *WithdrawalJob) SetCircuitBreakerSettings(settings circuitbreaker.Settings) {
+	j.breakers = make(map[string]*circuitbreaker.Breaker, len(payoutMethods))
+	for _, method := range payoutMethods {
+		j.breakers[method] = circuitbreaker.New("payout:"+method, settings)
 	}
 }
 
@@ -53,6 +109,13 @@ func (j *WithdrawalJob) RegisterHandlers(q *queue.QueueAdapter) {
 		queue:     j.queue,
 		paymentSvc: j.paymentSvc,
 		walletSvc: j.walletSvc,
+		webhookSvc: j.webhookSvc,
+		screeningSvc: j.screeningSvc,
+		auditLogger: j.auditLogger,
+		dryRun:          j.dryRun,
+		allowDryRunFlag: j.allowDryRunFlag,
+		breakers:        j.breakers,
+		manualResolveAfter: j.manualResolveAfter,
 	}
 
 	// Wrap the handler methods to match the JobHandler signature
@@ -81,7 +144,6 @@ func (j *WithdrawalJob) EnqueueWithdrawalJob(withdrawalID uuid.UUID) error {
 		ID:         uuid.New(),
 		Type:       queue.JobType(WithdrawalProcessJobType),
 		Payload:    payloadBytes,
-		MaxRetries: 3,
 	}
 
 	return j.queue.Enqueue(job)
@@ -107,55 +169,113 @@ func (j *WithdrawalJob) ProcessWithdrawal(ctx context.Context, job *queue.Job) e
 		return nil
 	}
 
+	// While the payout provider's circuit breaker is open the withdrawal stays pending and the
+	// queue reschedules the job without counting a retry
+	if breaker, ok := j.breakers[withdrawal.Method]; ok && !j.isDryRun(&withdrawal) {
+		if err := breaker.Ready(); err != nil {
+			return fmt.Errorf("payout provider unavailable for withdrawal %s: %w", withdrawal.ID, err)
+		}
+	}
+
 	// Get user
 	var user models.User
 	if err := j.db.First(&user, "id = ?", withdrawal.UserID).Error; err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
 	}
 
-	// Process withdrawal based on method
+	// Process withdrawal based on method. A suspended user's wallet is frozen, so their
+	// withdrawal fails and its funds are released back to the wallet.
 	var err error
-	switch withdrawal.Method {
-	case "bank_transfer":
+	switch {
+	case !user.IsActive:
+		err = wallet.ErrAccountSuspended
+	case withdrawal.Method == "bank_transfer":
 		err = j.processBankTransfer(ctx, &withdrawal, &user)
-	case "mobile_money":
+	case withdrawal.Method == "mobile_money":
 		err = j.processMobileMoneyWithdrawal(ctx, &withdrawal, &user)
-	case "crypto":
+	case withdrawal.Method == "crypto":
 		err = j.processCryptoWithdrawal(ctx, &withdrawal, &user)
-	case "paypal":
+	case withdrawal.Method == "paypal":
 		err = j.processPayPalWithdrawal(ctx, &withdrawal, &user)
 	default:
 		err = fmt.Errorf("unsupported withdrawal method: %s", withdrawal.Method)
 	}
 
+	// A payout the provider didn't answer in time may still go through, so rather than failing
+	// and refunding it, leave it processing for the status check to settle
+	if utils.IsTimeout(err) && withdrawal.Status == "processing" {
+		log.Printf("Payout for withdrawal %s timed out, leaving it processing: %v", withdrawal.ID, err)
+		j.auditStatusChange(ctx, &withdrawal, "pending", "provider timed out, awaiting status check")
+		j.notifyStatusChange(&withdrawal)
+		return j.scheduleStatusCheck(withdrawal.ID)
+	}
+
+	// The breaker opened after the check above, so the payout was never sent
+	if errors.Is(err, circuitbreaker.ErrOpen) {
+		return j.returnToPending(&withdrawal, err)
+	}
+
 	if err != nil {
 		// If withdrawal failed, update status and refund to wallet
 		if withdrawal.Status == "failed" && withdrawal.FailureReason != "" {
 			err = fmt.Errorf("withdrawal failed: %w", err)
 		}
+		// Providers may have moved the withdrawal to processing before failing
+		previousStatus := "pending"
+		if withdrawal.Status == "processing" {
+			previousStatus = "processing"
+		}
 		now := time.Now()
+		withdrawal.Status = "failed"
+		if errors.Is(err, screening.ErrAddressBlocked) {
+			withdrawal.Status = "blocked"
+		}
+		withdrawal.FailureReason = err.Error()
 		withdrawal.FailedAt = &now
 		withdrawal.UpdatedAt = now
-		
-		if dbErr := j.db.Save(&withdrawal).Error; dbErr != nil {
-			log.Printf("Failed to update withdrawal status: %v", dbErr)
-		}
-		
-		// Refund the user's wallet
-		refundErr := j.refundWithdrawal(ctx, &withdrawal)
-		if refundErr != nil {
-			log.Printf("Failed to refund withdrawal: %v", refundErr)
+
+		// Refund the user's wallet along with the status change
+		if refundErr := j.refundWithdrawal(&withdrawal, previousStatus); refundErr != nil {
+			if errors.Is(refundErr, errWithdrawalMovedOn) {
+				log.Printf("Withdrawal %s changed status while failing, leaving it", withdrawal.ID)
+				return nil
+			}
+			return fmt.Errorf("failed to fail and refund withdrawal %s: %v: %w", withdrawal.ID, refundErr, err)
 		}
-		
+		j.auditStatusChange(ctx, &withdrawal, previousStatus, withdrawal.FailureReason)
+		j.notifyStatusChange(&withdrawal)
+		j.auditRefund(ctx, &withdrawal)
+
 		return fmt.Errorf("failed to process withdrawal: %w", err)
 	}
 
+	if j.isDryRun(&withdrawal) {
+		return j.completeDryRun(ctx, &withdrawal)
+	}
+
+	j.auditStatusChange(ctx, &withdrawal, "pending", "")
+	j.notifyStatusChange(&withdrawal)
+
 	// Schedule a status check for the withdrawal
 	return j.scheduleStatusCheck(withdrawal.ID)
 }
 
+// returnToPending puts a withdrawal whose payout was never sent back to pending, so the
+// rescheduled job processes it again
+func (j *WithdrawalJob) returnToPending(withdrawal *models.Withdrawal, err error) error {
+	if withdrawal.Status == "processing" {
+		withdrawal.Status = "pending"
+		withdrawal.ProcessedAt = nil
+		withdrawal.UpdatedAt = time.Now()
+		if dbErr := j.db.Save(withdrawal).Error; dbErr != nil {
+			return fmt.Errorf("failed to return withdrawal %s to pending: %w", withdrawal.ID, dbErr)
+		}
+	}
+	return fmt.Errorf("payout provider unavailable for withdrawal %s: %w", withdrawal.ID, err)
+}
+
 // processBankTransfer processes a bank transfer withdrawal
-func (j *WithdrawalJob) processBankTransfer(_ context.Context, withdrawal *models.Withdrawal, user *models.User) error {
+func (j *WithdrawalJob) processBankTransfer(ctx context.Context, withdrawal *models.Withdrawal, user *models.User) error {
 	log.Printf("Processing bank transfer withdrawal %s for user %s", withdrawal.ID, user.ID)
 
 	// Update withdrawal status to processing
@@ -169,19 +289,11 @@ func (j *WithdrawalJob) processBankTransfer(_ context.Context, withdrawal *model
 	}
 
 	// In a real implementation, you would use a payment provider SDK to initiate the bank transfer
-	// For now, we'll simulate a successful initiation
-	withdrawal.Reference = uuid.New().String()
-	
-	if err := j.db.Save(withdrawal).Error; err != nil {
-		return fmt.Errorf("failed to update withdrawal with provider reference: %w", err)
-	}
-
-	log.Printf("Bank transfer initiated successfully, reference: %s", withdrawal.Reference)
-	return nil
+	return j.initiatePayout(ctx, withdrawal, "bank transfer")
 }
 
 // processMobileMoneyWithdrawal processes a mobile money withdrawal
-func (j *WithdrawalJob) processMobileMoneyWithdrawal(_ context.Context, withdrawal *models.Withdrawal, user *models.User) error {
+func (j *WithdrawalJob) processMobileMoneyWithdrawal(ctx context.Context, withdrawal *models.Withdrawal, user *models.User) error {
 	log.Printf("Processing mobile money withdrawal %s for user %s", withdrawal.ID, user.ID)
 
 	// Update withdrawal status to processing
@@ -234,26 +346,101 @@ func (j *WithdrawalJob) processMobileMoneyWithdrawal(_ context.Context, withdraw
 		Description:   "Withdrawal to mobile money",
 	}
 
-	if err := j.db.Create(&momoTx).Error; err != nil {
-		return fmt.Errorf("failed to create MoMo transaction: %w", err)
+	// Dry runs build the disbursement but don't record it, as it is never sent
+	if !j.isDryRun(withdrawal) {
+		if err := j.db.Create(&momoTx).Error; err != nil {
+			return fmt.Errorf("failed to create MoMo transaction: %w", err)
+		}
 	}
 
 	// In a real implementation, you would use the MTN MoMo API to initiate the disbursement
+	return j.initiatePayout(ctx, withdrawal, "mobile money")
+}
+
+// initiatePayout sends the payout to the provider and records the provider's reference.
+// Dry runs log the payout and return without calling the provider. The provider call is
+// bounded by the provider timeout and the job's deadline, and goes through the method's
+// circuit breaker.
+func (j *WithdrawalJob) initiatePayout(ctx context.Context, withdrawal *models.Withdrawal, provider string) error {
+	if j.isDryRun(withdrawal) {
+		log.Printf("Dry run: skipping %s payout of %.2f %s for withdrawal %s", provider, withdrawal.Amount, withdrawal.Currency, withdrawal.ID)
+		return nil
+	}
+
+	call, err := j.breakers[withdrawal.Method].Allow()
+	if err != nil {
+		return fmt.Errorf("failed to initiate %s payout: %w", provider, err)
+	}
+	defer call.Release()
+
+	ctx, cancel := utils.ProviderContext(ctx)
+	defer cancel()
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to initiate %s payout: %w", provider, err)
+	}
+
 	// For now, we'll simulate a successful initiation
 	withdrawal.Reference = uuid.New().String()
+	call.Done(nil)
 	
 	if err := j.db.Save(withdrawal).Error; err != nil {
 		return fmt.Errorf("failed to update withdrawal with provider reference: %w", err)
 	}
 
-	log.Printf("Mobile money transfer initiated successfully, reference: %s", withdrawal.Reference)
+	log.Printf("%s payout initiated successfully, reference: %s", provider, withdrawal.Reference)
 	return nil
 }
 
+// isDryRun reports whether a withdrawal should run without calling payout providers
+func (j *WithdrawalJob) isDryRun(withdrawal *models.Withdrawal) bool {
+	if j.dryRun {
+		return true
+	}
+	if !j.allowDryRunFlag {
+		return false
+	}
+	dryRun, _ := withdrawal.MetaData["dry_run"].(bool)
+	return dryRun
+}
+
 // processCryptoWithdrawal processes a crypto withdrawal
-func (j *WithdrawalJob) processCryptoWithdrawal(_ context.Context, withdrawal *models.Withdrawal, user *models.User) error {
+func (j *WithdrawalJob) processCryptoWithdrawal(ctx context.Context, withdrawal *models.Withdrawal, user *models.User) error {
 	log.Printf("Processing crypto withdrawal %s for user %s", withdrawal.ID, user.ID)
 
+	// Get destination address details from metadata
+	address, network := "", crypto.NetworkBase
+	metadataMap := map[string]interface{}{}
+	metadataBytes, _ := json.Marshal(withdrawal.MetaData)
+	if err := json.Unmarshal(metadataBytes, &metadataMap); err == nil {
+		if addr, ok := metadataMap["address"].(string); ok {
+			address = addr
+		}
+		if n, ok := metadataMap["network"].(string); ok && n != "" {
+			network = n
+		}
+	}
+
+	if address == "" {
+		return fmt.Errorf("destination address is required for crypto withdrawal")
+	}
+	if err := crypto.ValidateAddress(network, address); err != nil {
+		return err
+	}
+
+	// Screen the destination before any funds move. Without a screening service nothing is
+	// paid out, so a misconfigured worker can't skip the check.
+	if j.screeningSvc == nil {
+		return ErrScreeningNotConfigured
+	}
+	verdict, err := j.screeningSvc.ScreenAddress(network, address)
+	if err != nil {
+		return fmt.Errorf("failed to screen destination address: %w", err)
+	}
+	if verdict.Flagged {
+		j.screeningSvc.RecordBlocked(ctx, withdrawal.UserID, "withdrawal", withdrawal.ID, verdict)
+		return fmt.Errorf("%w: %s", screening.ErrAddressBlocked, verdict.Reason)
+	}
+
 	// Update withdrawal status to processing
 	withdrawal.Status = "processing"
 	now := time.Now()
@@ -265,19 +452,11 @@ func (j *WithdrawalJob) processCryptoWithdrawal(_ context.Context, withdrawal *m
 	}
 
 	// In a real implementation, you would use a crypto API to initiate the transfer
-	// For now, we'll simulate a successful initiation
-	withdrawal.Reference = uuid.New().String()
-	
-	if err := j.db.Save(withdrawal).Error; err != nil {
-		return fmt.Errorf("failed to update withdrawal with provider reference: %w", err)
-	}
-
-	log.Printf("Crypto transfer initiated successfully, reference: %s", withdrawal.Reference)
-	return nil
+	return j.initiatePayout(ctx, withdrawal, "crypto")
 }
 
 // processPayPalWithdrawal processes a PayPal withdrawal
-func (j *WithdrawalJob) processPayPalWithdrawal(_ context.Context, withdrawal *models.Withdrawal, user *models.User) error {
+func (j *WithdrawalJob) processPayPalWithdrawal(ctx context.Context, withdrawal *models.Withdrawal, user *models.User) error {
 	log.Printf("Processing PayPal withdrawal %s for user %s", withdrawal.ID, user.ID)
 
 	// Update withdrawal status to processing
@@ -291,46 +470,93 @@ func (j *WithdrawalJob) processPayPalWithdrawal(_ context.Context, withdrawal *m
 	}
 
 	// In a real implementation, you would use the PayPal API to initiate the payout
-	// For now, we'll simulate a successful initiation
-	withdrawal.Reference = uuid.New().String()
-	
-	if err := j.db.Save(withdrawal).Error; err != nil {
-		return fmt.Errorf("failed to update withdrawal with provider reference: %w", err)
-	}
+	return j.initiatePayout(ctx, withdrawal, "PayPal")
+}
 
-	log.Printf("PayPal transfer initiated successfully, reference: %s", withdrawal.Reference)
-	return nil
+// refundWithdrawal saves a failed withdrawal and returns its funds to the user's available
+// balance in one transaction, so a crash can't leave it failed without its refund. It returns
+// errWithdrawalMovedOn, changing nothing, if the withdrawal's status is no longer
+// previousStatus, such as when an admin resolved it.
+func (j *WithdrawalJob) refundWithdrawal(withdrawal *models.Withdrawal, previousStatus string) error {
+	return j.db.Transaction(func(tx *gorm.DB) error {
+		var current models.Withdrawal
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("status").First(&current, "id = ?", withdrawal.ID).Error; err != nil {
+			return fmt.Errorf("failed to lock withdrawal: %w", err)
+		}
+		if current.Status != previousStatus {
+			return errWithdrawalMovedOn
+		}
+
+		if err := tx.Save(withdrawal).Error; err != nil {
+			return fmt.Errorf("failed to update withdrawal status: %w", err)
+		}
+		if err := j.walletSvc.RefundWithdrawalWithTx(tx, withdrawal, withdrawal.FailureReason); err != nil {
+			return fmt.Errorf("failed to refund withdrawal: %w", err)
+		}
+		log.Printf("Refunded withdrawal %s to user %s", withdrawal.ID, withdrawal.UserID)
+		return nil
+	})
 }
 
-// refundWithdrawal refunds a failed withdrawal back to the user's wallet
-func (j *WithdrawalJob) refundWithdrawal(_ context.Context, withdrawal *models.Withdrawal) error {
-	log.Printf("Refunding withdrawal %s to user %s", withdrawal.ID, withdrawal.UserID)
+// completeWithdrawal marks a withdrawal completed and turns its hold into the final debit. It
+// returns errWithdrawalMovedOn, changing nothing, if the withdrawal's status changed since it
+// was loaded, such as by an admin resolving it.
+func (j *WithdrawalJob) completeWithdrawal(withdrawal *models.Withdrawal) error {
+	return j.db.Transaction(func(tx *gorm.DB) error {
+		var current models.Withdrawal
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("status").First(&current, "id = ?", withdrawal.ID).Error; err != nil {
+			return fmt.Errorf("failed to lock withdrawal: %w", err)
+		}
+		if current.Status != withdrawal.Status {
+			return errWithdrawalMovedOn
+		}
+		return j.walletSvc.CompleteWithdrawalWithTx(tx, withdrawal)
+	})
+}
 
-	// Get the wallet
-	var wallet models.Wallet
-	if err := j.db.First(&wallet, "id = ?", withdrawal.WalletID).Error; err != nil {
-		return fmt.Errorf("failed to get wallet: %w", err)
-	}
+// completeDryRun ends a dry run withdrawal in completed_dry_run, giving back the funds it
+// reserved so the user's balance ends where it started
+func (j *WithdrawalJob) completeDryRun(ctx context.Context, withdrawal *models.Withdrawal) error {
+	err := j.db.Transaction(func(tx *gorm.DB) error {
+		if withdrawal.HoldID != nil {
+			if err := j.walletSvc.ReleaseHoldWithTx(tx, *withdrawal.HoldID); err != nil && !errors.Is(err, wallet.ErrHoldNotActive) {
+				return fmt.Errorf("failed to release wallet hold: %w", err)
+			}
+		}
 
-	// Credit the wallet
-	_, err := j.walletSvc.Credit(
-		withdrawal.UserID,
-		withdrawal.Amount, // Refund the full amount
-		string(withdrawal.Currency),
-		fmt.Sprintf("Refund: %s", withdrawal.Reference),
-		"Withdrawal failed - amount refunded", // Description
-		map[string]interface{}{
-			"withdrawal_id": withdrawal.ID.String(),
-			"refund_reason": "withdrawal_failed",
-			"error":         withdrawal.FailureReason,
-		},
-	)
-	
+		now := time.Now()
+		withdrawal.Status = WithdrawalStatusCompletedDryRun
+		withdrawal.CompletedAt = &now
+		withdrawal.UpdatedAt = now
+		if err := tx.Save(withdrawal).Error; err != nil {
+			return fmt.Errorf("failed to update withdrawal status: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to credit wallet: %w", err)
+		return err
+	}
+
+	// Older withdrawals debited the wallet up front, so that debit is undone
+	if withdrawal.HoldID == nil {
+		if _, err := j.walletSvc.CreditOnce(
+			withdrawal.WalletID,
+			withdrawal.Amount,
+			"refund",
+			models.LedgerCategoryRefund,
+			fmt.Sprintf("Dry run: %s", withdrawal.Reference),
+			"Dry run withdrawal - amount returned",
+			map[string]interface{}{
+				"withdrawal_id": withdrawal.ID.String(),
+				"refund_reason": "dry_run",
+			},
+		); err != nil && !errors.Is(err, wallet.ErrDuplicateCredit) {
+			return fmt.Errorf("failed to return dry run withdrawal: %w", err)
+		}
 	}
 
-	log.Printf("Successfully refunded withdrawal %s to user %s", withdrawal.ID, withdrawal.UserID)
+	log.Printf("Dry run withdrawal %s completed", withdrawal.ID)
+	j.auditStatusChange(ctx, withdrawal, "pending", "dry run")
 	return nil
 }
 
@@ -349,7 +575,6 @@ func (j *WithdrawalJob) scheduleStatusCheck(withdrawalID uuid.UUID) error {
 		ID:         uuid.New(),
 		Type:       queue.JobType(WithdrawalStatusCheckJobType),
 		Payload:    payloadBytes,
-		MaxRetries: 5,
 		NextRetry:  func() *time.Time { t := time.Now().Add(15 * time.Minute); return &t }(), // Check status after 15 minutes
 	}
 
@@ -403,17 +628,19 @@ func (j *WithdrawalJob) CheckWithdrawalStatus(ctx context.Context, job *queue.Jo
 	}
 
 	if completed {
-		// Update withdrawal to completed
-		withdrawal.Status = "completed"
-		now := time.Now()
-		withdrawal.CompletedAt = &now
-		withdrawal.UpdatedAt = time.Now()
-		
-		if err := j.db.Save(&withdrawal).Error; err != nil {
-			return fmt.Errorf("failed to update withdrawal status: %w", err)
+		// Update withdrawal to completed and debit the held funds
+		err := j.completeWithdrawal(&withdrawal)
+		if errors.Is(err, errWithdrawalMovedOn) {
+			log.Printf("Withdrawal %s was settled while checking its status, leaving it", withdrawal.ID)
+			return nil
+		}
+		if err != nil {
+			return err
 		}
 		
 		log.Printf("Withdrawal %s completed successfully", withdrawal.ID)
+		j.auditStatusChange(ctx, &withdrawal, "processing", "confirmed by provider")
+		j.notifyStatusChange(&withdrawal)
 		return nil
 	}
 
@@ -423,6 +650,76 @@ func (j *WithdrawalJob) CheckWithdrawalStatus(ctx context.Context, job *queue.Jo
 	return j.queue.Enqueue(job)
 }
 
+// auditStatusChange records the withdrawal's move from previousStatus to its current status.
+// Withdrawals are only moved on by the job, so the actor is always the system.
+func (j *WithdrawalJob) auditStatusChange(ctx context.Context, withdrawal *models.Withdrawal, previousStatus, reason string) {
+	if withdrawal.Status == previousStatus {
+		return
+	}
+	if err := j.auditLogger.LogStatusTransition(ctx, utils.AuditEventWithdrawalStatus, utils.StatusTransition{
+		EntityID:  withdrawal.ID,
+		OwnerID:   withdrawal.UserID,
+		Reference: withdrawal.Reference,
+		From:      previousStatus,
+		To:        withdrawal.Status,
+		Amount:    withdrawal.Amount,
+		Currency:  string(withdrawal.Currency),
+		Actor:     utils.AuditActorSystem,
+		Reason:    reason,
+	}); err != nil {
+		log.Printf("Failed to write withdrawal status audit event for %s: %v", withdrawal.ID, err)
+	}
+}
+
+// auditRefund records a failed withdrawal's funds being returned to the user's wallet
+func (j *WithdrawalJob) auditRefund(ctx context.Context, withdrawal *models.Withdrawal) {
+	userID := withdrawal.UserID
+	if err := j.auditLogger.LogEvent(ctx, utils.AuditEventWithdrawalRefunded, utils.AuditSeverityInfo,
+		fmt.Sprintf("%s refunded to wallet", withdrawal.Reference), &userID, nil, "", "", true,
+		map[string]interface{}{
+			"entity_id": withdrawal.ID.String(),
+			"reference": withdrawal.Reference,
+			"status":    withdrawal.Status,
+			"amount":    withdrawal.Amount,
+			"currency":  withdrawal.Currency,
+			"actor":     utils.AuditActorSystem,
+			"reason":    withdrawal.FailureReason,
+		}); err != nil {
+		log.Printf("Failed to write withdrawal refund audit event for %s: %v", withdrawal.ID, err)
+	}
+}
+
+// notifyStatusChange sends the withdrawal's current status to the user's webhook endpoints
+func (j *WithdrawalJob) notifyStatusChange(withdrawal *models.Withdrawal) {
+	if j.webhookSvc == nil {
+		return
+	}
+
+	var eventType models.WebhookEventType
+	switch withdrawal.Status {
+	case "processing":
+		eventType = models.WebhookEventWithdrawalProcessing
+	case "completed":
+		eventType = models.WebhookEventWithdrawalCompleted
+	case "failed", "blocked":
+		eventType = models.WebhookEventWithdrawalFailed
+	default:
+		return
+	}
+
+	if err := j.webhookSvc.Dispatch(withdrawal.UserID, eventType, map[string]interface{}{
+		"withdrawal_id":  withdrawal.ID.String(),
+		"reference":      withdrawal.Reference,
+		"amount":         withdrawal.Amount,
+		"currency":       string(withdrawal.Currency),
+		"method":         withdrawal.Method,
+		"status":         withdrawal.Status,
+		"failure_reason": withdrawal.FailureReason,
+	}); err != nil {
+		log.Printf("Failed to dispatch withdrawal webhook for %s: %v", withdrawal.ID, err)
+	}
+}
+
 // checkBankTransferStatus checks the status of a bank transfer with the provider
 func (j *WithdrawalJob) checkBankTransferStatus(_ context.Context, _ *models.Withdrawal) (bool, error) {
 	// In a real implementation, you would use the payment provider API to check the status
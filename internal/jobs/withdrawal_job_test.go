@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/screening"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/revaspay/backend/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withdrawalJobFor returns a withdrawal processing job for the withdrawal
+func withdrawalJobFor(t *testing.T, withdrawal *models.Withdrawal) *queue.Job {
+	payload, err := json.Marshal(WithdrawalJobPayload{WithdrawalID: withdrawal.ID})
+	require.NoError(t, err)
+	return &queue.Job{Type: queue.JobType(WithdrawalProcessJobType), Payload: payload}
+}
+
+// A withdrawal that debited the wallet up front is credited back with its failure, once,
+// however many times the refund runs
+func TestFailedWithdrawalIsRefundedOnce(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+	walletSvc := wallet.NewWalletService(db)
+	job := NewWithdrawalJob(db, &recordingQueue{}, nil, walletSvc)
+
+	w, err := walletSvc.GetOrCreateWallet(user.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	_, err = walletSvc.Credit(w.ID, 100, "payment", models.LedgerCategorySales, "PAY-1", "Payment", nil)
+	require.NoError(t, err)
+	_, err = walletSvc.Debit(w.ID, 40, "withdrawal", models.LedgerCategoryPayout, "WD-LEGACY", "Withdrawal", nil)
+	require.NoError(t, err)
+	withdrawal := models.Withdrawal{
+		UserID:      user.ID,
+		WalletID:    w.ID,
+		Amount:      40,
+		Currency:    models.CurrencyGHS,
+		Method:      wallet.WithdrawalMethodBankTransfer,
+		Status:      "pending",
+		Reference:   "WD-LEGACY",
+		InitiatedAt: time.Now(),
+	}
+	require.NoError(t, db.Create(&withdrawal).Error)
+
+	// A suspended user's withdrawal fails
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).Update("is_active", false).Error)
+	assert.ErrorIs(t, job.ProcessWithdrawal(context.Background(), withdrawalJobFor(t, &withdrawal)), wallet.ErrAccountSuspended)
+
+	var failed models.Withdrawal
+	require.NoError(t, db.First(&failed, "id = ?", withdrawal.ID).Error)
+	assert.Equal(t, "failed", failed.Status)
+	require.NoError(t, job.refundWithdrawal(&failed, "failed"))
+	require.NoError(t, job.ProcessWithdrawal(context.Background(), withdrawalJobFor(t, &withdrawal)))
+
+	balance, err := walletSvc.GetWallet(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, balance.Balance)
+	var refunds int64
+	require.NoError(t, db.Model(&models.Transaction{}).Where("wallet_id = ? AND type = ?", w.ID, "refund").Count(&refunds).Error)
+	assert.Equal(t, int64(1), refunds)
+
+	// A withdrawal another path already moved on isn't failed or refunded here
+	assert.ErrorIs(t, job.refundWithdrawal(&failed, "processing"), errWithdrawalMovedOn)
+}
+
+// Crypto withdrawals aren't paid out unless their destination can be screened
+func TestCryptoWithdrawalRequiresScreening(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+	walletSvc := wallet.NewWalletService(db)
+	job := NewWithdrawalJob(db, &recordingQueue{}, nil, walletSvc)
+
+	w, err := walletSvc.GetOrCreateWallet(user.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	_, err = walletSvc.Credit(w.ID, 100, "payment", models.LedgerCategorySales, "PAY-1", "Payment", nil)
+	require.NoError(t, err)
+	withdraw := func() *models.Withdrawal {
+		withdrawal, err := walletSvc.CreateWithdrawal(user.ID, wallet.CreateWithdrawalRequest{
+			Currency: models.CurrencyGHS,
+			Amount:   40,
+			Method:   wallet.WithdrawalMethodCrypto,
+			Metadata: map[string]interface{}{"address": "0x52908400098527886E0F7030069857D2E4169EE7"},
+		})
+		require.NoError(t, err)
+		return withdrawal
+	}
+	status := func(withdrawal *models.Withdrawal) string {
+		var reloaded models.Withdrawal
+		require.NoError(t, db.First(&reloaded, "id = ?", withdrawal.ID).Error)
+		return reloaded.Status
+	}
+
+	unscreened := withdraw()
+	assert.ErrorIs(t, job.ProcessWithdrawal(context.Background(), withdrawalJobFor(t, unscreened)), ErrScreeningNotConfigured)
+	assert.Equal(t, "failed", status(unscreened))
+	balance, err := walletSvc.GetWallet(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, balance.Available, "the held funds are released")
+
+	job.SetScreeningService(screening.NewService(db, nil))
+	screened := withdraw()
+	require.NoError(t, job.ProcessWithdrawal(context.Background(), withdrawalJobFor(t, screened)))
+	assert.Equal(t, "processing", status(screened))
+}
+
+// Every status a withdrawal moves through is audited against its owner, along with the
+// refund of a failed one
+func TestWithdrawalStatusChangesAreAudited(t *testing.T) {
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&utils.AuditLog{}))
+	user := testutil.CreateUser(t, db)
+	walletSvc := wallet.NewWalletService(db)
+	job := NewWithdrawalJob(db, &recordingQueue{}, nil, walletSvc)
+
+	w, err := walletSvc.GetOrCreateWallet(user.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	_, err = walletSvc.Credit(w.ID, 100, "payment", models.LedgerCategorySales, "PAY-1", "Payment", nil)
+	require.NoError(t, err)
+	withdraw := func() *models.Withdrawal {
+		withdrawal, err := walletSvc.CreateWithdrawal(user.ID, wallet.CreateWithdrawalRequest{
+			Currency: models.CurrencyGHS,
+			Amount:   40,
+			Method:   wallet.WithdrawalMethodCrypto,
+			Metadata: map[string]interface{}{"address": "0x52908400098527886E0F7030069857D2E4169EE7"},
+		})
+		require.NoError(t, err)
+		return withdrawal
+	}
+	events := func(withdrawal *models.Withdrawal) []utils.AuditLog {
+		var logs []utils.AuditLog
+		require.NoError(t, db.Where("user_id = ? AND details LIKE ?", user.ID, "%"+withdrawal.ID.String()+"%").
+			Order("created_at").Find(&logs).Error)
+		return logs
+	}
+
+	failed := withdraw()
+	assert.Error(t, job.ProcessWithdrawal(context.Background(), withdrawalJobFor(t, failed)))
+	logs := events(failed)
+	require.Len(t, logs, 2)
+	assert.Equal(t, utils.AuditEventWithdrawalStatus, logs[0].EventType)
+	assert.Equal(t, failed.Reference+" changed from pending to failed", logs[0].Description)
+	assert.Equal(t, utils.AuditSeverityWarning, logs[0].Severity)
+	assert.False(t, logs[0].Success)
+	assert.Equal(t, utils.AuditEventWithdrawalRefunded, logs[1].EventType)
+
+	job.SetScreeningService(screening.NewService(db, nil))
+	paid := withdraw()
+	require.NoError(t, job.ProcessWithdrawal(context.Background(), withdrawalJobFor(t, paid)))
+	logs = events(paid)
+	require.Len(t, logs, 1)
+	// The payout provider's reference replaces ours
+	require.NoError(t, db.First(paid, "id = ?", paid.ID).Error)
+	assert.Equal(t, paid.Reference+" changed from pending to processing", logs[0].Description)
+	assert.True(t, logs[0].Success)
+}
+
+// Dry runs go through the whole withdrawal without paying out, ending in completed_dry_run with
+// the funds back where they started, and the per-withdrawal flag only counts when allowed
+func TestDryRunWithdrawalDoesNotPayOut(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+	walletSvc := wallet.NewWalletService(db)
+	job := NewWithdrawalJob(db, &recordingQueue{}, nil, walletSvc)
+	job.SetScreeningService(screening.NewService(db, nil))
+
+	w, err := walletSvc.GetOrCreateWallet(user.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	_, err = walletSvc.Credit(w.ID, 100, "payment", models.LedgerCategorySales, "PAY-1", "Payment", nil)
+	require.NoError(t, err)
+	withdraw := func() *models.Withdrawal {
+		withdrawal, err := walletSvc.CreateWithdrawal(user.ID, wallet.CreateWithdrawalRequest{
+			Currency: models.CurrencyGHS,
+			Amount:   40,
+			Method:   wallet.WithdrawalMethodCrypto,
+			Metadata: map[string]interface{}{"address": "0x52908400098527886E0F7030069857D2E4169EE7", "dry_run": true},
+		})
+		require.NoError(t, err)
+		return withdrawal
+	}
+	reload := func(withdrawal *models.Withdrawal) models.Withdrawal {
+		var reloaded models.Withdrawal
+		require.NoError(t, db.First(&reloaded, "id = ?", withdrawal.ID).Error)
+		return reloaded
+	}
+
+	job.SetDryRun(false, true)
+	dryRun := withdraw()
+	require.NoError(t, job.ProcessWithdrawal(context.Background(), withdrawalJobFor(t, dryRun)))
+	stored := reload(dryRun)
+	assert.Equal(t, WithdrawalStatusCompletedDryRun, stored.Status)
+	assert.NotNil(t, stored.CompletedAt)
+	assert.Equal(t, dryRun.Reference, stored.Reference, "no provider reference is recorded")
+	balance, err := walletSvc.GetBalance(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, balance.Balance)
+	assert.Equal(t, 100.0, balance.Available)
+
+	// Without the flag allowed, a withdrawal asking for a dry run is paid out
+	job.SetDryRun(false, false)
+	paid := withdraw()
+	require.NoError(t, job.ProcessWithdrawal(context.Background(), withdrawalJobFor(t, paid)))
+	assert.Equal(t, "processing", reload(paid).Status)
+	balance, err = walletSvc.GetBalance(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 40.0, balance.Held)
+}
@@ -0,0 +1,114 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultManualResolveAfter is how long a withdrawal must have been processing before an
+// admin can resolve it
+const DefaultManualResolveAfter = 24 * time.Hour
+
+// Resolutions an admin can give a stuck withdrawal
+const (
+	WithdrawalResolutionCompleted = "completed"
+	WithdrawalResolutionFailed    = "failed"
+)
+
+var (
+	// ErrInvalidResolution is returned when resolving a withdrawal as anything but completed or failed
+	ErrInvalidResolution = errors.New("resolution must be completed or failed")
+
+	// ErrResolutionReasonRequired is returned when resolving a withdrawal without a reason
+	ErrResolutionReasonRequired = errors.New("a reason is required to resolve a withdrawal")
+
+	// ErrWithdrawalNotProcessing is returned when resolving a withdrawal that isn't processing
+	ErrWithdrawalNotProcessing = errors.New("only processing withdrawals can be resolved")
+
+	// ErrWithdrawalNotStuck is returned when resolving a withdrawal the status check may still settle
+	ErrWithdrawalNotStuck = errors.New("withdrawal has not been processing long enough to resolve manually")
+
+	// errWithdrawalMovedOn is returned when a withdrawal changed status after it was loaded
+	errWithdrawalMovedOn = errors.New("withdrawal status changed")
+)
+
+// SetManualResolveAfter sets how long a withdrawal must have been processing before an admin
+// can resolve it. Until then the status check may still settle it.
+func (j *WithdrawalJob) SetManualResolveAfter(after time.Duration) {
+	j.manualResolveAfter = after
+}
+
+// ResolveWithdrawal lets an admin settle a withdrawal stuck in processing, such as when the
+// payout provider confirms out of band what the status check never picked up. A completed
+// withdrawal takes its held funds; a failed one returns them to the wallet. Only withdrawals
+// processing for longer than the manual resolve window can be resolved, and the status check
+// leaves them alone once they are.
+func (j *WithdrawalJob) ResolveWithdrawal(ctx context.Context, adminID, withdrawalID uuid.UUID, resolution, reason string) (*models.Withdrawal, error) {
+	if resolution != WithdrawalResolutionCompleted && resolution != WithdrawalResolutionFailed {
+		return nil, ErrInvalidResolution
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrResolutionReasonRequired
+	}
+
+	var withdrawal models.Withdrawal
+	err := j.db.Transaction(func(tx *gorm.DB) error {
+		// Locked so the status check can't settle it at the same time
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&withdrawal, "id = ?", withdrawalID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return wallet.ErrWithdrawalNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("error finding withdrawal: %w", err)
+		}
+		if withdrawal.Status != "processing" {
+			return ErrWithdrawalNotProcessing
+		}
+
+		processingSince := withdrawal.UpdatedAt
+		if withdrawal.ProcessedAt != nil {
+			processingSince = *withdrawal.ProcessedAt
+		}
+		if resolvableAt := processingSince.Add(j.manualResolveAfter); time.Now().Before(resolvableAt) {
+			return fmt.Errorf("%w: it can be resolved from %s", ErrWithdrawalNotStuck, resolvableAt.Format(time.RFC3339))
+		}
+
+		if resolution == WithdrawalResolutionCompleted {
+			return j.walletSvc.CompleteWithdrawalWithTx(tx, &withdrawal)
+		}
+		return j.walletSvc.FailWithdrawalWithTx(tx, &withdrawal, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Withdrawal %s manually resolved as %s by admin %s", withdrawal.ID, withdrawal.Status, adminID)
+	if err := j.auditLogger.LogStatusTransition(ctx, utils.AuditEventWithdrawalStatus, utils.StatusTransition{
+		EntityID:  withdrawal.ID,
+		OwnerID:   withdrawal.UserID,
+		Reference: withdrawal.Reference,
+		From:      "processing",
+		To:        withdrawal.Status,
+		Amount:    withdrawal.Amount,
+		Currency:  string(withdrawal.Currency),
+		Actor:     utils.AuditActorAdmin,
+		ActorID:   &adminID,
+		Reason:    reason,
+	}); err != nil {
+		log.Printf("Failed to write withdrawal resolution audit event for %s: %v", withdrawal.ID, err)
+	}
+	j.notifyStatusChange(&withdrawal)
+	return &withdrawal, nil
+}
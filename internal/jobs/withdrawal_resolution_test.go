@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/revaspay/backend/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveWithdrawal(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+	adminID := uuid.New()
+	walletSvc := wallet.NewWalletService(db)
+	job := NewWithdrawalJob(db, &recordingQueue{}, nil, walletSvc)
+	ctx := context.Background()
+
+	w, err := walletSvc.GetOrCreateWallet(user.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	_, err = walletSvc.Credit(w.ID, 100, "payment", models.LedgerCategorySales, "PAY-1", "Payment", nil)
+	require.NoError(t, err)
+
+	// processing starts a withdrawal processing since the given time
+	processing := func(since time.Time) *models.Withdrawal {
+		withdrawal, err := walletSvc.CreateWithdrawal(user.ID, wallet.CreateWithdrawalRequest{
+			Currency: models.CurrencyGHS,
+			Amount:   40,
+			Method:   wallet.WithdrawalMethodBankTransfer,
+		})
+		require.NoError(t, err)
+		require.NoError(t, db.Model(withdrawal).Updates(map[string]interface{}{"status": "processing", "processed_at": since}).Error)
+		return withdrawal
+	}
+
+	recent := processing(time.Now().Add(-time.Hour))
+	_, err = job.ResolveWithdrawal(ctx, adminID, recent.ID, WithdrawalResolutionCompleted, "Confirmed by the bank")
+	assert.ErrorIs(t, err, ErrWithdrawalNotStuck)
+	_, err = job.ResolveWithdrawal(ctx, adminID, recent.ID, WithdrawalResolutionCompleted, " ")
+	assert.ErrorIs(t, err, ErrResolutionReasonRequired)
+
+	// Completed: the held funds are taken
+	stuck := processing(time.Now().Add(-48 * time.Hour))
+	resolved, err := job.ResolveWithdrawal(ctx, adminID, stuck.ID, WithdrawalResolutionCompleted, "Confirmed by the bank")
+	require.NoError(t, err)
+	assert.Equal(t, "completed", resolved.Status)
+	_, err = job.ResolveWithdrawal(ctx, adminID, stuck.ID, WithdrawalResolutionFailed, "Reversed by the bank")
+	assert.ErrorIs(t, err, ErrWithdrawalNotProcessing)
+
+	balance, err := walletSvc.GetWallet(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, balance.Balance)
+
+	// Failed: the held funds are returned
+	job.SetManualResolveAfter(0)
+	resolved, err = job.ResolveWithdrawal(ctx, adminID, recent.ID, WithdrawalResolutionFailed, "Rejected by the bank")
+	require.NoError(t, err)
+	assert.Equal(t, "failed", resolved.Status)
+	assert.Equal(t, "Rejected by the bank", resolved.FailureReason)
+
+	balance, err = walletSvc.GetWallet(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, balance.Balance)
+	assert.Equal(t, 60.0, balance.Available)
+
+	// Each resolution is audited with the admin and reason
+	var audits []utils.AuditLog
+	require.NoError(t, db.Where("event_type = ?", utils.AuditEventWithdrawalStatus).Order("timestamp").Find(&audits).Error)
+	require.Len(t, audits, 2)
+	assert.Contains(t, audits[1].Details, adminID.String())
+	assert.Contains(t, audits[1].Details, "Rejected by the bank")
+}
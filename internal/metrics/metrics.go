@@ -0,0 +1,140 @@
+// Package metrics defines the Prometheus metrics exported on /metrics
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name
+const namespace = "revaspay"
+
+// Job outcomes
+const (
+	JobOutcomeSuccess = "success"
+	JobOutcomeFailure = "failure"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	jobsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_processed_total",
+		Help:      "Background jobs processed by type and outcome.",
+	}, []string{"type", "outcome"})
+
+	jobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Background job processing time by type and outcome.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"type", "outcome"})
+
+	walletMovements = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wallet_movements_total",
+		Help:      "Wallet credits and debits by direction and currency.",
+	}, []string{"direction", "currency"})
+
+	walletAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wallet_movement_amount_total",
+		Help:      "Sum of wallet credit and debit amounts by direction and currency.",
+	}, []string{"direction", "currency"})
+
+	circuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "Provider circuit breaker state by breaker: 0 closed, 1 half open, 2 open.",
+	}, []string{"name"})
+
+	circuitBreakerRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_rejections_total",
+		Help:      "Provider calls short-circuited by an open circuit breaker, by breaker.",
+	}, []string{"name"})
+)
+
+// circuitBreakerStates maps breaker states to the values of the circuit_breaker_state gauge
+var circuitBreakerStates = map[string]float64{
+	"closed":    0,
+	"half_open": 1,
+	"open":      2,
+}
+
+// ObserveHTTPRequest records a served request. route should be the route pattern rather
+// than the raw path so IDs in URLs don't create a series each.
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// ObserveJob records a processed background job
+func ObserveJob(jobType string, err error, duration time.Duration) {
+	outcome := JobOutcomeSuccess
+	if err != nil {
+		outcome = JobOutcomeFailure
+	}
+	jobsProcessed.WithLabelValues(jobType, outcome).Inc()
+	jobDuration.WithLabelValues(jobType, outcome).Observe(duration.Seconds())
+}
+
+// ObserveWalletMovement records a wallet balance change. Positive amounts are credits and
+// negative amounts debits; the amount total is always positive.
+func ObserveWalletMovement(currency string, amount float64) {
+	direction := "credit"
+	if amount < 0 {
+		direction = "debit"
+		amount = -amount
+	}
+	walletMovements.WithLabelValues(direction, currency).Inc()
+	walletAmount.WithLabelValues(direction, currency).Add(amount)
+}
+
+// ObserveCircuitBreakerState records a provider circuit breaker's new state
+func ObserveCircuitBreakerState(name, state string) {
+	circuitBreakerState.WithLabelValues(name).Set(circuitBreakerStates[state])
+}
+
+// ObserveCircuitBreakerRejection records a call rejected by an open circuit breaker
+func ObserveCircuitBreakerRejection(name string) {
+	circuitBreakerRejections.WithLabelValues(name).Inc()
+}
+
+// Handler serves the registered metrics. When token is set, scrapers must send it as a
+// bearer token.
+func Handler(token string) gin.HandlerFunc {
+	metricsHandler := promhttp.Handler()
+	return func(c *gin.Context) {
+		if token != "" {
+			provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+				return
+			}
+		}
+		metricsHandler.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHandlerRequiresToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/metrics", Handler("secret"))
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"token without scheme", "secret", http.StatusUnauthorized},
+		{"valid token", "Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, w.Code)
+		}
+	}
+}
+
+func TestObserveWalletMovement(t *testing.T) {
+	ObserveWalletMovement("GHS", 25)
+	ObserveWalletMovement("GHS", -10)
+
+	if got := testutil.ToFloat64(walletAmount.WithLabelValues("credit", "GHS")); got != 25 {
+		t.Errorf("expected credit total 25, got %v", got)
+	}
+	if got := testutil.ToFloat64(walletAmount.WithLabelValues("debit", "GHS")); got != 10 {
+		t.Errorf("expected debit total 10, got %v", got)
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// accountStatusTTL is how long a user's account status is cached, so a suspension made
+// through another instance applies here within this long
+const accountStatusTTL = 30 * time.Second
+
+// accountStatusPruneSize is how many cached statuses trigger dropping the expired ones
+const accountStatusPruneSize = 10000
+
+// accountState is what AuthMiddleware knows about a token's user
+type accountState int
+
+const (
+	accountActive accountState = iota
+	accountSuspended
+	accountMissing // Deleted since the token was issued
+)
+
+type accountStatusEntry struct {
+	state     accountState
+	checkedAt time.Time
+}
+
+// accountStatusCache looks up and caches whether users are active
+type accountStatusCache struct {
+	db *gorm.DB
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]accountStatusEntry
+}
+
+var (
+	accountStatusMu sync.RWMutex
+	accountStatus   *accountStatusCache
+)
+
+// ConfigureAccountStatus makes AuthMiddleware reject tokens of suspended and deleted users,
+// looking them up in db. Until it's called tokens are checked only for validity.
+func ConfigureAccountStatus(db *gorm.DB) {
+	accountStatusMu.Lock()
+	defer accountStatusMu.Unlock()
+	accountStatus = &accountStatusCache{
+		db:      db,
+		entries: make(map[uuid.UUID]accountStatusEntry),
+	}
+}
+
+// ForgetAccountStatus drops a user's cached account status so a suspension or reactivation
+// applies on this instance immediately
+func ForgetAccountStatus(userID uuid.UUID) {
+	if cache := currentAccountStatus(); cache != nil {
+		cache.mu.Lock()
+		delete(cache.entries, userID)
+		cache.mu.Unlock()
+	}
+}
+
+func currentAccountStatus() *accountStatusCache {
+	accountStatusMu.RLock()
+	defer accountStatusMu.RUnlock()
+	return accountStatus
+}
+
+// lookup returns the user's account state, from the cache while it's fresh
+func (c *accountStatusCache) lookup(userID uuid.UUID) (accountState, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[userID]
+	c.mu.Unlock()
+	if ok && now.Sub(entry.checkedAt) < accountStatusTTL {
+		return entry.state, nil
+	}
+
+	var user models.User
+	state := accountActive
+	err := c.db.Select("id", "is_active").First(&user, "id = ?", userID).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		state = accountMissing
+	case err != nil:
+		return accountActive, err
+	case !user.IsActive:
+		state = accountSuspended
+	}
+
+	c.mu.Lock()
+	if len(c.entries) >= accountStatusPruneSize {
+		for id, e := range c.entries {
+			if now.Sub(e.checkedAt) >= accountStatusTTL {
+				delete(c.entries, id)
+			}
+		}
+	}
+	c.entries[userID] = accountStatusEntry{state: state, checkedAt: now}
+	c.mu.Unlock()
+	return state, nil
+}
@@ -1,16 +1,33 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
 	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
 )
 
-// Use the Claims type from utils package
+// Context keys set for authenticated requests. AuthMiddleware sets the user ID, email, admin
+// flag, role permissions and session from the token; LoadUser adds the user record. Read them
+// with UserID, User and HasPermission rather than directly.
+const (
+	ContextUserID      = "user_id"     // string
+	ContextUserUUID    = "user_uuid"   // uuid.UUID
+	ContextEmail       = "email"       // string
+	ContextIsAdmin     = "is_admin"    // bool
+	ContextPermissions = "permissions" // map[models.Permission]bool
+	ContextSessionID   = "session_id"  // uuid.UUID, only set for tokens issued to a session
+	ContextUser        = "user"        // models.User, only set by LoadUser
+)
 
-// AuthMiddleware verifies JWT tokens and adds user info to context
+// AuthMiddleware verifies JWT tokens and adds user info to context. Once ConfigureAccountStatus
+// has been called, tokens of suspended or deleted users are rejected too, and once
+// ConfigureSessionActivity has been called so are tokens of sessions that are no longer active.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := extractToken(c)
@@ -28,20 +45,151 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 		
+		if cache := currentAccountStatus(); cache != nil {
+			state, err := cache.lookup(claims.UserID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+				c.Abort()
+				return
+			}
+			switch state {
+			case accountSuspended:
+				c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+				c.Abort()
+				return
+			case accountMissing:
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+				c.Abort()
+				return
+			}
+		}
+		
 		// Set user info in context
-		c.Set("user_id", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("is_admin", claims.IsAdmin)
+		c.Set(ContextUserID, claims.UserID.String())
+		c.Set(ContextUserUUID, claims.UserID)
+		c.Set(ContextEmail, claims.Email)
+		c.Set(ContextIsAdmin, claims.IsAdmin)
+		c.Set(ContextPermissions, permissionSet(claims.Permissions))
+
+		if claims.SessionID != uuid.Nil {
+			c.Set(ContextSessionID, claims.SessionID)
+			if tracker := currentSessionActivity(); tracker != nil && !tracker.touch(c, claims.SessionID) {
+				return
+			}
+		}
 		
 		c.Next()
 	}
 }
 
-// AdminMiddleware ensures the user has admin privileges
+// LoadUser loads the authenticated user's record into the context for handlers that need
+// more than the ID. It must run after AuthMiddleware, and rejects users that no longer exist
+// or have been deactivated.
+func LoadUser(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := UserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, "id = ?", userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+			}
+			c.Abort()
+			return
+		}
+		if !user.IsActive {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+			c.Abort()
+			return
+		}
+
+		c.Set(ContextUser, user)
+		c.Next()
+	}
+}
+
+// UserID returns the authenticated user's ID. ok is false for anonymous requests.
+func UserID(c *gin.Context) (uuid.UUID, bool) {
+	if value, exists := c.Get(ContextUserUUID); exists {
+		if id, ok := value.(uuid.UUID); ok {
+			return id, true
+		}
+	}
+
+	// Contexts built without AuthMiddleware, such as in tests, may only have the string ID
+	value, exists := c.Get(ContextUserID)
+	if !exists {
+		return uuid.Nil, false
+	}
+	switch id := value.(type) {
+	case uuid.UUID:
+		return id, true
+	case string:
+		parsed, err := uuid.Parse(id)
+		return parsed, err == nil
+	}
+	return uuid.Nil, false
+}
+
+// User returns the authenticated user's record loaded by LoadUser
+func User(c *gin.Context) (*models.User, bool) {
+	value, exists := c.Get(ContextUser)
+	if !exists {
+		return nil, false
+	}
+	user, ok := value.(models.User)
+	if !ok {
+		return nil, false
+	}
+	return &user, true
+}
+
+// HasPermission reports whether the authenticated user holds a permission. Admins hold
+// every permission.
+func HasPermission(c *gin.Context, permission models.Permission) bool {
+	if c.GetBool(ContextIsAdmin) {
+		return true
+	}
+	permissions, _ := c.Get(ContextPermissions)
+	set, _ := permissions.(map[models.Permission]bool)
+	return set[permission]
+}
+
+// RequirePermission rejects requests from users without the permission. It must run after
+// AuthMiddleware.
+func RequirePermission(permission models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions", "required_permission": permission})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// permissionSet indexes the permissions carried in a token
+func permissionSet(permissions []string) map[models.Permission]bool {
+	set := make(map[models.Permission]bool, len(permissions))
+	for _, permission := range permissions {
+		set[models.Permission(permission)] = true
+	}
+	return set
+}
+
+// AdminMiddleware ensures the user has admin privileges. Prefer RequirePermission, which also
+// admits staff whose roles grant the permission.
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		isAdmin, exists := c.Get("is_admin")
-		if !exists || !isAdmin.(bool) {
+		if !c.GetBool(ContextIsAdmin) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
 			c.Abort()
 			return
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/revaspay/backend/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Handlers behind AuthMiddleware and LoadUser read the token's user, and LoadUser refuses
+// users that are gone or deactivated
+func TestAuthContextContract(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+	inactive := testutil.CreateUser(t, db)
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", inactive.ID).Update("is_active", false).Error)
+
+	router := gin.New()
+	router.GET("/me", AuthMiddleware(), LoadUser(db), func(c *gin.Context) {
+		userID, ok := UserID(c)
+		require.True(t, ok)
+		loaded, ok := User(c)
+		require.True(t, ok)
+		assert.Equal(t, userID, loaded.ID)
+		assert.Equal(t, user.Email, c.GetString(ContextEmail))
+		assert.True(t, HasPermission(c, models.PermissionKYCView))
+		assert.False(t, HasPermission(c, models.PermissionKYCApprove))
+		c.Status(http.StatusNoContent)
+	})
+	get := func(userID uuid.UUID, email string) int {
+		tokens, err := utils.GenerateTokenPair(userID, uuid.New(), email, false, []string{string(models.PermissionKYCView)})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, get(user.ID, user.Email))
+	assert.Equal(t, http.StatusForbidden, get(inactive.ID, inactive.Email))
+	assert.Equal(t, http.StatusUnauthorized, get(uuid.New(), "gone@example.com"))
+
+	// Contexts that only carry the string ID still resolve, and anonymous ones don't
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	_, ok := UserID(c)
+	assert.False(t, ok)
+	_, ok = User(c)
+	assert.False(t, ok)
+	c.Set(ContextUserID, user.ID.String())
+	id, ok := UserID(c)
+	assert.True(t, ok)
+	assert.Equal(t, user.ID, id)
+	c.Set(ContextUserID, "not-a-uuid")
+	_, ok = UserID(c)
+	assert.False(t, ok)
+}
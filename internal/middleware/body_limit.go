@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxBodyBytes rejects requests whose body is larger than limit bytes with 413. File uploads
+// (multipart/form-data requests) may be up to uploadLimit bytes instead. Requests that declare
+// a larger Content-Length are rejected up front; chunked bodies are cut off at the limit, and
+// reads past it fail with an error IsBodyTooLarge recognizes.
+func MaxBodyBytes(limit, uploadLimit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		allowed := limit
+		if c.ContentType() == gin.MIMEMultipartPOSTForm {
+			allowed = uploadLimit
+		}
+		if c.Request.ContentLength > allowed {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, allowed)
+		c.Next()
+	}
+}
+
+// IsBodyTooLarge reports whether err came from reading past a MaxBodyBytes limit
+func IsBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxBodyBytes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(MaxBodyBytes(10, 100))
+	router.POST("/data", func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			if IsBodyTooLarge(err) {
+				c.Status(http.StatusRequestEntityTooLarge)
+				return
+			}
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		chunked     bool
+		status      int
+	}{
+		{"within limit", "application/json", "small", false, http.StatusOK},
+		{"declared too large", "application/json", strings.Repeat("x", 11), false, http.StatusRequestEntityTooLarge},
+		{"chunked too large", "application/json", strings.Repeat("x", 11), true, http.StatusRequestEntityTooLarge},
+		{"upload within upload limit", "multipart/form-data; boundary=x", strings.Repeat("x", 50), false, http.StatusOK},
+		{"upload too large", "multipart/form-data; boundary=x", strings.Repeat("x", 101), true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/data", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		if tt.chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, tt.name)
+	}
+}
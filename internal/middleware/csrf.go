@@ -5,6 +5,9 @@ import (
 	"encoding/base64"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/revaspay/backend/internal/utils"
@@ -60,11 +63,9 @@ func CSRFMiddleware(config CSRFConfig) gin.HandlerFunc {
 
 	return func(c *gin.Context) {
 		// Skip CSRF check for excluded paths
-		for _, path := range config.ExcludePaths {
-			if c.Request.URL.Path == path {
-				c.Next()
-				return
-			}
+		if csrfPathExcluded(c.Request.URL.Path, config.ExcludePaths) {
+			c.Next()
+			return
 		}
 
 		// Skip CSRF check for safe methods (GET, HEAD, OPTIONS, TRACE)
@@ -72,8 +73,6 @@ func CSRFMiddleware(config CSRFConfig) gin.HandlerFunc {
 		   c.Request.Method == "HEAD" || 
 		   c.Request.Method == "OPTIONS" || 
 		   c.Request.Method == "TRACE" {
-			// For GET requests, ensure a CSRF token exists
-			ensureCSRFToken(c, config)
 			c.Next()
 			return
 		}
@@ -89,7 +88,7 @@ func CSRFMiddleware(config CSRFConfig) gin.HandlerFunc {
 		}
 
 		// Validate the token
-		if !validateCSRFToken(token, cookie, config.Secret) {
+		if !validateCSRFToken(token, cookie, config.Secret, config.CookieMaxAge) {
 			config.ErrorFunc(c)
 			return
 		}
@@ -99,52 +98,51 @@ func CSRFMiddleware(config CSRFConfig) gin.HandlerFunc {
 	}
 }
 
-// ensureCSRFToken ensures a CSRF token exists in the cookie
-func ensureCSRFToken(c *gin.Context, config CSRFConfig) {
-	// Check if the token already exists
-	_, err := c.Cookie(config.CookieName)
-	if err == nil {
-		// Token exists, no need to create a new one
-		return
-	}
-
-	// Generate a new token
-	token, err := generateCSRFToken(config.TokenLength)
-	if err != nil {
-		// If we can't generate a token, fail open (better than blocking all requests)
-		return
+// csrfPathExcluded reports whether path bypasses CSRF checks. A pattern ending in "/*"
+// excludes everything under that prefix.
+func csrfPathExcluded(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if path == pattern {
+			return true
+		}
 	}
-
-	// Create a signed token
-	signedToken := utils.SignHMAC(token, config.Secret)
-
-	// Set the cookie
-	c.SetSameSite(config.CookieSameSite)
-	c.SetCookie(
-		config.CookieName,
-		signedToken,
-		config.CookieMaxAge,
-		config.CookiePath,
-		config.CookieDomain,
-		config.CookieSecure,
-		config.CookieHTTPOnly,
-	)
+	return false
 }
 
-// generateCSRFToken generates a random token
+// generateCSRFToken generates a random token stamped with the time it was issued
 func generateCSRFToken(length int) (string, error) {
 	bytes := make([]byte, length)
 	_, err := rand.Read(bytes)
 	if err != nil {
 		return "", err
 	}
-	return base64.StdEncoding.EncodeToString(bytes), nil
+	return base64.StdEncoding.EncodeToString(bytes) + "." + strconv.FormatInt(time.Now().Unix(), 10), nil
 }
 
-// validateCSRFToken validates a CSRF token
-func validateCSRFToken(token, cookie, secret string) bool {
+// validateCSRFToken checks the token against the signed cookie and that it was issued
+// within maxAge seconds, so a token stops working once its cookie would have expired
+func validateCSRFToken(token, cookie, secret string, maxAge int) bool {
 	// Constant-time comparison to prevent timing attacks
-	return utils.VerifyHMAC(token, cookie, secret)
+	if !utils.VerifyHMAC(token, cookie, secret) {
+		return false
+	}
+	if maxAge <= 0 {
+		return true
+	}
+
+	separator := strings.LastIndex(token, ".")
+	if separator < 0 {
+		return false
+	}
+	issuedAt, err := strconv.ParseInt(token[separator+1:], 10, 64)
+	if err != nil {
+		return false
+	}
+	return time.Since(time.Unix(issuedAt, 0)) <= time.Duration(maxAge)*time.Second
 }
 
 // GetCSRFToken returns the current CSRF token
@@ -156,7 +154,8 @@ func GetCSRFToken(c *gin.Context, config CSRFConfig) (string, error) {
 	return token, nil
 }
 
-// RegenerateCSRFToken regenerates the CSRF token
+// RegenerateCSRFToken issues a new CSRF token, replacing the previous one. The cookie holds
+// the token's signature; the returned token is what the client echoes in the header.
 func RegenerateCSRFToken(c *gin.Context, config CSRFConfig) (string, error) {
 	// Delete the existing cookie
 	c.SetCookie(
@@ -190,5 +189,30 @@ func RegenerateCSRFToken(c *gin.Context, config CSRFConfig) (string, error) {
 		config.CookieHTTPOnly,
 	)
 
-	return signedToken, nil
+	return token, nil
+}
+
+// CSRFTokenHandler issues a CSRF token to the client. The signed cookie is HttpOnly, so
+// the client reads the token from the response and sends it in the config's header
+// (X-CSRF-Token by default) on every POST, PUT, PATCH and DELETE, along with the cookie.
+// Every call rotates the token, and a token expires with its cookie, so clients should
+// fetch one when they load, after signing in and whenever a request fails CSRF checks.
+func CSRFTokenHandler(config CSRFConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := RegenerateCSRFToken(c, config)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue CSRF token"})
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data": gin.H{
+				"csrf_token":  token,
+				"header_name": config.HeaderName,
+				"expires_in":  config.CookieMaxAge,
+			},
+		})
+	}
 }
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A token issued by the CSRF endpoint lets the client make unsafe requests with its cookie,
+// until the token is rotated
+func TestCSRFTokenHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := DefaultCSRFConfig()
+	config.Secret = "csrf-secret"
+	config.ExcludePaths = []string{"/webhooks/*"}
+
+	router := gin.New()
+	router.Use(CSRFMiddleware(config))
+	router.GET("/csrf-token", CSRFTokenHandler(config))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/transfers", ok)
+	router.POST("/webhooks/paystack", ok)
+
+	// issue fetches a token, returning it with the cookie it was signed into
+	issue := func() (string, *http.Cookie) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/csrf-token", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+		var response struct {
+			Data struct {
+				CSRFToken  string `json:"csrf_token"`
+				HeaderName string `json:"header_name"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "X-CSRF-Token", response.Data.HeaderName)
+		cookies := w.Result().Cookies()
+		require.NotEmpty(t, cookies)
+		cookie := cookies[len(cookies)-1]
+		assert.True(t, cookie.HttpOnly)
+		assert.NotEqual(t, response.Data.CSRFToken, cookie.Value, "the cookie holds the signature, not the token")
+		return response.Data.CSRFToken, cookie
+	}
+	post := func(path, token string, cookie *http.Cookie) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if token != "" {
+			req.Header.Set("X-CSRF-Token", token)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	token, cookie := issue()
+	assert.Equal(t, http.StatusOK, post("/transfers", token, cookie))
+	assert.Equal(t, http.StatusForbidden, post("/transfers", "", cookie))
+	assert.Equal(t, http.StatusForbidden, post("/transfers", token, nil))
+	assert.Equal(t, http.StatusForbidden, post("/transfers", token+"x", cookie))
+	assert.Equal(t, http.StatusOK, post("/webhooks/paystack", "", nil))
+
+	// A rotated token replaces the old one
+	rotated, rotatedCookie := issue()
+	assert.Equal(t, http.StatusForbidden, post("/transfers", token, rotatedCookie))
+	assert.Equal(t, http.StatusOK, post("/transfers", rotated, rotatedCookie))
+}
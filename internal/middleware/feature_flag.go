@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureChecker reports whether a feature flag is switched on
+type FeatureChecker interface {
+	IsEnabled(key string) bool
+}
+
+// RequireFeature rejects requests with 404 and a feature_disabled error while the feature
+// flag is off. The flag is checked on every request, so toggling it applies without a restart.
+func RequireFeature(flags FeatureChecker, key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !flags.IsEnabled(key) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": gin.H{
+					"code":    "feature_disabled",
+					"message": "This feature is not available",
+					"details": gin.H{"feature": key},
+				},
+			})
+			return
+		}
+		c.Next()
+	}
+}
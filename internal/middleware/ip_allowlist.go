@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IPAllowList only lets through requests from the given CIDRs or bare IPs, rejecting others
+// with 403. The client IP is the connecting address unless that is one of trustedProxies,
+// in which case X-Forwarded-For is walked from the right to the first untrusted hop. With
+// no trusted proxies X-Forwarded-For is ignored, so it can't be spoofed. An empty allow
+// list lets every request through, so routes can be restricted one provider at a time, but
+// a list with any invalid entry rejects every request rather than silently loosening.
+func IPAllowList(cidrs []string, trustedProxies []string) gin.HandlerFunc {
+	allowed, invalid := parseNetworks(cidrs)
+	if len(invalid) > 0 {
+		log.Printf("Rejecting all requests: invalid IP allow list entries %q", invalid)
+		return func(c *gin.Context) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		}
+	}
+	if len(allowed) == 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	// Invalid proxies are only logged: an untrusted proxy's X-Forwarded-For is ignored anyway
+	proxies, _ := parseNetworks(trustedProxies)
+
+	return func(c *gin.Context) {
+		ip := requestIP(c.Request, proxies)
+		if ip == nil || !containsIP(allowed, ip) {
+			log.Printf("Rejected request to %s from disallowed IP %v", c.Request.URL.Path, ip)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// parseNetworks parses CIDRs and bare IPs, logging and returning invalid entries separately
+func parseNetworks(entries []string) (networks []*net.IPNet, invalid []string) {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				log.Printf("Invalid IP allow list entry %q", entry)
+				invalid = append(invalid, entry)
+				continue
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			bits := len(ip) * 8
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("Invalid IP allow list entry %q: %v", entry, err)
+			invalid = append(invalid, entry)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks, invalid
+}
+
+// requestIP returns the client's IP, trusting X-Forwarded-For only when the connection
+// comes from a trusted proxy
+func requestIP(r *http.Request, trustedProxies []*net.IPNet) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !containsIP(trustedProxies, ip) {
+		return ip
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// A malformed hop means the header can't be trusted past this point
+			return ip
+		}
+		ip = hop
+		if !containsIP(trustedProxies, hop) {
+			return hop
+		}
+	}
+	return ip
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPAllowList(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(cidrs, proxies []string) *gin.Engine {
+		router := gin.New()
+		router.POST("/webhooks/paystack", IPAllowList(cidrs, proxies), func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+	restricted := newRouter([]string{"52.31.139.75", "41.58.96.0/24"}, []string{"10.0.0.0/8"})
+
+	tests := []struct {
+		name         string
+		router       *gin.Engine
+		remoteAddr   string
+		forwardedFor string
+		status       int
+	}{
+		{"allowed IP", restricted, "52.31.139.75:443", "", http.StatusOK},
+		{"allowed range", restricted, "41.58.96.20:443", "", http.StatusOK},
+		{"other IP", restricted, "198.51.100.7:443", "", http.StatusForbidden},
+		{"spoofed header from an untrusted client", restricted, "198.51.100.7:443", "52.31.139.75", http.StatusForbidden},
+		{"allowed IP behind a trusted proxy", restricted, "10.0.0.5:443", "52.31.139.75, 10.0.0.9", http.StatusOK},
+		{"spoofed hop before the proxy's client", restricted, "10.0.0.5:443", "52.31.139.75, 198.51.100.7", http.StatusForbidden},
+		{"malformed header behind a trusted proxy", restricted, "10.0.0.5:443", "garbage", http.StatusForbidden},
+		{"empty allow list", newRouter(nil, nil), "198.51.100.7:443", "", http.StatusOK},
+		{"blank entries only", newRouter([]string{" ", ""}, nil), "198.51.100.7:443", "", http.StatusOK},
+		// A typo must not lift the restriction, even for IPs the valid entries allow
+		{"only invalid entries", newRouter([]string{"52.31.139.75/33"}, nil), "198.51.100.7:443", "", http.StatusForbidden},
+		{"some invalid entries", newRouter([]string{"52.31.139.75", "not-an-ip"}, nil), "52.31.139.75:443", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/paystack", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		tt.router.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, tt.name)
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/metrics"
+)
+
+// unmatchedRoute labels requests that didn't match any route, so scans of random paths
+// share one series
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware records each request's count and latency by route pattern and status.
+// Register it before other middleware so rejected requests are counted too.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
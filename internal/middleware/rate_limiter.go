@@ -1,7 +1,11 @@
 package middleware
 
 import (
+	"context"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,13 +17,19 @@ import (
 type RateLimiter struct {
 	ipLimiters     map[string]*rate.Limiter
 	authLimiters   map[string]*rate.Limiter
+	userLimiters   map[string]*rate.Limiter
 	ipMutex        sync.RWMutex
 	authMutex      sync.RWMutex
+	userMutex      sync.Mutex
 	ipLimiterRate  rate.Limit
 	authLimiterRate rate.Limit
 	ipBurst        int
 	authBurst      int
 	cleanupTicker  *time.Ticker
+
+	// redis holds the buckets when limits are shared across instances; the in-memory
+	// limiters are used when it's nil or unreachable
+	redis *redisLimitStore
 }
 
 // NewRateLimiter creates a new rate limiter
@@ -27,6 +37,7 @@ func NewRateLimiter(ipRequestsPerSecond, authRequestsPerMinute float64, ipBurst,
 	limiter := &RateLimiter{
 		ipLimiters:     make(map[string]*rate.Limiter),
 		authLimiters:   make(map[string]*rate.Limiter),
+		userLimiters:   make(map[string]*rate.Limiter),
 		ipLimiterRate:  rate.Limit(ipRequestsPerSecond),
 		authLimiterRate: rate.Limit(authRequestsPerMinute / 60), // Convert to per-second rate
 		ipBurst:        ipBurst,
@@ -50,6 +61,10 @@ func (rl *RateLimiter) cleanup() {
 		rl.authMutex.Lock()
 		rl.authLimiters = make(map[string]*rate.Limiter)
 		rl.authMutex.Unlock()
+
+		rl.userMutex.Lock()
+		rl.userLimiters = make(map[string]*rate.Limiter)
+		rl.userMutex.Unlock()
 	}
 }
 
@@ -90,17 +105,101 @@ func (rl *RateLimiter) getAuthLimiter(key string) *rate.Limiter {
 	return limiter
 }
 
+// take removes a token from the key's bucket, in Redis when the limiter is shared and
+// otherwise from the in-memory limiter returned by local. It returns how long until a
+// token is available when the bucket is empty; a negative wait means it never refills.
+func (rl *RateLimiter) take(ctx context.Context, key string, perSecond rate.Limit, burst int, local func() *rate.Limiter) (bool, time.Duration) {
+	if rl.redis != nil {
+		allowed, wait, err := rl.redis.take(ctx, key, float64(perSecond), burst)
+		if err == nil {
+			return allowed, wait
+		}
+		log.Printf("Rate limiting %s in memory, Redis unavailable: %v", key, err)
+	}
+
+	now := time.Now()
+	reservation := local().ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, -1
+	}
+	if wait := reservation.DelayFrom(now); wait > 0 {
+		reservation.CancelAt(now)
+		return false, wait
+	}
+	return true, 0
+}
+
+// takeIP takes a token from the client IP's bucket
+func (rl *RateLimiter) takeIP(ctx context.Context, ip string) (bool, time.Duration) {
+	return rl.take(ctx, "ip:"+ip, rl.ipLimiterRate, rl.ipBurst, func() *rate.Limiter {
+		return rl.getIPLimiter(ip)
+	})
+}
+
+// rejectRateLimited aborts the request with a 429 and a Retry-After header for when the
+// next request will be allowed
+func rejectRateLimited(c *gin.Context, wait time.Duration, message string) {
+	// A bucket that never refills, such as one with a zero rate, has no meaningful wait
+	retryAfter := 60
+	if wait >= 0 {
+		retryAfter = int(math.Max(1, math.Ceil(wait.Seconds())))
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": message,
+	})
+	c.Abort()
+}
+
+// UserRateLimit is a per-user token bucket refilled at RequestsPerMinute and holding up to Burst requests
+type UserRateLimit struct {
+	RequestsPerMinute float64
+	Burst             int
+}
+
+// getUserLimiter returns the rate limiter for a user in a scope
+func (rl *RateLimiter) getUserLimiter(key string, limit UserRateLimit) *rate.Limiter {
+	rl.userMutex.Lock()
+	defer rl.userMutex.Unlock()
+
+	limiter, exists := rl.userLimiters[key]
+	if !exists {
+		limiter = rate.NewLimiter(rate.Limit(limit.RequestsPerMinute/60), limit.Burst)
+		rl.userLimiters[key] = limiter
+	}
+
+	return limiter
+}
+
+// UserRateLimiterMiddleware limits requests per authenticated user, falling back to the
+// client IP for anonymous requests, so it must run after AuthMiddleware. Each scope has
+// its own buckets, so using up the payment allowance doesn't block withdrawals. Rejected
+// requests get a 429 with Retry-After set to when the next request will be allowed.
+func (rl *RateLimiter) UserRateLimiterMiddleware(scope string, limit UserRateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := scope + ":ip:" + c.ClientIP()
+		if userID, ok := UserID(c); ok {
+			key = scope + ":user:" + userID.String()
+		}
+
+		perSecond := rate.Limit(limit.RequestsPerMinute / 60)
+		if allowed, wait := rl.take(c.Request.Context(), key, perSecond, limit.Burst, func() *rate.Limiter {
+			return rl.getUserLimiter(key, limit)
+		}); !allowed {
+			rejectRateLimited(c, wait, "rate limit exceeded, please try again later")
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // IPRateLimiterMiddleware limits requests based on IP address
 func (rl *RateLimiter) IPRateLimiterMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ip := c.ClientIP()
-		limiter := rl.getIPLimiter(ip)
-		
-		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "rate limit exceeded",
-			})
-			c.Abort()
+		if allowed, wait := rl.takeIP(c.Request.Context(), ip); !allowed {
+			rejectRateLimited(c, wait, "rate limit exceeded")
 			return
 		}
 		
@@ -114,12 +213,8 @@ func (rl *RateLimiter) AuthRateLimiterMiddleware() gin.HandlerFunc {
 		ip := c.ClientIP()
 		
 		// For auth endpoints, we want to rate limit by IP first
-		ipLimiter := rl.getIPLimiter(ip)
-		if !ipLimiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "rate limit exceeded",
-			})
-			c.Abort()
+		if allowed, wait := rl.takeIP(c.Request.Context(), ip); !allowed {
+			rejectRateLimited(c, wait, "rate limit exceeded")
 			return
 		}
 		
@@ -140,13 +235,10 @@ func (rl *RateLimiter) AuthRateLimiterMiddleware() gin.HandlerFunc {
 				if identifier != "" {
 					// Create a key that combines IP and identifier
 					key := ip + ":" + identifier
-					authLimiter := rl.getAuthLimiter(key)
-					
-					if !authLimiter.Allow() {
-						c.JSON(http.StatusTooManyRequests, gin.H{
-							"error": "too many authentication attempts, please try again later",
-						})
-						c.Abort()
+					if allowed, wait := rl.take(c.Request.Context(), "auth:"+key, rl.authLimiterRate, rl.authBurst, func() *rate.Limiter {
+						return rl.getAuthLimiter(key)
+					}); !allowed {
+						rejectRateLimited(c, wait, "too many authentication attempts, please try again later")
 						return
 					}
 				}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Each user gets their own bucket per scope, anonymous requests are limited by IP, and
+// rejected requests are told when to retry
+func TestUserRateLimiterMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewRateLimiter(100, 60, 100, 10)
+	defer limiter.Stop()
+
+	// One request a minute, with a burst of two
+	limit := UserRateLimit{RequestsPerMinute: 1, Burst: 2}
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			c.Set(ContextUserUUID, uuid.MustParse(userID))
+		}
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/payments", limiter.UserRateLimiterMiddleware("payments", limit), ok)
+	router.POST("/withdrawals", limiter.UserRateLimiterMiddleware("withdrawals", limit), ok)
+
+	post := func(path, userID, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		if userID != "" {
+			req.Header.Set("X-Test-User", userID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	alice, bob := uuid.NewString(), uuid.NewString()
+	assert.Equal(t, http.StatusOK, post("/payments", alice, "203.0.113.1").Code)
+	assert.Equal(t, http.StatusOK, post("/payments", alice, "203.0.113.2").Code)
+	w := post("/payments", alice, "203.0.113.3")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "changing IP doesn't reset a user's limit")
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, post("/withdrawals", alice, "203.0.113.1").Code, "scopes have separate buckets")
+	assert.Equal(t, http.StatusOK, post("/payments", bob, "203.0.113.1").Code, "users have separate buckets")
+
+	// Anonymous requests share their IP's bucket
+	assert.Equal(t, http.StatusOK, post("/payments", "", "198.51.100.1").Code)
+	assert.Equal(t, http.StatusOK, post("/payments", "", "198.51.100.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, post("/payments", "", "198.51.100.1").Code)
+	assert.Equal(t, http.StatusOK, post("/payments", "", "198.51.100.2").Code)
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisRateLimitPrefix namespaces rate limit buckets in Redis
+const redisRateLimitPrefix = "ratelimit:"
+
+// tokenBucketScript takes a token from the bucket at KEYS[1], refilling it at ARGV[1]
+// tokens per second up to ARGV[2]. It uses the Redis server clock so every instance sees
+// the same time. It returns whether a token was taken and, if not, the milliseconds until
+// one is available, or -1 if the bucket never refills.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+elseif rate > 0 then
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+else
+	wait = -1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+if rate > 0 then
+	redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+else
+	redis.call('PEXPIRE', KEYS[1], 3600000)
+end
+return {allowed, wait}
+`)
+
+// redisLimitStore keeps token buckets in Redis so limits hold across API instances
+type redisLimitStore struct {
+	client *redis.Client
+}
+
+// take removes a token from the key's bucket, returning how long until one is available
+// if it's empty. A negative wait means the bucket never refills.
+func (s *redisLimitStore) take(ctx context.Context, key string, perSecond float64, burst int) (bool, time.Duration, error) {
+	result, err := tokenBucketScript.Run(ctx, s.client, []string{redisRateLimitPrefix + key}, perSecond, burst).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("error running rate limit script: %w", err)
+	}
+	if len(result) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit script result %v", result)
+	}
+	if result[0] == 1 {
+		return true, 0, nil
+	}
+	if result[1] < 0 {
+		return false, -1, nil
+	}
+	return false, time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// NewRedisRateLimiter creates a rate limiter that keeps its buckets in Redis, so limits are
+// enforced across every instance. If Redis can't be reached, requests are limited by the
+// in-memory buckets instead until it comes back.
+func NewRedisRateLimiter(client *redis.Client, ipRequestsPerSecond, authRequestsPerMinute float64, ipBurst, authBurst int) *RateLimiter {
+	limiter := NewRateLimiter(ipRequestsPerSecond, authRequestsPerMinute, ipBurst, authBurst)
+	limiter.redis = &redisLimitStore{client: client}
+	return limiter
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+// Instances sharing Redis share their buckets, and keep limiting in memory while Redis is down
+func TestRedisRateLimiterSharesLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	defer client.Close()
+
+	// Two API instances, each allowing an IP two requests before refilling at one a minute
+	newInstance := func() *gin.Engine {
+		limiter := NewRedisRateLimiter(client, 1.0/60, 60, 2, 10)
+		t.Cleanup(limiter.Stop)
+		router := gin.New()
+		router.GET("/", limiter.IPRateLimiterMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+	first, second := newInstance(), newInstance()
+	get := func(router *gin.Engine, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, get(first, "203.0.113.1").Code)
+	assert.Equal(t, http.StatusOK, get(second, "203.0.113.1").Code)
+	w := get(first, "203.0.113.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "the other instance's request counts")
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.True(t, server.Exists(redisRateLimitPrefix+"ip:203.0.113.1"))
+
+	// Without Redis each instance limits on its own
+	server.Close()
+	assert.Equal(t, http.StatusOK, get(first, "198.51.100.1").Code)
+	assert.Equal(t, http.StatusOK, get(first, "198.51.100.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, get(first, "198.51.100.1").Code)
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -128,7 +129,7 @@ func (m *SecurityMiddleware) BruteForceProtection() gin.HandlerFunc {
 					}
 
 					// Return 429 Too Many Requests with Retry-After header
-					c.Header("Retry-After", string(retryAfter))
+					c.Header("Retry-After", strconv.Itoa(retryAfter))
 					c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 						"error":      "Account temporarily locked due to too many failed login attempts",
 						"retry_after": retryAfter,
@@ -184,7 +185,7 @@ func (m *SecurityMiddleware) BruteForceProtection() gin.HandlerFunc {
 						}
 
 						// Return 429 Too Many Requests with Retry-After header
-						c.Header("Retry-After", string(retryAfter))
+						c.Header("Retry-After", strconv.Itoa(retryAfter))
 						c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 							"error":      "Account temporarily locked due to too many failed login attempts",
 							"retry_after": retryAfter,
@@ -199,29 +200,6 @@ func (m *SecurityMiddleware) BruteForceProtection() gin.HandlerFunc {
 	}
 }
 
-// SessionActivity updates session activity and performs risk assessment
-func (m *SecurityMiddleware) SessionActivity() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Skip for authentication routes
-		if c.FullPath() == "/api/auth/login" || c.FullPath() == "/api/auth/register" {
-			c.Next()
-			return
-		}
-
-		// Get session ID from context (set by auth middleware)
-		sessionID, exists := c.Get("session_id")
-		if !exists {
-			c.Next()
-			return
-		}
-
-		// Update session activity
-		database.UpdateSessionActivity(m.db, sessionID.(uuid.UUID), c.ClientIP(), "", "") // Using the version in enhanced_session.go
-
-		c.Next()
-	}
-}
-
 // RiskBasedAuthentication performs risk assessment on authenticated requests
 func (m *SecurityMiddleware) RiskBasedAuthentication() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -232,14 +210,14 @@ func (m *SecurityMiddleware) RiskBasedAuthentication() gin.HandlerFunc {
 		}
 
 		// Check if user is authenticated (set by auth middleware)
-		_, exists := c.Get("user_id")
+		_, exists := c.Get(ContextUserID)
 		if !exists {
 			c.Next()
 			return
 		}
 
 		// Get session ID from context
-		sessionID, exists := c.Get("session_id")
+		sessionID, exists := c.Get(ContextSessionID)
 		if !exists {
 			c.Next()
 			return
@@ -310,7 +288,7 @@ func (m *SecurityMiddleware) VerifySessionStatus() gin.HandlerFunc {
 		}
 
 		// Get session ID from context
-		sessionID, exists := c.Get("session_id")
+		sessionID, exists := c.Get(ContextSessionID)
 		if !exists {
 			c.Next()
 			return
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"gorm.io/gorm"
+)
+
+// sessionActivity records the use of the sessions tokens belong to
+type sessionActivity struct {
+	db       *gorm.DB
+	timeouts database.IdleTimeouts
+}
+
+var (
+	sessionActivityMu sync.RWMutex
+	sessionTracker    *sessionActivity
+)
+
+// ConfigureSessionActivity makes AuthMiddleware record each use of the session a token was
+// issued to, and reject tokens of sessions that have been revoked, have expired, or have gone
+// unused for longer than their idle timeout. Until it's called, or after it's called with a nil
+// db, sessions aren't checked.
+func ConfigureSessionActivity(db *gorm.DB, timeouts database.IdleTimeouts) {
+	sessionActivityMu.Lock()
+	defer sessionActivityMu.Unlock()
+	if db == nil {
+		sessionTracker = nil
+		return
+	}
+	sessionTracker = &sessionActivity{db: db, timeouts: timeouts}
+}
+
+func currentSessionActivity() *sessionActivity {
+	sessionActivityMu.RLock()
+	defer sessionActivityMu.RUnlock()
+	return sessionTracker
+}
+
+// touch records a use of the session from the request's client, aborting the request if the
+// session can no longer be used. It reports whether the request may continue.
+func (s *sessionActivity) touch(c *gin.Context, sessionID uuid.UUID) bool {
+	err := database.TouchSession(s.db, sessionID, c.ClientIP(), s.timeouts)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, database.ErrSessionIdle):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{
+				"code":    "session_expired",
+				"message": "Session expired due to inactivity, please sign in again",
+			},
+		})
+	case errors.Is(err, database.ErrSessionInactive), errors.Is(err, gorm.ErrRecordNotFound):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{
+				"code":    "session_revoked",
+				"message": "Session is no longer active, please sign in again",
+			},
+		})
+	default:
+		// A failure to record activity shouldn't sign everyone out
+		log.Printf("Failed to update activity of session %v: %v", sessionID, err)
+		return true
+	}
+	return false
+}
@@ -23,19 +23,6 @@ type Session struct {
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
-// PasswordResetToken represents a password reset token
-type PasswordResetToken struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
-	UserID    uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`
-	User      User           `gorm:"foreignKey:UserID" json:"-"`
-	Token     string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"token"`
-	ExpiresAt time.Time      `json:"expires_at"`
-	UsedAt    *time.Time     `json:"used_at"`
-	CreatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
-}
-
 // EmailVerificationToken represents an email verification token
 type EmailVerificationToken struct {
 	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
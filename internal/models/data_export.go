@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataExportStatus is the state of a user data export
+type DataExportStatus string
+
+const (
+	DataExportStatusPending   DataExportStatus = "pending"
+	DataExportStatusCompleted DataExportStatus = "completed"
+	DataExportStatusFailed    DataExportStatus = "failed"
+)
+
+// DataExportFormat is the file format of a user data export
+type DataExportFormat string
+
+const (
+	DataExportFormatJSON DataExportFormat = "json"
+	DataExportFormatZip  DataExportFormat = "zip"
+)
+
+// DataExport is a user's request for a copy of their data. The file is kept in storage until
+// ExpiresAt, and users are emailed a signed link to it.
+type DataExport struct {
+	ID          uuid.UUID        `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID      uuid.UUID        `gorm:"type:uuid;not null;index" json:"user_id"`
+	Status      DataExportStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Format      DataExportFormat `gorm:"type:varchar(10);not null;default:'json'" json:"format"`
+	StorageKey  string           `gorm:"type:varchar(255)" json:"-"`
+	Error       string           `gorm:"type:text" json:"error,omitempty"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time       `gorm:"index" json:"expires_at,omitempty"`
+	CreatedAt   time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
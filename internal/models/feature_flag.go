@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FeatureFlag is a runtime override of a feature flag, set by an admin. Flags without a row use
+// their configured default.
+type FeatureFlag struct {
+	Key       string     `gorm:"type:varchar(100);primaryKey" json:"key"`
+	Enabled   bool       `gorm:"not null" json:"enabled"`
+	UpdatedBy *uuid.UUID `gorm:"type:uuid" json:"updated_by"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
@@ -11,11 +11,13 @@ import (
 type KYCStatus string
 
 const (
+	KYCStatusNotSubmitted KYCStatus = "not_submitted"
 	KYCStatusPending    KYCStatus = "pending"
 	KYCStatusInProgress KYCStatus = "in_progress"
 	KYCStatusApproved   KYCStatus = "approved"
 	KYCStatusRejected   KYCStatus = "rejected"
 	KYCStatusExpired    KYCStatus = "expired"
+	KYCStatusTimedOut   KYCStatus = "timed_out" // The provider never gave a result; the user must resubmit
 )
 
 // DocumentType represents the type of document uploaded for KYC
@@ -26,13 +28,21 @@ const (
 	DocumentTypePassport DocumentType = "passport"
 	DocumentTypeLicense  DocumentType = "license"
 	DocumentTypeSelfie   DocumentType = "selfie"
+	DocumentTypeProofOfAddress DocumentType = "proof_of_address"
+)
+
+// KYC providers that can run a verification
+const (
+	KYCProviderDidit         = "didit"
+	KYCProviderSmileIdentity = "smile_identity"
 )
 
 // KYCVerification represents a KYC verification record
 type KYCVerification struct {
 	ID             uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
-	UserID         uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`
+	UserID         uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_kyc_verifications_user_active,where:(status = 'pending' OR status = 'in_progress' OR status = 'approved') AND deleted_at IS NULL" json:"user_id"` // A user has at most one active verification
 	User           User           `gorm:"foreignKey:UserID" json:"-"`
+	Provider       string         `gorm:"type:varchar(50);not null;default:'didit';index" json:"provider"`
 	Status         KYCStatus      `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
 	SessionID      string         `gorm:"type:varchar(255)" json:"session_id"`
 	WorkflowID     string         `gorm:"type:varchar(255)" json:"workflow_id"`
@@ -47,6 +57,10 @@ type KYCVerification struct {
 	ReportURL      *string        `gorm:"type:text" json:"report_url"`
 	AdminNotes     *string        `gorm:"type:text" json:"admin_notes"`
 	RejectionReason *string       `gorm:"type:text" json:"rejection_reason"`
+	VerifiedAt     *time.Time     `json:"verified_at"`
+	SubmittedAt    *time.Time     `json:"submitted_at,omitempty"`           // When it was sent to the provider
+	PollAttempts   int            `gorm:"default:0" json:"-"`                 // Times the provider has been asked for the result
+	NextPollAt     *time.Time     `gorm:"index" json:"-"`                     // When to next ask the provider for the result; nil once it has given one
 	CreatedAt      time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt      time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
@@ -58,7 +72,7 @@ type KYCDocument struct {
 	VerificationID uuid.UUID    `gorm:"type:uuid;not null" json:"verification_id"`
 	Verification   KYCVerification `gorm:"foreignKey:VerificationID" json:"-"`
 	Type           DocumentType `gorm:"type:varchar(50);not null" json:"type"`
-	FilePath       string       `gorm:"type:text;not null" json:"file_path"`
+	StorageKey     string       `gorm:"column:file_path;type:text;not null" json:"-"` // storage backend key, never exposed to clients
 	FileName       string       `gorm:"type:varchar(255);not null" json:"file_name"`
 	FileSize       int64        `json:"file_size"`
 	MimeType       string       `gorm:"type:varchar(100)" json:"mime_type"`
@@ -73,8 +87,18 @@ type KYCVerificationHistory struct {
 	Verification   KYCVerification `gorm:"foreignKey:VerificationID" json:"-"`
 	PreviousStatus KYCStatus `gorm:"type:varchar(20);not null" json:"previous_status"`
 	NewStatus      KYCStatus `gorm:"type:varchar(20);not null" json:"new_status"`
-	ChangedBy      uuid.UUID `gorm:"type:uuid" json:"changed_by"`
+	ChangedBy      *uuid.UUID `gorm:"type:uuid" json:"changed_by"` // nil for changes made by a provider or job
 	ChangedByUser  User      `gorm:"foreignKey:ChangedBy" json:"-"`
 	Notes          *string   `gorm:"type:text" json:"notes"`
 	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 }
+
+// KYCWebhookEvent records a provider webhook that has been applied, so redeliveries of the
+// same event are acknowledged without running its status transition again
+type KYCWebhookEvent struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Provider       string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_kyc_webhook_events_key" json:"provider"`
+	EventKey       string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_kyc_webhook_events_key" json:"event_key"`
+	VerificationID uuid.UUID `gorm:"type:uuid;index" json:"verification_id"`
+	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
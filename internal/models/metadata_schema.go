@@ -0,0 +1,79 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MetadataFieldType is the JSON type a metadata value must have
+type MetadataFieldType string
+
+const (
+	MetadataFieldString  MetadataFieldType = "string"
+	MetadataFieldNumber  MetadataFieldType = "number"
+	MetadataFieldBoolean MetadataFieldType = "boolean"
+	MetadataFieldObject  MetadataFieldType = "object"
+	MetadataFieldArray   MetadataFieldType = "array"
+)
+
+// IsValid reports whether t is a known field type
+func (t MetadataFieldType) IsValid() bool {
+	switch t {
+	case MetadataFieldString, MetadataFieldNumber, MetadataFieldBoolean, MetadataFieldObject, MetadataFieldArray:
+		return true
+	}
+	return false
+}
+
+// MetadataField describes one key merchants may set in payment metadata
+type MetadataField struct {
+	Key      string            `json:"key"`
+	Type     MetadataFieldType `json:"type"`
+	Required bool              `json:"required"`
+}
+
+// MetadataFields is a list of metadata fields stored as JSON
+type MetadataFields []MetadataField
+
+// Value implements the driver.Valuer interface for MetadataFields
+func (f MetadataFields) Value() (driver.Value, error) {
+	if f == nil {
+		return json.Marshal([]MetadataField{})
+	}
+	return json.Marshal(f)
+}
+
+// Scan implements the sql.Scanner interface for MetadataFields
+func (f *MetadataFields) Scan(value interface{}) error {
+	if value == nil {
+		*f = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	var result MetadataFields
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return err
+	}
+	*f = result
+	return nil
+}
+
+// PaymentMetadataSchema is a merchant's optional schema for the metadata on their payments and
+// payment links. Metadata that doesn't conform is rejected.
+type PaymentMetadataSchema struct {
+	ID               uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID           uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex" json:"user_id"`
+	Fields           MetadataFields `gorm:"type:jsonb;not null" json:"fields"`
+	AllowUnknownKeys bool           `gorm:"default:false" json:"allow_unknown_keys"` // Accept keys that aren't in Fields
+	CreatedAt        time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt        time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationType represents the kind of event a user is notified about
+type NotificationType string
+
+const (
+	NotificationTypePaymentStatus    NotificationType = "payment_status"
+	NotificationTypeWithdrawalStatus NotificationType = "withdrawal_status"
+	NotificationTypeSubscription     NotificationType = "subscription"
+)
+
+// Notification is an in-app message shown to a user
+type Notification struct {
+	ID        uuid.UUID        `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID    uuid.UUID        `gorm:"type:uuid;index;not null" json:"user_id"`
+	User      User             `gorm:"foreignKey:UserID" json:"-"`
+	Type      NotificationType `gorm:"type:varchar(50);not null" json:"type"`
+	Title     string           `gorm:"type:varchar(255);not null" json:"title"`
+	Message   string           `gorm:"type:text" json:"message"`
+	Data      JSON             `gorm:"type:jsonb" json:"data"`
+	ReadAt    *time.Time       `gorm:"index" json:"read_at"`
+	CreatedAt time.Time        `gorm:"default:CURRENT_TIMESTAMP;index" json:"created_at"`
+	UpdatedAt time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
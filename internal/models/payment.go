@@ -27,8 +27,41 @@ const (
 	PaymentStatusFailed    PaymentStatus = "failed"
 	PaymentStatusRefunded  PaymentStatus = "refunded"
 	PaymentStatusCancelled PaymentStatus = "cancelled"
+	PaymentStatusExpired   PaymentStatus = "expired" // Abandoned at checkout and no longer payable
 )
 
+// IsValid reports whether s is a known payment status
+func (s PaymentStatus) IsValid() bool {
+	switch s {
+	case PaymentStatusPending, PaymentStatusCompleted, PaymentStatusFailed, PaymentStatusRefunded,
+		PaymentStatusCancelled, PaymentStatusExpired:
+		return true
+	}
+	return false
+}
+
+// PaymentActionType is a step the customer must complete before a provider confirms a payment
+type PaymentActionType string
+
+const (
+	PaymentActionRedirect PaymentActionType = "redirect" // Visit the action URL, such as a 3D Secure challenge
+	PaymentActionOTP      PaymentActionType = "otp"      // Enter a one-time code sent by the issuer
+	PaymentActionPIN      PaymentActionType = "pin"      // Enter the card or wallet PIN
+)
+
+// PaymentAction is an action the customer must complete before the payment can be confirmed
+type PaymentAction struct {
+	Type    PaymentActionType `json:"type"`
+	URL     string            `json:"url,omitempty"`     // Where to send the customer, for redirects
+	Message string            `json:"message,omitempty"` // Provider's instructions to show the customer
+}
+
+// PaymentInitiation is a provider's response to starting a payment
+type PaymentInitiation struct {
+	CheckoutURL string         // Hosted checkout page, if the provider has one
+	Action      *PaymentAction // Set when the customer must complete an action before confirming
+}
+
 // PaymentLink represents a payment link for collecting payments
 type PaymentLink struct {
 	ID          uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
@@ -47,6 +80,34 @@ type PaymentLink struct {
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// PaymentAmountLimit overrides the configured minimum and maximum payment amount for one
+// merchant in one currency. Entries are hard-deleted to fall back to the configured limits.
+type PaymentAmountLimit struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_payment_amount_limits_user_currency" json:"user_id"`
+	Currency  Currency   `gorm:"type:varchar(3);not null;uniqueIndex:idx_payment_amount_limits_user_currency" json:"currency"`
+	MinAmount float64    `gorm:"type:decimal(20,8);not null" json:"min_amount"`
+	MaxAmount float64    `gorm:"type:decimal(20,8);not null" json:"max_amount"`
+	SetBy     *uuid.UUID `gorm:"type:uuid" json:"set_by"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// ProviderCredential is a merchant's own account with a payment provider. The merchant's
+// payments are made through it rather than the platform's account, and webhooks for it are
+// verified with its secret key. Entries are hard-deleted to fall back to the platform account.
+type ProviderCredential struct {
+	ID                 uuid.UUID       `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID             uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_provider_credentials_user_provider" json:"user_id"`
+	Provider           PaymentProvider `gorm:"type:varchar(20);not null;uniqueIndex:idx_provider_credentials_user_provider;uniqueIndex:idx_provider_credentials_provider_account" json:"provider"`
+	AccountID          *string         `gorm:"type:varchar(100);uniqueIndex:idx_provider_credentials_provider_account" json:"account_id,omitempty"` // Provider's ID for the account in webhook payloads, such as a Paystack subaccount code
+	EncryptedSecretKey string          `gorm:"type:text;not null" json:"-"`
+	PublicKey          string          `gorm:"type:varchar(255)" json:"public_key,omitempty"`
+	SetBy              *uuid.UUID      `gorm:"type:uuid" json:"set_by"`
+	CreatedAt          time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt          time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
 // Payment represents a payment transaction
 type Payment struct {
 	ID              uuid.UUID       `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
@@ -70,6 +131,12 @@ type Payment struct {
 	ReceiptURL      string          `gorm:"type:varchar(255)" json:"receipt_url"`
 	WebhookReceived bool            `gorm:"default:false" json:"webhook_received"`
 	WebhookData     JSON            `gorm:"type:jsonb" json:"webhook_data"`
+	Error           string          `gorm:"type:text" json:"error,omitempty"` // Provider error when the payment failed or timed out
+	ReceiptSentAt   *time.Time      `json:"receipt_sent_at,omitempty"`              // When the customer was emailed a receipt
+	ExpiresAt       *time.Time      `gorm:"index" json:"expires_at,omitempty"`       // When a pending payment stops being payable; nil never expires
+	PaidAt          *time.Time      `json:"paid_at,omitempty"`                       // When the provider says the customer paid, if it says
+	RequiredAction  PaymentActionType `gorm:"type:varchar(20)" json:"required_action,omitempty"` // Action the customer must complete before confirming
+	MerchantAccount bool            `gorm:"default:false" json:"merchant_account"`              // Collected into the merchant's own provider account, so not credited to their wallet
 	CreatedAt       time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt       time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 	DeletedAt       gorm.DeletedAt  `gorm:"index" json:"-"`
@@ -77,9 +144,10 @@ type Payment struct {
 
 // PaymentWebhook represents a webhook received from a payment provider
 type PaymentWebhook struct {
-	ID          uuid.UUID       `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
-	Provider    PaymentProvider `gorm:"type:varchar(20);not null" json:"provider"`
-	Event       string          `gorm:"type:varchar(100)" json:"event"`
+	ID              uuid.UUID       `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Provider        PaymentProvider `gorm:"type:varchar(20);not null;uniqueIndex:idx_payment_webhooks_provider_event" json:"provider"`
+	ProviderEventID *string         `gorm:"type:varchar(150);uniqueIndex:idx_payment_webhooks_provider_event" json:"provider_event_id,omitempty"` // Provider's ID for the event, used to drop redelivered webhooks
+	Event           string          `gorm:"type:varchar(100)" json:"event"`
 	Reference   string          `gorm:"type:varchar(100);index" json:"reference"`
 	PaymentID   *uuid.UUID      `gorm:"type:uuid;index" json:"payment_id,omitempty"`
 	Payment     *Payment        `gorm:"foreignKey:PaymentID" json:"-"`
@@ -90,6 +158,31 @@ type PaymentWebhook struct {
 	UpdatedAt   time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
 
+// PaymentSplitType says who a payment split is for
+type PaymentSplitType string
+
+const (
+	PaymentSplitRecipient   PaymentSplitType = "recipient"    // Credited to another merchant's wallet
+	PaymentSplitPlatformFee PaymentSplitType = "platform_fee" // Kept by the platform rather than credited
+)
+
+// PaymentSplit is one party's share of a split payment. Shares are a fixed amount or a
+// percentage of the payment's net amount, and are settled when the payment completes; the
+// payment's merchant is credited whatever the splits leave.
+type PaymentSplit struct {
+	ID            uuid.UUID        `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	PaymentID     uuid.UUID        `gorm:"type:uuid;not null;index" json:"payment_id"`
+	Type          PaymentSplitType `gorm:"type:varchar(20);not null" json:"type"`
+	WalletID      *uuid.UUID       `gorm:"type:uuid;index" json:"wallet_id,omitempty"`         // Recipient's wallet; nil for the platform fee
+	Amount        float64          `gorm:"type:decimal(20,8);default:0" json:"amount"`         // Fixed share, if not a percentage
+	Percentage    float64          `gorm:"type:decimal(5,2);default:0" json:"percentage"`      // Share of the net amount, if not fixed
+	SettledAmount float64          `gorm:"type:decimal(20,8);default:0" json:"settled_amount"` // What the share came to when the payment completed
+	TransactionID *uuid.UUID       `gorm:"type:uuid" json:"transaction_id,omitempty"`          // Credit to the recipient's wallet
+	SettledAt     *time.Time       `json:"settled_at,omitempty"`
+	CreatedAt     time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
 // CryptoPayment represents a cryptocurrency payment
 type CryptoPayment struct {
 	ID            uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Permission names an action on the admin API, as resource:action
+type Permission string
+
+// Permissions granted through roles. Admins (User.IsAdmin) hold every permission.
+const (
+	PermissionUsersView          Permission = "users:view"
+	PermissionUsersManage        Permission = "users:manage"
+	PermissionRolesManage        Permission = "roles:manage"
+	PermissionKYCView            Permission = "kyc:view"
+	PermissionKYCApprove         Permission = "kyc:approve"
+	PermissionWalletsView        Permission = "wallets:view"
+	PermissionWalletsAdjust      Permission = "wallets:adjust"
+	PermissionWithdrawalsView    Permission = "withdrawals:view"
+	PermissionWithdrawalsApprove Permission = "withdrawals:approve"
+	PermissionPaymentsView       Permission = "payments:view"
+	PermissionComplianceView     Permission = "compliance:view"
+	PermissionScreeningManage    Permission = "screening:manage"
+	PermissionAuditView          Permission = "audit:view"
+	PermissionWebhooksManage     Permission = "webhooks:manage"
+	PermissionSecurityManage     Permission = "security:manage"
+	PermissionFeatureFlagsManage Permission = "feature_flags:manage"
+)
+
+// AllPermissions lists every permission a role can be granted
+var AllPermissions = []Permission{
+	PermissionUsersView,
+	PermissionUsersManage,
+	PermissionRolesManage,
+	PermissionKYCView,
+	PermissionKYCApprove,
+	PermissionWalletsView,
+	PermissionWalletsAdjust,
+	PermissionWithdrawalsView,
+	PermissionWithdrawalsApprove,
+	PermissionPaymentsView,
+	PermissionComplianceView,
+	PermissionScreeningManage,
+	PermissionAuditView,
+	PermissionWebhooksManage,
+	PermissionSecurityManage,
+	PermissionFeatureFlagsManage,
+}
+
+// IsValid reports whether p is a known permission
+func (p Permission) IsValid() bool {
+	for _, known := range AllPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Role is a named set of permissions granted to staff users
+type Role struct {
+	ID          uuid.UUID        `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Name        string           `gorm:"type:varchar(50);uniqueIndex;not null" json:"name"`
+	Description string           `gorm:"type:text" json:"description"`
+	Permissions []RolePermission `gorm:"foreignKey:RoleID;constraint:OnDelete:CASCADE" json:"permissions"`
+	CreatedAt   time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// RolePermission grants a permission to a role
+type RolePermission struct {
+	RoleID     uuid.UUID  `gorm:"type:uuid;primaryKey" json:"-"`
+	Permission Permission `gorm:"type:varchar(50);primaryKey" json:"permission"`
+}
+
+// UserRole assigns a role to a user
+type UserRole struct {
+	UserID    uuid.UUID  `gorm:"type:uuid;primaryKey" json:"user_id"`
+	RoleID    uuid.UUID  `gorm:"type:uuid;primaryKey;index" json:"role_id"`
+	Role      Role       `gorm:"foreignKey:RoleID;constraint:OnDelete:CASCADE" json:"role"`
+	GrantedBy *uuid.UUID `gorm:"type:uuid" json:"granted_by"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// DefaultRoles are the staff roles created by the roles migration
+var DefaultRoles = []Role{
+	{
+		Name:        "support",
+		Description: "Support agents: look up users, KYC submissions, wallets and payments",
+		Permissions: rolePermissions(
+			PermissionUsersView,
+			PermissionKYCView,
+			PermissionWalletsView,
+			PermissionWithdrawalsView,
+			PermissionPaymentsView,
+		),
+	},
+	{
+		Name:        "compliance_officer",
+		Description: "Compliance officers: review KYC, screening and compliance reports",
+		Permissions: rolePermissions(
+			PermissionUsersView,
+			PermissionKYCView,
+			PermissionKYCApprove,
+			PermissionPaymentsView,
+			PermissionWithdrawalsView,
+			PermissionComplianceView,
+			PermissionScreeningManage,
+			PermissionAuditView,
+		),
+	},
+	{
+		Name:        "finance",
+		Description: "Finance operations: manage wallets, withdrawals and payment limits",
+		Permissions: rolePermissions(
+			PermissionUsersView,
+			PermissionUsersManage,
+			PermissionWalletsView,
+			PermissionWalletsAdjust,
+			PermissionWithdrawalsView,
+			PermissionWithdrawalsApprove,
+			PermissionPaymentsView,
+			PermissionWebhooksManage,
+		),
+	},
+}
+
+// rolePermissions builds a role's permission rows
+func rolePermissions(permissions ...Permission) []RolePermission {
+	rows := make([]RolePermission, 0, len(permissions))
+	for _, permission := range permissions {
+		rows = append(rows, RolePermission{Permission: permission})
+	}
+	return rows
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRolesGrantKnownPermissions(t *testing.T) {
+	names := make(map[string]bool)
+	for _, role := range DefaultRoles {
+		assert.False(t, names[role.Name], "duplicate role %s", role.Name)
+		names[role.Name] = true
+
+		for _, permission := range role.Permissions {
+			assert.True(t, permission.Permission.IsValid(), "role %s grants unknown permission %s", role.Name, permission.Permission)
+		}
+	}
+}
+
+func TestSupportCannotApprove(t *testing.T) {
+	for _, role := range DefaultRoles {
+		if role.Name != "support" {
+			continue
+		}
+		for _, permission := range role.Permissions {
+			assert.NotEqual(t, PermissionKYCApprove, permission.Permission)
+			assert.NotEqual(t, PermissionWithdrawalsApprove, permission.Permission)
+		}
+	}
+	assert.False(t, Permission("kyc:delete").IsValid())
+}
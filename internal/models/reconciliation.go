@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReconciliationReportStatus is the outcome of reconciling one provider for one period
+type ReconciliationReportStatus string
+
+const (
+	ReconciliationReportBalanced      ReconciliationReportStatus = "balanced"      // Every record matched
+	ReconciliationReportDiscrepancies ReconciliationReportStatus = "discrepancies" // Some records need review
+	ReconciliationReportFailed        ReconciliationReportStatus = "failed"        // The provider's records couldn't be fetched
+)
+
+// ReconciliationDiscrepancyKind says how a record differs between our books and the provider's
+type ReconciliationDiscrepancyKind string
+
+const (
+	ReconciliationMissingInternal ReconciliationDiscrepancyKind = "missing_internal" // The provider has a record we don't
+	ReconciliationMissingProvider ReconciliationDiscrepancyKind = "missing_provider" // We settled a record the provider doesn't have
+	ReconciliationAmountMismatch  ReconciliationDiscrepancyKind = "amount_mismatch"  // Amount or currency differs
+	ReconciliationFeeMismatch     ReconciliationDiscrepancyKind = "fee_mismatch"     // Provider fee differs
+	ReconciliationStatusMismatch  ReconciliationDiscrepancyKind = "status_mismatch"  // e.g. a payment the provider settled is still pending here
+)
+
+// ReconciliationReport compares a provider's transactions and payouts for a period with our
+// payments and withdrawals. There is one report per provider per period.
+type ReconciliationReport struct {
+	ID               uuid.UUID                   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Provider         PaymentProvider             `gorm:"type:varchar(20);not null;uniqueIndex:idx_reconciliation_reports_provider_period" json:"provider"`
+	PeriodStart      time.Time                   `gorm:"not null;uniqueIndex:idx_reconciliation_reports_provider_period" json:"period_start"`
+	PeriodEnd        time.Time                   `gorm:"not null" json:"period_end"`
+	Status           ReconciliationReportStatus  `gorm:"type:varchar(20);not null;index" json:"status"`
+	InternalRecords  int                         `json:"internal_records"`
+	ProviderRecords  int                         `json:"provider_records"`
+	DiscrepancyCount int                         `json:"discrepancy_count"`
+	Totals           JSON                        `gorm:"type:jsonb" json:"totals"` // Settled counts, amounts and fees on each side, by record type and currency
+	Error            string                      `gorm:"type:text" json:"error,omitempty"`
+	Discrepancies    []ReconciliationDiscrepancy `gorm:"foreignKey:ReportID;constraint:OnDelete:CASCADE" json:"discrepancies,omitempty"`
+	CreatedAt        time.Time                   `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt        time.Time                   `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// ReconciliationDiscrepancy is one record that differs between our books and the provider's.
+// Values missing on one side are nil.
+type ReconciliationDiscrepancy struct {
+	ID             uuid.UUID                     `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	ReportID       uuid.UUID                     `gorm:"type:uuid;not null;index" json:"report_id"`
+	Kind           ReconciliationDiscrepancyKind `gorm:"type:varchar(30);not null" json:"kind"`
+	RecordType     string                        `gorm:"type:varchar(20);not null" json:"record_type"` // payment or withdrawal
+	Reference      string                        `gorm:"type:varchar(100);index" json:"reference"`
+	PaymentID      *uuid.UUID                    `gorm:"type:uuid" json:"payment_id,omitempty"`
+	WithdrawalID   *uuid.UUID                    `gorm:"type:uuid" json:"withdrawal_id,omitempty"`
+	Currency       Currency                      `gorm:"type:varchar(3)" json:"currency"`
+	InternalAmount *float64                      `gorm:"type:decimal(20,8)" json:"internal_amount"`
+	ProviderAmount *float64                      `gorm:"type:decimal(20,8)" json:"provider_amount"`
+	InternalFee    *float64                      `gorm:"type:decimal(20,8)" json:"internal_fee,omitempty"`
+	ProviderFee    *float64                      `gorm:"type:decimal(20,8)" json:"provider_fee,omitempty"`
+	InternalStatus string                        `gorm:"type:varchar(20)" json:"internal_status,omitempty"`
+	ProviderStatus string                        `gorm:"type:varchar(20)" json:"provider_status,omitempty"`
+	CreatedAt      time.Time                     `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BlockedAddress represents a crypto address we refuse to send funds to.
+// Entries are hard-deleted so an address can be blocked again later.
+type BlockedAddress struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Network   string     `gorm:"type:varchar(20);not null;uniqueIndex:idx_blocked_addresses_network_address" json:"network"`
+	Address   string     `gorm:"type:varchar(100);not null;uniqueIndex:idx_blocked_addresses_network_address" json:"address"` // Normalized per network
+	Reason    string     `gorm:"type:text" json:"reason"`
+	Source    string     `gorm:"type:varchar(50)" json:"source"` // manual, ofac, etc.
+	AddedBy   *uuid.UUID `gorm:"type:uuid" json:"added_by"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
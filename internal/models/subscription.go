@@ -62,6 +62,8 @@ type Subscription struct {
 	TrialEnd           *time.Time       `json:"trial_end"`
 	PaymentMethod      string           `gorm:"type:varchar(50)" json:"payment_method"`
 	PaymentMethodDetails JSON            `gorm:"type:jsonb" json:"payment_method_details"`
+	// AuthorizationCode is the provider's reusable token for the subscriber's saved card
+	AuthorizationCode   string          `gorm:"type:varchar(255)" json:"-"`
 	LastPaymentDate     *time.Time      `json:"last_payment_date"`
 	NextPaymentDate     *time.Time      `json:"next_payment_date"`
 	FailedPaymentCount  int             `gorm:"default:0" json:"failed_payment_count"`
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TransactionPIN is a user's PIN for confirming withdrawals and transfers. It is kept apart from
+// the password so a hijacked session can't move funds without it, and is locked for a while
+// after too many wrong attempts.
+type TransactionPIN struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"user_id"`
+	PINHash        string     `gorm:"type:varchar(255);not null" json:"-"`
+	FailedAttempts int        `gorm:"default:0" json:"-"`     // Wrong attempts since the last correct one or lockout
+	LockedUntil    *time.Time `json:"locked_until,omitempty"` // Set after too many wrong attempts
+	CreatedAt      time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
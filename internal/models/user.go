@@ -9,20 +9,23 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID            uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
-	Email         string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
-	Username      string         `gorm:"type:varchar(50);uniqueIndex" json:"username"`
-	FirstName     string         `gorm:"type:varchar(100)" json:"first_name"`
-	LastName      string         `gorm:"type:varchar(100)" json:"last_name"`
-	PasswordHash  string         `gorm:"type:varchar(255);not null" json:"-"`
-	IsVerified    bool           `gorm:"default:false" json:"is_verified"`
-	IsActive      bool           `gorm:"default:true" json:"is_active"`
-	IsAdmin       bool           `gorm:"default:false" json:"is_admin"`
-	PhoneNumber   *string        `gorm:"type:varchar(20)" json:"phone_number"`
-	CountryCode   *string        `gorm:"type:varchar(5)" json:"country_code"`
-	ProfileImage  *string        `gorm:"type:text" json:"profile_image"`
-	LastLoginAt   *time.Time     `json:"last_login_at"`
-	CreatedAt     time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt     time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Email            string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
+	Username         string         `gorm:"type:varchar(50);uniqueIndex" json:"username"`
+	FirstName        string         `gorm:"type:varchar(100)" json:"first_name"`
+	LastName         string         `gorm:"type:varchar(100)" json:"last_name"`
+	PasswordHash     string         `gorm:"type:varchar(255);not null" json:"-"`
+	IsVerified       bool           `gorm:"default:false" json:"is_verified"`
+	IsActive         bool           `gorm:"default:true" json:"is_active"` // False while an admin has suspended the account
+	SuspendedAt      *time.Time     `json:"suspended_at,omitempty"`
+	SuspensionReason string         `gorm:"type:text" json:"suspension_reason,omitempty"`
+	IsAdmin          bool           `gorm:"default:false" json:"is_admin"`
+	SendPaymentReceipts bool        `gorm:"default:true" json:"send_payment_receipts"` // Email customers a receipt when their payment completes
+	PhoneNumber      *string        `gorm:"type:varchar(20)" json:"phone_number"`
+	CountryCode      *string        `gorm:"type:varchar(5)" json:"country_code"`
+	ProfileImage     *string        `gorm:"type:text" json:"profile_image"`
+	LastLoginAt      *time.Time     `json:"last_login_at"`
+	CreatedAt        time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt        time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
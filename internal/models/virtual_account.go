@@ -63,8 +63,54 @@ type VirtualAccount struct {
 	Status           VirtualAccountStatus  `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
 	Balance          float64              `gorm:"type:decimal(20,2);default:0" json:"balance"`
 	LastSyncedAt     *time.Time           `json:"last_synced_at"`
+	ReconciliationCursor string           `gorm:"type:varchar(255)" json:"-"`
 	ProviderData     string               `gorm:"type:jsonb" json:"provider_data"`
 	CreatedAt        time.Time            `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt        time.Time            `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 	DeletedAt        gorm.DeletedAt       `gorm:"index" json:"-"`
 }
+
+// VirtualAccountTransactionType represents the direction of a virtual account transaction
+type VirtualAccountTransactionType string
+
+const (
+	// VirtualAccountTransactionInbound represents funds received into a virtual account
+	VirtualAccountTransactionInbound VirtualAccountTransactionType = "inbound"
+	// VirtualAccountTransactionOutbound represents funds sent from a virtual account
+	VirtualAccountTransactionOutbound VirtualAccountTransactionType = "outbound"
+)
+
+// VirtualAccountTransaction represents money moving through a virtual account.
+// TransactionID is the provider's transaction ID. Status is pending, processing,
+// completed or failed; transactions that disagree with the provider's records are
+// flagged for review rather than changed.
+type VirtualAccountTransaction struct {
+	ID                     uuid.UUID                     `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	VirtualAccountID       uuid.UUID                     `gorm:"type:uuid;not null;uniqueIndex:idx_virtual_account_transactions_provider_tx" json:"virtual_account_id"`
+	Amount                 float64                       `gorm:"type:decimal(20,2);not null" json:"amount"`
+	Currency               string                        `gorm:"type:varchar(3);not null" json:"currency"`
+	TransactionID          string                        `gorm:"type:varchar(255);not null;uniqueIndex:idx_virtual_account_transactions_provider_tx" json:"transaction_id"`
+	Reference              string                        `gorm:"type:varchar(255)" json:"reference"`
+	Type                   VirtualAccountTransactionType `gorm:"type:varchar(20);not null" json:"type"`
+	Status                 string                        `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Provider               string                        `gorm:"type:varchar(20);not null" json:"provider"`
+	SenderUserID           uuid.UUID                     `gorm:"type:uuid" json:"sender_user_id"`
+	SenderName             string                        `gorm:"type:varchar(255)" json:"sender_name"`
+	SenderEmail            string                        `gorm:"type:varchar(255)" json:"sender_email"`
+	SenderBank             string                        `gorm:"type:varchar(255)" json:"sender_bank"`
+	SenderAccountNumber    string                        `gorm:"type:varchar(50)" json:"sender_account_number"`
+	RecipientUserID        uuid.UUID                     `gorm:"type:uuid" json:"recipient_user_id"`
+	RecipientName          string                        `gorm:"type:varchar(255)" json:"recipient_name"`
+	RecipientBank          string                        `gorm:"type:varchar(255)" json:"recipient_bank"`
+	RecipientAccountNumber string                        `gorm:"type:varchar(50)" json:"recipient_account_number"`
+	RecipientAccountID     string                        `gorm:"type:varchar(255)" json:"recipient_account_id"`
+	Fee                    float64                       `gorm:"type:decimal(20,2);default:0" json:"fee"`
+	PaymentID              *uuid.UUID                    `gorm:"type:uuid" json:"payment_id"`
+	WithdrawalID           *uuid.UUID                    `gorm:"type:uuid" json:"withdrawal_id"`
+	NeedsReview            bool                          `gorm:"default:false;index" json:"needs_review"`
+	ReviewReason           string                        `gorm:"type:text" json:"review_reason,omitempty"`
+	Metadata               JSON                          `gorm:"type:jsonb" json:"metadata"`
+	CreatedAt              time.Time                     `json:"created_at"`
+	UpdatedAt              time.Time                     `json:"updated_at"`
+	CompletedAt            *time.Time                    `json:"completed_at"`
+}
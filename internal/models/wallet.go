@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -28,6 +29,7 @@ type Wallet struct {
 	Currency  Currency       `gorm:"type:varchar(3);not null" json:"currency"`
 	Balance   float64        `gorm:"type:decimal(20,8);default:0" json:"balance"`
 	Available float64        `gorm:"type:decimal(20,8);default:0" json:"available"` // Available balance (excluding pending)
+	Held      float64        `gorm:"type:decimal(20,8);default:0" json:"held"`      // Reserved for pending withdrawals; Balance = Available + Held
 	CreatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -39,6 +41,8 @@ type Transaction struct {
 	WalletID      uuid.UUID      `gorm:"type:uuid;index" json:"wallet_id"`
 	Wallet        Wallet         `gorm:"foreignKey:WalletID" json:"-"`
 	Type          string         `gorm:"type:varchar(50);not null" json:"type"` // deposit, withdrawal, transfer, fee
+	Category      LedgerCategory `gorm:"type:varchar(20);index" json:"category"`
+	Tag           string         `gorm:"type:varchar(50)" json:"tag,omitempty"` // Free-form label set by the merchant
 	Amount        float64        `gorm:"type:decimal(20,8);not null" json:"amount"`
 	Fee           float64        `gorm:"type:decimal(20,8);default:0" json:"fee"`
 	Currency      Currency       `gorm:"type:varchar(3);not null" json:"currency"`
@@ -53,6 +57,52 @@ type Transaction struct {
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// PendingWalletCredit is a credit waiting to be applied to its wallet with the others in its
+// batch. Its transaction and ledger entry are written, and the row deleted, when it's applied.
+type PendingWalletCredit struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	WalletID    uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_pending_wallet_credits_reference" json:"wallet_id"`
+	Type        string         `gorm:"type:varchar(50);not null;uniqueIndex:idx_pending_wallet_credits_reference" json:"type"`
+	Category    LedgerCategory `gorm:"type:varchar(20)" json:"category"`
+	Amount      float64        `gorm:"type:decimal(20,8);not null" json:"amount"`
+	Reference   string         `gorm:"type:varchar(100);not null;uniqueIndex:idx_pending_wallet_credits_reference" json:"reference"`
+	Description string         `gorm:"type:text" json:"description"`
+	MetaData    JSON           `gorm:"type:jsonb" json:"metadata"`
+	CreatedAt   time.Time      `gorm:"default:CURRENT_TIMESTAMP;index" json:"created_at"`
+}
+
+// WalletBalanceChange collects a wallet's balance changes until they are sent as one
+// wallet.balance_changed event to the owner's webhook endpoints that opted in to them. Each
+// wallet has at most one, holding the combined delta and the latest change's reference.
+type WalletBalanceChange struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	WalletID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"wallet_id"`
+	Delta     float64   `gorm:"type:decimal(20,8);not null" json:"delta"`
+	Reference string    `gorm:"type:varchar(100)" json:"reference"`
+	Changes   int       `gorm:"not null;default:1" json:"changes"`                 // Movements coalesced into the event
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;index" json:"created_at"` // When the first of them was made
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// Transfer is a wallet-to-wallet transfer between two users in the same currency. The sender's
+// debit and the recipient's credit share its reference. A sender's idempotency key identifies
+// the transfer, so retrying a request with the same key can't send the funds twice.
+type Transfer struct {
+	ID                  uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	SenderUserID        uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_transfers_sender_idempotency_key" json:"sender_user_id"`
+	RecipientUserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"recipient_user_id"`
+	SenderWalletID      uuid.UUID  `gorm:"type:uuid;not null" json:"sender_wallet_id"`
+	RecipientWalletID   uuid.UUID  `gorm:"type:uuid;not null" json:"recipient_wallet_id"`
+	Amount              float64    `gorm:"type:decimal(20,8);not null" json:"amount"`
+	Currency            Currency   `gorm:"type:varchar(3);not null" json:"currency"`
+	Reference           string     `gorm:"type:varchar(100);uniqueIndex" json:"reference"`
+	IdempotencyKey      string     `gorm:"type:varchar(100);not null;uniqueIndex:idx_transfers_sender_idempotency_key" json:"-"`
+	DebitTransactionID  *uuid.UUID `gorm:"type:uuid" json:"debit_transaction_id,omitempty"`  // Sender's transfer_out transaction
+	CreditTransactionID *uuid.UUID `gorm:"type:uuid" json:"credit_transaction_id,omitempty"` // Recipient's transfer_in transaction
+	CreatedAt           time.Time  `gorm:"default:CURRENT_TIMESTAMP;index" json:"created_at"`
+	UpdatedAt           time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
 // AutoWithdrawConfig represents a user's auto-withdraw configuration
 type AutoWithdrawConfig struct {
 	ID             uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
@@ -67,3 +117,95 @@ type AutoWithdrawConfig struct {
 	UpdatedAt      time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// LedgerEntryType represents the direction of a wallet ledger entry
+type LedgerEntryType string
+
+const (
+	LedgerEntryCredit         LedgerEntryType = "credit"
+	LedgerEntryDebit          LedgerEntryType = "debit"
+	LedgerEntryOpeningBalance LedgerEntryType = "opening_balance"
+)
+
+// LedgerCategory classifies a wallet movement for bookkeeping. It is set by the code that
+// credits or debits the wallet.
+type LedgerCategory string
+
+const (
+	LedgerCategorySales      LedgerCategory = "sales"      // Customer payments
+	LedgerCategoryDeposit    LedgerCategory = "deposit"    // Top-ups not tied to a payment
+	LedgerCategoryRefund     LedgerCategory = "refund"     // Funds returned to the wallet
+	LedgerCategoryPayout     LedgerCategory = "payout"     // Withdrawals
+	LedgerCategoryFee        LedgerCategory = "fee"        // Platform and provider fees
+	LedgerCategoryReward     LedgerCategory = "reward"     // Referral and promotional rewards
+	LedgerCategoryAdjustment LedgerCategory = "adjustment" // Admin corrections and opening balances
+	LedgerCategoryTransfer   LedgerCategory = "transfer"   // Funds sent to or received from another user
+)
+
+// IsValid reports whether c is a known category
+func (c LedgerCategory) IsValid() bool {
+	switch c {
+	case LedgerCategorySales, LedgerCategoryDeposit, LedgerCategoryRefund, LedgerCategoryPayout,
+		LedgerCategoryFee, LedgerCategoryReward, LedgerCategoryAdjustment, LedgerCategoryTransfer:
+		return true
+	}
+	return false
+}
+
+// ErrLedgerEntryImmutable is returned when code tries to change or remove a ledger entry
+var ErrLedgerEntryImmutable = errors.New("wallet ledger entries are append-only")
+
+// WalletLedgerEntry is an append-only record of a balance movement.
+// A wallet's balance must always equal the sum of its entries' amounts.
+type WalletLedgerEntry struct {
+	ID            uuid.UUID       `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	WalletID      uuid.UUID       `gorm:"type:uuid;index;not null" json:"wallet_id"`
+	TransactionID *uuid.UUID      `gorm:"type:uuid;index" json:"transaction_id"`
+	EntryType     LedgerEntryType `gorm:"type:varchar(20);not null" json:"entry_type"`
+	Category      LedgerCategory  `gorm:"type:varchar(20);index" json:"category"`
+	Amount        float64         `gorm:"type:decimal(20,8);not null" json:"amount"` // Signed: positive for credits, negative for debits
+	BalanceAfter  float64         `gorm:"type:decimal(20,8);not null" json:"balance_after"`
+	Currency      Currency        `gorm:"type:varchar(3);not null" json:"currency"`
+	Reference     string          `gorm:"type:varchar(100)" json:"reference"`
+	CreatedAt     time.Time       `gorm:"default:CURRENT_TIMESTAMP;index" json:"created_at"`
+}
+
+// TableName keeps the ledger table name singular
+func (WalletLedgerEntry) TableName() string {
+	return "wallet_ledger"
+}
+
+// BeforeUpdate prevents ledger entries from being changed
+func (e *WalletLedgerEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrLedgerEntryImmutable
+}
+
+// BeforeDelete prevents ledger entries from being removed
+func (e *WalletLedgerEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrLedgerEntryImmutable
+}
+
+// WalletHoldStatus represents the state of a hold on wallet funds
+type WalletHoldStatus string
+
+const (
+	WalletHoldActive   WalletHoldStatus = "active"
+	WalletHoldCaptured WalletHoldStatus = "captured"
+	WalletHoldReleased WalletHoldStatus = "released"
+)
+
+// WalletHold reserves part of a wallet's available balance until the operation it
+// backs settles. Capturing a hold debits the funds; releasing it returns them to available.
+type WalletHold struct {
+	ID            uuid.UUID        `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	WalletID      uuid.UUID        `gorm:"type:uuid;index;not null" json:"wallet_id"`
+	Amount        float64          `gorm:"type:decimal(20,8);not null" json:"amount"`
+	Currency      Currency         `gorm:"type:varchar(3);not null" json:"currency"`
+	Status        WalletHoldStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	Reference     string           `gorm:"type:varchar(100)" json:"reference"`
+	Description   string           `gorm:"type:text" json:"description"`
+	TransactionID *uuid.UUID       `gorm:"type:uuid" json:"transaction_id"` // Debit transaction once captured
+	SettledAt     *time.Time       `json:"settled_at"`
+	CreatedAt     time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
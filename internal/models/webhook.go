@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookEventType represents the type of event sent to merchant webhook endpoints
+type WebhookEventType string
+
+const (
+	WebhookEventPaymentCompleted     WebhookEventType = "payment.completed"
+	WebhookEventPaymentExpired       WebhookEventType = "payment.expired"
+	WebhookEventWithdrawalProcessing WebhookEventType = "withdrawal.processing"
+	WebhookEventWithdrawalCompleted  WebhookEventType = "withdrawal.completed"
+	WebhookEventWithdrawalFailed     WebhookEventType = "withdrawal.failed"
+
+	// WebhookEventWalletBalanceChanged is only sent to endpoints with BalanceEvents set
+	WebhookEventWalletBalanceChanged WebhookEventType = "wallet.balance_changed"
+)
+
+// WebhookDeliveryStatus represents the status of an outbound webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSending   WebhookDeliveryStatus = "sending" // Claimed by the worker sending it
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookEndpoint represents a merchant URL that receives event notifications
+type WebhookEndpoint struct {
+	ID                uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	UserID            uuid.UUID      `gorm:"type:uuid;index" json:"user_id"`
+	User              User           `gorm:"foreignKey:UserID" json:"-"`
+	URL               string         `gorm:"type:varchar(500);not null" json:"url"`
+	Secret            string         `gorm:"type:varchar(100);not null" json:"-"`      // Used to sign payloads, only returned on creation
+	SecretVersion     int            `gorm:"not null;default:1" json:"secret_version"` // Sent with each delivery so merchants know which secret signed it
+	PendingSecret     string         `gorm:"type:varchar(100)" json:"-"`               // Replaces Secret when a rotation is finalized
+	RotationStartedAt *time.Time     `json:"rotation_started_at,omitempty"`            // Set while a rotation is in progress
+	Description       string         `gorm:"type:varchar(255)" json:"description"`
+	Active            bool           `gorm:"default:true" json:"active"`
+	BalanceEvents     bool           `gorm:"not null;default:false" json:"balance_events"` // Opts in to wallet.balance_changed events
+	CreatedAt         time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt         time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// WebhookDelivery represents a single event queued for delivery to a webhook endpoint
+type WebhookDelivery struct {
+	ID             uuid.UUID                `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	EndpointID     uuid.UUID                `gorm:"type:uuid;index" json:"endpoint_id"`
+	Endpoint       WebhookEndpoint          `gorm:"foreignKey:EndpointID" json:"-"`
+	UserID         uuid.UUID                `gorm:"type:uuid;index" json:"user_id"`
+	EventType      WebhookEventType         `gorm:"type:varchar(50);not null" json:"event_type"`
+	Payload        JSON                     `gorm:"type:jsonb" json:"payload"`
+	Status         WebhookDeliveryStatus    `gorm:"type:varchar(20);not null;index" json:"status"`
+	Attempts       int                      `gorm:"default:0" json:"attempts"`
+	LastStatusCode int                      `gorm:"default:0" json:"last_status_code"`
+	LastError      string                   `gorm:"type:text" json:"last_error"`
+	NextRetryAt    *time.Time               `gorm:"index" json:"next_retry_at"`
+	DeliveredAt    *time.Time               `json:"delivered_at"`
+	AttemptLogs    []WebhookDeliveryAttempt `gorm:"foreignKey:DeliveryID" json:"attempt_logs,omitempty"`
+	CreatedAt      time.Time                `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time                `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// WebhookDeliveryAttempt records the outcome of one HTTP attempt for a delivery
+type WebhookDeliveryAttempt struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	DeliveryID uuid.UUID `gorm:"type:uuid;index" json:"delivery_id"`
+	Attempt    int       `gorm:"not null" json:"attempt"`
+	StatusCode int       `gorm:"default:0" json:"status_code"`
+	Error      string    `gorm:"type:text" json:"error"`
+	DurationMs int64     `gorm:"default:0" json:"duration_ms"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
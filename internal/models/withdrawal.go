@@ -14,6 +14,7 @@ type Withdrawal struct {
 	User          User           `gorm:"foreignKey:UserID" json:"-"`
 	WalletID      uuid.UUID      `gorm:"type:uuid;index" json:"wallet_id"`
 	Wallet        Wallet         `gorm:"foreignKey:WalletID" json:"-"`
+	HoldID        *uuid.UUID     `gorm:"type:uuid" json:"hold_id"` // Funds reserved while the withdrawal is pending
 	Amount        float64        `gorm:"type:decimal(20,8);not null" json:"amount"`
 	Currency      Currency       `gorm:"type:varchar(3);not null" json:"currency"`
 	Method        string         `gorm:"type:varchar(50);not null" json:"method"` // bank, mobile_money, crypto
@@ -0,0 +1,151 @@
+// Package money rounds amounts to their currency's precision with an explicit rounding mode,
+// so fees and conversions round the same way everywhere instead of by float truncation
+package money
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/revaspay/backend/internal/currency"
+	"github.com/revaspay/backend/internal/models"
+)
+
+// Mode is how an amount between two minor units is rounded. Modes are symmetric around
+// zero, so a negative amount rounds the same way as its absolute value.
+type Mode string
+
+const (
+	// HalfUp rounds to the nearest unit, and halves away from zero (1.005 to 1.01)
+	HalfUp Mode = "half_up"
+	// HalfEven rounds to the nearest unit, and halves to the even unit (1.005 to 1.00, 1.015 to 1.02)
+	HalfEven Mode = "half_even"
+	// Down rounds toward zero, dropping any fraction of a unit (1.009 to 1.00)
+	Down Mode = "down"
+	// Up rounds away from zero when there's any fraction of a unit (1.001 to 1.01)
+	Up Mode = "up"
+)
+
+// ParseMode parses a rounding mode name. An empty name is HalfUp.
+func ParseMode(name string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(name))); mode {
+	case "":
+		return HalfUp, nil
+	case HalfUp, HalfEven, Down, Up:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown rounding mode %q, use half_up, half_even, down or up", name)
+	}
+}
+
+// Round rounds an amount to its currency's precision in currency.Default. Amounts in
+// unsupported currencies are returned unchanged, as they should have been rejected already.
+//
+// Digits more than noiseDigits past the precision are treated as float error and dropped
+// first, so 0.005 is exactly half a cent rather than the binary value just above it, and
+// 0.1+0.2 is 0.30 in every mode.
+func Round(amount float64, code models.Currency, mode Mode) float64 {
+	info, err := currency.Default.Lookup(code)
+	if err != nil {
+		return amount
+	}
+	return RoundTo(amount, info.Precision, mode)
+}
+
+// noiseDigits is how many digits past the precision are kept before rounding. Float error in
+// amounts of any realistic size is well beyond it.
+const noiseDigits = 6
+
+// RoundTo rounds an amount to the given number of decimal places
+func RoundTo(amount float64, precision int, mode Mode) float64 {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return amount
+	}
+
+	digits := strconv.FormatFloat(math.Abs(amount), 'f', precision+noiseDigits, 64)
+	whole, fraction, _ := strings.Cut(digits, ".")
+	kept, rest := fraction[:precision], fraction[precision:]
+
+	units, err := strconv.ParseInt(whole+kept, 10, 64)
+	if err != nil {
+		// Too large to count in minor units; no real amount gets here
+		return amount
+	}
+	if roundsAway(mode, rest, units%2 == 1) {
+		units++
+	}
+
+	rounded := float64(units) / math.Pow10(precision)
+	if amount < 0 {
+		return -rounded
+	}
+	return rounded
+}
+
+// roundsAway reports whether an amount whose digits past the precision are rest should be
+// rounded away from zero. odd is whether the last kept digit is odd.
+func roundsAway(mode Mode, rest string, odd bool) bool {
+	nonZero := strings.Trim(rest, "0") != ""
+	switch mode {
+	case Down:
+		return false
+	case Up:
+		return nonZero
+	case HalfEven:
+		if rest[0] != '5' {
+			return rest[0] > '5'
+		}
+		// Exactly half goes to the even unit; anything past half goes up
+		return strings.Trim(rest[1:], "0") != "" || odd
+	default:
+		return rest[0] >= '5'
+	}
+}
+
+// Policy is the rounding mode for each kind of amount
+type Policy struct {
+	Customer   Mode // Amounts shown to and charged or credited to customers and merchants
+	Fee        Mode // Our fees. Rounding them in our favour isn't allowed everywhere.
+	Conversion Mode // Amounts converted between currencies
+}
+
+// DefaultPolicy rounds everything to the nearest unit, halves away from zero
+var DefaultPolicy = Policy{Customer: HalfUp, Fee: HalfUp, Conversion: HalfUp}
+
+var (
+	policyMu sync.RWMutex
+	policy   = DefaultPolicy
+)
+
+// SetPolicy sets the rounding modes used by fee calculation and currency conversion. Call it
+// at startup.
+func SetPolicy(p Policy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	policy = p
+}
+
+// CurrentPolicy returns the rounding modes in use
+func CurrentPolicy() Policy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return policy
+}
+
+// ParsePolicy parses the rounding mode names of a policy. Empty names are HalfUp.
+func ParsePolicy(customer, fee, conversion string) (Policy, error) {
+	var p Policy
+	var err error
+	if p.Customer, err = ParseMode(customer); err != nil {
+		return Policy{}, fmt.Errorf("customer amounts: %w", err)
+	}
+	if p.Fee, err = ParseMode(fee); err != nil {
+		return Policy{}, fmt.Errorf("fees: %w", err)
+	}
+	if p.Conversion, err = ParseMode(conversion); err != nil {
+		return Policy{}, fmt.Errorf("conversions: %w", err)
+	}
+	return p, nil
+}
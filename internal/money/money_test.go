@@ -0,0 +1,81 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRound(t *testing.T) {
+	// Variables, so the sums below are computed in float64 at run time rather than exactly
+	tenth, fifth, price := 0.1, 0.2, 19.99
+
+	tests := []struct {
+		amount float64
+		mode   Mode
+		want   float64
+	}{
+		// Exactly half a cent, which math.Round(x*100) gets wrong for some values
+		{0.005, HalfUp, 0.01},
+		{0.005, HalfEven, 0},
+		{0.005, Down, 0},
+		{0.005, Up, 0.01},
+		{1.005, HalfUp, 1.01},
+		{1.005, HalfEven, 1.00},
+		{1.015, HalfEven, 1.02},
+		{2.675, HalfUp, 2.68},
+		{-0.005, HalfUp, -0.01},
+		{-1.005, HalfEven, -1.00},
+		{-1.009, Down, -1.00},
+		{-1.001, Up, -1.01},
+
+		// Just either side of half
+		{0.0049999, HalfUp, 0},
+		{0.0050001, HalfEven, 0.01},
+		{1.0050001, HalfEven, 1.01},
+
+		// Amounts already at the currency's precision are unchanged in every mode
+		{12.34, Down, 12.34},
+		{12.34, Up, 12.34},
+		{0, Up, 0},
+
+		// Float error from arithmetic is rounded away, not charged
+		{tenth + fifth, Up, 0.3},
+		{tenth + fifth, HalfUp, 0.3},
+		{price * 3, Down, 59.97},
+		{price * 3, Up, 59.97},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Round(tt.amount, models.CurrencyUSD, tt.mode), "Round(%v, %s)", tt.amount, tt.mode)
+	}
+}
+
+func TestRoundIsDeterministic(t *testing.T) {
+	// The same input rounds the same way however often it's rounded, and re-rounding is a no-op
+	for i := 0; i < 100; i++ {
+		rounded := Round(0.005, models.CurrencyGHS, HalfUp)
+		require.Equal(t, 0.01, rounded)
+		require.Equal(t, rounded, Round(rounded, models.CurrencyGHS, HalfUp))
+	}
+}
+
+func TestRoundTo(t *testing.T) {
+	assert.Equal(t, 1501.0, RoundTo(1500.5, 0, HalfUp))
+	assert.Equal(t, 1500.0, RoundTo(1500.5, 0, HalfEven))
+	assert.Equal(t, 0.123457, RoundTo(0.1234565, 6, HalfUp))
+}
+
+func TestRoundUnsupportedCurrency(t *testing.T) {
+	assert.Equal(t, 1.23456, Round(1.23456, models.Currency("XYZ"), Down))
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("", "DOWN", "half_even")
+	require.NoError(t, err)
+	assert.Equal(t, Policy{Customer: HalfUp, Fee: Down, Conversion: HalfEven}, p)
+
+	_, err = ParsePolicy("half_up", "bankers", "")
+	assert.ErrorContains(t, err, "fees")
+}
@@ -0,0 +1,81 @@
+// Package pagination reads list endpoints' paging parameters and describes the page returned
+package pagination
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxPageSize is the largest page size list endpoints return. Larger requested sizes are clamped.
+const MaxPageSize = 100
+
+// Params is the page a list request asked for
+type Params struct {
+	Page     int
+	PageSize int
+}
+
+// Offset returns the number of records before the page
+func (p Params) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// ParseParams reads the page and page_size query parameters, accepting limit as an alias of
+// page_size. Missing or invalid values fall back to page 1 and defaultPageSize, and page sizes
+// over MaxPageSize are clamped to it.
+func ParseParams(c *gin.Context, defaultPageSize int) Params {
+	return ParseParamsWithMax(c, defaultPageSize, MaxPageSize)
+}
+
+// ParseParamsWithMax is ParseParams for endpoints allowing pages larger than MaxPageSize
+func ParseParamsWithMax(c *gin.Context, defaultPageSize, maxPageSize int) Params {
+	pageSize := positiveInt(c.Query("page_size"), 0)
+	if pageSize == 0 {
+		pageSize = positiveInt(c.Query("limit"), defaultPageSize)
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	// Bound the page so the offset can't overflow
+	page := positiveInt(c.Query("page"), 1)
+	if maxPage := math.MaxInt32 / pageSize; page > maxPage {
+		page = maxPage
+	}
+
+	return Params{Page: page, PageSize: pageSize}
+}
+
+// positiveInt parses a positive integer, returning fallback for anything else
+func positiveInt(value string, fallback int) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return fallback
+	}
+	return n
+}
+
+// Meta describes the page a list endpoint returned
+type Meta struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	Total      int64 `json:"total"`
+	TotalPages int64 `json:"total_pages"`
+}
+
+// NewMeta describes the page params selected out of total records
+func NewMeta(params Params, total int64) Meta {
+	pageSize := int64(params.PageSize)
+	if pageSize < 1 {
+		pageSize = 1
+	}
+
+	return Meta{
+		Page:       params.Page,
+		PageSize:   params.PageSize,
+		Total:      total,
+		TotalPages: (total + pageSize - 1) / pageSize,
+	}
+}
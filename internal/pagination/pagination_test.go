@@ -0,0 +1,54 @@
+package pagination
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func contextWithQuery(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?"+query, nil)
+	return c
+}
+
+func TestParseParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected Params
+	}{
+		{"defaults", "", Params{Page: 1, PageSize: 20}},
+		{"explicit", "page=3&page_size=50", Params{Page: 3, PageSize: 50}},
+		{"limit alias", "page=2&limit=15", Params{Page: 2, PageSize: 15}},
+		{"page_size wins over limit", "page_size=30&limit=15", Params{Page: 1, PageSize: 30}},
+		{"large page size is clamped", "page_size=100000", Params{Page: 1, PageSize: MaxPageSize}},
+		{"invalid values fall back", "page=-4&page_size=abc", Params{Page: 1, PageSize: 20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseParams(contextWithQuery(tt.query), 20))
+		})
+	}
+}
+
+func TestParseParamsBoundsOffset(t *testing.T) {
+	params := ParseParams(contextWithQuery("page=999999999999&page_size=100"), 20)
+	assert.Greater(t, params.Page, 1)
+	assert.GreaterOrEqual(t, params.Offset(), 0)
+}
+
+func TestParseParamsWithMax(t *testing.T) {
+	params := ParseParamsWithMax(contextWithQuery("page_size=500"), 50, 200)
+	assert.Equal(t, 200, params.PageSize)
+}
+
+func TestNewMeta(t *testing.T) {
+	assert.Equal(t, Meta{Page: 1, PageSize: 10, Total: 0, TotalPages: 0}, NewMeta(Params{Page: 1, PageSize: 10}, 0))
+	assert.Equal(t, int64(1), NewMeta(Params{Page: 1, PageSize: 10}, 10).TotalPages)
+	assert.Equal(t, int64(2), NewMeta(Params{Page: 1, PageSize: 10}, 11).TotalPages)
+	assert.Equal(t, Params{Page: 3, PageSize: 25}.Offset(), 50)
+}
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/revaspay/backend/internal/metrics"
 )
 
 // JobProcessorHandler is a function that processes a job's payload
@@ -20,8 +23,13 @@ type JobProcessor struct {
 	stopChan       chan struct{}
 	wg             sync.WaitGroup
 	processingJobs sync.Map
+	jobTimeout     time.Duration
 	ctx            context.Context
 	cancel         context.CancelFunc
+
+	dispatchMu sync.Mutex
+	limits     map[string]int // Most jobs of each type that may run at once
+	inFlight   map[string]int // Jobs of each type running now
 }
 
 // NewJobProcessor creates a new JobProcessor
@@ -32,9 +40,34 @@ func NewJobProcessor(queue *RedisQueue, workerCount int) *JobProcessor {
 		handlers:    make(map[string]JobProcessorHandler),
 		workerCount: workerCount,
 		stopChan:    make(chan struct{}),
+		jobTimeout:  DefaultJobTimeout,
 		ctx:         ctx,
 		cancel:      cancel,
+		limits:      make(map[string]int),
+		inFlight:    make(map[string]int),
+	}
+}
+
+// SetJobTimeout sets how long each job may run before its context's deadline passes. A
+// timeout of zero or less restores DefaultJobTimeout.
+func (p *JobProcessor) SetJobTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultJobTimeout
+	}
+	p.jobTimeout = timeout
+}
+
+// SetConcurrencyLimit sets the most jobs of a type that may run at once, so one slow job type
+// can't take every worker. A limit of zero or less removes it, letting the type's jobs use
+// all the workers.
+func (p *JobProcessor) SetConcurrencyLimit(jobType string, limit int) {
+	p.dispatchMu.Lock()
+	defer p.dispatchMu.Unlock()
+	if limit <= 0 {
+		delete(p.limits, jobType)
+		return
 	}
+	p.limits[jobType] = limit
 }
 
 // RegisterHandler registers a handler for a specific queue
@@ -42,6 +75,91 @@ func (p *JobProcessor) RegisterHandler(queueName string, handler JobProcessorHan
 	p.handlers[queueName] = handler
 }
 
+// handler returns the handler for a job type. Handlers registered on the processor take
+// precedence over those registered on its queue.
+func (p *JobProcessor) handler(jobType string) (JobProcessorHandler, bool) {
+	if handler, ok := p.handlers[jobType]; ok {
+		return handler, true
+	}
+	if handler, ok := p.queue.handlers[JobType(jobType)]; ok {
+		return JobProcessorHandler(handler), true
+	}
+	return nil, false
+}
+
+// JobTypes returns the job types the processor has a handler for
+func (p *JobProcessor) JobTypes() []string {
+	seen := make(map[string]bool, len(p.handlers)+len(p.queue.handlers))
+	jobTypes := make([]string, 0, len(seen))
+	for jobType := range p.handlers {
+		seen[jobType] = true
+		jobTypes = append(jobTypes, jobType)
+	}
+	for jobType := range p.queue.handlers {
+		if !seen[string(jobType)] {
+			jobTypes = append(jobTypes, string(jobType))
+		}
+	}
+	sort.Strings(jobTypes)
+	return jobTypes
+}
+
+// InFlight returns how many jobs of a type are running on the processor's workers
+func (p *JobProcessor) InFlight(jobType string) int {
+	p.dispatchMu.Lock()
+	defer p.dispatchMu.Unlock()
+	return p.inFlight[jobType]
+}
+
+// GetQueueStats counts the jobs of one type waiting in Redis, along with those running on
+// this processor's workers
+func (p *JobProcessor) GetQueueStats(jobType string) (*QueueStats, error) {
+	stats, err := p.queue.GetQueueStats(jobType)
+	if err != nil {
+		return nil, err
+	}
+
+	p.dispatchMu.Lock()
+	defer p.dispatchMu.Unlock()
+	stats.InFlight = p.inFlight[jobType]
+	stats.Processing = stats.InFlight
+	stats.ConcurrencyLimit = p.limits[jobType]
+	return stats, nil
+}
+
+// nextJob takes the next job for a worker from the queues whose job type is below its
+// concurrency limit, and counts it as in flight. Workers take jobs one at a time, so a limit
+// is never exceeded by two workers taking the last slot together.
+func (p *JobProcessor) nextJob(queues []string, poll int) (*RedisJob, error) {
+	p.dispatchMu.Lock()
+	defer p.dispatchMu.Unlock()
+
+	available := make([]string, 0, len(queues))
+	for _, queueName := range queues {
+		if limit, ok := p.limits[queueName]; !ok || p.inFlight[queueName] < limit {
+			available = append(available, queueName)
+		}
+	}
+	if len(available) == 0 {
+		return nil, nil
+	}
+
+	job, err := p.queue.DequeueNext(available, priorityOrder(poll))
+	if job != nil {
+		p.inFlight[job.Queue]++
+	}
+	return job, err
+}
+
+// finishJob releases a job's concurrency slot once it has run
+func (p *JobProcessor) finishJob(job *RedisJob) {
+	p.dispatchMu.Lock()
+	defer p.dispatchMu.Unlock()
+	if p.inFlight[job.Queue]--; p.inFlight[job.Queue] <= 0 {
+		delete(p.inFlight, job.Queue)
+	}
+}
+
 // Start starts the job processor
 func (p *JobProcessor) Start() {
 	log.Printf("Starting job processor with %d workers", p.workerCount)
@@ -69,10 +187,7 @@ func (p *JobProcessor) worker(id int) {
 	log.Printf("Worker %d started", id)
 	
 	// List of queues to poll
-	queues := make([]string, 0, len(p.handlers))
-	for queue := range p.handlers {
-		queues = append(queues, queue)
-	}
+	queues := p.JobTypes()
 	
 	// If no queues are registered, exit
 	if len(queues) == 0 {
@@ -80,50 +195,38 @@ func (p *JobProcessor) worker(id int) {
 		return
 	}
 	
-	// Process jobs until stopped
-	for {
+	// Process jobs until stopped, highest priority first across all queues
+	for poll := 0; ; poll++ {
 		select {
 		case <-p.stopChan:
 			log.Println("Worker stopping")
 			return
 		default:
-			// Process jobs from each queue
-			for _, queueName := range queues {
-				// Try to get a job from the queue
-				redisJob, err := p.queue.Dequeue(queueName)
-				
-				if err != nil {
-					log.Printf("Worker %d error getting job from queue %s: %v", id, queueName, err)
-					continue
-				}
-				
-				if redisJob == nil {
-					// No jobs available in this queue
-					continue
-				}
-				
-				// Process the job
-				jobID := redisJob.ID
-				p.processingJobs.Store(jobID, true)
-				err = p.ProcessJob(redisJob)
-				p.processingJobs.Delete(jobID)
-				
-				if err != nil {
-					log.Printf("Worker %d error processing job %s: %v", id, jobID, err)
-				}
-				
-				// Only process one job per iteration to give other queues a chance
-				break
+			redisJob, err := p.nextJob(queues, poll)
+			if err != nil {
+				log.Printf("Worker %d error getting job: %v", id, err)
+			}
+			
+			if redisJob == nil {
+				// No jobs available, so sleep briefly to avoid hammering Redis
+				time.Sleep(100 * time.Millisecond)
+				continue
 			}
 			
-			// Sleep briefly to avoid hammering Redis
-			time.Sleep(100 * time.Millisecond)
+			// Process the job
+			jobID := redisJob.ID
+			p.processingJobs.Store(jobID, true)
+			err = p.ProcessJob(redisJob)
+			p.processingJobs.Delete(jobID)
+			p.finishJob(redisJob)
+			
+			if err != nil {
+				log.Printf("Worker %d error processing job %s: %v", id, jobID, err)
+			}
 		}
 	}
 }
 
-
-
 // ProcessJob processes a single job
 func (p *JobProcessor) ProcessJob(redisJob *RedisJob) error {
 	if redisJob == nil {
@@ -134,7 +237,7 @@ func (p *JobProcessor) ProcessJob(redisJob *RedisJob) error {
 	job := redisJob.ConvertToJob()
 	
 	// Check if we have a handler for this job type
-	handler, ok := p.handlers[string(job.Type)]
+	handler, ok := p.handler(string(job.Type))
 	if !ok {
 		// Mark job as failed
 		p.queue.Fail(redisJob.ID, fmt.Errorf("no handler registered for job type: %s", job.Type))
@@ -142,8 +245,20 @@ func (p *JobProcessor) ProcessJob(redisJob *RedisJob) error {
 	}
 	
 	// Process the job
-	_, err := handler(p.ctx, *job)
+	ctx, cancel := context.WithTimeout(p.ctx, p.jobTimeout)
+	defer cancel()
+	start := time.Now()
+	_, err := handler(ctx, *job)
+	metrics.ObserveJob(string(job.Type), err, time.Since(start))
 	if err != nil {
+		// Jobs turned away by an open provider circuit breaker wait for it without using up a retry
+		if delay, ok := circuitOpenDelay(err); ok {
+			if deferErr := p.queue.Defer(redisJob.ID, delay); deferErr != nil {
+				log.Printf("Failed to reschedule job %s: %v", redisJob.ID, deferErr)
+			}
+			return fmt.Errorf("job deferred for %v: %w", delay, err)
+		}
+
 		// Mark job as failed
 		p.queue.Fail(redisJob.ID, err)
 		return fmt.Errorf("job processing failed: %w", err)
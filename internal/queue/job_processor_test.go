@@ -0,0 +1,39 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobProcessorConcurrencyLimit(t *testing.T) {
+	redisQueue := NewRedisQueue(nil, nil)
+	redisQueue.RegisterHandler("process_kyc_verification", func(ctx context.Context, job Job) (interface{}, error) {
+		return nil, nil
+	})
+	p := NewJobProcessor(redisQueue, 5)
+	p.RegisterHandler("payment_webhook", func(ctx context.Context, job Job) (interface{}, error) {
+		return nil, nil
+	})
+
+	assert.Equal(t, []string{"payment_webhook", "process_kyc_verification"}, p.JobTypes())
+	_, ok := p.handler("process_kyc_verification")
+	assert.True(t, ok, "handlers registered on the queue are used")
+
+	p.SetConcurrencyLimit("process_kyc_verification", 1)
+	p.inFlight["process_kyc_verification"] = 1
+
+	// Every queue is at its limit, so no job is taken and Redis isn't asked for one
+	job, err := p.nextJob([]string{"process_kyc_verification"}, 0)
+	require.NoError(t, err)
+	assert.Nil(t, job)
+	assert.Equal(t, 1, p.InFlight("process_kyc_verification"))
+
+	p.finishJob(&RedisJob{Queue: "process_kyc_verification"})
+	assert.Equal(t, 0, p.InFlight("process_kyc_verification"))
+
+	p.SetConcurrencyLimit("process_kyc_verification", 0)
+	assert.NotContains(t, p.limits, "process_kyc_verification")
+}
@@ -0,0 +1,58 @@
+package queue
+
+import (
+	"log"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StatsSource is a queue that can report how many jobs it holds in each state
+type StatsSource interface {
+	GetQueueStats(queueName string) (*QueueStats, error)
+}
+
+// DepthCollector exports queue depth gauges, reading them from the queue on each scrape
+type DepthCollector struct {
+	source StatsSource
+	queues func() []string
+	depth  *prometheus.Desc
+}
+
+// NewDepthCollector creates a collector reporting the depth of the queues named by queues.
+// They're listed on each scrape so queues registered after startup are included.
+func NewDepthCollector(source StatsSource, queues func() []string) *DepthCollector {
+	return &DepthCollector{
+		source: source,
+		queues: queues,
+		depth: prometheus.NewDesc(
+			"revaspay_queue_jobs",
+			"Jobs in each queue by state.",
+			[]string{"queue", "state"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *DepthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.depth
+}
+
+// Collect implements prometheus.Collector. Queues whose stats can't be read are left out
+// of the scrape rather than failing it.
+func (c *DepthCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, queueName := range c.queues() {
+		stats, err := c.source.GetQueueStats(queueName)
+		if err != nil {
+			log.Printf("Failed to get stats for queue %s: %v", queueName, err)
+			continue
+		}
+		for state, count := range map[string]int{
+			"waiting":    stats.Waiting,
+			"processing": stats.Processing,
+			"delayed":    stats.Delayed,
+			"failed":     stats.Failed,
+		} {
+			ch <- prometheus.MustNewConstMetric(c.depth, prometheus.GaugeValue, float64(count), queueName, state)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/services/notification"
+)
+
+// NotifyPaymentStatusPayload represents the payload for notifying a user of a status change.
+// Exactly one of PaymentID or WithdrawalID is set.
+type NotifyPaymentStatusPayload struct {
+	PaymentID    uuid.UUID `json:"payment_id,omitempty"`
+	WithdrawalID uuid.UUID `json:"withdrawal_id,omitempty"`
+	Status       string    `json:"status"`
+}
+
+// NewNotificationJobHandlers creates the handlers that turn status changes into user notifications
+func NewNotificationJobHandlers(notificationService *notification.Service) map[JobType]JobHandler {
+	handlers := make(map[JobType]JobHandler)
+
+	handlers[JobTypeNotifyPaymentStatus] = func(ctx context.Context, job Job) (interface{}, error) {
+		var payload NotifyPaymentStatusPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal notification payload: %w", err)
+		}
+
+		switch {
+		case payload.PaymentID != uuid.Nil:
+			log.Printf("Notifying payment %s status: %s", payload.PaymentID, payload.Status)
+			if err := notificationService.NotifyPaymentStatus(payload.PaymentID, payload.Status); err != nil {
+				return nil, fmt.Errorf("failed to notify payment status: %w", err)
+			}
+		case payload.WithdrawalID != uuid.Nil:
+			log.Printf("Notifying withdrawal %s status: %s", payload.WithdrawalID, payload.Status)
+			if err := notificationService.NotifyWithdrawalStatus(payload.WithdrawalID, payload.Status); err != nil {
+				return nil, fmt.Errorf("failed to notify withdrawal status: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("notification payload has no payment or withdrawal ID")
+		}
+
+		return nil, nil
+	}
+
+	return handlers
+}
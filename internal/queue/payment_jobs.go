@@ -104,33 +104,18 @@ func NewPaymentJobHandlers(
 			return handlePaymentError(db, payment.ID, "compliance_failed", errorMsg)
 		}
 
-		// 2. Create bank transaction record
-		bankTx := database.GhanaBankTransaction{
-			ID:            uuid.New(),
-			UserID:        payloadData.UserID,
-			BankAccountID: payloadData.BankAccountID,
-			Type:          "debit",
-			Amount:        payloadData.Amount,
-			Currency:      payloadData.Currency,
-			Description:   payloadData.Description,
-			Reference:     payloadData.Reference,
-			Status:        "pending",
-			CreatedAt:     time.Now(),
-			UpdatedAt:     time.Now(),
-		}
-		if err := db.Create(&bankTx).Error; err != nil {
-			return handlePaymentError(db, payment.ID, "bank_tx_creation_failed", err.Error())
+		// 2. Load the bank and crypto transactions created alongside the payment
+		var bankTx database.GhanaBankTransaction
+		if err := db.First(&bankTx, "id = ?", payment.BankTransactionID).Error; err != nil {
+			return handlePaymentError(db, payment.ID, "bank_tx_not_found", err.Error())
 		}
 
-		// 3. Update payment with bank transaction ID
-		if err := db.Model(&payment).Updates(map[string]interface{}{
-			"bank_transaction_id": bankTx.ID,
-			"updated_at":          time.Now(),
-		}).Error; err != nil {
-			return nil, fmt.Errorf("failed to update payment with bank transaction ID: %w", err)
+		var cryptoTx database.CryptoTransaction
+		if err := db.First(&cryptoTx, "id = ?", payment.CryptoTxID).Error; err != nil {
+			return handlePaymentError(db, payment.ID, "crypto_tx_not_found", err.Error())
 		}
 
-		// 4. Process bank transaction (in a real system, this would interact with Ghana banking APIs)
+		// 3. Process bank transaction (in a real system, this would interact with Ghana banking APIs)
 		// Simulate bank processing
 		time.Sleep(2 * time.Second)
 
@@ -142,42 +127,11 @@ func NewPaymentJobHandlers(
 			return handlePaymentError(db, payment.ID, "bank_tx_update_failed", err.Error())
 		}
 
-		// 5. Create crypto transaction record
-		// Convert amount to USDC (or appropriate stablecoin)
-		// In a real system, this would use an exchange rate service
-		exchangeRate := 0.085 // Example GHS to USD rate
-		cryptoAmount := payloadData.Amount * exchangeRate
-
-		cryptoTx := database.CryptoTransaction{
-			ID:                    uuid.New(),
-			UserID:                payloadData.UserID,
-			WalletID:              payloadData.WalletID,
-			Type:                  "send",
-			Amount:                fmt.Sprintf("%.2f", cryptoAmount),
-			TokenSymbol:           "USDC",
-			RecipientAddress:      payloadData.RecipientAddress,
-			Status:                "created",
-			InternationalPaymentID: payment.ID,
-			CreatedAt:             time.Now(),
-			UpdatedAt:             time.Now(),
-		}
-		if err := db.Create(&cryptoTx).Error; err != nil {
-			return handlePaymentError(db, payment.ID, "crypto_tx_creation_failed", err.Error())
-		}
-
-		// 6. Update payment with crypto transaction ID
-		if err := db.Model(&payment).Updates(map[string]interface{}{
-			"crypto_transaction_id": cryptoTx.ID,
-			"updated_at":            time.Now(),
-		}).Error; err != nil {
-			return nil, fmt.Errorf("failed to update payment with crypto transaction ID: %w", err)
-		}
-
-		// 7. Queue job to send the blockchain transaction
+		// 4. Queue job to send the blockchain transaction
 		sendTxPayload := SendTransactionPayload{
 			TransactionID:         cryptoTx.ID,
-			FromWalletID:          payloadData.WalletID,
-			ToAddress:             payloadData.RecipientAddress,
+			FromWalletID:          cryptoTx.WalletID,
+			ToAddress:             cryptoTx.RecipientAddress,
 			Amount:                cryptoTx.Amount,
 			TokenSymbol:           cryptoTx.TokenSymbol,
 			InternationalPaymentID: payment.ID,
@@ -190,7 +144,7 @@ func NewPaymentJobHandlers(
 
 		log.Printf("Queued send transaction job %s for payment %s", jobID, payment.ID)
 
-		// 8. Generate compliance report asynchronously
+		// 5. Generate compliance report asynchronously
 		reportPayload := ComplianceReportPayload{
 			UserID:          payloadData.UserID,
 			TransactionID:   payment.ID,
@@ -227,30 +181,14 @@ func NewPaymentJobHandlers(
 
 		log.Printf("Generating compliance report for transaction ID: %s", payloadData.TransactionID)
 
-		// Generate compliance report
-		reportJSON, err := complianceService.GenerateComplianceReport(
-			payloadData.UserID,
-			payloadData.TransactionID,
-			payloadData.TransactionType,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate compliance report: %w", err)
-		}
-
-		// Store report in database
-		report := database.ComplianceReport{
-			ID:                    uuid.New(),
-			UserID:                payloadData.UserID,
-			InternationalPaymentID: payloadData.TransactionID,
-			ReportType:            payloadData.TransactionType,
-			ReportData:            reportJSON,
-			Status:                "generated",
-			CreatedAt:             time.Now(),
-			UpdatedAt:             time.Now(),
+		if payloadData.TransactionType != "international_payment" {
+			return nil, fmt.Errorf("unsupported compliance report type: %s", payloadData.TransactionType)
 		}
 
-		if err := db.Create(&report).Error; err != nil {
-			return nil, fmt.Errorf("failed to store compliance report: %w", err)
+		// Generate and store the report; this is a no-op if one already exists
+		report, err := complianceService.GenerateInternationalPaymentReport(payloadData.TransactionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate compliance report: %w", err)
 		}
 
 		log.Printf("Compliance report generated successfully: %s", report.ID)
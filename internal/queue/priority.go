@@ -0,0 +1,84 @@
+package queue
+
+import "sync"
+
+// Priority decides which waiting jobs workers take first
+type Priority string
+
+// Job priorities
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// Workers lead with a lower priority on some polls, so a steady stream of high priority jobs
+// can't starve the rest: every 4th poll tries normal priority jobs first and every 10th tries
+// low priority jobs first.
+const (
+	normalPriorityTurn = 4
+	lowPriorityTurn    = 10
+)
+
+// defaultPriorities holds the priority of each job type's jobs when none is given on enqueue
+var (
+	defaultPrioritiesMu sync.RWMutex
+	defaultPriorities   = map[JobType]Priority{
+		JobTypeProcessPayment:  PriorityHigh,
+		JobTypeSendTransaction: PriorityHigh,
+	}
+)
+
+// SetDefaultPriority sets the priority of a job type's jobs when none is given on enqueue
+func SetDefaultPriority(jobType JobType, priority Priority) {
+	defaultPrioritiesMu.Lock()
+	defer defaultPrioritiesMu.Unlock()
+	defaultPriorities[jobType] = priority.normalize()
+}
+
+// PriorityFor returns the default priority of a job type's jobs, which is PriorityNormal
+// unless one was set
+func PriorityFor(jobType JobType) Priority {
+	defaultPrioritiesMu.RLock()
+	defer defaultPrioritiesMu.RUnlock()
+	if priority, ok := defaultPriorities[jobType]; ok {
+		return priority
+	}
+	return PriorityNormal
+}
+
+// normalize treats unknown priorities, including those of jobs queued before priorities
+// existed, as PriorityNormal
+func (p Priority) normalize() Priority {
+	switch p {
+	case PriorityHigh, PriorityLow:
+		return p
+	default:
+		return PriorityNormal
+	}
+}
+
+// priorityListKey returns the Redis list holding a queue's jobs of one priority. Normal
+// priority jobs stay on the queue's own list.
+func priorityListKey(queueName string, priority Priority) string {
+	switch priority.normalize() {
+	case PriorityHigh:
+		return queueName + ":high"
+	case PriorityLow:
+		return queueName + ":low"
+	default:
+		return queueName
+	}
+}
+
+// priorityOrder returns the order in which a worker checks priorities on its nth poll
+func priorityOrder(poll int) []Priority {
+	switch {
+	case poll%lowPriorityTurn == lowPriorityTurn-1:
+		return []Priority{PriorityLow, PriorityNormal, PriorityHigh}
+	case poll%normalPriorityTurn == normalPriorityTurn-1:
+		return []Priority{PriorityNormal, PriorityHigh, PriorityLow}
+	default:
+		return []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+	}
+}
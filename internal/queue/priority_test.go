@@ -0,0 +1,38 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorityFor(t *testing.T) {
+	jobType := JobType("test_priority")
+	assert.Equal(t, PriorityNormal, PriorityFor(jobType))
+
+	SetDefaultPriority(jobType, PriorityLow)
+	assert.Equal(t, PriorityLow, PriorityFor(jobType))
+
+	SetDefaultPriority(jobType, Priority("urgent"))
+	assert.Equal(t, PriorityNormal, PriorityFor(jobType))
+}
+
+func TestPriorityListKey(t *testing.T) {
+	assert.Equal(t, "payment_webhook:high", priorityListKey("payment_webhook", PriorityHigh))
+	assert.Equal(t, "payment_webhook", priorityListKey("payment_webhook", PriorityNormal))
+	assert.Equal(t, "payment_webhook", priorityListKey("payment_webhook", ""))
+	assert.Equal(t, "payment_webhook:low", priorityListKey("payment_webhook", PriorityLow))
+}
+
+func TestPriorityOrderAvoidsStarvation(t *testing.T) {
+	first := make(map[Priority]int)
+	for poll := 0; poll < 100; poll++ {
+		order := priorityOrder(poll)
+		assert.ElementsMatch(t, []Priority{PriorityHigh, PriorityNormal, PriorityLow}, order)
+		first[order[0]]++
+	}
+
+	assert.Greater(t, first[PriorityHigh], first[PriorityNormal])
+	assert.Greater(t, first[PriorityNormal], first[PriorityLow])
+	assert.NotZero(t, first[PriorityLow], "low priority jobs must get a turn")
+}
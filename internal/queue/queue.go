@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/metrics"
 	"gorm.io/gorm"
 )
 
@@ -26,6 +29,10 @@ const (
 	JobTypeNotifyPaymentStatus          JobType = "notify_payment_status"
 )
 
+// DefaultJobTimeout bounds each job when no timeout is configured. Handlers get a context
+// with this deadline and pass it on to the provider calls they make.
+const DefaultJobTimeout = 5 * time.Minute
+
 // JobStatus defines the status of a job
 type JobStatus string
 
@@ -56,7 +63,10 @@ type Queue struct {
 	db          *gorm.DB
 	handlers    map[JobType]JobHandler
 	retryHandler *RetryHandler
-	processing bool
+	jobTimeout  time.Duration
+	lastPoll    atomic.Int64 // Unix nanoseconds of the processor's last pass over the queue
+	processing  atomic.Bool
+	workers     sync.WaitGroup // Tracks the processing loop so shutdown can wait for it
 }
 
 // QueueInterface defines the interface for job queue operations
@@ -78,6 +88,7 @@ func NewQueue(db *gorm.DB) *Queue {
 	q := &Queue{
 		db:       db,
 		handlers: make(map[JobType]JobHandler),
+		jobTimeout: DefaultJobTimeout,
 	}
 	
 	// Create retry handler with reference to this queue
@@ -94,6 +105,15 @@ func (q *Queue) RegisterHandler(jobType JobType, handler JobHandler) {
 	q.handlers[jobType] = handler
 }
 
+// SetJobTimeout sets how long each job may run before its context's deadline passes. A
+// timeout of zero or less restores DefaultJobTimeout.
+func (q *Queue) SetJobTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultJobTimeout
+	}
+	q.jobTimeout = timeout
+}
+
 // EnqueueJob adds a job to the queue
 func (q *Queue) EnqueueJob(jobType JobType, payload interface{}) (string, error) {
 	payloadBytes, err := json.Marshal(payload)
@@ -102,12 +122,13 @@ func (q *Queue) EnqueueJob(jobType JobType, payload interface{}) (string, error)
 	}
 
 	job := Job{
-		ID:        uuid.New(),
-		Type:      jobType,
-		Payload:   payloadBytes,
-		Status:    JobStatusPending,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:         uuid.New(),
+		Type:       jobType,
+		Payload:    payloadBytes,
+		Status:     JobStatusPending,
+		MaxRetries: RetryPolicyFor(jobType).MaxRetries,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
 	}
 
 	// Save job to database using GORM
@@ -165,13 +186,16 @@ func (q *Queue) ProcessJobs() {
 
 // StartProcessing starts processing jobs from the queue
 func (q *Queue) StartProcessing() {
-	if q.processing {
+	if !q.processing.CompareAndSwap(false, true) {
 		return
 	}
 
-	q.processing = true
+	q.workers.Add(1)
 	go func() {
-		for q.processing {
+		defer q.workers.Done()
+		for q.processing.Load() {
+			q.lastPoll.Store(time.Now().UnixNano())
+			
 			// Get a job from the queue
 			var job Job
 			err := q.db.Model(&Job{}).Where("status = ?", JobStatusPending).First(&job).Error
@@ -189,6 +213,16 @@ func (q *Queue) StartProcessing() {
 	}()
 }
 
+// LastPoll returns when the processor last looked for a job, or the zero time if it
+// has never run. A processor stuck in a job or stopped stops advancing it.
+func (q *Queue) LastPoll() time.Time {
+	nanos := q.lastPoll.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
 func (q *Queue) processJob(job Job) {
 	handler, ok := q.handlers[job.Type]
 	if !ok {
@@ -206,7 +240,11 @@ func (q *Queue) processJob(job Job) {
 	}
 
 	// Process the job
-	result, err := handler(context.Background(), job)
+	ctx, cancel := context.WithTimeout(context.Background(), q.jobTimeout)
+	defer cancel()
+	start := time.Now()
+	result, err := handler(ctx, job)
+	metrics.ObserveJob(string(job.Type), err, time.Since(start))
 
 	// Handle job result
 	if err != nil {
@@ -249,9 +287,72 @@ func (q *Queue) processJob(job Job) {
 	}
 }
 
-// StopProcessing stops processing jobs
+// JobTypes returns the job types with a registered handler
+func (q *Queue) JobTypes() []string {
+	jobTypes := make([]string, 0, len(q.handlers))
+	for jobType := range q.handlers {
+		jobTypes = append(jobTypes, string(jobType))
+	}
+	return jobTypes
+}
+
+// GetQueueStats counts the jobs of one type by status. Pending jobs waiting out a retry
+// backoff are counted as delayed.
+func (q *Queue) GetQueueStats(jobType string) (*QueueStats, error) {
+	var counts []struct {
+		Status  JobStatus
+		Delayed bool
+		Count   int
+	}
+	err := q.db.Model(&Job{}).
+		Select("status, (next_retry IS NOT NULL AND next_retry > ?) AS delayed, COUNT(*) AS count", time.Now()).
+		Where("type = ?", jobType).
+		Group("1, 2").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	stats := &QueueStats{Queue: jobType}
+	for _, count := range counts {
+		switch {
+		case count.Status == JobStatusPending && count.Delayed:
+			stats.Delayed += count.Count
+		case count.Status == JobStatusPending:
+			stats.Waiting += count.Count
+		case count.Status == JobStatusProcessing:
+			stats.Processing += count.Count
+		case count.Status == JobStatusFailed:
+			stats.Failed += count.Count
+		case count.Status == JobStatusCompleted:
+			stats.Completed += count.Count
+		}
+	}
+	return stats, nil
+}
+
+// StopProcessing stops processing jobs. A job already running is left to finish.
 func (q *Queue) StopProcessing() {
-	q.processing = false
+	q.processing.Store(false)
+}
+
+// Shutdown stops the processor taking new jobs and waits for the job in flight to finish.
+// It returns ctx's error if ctx is done first, leaving the job running.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.StopProcessing()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // Close stops all processing
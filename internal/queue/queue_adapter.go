@@ -34,10 +34,21 @@ func (a *QueueAdapter) Enqueue(job *Job) error {
 		return fmt.Errorf("failed to unmarshal job payload: %w", err)
 	}
 	
+	if job.NextRetry != nil {
+		// Delayed jobs, such as recurring runs, wait in the scheduled set until they're due
+		_, err := a.redisQueue.Schedule(string(job.Type), payload, *job.NextRetry)
+		return err
+	}
+
 	_, err := a.redisQueue.Enqueue(string(job.Type), payload)
 	return err
 }
 
+// EnqueueJob adds a job of the given type to the queue and returns its ID
+func (a *QueueAdapter) EnqueueJob(jobType JobType, payload interface{}) (string, error) {
+	return a.redisQueue.Enqueue(string(jobType), payload)
+}
+
 // Dequeue gets a job from the queue
 func (a *QueueAdapter) Dequeue(queueName string) (*RedisJob, error) {
 	return a.redisQueue.Dequeue(queueName)
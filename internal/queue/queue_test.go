@@ -236,3 +236,17 @@ func TestGetPendingJobs(t *testing.T) {
 	assert.Len(t, limitedJobs, 1)
 	assert.Equal(t, jobs[0].ID, limitedJobs[0].ID)
 }
+
+func TestShutdownWaitsForInFlightJob(t *testing.T) {
+	q := &Queue{handlers: make(map[JobType]JobHandler)}
+	q.processing.Store(true)
+	q.workers.Add(1) // Stands in for the processing loop running a job
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.Shutdown(ctx), context.DeadlineExceeded)
+	assert.False(t, q.processing.Load(), "shutdown should stop new jobs being taken")
+
+	q.workers.Done()
+	assert.NoError(t, q.Shutdown(context.Background()))
+}
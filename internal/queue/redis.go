@@ -72,8 +72,7 @@ func (r *RedisClient) Close() error {
 func (r *RedisClient) Enqueue(jobType JobType, payload interface{}, opts ...EnqueueOption) (string, error) {
 	// Apply options
 	options := &EnqueueOptions{
-		delay:    0,
-		maxRetry: 3, // Default max retries
+		delay: 0,
 	}
 	
 	for _, opt := range opts {
@@ -94,7 +93,7 @@ func (r *RedisClient) Enqueue(jobType JobType, payload interface{}, opts ...Enqu
 		Payload:    payloadBytes,
 		Status:     JobStatusPending,
 		RetryCount: 0,
-		MaxRetries: options.maxRetry,
+		MaxRetries: RetryPolicyFor(jobType).MaxRetries,
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 	}
@@ -341,10 +340,10 @@ func (r *RedisClient) Fail(queueName string, job *Job, err error) error {
 		errMsg = err.Error()
 	}
 
-	// If retries < max retries, requeue with backoff
-	if retryCount < job.MaxRetries {
-		// Calculate backoff using the function from types.go
-		backoff := calculateBackoff(retryCount)
+	// Requeue with backoff while the job type's retry policy allows
+	policy := RetryPolicyFor(job.Type)
+	if policy.CanRetry(job.RetryCount) {
+		backoff := policy.Backoff(retryCount)
 		nextRetry := time.Now().Add(backoff)
 
 		// Update job
@@ -381,7 +380,10 @@ func (r *RedisClient) Fail(queueName string, job *Job, err error) error {
 		return fmt.Errorf("failed to update job as failed: %w", err)
 	}
 
-	// Add to failed set
+	// Keep the job in the failed set if its policy dead-letters exhausted jobs
+	if !policy.DeadLetter {
+		return nil
+	}
 	failedKey := failedPrefix + string(job.Type)
 	if err := r.client.HSet(r.ctx, failedKey, job.ID.String(), time.Now().String()).Err(); err != nil {
 		return fmt.Errorf("failed to add job to failed set: %w", err)
@@ -493,4 +495,3 @@ func (r *RedisClient) GetQueueStats(queueName string) (*QueueStats, error) {
 	return stats, nil
 }
 
-// Use the calculateBackoff function from types.go
@@ -35,6 +35,7 @@ type RedisJob struct {
 	CreatedAt time.Time       `json:"created_at"`
 	UpdatedAt time.Time       `json:"updated_at"`
 	RunAt     time.Time       `json:"run_at"`
+	Priority  Priority        `json:"priority,omitempty"`
 }
 
 // ConvertToJob converts a RedisJob to a Job
@@ -82,10 +83,10 @@ type RedisQueueInterface interface {
 // RedisEnqueueOption defines options for enqueueing jobs
 type RedisEnqueueOption func(*RedisJob)
 
-// WithMaxRetries sets the maximum number of retries for a job
-func WithMaxRetries(maxRetries int) RedisEnqueueOption {
+// WithPriority sets a job's priority, overriding its job type's default
+func WithPriority(priority Priority) RedisEnqueueOption {
 	return func(j *RedisJob) {
-		j.MaxRetries = maxRetries
+		j.Priority = priority.normalize()
 	}
 }
 
@@ -127,7 +128,8 @@ func (q *RedisQueue) Enqueue(queueName string, payload interface{}, opts ...Redi
 		Payload:    payloadBytes,
 		Status:     JobStatusPending,
 		RetryCount: 0,
-		MaxRetries: DefaultRetryCount,
+		MaxRetries: RetryPolicyFor(JobType(queueName)).MaxRetries,
+		Priority:   PriorityFor(JobType(queueName)),
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 		RunAt:      time.Now(),
@@ -144,8 +146,8 @@ func (q *RedisQueue) Enqueue(queueName string, payload interface{}, opts ...Redi
 		return "", fmt.Errorf("failed to marshal job: %w", err)
 	}
 	
-	// Add to queue
-	err = q.client.LPush(q.ctx, queueName, jobBytes).Err()
+	// Add to the queue's list for the job's priority
+	err = q.client.LPush(q.ctx, priorityListKey(queueName, job.Priority), jobBytes).Err()
 	if err != nil {
 		return "", fmt.Errorf("failed to push job to queue: %w", err)
 	}
@@ -180,7 +182,8 @@ func (q *RedisQueue) EnqueueIn(queueName string, payload interface{}, delay time
 		Payload:    payloadBytes,
 		Status:     JobStatusPending,
 		RetryCount: 0,
-		MaxRetries: DefaultRetryCount,
+		MaxRetries: RetryPolicyFor(JobType(queueName)).MaxRetries,
+		Priority:   PriorityFor(JobType(queueName)),
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 		RunAt:      runAt,
@@ -234,26 +237,39 @@ func (q *RedisQueue) Schedule(queueName string, payload interface{}, runAt time.
 	return q.EnqueueIn(queueName, payload, delay, opts...)
 }
 
-// Dequeue gets a job from the queue
+// Dequeue gets the next job from the queue, taking higher priority jobs first
 func (q *RedisQueue) Dequeue(queueName string) (*RedisJob, error) {
-	// First, check for delayed jobs that are ready to run
-	q.moveReadyDelayedJobs(queueName)
-	
-	// Try to get a job from the queue
-	result := q.client.BRPop(q.ctx, 1*time.Second, queueName)
-	if result.Err() != nil {
-		if result.Err() == redis.Nil {
-			return nil, nil // No jobs available
-		}
-		return nil, fmt.Errorf("failed to pop job from queue: %w", result.Err())
+	return q.DequeueNext([]string{queueName}, priorityOrder(0))
+}
+
+// DequeueNext gets the next job from any of the queues, checking each priority in the given
+// order across all of them, so a high priority job of one type is taken before a normal
+// priority job of another. It returns nil if every queue is empty.
+func (q *RedisQueue) DequeueNext(queueNames []string, priorities []Priority) (*RedisJob, error) {
+	// First, move delayed jobs that are ready to run onto their lists
+	for _, queueName := range queueNames {
+		q.moveReadyDelayedJobs(queueName)
 	}
-	
-	if len(result.Val()) < 2 {
-		return nil, fmt.Errorf("unexpected result format from BRPOP")
+
+	for _, priority := range priorities {
+		for _, queueName := range queueNames {
+			jobBytes, err := q.client.RPop(q.ctx, priorityListKey(queueName, priority)).Result()
+			if err == redis.Nil {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to pop job from queue %s: %w", queueName, err)
+			}
+			return q.startJob(jobBytes)
+		}
 	}
-	
+
+	return nil, nil // No jobs available
+}
+
+// startJob marks a job taken from a queue as processing
+func (q *RedisQueue) startJob(jobBytes string) (*RedisJob, error) {
 	// Parse job
-	jobBytes := result.Val()[1]
 	var job RedisJob
 	if err := json.Unmarshal([]byte(jobBytes), &job); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
@@ -278,6 +294,33 @@ func (q *RedisQueue) Dequeue(queueName string) (*RedisJob, error) {
 	return &job, nil
 }
 
+// GetQueueStats counts a queue's waiting, delayed and dead-lettered jobs. Jobs being
+// processed aren't tracked in Redis, so they're left to the processor running them.
+func (q *RedisQueue) GetQueueStats(queueName string) (*QueueStats, error) {
+	stats := &QueueStats{Queue: queueName}
+	for _, priority := range []Priority{PriorityHigh, PriorityNormal, PriorityLow} {
+		waiting, err := q.client.LLen(q.ctx, priorityListKey(queueName, priority)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to count waiting jobs: %w", err)
+		}
+		stats.Waiting += int(waiting)
+	}
+
+	delayed, err := q.client.ZCard(q.ctx, "delayed:"+queueName).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count delayed jobs: %w", err)
+	}
+	stats.Delayed = int(delayed)
+
+	failed, err := q.client.HLen(q.ctx, failedPrefix+queueName).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count failed jobs: %w", err)
+	}
+	stats.Failed = int(failed)
+
+	return stats, nil
+}
+
 // moveReadyDelayedJobs moves delayed jobs that are ready to run to the main queue
 func (q *RedisQueue) moveReadyDelayedJobs(queueName string) {
 	now := time.Now().Unix()
@@ -306,8 +349,8 @@ func (q *RedisQueue) moveReadyDelayedJobs(queueName string) {
 			continue
 		}
 		
-		// Add to main queue
-		err = q.client.LPush(q.ctx, queueName, jobStr).Err()
+		// Add to the main queue's list for the job's priority
+		err = q.client.LPush(q.ctx, priorityListKey(queueName, job.Priority), jobStr).Err()
 		if err != nil {
 			log.Printf("Error moving delayed job to main queue: %v", err)
 			continue
@@ -386,16 +429,35 @@ func (q *RedisQueue) Fail(jobID string, jobErr error) error {
 		return fmt.Errorf("failed to update job status: %w", err)
 	}
 	
-	// Check if we should retry
-	if job.RetryCount < job.MaxRetries {
-		return q.Retry(jobID, time.Duration(job.RetryCount+1)*5*time.Second)
+	// Retry while the job type's retry policy allows
+	policy := RetryPolicyFor(JobType(job.Queue))
+	if policy.CanRetry(job.RetryCount) {
+		return q.Retry(jobID, policy.Backoff(job.RetryCount+1))
 	}
-	
+
+	// Keep the job in the failed set if its policy dead-letters exhausted jobs
+	if policy.DeadLetter {
+		if err := q.client.HSet(q.ctx, failedPrefix+job.Queue, jobID, updatedJobBytes).Err(); err != nil {
+			return fmt.Errorf("failed to add job to failed set: %w", err)
+		}
+	}
+
 	return nil
 }
 
 // Retry retries a failed job after a delay
 func (q *RedisQueue) Retry(jobID string, delay time.Duration) error {
+	return q.reschedule(jobID, delay, true)
+}
+
+// Defer runs a job again after a delay without counting it as a retry, for jobs that couldn't
+// run at all, such as ones rejected by an open provider circuit breaker
+func (q *RedisQueue) Defer(jobID string, delay time.Duration) error {
+	return q.reschedule(jobID, delay, false)
+}
+
+// reschedule puts a job back on its delayed queue, counting a retry if countRetry is set
+func (q *RedisQueue) reschedule(jobID string, delay time.Duration, countRetry bool) error {
 	// Get job details
 	jobData, err := q.client.HGet(q.ctx, "jobs:"+jobID, "data").Result()
 	if err != nil {
@@ -410,7 +472,9 @@ func (q *RedisQueue) Retry(jobID string, delay time.Duration) error {
 	
 	// Update job for retry
 	job.Status = JobStatusPending
-	job.RetryCount++
+	if countRetry {
+		job.RetryCount++
+	}
 	job.UpdatedAt = time.Now()
 	job.RunAt = time.Now().Add(delay)
 	
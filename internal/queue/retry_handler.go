@@ -7,105 +7,80 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/circuitbreaker"
 	"github.com/revaspay/backend/internal/database"
 	"gorm.io/gorm"
 )
 
-// RetryConfig defines the configuration for job retries
-type RetryConfig struct {
-	MaxRetries      int           // Maximum number of retry attempts
-	InitialInterval time.Duration // Initial retry interval
-	MaxInterval     time.Duration // Maximum retry interval
-	Multiplier      float64       // Backoff multiplier for subsequent retries
-	JobTypes        []JobType     // Job types that can be retried
+// CircuitOpenRetryDelay is the shortest delay before rerunning a job that was rejected by an
+// open provider circuit breaker. Such jobs never reached the provider, so they're rescheduled
+// without counting as a retry.
+const CircuitOpenRetryDelay = 5 * time.Minute
+
+// circuitOpenDelay returns how long to wait before rerunning a job that failed with err, and
+// false if err isn't a rejection by an open circuit breaker
+func circuitOpenDelay(err error) (time.Duration, bool) {
+	retryAfter, ok := circuitbreaker.RetryAfter(err)
+	if !ok {
+		return 0, false
+	}
+	return max(retryAfter, CircuitOpenRetryDelay), true
 }
 
-// RetryHandler manages job retries with exponential backoff
+// RetryHandler retries failed jobs with exponential backoff, following each job type's
+// RetryPolicy
 type RetryHandler struct {
-	db         *gorm.DB
-	queue      *Queue
-	retryConf  RetryConfig
-	retryTypes map[JobType]bool
+	db    *gorm.DB
+	queue *Queue
 }
 
 // NewRetryHandler creates a new retry handler
 func NewRetryHandler(db *gorm.DB, queue *Queue) *RetryHandler {
-	// Default retry configuration
-	conf := RetryConfig{
-		MaxRetries:      5,
-		InitialInterval: 30 * time.Second,
-		MaxInterval:     24 * time.Hour,
-		Multiplier:      2.0,
-		JobTypes: []JobType{
-			JobTypeProcessPayment,
-			JobTypeSendTransaction,
-			JobTypeUpdateTransactionStatus,
-			JobTypeGenerateComplianceReport,
-		},
-	}
-
-	// Build map of retryable job types for quick lookup
-	retryTypes := make(map[JobType]bool)
-	for _, jt := range conf.JobTypes {
-		retryTypes[jt] = true
-	}
-
 	return &RetryHandler{
-		db:         db,
-		queue:      queue,
-		retryConf:  conf,
-		retryTypes: retryTypes,
+		db:    db,
+		queue: queue,
 	}
 }
 
 // HandleFailedJob processes a failed job and schedules a retry if appropriate
 func (h *RetryHandler) HandleFailedJob(job Job, err error) {
-	// Check if job type is retryable
-	if !h.retryTypes[job.Type] {
+	// A provider's circuit breaker is open, so run the job again once it's likely to have
+	// closed, keeping the job's retries for real failures
+	if delay, ok := circuitOpenDelay(err); ok {
+		log.Printf("Provider unavailable, rescheduling job %s in %v without counting a retry: %v", job.ID, delay, err)
+		h.updateJobForRetry(job.ID, job.RetryCount, time.Now().Add(delay), err.Error())
+		return
+	}
+
+	policy := RetryPolicyFor(job.Type)
+	if policy.MaxRetries == 0 {
 		log.Printf("Job type %s is not configured for retries. Job ID: %s, Error: %v", job.Type, job.ID, err)
 		h.updateJobStatus(job.ID, "failed", err.Error())
 		return
 	}
 
-	// Get current retry count
-	retryCount := job.RetryCount + 1
-
-	if retryCount > h.retryConf.MaxRetries {
+	if !policy.CanRetry(job.RetryCount) {
 		log.Printf("Job exceeded maximum retry attempts (%d). Job ID: %s, Error: %v", 
-			h.retryConf.MaxRetries, job.ID, err)
+			policy.MaxRetries, job.ID, err)
 		h.updateJobStatus(job.ID, "failed", fmt.Sprintf("Exceeded max retries: %v", err))
 		
 		// Trigger failure notification
-		h.notifyJobFailure(job, err)
+		h.notifyJobFailure(job, policy.MaxRetries, err)
 		return
 	}
 
 	// Calculate next retry time with exponential backoff
-	nextRetryDelay := h.calculateBackoff(retryCount)
+	retryCount := job.RetryCount + 1
+	nextRetryDelay := policy.Backoff(retryCount)
 	nextRetryTime := time.Now().Add(nextRetryDelay)
 
 	log.Printf("Scheduling retry %d/%d for job %s in %v. Error: %v", 
-		retryCount, h.retryConf.MaxRetries, job.ID, nextRetryDelay, err)
+		retryCount, policy.MaxRetries, job.ID, nextRetryDelay, err)
 
 	// Update job with retry information
 	h.updateJobForRetry(job.ID, retryCount, nextRetryTime, err.Error())
 }
 
-// calculateBackoff calculates the backoff duration for a retry attempt
-func (h *RetryHandler) calculateBackoff(attempt int) time.Duration {
-	// Calculate exponential backoff: initialInterval * multiplier^(attempt-1)
-	// with a maximum of maxInterval
-	interval := h.retryConf.InitialInterval
-	for i := 1; i < attempt; i++ {
-		interval = time.Duration(float64(interval) * h.retryConf.Multiplier)
-		if interval > h.retryConf.MaxInterval {
-			interval = h.retryConf.MaxInterval
-			break
-		}
-	}
-	return interval
-}
-
 // updateJobStatus updates the status of a job
 func (h *RetryHandler) updateJobStatus(jobID uuid.UUID, status, errorMsg string) {
 	if err := h.db.Model(&Job{}).
@@ -135,11 +110,11 @@ func (h *RetryHandler) updateJobForRetry(jobID uuid.UUID, retryCount int, nextRe
 }
 
 // notifyJobFailure sends notifications about permanently failed jobs
-func (h *RetryHandler) notifyJobFailure(job Job, err error) {
+func (h *RetryHandler) notifyJobFailure(job Job, retries int, err error) {
 	// In production, this would send notifications via email, Slack, etc.
 	// For now, just log it
 	log.Printf("JOB FAILURE NOTIFICATION: Job %s of type %s has permanently failed after %d retries. Error: %v",
-		job.ID, job.Type, h.retryConf.MaxRetries, err)
+		job.ID, job.Type, retries, err)
 	
 	// Depending on job type, we might want to update related records
 	switch job.Type {
@@ -155,7 +130,7 @@ func (h *RetryHandler) notifyJobFailure(job Job, err error) {
 			Where("id = ?", payload.PaymentID).
 			Updates(map[string]interface{}{
 				"status":     "failed",
-				"error":      fmt.Sprintf("Job failed after %d retries: %v", h.retryConf.MaxRetries, err),
+				"error":      fmt.Sprintf("Job failed after %d retries: %v", retries, err),
 				"updated_at": time.Now(),
 			}).Error; err != nil {
 			log.Printf("Failed to update payment status: %v", err)
@@ -176,7 +151,7 @@ func (h *RetryHandler) ProcessRetryQueue() {
 	
 	for _, job := range jobsToRetry {
 		log.Printf("Processing retry for job %s (attempt %d/%d)", 
-			job.ID, job.RetryCount, h.retryConf.MaxRetries)
+			job.ID, job.RetryCount, RetryPolicyFor(job.Type).MaxRetries)
 		
 		// Update job status to pending
 		if err := h.db.Model(&Job{}).
@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how a failed job of one type is retried
+type RetryPolicy struct {
+	MaxRetries  int           // Retries after the first attempt; 0 never retries
+	BaseBackoff time.Duration // Delay before the first retry, doubled for each retry after it
+	MaxBackoff  time.Duration // Longest delay between retries
+	DeadLetter  bool          // Keep jobs that run out of retries in the failed set for inspection
+}
+
+// DefaultRetryPolicy applies to job types without a policy of their own
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:  DefaultRetryCount,
+	BaseBackoff: 5 * time.Second,
+	MaxBackoff:  time.Hour,
+	DeadLetter:  true,
+}
+
+// retryPolicies holds the retry policy of each job type. Jobs that move money or report on it
+// keep retrying for longer than the default, as they did before policies were configurable.
+var (
+	retryPoliciesMu sync.RWMutex
+	retryPolicies   = map[JobType]RetryPolicy{
+		JobTypeProcessPayment:           {MaxRetries: 5, BaseBackoff: 30 * time.Second, MaxBackoff: 24 * time.Hour, DeadLetter: true},
+		JobTypeSendTransaction:          {MaxRetries: 5, BaseBackoff: 30 * time.Second, MaxBackoff: 24 * time.Hour, DeadLetter: true},
+		JobTypeUpdateTransactionStatus:  {MaxRetries: 5, BaseBackoff: 30 * time.Second, MaxBackoff: 24 * time.Hour, DeadLetter: true},
+		JobTypeGenerateComplianceReport: {MaxRetries: 5, BaseBackoff: 30 * time.Second, MaxBackoff: 24 * time.Hour, DeadLetter: true},
+	}
+)
+
+// SetRetryPolicy sets the retry policy for a job type, replacing any it had
+func SetRetryPolicy(jobType JobType, policy RetryPolicy) {
+	retryPoliciesMu.Lock()
+	defer retryPoliciesMu.Unlock()
+	retryPolicies[jobType] = policy.normalize()
+}
+
+// RetryPolicyFor returns the retry policy for a job type, or DefaultRetryPolicy if it has none
+func RetryPolicyFor(jobType JobType) RetryPolicy {
+	retryPoliciesMu.RLock()
+	defer retryPoliciesMu.RUnlock()
+	if policy, ok := retryPolicies[jobType]; ok {
+		return policy
+	}
+	return DefaultRetryPolicy
+}
+
+// CanRetry reports whether a job that has already been retried retryCount times may be retried again
+func (p RetryPolicy) CanRetry(retryCount int) bool {
+	return retryCount < p.MaxRetries
+}
+
+// Backoff returns how long to wait before the given retry, counting from 1. The delay doubles
+// with each retry up to MaxBackoff, with ±20% jitter so failed jobs don't all retry at once.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	seconds := math.Min(p.MaxBackoff.Seconds(), p.BaseBackoff.Seconds()*math.Pow(2, float64(max(retry, 1)-1)))
+	jitter := seconds * 0.2
+	seconds = seconds - jitter + rand.Float64()*jitter*2
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// normalize fills in missing backoff bounds from DefaultRetryPolicy
+func (p RetryPolicy) normalize() RetryPolicy {
+	if p.MaxRetries < 0 {
+		p.MaxRetries = 0
+	}
+	if p.BaseBackoff <= 0 {
+		p.BaseBackoff = DefaultRetryPolicy.BaseBackoff
+	}
+	if p.MaxBackoff < p.BaseBackoff {
+		p.MaxBackoff = max(p.BaseBackoff, DefaultRetryPolicy.MaxBackoff)
+	}
+	return p
+}
@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyFor(t *testing.T) {
+	jobType := JobType("test_retry_policy")
+	assert.Equal(t, DefaultRetryPolicy, RetryPolicyFor(jobType))
+
+	SetRetryPolicy(jobType, RetryPolicy{MaxRetries: 2, BaseBackoff: time.Second})
+	policy := RetryPolicyFor(jobType)
+	assert.Equal(t, 2, policy.MaxRetries)
+	assert.Equal(t, DefaultRetryPolicy.MaxBackoff, policy.MaxBackoff, "missing max backoff is filled in")
+
+	assert.True(t, policy.CanRetry(0))
+	assert.True(t, policy.CanRetry(1))
+	assert.False(t, policy.CanRetry(2))
+	assert.False(t, RetryPolicy{}.CanRetry(0))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 10, BaseBackoff: 10 * time.Second, MaxBackoff: time.Minute}
+
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, time.Minute},
+		{10, time.Minute},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			backoff := policy.Backoff(tt.retry)
+			assert.GreaterOrEqual(t, backoff, tt.want*8/10, "retry %d", tt.retry)
+			assert.LessOrEqual(t, backoff, tt.want*12/10, "retry %d", tt.retry)
+		}
+	}
+}
@@ -1,8 +1,6 @@
 package queue
 
 import (
-	"math"
-	"math/rand"
 	"time"
 )
 
@@ -20,18 +18,19 @@ type RecurringJob struct {
 
 // QueueStats represents statistics for a queue
 type QueueStats struct {
-	Queue      string `json:"queue"`
-	Waiting    int    `json:"waiting"`
-	Processing int    `json:"processing"`
-	Delayed    int    `json:"delayed"`
-	Failed     int    `json:"failed"`
-	Completed  int    `json:"completed"`
+	Queue            string `json:"queue"`
+	Waiting          int    `json:"waiting"`
+	Processing       int    `json:"processing"`
+	Delayed          int    `json:"delayed"`
+	Failed           int    `json:"failed"`
+	Completed        int    `json:"completed"`
+	InFlight         int    `json:"in_flight"`                   // Jobs running on this instance's workers
+	ConcurrencyLimit int    `json:"concurrency_limit,omitempty"` // Most jobs this instance runs at once; 0 is no limit
 }
 
 // EnqueueOptions represents options for enqueueing a job
 type EnqueueOptions struct {
-	delay    time.Duration
-	maxRetry int
+	delay time.Duration
 }
 
 // EnqueueOption is a function that modifies EnqueueOptions
@@ -44,29 +43,4 @@ func WithDelay(delay time.Duration) EnqueueOption {
 	}
 }
 
-// WithMaxRetry sets the maximum number of retries for a job
-func WithMaxRetry(maxRetry int) EnqueueOption {
-	return func(o *EnqueueOptions) {
-		o.maxRetry = maxRetry
-	}
-}
-
 // Note: Default options are now handled directly in the Enqueue method
-
-// calculateBackoff calculates the backoff duration for a retry
-func calculateBackoff(retry int) time.Duration {
-	// Exponential backoff with jitter
-	// Base: 5 seconds
-	// Max: 1 hour
-	base := 5.0
-	max := 3600.0
-	
-	// Calculate exponential backoff
-	seconds := math.Min(max, base*math.Pow(2, float64(retry)))
-	
-	// Add jitter (±20%)
-	jitter := seconds * 0.2
-	seconds = seconds - jitter + (rand.Float64() * jitter * 2)
-	
-	return time.Duration(seconds) * time.Second
-}
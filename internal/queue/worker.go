@@ -83,7 +83,6 @@ func (w *Worker) process(workerID int) {
 
 			// Process the job
 			log.Printf("Worker %d processing job %s from queue %s", workerID, job.ID, w.queue)
-			ctx := context.Background()
 
 			// Parse the payload for the handler
 			var payload interface{}
@@ -96,7 +95,9 @@ func (w *Worker) process(workerID int) {
 			}
 
 			// Execute the handler
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultJobTimeout)
 			result, err := w.handler(ctx, *job)
+			cancel()
 			if err != nil {
 				log.Printf("Error processing job %s: %v", job.ID, err)
 				if err := w.redis.Fail(w.queue, job, err); err != nil {
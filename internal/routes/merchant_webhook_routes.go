@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/handlers"
+	"github.com/revaspay/backend/internal/middleware"
+)
+
+// SetupMerchantWebhookRoutes sets up routes for managing merchant webhook endpoints
+func SetupMerchantWebhookRoutes(router *gin.Engine, merchantWebhookHandler *handlers.MerchantWebhookHandler) {
+	endpoints := router.Group("/api/webhook-endpoints")
+	endpoints.Use(middleware.AuthMiddleware())
+	{
+		endpoints.POST("", merchantWebhookHandler.CreateEndpoint)
+		endpoints.GET("", merchantWebhookHandler.GetEndpoints)
+		endpoints.GET("/:id", merchantWebhookHandler.GetEndpoint)
+		endpoints.PUT("/:id", merchantWebhookHandler.UpdateEndpoint)
+		endpoints.DELETE("/:id", merchantWebhookHandler.DeleteEndpoint)
+		endpoints.GET("/:id/deliveries", merchantWebhookHandler.GetDeliveries)
+		endpoints.POST("/:id/secret/rotate", merchantWebhookHandler.StartSecretRotation)
+		endpoints.POST("/:id/secret/finalize", merchantWebhookHandler.FinalizeSecretRotation)
+	}
+}
@@ -4,18 +4,22 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/revaspay/backend/internal/handlers"
 	"github.com/revaspay/backend/internal/middleware"
+	"gorm.io/gorm"
 )
 
-// SetupPaymentRoutes sets up payment routes
-func SetupPaymentRoutes(router *gin.Engine, paymentHandler *handlers.PaymentHandler) {
+// SetupPaymentRoutes sets up payment routes. webhookAllowList returns the IP allow list
+// for a provider's webhook, and publicStatusRateLimit limits unauthenticated status lookups.
+// Authenticated routes have the user's record loaded from db.
+func SetupPaymentRoutes(router *gin.Engine, db *gorm.DB, paymentHandler *handlers.PaymentHandler, paymentRateLimit, publicStatusRateLimit gin.HandlerFunc, webhookAllowList func(provider string) gin.HandlerFunc) {
 	// API routes (authenticated)
 	api := router.Group("/api")
-	api.Use(middleware.AuthMiddleware())
+	api.Use(middleware.AuthMiddleware(), middleware.LoadUser(db))
 	{
 		// Payment links
 		paymentLinks := api.Group("/payment-links")
 		{
 			paymentLinks.POST("", paymentHandler.CreatePaymentLink)
+			paymentLinks.POST("/bulk", paymentHandler.CreatePaymentLinks)
 			paymentLinks.GET("", paymentHandler.GetPaymentLinks)
 			paymentLinks.GET("/:id", paymentHandler.GetPaymentLink)
 			paymentLinks.PUT("/:id", paymentHandler.UpdatePaymentLink)
@@ -25,33 +29,44 @@ func SetupPaymentRoutes(router *gin.Engine, paymentHandler *handlers.PaymentHand
 		// Payments
 		payments := api.Group("/payments")
 		{
-			payments.POST("", paymentHandler.InitiatePayment)
+			payments.POST("", paymentRateLimit, paymentHandler.InitiatePayment)
 			payments.GET("", paymentHandler.GetPayments)
 			payments.GET("/:id", paymentHandler.GetPayment)
+			payments.GET("/:id/events", paymentHandler.GetPaymentEvents)
 			payments.GET("/verify/:reference", paymentHandler.VerifyPayment)
+			payments.POST("/confirm/:reference", paymentHandler.ConfirmPayment)
+
+			// Optional schema for the metadata on the merchant's payments and links
+			payments.GET("/metadata-schema", paymentHandler.GetMetadataSchema)
+			payments.PUT("/metadata-schema", paymentHandler.SetMetadataSchema)
+			payments.DELETE("/metadata-schema", paymentHandler.DeleteMetadataSchema)
 		}
 
 		// Crypto payments
 		crypto := api.Group("/crypto")
 		{
-			crypto.POST("/payments", paymentHandler.InitiateCryptoPayment)
+			crypto.POST("/payments", paymentRateLimit, paymentHandler.InitiateCryptoPayment)
 		}
 	}
 
-	// Public routes
+	// Public routes - no authentication, so rate limited by IP
 	public := router.Group("/public")
 	{
 		// Payment from link
-		public.POST("/pay/:slug", paymentHandler.InitiatePaymentFromLink)
-		public.GET("/verify/:reference", paymentHandler.VerifyPayment)
+		public.POST("/pay/:slug", paymentRateLimit, paymentHandler.InitiatePaymentFromLink)
+		public.GET("/verify/:reference", publicStatusRateLimit, paymentHandler.VerifyPayment)
+		public.POST("/confirm/:reference", paymentRateLimit, paymentHandler.ConfirmPayment)
 	}
 
+	// Payment status for the hosted checkout page - no authentication, so rate limited by IP
+	router.GET("/api/public/payments/:reference/status", publicStatusRateLimit, paymentHandler.GetPublicPaymentStatus)
+
 	// Webhook routes (no authentication)
 	webhooks := router.Group("/webhooks")
 	{
-		webhooks.POST("/paystack", paymentHandler.ProcessPaystackWebhook)
-		webhooks.POST("/stripe", paymentHandler.ProcessStripeWebhook)
-		webhooks.POST("/paypal", paymentHandler.ProcessPayPalWebhook)
-		webhooks.POST("/crypto", paymentHandler.ProcessCryptoWebhook)
+		webhooks.POST("/paystack", webhookAllowList("paystack"), paymentHandler.ProcessPaystackWebhook)
+		webhooks.POST("/stripe", webhookAllowList("stripe"), paymentHandler.ProcessStripeWebhook)
+		webhooks.POST("/paypal", webhookAllowList("paypal"), paymentHandler.ProcessPayPalWebhook)
+		webhooks.POST("/crypto", webhookAllowList("crypto"), paymentHandler.ProcessCryptoWebhook)
 	}
 }
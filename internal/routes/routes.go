@@ -1,18 +1,42 @@
 package routes
 
 import (
+	"context"
+	"log"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
 	"gorm.io/gorm"
 
+	"github.com/revaspay/backend/internal/circuitbreaker"
 	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/database"
 	"github.com/revaspay/backend/internal/handlers"
+	"github.com/revaspay/backend/internal/jobs"
+	"github.com/revaspay/backend/internal/metrics"
 	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/money"
 	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/banking"
 	"github.com/revaspay/backend/internal/services/crypto"
+	"github.com/revaspay/backend/internal/services/dashboard"
+	"github.com/revaspay/backend/internal/services/dataexport"
+	"github.com/revaspay/backend/internal/services/email"
+	"github.com/revaspay/backend/internal/services/featureflags"
+	"github.com/revaspay/backend/internal/services/kyc"
+	"github.com/revaspay/backend/internal/services/notification"
+	"github.com/revaspay/backend/internal/services/payment"
+	"github.com/revaspay/backend/internal/services/payment/providers/paystack"
+	"github.com/revaspay/backend/internal/services/screening"
+	"github.com/revaspay/backend/internal/services/storage"
+	"github.com/revaspay/backend/internal/services/subscription"
+	"github.com/revaspay/backend/internal/services/virtualaccount"
+	"github.com/revaspay/backend/internal/services/wallet"
 	"github.com/revaspay/backend/internal/utils"
 )
 
@@ -26,8 +50,10 @@ func RegisterAuthRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, s
 		authGroup.POST("/login", authHandler.Login)
 		authGroup.POST("/refresh", authHandler.RefreshToken)
 		authGroup.POST("/forgot-password", authHandler.ForgotPassword)
+		authGroup.POST("/resend-password-reset", authHandler.ForgotPassword) // Issues a fresh link; shares forgot-password's limits
 		authGroup.POST("/reset-password", passwordHandler.ResetPassword)
 		authGroup.GET("/verify-email", authHandler.VerifyEmail)
+		authGroup.POST("/sessions/revoke", enhancedSessionHandler.RevokeSessionWithToken) // "This wasn't me" link in new login emails
 		authGroup.POST("/send-verification", authHandler.SendVerificationEmail)
 		authGroup.POST("/google", authHandler.GoogleAuth)
 		
@@ -81,16 +107,74 @@ func RegisterAuthRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, s
 	
 	// Admin security endpoints
 	adminSecurityGroup := router.Group("/api/admin/security")
-	adminSecurityGroup.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	adminSecurityGroup.Use(middleware.AuthMiddleware(), middleware.RequirePermission(models.PermissionSecurityManage))
 	{
 		adminSecurityGroup.POST("/force-mfa", enhancedSessionHandler.ForceMFAVerification)
 		adminSecurityGroup.POST("/force-password-reset", enhancedSessionHandler.ForcePasswordReset)
 		adminSecurityGroup.POST("/suspend-sessions", enhancedSessionHandler.SuspendSuspiciousSessions)
+		adminSecurityGroup.POST("/revoke-by", enhancedSessionHandler.RevokeSessionsBy) // All users' sessions from an IP range or device
 	}
 }
 
+// newRateLimiter creates a rate limiter backed by Redis when a client is given, so limits
+// hold across every instance, and by this process's memory otherwise
+func newRateLimiter(redisClient *redis.Client) *middleware.RateLimiter {
+	if redisClient == nil {
+		return middleware.NewRateLimiter(60, 10, 5, 3)
+	}
+	return middleware.NewRedisRateLimiter(redisClient, 60, 10, 5, 3)
+}
+
+// newRedisClient creates a Redis client from the configured URL, password and database
+func newRedisClient(redisConfig config.RedisConfig) (*redis.Client, error) {
+	options, err := redis.ParseURL(redisConfig.URL)
+	if err != nil {
+		return nil, err
+	}
+	if redisConfig.Password != "" {
+		options.Password = redisConfig.Password
+	}
+	if redisConfig.DB != 0 {
+		options.DB = redisConfig.DB
+	}
+	return redis.NewClient(options), nil
+}
+
+// WebhookIPAllowList returns a function giving the IP allow list middleware for a
+// provider's webhook, restricted to the provider's configured ranges
+func WebhookIPAllowList(cfg config.SecurityConfig) func(provider string) gin.HandlerFunc {
+	return func(provider string) gin.HandlerFunc {
+		return middleware.IPAllowList(cfg.WebhookAllowedIPs[provider], cfg.WebhookTrustedProxies)
+	}
+}
+
+// PaymentRateLimit returns the per-user limiter for endpoints that initiate payments
+func PaymentRateLimit(rateLimiter *middleware.RateLimiter, cfg config.SecurityConfig) gin.HandlerFunc {
+	return rateLimiter.UserRateLimiterMiddleware("payments", middleware.UserRateLimit{
+		RequestsPerMinute: cfg.PaymentRateLimit,
+		Burst:             cfg.PaymentRateBurst,
+	})
+}
+
+// PublicPaymentStatusRateLimit returns the per-IP limiter for unauthenticated payment status lookups
+func PublicPaymentStatusRateLimit(rateLimiter *middleware.RateLimiter, cfg config.SecurityConfig) gin.HandlerFunc {
+	return rateLimiter.UserRateLimiterMiddleware("public_payment_status", middleware.UserRateLimit{
+		RequestsPerMinute: cfg.PublicPaymentStatusRateLimit,
+		Burst:             cfg.PublicPaymentStatusRateBurst,
+	})
+}
+
+// WithdrawalRateLimit returns the per-user limiter for endpoints that initiate withdrawals
+func WithdrawalRateLimit(rateLimiter *middleware.RateLimiter, cfg config.SecurityConfig) gin.HandlerFunc {
+	return rateLimiter.UserRateLimiterMiddleware("withdrawals", middleware.UserRateLimit{
+		RequestsPerMinute: cfg.WithdrawalRateLimit,
+		Burst:             cfg.WithdrawalRateBurst,
+	})
+}
+
 // placeholderHandler is a temporary handler that returns a 501 Not Implemented response
-// It's used to make the code compile while we're still developing these features
+// for features that are still being built. Its routes sit behind feature flags, so they're
+// only reachable where the feature has been switched on.
 func placeholderHandler(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{
 		"error": "This feature is not yet implemented",
@@ -99,10 +183,43 @@ func placeholderHandler(c *gin.Context) {
 
 // RegisterRoutes configures all API routes
 func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
+	// Load configuration
+	cfg := config.LoadConfig()
+	
+	// Failed jobs are retried as their job type's retry policy says
+	jobs.ConfigureRetryPolicies(cfg.JobRetry)
+
+	// Fees, conversions and customer amounts round with the configured modes
+	roundingPolicy, err := money.ParsePolicy(cfg.Rounding.Customer, cfg.Rounding.Fee, cfg.Rounding.Conversion)
+	if err != nil {
+		log.Fatalf("Invalid rounding configuration: %v", err)
+	}
+	money.SetPolicy(roundingPolicy)
+	
 	// Initialize security middleware
 	
 	// Setup rate limiter - 60 requests per minute per IP, 5 auth attempts per minute
-	rateLimiter := middleware.NewRateLimiter(60, 10, 5, 3)
+	securityConfig := config.DefaultSecurityConfig()
+	
+	// Redis is only needed when rate limits are shared across instances
+	var redisClient *redis.Client
+	if securityConfig.RateLimitBackend == "redis" {
+		client, err := newRedisClient(cfg.Redis)
+		if err != nil {
+			log.Printf("Invalid REDIS_URL for rate limiting, using in-memory limits: %v", err)
+		} else {
+			redisClient = client
+		}
+	}
+	rateLimiter := newRateLimiter(redisClient)
+	
+	// Tighter per-user limits on endpoints that call out to payment and KYC providers
+	paymentRateLimit := PaymentRateLimit(rateLimiter, securityConfig)
+	withdrawalRateLimit := WithdrawalRateLimit(rateLimiter, securityConfig)
+	kycSubmitRateLimit := rateLimiter.UserRateLimiterMiddleware("kyc_submit", middleware.UserRateLimit{
+		RequestsPerMinute: securityConfig.KYCSubmitRateLimit,
+		Burst:             securityConfig.KYCSubmitRateBurst,
+	})
 	
 	// Setup security middleware for risk-based authentication
 	securityMiddleware := middleware.NewSecurityMiddleware(db)
@@ -120,7 +237,7 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 	}
 	csrfConfig := middleware.DefaultCSRFConfig()
 	csrfConfig.Secret = csrfSecret
-	csrfConfig.ExcludePaths = []string{"/api/webhooks/*", "/health"}
+	csrfConfig.ExcludePaths = []string{"/webhooks/*", "/api/webhooks/*", "/health"}
 	
 	// Initialize audit logger
 	auditLogger := utils.NewAuditLogger(db)
@@ -128,13 +245,18 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 	// Initialize session security handler
 	sessionSecurityHandler := handlers.NewSessionSecurityHandler(db)
 	
-	// Apply global middleware
+	// Apply global middleware - metrics first so requests rejected by later middleware are counted
+	router.Use(middleware.MetricsMiddleware())
+	router.Use(middleware.MaxBodyBytes(securityConfig.MaxBodyBytes, securityConfig.MaxUploadBodyBytes))
 	router.Use(middleware.SecureHeadersMiddleware(secureHeadersConfig))
 	router.Use(rateLimiter.IPRateLimiterMiddleware())
 	router.Use(securityMiddleware.BruteForceProtection())
 	
-	// Apply session activity tracking to authenticated routes
-	router.Use(securityMiddleware.SessionActivity())
+	// Record session activity on authenticated routes, expiring idle sessions
+	middleware.ConfigureSessionActivity(db, database.IdleTimeouts{
+		Default:  securityConfig.SessionIdleTimeout,
+		HighRisk: securityConfig.SessionIdleTimeoutHighRisk,
+	})
 	
 	// Apply risk-based authentication to sensitive routes
 	router.Use(securityMiddleware.RiskBasedAuthentication())
@@ -145,30 +267,136 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 	// Apply CSRF protection to state-changing routes
 	// This protects against cross-site request forgery attacks
 	router.Use(middleware.CSRFMiddleware(csrfConfig))
-	// Load configuration
-	_ = config.LoadConfig() // Load config but we don't need it directly
-	
+
+	// Reject tokens of suspended and deleted users
+	middleware.ConfigureAccountStatus(db)
+
 	// Create crypto service
 	baseService := crypto.NewBaseService(db)
 	
 	// Create handlers with database access
 	authHandler := handlers.NewAuthHandler(db)
 	userHandler := handlers.NewUserHandler(db)
+	roleHandler := handlers.NewRoleHandler(db)
+	accountSuspensionHandler := handlers.NewAccountSuspensionHandler(db)
+	
+	// Features still being built ship dark behind flags, configured per environment with
+	// FEATURE_<KEY> and toggled at runtime by admins
+	featureFlags := featureflags.NewService(db, cfg.Features)
+	featureFlagHandler := handlers.NewFeatureFlagHandler(featureFlags)
 	sessionHandler := handlers.NewSessionHandler(db)
 	enhancedSessionHandler := handlers.NewEnhancedSessionHandler(db)
-	kycHandler := handlers.NewKYCHandler(db)
+	// KYC documents and profile images are kept in the configured storage backend (local disk, S3 or GCS)
+	documentStore, err := storage.DefaultBackend()
+	if err != nil {
+		panic(err)
+	}
+	kycHandler := handlers.NewKYCHandler(db, jobQueue, documentStore)
 	walletHandler := handlers.NewWalletHandler(db)
 	adminWalletHandler := handlers.NewAdminWalletHandler(db)
-	webhookHandler := handlers.NewWebhookHandler(db, baseService, nil)
+	webhookHandler := handlers.NewWebhookHandler(db, baseService, jobQueue, securityConfig.WebhookAPIKey)
+	momoWebhookHandler := handlers.NewMoMoWebhookHandler(db, cfg)
 	mfaHandler := handlers.NewMFAHandler(db, auditLogger)
-	profileHandler := handlers.NewProfileHandler(db)
+	profileHandler := handlers.NewProfileHandler(db, documentStore)
+	accountDeletionHandler := handlers.NewAccountDeletionHandler(db)
+	transactionPINHandler := handlers.NewTransactionPINHandler(db)
+	meHandler := handlers.NewMeHandler(db)
 	securityQuestionHandler := handlers.NewSecurityQuestionHandler(db)
 	passwordHandler := handlers.NewPasswordHandler(db)
 	recoveryHandler := handlers.NewRecoveryHandler(db)
+	
+	// Bank accounts are resolved and verified through Paystack
+	paystackProvider := paystack.NewPaystackProvider(paystack.PaystackConfig{
+		SecretKey: cfg.Paystack.SecretKey,
+		PublicKey: cfg.Paystack.PublicKey,
+	})
+	bankingHandler := handlers.NewBankingHandler(db, banking.NewPaystackAccountResolver(paystackProvider))
+	
+	// Payment credits are batched per wallet when WALLET_CREDIT_BATCH_WINDOW is set, and the
+	// flusher applies them for the life of the process
+	walletService := wallet.NewWalletService(db)
+	walletService.SetCreditBatchWindow(cfg.Wallet.CreditBatchWindow)
+	go walletService.RunCreditFlusher(context.Background())
+
+	// Subscription renewals charge subscribers' saved Paystack cards into merchants' wallets
+	paymentService := payment.NewPaymentService(db, walletService)
+	paymentService.RegisterProvider(models.PaymentProviderPaystack, paystackProvider)
+	paymentService.RegisterAccountProvider(models.PaymentProviderPaystack, func(credentials payment.ProviderCredentials) payment.PaymentProvider {
+		return paystackProvider.WithKeys(credentials.SecretKey, credentials.PublicKey)
+	})
+	credentialBox, err := utils.NewSecretBoxFromBase64(cfg.Payment.CredentialsKey)
+	if err != nil {
+		log.Printf("Merchant provider credentials unavailable: %v", err)
+	}
+	paymentService.SetCredentialBox(credentialBox)
+	paymentService.SetAmountLimits(cfg.Payment.AmountLimits)
+	paymentService.SetMetadataLimits(cfg.Payment.MetadataLimits)
+	paymentService.SetPaymentExpiry(cfg.Payment.Expiry, cfg.Payment.ProviderExpiry)
+	paymentService.SetCircuitBreakerSettings(circuitbreaker.Settings(cfg.CircuitBreaker))
+	paymentService.SetReceiptQueue(jobQueue)
+	paymentLimitHandler := handlers.NewPaymentLimitHandler(paymentService)
+	providerCredentialHandler := handlers.NewProviderCredentialHandler(paymentService)
+	transactionHandler := handlers.NewTransactionHandler(paymentService)
+	subscriptionService := subscription.NewSubscriptionService(db, paymentService)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
+	for jobType, handler := range jobs.NewSubscriptionBillingJobHandlers(subscriptionService) {
+		jobQueue.RegisterHandler(jobType, handler)
+	}
+	jobs.ScheduleSubscriptionBilling(jobQueue, jobs.SubscriptionBillingInterval)
+	// Foreign currency receiving accounts are issued by whichever of Grey, Wise and Barter are configured
+	virtualAccountService := virtualaccount.NewVirtualAccountService(db, virtualaccount.DefaultProviders(cfg)...)
+	virtualAccountHandler := handlers.NewVirtualAccountHandler(virtualAccountService)
+	screeningService := screening.NewService(db, nil)
+	internationalPaymentHandler := handlers.NewInternationalPaymentHandler(db, jobQueue, screeningService)
+	screeningHandler := handlers.NewScreeningHandler(screeningService)
+	complianceReportHandler := handlers.NewComplianceReportHandler(db)
+	reconciliationReportHandler := handlers.NewReconciliationReportHandler(db)
+	auditLogHandler := handlers.NewAuditLogHandler(db)
+	adminMetricsHandler := handlers.NewAdminMetricsHandler(dashboard.NewService(db))
+	
+	// Stored provider webhooks can be replayed by admins through the payment webhook job
+	for jobType, handler := range jobs.NewPaymentWebhookJobHandlers(db, paymentService, walletService) {
+		jobQueue.RegisterHandler(jobType, handler)
+	}
+	webhookReplayHandler := handlers.NewWebhookReplayHandler(db, paymentService, jobQueue)
+
+	// Customers are emailed a receipt when their payment completes
+	for jobType, handler := range jobs.NewPaymentReceiptJobHandlers(db, email.NewEmailService()) {
+		jobQueue.RegisterHandler(jobType, handler)
+	}
+
+	// Users are emailed when their account is signed in to from a new device or country
+	for jobType, handler := range jobs.NewLoginNotificationJobHandlers(db, email.NewEmailService()) {
+		jobQueue.RegisterHandler(jobType, handler)
+	}
+	enhancedSessionHandler.SetNotificationQueue(jobQueue)
+
+	// Users can download a copy of their data, built in the background and emailed as a link
+	dataExportService := dataexport.NewService(db, documentStore, email.NewEmailService(), jobQueue)
+	for jobType, handler := range jobs.NewDataExportJobHandlers(dataExportService) {
+		jobQueue.RegisterHandler(jobType, handler)
+	}
+	dataExportHandler := handlers.NewDataExportHandler(db, dataExportService)
+	
+	// Payment status notifications are delivered in-app and by email
+	notificationService := notification.NewService(db,
+		notification.NewInAppChannel(db),
+		notification.NewEmailChannel(email.NewEmailService()),
+	)
+	for jobType, handler := range queue.NewNotificationJobHandlers(notificationService) {
+		jobQueue.RegisterHandler(jobType, handler)
+	}
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	subscriptionService.SetNotificationService(notificationService)
+
+	// Submitted KYC verifications are sent to their provider in the background
+	for jobType, handler := range jobs.NewKYCVerificationJobHandlers(db, kyc.DefaultProviders(db, documentStore)...) {
+		jobQueue.RegisterHandler(jobType, handler)
+	}
 	// sessionSecurityHandler already initialized above
 	
 	// Create Didit KYC handler
-	diditKYCHandler, err := handlers.NewDiditKYCHandler(db)
+	diditKYCHandler, err := handlers.NewDiditKYCHandler(db, documentStore)
 	if err != nil {
 		panic(err)
 	}
@@ -183,12 +411,22 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 	// Register authentication routes
 	RegisterAuthRoutes(router, authHandler, sessionHandler, enhancedSessionHandler, mfaHandler, passwordHandler, recoveryHandler, sessionSecurityHandler, rateLimiter, securityMiddleware, csrfConfig)
 
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-		})
-	})
+	// Health checks - /health is a cheap liveness probe, /health/ready checks dependencies
+	var queueProcessor handlers.QueueProcessor
+	if jobQueue != nil {
+		queueProcessor = jobQueue
+	}
+	healthHandler := handlers.NewHealthHandler(db, redisClient, queueProcessor)
+	router.GET("/health", healthHandler.Live)
+	router.GET("/health/ready", healthHandler.Ready)
+
+	// Prometheus metrics, protected by a bearer token when METRICS_TOKEN is set
+	if jobQueue != nil {
+		if err := prometheus.Register(queue.NewDepthCollector(jobQueue, jobQueue.JobTypes)); err != nil {
+			log.Printf("Failed to register queue depth metrics: %v", err)
+		}
+	}
+	router.GET("/metrics", metrics.Handler(os.Getenv("METRICS_TOKEN")))
 
 	// API v1 group
 	v1 := router.Group("/api")
@@ -202,26 +440,39 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 			c.JSON(http.StatusOK, gin.H{"version": "1.0.0"})
 		})
 		
+		// CSRF token for browser clients - echo it in the X-CSRF-Token header on writes
+		v1.GET("/csrf-token", middleware.CSRFTokenHandler(csrfConfig))
+		
 		// Public security question verification endpoint (used during account recovery)
 		v1.POST("/auth/verify-security-questions", securityQuestionHandler.VerifySecurityQuestions)
 		
-		// Webhook routes - no authentication but verified by signature
+		// Profile images are public, like the avatars they're shown as
+		v1.GET("/profile-images/:userID", profileHandler.ServeProfileImage)
+		
+		// Signed links to locally stored files - no authentication but verified by signature
+		if localStore, ok := documentStore.(*storage.LocalBackend); ok {
+			router.GET(storage.LocalObjectsPath+"*key", handlers.NewStorageHandler(localStore).ServeObject)
+		}
+		
+		// Webhook routes - no authentication but verified by signature, and restricted to
+		// the provider's published IP ranges where configured
+		webhookAllowList := WebhookIPAllowList(securityConfig)
 		webhooks := router.Group("/webhooks")
 		{
 			// Payment provider webhooks
-			webhooks.POST("/paystack", func(c *gin.Context) {
+			webhooks.POST("/paystack", webhookAllowList("paystack"), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Paystack webhook received"})
 			})
-			webhooks.POST("/flutterwave", func(c *gin.Context) {
+			webhooks.POST("/flutterwave", webhookAllowList("flutterwave"), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Flutterwave webhook received"})
 			})
-			webhooks.POST("/stripe", func(c *gin.Context) {
+			webhooks.POST("/stripe", webhookAllowList("stripe"), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Stripe webhook received"})
 			})
 			
 			// KYC verification webhooks
-			// Removed Smile Identity webhook route
-			webhooks.POST("/kyc/didit", kycHandler.HandleDiditWebhook)
+			webhooks.POST("/kyc/smile", webhookAllowList("smile"), kycHandler.HandleSmileWebhook)
+			webhooks.POST("/kyc/didit", webhookAllowList("didit"), kycHandler.HandleDiditWebhook)
 			
 			// Blockchain transaction webhooks
 			webhooks.POST("/blockchain/transaction", webhookHandler.BlockchainTransactionWebhook)
@@ -233,8 +484,8 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 			webhooks.POST("/exchange/rates", webhookHandler.ExchangeRateWebhook)
 			
 			// MTN MoMo webhooks
-			webhooks.POST("/momo/payment", placeholderHandler)
-			webhooks.POST("/momo/disbursement", placeholderHandler)
+			webhooks.POST("/momo/payment", webhookAllowList("momo"), momoWebhookHandler.PaymentNotification)
+			webhooks.POST("/momo/disbursement", webhookAllowList("momo"), momoWebhookHandler.DisbursementNotification)
 		}
 
 		// Protected routes - require authentication
@@ -243,6 +494,9 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 		// Apply CSRF protection to all state-changing endpoints
 		protected.Use(middleware.CSRFMiddleware(csrfConfig))
 		{
+			// Everything the app needs about the signed-in user on load
+			protected.GET("/me", meHandler.GetMe)
+
 			// User routes
 			user := protected.Group("/user")
 			{
@@ -251,6 +505,14 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 				user.PUT("/profile", profileHandler.UpdateProfile)
 				user.POST("/profile/image", profileHandler.UploadProfileImage)
 				user.DELETE("/profile/image", profileHandler.DeleteProfileImage)
+				user.POST("/data-export", dataExportHandler.RequestDataExport)
+
+				// Closing the account; financial records are kept anonymized
+				user.DELETE("/account", accountDeletionHandler.DeleteAccount)
+
+				// Transaction PIN confirming withdrawals and transfers
+				user.GET("/transaction-pin", transactionPINHandler.GetTransactionPIN)
+				user.PUT("/transaction-pin", transactionPINHandler.SetTransactionPIN)
 				
 				// Password management
 				user.PUT("/password", passwordHandler.UpdatePassword)
@@ -274,15 +536,15 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 			// KYC routes
 			kycRoutes := protected.Group("/kyc")
 			{
-				// Legacy Smile Identity KYC routes
+				// Smile Identity KYC routes; status covers every provider
 				kycRoutes.GET("/status", kycHandler.GetKYCStatus)
-				kycRoutes.POST("/submit", kycHandler.SubmitKYC)
+				kycRoutes.POST("/submit", kycSubmitRateLimit, kycHandler.SubmitKYC)
 				
 				// Didit KYC routes
 				diditRoutes := kycRoutes.Group("/didit")
 				{
 					diditRoutes.GET("/status", diditKYCHandler.GetKYCStatus)
-					diditRoutes.POST("/initiate", diditKYCHandler.InitiateKYCVerification)
+					diditRoutes.POST("/initiate", kycSubmitRateLimit, diditKYCHandler.InitiateKYCVerification)
 					diditRoutes.POST("/:id/upload", diditKYCHandler.UploadDocument)
 					diditRoutes.GET("/verifications", diditKYCHandler.GetUserVerifications)
 				}
@@ -303,8 +565,13 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 			{
 				wallet.GET("/", walletHandler.GetWallets)
 				wallet.POST("/", walletHandler.CreateWallet)
+				// Transfers check the sender's verification, so they need the user record
+				wallet.POST("/transfer", withdrawalRateLimit, middleware.LoadUser(db), walletHandler.Transfer)
 				wallet.GET("/:id", walletHandler.GetWallet)
+				wallet.GET("/:id/balance", walletHandler.GetWalletBalance)
 				wallet.GET("/:id/transactions", walletHandler.GetTransactionHistory)
+				wallet.GET("/transactions/export", walletHandler.ExportTransactions)
+				wallet.PUT("/transactions/:id/tag", walletHandler.TagTransaction)
 				wallet.GET("/auto-withdraw", walletHandler.GetAutoWithdrawConfig)
 				wallet.PUT("/auto-withdraw", walletHandler.UpdateAutoWithdrawConfig)
 			}
@@ -312,17 +579,18 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 			// Banking routes for Ghanaian bank integration
 			banking := protected.Group("/banking")
 			{
-				banking.POST("/link-account", placeholderHandler)
-				banking.GET("/accounts", placeholderHandler)
-				banking.GET("/accounts/:id", placeholderHandler)
-				banking.PUT("/accounts/:id", placeholderHandler)
-				banking.DELETE("/accounts/:id", placeholderHandler)
-				banking.GET("/banks", placeholderHandler)
-				banking.POST("/verify-account", placeholderHandler)
+				banking.POST("/link-account", bankingHandler.LinkBankAccount)
+				banking.GET("/accounts", bankingHandler.GetBankAccounts)
+				banking.GET("/accounts/:id", bankingHandler.GetBankAccount)
+				banking.PUT("/accounts/:id", bankingHandler.UpdateBankAccount)
+				banking.DELETE("/accounts/:id", bankingHandler.DeleteBankAccount)
+				banking.GET("/banks", bankingHandler.GetBanks)
+				banking.POST("/verify-account", bankingHandler.VerifyBankAccount)
 			}
 			
 			// Crypto wallet routes for Base blockchain
 			crypto := protected.Group("/crypto")
+			crypto.Use(middleware.RequireFeature(featureFlags, featureflags.CryptoWallets))
 			{
 				crypto.POST("/wallets", placeholderHandler)
 				crypto.GET("/wallets", placeholderHandler)
@@ -334,16 +602,25 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 			// International payment routes
 			intl := protected.Group("/international-payments")
 			{
-				intl.POST("/", placeholderHandler)
-				intl.GET("/", placeholderHandler)
-				intl.GET("/:id", placeholderHandler)
-				intl.GET("/:id/compliance-report", placeholderHandler)
+				intl.POST("/", paymentRateLimit, internationalPaymentHandler.InitiatePayment)
+				intl.GET("/", internationalPaymentHandler.GetPayments)
+				intl.GET("/:id", internationalPaymentHandler.GetPayment)
+				intl.GET("/:id/compliance-report", internationalPaymentHandler.GetComplianceReport)
+			}
+			
+			// In-app notification routes
+			notifications := protected.Group("/notifications")
+			{
+				notifications.GET("/", notificationHandler.GetNotifications)
+				notifications.PUT("/read-all", notificationHandler.MarkAllNotificationsRead)
+				notifications.PUT("/:id/read", notificationHandler.MarkNotificationRead)
 			}
 			
 			// Transaction routes
 			protected.GET("/transactions", func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Transactions endpoint"})
 			})
+			protected.GET("/transactions/summary", transactionHandler.GetSummary)
 			
 			// Payment routes - will be implemented later
 			protected.POST("/payment-links", func(c *gin.Context) {
@@ -363,7 +640,7 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 			})
 			
 			// Withdrawal routes - will be implemented later
-			protected.POST("/withdraw", func(c *gin.Context) {
+			protected.POST("/withdraw", withdrawalRateLimit, func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Create withdrawal endpoint"})
 			})
 			protected.GET("/withdrawals", func(c *gin.Context) {
@@ -375,105 +652,142 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue) {
 			
 			// MTN MoMo API routes
 			momo := protected.Group("/momo")
+			momo.Use(middleware.RequireFeature(featureFlags, featureflags.MoMo))
 			{
 				// Collection (payment) endpoints
-				momo.POST("/request-payment", placeholderHandler)
+				momo.POST("/request-payment", paymentRateLimit, placeholderHandler)
 				momo.POST("/check-payment", placeholderHandler)
 				
 				// Disbursement endpoints
-				momo.POST("/disburse", placeholderHandler)
+				momo.POST("/disburse", withdrawalRateLimit, placeholderHandler)
 				momo.POST("/check-disbursement", placeholderHandler)
 			}
 			
-			// Subscription routes - will be implemented later
-			protected.POST("/subscription-plans", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "Create subscription plan endpoint"})
-			})
-			protected.GET("/subscription-plans", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "Get subscription plans endpoint"})
-			})
-			protected.PUT("/subscription-plans/:id", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "Update subscription plan endpoint"})
-			})
-			protected.DELETE("/subscription-plans/:id", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "Delete subscription plan endpoint"})
-			})
-			protected.GET("/subscriptions", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "Get subscriptions endpoint"})
-			})
+			// Subscription plan routes for merchants
+			plans := protected.Group("/subscription-plans")
+			{
+				plans.POST("/", subscriptionHandler.CreatePlan)
+				plans.GET("/", subscriptionHandler.GetPlans)
+				plans.GET("/:id", subscriptionHandler.GetPlan)
+				plans.PUT("/:id", subscriptionHandler.UpdatePlan)
+				plans.DELETE("/:id", subscriptionHandler.DeletePlan)
+				plans.GET("/:id/subscriptions", subscriptionHandler.GetPlanSubscriptions)
+				plans.POST("/:id/subscribe", subscriptionHandler.Subscribe)
+			}
 			
-			// Virtual account routes - will be implemented later
-			protected.POST("/virtual-accounts", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "Create virtual account endpoint"})
-			})
-			protected.GET("/virtual-accounts", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "Get virtual accounts endpoint"})
-			})
-			protected.DELETE("/virtual-accounts/:id", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "Delete virtual account endpoint"})
-			})
+			// Subscription routes for subscribers
+			subscriptions := protected.Group("/subscriptions")
+			{
+				subscriptions.GET("/", subscriptionHandler.GetSubscriptions)
+				subscriptions.POST("/:id/cancel", subscriptionHandler.CancelSubscription)
+			}
+			
+			// Virtual account routes
+			protected.POST("/virtual-accounts", virtualAccountHandler.CreateVirtualAccount)
+			protected.GET("/virtual-accounts", virtualAccountHandler.GetVirtualAccounts)
+			protected.DELETE("/virtual-accounts/:id", virtualAccountHandler.DeactivateVirtualAccount)
 			
 			// Referral routes - will be implemented later
-			protected.GET("/referrals", func(c *gin.Context) {
+			protected.GET("/referrals", middleware.RequireFeature(featureFlags, featureflags.Referrals), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Get referrals endpoint"})
 			})
-			protected.GET("/referrals/stats", func(c *gin.Context) {
+			protected.GET("/referrals/stats", middleware.RequireFeature(featureFlags, featureflags.Referrals), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Get referral stats endpoint"})
 			})
 		}
 
-		// Admin routes - require admin role
+		// Admin routes - each requires a permission granted by the staff member's roles.
+		// Admins hold every permission.
 		admin := v1.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+		admin.Use(middleware.AuthMiddleware())
 		{
+			// Operational metrics for the admin home screen
+			admin.GET("/metrics", middleware.RequirePermission(models.PermissionPaymentsView), adminMetricsHandler.GetMetrics)
+
 			// Admin user management
-			admin.GET("/users", userHandler.GetAllUsers)
-			admin.GET("/users/:id", userHandler.GetUserByID)
-			admin.PUT("/users/:id/verify", userHandler.VerifyUser)
+			admin.GET("/users", middleware.RequirePermission(models.PermissionUsersView), userHandler.GetAllUsers)
+			admin.GET("/users/:id", middleware.RequirePermission(models.PermissionUsersView), userHandler.GetUserByID)
+			admin.PUT("/users/:id/verify", middleware.RequirePermission(models.PermissionUsersManage), userHandler.VerifyUser)
+			admin.PUT("/users/:id/suspend", middleware.RequirePermission(models.PermissionUsersManage), accountSuspensionHandler.SuspendUser)
+			admin.PUT("/users/:id/reactivate", middleware.RequirePermission(models.PermissionUsersManage), accountSuspensionHandler.ReactivateUser)
+			admin.GET("/users/:id/payment-limits", middleware.RequirePermission(models.PermissionUsersView), paymentLimitHandler.ListPaymentLimits)
+			admin.PUT("/users/:id/payment-limits/:currency", middleware.RequirePermission(models.PermissionUsersManage), paymentLimitHandler.SetPaymentLimit)
+			admin.DELETE("/users/:id/payment-limits/:currency", middleware.RequirePermission(models.PermissionUsersManage), paymentLimitHandler.DeletePaymentLimit)
+			admin.GET("/users/:id/provider-credentials", middleware.RequirePermission(models.PermissionUsersView), providerCredentialHandler.ListProviderCredentials)
+			admin.PUT("/users/:id/provider-credentials/:provider", middleware.RequirePermission(models.PermissionUsersManage), providerCredentialHandler.SetProviderCredential)
+			admin.DELETE("/users/:id/provider-credentials/:provider", middleware.RequirePermission(models.PermissionUsersManage), providerCredentialHandler.DeleteProviderCredential)
+			
+			// Admin role management
+			admin.GET("/roles", middleware.RequirePermission(models.PermissionRolesManage), roleHandler.ListRoles)
+			admin.GET("/users/:id/roles", middleware.RequirePermission(models.PermissionUsersView), roleHandler.GetUserRoles)
+			admin.PUT("/users/:id/roles/:role", middleware.RequirePermission(models.PermissionRolesManage), roleHandler.AssignRole)
+			admin.DELETE("/users/:id/roles/:role", middleware.RequirePermission(models.PermissionRolesManage), roleHandler.RemoveRole)
+			
+			// Admin feature flag management
+			admin.GET("/feature-flags", middleware.RequirePermission(models.PermissionFeatureFlagsManage), featureFlagHandler.ListFeatureFlags)
+			admin.PUT("/feature-flags/:key", middleware.RequirePermission(models.PermissionFeatureFlagsManage), featureFlagHandler.SetFeatureFlag)
+			admin.DELETE("/feature-flags/:key", middleware.RequirePermission(models.PermissionFeatureFlagsManage), featureFlagHandler.ResetFeatureFlag)
 			
 			// Admin transaction management
-			admin.GET("/transactions", func(c *gin.Context) {
+			admin.GET("/transactions", middleware.RequirePermission(models.PermissionPaymentsView), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Admin transactions endpoint"})
 			})
 			
 			// Admin KYC management
-			admin.GET("/kyc/pending", kycHandler.GetPendingKYC)
-			admin.GET("/kyc/:id", kycHandler.GetKYCByID)
-			admin.PUT("/kyc/:id/approve", kycHandler.ApproveKYC)
-			admin.PUT("/kyc/:id/reject", kycHandler.RejectKYC)
-			admin.PUT("/kyc/status", kycHandler.UpdateKYCStatus)
+			admin.GET("/kyc/pending", middleware.RequirePermission(models.PermissionKYCView), kycHandler.GetPendingKYC)
+			admin.GET("/kyc/:id", middleware.RequirePermission(models.PermissionKYCView), kycHandler.GetKYCByID)
+			admin.GET("/kyc/:id/documents/:docType", middleware.RequirePermission(models.PermissionKYCView), kycHandler.GetKYCDocument)
+			admin.PUT("/kyc/:id/approve", middleware.RequirePermission(models.PermissionKYCApprove), kycHandler.ApproveKYC)
+			admin.PUT("/kyc/:id/reject", middleware.RequirePermission(models.PermissionKYCApprove), kycHandler.RejectKYC)
+			admin.PUT("/kyc/status", middleware.RequirePermission(models.PermissionKYCApprove), kycHandler.UpdateKYCStatus)
 			
 			// Admin Didit KYC management
-			admin.GET("/kyc/didit/pending", diditKYCHandler.GetPendingVerifications)
-			admin.GET("/kyc/didit/:id", diditKYCHandler.GetVerificationByID)
-			admin.PUT("/kyc/didit/status", diditKYCHandler.UpdateVerificationStatus)
+			admin.GET("/kyc/didit/pending", middleware.RequirePermission(models.PermissionKYCView), diditKYCHandler.GetPendingVerifications)
+			admin.GET("/kyc/didit/:id", middleware.RequirePermission(models.PermissionKYCView), diditKYCHandler.GetVerificationByID)
+			admin.PUT("/kyc/didit/status", middleware.RequirePermission(models.PermissionKYCApprove), diditKYCHandler.UpdateVerificationStatus)
 			
 			// Admin wallet management
-			admin.GET("/wallets", adminWalletHandler.GetAllWallets)
-			admin.GET("/users/:user_id/wallets", adminWalletHandler.GetUserWallets)
-			admin.GET("/wallets/:id/transactions", adminWalletHandler.GetWalletTransactions)
-			admin.POST("/wallets/:id/adjust", adminWalletHandler.AdjustWalletBalance)
-			admin.GET("/auto-withdraw-configs", adminWalletHandler.GetAllAutoWithdrawConfigs)
+			admin.GET("/wallets", middleware.RequirePermission(models.PermissionWalletsView), adminWalletHandler.GetAllWallets)
+			admin.GET("/users/:user_id/wallets", middleware.RequirePermission(models.PermissionWalletsView), adminWalletHandler.GetUserWallets)
+			admin.GET("/wallets/:id/transactions", middleware.RequirePermission(models.PermissionWalletsView), adminWalletHandler.GetWalletTransactions)
+			admin.POST("/wallets/:id/adjust", middleware.RequirePermission(models.PermissionWalletsAdjust), adminWalletHandler.AdjustWalletBalance)
+			admin.GET("/auto-withdraw-configs", middleware.RequirePermission(models.PermissionWalletsView), adminWalletHandler.GetAllAutoWithdrawConfigs)
 			
 			// Admin withdrawals management
-			admin.GET("/withdrawals", func(c *gin.Context) {
+			admin.GET("/withdrawals", middleware.RequirePermission(models.PermissionWithdrawalsView), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Admin get all withdrawals endpoint"})
 			})
-			admin.PUT("/withdrawals/:id/process", func(c *gin.Context) {
+			admin.PUT("/withdrawals/:id/process", middleware.RequirePermission(models.PermissionWithdrawalsApprove), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Admin process withdrawal endpoint"})
 			})
 			
 			// Admin international payment management
-			admin.GET("/international-payments", func(c *gin.Context) {
+			admin.GET("/international-payments", middleware.RequirePermission(models.PermissionPaymentsView), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Admin get all international payments endpoint"})
 			})
-			admin.GET("/international-payments/:id", func(c *gin.Context) {
+			admin.GET("/international-payments/:id", middleware.RequirePermission(models.PermissionPaymentsView), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Admin get international payment details endpoint"})
 			})
-			admin.GET("/compliance-reports", func(c *gin.Context) {
-				c.JSON(http.StatusOK, gin.H{"message": "Admin get all compliance reports endpoint"})
-			})
-			admin.GET("/bank-accounts", func(c *gin.Context) {
+			admin.GET("/compliance-reports", middleware.RequirePermission(models.PermissionComplianceView), complianceReportHandler.ListReports)
+			admin.GET("/compliance-reports/:id", middleware.RequirePermission(models.PermissionComplianceView), complianceReportHandler.GetReport)
+			
+			// Admin provider reconciliation reports
+			admin.GET("/reconciliation-reports", middleware.RequirePermission(models.PermissionPaymentsView), reconciliationReportHandler.ListReports)
+			admin.GET("/reconciliation-reports/:id", middleware.RequirePermission(models.PermissionPaymentsView), reconciliationReportHandler.GetReport)
+			
+			// Admin address blocklist management
+			admin.GET("/blocked-addresses", middleware.RequirePermission(models.PermissionScreeningManage), screeningHandler.ListBlockedAddresses)
+			admin.POST("/blocked-addresses", middleware.RequirePermission(models.PermissionScreeningManage), screeningHandler.BlockAddress)
+			admin.DELETE("/blocked-addresses/:id", middleware.RequirePermission(models.PermissionScreeningManage), screeningHandler.UnblockAddress)
+			admin.POST("/blocked-addresses/screen", middleware.RequirePermission(models.PermissionScreeningManage), screeningHandler.ScreenAddress)
+			
+			// Admin audit log search for incident investigation
+			admin.GET("/audit-logs", middleware.RequirePermission(models.PermissionAuditView), auditLogHandler.ListAuditLogs)
+			
+			// Reprocess stored provider webhooks
+			admin.POST("/webhooks/:id/replay", middleware.RequirePermission(models.PermissionWebhooksManage), webhookReplayHandler.ReplayWebhook)
+			admin.POST("/webhooks/replay", middleware.RequirePermission(models.PermissionWebhooksManage), webhookReplayHandler.BulkReplayWebhooks)
+			admin.GET("/bank-accounts", middleware.RequirePermission(models.PermissionPaymentsView), func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "Admin get all bank accounts endpoint"})
 			})
 		}
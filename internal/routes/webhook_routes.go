@@ -2,8 +2,10 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/config"
 	"github.com/revaspay/backend/internal/handlers"
 	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/models"
 	"github.com/revaspay/backend/internal/queue"
 	"github.com/revaspay/backend/internal/services/crypto"
 	"gorm.io/gorm"
@@ -15,7 +17,7 @@ func SetupWebhookRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue)
 	baseService := crypto.NewBaseService(db)
 	
 	// Create webhook handler
-	webhookHandler := handlers.NewWebhookHandler(db, baseService, jobQueue)
+	webhookHandler := handlers.NewWebhookHandler(db, baseService, jobQueue, config.DefaultSecurityConfig().WebhookAPIKey)
 	
 	// Webhook routes group
 	webhookGroup := router.Group("/api/v1/webhooks")
@@ -35,7 +37,7 @@ func SetupWebhookRoutes(router *gin.Engine, db *gorm.DB, jobQueue *queue.Queue)
 		// Admin webhook endpoints (require authentication)
 		adminWebhookGroup := webhookGroup.Group("/admin")
 		adminWebhookGroup.Use(middleware.AuthMiddleware())
-		adminWebhookGroup.Use(middleware.AdminMiddleware())
+		adminWebhookGroup.Use(middleware.RequirePermission(models.PermissionWebhooksManage))
 		{
 			// Admin-only webhook management endpoints could go here
 			// For example, to view webhook logs, resend webhooks, etc.
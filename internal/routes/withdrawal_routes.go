@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/revaspay/backend/internal/handlers"
+	"github.com/revaspay/backend/internal/middleware"
+	"github.com/revaspay/backend/internal/models"
+)
+
+// SetupWithdrawalRoutes sets up routes for user withdrawals, and for admins resolving
+// withdrawals stuck in processing
+func SetupWithdrawalRoutes(router *gin.Engine, withdrawalHandler *handlers.WithdrawalHandler, withdrawalRateLimit gin.HandlerFunc) {
+	withdrawals := router.Group("/api/withdrawals")
+	withdrawals.Use(middleware.AuthMiddleware())
+	{
+		withdrawals.POST("", withdrawalRateLimit, withdrawalHandler.CreateWithdrawal)
+		withdrawals.GET("", withdrawalHandler.GetWithdrawals)
+		withdrawals.GET("/:id", withdrawalHandler.GetWithdrawal)
+	}
+
+	admin := router.Group("/api/admin/withdrawals")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequirePermission(models.PermissionWithdrawalsApprove))
+	{
+		admin.POST("/:id/resolve", withdrawalHandler.ResolveWithdrawal)
+	}
+}
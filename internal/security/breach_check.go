@@ -0,0 +1,80 @@
+package security
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHIBPURL is the Have I Been Pwned Pwned Passwords range API
+const DefaultHIBPURL = "https://api.pwnedpasswords.com/range/"
+
+// BreachChecker reports how many times a password appears in known data breaches
+type BreachChecker interface {
+	BreachCount(password string) (int, error)
+}
+
+// HIBPClient checks passwords against Have I Been Pwned using k-anonymity:
+// only the first five characters of the password's SHA-1 hash leave the server
+type HIBPClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewHIBPClient creates a client for the Pwned Passwords API
+func NewHIBPClient() *HIBPClient {
+	return &HIBPClient{
+		baseURL:    DefaultHIBPURL,
+		httpClient: &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// BreachCount returns how many times the password has been seen in breaches, or 0 if never
+func (c *HIBPClient) BreachCount(password string) (int, error) {
+	hash := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(hash[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+prefix, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating breach check request: %w", err)
+	}
+	// Padding hides the real number of matching suffixes from anyone watching the response size
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "RevasPay")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error calling breach check service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("breach check service returned status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		candidate, count, found := strings.Cut(line, ":")
+		if !found || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		// Padding entries have a count of zero
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, fmt.Errorf("error parsing breach count: %w", err)
+		}
+		return n, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("error reading breach check response: %w", err)
+	}
+
+	return 0, nil
+}
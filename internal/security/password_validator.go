@@ -0,0 +1,149 @@
+package security
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/revaspay/backend/internal/config"
+)
+
+var (
+	// ErrWeakPassword is returned when a password scores below the minimum strength
+	ErrWeakPassword = errors.New("password is too weak")
+
+	// ErrBreachedPassword is returned when a password appears in known data breaches
+	ErrBreachedPassword = errors.New("password has appeared in a data breach")
+)
+
+// PasswordRejection explains why a password was rejected and how to fix it
+type PasswordRejection struct {
+	Reason   error
+	Feedback []string
+}
+
+// Error returns the rejection reason
+func (e *PasswordRejection) Error() string {
+	return e.Reason.Error()
+}
+
+// Unwrap returns ErrWeakPassword or ErrBreachedPassword
+func (e *PasswordRejection) Unwrap() error {
+	return e.Reason
+}
+
+// PasswordValidator enforces a minimum password strength and rejects breached passwords
+type PasswordValidator struct {
+	minStrength   PasswordStrength
+	breachChecker BreachChecker
+}
+
+// NewPasswordValidator creates a validator. A nil breach checker disables the breach check.
+func NewPasswordValidator(minStrength PasswordStrength, breachChecker BreachChecker) *PasswordValidator {
+	return &PasswordValidator{
+		minStrength:   minStrength,
+		breachChecker: breachChecker,
+	}
+}
+
+// DefaultPasswordValidator creates a validator from the security configuration
+func DefaultPasswordValidator() *PasswordValidator {
+	securityConfig := config.DefaultSecurityConfig()
+
+	minStrength, ok := ParsePasswordStrength(securityConfig.PasswordMinStrength)
+	if !ok {
+		log.Printf("Invalid PASSWORD_MIN_STRENGTH %q, using moderate", securityConfig.PasswordMinStrength)
+		minStrength = Moderate
+	}
+
+	var breachChecker BreachChecker
+	if securityConfig.PasswordBreachCheck {
+		breachChecker = NewHIBPClient()
+	}
+
+	return NewPasswordValidator(minStrength, breachChecker)
+}
+
+// Validate checks a new password. It returns a *PasswordRejection with feedback for the user
+// when the password is too weak or breached. If the breach service can't be reached the
+// password is allowed, so an outage there never blocks signups or resets.
+func (v *PasswordValidator) Validate(password string) error {
+	if strength := EvaluatePasswordStrength(password); strength < v.minStrength {
+		return &PasswordRejection{
+			Reason:   ErrWeakPassword,
+			Feedback: PasswordFeedback(password),
+		}
+	}
+
+	if v.breachChecker == nil {
+		return nil
+	}
+
+	count, err := v.breachChecker.BreachCount(password)
+	if err != nil {
+		log.Printf("Password breach check unavailable, allowing password: %v", err)
+		return nil
+	}
+	if count > 0 {
+		return &PasswordRejection{
+			Reason:   ErrBreachedPassword,
+			Feedback: []string{"This password has appeared in a known data breach. Please choose a different password."},
+		}
+	}
+
+	return nil
+}
+
+// PasswordFeedback suggests how to make a password stronger
+func PasswordFeedback(password string) []string {
+	var feedback []string
+
+	if len(password) < 12 {
+		feedback = append(feedback, "Use at least 12 characters")
+	}
+	if !regexp.MustCompile(`[A-Z]`).MatchString(password) {
+		feedback = append(feedback, "Add an uppercase letter")
+	}
+	if !regexp.MustCompile(`[a-z]`).MatchString(password) {
+		feedback = append(feedback, "Add a lowercase letter")
+	}
+	if !regexp.MustCompile(`[0-9]`).MatchString(password) {
+		feedback = append(feedback, "Add a number")
+	}
+	if !regexp.MustCompile(`[!@#$%^&*()_+\-=\[\]{};':"\\|,.<>\/?]`).MatchString(password) {
+		feedback = append(feedback, "Add a special character")
+	}
+
+	uniqueChars := make(map[rune]bool)
+	for _, char := range password {
+		uniqueChars[char] = true
+	}
+	if len(uniqueChars) < 8 {
+		feedback = append(feedback, "Use more distinct characters and avoid repeating the same ones")
+	}
+
+	if len(feedback) == 0 {
+		feedback = append(feedback, "Make the password longer or less predictable")
+	}
+
+	return feedback
+}
+
+// ParsePasswordStrength converts a configured minimum strength such as "moderate" or "2" to a PasswordStrength
+func ParsePasswordStrength(value string) (PasswordStrength, bool) {
+	switch strings.ToLower(strings.ReplaceAll(strings.TrimSpace(value), " ", "_")) {
+	case "0", "very_weak":
+		return VeryWeak, true
+	case "1", "weak":
+		return Weak, true
+	case "2", "moderate":
+		return Moderate, true
+	case "3", "strong":
+		return Strong, true
+	case "4", "very_strong":
+		return VeryStrong, true
+	default:
+		return VeryWeak, false
+	}
+}
@@ -0,0 +1,108 @@
+package security
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeBreachChecker struct {
+	count int
+	err   error
+}
+
+func (f *fakeBreachChecker) BreachCount(password string) (int, error) {
+	return f.count, f.err
+}
+
+func TestPasswordValidator(t *testing.T) {
+	strongPassword := "Correct-Horse-42-Battery"
+
+	tests := []struct {
+		name     string
+		password string
+		checker  BreachChecker
+		expected error
+	}{
+		{"weak password", "password", nil, ErrWeakPassword},
+		{"strong password without breach check", strongPassword, nil, nil},
+		{"strong password not breached", strongPassword, &fakeBreachChecker{count: 0}, nil},
+		{"strong password breached", strongPassword, &fakeBreachChecker{count: 12}, ErrBreachedPassword},
+		{"breach service unavailable fails open", strongPassword, &fakeBreachChecker{err: errors.New("timeout")}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewPasswordValidator(Moderate, tt.checker)
+			err := validator.Validate(tt.password)
+			if tt.expected == nil {
+				if err != nil {
+					t.Fatalf("expected password to be accepted, got %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+			var rejection *PasswordRejection
+			if !errors.As(err, &rejection) || len(rejection.Feedback) == 0 {
+				t.Errorf("expected rejection with feedback, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParsePasswordStrength(t *testing.T) {
+	for value, expected := range map[string]PasswordStrength{
+		"moderate":    Moderate,
+		"Very Strong": VeryStrong,
+		"1":           Weak,
+	} {
+		strength, ok := ParsePasswordStrength(value)
+		if !ok || strength != expected {
+			t.Errorf("ParsePasswordStrength(%q) = %v, %v; want %v", value, strength, ok, expected)
+		}
+	}
+
+	if _, ok := ParsePasswordStrength("unbreakable"); ok {
+		t.Error("expected unknown strength to be rejected")
+	}
+}
+
+func TestHIBPClientBreachCount(t *testing.T) {
+	password := "Correct-Horse-42-Battery"
+	hash := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(hash[:]))
+
+	var requestedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:0\r\n%s:37\r\n", digest[5:])
+	}))
+	defer server.Close()
+
+	client := &HIBPClient{baseURL: server.URL + "/range/", httpClient: &http.Client{Timeout: time.Second}}
+
+	count, err := client.BreachCount(password)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 37 {
+		t.Errorf("expected breach count 37, got %d", count)
+	}
+	if requestedPath != "/range/"+digest[:5] {
+		t.Errorf("expected only the hash prefix to be sent, got path %s", requestedPath)
+	}
+
+	count, err = client.BreachCount("a different password")
+	if err != nil || count != 0 {
+		t.Errorf("expected unbreached password to return 0, got %d, %v", count, err)
+	}
+}
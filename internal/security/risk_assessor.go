@@ -1,36 +1,71 @@
 package security
 
 import (
+	"log"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/config"
 	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/services/geoip"
 	"gorm.io/gorm"
 )
 
+// RiskFactorImpossibleTravel is recorded when a login comes from somewhere the user
+// could not have reached since their previous login
+const RiskFactorImpossibleTravel = "impossible_travel"
+
+// RiskFactorTrustedDevice is recorded when the login comes from a device the user marked as trusted.
+// It lowers the score so trusted devices aren't repeatedly challenged for MFA.
+const RiskFactorTrustedDevice = "trusted_device"
+
+// trustedDeviceDiscount is subtracted from the score of logins from a trusted device
+const trustedDeviceDiscount = 20
+
+// earthRadiusKm is the mean radius of the Earth used for great-circle distances
+const earthRadiusKm = 6371.0
+
 // RiskAssessment represents the result of a risk assessment
 type RiskAssessment struct {
-	AssessmentID string
-	Score        float64
-	Action       string // "allow", "challenge", "block"
-	RequireMFA   bool
-	Factors      map[string]float64
+	AssessmentID  string
+	Score         float64
+	Action        string // "allow", "challenge", "block"
+	RequireMFA    bool
+	TrustedDevice bool
+	Factors       map[string]float64
+	Location      *geoip.Location // Resolved login location, nil when the IP could not be geolocated
 }
 
 // RiskAssessor handles risk assessment for login attempts
 type RiskAssessor struct {
-	db *gorm.DB
+	db  *gorm.DB
+	geo *geoip.Service
+
+	// Impossible travel thresholds
+	maxTravelSpeedKmh   float64
+	minTravelDistanceKm float64
 }
 
 // NewRiskAssessor creates a new risk assessor
 func NewRiskAssessor(db *gorm.DB) *RiskAssessor {
+	securityConfig := config.DefaultSecurityConfig()
 	return &RiskAssessor{
-		db: db,
+		db:                  db,
+		geo:                 geoip.DefaultService(),
+		maxTravelSpeedKmh:   securityConfig.ImpossibleTravelSpeedKmh,
+		minTravelDistanceKm: securityConfig.ImpossibleTravelMinDistanceKm,
 	}
 }
 
 // AssessLoginRisk assesses the risk of a login attempt
 func (r *RiskAssessor) AssessLoginRisk(userID uuid.UUID, ipAddress, userAgent string) (*RiskAssessment, error) {
+	return r.AssessLoginRiskWithDevice(userID, ipAddress, userAgent, "")
+}
+
+// AssessLoginRiskWithDevice assesses the risk of a login attempt, taking into account the
+// trusted device token the client presented (empty if it has none)
+func (r *RiskAssessor) AssessLoginRiskWithDevice(userID uuid.UUID, ipAddress, userAgent, deviceToken string) (*RiskAssessment, error) {
 	// Create a new assessment
 	assessment := &RiskAssessment{
 		AssessmentID: uuid.New().String(),
@@ -46,11 +81,29 @@ func (r *RiskAssessor) AssessLoginRisk(userID uuid.UUID, ipAddress, userAgent st
 		return assessment, err
 	}
 
+	// Resolve where the login is coming from; lookups are cached by the geoip service
+	if location, err := r.geo.Lookup(ipAddress); err == nil {
+		assessment.Location = location
+	}
+
 	// Check if this is a new device/location
 	newDevice := true
 	newLocation := true
+	newCountry := false
+	impossibleTravel := false
 	unusualTime := false
 
+	// Compare against where the user last logged in successfully. A failed lookup is treated
+	// as having no previous location, so it can't stop the user logging in.
+	if assessment.Location != nil {
+		lastLogin, err := database.GetLastLoginLocation(r.db, userID)
+		if err != nil {
+			log.Printf("Failed to get last login location for user %s: %v", userID, err)
+			lastLogin = nil
+		}
+		impossibleTravel = r.isImpossibleTravel(lastLogin, assessment.Location, time.Now())
+	}
+
 	if len(sessions) > 0 {
 		// Check if device has been seen before
 		for _, session := range sessions {
@@ -60,6 +113,16 @@ func (r *RiskAssessor) AssessLoginRisk(userID uuid.UUID, ipAddress, userAgent st
 			}
 		}
 
+		// A device the user trusted is known, whatever its user agent now reports
+		trusted, err := database.IsTrustedDevice(r.db, userID, deviceToken)
+		if err != nil {
+			return assessment, err
+		}
+		if trusted {
+			assessment.TrustedDevice = true
+			newDevice = false
+		}
+
 		// Check if IP has been seen before
 		for _, session := range sessions {
 			if session.IPAddress == ipAddress {
@@ -68,6 +131,28 @@ func (r *RiskAssessor) AssessLoginRisk(userID uuid.UUID, ipAddress, userAgent st
 			}
 		}
 
+		// A new IP in a city the user already logs in from is not a new location,
+		// but a country the user has never logged in from is
+		if assessment.Location != nil {
+			knownCountry := false
+			seenCountries := 0
+			for _, session := range sessions {
+				metadata, err := session.GetMetadata()
+				if err != nil || metadata.CountryCode == "" {
+					continue
+				}
+				seenCountries++
+				if metadata.CountryCode != assessment.Location.CountryCode {
+					continue
+				}
+				knownCountry = true
+				if assessment.Location.City != "" && metadata.City == assessment.Location.City {
+					newLocation = false
+				}
+			}
+			newCountry = seenCountries > 0 && !knownCountry
+		}
+
 		// Check if login time is unusual
 		if len(sessions) >= 3 {
 			// Calculate average login hour
@@ -96,11 +181,28 @@ func (r *RiskAssessor) AssessLoginRisk(userID uuid.UUID, ipAddress, userAgent st
 		assessment.Factors["new_location"] = 20
 	}
 
+	if newCountry {
+		assessment.Score += 15
+		assessment.Factors["new_country"] = 15
+	}
+
+	if impossibleTravel {
+		assessment.Score += 40
+		assessment.Factors[RiskFactorImpossibleTravel] = 40
+	}
+
 	if unusualTime {
 		assessment.Score += 15
 		assessment.Factors["unusual_time"] = 15
 	}
 
+	// Trusted devices get a discount, but never go below zero
+	if assessment.TrustedDevice {
+		discount := math.Min(trustedDeviceDiscount, assessment.Score)
+		assessment.Score -= discount
+		assessment.Factors[RiskFactorTrustedDevice] = -discount
+	}
+
 	// Check for failed login attempts
 	var failedAttempts int64
 	r.db.Model(&database.LoginAttempt{}).
@@ -127,6 +229,12 @@ func (r *RiskAssessor) AssessLoginRisk(userID uuid.UUID, ipAddress, userAgent st
 		assessment.RequireMFA = true
 	}
 
+	// Impossible travel always needs extra verification, even if the rest of the login looks normal
+	if impossibleTravel && assessment.Action != "block" {
+		assessment.Action = "challenge"
+		assessment.RequireMFA = true
+	}
+
 	return assessment, nil
 }
 
@@ -142,7 +250,29 @@ func (r *RiskAssessor) RecordSuccessfulLogin(userID, sessionID uuid.UUID, ipAddr
 		CreatedAt: time.Now(),
 	}
 
-	return r.db.Create(&attempt).Error
+	if err := r.db.Create(&attempt).Error; err != nil {
+		return err
+	}
+
+	// Remember where this login came from for the next impossible travel check
+	location, err := r.geo.Lookup(ipAddress)
+	if err != nil {
+		return nil
+	}
+
+	if err := database.SaveLastLoginLocation(r.db, &database.UserLoginLocation{
+		UserID:      userID,
+		IPAddress:   ipAddress,
+		Country:     location.Country,
+		CountryCode: location.CountryCode,
+		City:        location.City,
+		Latitude:    location.Latitude,
+		Longitude:   location.Longitude,
+		LoggedInAt:  attempt.CreatedAt,
+	}); err != nil {
+		log.Printf("Failed to save login location for user %s: %v", userID, err)
+	}
+	return nil
 }
 
 // UpdateSessionRiskMetadata updates session metadata with risk assessment
@@ -175,6 +305,38 @@ func (r *RiskAssessor) UpdateSessionRiskMetadata(sessionID uuid.UUID, assessment
 	return r.db.Save(&session).Error
 }
 
+// isImpossibleTravel checks whether getting from the last login location to the current one
+// since the last login would need a speed above the configured threshold
+func (r *RiskAssessor) isImpossibleTravel(lastLogin *database.UserLoginLocation, current *geoip.Location, now time.Time) bool {
+	if lastLogin == nil {
+		return false
+	}
+
+	distance := haversineKm(lastLogin.Latitude, lastLogin.Longitude, current.Latitude, current.Longitude)
+	if distance < r.minTravelDistanceKm {
+		return false
+	}
+
+	hours := now.Sub(lastLogin.LoggedInAt).Hours()
+	if hours <= 0 {
+		return true
+	}
+
+	return distance/hours > r.maxTravelSpeedKmh
+}
+
+// haversineKm returns the great-circle distance between two coordinates in kilometres
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
 // absInt returns the absolute value of an integer
 func absInt(n int) int {
 	if n < 0 {
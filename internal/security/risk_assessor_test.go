@@ -0,0 +1,171 @@
+package security
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/services/geoip"
+	"github.com/revaspay/backend/internal/testutil"
+)
+
+// fixedLocation is a geoip provider that puts every IP address in the same place
+type fixedLocation geoip.Location
+
+func (l fixedLocation) Lookup(net.IP) (*geoip.Location, error) {
+	location := geoip.Location(l)
+	return &location, nil
+}
+
+func TestHaversineKm(t *testing.T) {
+	// Accra to London is roughly 5,100 km
+	distance := haversineKm(5.6037, -0.1870, 51.5074, -0.1278)
+	if math.Abs(distance-5100) > 100 {
+		t.Errorf("expected about 5100 km, got %.0f", distance)
+	}
+
+	if distance := haversineKm(5.6037, -0.1870, 5.6037, -0.1870); distance != 0 {
+		t.Errorf("expected 0 km for identical points, got %.2f", distance)
+	}
+}
+
+func TestIsImpossibleTravel(t *testing.T) {
+	assessor := &RiskAssessor{maxTravelSpeedKmh: 900, minTravelDistanceKm: 500}
+	now := time.Now()
+
+	accra := &database.UserLoginLocation{Latitude: 5.6037, Longitude: -0.1870}
+	london := &geoip.Location{Latitude: 51.5074, Longitude: -0.1278}
+	kumasi := &geoip.Location{Latitude: 6.6885, Longitude: -1.6244}
+
+	tests := []struct {
+		name     string
+		elapsed  time.Duration
+		current  *geoip.Location
+		expected bool
+	}{
+		{"Accra to London in one hour", time.Hour, london, true},
+		{"Accra to London in a day", 24 * time.Hour, london, false},
+		{"Accra to Kumasi in ten minutes is below the minimum distance", 10 * time.Minute, kumasi, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lastLogin := *accra
+			lastLogin.LoggedInAt = now.Add(-tt.elapsed)
+			if got := assessor.isImpossibleTravel(&lastLogin, tt.current, now); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if assessor.isImpossibleTravel(nil, london, now) {
+		t.Error("expected no impossible travel without a previous login")
+	}
+}
+
+// Logins aren't failed because the last login location can't be read, e.g. before the
+// user_login_locations table exists
+func TestAssessLoginRiskWithoutLoginLocations(t *testing.T) {
+	db := testutil.NewDB(t)
+	if err := db.AutoMigrate(&database.EnhancedSession{}); err != nil {
+		t.Fatal(err)
+	}
+	assessor := &RiskAssessor{
+		db:                  db,
+		geo:                 geoip.NewService(fixedLocation{Country: "Ghana", Latitude: 5.6037, Longitude: -0.1870}, 0),
+		maxTravelSpeedKmh:   900,
+		minTravelDistanceKm: 500,
+	}
+	userID := uuid.New()
+
+	// With a previous location, travel is checked against it
+	if err := database.SaveLastLoginLocation(db, &database.UserLoginLocation{
+		UserID: userID, Latitude: 51.5074, Longitude: -0.1278, LoggedInAt: time.Now().Add(-time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	assessment, err := assessor.AssessLoginRisk(userID, "203.0.113.10", "test-agent")
+	if err != nil {
+		t.Fatalf("expected the assessment to succeed, got %v", err)
+	}
+	if _, ok := assessment.Factors[RiskFactorImpossibleTravel]; !ok {
+		t.Error("expected London to Accra in an hour to be impossible travel")
+	}
+
+	if err := db.Migrator().DropTable(&database.UserLoginLocation{}); err != nil {
+		t.Fatal(err)
+	}
+	assessment, err = assessor.AssessLoginRisk(userID, "203.0.113.10", "test-agent")
+	if err != nil {
+		t.Fatalf("expected the assessment to succeed, got %v", err)
+	}
+	if _, ok := assessment.Factors[RiskFactorImpossibleTravel]; ok {
+		t.Error("expected no impossible travel without a previous location")
+	}
+}
+
+// A device the user trusted isn't challenged for MFA from a new address, until its trust
+// expires or the session that granted it is revoked
+func TestAssessLoginRiskTrustedDevice(t *testing.T) {
+	db := testutil.NewDB(t)
+	if err := db.AutoMigrate(&database.EnhancedSession{}); err != nil {
+		t.Fatal(err)
+	}
+	assessor := &RiskAssessor{
+		db:                  db,
+		geo:                 geoip.NewService(fixedLocation{Country: "Ghana", Latitude: 5.6037, Longitude: -0.1870}, 0),
+		maxTravelSpeedKmh:   900,
+		minTravelDistanceKm: 500,
+	}
+	userID := uuid.New()
+
+	session, err := database.CreateEnhancedSession(db, uuid.Nil, userID, "refresh-token", "old-agent", "198.51.100.7", time.Now().Add(time.Hour), &database.SessionDevice{DeviceType: "mobile"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.TrustSessionDevice(db, session.ID, database.HashDeviceToken("device-token"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	assess := func(deviceToken string) *RiskAssessment {
+		t.Helper()
+		assessment, err := assessor.AssessLoginRiskWithDevice(userID, "203.0.113.10", "new-agent", deviceToken)
+		if err != nil {
+			t.Fatalf("expected the assessment to succeed, got %v", err)
+		}
+		return assessment
+	}
+
+	if assessment := assess("wrong-token"); assessment.TrustedDevice || !assessment.RequireMFA {
+		t.Errorf("expected an unknown device to be challenged, got %+v", assessment)
+	}
+	assessment := assess("device-token")
+	if !assessment.TrustedDevice || assessment.RequireMFA {
+		t.Errorf("expected the trusted device to skip MFA, got %+v", assessment)
+	}
+	if _, ok := assessment.Factors["new_device"]; ok {
+		t.Error("expected the trusted device not to count as a new device")
+	}
+
+	// Expired trust
+	if err := database.TrustSessionDevice(db, session.ID, database.HashDeviceToken("device-token"), time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if assessment := assess("device-token"); assessment.TrustedDevice || !assessment.RequireMFA {
+		t.Errorf("expected expired trust to be ignored, got %+v", assessment)
+	}
+
+	// Revoked session
+	if err := database.TrustSessionDevice(db, session.ID, database.HashDeviceToken("device-token"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.RevokeSession(db, session.ID, "lost phone"); err != nil {
+		t.Fatal(err)
+	}
+	if assessment := assess("device-token"); assessment.TrustedDevice || !assessment.RequireMFA {
+		t.Errorf("expected trust from a revoked session to be ignored, got %+v", assessment)
+	}
+}
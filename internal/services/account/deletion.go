@@ -0,0 +1,161 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidPassword is returned when the password confirming a deletion is wrong
+	ErrInvalidPassword = errors.New("invalid password")
+	// ErrMFACodeRequired is returned when deleting an account with 2FA enabled without a code
+	ErrMFACodeRequired = errors.New("2FA code required")
+	// ErrInvalidMFACode is returned when the 2FA code confirming a deletion is wrong
+	ErrInvalidMFACode = errors.New("invalid 2FA code")
+	// ErrWalletNotEmpty is returned when deleting an account that still holds funds
+	ErrWalletNotEmpty = errors.New("withdraw your wallet balance before deleting your account")
+	// ErrWithdrawalsInFlight is returned when deleting an account with unfinished withdrawals
+	ErrWithdrawalsInFlight = errors.New("wait for your pending withdrawals to complete before deleting your account")
+)
+
+// inFlightWithdrawalStatuses are the withdrawal statuses that block an account's deletion
+var inFlightWithdrawalStatuses = []string{"pending", "processing"}
+
+// DeletionRequest confirms a user's request to delete their own account
+type DeletionRequest struct {
+	Password  string
+	TOTPCode  string // Required when the user has 2FA enabled
+	IPAddress string
+	UserAgent string
+}
+
+// Delete closes a user's account at their request. Accounts that still hold funds or have
+// withdrawals in flight can't be deleted. The user's personal details are anonymized and the
+// user is soft deleted, so they can't sign in again, while their payments, withdrawals and
+// wallet transactions are kept for audit.
+func (s *Service) Delete(ctx context.Context, userID uuid.UUID, req DeletionRequest) error {
+	var revoked int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user database.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "password", "two_factor_enabled", "two_factor_secret").
+			First(&user, "id = ?", userID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("error finding user: %w", err)
+		}
+
+		if err := checkCredentials(&user, req.Password, req.TOTPCode); err != nil {
+			return err
+		}
+
+		if err := checkDeletable(tx, userID); err != nil {
+			return err
+		}
+
+		if err := anonymize(tx, userID); err != nil {
+			return err
+		}
+		if revoked, err = database.RevokeAllUserSessions(tx, userID, "Account deleted", nil); err != nil {
+			return fmt.Errorf("error revoking sessions: %w", err)
+		}
+		if err := tx.Delete(&models.User{ID: userID}).Error; err != nil {
+			return fmt.Errorf("error deleting user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidPassword) || errors.Is(err, ErrInvalidMFACode) {
+			s.auditLogger.LogEvent(ctx, utils.AuditEventUserDeleted, utils.AuditSeverityWarning, "Account deletion failed confirmation", &userID, nil, req.IPAddress, req.UserAgent, false, map[string]interface{}{
+				"reason": err.Error(),
+			})
+		}
+		return err
+	}
+
+	s.auditLogger.LogEvent(ctx, utils.AuditEventUserDeleted, utils.AuditSeverityWarning, "User deleted their account", &userID, nil, req.IPAddress, req.UserAgent, true, map[string]interface{}{
+		"sessions_revoked": revoked,
+	})
+	return nil
+}
+
+// checkDeletable returns an error if the user still has funds or withdrawals in flight. The
+// user's wallets are locked until the deletion commits, so a credit, hold or withdrawal can't
+// land between the check and the deletion.
+func checkDeletable(tx *gorm.DB, userID uuid.UUID) error {
+	var wallets []models.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		Order("id").
+		Find(&wallets).Error; err != nil {
+		return fmt.Errorf("error checking wallets: %w", err)
+	}
+	for _, wallet := range wallets {
+		if wallet.Balance != 0 || wallet.Held != 0 {
+			return ErrWalletNotEmpty
+		}
+	}
+
+	// Credits still waiting to be applied in a batch are funds too
+	var pending int64
+	if err := tx.Model(&models.PendingWalletCredit{}).
+		Where("wallet_id IN (?)", tx.Model(&models.Wallet{}).Select("id").Where("user_id = ?", userID)).
+		Count(&pending).Error; err != nil {
+		return fmt.Errorf("error checking pending credits: %w", err)
+	}
+	if pending > 0 {
+		return ErrWalletNotEmpty
+	}
+
+	var inFlight int64
+	if err := tx.Model(&models.Withdrawal{}).
+		Where("user_id = ? AND status IN ?", userID, inFlightWithdrawalStatuses).
+		Count(&inFlight).Error; err != nil {
+		return fmt.Errorf("error checking withdrawals: %w", err)
+	}
+	if inFlight > 0 {
+		return ErrWithdrawalsInFlight
+	}
+	return nil
+}
+
+// anonymize replaces the user's personal details and credentials. The email and username are
+// replaced with placeholders derived from the ID, as both must stay unique.
+func anonymize(tx *gorm.DB, userID uuid.UUID) error {
+	now := time.Now()
+	err := tx.Model(&database.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"email":              fmt.Sprintf("deleted-%s@deleted.revaspay.invalid", userID),
+		"username":           fmt.Sprintf("deleted-%s", userID),
+		"first_name":         "",
+		"last_name":          "",
+		"display_name":       "",
+		"business_name":      "",
+		"bio":                "",
+		"website":            "",
+		"phone_number":       nil,
+		"profile_image":      nil,
+		"social_links":       nil,
+		"password":           "",
+		"password_hash":      "",
+		"two_factor_enabled": false,
+		"two_factor_secret":  "",
+		"is_active":          false,
+		"suspended_at":       &now,
+		"suspension_reason":  "Account deleted by user",
+	}).Error
+	if err != nil {
+		return fmt.Errorf("error anonymizing user: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package account
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// newDeletionTestDB returns a test database with the credential columns database.User reads
+// when confirming a deletion
+func newDeletionTestDB(t *testing.T) *gorm.DB {
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&database.EnhancedSession{}))
+	for _, column := range []string{"password TEXT", "social_links TEXT", "two_factor_enabled BOOLEAN", "two_factor_secret TEXT"} {
+		require.NoError(t, db.Exec("ALTER TABLE users ADD COLUMN "+column).Error)
+	}
+	return db
+}
+
+func TestDeleteAccount(t *testing.T) {
+	db := newDeletionTestDB(t)
+	user := testutil.CreateUser(t, db)
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&database.User{}).Where("id = ?", user.ID).Update("password", string(hash)).Error)
+
+	wallet := models.Wallet{ID: uuid.New(), UserID: user.ID, Currency: models.CurrencyGHS, Balance: 10, Available: 10}
+	require.NoError(t, db.Create(&wallet).Error)
+	require.NoError(t, db.Create(&models.Payment{
+		ID: uuid.New(), UserID: user.ID, Amount: 10, Currency: models.CurrencyGHS,
+		Provider: models.PaymentProviderPaystack, Status: models.PaymentStatusCompleted, Reference: "PAY-1",
+	}).Error)
+
+	s := NewService(db)
+	ctx := context.Background()
+
+	err = s.Delete(ctx, user.ID, DeletionRequest{Password: "wrong"})
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+
+	err = s.Delete(ctx, user.ID, DeletionRequest{Password: "correct horse"})
+	assert.ErrorIs(t, err, ErrWalletNotEmpty)
+
+	require.NoError(t, db.Model(&wallet).Updates(map[string]interface{}{"balance": 0, "available": 0}).Error)
+	require.NoError(t, db.Create(&models.Withdrawal{
+		ID: uuid.New(), UserID: user.ID, WalletID: wallet.ID, Amount: 10, Currency: models.CurrencyGHS,
+		Method: "bank", Status: "processing",
+	}).Error)
+	err = s.Delete(ctx, user.ID, DeletionRequest{Password: "correct horse"})
+	assert.ErrorIs(t, err, ErrWithdrawalsInFlight)
+
+	require.NoError(t, db.Model(&models.Withdrawal{}).Where("user_id = ?", user.ID).Update("status", "completed").Error)
+	require.NoError(t, db.Create(&database.EnhancedSession{
+		ID: uuid.New(), UserID: user.ID, Status: database.SessionStatusActive,
+	}).Error)
+	require.NoError(t, s.Delete(ctx, user.ID, DeletionRequest{Password: "correct horse"}))
+
+	// The user can no longer be found, so can't sign in, but the row is kept anonymized
+	assert.ErrorIs(t, db.First(&models.User{}, "id = ?", user.ID).Error, gorm.ErrRecordNotFound)
+	var deleted models.User
+	require.NoError(t, db.Unscoped().First(&deleted, "id = ?", user.ID).Error)
+	assert.NotEqual(t, user.Email, deleted.Email)
+	assert.Empty(t, deleted.FirstName)
+	assert.False(t, deleted.IsActive)
+
+	var payments int64
+	require.NoError(t, db.Model(&models.Payment{}).Where("user_id = ?", user.ID).Count(&payments).Error)
+	assert.Equal(t, int64(1), payments, "financial records are kept")
+
+	var active int64
+	require.NoError(t, db.Model(&database.EnhancedSession{}).
+		Where("user_id = ? AND status = ?", user.ID, database.SessionStatusActive).Count(&active).Error)
+	assert.Zero(t, active)
+}
@@ -0,0 +1,199 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// MaxPINAttempts is how many wrong PINs in a row lock the transaction PIN
+	MaxPINAttempts = 5
+
+	// PINLockoutDuration is how long the transaction PIN stays locked after too many wrong attempts
+	PINLockoutDuration = 30 * time.Minute
+)
+
+// pinPattern matches a transaction PIN: 4 to 6 digits
+var pinPattern = regexp.MustCompile(`^[0-9]{4,6}$`)
+
+var (
+	// ErrInvalidPINFormat is returned when a new transaction PIN isn't 4 to 6 digits
+	ErrInvalidPINFormat = errors.New("transaction PIN must be 4 to 6 digits")
+	// ErrPINNotSet is returned when confirming an operation before setting a transaction PIN
+	ErrPINNotSet = errors.New("set a transaction PIN first")
+	// ErrIncorrectPIN is returned when a transaction PIN is wrong
+	ErrIncorrectPIN = errors.New("incorrect transaction PIN")
+	// ErrPINLocked is returned while the transaction PIN is locked after too many wrong attempts
+	ErrPINLocked = errors.New("transaction PIN is locked after too many wrong attempts")
+)
+
+// PINChangeRequest sets or changes a user's transaction PIN. Changing it needs the current PIN
+// or, as when setting it the first time or after forgetting it, the password and 2FA code.
+type PINChangeRequest struct {
+	PIN        string
+	CurrentPIN string
+	Password   string
+	TOTPCode   string // Required with the password when the user has 2FA enabled
+	IPAddress  string
+	UserAgent  string
+}
+
+// PINStatus says whether a user has a transaction PIN and whether it's locked
+type PINStatus struct {
+	Set         bool       `json:"set"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+// GetPINStatus returns whether the user has set a transaction PIN and whether it's locked
+func (s *Service) GetPINStatus(userID uuid.UUID) (*PINStatus, error) {
+	var pin models.TransactionPIN
+	err := s.db.First(&pin, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &PINStatus{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error finding transaction PIN: %w", err)
+	}
+
+	status := &PINStatus{Set: true}
+	if pin.LockedUntil != nil && time.Now().Before(*pin.LockedUntil) {
+		status.LockedUntil = pin.LockedUntil
+	}
+	return status, nil
+}
+
+// SetTransactionPIN sets or changes the user's transaction PIN once they have confirmed it with
+// their current PIN, or their password and 2FA code. Confirming with the password also unlocks
+// a PIN locked after too many wrong attempts.
+func (s *Service) SetTransactionPIN(ctx context.Context, userID uuid.UUID, req PINChangeRequest) error {
+	if !pinPattern.MatchString(req.PIN) {
+		return ErrInvalidPINFormat
+	}
+
+	if req.CurrentPIN != "" {
+		if err := s.VerifyTransactionPIN(ctx, userID, req.CurrentPIN); err != nil {
+			return err
+		}
+	} else if err := s.checkPassword(userID, req.Password, req.TOTPCode); err != nil {
+		if errors.Is(err, ErrInvalidPassword) || errors.Is(err, ErrInvalidMFACode) {
+			s.auditLogger.LogEvent(ctx, utils.AuditEventTransactionPINChange, utils.AuditSeverityWarning, "Transaction PIN change failed confirmation", &userID, nil, req.IPAddress, req.UserAgent, false, map[string]interface{}{
+				"reason": err.Error(),
+			})
+		}
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.PIN), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("error hashing transaction PIN: %w", err)
+	}
+	pin := models.TransactionPIN{UserID: userID, PINHash: string(hash)}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"pin_hash":        pin.PINHash,
+			"failed_attempts": 0,
+			"locked_until":    nil,
+			"updated_at":      time.Now(),
+		}),
+	}).Create(&pin).Error; err != nil {
+		return fmt.Errorf("error saving transaction PIN: %w", err)
+	}
+
+	s.auditLogger.LogEvent(ctx, utils.AuditEventTransactionPINChange, utils.AuditSeverityInfo, "User set their transaction PIN", &userID, nil, req.IPAddress, req.UserAgent, true, nil)
+	return nil
+}
+
+// VerifyTransactionPIN checks the PIN confirming a withdrawal or transfer. Each wrong PIN counts
+// towards MaxPINAttempts, after which the PIN is locked for PINLockoutDuration; a correct one
+// clears the count.
+func (s *Service) VerifyTransactionPIN(ctx context.Context, userID uuid.UUID, pin string) error {
+	var attemptsLeft int
+	var lockedUntil *time.Time
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Locked so concurrent guesses each count
+		var record models.TransactionPIN
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&record, "user_id = ?", userID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPINNotSet
+		}
+		if err != nil {
+			return fmt.Errorf("error finding transaction PIN: %w", err)
+		}
+
+		now := time.Now()
+		if record.LockedUntil != nil && now.Before(*record.LockedUntil) {
+			return fmt.Errorf("%w: try again after %s", ErrPINLocked, record.LockedUntil.Format(time.RFC3339))
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(record.PINHash), []byte(pin)) == nil {
+			if record.FailedAttempts == 0 && record.LockedUntil == nil {
+				return nil
+			}
+			return tx.Model(&record).Updates(map[string]interface{}{"failed_attempts": 0, "locked_until": nil}).Error
+		}
+
+		updates := map[string]interface{}{"failed_attempts": record.FailedAttempts + 1}
+		attemptsLeft = MaxPINAttempts - record.FailedAttempts - 1
+		if attemptsLeft <= 0 {
+			until := now.Add(PINLockoutDuration)
+			lockedUntil = &until
+			updates = map[string]interface{}{"failed_attempts": 0, "locked_until": until}
+		}
+		return tx.Model(&record).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case lockedUntil != nil:
+		s.auditLogger.LogEvent(ctx, utils.AuditEventTransactionPINLocked, utils.AuditSeverityWarning, "Transaction PIN locked after too many wrong attempts", &userID, nil, "", "", false, map[string]interface{}{
+			"locked_until": lockedUntil.Format(time.RFC3339),
+		})
+		return fmt.Errorf("%w: try again after %s", ErrPINLocked, lockedUntil.Format(time.RFC3339))
+	case attemptsLeft > 0:
+		return fmt.Errorf("%w: %d attempts left", ErrIncorrectPIN, attemptsLeft)
+	}
+	return nil
+}
+
+// checkPassword confirms a user's password, and their 2FA code if enabled
+func (s *Service) checkPassword(userID uuid.UUID, password, totpCode string) error {
+	var user database.User
+	err := s.db.Select("id", "password", "two_factor_enabled", "two_factor_secret").First(&user, "id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error finding user: %w", err)
+	}
+	return checkCredentials(&user, password, totpCode)
+}
+
+// checkCredentials confirms a loaded user's password, and their 2FA code if enabled
+func checkCredentials(user *database.User, password, totpCode string) error {
+	if password == "" || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return ErrInvalidPassword
+	}
+	if user.TwoFactorEnabled {
+		if totpCode == "" {
+			return ErrMFACodeRequired
+		}
+		if !utils.ValidateTOTP(user.TwoFactorSecret, totpCode) {
+			return ErrInvalidMFACode
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package account
+
+import (
+	"context"
+	"testing"
+
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestTransactionPIN(t *testing.T) {
+	db := newDeletionTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.TransactionPIN{}))
+	user := testutil.CreateUser(t, db)
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&database.User{}).Where("id = ?", user.ID).Update("password", string(hash)).Error)
+
+	s := NewService(db)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, s.VerifyTransactionPIN(ctx, user.ID, "1234"), ErrPINNotSet)
+	assert.ErrorIs(t, s.SetTransactionPIN(ctx, user.ID, PINChangeRequest{PIN: "12a4", Password: "correct horse"}), ErrInvalidPINFormat)
+	assert.ErrorIs(t, s.SetTransactionPIN(ctx, user.ID, PINChangeRequest{PIN: "1234", Password: "wrong"}), ErrInvalidPassword)
+
+	require.NoError(t, s.SetTransactionPIN(ctx, user.ID, PINChangeRequest{PIN: "1234", Password: "correct horse"}))
+	require.NoError(t, s.VerifyTransactionPIN(ctx, user.ID, "1234"))
+
+	// Changing it needs the current PIN
+	assert.ErrorIs(t, s.SetTransactionPIN(ctx, user.ID, PINChangeRequest{PIN: "567890", CurrentPIN: "0000"}), ErrIncorrectPIN)
+	require.NoError(t, s.SetTransactionPIN(ctx, user.ID, PINChangeRequest{PIN: "567890", CurrentPIN: "1234"}))
+
+	// Too many wrong PINs lock it, even for the right one
+	for i := 1; i < MaxPINAttempts; i++ {
+		assert.ErrorIs(t, s.VerifyTransactionPIN(ctx, user.ID, "0000"), ErrIncorrectPIN)
+	}
+	assert.ErrorIs(t, s.VerifyTransactionPIN(ctx, user.ID, "0000"), ErrPINLocked)
+	assert.ErrorIs(t, s.VerifyTransactionPIN(ctx, user.ID, "567890"), ErrPINLocked)
+
+	status, err := s.GetPINStatus(user.ID)
+	require.NoError(t, err)
+	assert.True(t, status.Set)
+	assert.NotNil(t, status.LockedUntil)
+
+	// The password resets a locked PIN
+	require.NoError(t, s.SetTransactionPIN(ctx, user.ID, PINChangeRequest{PIN: "4321", Password: "correct horse"}))
+	require.NoError(t, s.VerifyTransactionPIN(ctx, user.ID, "4321"))
+}
@@ -0,0 +1,145 @@
+// Package account handles whether user accounts can be used: admin suspensions, and users
+// deleting their own accounts
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxReasonLength bounds the suspension reason kept on the user
+const maxReasonLength = 1000
+
+var (
+	// ErrUserNotFound is returned when suspending or reactivating a user that doesn't exist
+	ErrUserNotFound = errors.New("user not found")
+	// ErrReasonRequired is returned when suspending without a reason
+	ErrReasonRequired = errors.New("a suspension reason is required")
+	// ErrReasonTooLong is returned when the suspension reason is longer than maxReasonLength
+	ErrReasonTooLong = errors.New("suspension reason is too long")
+	// ErrSuspendSelf is returned when an admin tries to suspend their own account
+	ErrSuspendSelf = errors.New("you cannot suspend your own account")
+	// ErrAlreadySuspended is returned when suspending a suspended user
+	ErrAlreadySuspended = errors.New("user is already suspended")
+	// ErrNotSuspended is returned when reactivating a user that isn't suspended
+	ErrNotSuspended = errors.New("user is not suspended")
+)
+
+// Service suspends and reactivates user accounts
+type Service struct {
+	db          *gorm.DB
+	auditLogger *utils.AuditLogger
+}
+
+// NewService creates a new account service
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		db:          db,
+		auditLogger: utils.NewAuditLogger(db),
+	}
+}
+
+// Suspend blocks a user from signing in and withdrawing funds, and signs them out everywhere.
+// Access tokens already issued stop working once AuthMiddleware's status cache expires.
+func (s *Service) Suspend(ctx context.Context, adminID, userID uuid.UUID, reason string) (*models.User, error) {
+	reason = strings.TrimSpace(reason)
+	switch {
+	case reason == "":
+		return nil, ErrReasonRequired
+	case len(reason) > maxReasonLength:
+		return nil, ErrReasonTooLong
+	case adminID == userID:
+		return nil, ErrSuspendSelf
+	}
+
+	var user models.User
+	var revoked int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.findUser(tx, userID, &user); err != nil {
+			return err
+		}
+		if !user.IsActive {
+			return ErrAlreadySuspended
+		}
+
+		now := time.Now()
+		user.IsActive = false
+		user.SuspendedAt = &now
+		user.SuspensionReason = reason
+		if err := tx.Model(&user).Select("is_active", "suspended_at", "suspension_reason").Updates(&user).Error; err != nil {
+			return fmt.Errorf("error suspending user: %w", err)
+		}
+
+		var err error
+		if revoked, err = database.RevokeAllUserSessions(tx, userID, "Account suspended", nil); err != nil {
+			return fmt.Errorf("error revoking sessions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditLogger.LogEvent(ctx, utils.AuditEventUserSuspended, utils.AuditSeverityWarning, "User account suspended", &userID, nil, "", "", true, map[string]interface{}{
+		"admin_id":         adminID.String(),
+		"reason":           reason,
+		"sessions_revoked": revoked,
+	})
+
+	return &user, nil
+}
+
+// Reactivate lifts a user's suspension. They have to sign in again.
+func (s *Service) Reactivate(ctx context.Context, adminID, userID uuid.UUID) (*models.User, error) {
+	var user models.User
+	var previousReason string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.findUser(tx, userID, &user); err != nil {
+			return err
+		}
+		if user.IsActive {
+			return ErrNotSuspended
+		}
+
+		previousReason = user.SuspensionReason
+		user.IsActive = true
+		user.SuspendedAt = nil
+		user.SuspensionReason = ""
+		if err := tx.Model(&user).Select("is_active", "suspended_at", "suspension_reason").Updates(&user).Error; err != nil {
+			return fmt.Errorf("error reactivating user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditLogger.LogEvent(ctx, utils.AuditEventUserReinstated, utils.AuditSeverityInfo, "User account reactivated", &userID, nil, "", "", true, map[string]interface{}{
+		"admin_id":          adminID.String(),
+		"suspension_reason": previousReason,
+	})
+
+	return &user, nil
+}
+
+// findUser loads a user, locking the row for the rest of the transaction
+func (s *Service) findUser(tx *gorm.DB, userID uuid.UUID, user *models.User) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(user, "id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error finding user: %w", err)
+	}
+	return nil
+}
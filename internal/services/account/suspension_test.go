@@ -0,0 +1,36 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSuspendValidation(t *testing.T) {
+	// Validation happens before the database is touched
+	s := &Service{}
+	adminID, userID := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		reason  string
+		wantErr error
+	}{
+		{"missing reason", userID, "", ErrReasonRequired},
+		{"blank reason", userID, "   ", ErrReasonRequired},
+		{"long reason", userID, strings.Repeat("x", maxReasonLength+1), ErrReasonTooLong},
+		{"self", adminID, "testing", ErrSuspendSelf},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Suspend(context.Background(), adminID, tt.userID, tt.reason)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Suspend() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package banking
+
+import (
+	"github.com/revaspay/backend/internal/services/payment/providers/paystack"
+)
+
+// Bank represents a bank that accounts can be linked from
+type Bank struct {
+	Name string `json:"name"`
+	Code string `json:"code"`
+}
+
+// ResolvedAccount holds the account details confirmed by the provider
+type ResolvedAccount struct {
+	AccountNumber string `json:"account_number"`
+	AccountName   string `json:"account_name"`
+	BankCode      string `json:"bank_code"`
+	BankName      string `json:"bank_name"`
+}
+
+// AccountResolver looks up banks and confirms account ownership with a provider
+type AccountResolver interface {
+	ListBanks() ([]Bank, error)
+	ResolveAccount(accountNumber, bankCode string) (*ResolvedAccount, error)
+}
+
+// PaystackAccountResolver resolves Ghanaian bank accounts through Paystack
+type PaystackAccountResolver struct {
+	provider *paystack.PaystackProvider
+}
+
+// NewPaystackAccountResolver creates a resolver backed by the Paystack API
+func NewPaystackAccountResolver(provider *paystack.PaystackProvider) *PaystackAccountResolver {
+	return &PaystackAccountResolver{provider: provider}
+}
+
+// ListBanks returns the active Ghanaian banks known to Paystack
+func (r *PaystackAccountResolver) ListBanks() ([]Bank, error) {
+	paystackBanks, err := r.provider.ListBanks("ghana")
+	if err != nil {
+		return nil, err
+	}
+
+	banks := make([]Bank, 0, len(paystackBanks))
+	for _, b := range paystackBanks {
+		if !b.Active {
+			continue
+		}
+		banks = append(banks, Bank{Name: b.Name, Code: b.Code})
+	}
+	return banks, nil
+}
+
+// ResolveAccount confirms the account number belongs to an account at the given bank
+func (r *PaystackAccountResolver) ResolveAccount(accountNumber, bankCode string) (*ResolvedAccount, error) {
+	resp, err := r.provider.ResolveAccount(accountNumber, bankCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResolvedAccount{
+		AccountNumber: resp.Data.AccountNumber,
+		AccountName:   resp.Data.AccountName,
+		BankCode:      bankCode,
+	}, nil
+}
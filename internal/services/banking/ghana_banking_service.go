@@ -2,11 +2,17 @@ package banking
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
 	"github.com/revaspay/backend/internal/services/crypto"
 	"github.com/revaspay/backend/internal/utils"
 	"gorm.io/gorm"
@@ -21,27 +27,116 @@ type BankAccountDetails struct {
 	BranchCode    string `json:"branch_code"`
 }
 
+var (
+	// ErrBankAccountNotVerified is returned when the provider cannot confirm an account
+	ErrBankAccountNotVerified = errors.New("bank account could not be verified")
+
+	// ErrAccountNameMismatch is returned when the supplied name differs from the name on the account
+	ErrAccountNameMismatch = errors.New("account name does not match the name registered with the bank")
+
+	// ErrBankAccountAlreadyLinked is returned when the user has already linked the account
+	ErrBankAccountAlreadyLinked = errors.New("bank account is already linked")
+
+	// ErrBankAccountNotFound is returned when an account does not exist or belongs to another user
+	ErrBankAccountNotFound = errors.New("bank account not found")
+
+	// ErrBankAccountInUse is returned when deleting an account with transactions still in flight
+	ErrBankAccountInUse = errors.New("bank account has pending transactions and cannot be deleted")
+)
+
+// bankListCacheTTL is how long the provider's bank list is cached
+const bankListCacheTTL = 24 * time.Hour
+
 // GhanaBankingService handles interactions with Ghanaian banks
 type GhanaBankingService struct {
 	db          *gorm.DB
 	baseService *crypto.BaseService
+	resolver    AccountResolver
+
+	banksMutex    sync.RWMutex
+	banksCache    []Bank
+	banksCachedAt time.Time
 }
 
 // NewGhanaBankingService creates a new Ghana banking service
-func NewGhanaBankingService(db *gorm.DB) *GhanaBankingService {
+func NewGhanaBankingService(db *gorm.DB, resolver AccountResolver) *GhanaBankingService {
 	return &GhanaBankingService{
 		db:          db,
 		baseService: crypto.NewBaseService(db),
+		resolver:    resolver,
+	}
+}
+
+// GetBanks returns the provider's list of supported banks, cached for a day
+func (s *GhanaBankingService) GetBanks() ([]Bank, error) {
+	s.banksMutex.RLock()
+	if s.banksCache != nil && time.Since(s.banksCachedAt) < bankListCacheTTL {
+		banks := s.banksCache
+		s.banksMutex.RUnlock()
+		return banks, nil
+	}
+	s.banksMutex.RUnlock()
+
+	banks, err := s.resolver.ListBanks()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching bank list: %w", err)
+	}
+
+	s.banksMutex.Lock()
+	s.banksCache = banks
+	s.banksCachedAt = time.Now()
+	s.banksMutex.Unlock()
+
+	return banks, nil
+}
+
+// VerifyBankAccount confirms an account number and bank code with the provider
+func (s *GhanaBankingService) VerifyBankAccount(accountNumber, bankCode string) (*ResolvedAccount, error) {
+	resolved, err := s.resolver.ResolveAccount(accountNumber, bankCode)
+	if err != nil || resolved == nil || resolved.AccountName == "" {
+		return nil, ErrBankAccountNotVerified
+	}
+
+	// Fill in the bank name from the cached list when we have it
+	if resolved.BankName == "" {
+		if banks, err := s.GetBanks(); err == nil {
+			for _, bank := range banks {
+				if bank.Code == bankCode {
+					resolved.BankName = bank.Name
+					break
+				}
+			}
+		}
 	}
+
+	return resolved, nil
 }
 
 // LinkBankAccount connects a user's Ghanaian bank account to their RevasPay account
 func (s *GhanaBankingService) LinkBankAccount(userID uuid.UUID, bankDetails BankAccountDetails) (*database.BankAccount, error) {
-	// Verify bank account details with Ghana banking API
-	// In production, this would call an actual API
-	verified, err := s.verifyGhanaianBankAccount(bankDetails)
-	if err != nil || !verified {
-		return nil, fmt.Errorf("bank account verification failed: %v", err)
+	// Each account can only be linked once per user
+	var existing int64
+	if err := s.db.Model(&database.BankAccount{}).
+		Where("user_id = ? AND bank_code = ? AND account_number = ?", userID, bankDetails.BankCode, bankDetails.AccountNumber).
+		Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("error checking existing bank accounts: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrBankAccountAlreadyLinked
+	}
+
+	// Only accounts the provider can resolve may be linked
+	resolved, err := s.VerifyBankAccount(bankDetails.AccountNumber, bankDetails.BankCode)
+	if err != nil {
+		return nil, err
+	}
+	if bankDetails.AccountName != "" && !accountNamesMatch(bankDetails.AccountName, resolved.AccountName) {
+		return nil, ErrAccountNameMismatch
+	}
+
+	bankName := resolved.BankName
+	if bankName == "" {
+		bankName = bankDetails.BankName
 	}
 
 	// Start transaction
@@ -51,8 +146,8 @@ func (s *GhanaBankingService) LinkBankAccount(userID uuid.UUID, bankDetails Bank
 	bankAccount := &database.BankAccount{
 		UserID:        userID,
 		AccountNumber: bankDetails.AccountNumber,
-		AccountName:   bankDetails.AccountName,
-		BankName:      bankDetails.BankName,
+		AccountName:   resolved.AccountName,
+		BankName:      bankName,
 		BankCode:      bankDetails.BankCode,
 		BranchCode:    bankDetails.BranchCode,
 		Country:       "Ghana",
@@ -104,12 +199,79 @@ func (s *GhanaBankingService) LinkBankAccount(userID uuid.UUID, bankDetails Bank
 // GetBankAccounts retrieves all bank accounts for a user
 func (s *GhanaBankingService) GetBankAccounts(userID uuid.UUID) ([]database.BankAccount, error) {
 	var accounts []database.BankAccount
-	if err := s.db.Where("user_id = ?", userID).Find(&accounts).Error; err != nil {
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&accounts).Error; err != nil {
 		return nil, err
 	}
 	return accounts, nil
 }
 
+// GetBankAccount retrieves a bank account owned by the user
+func (s *GhanaBankingService) GetBankAccount(id, userID uuid.UUID) (*database.BankAccount, error) {
+	var account database.BankAccount
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBankAccountNotFound
+		}
+		return nil, fmt.Errorf("error finding bank account: %w", err)
+	}
+	return &account, nil
+}
+
+// UpdateBankAccountLabel sets the user's nickname for a bank account
+func (s *GhanaBankingService) UpdateBankAccountLabel(id, userID uuid.UUID, label string) (*database.BankAccount, error) {
+	account, err := s.GetBankAccount(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(account).Update("label", label).Error; err != nil {
+		return nil, fmt.Errorf("error updating bank account: %w", err)
+	}
+
+	return account, nil
+}
+
+// DeleteBankAccount soft-deletes a bank account and its wallet links.
+// Accounts with bank transactions or withdrawals still in flight cannot be deleted.
+func (s *GhanaBankingService) DeleteBankAccount(id, userID uuid.UUID) error {
+	account, err := s.GetBankAccount(id, userID)
+	if err != nil {
+		return err
+	}
+
+	var pendingTransactions int64
+	if err := s.db.Model(&database.GhanaBankTransaction{}).
+		Where("bank_account_id = ? AND status IN ?", account.ID, []string{"pending", "processing"}).
+		Count(&pendingTransactions).Error; err != nil {
+		return fmt.Errorf("error checking bank transactions: %w", err)
+	}
+
+	var pendingWithdrawals int64
+	if err := s.db.Model(&models.Withdrawal{}).
+		Where("destination_id = ? AND status IN ?", account.ID, []string{"pending", "processing"}).
+		Count(&pendingWithdrawals).Error; err != nil {
+		return fmt.Errorf("error checking withdrawals: %w", err)
+	}
+
+	if pendingTransactions > 0 || pendingWithdrawals > 0 {
+		return ErrBankAccountInUse
+	}
+
+	tx := s.db.Begin()
+
+	if err := tx.Where("bank_account_id = ?", account.ID).Delete(&database.BankWalletLink{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error removing bank wallet links: %w", err)
+	}
+
+	if err := tx.Delete(account).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting bank account: %w", err)
+	}
+
+	return tx.Commit().Error
+}
+
 // ProcessInternationalPayment handles payments to international vendors using Cedis
 func (s *GhanaBankingService) ProcessInternationalPayment(
 	userID uuid.UUID,
@@ -215,12 +377,16 @@ func (s *GhanaBankingService) GetInternationalPayments(userID uuid.UUID) ([]data
 
 // Helper methods
 
-// verifyGhanaianBankAccount verifies a bank account with Ghana banking API
-// In production, this would call an actual API
-func (s *GhanaBankingService) verifyGhanaianBankAccount(details BankAccountDetails) (bool, error) {
-	// Simulate verification
-	// In production, this would call the actual Ghana banking API
-	return true, nil
+// accountNamesMatch compares account names ignoring case, punctuation and word order
+func accountNamesMatch(a, b string) bool {
+	normalize := func(name string) string {
+		words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		sort.Strings(words)
+		return strings.Join(words, " ")
+	}
+	return normalize(a) == normalize(b)
 }
 
 // generateComplianceDetails creates a JSON string with compliance information
@@ -0,0 +1,121 @@
+package banking
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/services/payment/providers/paystack"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestBankingService returns a banking service whose accounts are resolved by a fake
+// Paystack API that knows one account, 0123456789 at bank GCB, held by Kwame Mensah
+func newTestBankingService(t *testing.T) (*GhanaBankingService, *gorm.DB) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bank":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status": true,
+				"data": []map[string]interface{}{
+					{"name": "GCB Bank", "code": "GCB", "active": true},
+					{"name": "Closed Bank", "code": "CLOSED", "active": false},
+				},
+			})
+		case "/bank/resolve":
+			query := r.URL.Query()
+			if query.Get("account_number") != "0123456789" || query.Get("bank_code") != "GCB" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(map[string]interface{}{"status": false, "message": "Could not resolve account name"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status": true,
+				"data":   map[string]interface{}{"account_number": "0123456789", "account_name": "KWAME MENSAH"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	db := testutil.NewDB(t)
+	provider := paystack.NewPaystackProvider(paystack.PaystackConfig{SecretKey: "sk_test", BaseURL: server.URL})
+	return NewGhanaBankingService(db, NewPaystackAccountResolver(provider)), db
+}
+
+// Only accounts Paystack resolves are linked, under the name registered with the bank
+func TestLinkBankAccountVerifiesWithPaystack(t *testing.T) {
+	service, db := newTestBankingService(t)
+	user := testutil.CreateUser(t, db)
+	wallet := database.CryptoWallet{UserID: user.ID, Address: "0x1111222233334444", WalletType: "BASE", IsActive: true}
+	require.NoError(t, db.Create(&wallet).Error)
+
+	banks, err := service.GetBanks()
+	require.NoError(t, err)
+	assert.Equal(t, []Bank{{Name: "GCB Bank", Code: "GCB"}}, banks)
+
+	_, err = service.LinkBankAccount(user.ID, BankAccountDetails{AccountNumber: "9999999999", BankCode: "GCB", AccountName: "Kwame Mensah"})
+	assert.ErrorIs(t, err, ErrBankAccountNotVerified)
+	_, err = service.LinkBankAccount(user.ID, BankAccountDetails{AccountNumber: "0123456789", BankCode: "GCB", AccountName: "Ama Owusu"})
+	assert.ErrorIs(t, err, ErrAccountNameMismatch)
+
+	var accounts int64
+	require.NoError(t, db.Model(&database.BankAccount{}).Count(&accounts).Error)
+	assert.Zero(t, accounts, "nothing is linked when verification fails")
+
+	account, err := service.LinkBankAccount(user.ID, BankAccountDetails{AccountNumber: "0123456789", BankCode: "GCB", AccountName: "mensah, kwame"})
+	require.NoError(t, err)
+	assert.True(t, account.IsVerified)
+	assert.Equal(t, "KWAME MENSAH", account.AccountName)
+	assert.Equal(t, "GCB Bank", account.BankName)
+
+	var link database.BankWalletLink
+	require.NoError(t, db.First(&link, "bank_account_id = ?", account.ID).Error)
+	assert.Equal(t, "active", link.Status)
+	assert.Equal(t, wallet.ID, link.WalletID)
+
+	_, err = service.LinkBankAccount(user.ID, BankAccountDetails{AccountNumber: "0123456789", BankCode: "GCB"})
+	assert.ErrorIs(t, err, ErrBankAccountAlreadyLinked)
+}
+
+// Accounts can only be read, relabelled or removed by their owner, and not while money is
+// moving through them
+func TestBankAccountsAreOwnerScoped(t *testing.T) {
+	service, db := newTestBankingService(t)
+	owner := testutil.CreateUser(t, db)
+	other := testutil.CreateUser(t, db)
+
+	account := database.BankAccount{UserID: owner.ID, AccountNumber: "0123456789", AccountName: "KWAME MENSAH", BankCode: "GCB", Country: "Ghana", IsActive: true}
+	require.NoError(t, db.Create(&account).Error)
+
+	_, err := service.GetBankAccount(account.ID, other.ID)
+	assert.ErrorIs(t, err, ErrBankAccountNotFound)
+	_, err = service.UpdateBankAccountLabel(account.ID, other.ID, "Mine now")
+	assert.ErrorIs(t, err, ErrBankAccountNotFound)
+	assert.ErrorIs(t, service.DeleteBankAccount(account.ID, other.ID), ErrBankAccountNotFound)
+	accounts, err := service.GetBankAccounts(other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, accounts)
+
+	updated, err := service.UpdateBankAccountLabel(account.ID, owner.ID, "Salary")
+	require.NoError(t, err)
+	assert.Equal(t, "Salary", updated.Label)
+	stored, err := service.GetBankAccount(account.ID, owner.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Salary", stored.Label)
+
+	transfer := database.GhanaBankTransaction{UserID: owner.ID, BankAccountID: account.ID, Amount: 50, Currency: "GHS", Status: "pending", Reference: "GBT-1"}
+	require.NoError(t, db.Create(&transfer).Error)
+	assert.ErrorIs(t, service.DeleteBankAccount(account.ID, owner.ID), ErrBankAccountInUse)
+
+	require.NoError(t, db.Model(&transfer).Update("status", "completed").Error)
+	require.NoError(t, service.DeleteBankAccount(account.ID, owner.ID))
+	_, err = service.GetBankAccount(account.ID, owner.ID)
+	assert.ErrorIs(t, err, ErrBankAccountNotFound)
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/kyc"
 	"gorm.io/gorm"
 )
 
@@ -40,14 +42,14 @@ func NewGhanaComplianceService(db *gorm.DB) *GhanaComplianceService {
 
 // ValidateTransaction checks if a transaction complies with Ghanaian banking regulations
 func (s *GhanaComplianceService) ValidateTransaction(userID uuid.UUID, amount float64, transactionType string, vendorName string, vendorAddress string) (bool, []ComplianceCheck, error) {
-	// Get user's KYC status
-	var kyc database.KYC
-	if err := s.db.Where("user_id = ?", userID).First(&kyc).Error; err != nil {
+	// Get user's KYC status from their latest verification with any provider
+	kycStatus, err := kyc.GetUserStatus(s.db, userID)
+	if err != nil {
 		return false, nil, fmt.Errorf("KYC record not found: %v", err)
 	}
 
 	// Check if KYC is verified
-	if kyc.Status != "verified" {
+	if kycStatus.Status != models.KYCStatusApproved {
 		return false, []ComplianceCheck{
 			{
 				Passed:      false,
@@ -168,8 +170,8 @@ func (s *GhanaComplianceService) GenerateComplianceReport(transactionID uuid.UUI
 	}
 
 	// Get KYC details
-	var kyc database.KYC
-	if err := s.db.Where("user_id = ?", userID).First(&kyc).Error; err != nil {
+	kycStatus, err := kyc.GetUserStatus(s.db, userID)
+	if err != nil {
 		return "", err
 	}
 
@@ -183,7 +185,7 @@ func (s *GhanaComplianceService) GenerateComplianceReport(transactionID uuid.UUI
 			"user_id":    userID.String(),
 			"name":       user.FirstName + " " + user.LastName,
 			"email":      user.Email,
-			"kyc_status": kyc.Status,
+			"kyc_status": kycStatus.Status,
 		},
 		"transaction_details": transaction,
 		"compliance_checks": []map[string]interface{}{
@@ -0,0 +1,143 @@
+package compliance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/revaspay/backend/internal/database"
+)
+
+// PDF page layout in points (A4)
+const (
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 50
+	pdfLineHeight   = 16
+	pdfFontSize     = 10
+	pdfTitleSize    = 16
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+)
+
+// RenderReportPDF renders a compliance report as a simple text PDF for export
+func RenderReportPDF(report *database.ComplianceReport) ([]byte, error) {
+	var data InternationalPaymentReport
+	if err := json.Unmarshal([]byte(report.ReportData), &data); err != nil {
+		return nil, fmt.Errorf("error decoding compliance report: %w", err)
+	}
+
+	lines := []string{
+		fmt.Sprintf("Report ID: %s", report.ID),
+		fmt.Sprintf("Report type: %s", report.ReportType),
+		fmt.Sprintf("Status: %s", report.Status),
+		fmt.Sprintf("Generated at: %s", report.CreatedAt.UTC().Format(time.RFC3339)),
+		fmt.Sprintf("Regulatory authority: %s", data.RegulatoryAuthority),
+		"",
+		"Sender",
+		fmt.Sprintf("  Name: %s", data.SenderName),
+		fmt.Sprintf("  Email: %s", data.SenderEmail),
+		fmt.Sprintf("  User ID: %s", data.SenderID),
+		fmt.Sprintf("  KYC status: %s", data.SenderKYCStatus),
+		"",
+		"Payment",
+		fmt.Sprintf("  Payment ID: %s", data.PaymentID),
+		fmt.Sprintf("  Reference: %s", data.PaymentReference),
+		fmt.Sprintf("  Status at generation: %s", data.PaymentStatus),
+		fmt.Sprintf("  Created at: %s", data.PaymentCreatedAt.UTC().Format(time.RFC3339)),
+		fmt.Sprintf("  Amount (GHS): %.2f", data.AmountCedis),
+		fmt.Sprintf("  Amount (%s): %s", data.CryptoCurrency, data.AmountCrypto),
+		fmt.Sprintf("  Exchange rate (GHS/USD): %.6f", data.ExchangeRate),
+		"",
+		"Counterparty",
+		fmt.Sprintf("  Vendor: %s", data.VendorName),
+		fmt.Sprintf("  Network: %s", data.Network),
+		fmt.Sprintf("  Address: %s", data.CounterpartyAddress),
+		fmt.Sprintf("  Screening result: %s", data.ScreeningResult),
+		"",
+		fmt.Sprintf("Content hash (SHA-256): %s", report.ContentHash),
+	}
+
+	return renderTextPDF("RevasPay Compliance Report", lines), nil
+}
+
+// renderTextPDF writes a minimal PDF with a title and lines of Helvetica text,
+// starting a new page whenever the current one is full.
+func renderTextPDF(title string, lines []string) []byte {
+	var pages [][]string
+	for start := 0; start < len(lines); start += pdfLinesPerPage - 2 {
+		end := start + pdfLinesPerPage - 2
+		if end > len(lines) {
+			end = len(lines)
+		}
+		pages = append(pages, lines[start:end])
+	}
+	if len(pages) == 0 {
+		pages = append(pages, nil)
+	}
+
+	// Object layout: 1 catalog, 2 page tree, 3 font, then a page and content stream per page
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+
+	for i, pageLines := range pages {
+		var content bytes.Buffer
+		y := pdfPageHeight - pdfMargin
+		if i == 0 {
+			fmt.Fprintf(&content, "BT /F1 %d Tf %d %d Td (%s) Tj ET\n", pdfTitleSize, pdfMargin, y, pdfEscape(title))
+		}
+		y -= 2 * pdfLineHeight
+		for _, line := range pageLines {
+			fmt.Fprintf(&content, "BT /F1 %d Tf %d %d Td (%s) Tj ET\n", pdfFontSize, pdfMargin, y, pdfEscape(line))
+			y -= pdfLineHeight
+		}
+
+		objects = append(objects, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 5+2*i,
+		))
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset)
+
+	return buf.Bytes()
+}
+
+// pdfEscape escapes a string for a PDF literal and replaces characters the standard font cannot show
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteRune('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
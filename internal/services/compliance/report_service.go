@@ -0,0 +1,227 @@
+package compliance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/kyc"
+	"gorm.io/gorm"
+)
+
+// Report types and statuses
+const (
+	ReportTypeInternationalPayment = "international_payment"
+
+	ReportStatusGenerated = "generated"
+	ReportStatusFlagged   = "flagged"
+
+	ScreeningResultClear       = "clear"
+	ScreeningResultFlagged     = "flagged"
+	ScreeningResultNotScreened = "not_screened"
+)
+
+var (
+	// ErrReportNotFound is returned when a compliance report does not exist
+	ErrReportNotFound = errors.New("compliance report not found")
+
+	// ErrPaymentNotFound is returned when the payment a report is requested for does not exist
+	ErrPaymentNotFound = errors.New("international payment not found")
+)
+
+// InternationalPaymentReport is the regulatory snapshot stored in ComplianceReport.ReportData
+type InternationalPaymentReport struct {
+	ReportVersion       int       `json:"report_version"`
+	GeneratedAt         time.Time `json:"generated_at"`
+	PaymentID           uuid.UUID `json:"payment_id"`
+	PaymentReference    string    `json:"payment_reference"`
+	PaymentStatus       string    `json:"payment_status"`
+	PaymentCreatedAt    time.Time `json:"payment_created_at"`
+	SenderID            uuid.UUID `json:"sender_id"`
+	SenderName          string    `json:"sender_name"`
+	SenderEmail         string    `json:"sender_email"`
+	SenderKYCStatus     string    `json:"sender_kyc_status"`
+	VendorName          string    `json:"vendor_name"`
+	CounterpartyAddress string    `json:"counterparty_address"`
+	Network             string    `json:"network"`
+	AmountCedis         float64   `json:"amount_cedis"`
+	AmountCrypto        string    `json:"amount_crypto"`
+	CryptoCurrency      string    `json:"crypto_currency"`
+	ExchangeRate        float64   `json:"exchange_rate"`
+	ScreeningResult     string    `json:"screening_result"`
+	RegulatoryAuthority string    `json:"regulatory_authority"`
+}
+
+// ReportFilter narrows the compliance reports returned to admins
+type ReportFilter struct {
+	From   *time.Time
+	To     *time.Time
+	Status string
+}
+
+// GenerateInternationalPaymentReport creates the compliance report for an international payment.
+// Reports are immutable, so if one already exists for the payment it is returned unchanged.
+func (s *GhanaComplianceService) GenerateInternationalPaymentReport(paymentID uuid.UUID) (*database.ComplianceReport, error) {
+	existing, err := s.findPaymentReport(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	var payment database.InternationalPayment
+	if err := s.db.First(&payment, "id = ?", paymentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, fmt.Errorf("error getting international payment: %w", err)
+	}
+
+	var user database.User
+	if err := s.db.First(&user, "id = ?", payment.UserID).Error; err != nil {
+		return nil, fmt.Errorf("error getting sender: %w", err)
+	}
+
+	senderKYC, err := kyc.GetUserStatus(s.db, payment.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting sender KYC: %w", err)
+	}
+	kycStatus := string(senderKYC.Status)
+
+	screeningResult := payment.ScreeningResult
+	if screeningResult == "" {
+		screeningResult = ScreeningResultNotScreened
+	}
+
+	now := time.Now()
+	data := InternationalPaymentReport{
+		ReportVersion:       1,
+		GeneratedAt:         now,
+		PaymentID:           payment.ID,
+		PaymentReference:    payment.Reference,
+		PaymentStatus:       payment.Status,
+		PaymentCreatedAt:    payment.CreatedAt,
+		SenderID:            user.ID,
+		SenderName:          user.FirstName + " " + user.LastName,
+		SenderEmail:         user.Email,
+		SenderKYCStatus:     kycStatus,
+		VendorName:          payment.VendorName,
+		CounterpartyAddress: payment.VendorAddress,
+		Network:             payment.Network,
+		AmountCedis:         payment.AmountCedis,
+		AmountCrypto:        payment.AmountCrypto,
+		CryptoCurrency:      "USDC",
+		ExchangeRate:        payment.ExchangeRate,
+		ScreeningResult:     screeningResult,
+		RegulatoryAuthority: "Bank of Ghana",
+	}
+
+	reportJSON, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding compliance report: %w", err)
+	}
+	hash := sha256.Sum256(reportJSON)
+
+	status := ReportStatusGenerated
+	if senderKYC.Status != models.KYCStatusApproved || screeningResult == ScreeningResultFlagged {
+		status = ReportStatusFlagged
+	}
+
+	report := database.ComplianceReport{
+		UserID:                 payment.UserID,
+		InternationalPaymentID: payment.ID,
+		ReportType:             ReportTypeInternationalPayment,
+		ReportData:             string(reportJSON),
+		ContentHash:            hex.EncodeToString(hash[:]),
+		Status:                 status,
+		SenderKYCStatus:        kycStatus,
+		AmountCedis:            payment.AmountCedis,
+		AmountCrypto:           payment.AmountCrypto,
+		ExchangeRate:           payment.ExchangeRate,
+		Network:                payment.Network,
+		CounterpartyAddress:    payment.VendorAddress,
+		ScreeningResult:        screeningResult,
+	}
+
+	if err := s.db.Create(&report).Error; err != nil {
+		return nil, fmt.Errorf("error storing compliance report: %w", err)
+	}
+
+	return &report, nil
+}
+
+// GetPaymentReport returns the compliance report for a payment owned by the user,
+// generating it on first access.
+func (s *GhanaComplianceService) GetPaymentReport(paymentID, userID uuid.UUID) (*database.ComplianceReport, error) {
+	var count int64
+	if err := s.db.Model(&database.InternationalPayment{}).
+		Where("id = ? AND user_id = ?", paymentID, userID).
+		Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("error getting international payment: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrPaymentNotFound
+	}
+
+	return s.GenerateInternationalPaymentReport(paymentID)
+}
+
+// GetReport returns a compliance report by ID
+func (s *GhanaComplianceService) GetReport(reportID uuid.UUID) (*database.ComplianceReport, error) {
+	var report database.ComplianceReport
+	if err := s.db.First(&report, "id = ?", reportID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReportNotFound
+		}
+		return nil, fmt.Errorf("error getting compliance report: %w", err)
+	}
+	return &report, nil
+}
+
+// ListReports returns a page of compliance reports matching the filter, newest first
+func (s *GhanaComplianceService) ListReports(filter ReportFilter, page, pageSize int) ([]database.ComplianceReport, int64, error) {
+	query := s.db.Model(&database.ComplianceReport{})
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error counting compliance reports: %w", err)
+	}
+
+	var reports []database.ComplianceReport
+	offset := (page - 1) * pageSize
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&reports).Error; err != nil {
+		return nil, 0, fmt.Errorf("error getting compliance reports: %w", err)
+	}
+
+	return reports, total, nil
+}
+
+// findPaymentReport returns the existing report for a payment, or nil if there is none
+func (s *GhanaComplianceService) findPaymentReport(paymentID uuid.UUID) (*database.ComplianceReport, error) {
+	var report database.ComplianceReport
+	err := s.db.Where("international_payment_id = ? AND report_type = ?", paymentID, ReportTypeInternationalPayment).
+		First(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting compliance report: %w", err)
+	}
+	return &report, nil
+}
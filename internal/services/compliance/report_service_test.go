@@ -0,0 +1,60 @@
+package compliance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A payment's report snapshots it once, is flagged when the sender isn't verified, and can't
+// be changed afterwards
+func TestGenerateInternationalPaymentReport(t *testing.T) {
+	db := testutil.NewDB(t)
+	service := NewGhanaComplianceService(db)
+	user := testutil.CreateUser(t, db)
+
+	payment := database.InternationalPayment{
+		UserID:          user.ID,
+		VendorName:      "Acme Ltd",
+		VendorAddress:   "0x1234567890abcdef1234567890abcdef12345678",
+		Network:         "base",
+		ScreeningResult: ScreeningResultClear,
+		AmountCedis:     500,
+		AmountCrypto:    "40.000000",
+		ExchangeRate:    0.08,
+		Reference:       "IP-1",
+		Status:          "completed",
+	}
+	require.NoError(t, db.Create(&payment).Error)
+
+	_, err := service.GetPaymentReport(payment.ID, testutil.CreateUser(t, db).ID)
+	assert.ErrorIs(t, err, ErrPaymentNotFound)
+
+	report, err := service.GetPaymentReport(payment.ID, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ReportStatusFlagged, report.Status, "senders without approved KYC are flagged")
+	assert.Equal(t, string(models.KYCStatusNotSubmitted), report.SenderKYCStatus)
+	assert.Equal(t, 500.0, report.AmountCedis)
+	assert.Equal(t, "40.000000", report.AmountCrypto)
+	assert.Equal(t, payment.VendorAddress, report.CounterpartyAddress)
+	assert.Equal(t, ScreeningResultClear, report.ScreeningResult)
+	hash := sha256.Sum256([]byte(report.ReportData))
+	assert.Equal(t, hex.EncodeToString(hash[:]), report.ContentHash)
+
+	again, err := service.GenerateInternationalPaymentReport(payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, report.ID, again.ID, "reports are generated once per payment")
+
+	assert.ErrorIs(t, db.Model(report).Update("status", ReportStatusGenerated).Error, database.ErrComplianceReportImmutable)
+	assert.ErrorIs(t, db.Delete(report).Error, database.ErrComplianceReportImmutable)
+	stored, err := service.GetReport(report.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ReportStatusFlagged, stored.Status)
+	assert.Equal(t, report.ContentHash, stored.ContentHash)
+}
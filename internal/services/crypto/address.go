@@ -0,0 +1,59 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Supported networks for vendor payouts
+const (
+	NetworkBase     = "base"
+	NetworkEthereum = "ethereum"
+	NetworkPolygon  = "polygon"
+	NetworkBitcoin  = "bitcoin"
+	NetworkTron     = "tron"
+)
+
+var (
+	// ErrUnsupportedNetwork is returned for networks we cannot send to
+	ErrUnsupportedNetwork = errors.New("unsupported network")
+
+	// ErrInvalidAddress is returned when an address is not valid for its network
+	ErrInvalidAddress = errors.New("invalid address for network")
+
+	bitcoinLegacyAddress = regexp.MustCompile(`^[13][a-km-zA-HJ-NP-Z1-9]{25,34}$`)
+	bitcoinBech32Address = regexp.MustCompile(`^(bc1|BC1)[02-9ac-hj-np-zAC-HJ-NP-Z]{11,71}$`)
+	tronAddress          = regexp.MustCompile(`^T[1-9A-HJ-NP-Za-km-z]{33}$`)
+)
+
+// ValidateAddress checks that an address has the right format for the given network.
+// EVM addresses with mixed case must also carry a valid EIP-55 checksum.
+func ValidateAddress(network, address string) error {
+	switch strings.ToLower(network) {
+	case NetworkBase, NetworkEthereum, NetworkPolygon:
+		if !common.IsHexAddress(address) || !strings.HasPrefix(address, "0x") {
+			return fmt.Errorf("%w: %s", ErrInvalidAddress, network)
+		}
+		hexPart := address[2:]
+		if strings.ToLower(hexPart) != hexPart && strings.ToUpper(hexPart) != hexPart {
+			if common.HexToAddress(address).Hex() != address {
+				return fmt.Errorf("%w: %s (bad checksum)", ErrInvalidAddress, network)
+			}
+		}
+	case NetworkBitcoin:
+		if !bitcoinLegacyAddress.MatchString(address) && !bitcoinBech32Address.MatchString(address) {
+			return fmt.Errorf("%w: %s", ErrInvalidAddress, network)
+		}
+	case NetworkTron:
+		if !tronAddress.MatchString(address) {
+			return fmt.Errorf("%w: %s", ErrInvalidAddress, network)
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedNetwork, network)
+	}
+	return nil
+}
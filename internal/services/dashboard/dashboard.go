@@ -0,0 +1,162 @@
+// Package dashboard computes the operational metrics shown on the admin home screen
+package dashboard
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"gorm.io/gorm"
+)
+
+// cacheTTL is how long computed metrics are served before they're recomputed, so a busy
+// dashboard doesn't rerun the aggregates on every request
+const cacheTTL = 30 * time.Second
+
+// UserMetrics counts registered users
+type UserMetrics struct {
+	Total    int64 `json:"total"`
+	Verified int64 `json:"verified"`
+}
+
+// CurrencyBalance totals wallet balances in one currency
+type CurrencyBalance struct {
+	Currency models.Currency `json:"currency"`
+	Balance  float64         `json:"balance"`
+	Held     float64         `json:"held"` // Reserved for pending withdrawals
+}
+
+// CurrencyVolume totals completed amounts in one currency over the last day, week and month.
+// Today starts at midnight UTC.
+type CurrencyVolume struct {
+	Currency   models.Currency `json:"currency"`
+	Today      float64         `json:"today"`
+	Last7Days  float64         `gorm:"column:last_7_days" json:"last_7_days"`
+	Last30Days float64         `gorm:"column:last_30_days" json:"last_30_days"`
+}
+
+// PendingPayouts totals the withdrawals in one currency that haven't been paid out yet
+type PendingPayouts struct {
+	Currency models.Currency `json:"currency"`
+	Count    int64           `json:"count"`
+	Amount   float64         `json:"amount"`
+}
+
+// Metrics is an at-a-glance view of the platform. Amounts are reported per currency.
+type Metrics struct {
+	Users            UserMetrics       `json:"users"`
+	PendingKYC       int64             `json:"pending_kyc"` // Verifications waiting on the user or the provider
+	WalletBalances   []CurrencyBalance `json:"wallet_balances"`
+	PaymentVolume    []CurrencyVolume  `json:"payment_volume"`
+	WithdrawalVolume []CurrencyVolume  `json:"withdrawal_volume"`
+	PendingPayouts   []PendingPayouts  `json:"pending_payouts"`
+	FailedJobs       int64             `json:"failed_jobs"`
+	GeneratedAt      time.Time         `json:"generated_at"`
+}
+
+// Service computes dashboard metrics, caching them for cacheTTL
+type Service struct {
+	db *gorm.DB
+
+	mu      sync.Mutex
+	metrics *Metrics
+}
+
+// NewService creates a dashboard metrics service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Metrics returns the current metrics, recomputing them once the cached ones are older than
+// cacheTTL. Each metric is a single aggregate query.
+func (s *Service) Metrics() (*Metrics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.metrics != nil && time.Since(s.metrics.GeneratedAt) < cacheTTL {
+		return s.metrics, nil
+	}
+
+	metrics, err := s.compute(time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	s.metrics = metrics
+	return metrics, nil
+}
+
+// compute runs the aggregate queries as of now
+func (s *Service) compute(now time.Time) (*Metrics, error) {
+	metrics := &Metrics{
+		WalletBalances:   []CurrencyBalance{},
+		PaymentVolume:    []CurrencyVolume{},
+		WithdrawalVolume: []CurrencyVolume{},
+		PendingPayouts:   []PendingPayouts{},
+		GeneratedAt:      now,
+	}
+
+	if err := s.db.Model(&models.User{}).
+		Select("COUNT(*) AS total, COUNT(*) FILTER (WHERE is_verified) AS verified").
+		Scan(&metrics.Users).Error; err != nil {
+		return nil, fmt.Errorf("error counting users: %w", err)
+	}
+
+	if err := s.db.Model(&models.KYCVerification{}).
+		Where("status IN ?", []models.KYCStatus{models.KYCStatusPending, models.KYCStatusInProgress}).
+		Count(&metrics.PendingKYC).Error; err != nil {
+		return nil, fmt.Errorf("error counting pending KYC verifications: %w", err)
+	}
+
+	if err := s.db.Model(&models.Wallet{}).
+		Select("currency, COALESCE(SUM(balance), 0) AS balance, COALESCE(SUM(held), 0) AS held").
+		Group("currency").
+		Order("currency").
+		Scan(&metrics.WalletBalances).Error; err != nil {
+		return nil, fmt.Errorf("error totalling wallet balances: %w", err)
+	}
+
+	if err := volumeQuery(s.db.Model(&models.Payment{}).Where("status = ?", models.PaymentStatusCompleted), now).
+		Scan(&metrics.PaymentVolume).Error; err != nil {
+		return nil, fmt.Errorf("error totalling payment volume: %w", err)
+	}
+
+	if err := volumeQuery(s.db.Model(&models.Withdrawal{}).Where("status = ?", "completed"), now).
+		Scan(&metrics.WithdrawalVolume).Error; err != nil {
+		return nil, fmt.Errorf("error totalling withdrawal volume: %w", err)
+	}
+
+	if err := s.db.Model(&models.Withdrawal{}).
+		Select("currency, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").
+		Where("status IN ?", []string{"pending", "processing"}).
+		Group("currency").
+		Order("currency").
+		Scan(&metrics.PendingPayouts).Error; err != nil {
+		return nil, fmt.Errorf("error totalling pending payouts: %w", err)
+	}
+
+	if err := s.db.Model(&queue.Job{}).
+		Where("status = ?", queue.JobStatusFailed).
+		Count(&metrics.FailedJobs).Error; err != nil {
+		return nil, fmt.Errorf("error counting failed jobs: %w", err)
+	}
+
+	return metrics, nil
+}
+
+// volumeQuery totals the amounts of the rows matched by query per currency over the last day,
+// week and month, in one pass over the last month's rows
+func volumeQuery(query *gorm.DB, now time.Time) *gorm.DB {
+	today := now.Truncate(24 * time.Hour)
+	week := now.AddDate(0, 0, -7)
+	month := now.AddDate(0, 0, -30)
+
+	return query.
+		Select(`currency,
+			COALESCE(SUM(amount) FILTER (WHERE created_at >= ?), 0) AS today,
+			COALESCE(SUM(amount) FILTER (WHERE created_at >= ?), 0) AS last_7_days,
+			COALESCE(SUM(amount), 0) AS last_30_days`, today, week).
+		Where("created_at >= ?", month).
+		Group("currency").
+		Order("currency")
+}
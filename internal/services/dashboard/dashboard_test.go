@@ -0,0 +1,78 @@
+package dashboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&queue.Job{}))
+
+	now := time.Now().UTC()
+	user := testutil.CreateUser(t, db)
+	unverified := testutil.CreateUser(t, db)
+	require.NoError(t, db.Model(unverified).Update("is_verified", false).Error)
+
+	require.NoError(t, db.Create(&models.KYCVerification{ID: uuid.New(), UserID: unverified.ID, Status: models.KYCStatusInProgress}).Error)
+	require.NoError(t, db.Create(&models.KYCVerification{ID: uuid.New(), UserID: user.ID, Status: models.KYCStatusApproved}).Error)
+
+	wallet := models.Wallet{ID: uuid.New(), UserID: user.ID, Currency: models.CurrencyGHS, Balance: 150, Available: 100, Held: 50}
+	require.NoError(t, db.Create(&wallet).Error)
+	require.NoError(t, db.Create(&models.Wallet{ID: uuid.New(), UserID: unverified.ID, Currency: models.CurrencyGHS, Balance: 25, Available: 25}).Error)
+	require.NoError(t, db.Create(&models.Wallet{ID: uuid.New(), UserID: user.ID, Currency: models.CurrencyUSD, Balance: 10, Available: 10}).Error)
+
+	payment := func(amount float64, status models.PaymentStatus, age time.Duration) {
+		require.NoError(t, db.Create(&models.Payment{
+			ID: uuid.New(), UserID: user.ID, Amount: amount, Currency: models.CurrencyGHS, Provider: models.PaymentProviderPaystack,
+			Status: status, Reference: uuid.NewString(), CreatedAt: now.Add(-age),
+		}).Error)
+	}
+	payment(10, models.PaymentStatusCompleted, 0)
+	payment(20, models.PaymentStatusCompleted, 3*24*time.Hour)
+	payment(40, models.PaymentStatusCompleted, 20*24*time.Hour)
+	payment(80, models.PaymentStatusCompleted, 40*24*time.Hour)
+	payment(160, models.PaymentStatusFailed, time.Minute)
+
+	withdrawal := func(amount float64, status string, age time.Duration) {
+		require.NoError(t, db.Create(&models.Withdrawal{
+			ID: uuid.New(), UserID: user.ID, WalletID: wallet.ID, Amount: amount, Currency: models.CurrencyGHS,
+			Method: "bank", Status: status, CreatedAt: now.Add(-age),
+		}).Error)
+	}
+	withdrawal(5, "completed", 0)
+	withdrawal(30, "pending", time.Minute)
+	withdrawal(20, "processing", time.Hour)
+	withdrawal(7, "failed", time.Minute)
+
+	require.NoError(t, db.Create(&queue.Job{ID: uuid.New(), Type: "test", Status: queue.JobStatusFailed}).Error)
+	require.NoError(t, db.Create(&queue.Job{ID: uuid.New(), Type: "test", Status: queue.JobStatusCompleted}).Error)
+
+	service := NewService(db)
+	metrics, err := service.Metrics()
+	require.NoError(t, err)
+
+	assert.Equal(t, UserMetrics{Total: 2, Verified: 1}, metrics.Users)
+	assert.Equal(t, int64(1), metrics.PendingKYC)
+	assert.Equal(t, []CurrencyBalance{
+		{Currency: models.CurrencyGHS, Balance: 175, Held: 50},
+		{Currency: models.CurrencyUSD, Balance: 10},
+	}, metrics.WalletBalances)
+	assert.Equal(t, []CurrencyVolume{{Currency: models.CurrencyGHS, Today: 10, Last7Days: 30, Last30Days: 70}}, metrics.PaymentVolume)
+	assert.Equal(t, []CurrencyVolume{{Currency: models.CurrencyGHS, Today: 5, Last7Days: 5, Last30Days: 5}}, metrics.WithdrawalVolume)
+	assert.Equal(t, []PendingPayouts{{Currency: models.CurrencyGHS, Count: 2, Amount: 50}}, metrics.PendingPayouts)
+	assert.Equal(t, int64(1), metrics.FailedJobs)
+
+	// Metrics are cached briefly
+	payment(1000, models.PaymentStatusCompleted, time.Minute)
+	cached, err := service.Metrics()
+	require.NoError(t, err)
+	assert.Same(t, metrics, cached)
+}
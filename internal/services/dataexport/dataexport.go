@@ -0,0 +1,329 @@
+// Package dataexport compiles a copy of everything RevasPay holds about a user, for users
+// exercising their right of access under GDPR and similar data protection laws
+package dataexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/email"
+	"github.com/revaspay/backend/internal/services/storage"
+	"gorm.io/gorm"
+)
+
+// BuildDataExportJobType is the job type for compiling a requested data export
+const BuildDataExportJobType queue.JobType = "build_data_export"
+
+const (
+	// RequestInterval is how long a user has to wait between exports
+	RequestInterval = 24 * time.Hour
+
+	// LinkExpiry is how long the emailed download link, and the file behind it, are kept
+	LinkExpiry = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrExportRateLimited is returned when the user already requested an export within RequestInterval
+	ErrExportRateLimited = errors.New("a data export was already requested in the last 24 hours")
+
+	// ErrInvalidFormat is returned for export formats other than json and zip
+	ErrInvalidFormat = errors.New("export format must be json or zip")
+)
+
+// BuildDataExportPayload is the payload of a BuildDataExportJobType job
+type BuildDataExportPayload struct {
+	ExportID uuid.UUID `json:"export_id"`
+}
+
+// JobEnqueuer enqueues background jobs
+type JobEnqueuer interface {
+	EnqueueJob(jobType queue.JobType, payload interface{}) (string, error)
+}
+
+// Mailer sends templated emails
+type Mailer interface {
+	SendTemplate(toEmail, name, locale string, data map[string]interface{}) error
+}
+
+// Service takes users' export requests and builds the exports in the background
+type Service struct {
+	db     *gorm.DB
+	store  storage.Backend
+	mailer Mailer
+	queue  JobEnqueuer
+}
+
+// NewService creates a new data export service
+func NewService(db *gorm.DB, store storage.Backend, mailer Mailer, q JobEnqueuer) *Service {
+	return &Service{
+		db:     db,
+		store:  store,
+		mailer: mailer,
+		queue:  q,
+	}
+}
+
+// Request records a data export for the user and queues it to be built. Users can request one
+// export per RequestInterval.
+func (s *Service) Request(userID uuid.UUID, format models.DataExportFormat) (*models.DataExport, error) {
+	if format == "" {
+		format = models.DataExportFormatJSON
+	}
+	if format != models.DataExportFormatJSON && format != models.DataExportFormatZip {
+		return nil, ErrInvalidFormat
+	}
+
+	var recent int64
+	if err := s.db.Model(&models.DataExport{}).
+		Where("user_id = ? AND created_at > ? AND status <> ?", userID, time.Now().Add(-RequestInterval), models.DataExportStatusFailed).
+		Count(&recent).Error; err != nil {
+		return nil, fmt.Errorf("failed to check recent data exports: %w", err)
+	}
+	if recent > 0 {
+		return nil, ErrExportRateLimited
+	}
+
+	export := &models.DataExport{
+		ID:     uuid.New(),
+		UserID: userID,
+		Status: models.DataExportStatusPending,
+		Format: format,
+	}
+	if err := s.db.Create(export).Error; err != nil {
+		return nil, fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	if _, err := s.queue.EnqueueJob(BuildDataExportJobType, BuildDataExportPayload{ExportID: export.ID}); err != nil {
+		s.fail(export, err)
+		return nil, fmt.Errorf("failed to queue data export: %w", err)
+	}
+	return export, nil
+}
+
+// Build compiles a pending export, stores it and emails the user a link to download it
+func (s *Service) Build(ctx context.Context, exportID uuid.UUID) error {
+	var export models.DataExport
+	if err := s.db.First(&export, "id = ?", exportID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Data export %s not found, skipping", exportID)
+			return nil
+		}
+		return fmt.Errorf("failed to find data export: %w", err)
+	}
+	if export.Status != models.DataExportStatusPending {
+		return nil
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", export.UserID).Error; err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	data, err := s.compile(&user)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal data export: %w", err)
+	}
+	key := fmt.Sprintf("data-exports/%s/%s.json", user.ID, export.ID)
+	contentType := "application/json"
+	if export.Format == models.DataExportFormatZip {
+		if body, err = zipFile("revaspay-data.json", body); err != nil {
+			return fmt.Errorf("failed to zip data export: %w", err)
+		}
+		key = fmt.Sprintf("data-exports/%s/%s.zip", user.ID, export.ID)
+		contentType = "application/zip"
+	}
+
+	if err := s.store.Put(ctx, key, body, contentType); err != nil {
+		return fmt.Errorf("failed to store data export: %w", err)
+	}
+	link, err := s.store.SignedURL(ctx, key, LinkExpiry)
+	if err != nil {
+		return fmt.Errorf("failed to sign data export link: %w", err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(LinkExpiry)
+	if err := s.db.Model(&export).Updates(map[string]interface{}{
+		"status":       models.DataExportStatusCompleted,
+		"storage_key":  key,
+		"completed_at": now,
+		"expires_at":   expiresAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to complete data export: %w", err)
+	}
+
+	if err := s.mailer.SendTemplate(user.Email, email.TemplateDataExportReady, email.DefaultLocale, map[string]interface{}{
+		"Name":      user.FirstName,
+		"Link":      link,
+		"ExpiresAt": expiresAt.UTC().Format("2 Jan 2006, 15:04 MST"),
+	}); err != nil {
+		// The export is stored, so retrying the job would only build it again
+		log.Printf("Failed to email data export %s to user %s: %v", export.ID, user.ID, err)
+	}
+	return nil
+}
+
+// MarkFailed records that an export could not be built, so the user can request another
+func (s *Service) MarkFailed(exportID uuid.UUID, cause error) {
+	s.fail(&models.DataExport{ID: exportID}, cause)
+}
+
+// DeleteExpired deletes the files and records of exports whose links have expired, returning
+// how many were removed
+func (s *Service) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var expired []models.DataExport
+	if err := s.db.Where("expires_at < ?", now).Find(&expired).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired data exports: %w", err)
+	}
+
+	var removed int64
+	for _, export := range expired {
+		if export.StorageKey != "" {
+			if err := s.store.Delete(ctx, export.StorageKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				log.Printf("Failed to delete data export %s: %v", export.ID, err)
+				continue
+			}
+		}
+		if err := s.db.Delete(&export).Error; err != nil {
+			return removed, fmt.Errorf("failed to delete data export %s: %w", export.ID, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// fail marks an export as failed
+func (s *Service) fail(export *models.DataExport, cause error) {
+	if err := s.db.Model(export).Updates(map[string]interface{}{
+		"status": models.DataExportStatusFailed,
+		"error":  cause.Error(),
+	}).Error; err != nil {
+		log.Printf("Failed to mark data export %s as failed: %v", export.ID, err)
+	}
+}
+
+// UserData is the content of a data export
+type UserData struct {
+	ExportedAt         time.Time            `json:"exported_at"`
+	Profile            models.User          `json:"profile"`
+	KYC                []KYCSummary         `json:"kyc"`
+	Payments           []models.Payment     `json:"payments"`
+	Withdrawals        []models.Withdrawal  `json:"withdrawals"`
+	WalletTransactions []models.Transaction `json:"wallet_transactions"`
+	Sessions           []SessionSummary     `json:"sessions"`
+	Referrals          []models.Referral    `json:"referrals"`
+}
+
+// KYCSummary is the outcome of a KYC verification. Identity documents and the details read
+// from them are left out of exports.
+type KYCSummary struct {
+	Provider        string           `json:"provider"`
+	Status          models.KYCStatus `json:"status"`
+	RejectionReason *string          `json:"rejection_reason,omitempty"`
+	VerifiedAt      *time.Time       `json:"verified_at,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+}
+
+// SessionSummary is a login session, without its tokens
+type SessionSummary struct {
+	UserAgent    string                 `json:"user_agent"`
+	IPAddress    string                 `json:"ip_address"`
+	Status       database.SessionStatus `json:"status"`
+	CreatedAt    time.Time              `json:"created_at"`
+	LastActiveAt time.Time              `json:"last_active_at"`
+	ExpiresAt    time.Time              `json:"expires_at"`
+	RevokedAt    *time.Time             `json:"revoked_at,omitempty"`
+}
+
+// compile gathers the user's data
+func (s *Service) compile(user *models.User) (*UserData, error) {
+	data := &UserData{
+		ExportedAt:         time.Now().UTC(),
+		Profile:            *user,
+		KYC:                []KYCSummary{},
+		Payments:           []models.Payment{},
+		Withdrawals:        []models.Withdrawal{},
+		WalletTransactions: []models.Transaction{},
+		Sessions:           []SessionSummary{},
+		Referrals:          []models.Referral{},
+	}
+
+	var verifications []models.KYCVerification
+	if err := s.db.Where("user_id = ?", user.ID).Order("created_at").Find(&verifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to load KYC verifications: %w", err)
+	}
+	for _, v := range verifications {
+		data.KYC = append(data.KYC, KYCSummary{
+			Provider:        v.Provider,
+			Status:          v.Status,
+			RejectionReason: v.RejectionReason,
+			VerifiedAt:      v.VerifiedAt,
+			CreatedAt:       v.CreatedAt,
+		})
+	}
+
+	if err := s.db.Where("user_id = ?", user.ID).Order("created_at").Find(&data.Payments).Error; err != nil {
+		return nil, fmt.Errorf("failed to load payments: %w", err)
+	}
+	if err := s.db.Where("user_id = ?", user.ID).Order("created_at").Find(&data.Withdrawals).Error; err != nil {
+		return nil, fmt.Errorf("failed to load withdrawals: %w", err)
+	}
+	if err := s.db.Where("wallet_id IN (?)", s.db.Model(&models.Wallet{}).Select("id").Where("user_id = ?", user.ID)).
+		Order("created_at").Find(&data.WalletTransactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load wallet transactions: %w", err)
+	}
+
+	var sessions []database.EnhancedSession
+	if err := s.db.Where("user_id = ?", user.ID).Order("created_at").Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load sessions: %w", err)
+	}
+	for _, session := range sessions {
+		data.Sessions = append(data.Sessions, SessionSummary{
+			UserAgent:    session.UserAgent,
+			IPAddress:    session.IPAddress,
+			Status:       session.Status,
+			CreatedAt:    session.CreatedAt,
+			LastActiveAt: session.LastActiveAt,
+			ExpiresAt:    session.ExpiresAt,
+			RevokedAt:    session.RevokedAt,
+		})
+	}
+
+	if err := s.db.Where("referrer_id = ? OR referred_user_id = ?", user.ID, user.ID).
+		Order("created_at").Find(&data.Referrals).Error; err != nil {
+		return nil, fmt.Errorf("failed to load referrals: %w", err)
+	}
+	return data, nil
+}
+
+// zipFile returns a zip archive holding a single file
+func zipFile(name string, content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(content); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
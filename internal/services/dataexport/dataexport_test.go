@@ -0,0 +1,95 @@
+package dataexport
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/storage"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingQueue struct {
+	payloads []interface{}
+}
+
+func (q *recordingQueue) EnqueueJob(jobType queue.JobType, payload interface{}) (string, error) {
+	q.payloads = append(q.payloads, payload)
+	return uuid.NewString(), nil
+}
+
+type recordingMailer struct {
+	sent []map[string]interface{}
+}
+
+func (m *recordingMailer) SendTemplate(toEmail, name, locale string, data map[string]interface{}) error {
+	m.sent = append(m.sent, data)
+	return nil
+}
+
+func TestRequestAndBuildDataExport(t *testing.T) {
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&database.EnhancedSession{}))
+	user := testutil.CreateUser(t, db)
+
+	require.NoError(t, db.Create(&models.Payment{
+		ID: uuid.New(), UserID: user.ID, Amount: 100, Currency: "GHS", Provider: models.PaymentProviderPaystack,
+		Status: models.PaymentStatusCompleted, Reference: "PAY-1",
+	}).Error)
+	require.NoError(t, db.Create(&database.EnhancedSession{
+		ID: uuid.New(), UserID: user.ID, RefreshToken: "secret-refresh-token", IPAddress: "203.0.113.5",
+		Status: database.SessionStatusActive, ExpiresAt: time.Now().Add(time.Hour),
+	}).Error)
+
+	store, err := storage.NewLocalBackend(storage.LocalConfig{Root: t.TempDir()})
+	require.NoError(t, err)
+	q := &recordingQueue{}
+	mailer := &recordingMailer{}
+	service := NewService(db, store, mailer, q)
+
+	export, err := service.Request(user.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.DataExportFormatJSON, export.Format)
+	require.Len(t, q.payloads, 1)
+
+	_, err = service.Request(user.ID, models.DataExportFormatJSON)
+	assert.ErrorIs(t, err, ErrExportRateLimited)
+
+	require.NoError(t, service.Build(context.Background(), export.ID))
+
+	var built models.DataExport
+	require.NoError(t, db.First(&built, "id = ?", export.ID).Error)
+	assert.Equal(t, models.DataExportStatusCompleted, built.Status)
+	require.NotNil(t, built.ExpiresAt)
+	require.Len(t, mailer.sent, 1)
+	assert.NotEmpty(t, mailer.sent[0]["Link"])
+
+	r, _, err := store.Get(context.Background(), built.StorageKey)
+	require.NoError(t, err)
+	body, err := io.ReadAll(r)
+	r.Close()
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "secret-refresh-token")
+
+	var data UserData
+	require.NoError(t, json.Unmarshal(body, &data))
+	assert.Equal(t, user.Email, data.Profile.Email)
+	require.Len(t, data.Payments, 1)
+	assert.Equal(t, "PAY-1", data.Payments[0].Reference)
+	require.Len(t, data.Sessions, 1)
+	assert.Equal(t, "203.0.113.5", data.Sessions[0].IPAddress)
+
+	removed, err := service.DeleteExpired(context.Background(), built.ExpiresAt.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	_, _, err = store.Get(context.Background(), built.StorageKey)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
@@ -1,9 +1,13 @@
 package email
 
 import (
+	"bytes"
 	"fmt"
 	"log"
+	"mime"
+	"mime/multipart"
 	"net/smtp"
+	"net/textproto"
 	"os"
 )
 
@@ -14,6 +18,7 @@ type EmailService struct {
 	smtpUsername string
 	smtpPassword string
 	fromEmail    string
+	templates    *TemplateService
 }
 
 // NewEmailService creates a new email service
@@ -24,103 +29,83 @@ func NewEmailService() *EmailService {
 		smtpUsername: os.Getenv("SMTP_USERNAME"),
 		smtpPassword: os.Getenv("SMTP_PASSWORD"),
 		fromEmail:    os.Getenv("FROM_EMAIL"),
+		templates:    defaultTemplates(),
 	}
 }
 
+// Templates returns the templates the service renders emails from
+func (s *EmailService) Templates() *TemplateService {
+	return s.templates
+}
+
+// SendTemplate renders a template with the given variables and emails it. locale may be empty
+// to use DefaultLocale.
+func (s *EmailService) SendTemplate(toEmail, name, locale string, data map[string]interface{}) error {
+	msg, err := s.templates.Render(name, locale, data)
+	if err != nil {
+		return err
+	}
+	return s.sendEmail(toEmail, msg)
+}
+
 // SendVerificationEmail sends an email with a verification link
 func (s *EmailService) SendVerificationEmail(toEmail, username, token string) error {
-	subject := "Verify Your RevasPay Account"
-	verificationLink := fmt.Sprintf("%s/verify-email?token=%s", os.Getenv("FRONTEND_URL"), token)
-	
-	body := fmt.Sprintf(`
-	<!DOCTYPE html>
-	<html>
-	<head>
-		<style>
-			body { font-family: Arial, sans-serif; line-height: 1.6; }
-			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
-			.header { background-color: #4F46E5; color: white; padding: 10px; text-align: center; }
-			.content { padding: 20px; }
-			.button { display: inline-block; background-color: #4F46E5; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px; }
-		</style>
-	</head>
-	<body>
-		<div class="container">
-			<div class="header">
-				<h1>RevasPay</h1>
-			</div>
-			<div class="content">
-				<h2>Hello %s,</h2>
-				<p>Thank you for signing up with RevasPay! Please verify your email address to activate your account.</p>
-				<p><a href="%s" class="button">Verify Email</a></p>
-				<p>Or copy and paste this link in your browser: %s</p>
-				<p>This link will expire in 48 hours.</p>
-				<p>If you did not create an account with RevasPay, please ignore this email.</p>
-				<p>Best regards,<br>The RevasPay Team</p>
-			</div>
-		</div>
-	</body>
-	</html>
-	`, username, verificationLink, verificationLink)
-	
-	return s.sendEmail(toEmail, subject, body)
+	return s.SendTemplate(toEmail, TemplateVerification, DefaultLocale, map[string]interface{}{
+		"Name": username,
+		"Link": fmt.Sprintf("%s/verify-email?token=%s", os.Getenv("FRONTEND_URL"), token),
+	})
 }
 
 // SendPasswordResetEmail sends an email with a password reset link
 func (s *EmailService) SendPasswordResetEmail(toEmail, username, token string) error {
-	subject := "Reset Your RevasPay Password"
-	resetLink := fmt.Sprintf("%s/reset-password?token=%s", os.Getenv("FRONTEND_URL"), token)
-	
-	body := fmt.Sprintf(`
-	<!DOCTYPE html>
-	<html>
-	<head>
-		<style>
-			body { font-family: Arial, sans-serif; line-height: 1.6; }
-			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
-			.header { background-color: #4F46E5; color: white; padding: 10px; text-align: center; }
-			.content { padding: 20px; }
-			.button { display: inline-block; background-color: #4F46E5; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px; }
-		</style>
-	</head>
-	<body>
-		<div class="container">
-			<div class="header">
-				<h1>RevasPay</h1>
-			</div>
-			<div class="content">
-				<h2>Hello %s,</h2>
-				<p>We received a request to reset your RevasPay password. Click the button below to create a new password:</p>
-				<p><a href="%s" class="button">Reset Password</a></p>
-				<p>Or copy and paste this link in your browser: %s</p>
-				<p>This link will expire in 24 hours.</p>
-				<p>If you did not request a password reset, please ignore this email or contact support if you have concerns.</p>
-				<p>Best regards,<br>The RevasPay Team</p>
-			</div>
-		</div>
-	</body>
-	</html>
-	`, username, resetLink, resetLink)
-	
-	return s.sendEmail(toEmail, subject, body)
+	return s.SendTemplate(toEmail, TemplatePasswordReset, DefaultLocale, map[string]interface{}{
+		"Name": username,
+		"Link": fmt.Sprintf("%s/reset-password?token=%s", os.Getenv("FRONTEND_URL"), token),
+	})
+}
+
+// SendNotificationEmail sends a plain account notification, such as a payment status update
+func (s *EmailService) SendNotificationEmail(toEmail, username, subject, message string) error {
+	return s.SendTemplate(toEmail, TemplateNotification, DefaultLocale, map[string]interface{}{
+		"Name":    username,
+		"Title":   subject,
+		"Message": message,
+	})
 }
 
-// sendEmail sends an email with HTML content
-func (s *EmailService) sendEmail(toEmail, subject, htmlBody string) error {
+// sendEmail sends a rendered email with both its HTML and plain text versions
+func (s *EmailService) sendEmail(toEmail string, msg *Message) error {
 	if s.smtpHost == "" || s.smtpPort == "" || s.smtpUsername == "" || s.smtpPassword == "" {
 		log.Println("Email service not configured properly. Check environment variables.")
 		return fmt.Errorf("email service not configured")
 	}
 
-	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-	from := fmt.Sprintf("From: RevasPay <%s>\n", s.fromEmail)
-	to := fmt.Sprintf("To: %s\n", toEmail)
-	subject = fmt.Sprintf("Subject: %s\n", subject)
-	
-	message := []byte(from + to + subject + mime + htmlBody)
-	
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {part.contentType + "; charset=\"UTF-8\""},
+		})
+		if err != nil {
+			return fmt.Errorf("error building email: %w", err)
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return fmt.Errorf("error building email: %w", err)
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return fmt.Errorf("error building email: %w", err)
+	}
+
+	header := fmt.Sprintf("From: RevasPay <%s>\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/alternative; boundary=%q\r\n\r\n",
+		s.fromEmail, toEmail, mime.QEncoding.Encode("utf-8", msg.Subject), parts.Boundary())
+	message := append([]byte(header), body.Bytes()...)
+
 	auth := smtp.PlainAuth("", s.smtpUsername, s.smtpPassword, s.smtpHost)
 	addr := fmt.Sprintf("%s:%s", s.smtpHost, s.smtpPort)
-	
+
 	return smtp.SendMail(addr, auth, s.fromEmail, []string{toEmail}, message)
 }
@@ -0,0 +1,209 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+// Transactional email templates
+const (
+	TemplateVerification           = "verification"
+	TemplatePasswordReset          = "password_reset"
+	TemplateNotification           = "notification"
+	TemplatePaymentReceipt         = "payment_receipt"
+	TemplateWithdrawalConfirmation = "withdrawal_confirmation"
+	TemplateKYCApproved            = "kyc_approved"
+	TemplateKYCRejected            = "kyc_rejected"
+	TemplateDataExportReady        = "data_export_ready"
+	TemplateNewLogin               = "new_login"
+)
+
+// DefaultLocale is used when an email has no template in the requested locale
+const DefaultLocale = "en"
+
+// ErrTemplateNotFound is returned when a template doesn't exist in the requested or default locale
+var ErrTemplateNotFound = errors.New("email template not found")
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// defaultTemplates loads the templates built into the binary. They are checked by the tests,
+// so failing to load them is a programming error.
+var defaultTemplates = sync.OnceValue(func() *TemplateService {
+	templates, err := NewTemplateService(embeddedTemplates)
+	if err != nil {
+		panic(err)
+	}
+	return templates
+})
+
+// Message is a rendered email
+type Message struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// emailTemplate is one email in one locale
+type emailTemplate struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+}
+
+// TemplateService renders transactional emails from templates.
+//
+// Templates live under templates/<locale>/ as three files named after the email:
+// <name>.subject and <name>.txt are text templates, and <name>.html defines a "content"
+// block that is rendered inside templates/layout.html. Templates refer to their variables by
+// name, such as {{.Name}}, and rendering fails if a variable isn't given.
+type TemplateService struct {
+	templates map[string]map[string]*emailTemplate // locale -> name -> template
+}
+
+// NewTemplateService parses the templates in fsys, which has the layout described on
+// TemplateService rooted at a "templates" directory
+func NewTemplateService(fsys fs.FS) (*TemplateService, error) {
+	layout, err := fs.ReadFile(fsys, "templates/layout.html")
+	if err != nil {
+		return nil, fmt.Errorf("error reading email layout: %w", err)
+	}
+
+	locales, err := fs.ReadDir(fsys, "templates")
+	if err != nil {
+		return nil, fmt.Errorf("error reading email templates: %w", err)
+	}
+
+	s := &TemplateService{templates: make(map[string]map[string]*emailTemplate)}
+	for _, locale := range locales {
+		if !locale.IsDir() {
+			continue
+		}
+		dir := path.Join("templates", locale.Name())
+		subjects, err := fs.Glob(fsys, path.Join(dir, "*.subject"))
+		if err != nil {
+			return nil, err
+		}
+
+		byName := make(map[string]*emailTemplate, len(subjects))
+		for _, subjectFile := range subjects {
+			name := strings.TrimSuffix(path.Base(subjectFile), ".subject")
+			tmpl, err := parseEmailTemplate(fsys, dir, name, string(layout))
+			if err != nil {
+				return nil, fmt.Errorf("error parsing email template %s/%s: %w", locale.Name(), name, err)
+			}
+			byName[name] = tmpl
+		}
+		s.templates[normalizeLocale(locale.Name())] = byName
+	}
+
+	if len(s.templates[DefaultLocale]) == 0 {
+		return nil, fmt.Errorf("no email templates for default locale %q", DefaultLocale)
+	}
+	return s, nil
+}
+
+// parseEmailTemplate parses the subject, HTML and text templates of one email
+func parseEmailTemplate(fsys fs.FS, dir, name, layout string) (*emailTemplate, error) {
+	read := func(ext string) (string, error) {
+		data, err := fs.ReadFile(fsys, path.Join(dir, name+ext))
+		return string(data), err
+	}
+
+	subject, err := read(".subject")
+	if err != nil {
+		return nil, err
+	}
+	htmlBody, err := read(".html")
+	if err != nil {
+		return nil, err
+	}
+	textBody, err := read(".txt")
+	if err != nil {
+		return nil, err
+	}
+
+	var tmpl emailTemplate
+	if tmpl.subject, err = texttemplate.New("subject").Option("missingkey=error").Parse(strings.TrimSpace(subject)); err != nil {
+		return nil, err
+	}
+	if tmpl.html, err = htmltemplate.New("layout").Option("missingkey=error").Parse(layout); err != nil {
+		return nil, err
+	}
+	if _, err = tmpl.html.Parse(htmlBody); err != nil {
+		return nil, err
+	}
+	if tmpl.text, err = texttemplate.New("text").Option("missingkey=error").Parse(textBody); err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// Render renders an email in the given locale, such as "fr" or "fr-GH". It falls back to the
+// locale's language and then to DefaultLocale when there is no template for it. Render has
+// no side effects, so it can also be used to preview emails.
+func (s *TemplateService) Render(name, locale string, data map[string]interface{}) (*Message, error) {
+	tmpl := s.lookup(name, locale)
+	if tmpl == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	var subject, htmlBody, textBody bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("error rendering %s subject: %w", name, err)
+	}
+	if err := tmpl.html.ExecuteTemplate(&htmlBody, "layout", data); err != nil {
+		return nil, fmt.Errorf("error rendering %s HTML: %w", name, err)
+	}
+	if err := tmpl.text.Execute(&textBody, data); err != nil {
+		return nil, fmt.Errorf("error rendering %s text: %w", name, err)
+	}
+
+	return &Message{
+		// Subjects go into a header, so they must stay on one line
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		HTML:    htmlBody.String(),
+		Text:    textBody.String(),
+	}, nil
+}
+
+// Names returns the names of the templates available in the default locale
+func (s *TemplateService) Names() []string {
+	names := make([]string, 0, len(s.templates[DefaultLocale]))
+	for name := range s.templates[DefaultLocale] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup finds the template for the locale, falling back to its language and the default locale
+func (s *TemplateService) lookup(name, locale string) *emailTemplate {
+	locale = normalizeLocale(locale)
+	candidates := []string{locale}
+	if language, _, ok := strings.Cut(locale, "-"); ok {
+		candidates = append(candidates, language)
+	}
+	candidates = append(candidates, DefaultLocale)
+
+	for _, candidate := range candidates {
+		if tmpl, ok := s.templates[candidate][name]; ok {
+			return tmpl
+		}
+	}
+	return nil
+}
+
+// normalizeLocale lowercases a locale and uses "-" as its separator, so "fr_GH" becomes "fr-gh"
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
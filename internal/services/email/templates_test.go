@@ -0,0 +1,116 @@
+package email
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleData holds the variables each built-in template needs
+var sampleData = map[string]map[string]interface{}{
+	TemplateVerification:  {"Name": "ama", "Link": "https://app.test/verify-email?token=abc"},
+	TemplatePasswordReset: {"Name": "ama", "Link": "https://app.test/reset-password?token=abc"},
+	TemplateNotification:  {"Name": "ama", "Title": "Payment received", "Message": "You were paid"},
+	TemplatePaymentReceipt: {
+		"Name": "ama", "Merchant": "Kente Co", "Amount": 125.5, "Currency": "GHS", "Reference": "REV-123",
+		"Date": "16 Oct 2026", "ReceiptURL": "https://app.test/receipts/REV-123",
+	},
+	TemplateWithdrawalConfirmation: {
+		"Name": "ama", "Amount": 40.0, "Currency": "GHS", "Reference": "WD-123", "Destination": "MTN ****1234",
+	},
+	TemplateKYCApproved: {"Name": "ama"},
+	TemplateKYCRejected: {"Name": "ama", "Reason": "Document expired"},
+	TemplateDataExportReady: {
+		"Name": "ama", "Link": "https://api.test/files/data-exports/export.json?signature=abc", "ExpiresAt": "23 Oct 2026",
+	},
+	TemplateNewLogin: {
+		"Name": "ama", "Device": "Chrome on Windows", "Location": "Accra, Ghana", "IPAddress": "41.66.0.1",
+		"Time": "16 Oct 2026, 09:30 UTC", "RevokeURL": "https://app.test/security/revoke-session?token=abc",
+	},
+}
+
+func TestBuiltInTemplatesRender(t *testing.T) {
+	templates := defaultTemplates()
+
+	names := templates.Names()
+	require.Len(t, names, len(sampleData))
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			data, ok := sampleData[name]
+			require.True(t, ok, "no sample data for template")
+
+			msg, err := templates.Render(name, DefaultLocale, data)
+			require.NoError(t, err)
+			assert.NotEmpty(t, msg.Subject)
+			assert.Contains(t, msg.HTML, "<h1>RevasPay</h1>")
+			assert.Contains(t, msg.HTML, "Hello ama,")
+			assert.Contains(t, msg.Text, "Hello ama,")
+		})
+	}
+
+	msg, err := templates.Render(TemplatePaymentReceipt, DefaultLocale, sampleData[TemplatePaymentReceipt])
+	require.NoError(t, err)
+	assert.Equal(t, "Your receipt from Kente Co", msg.Subject)
+	assert.Contains(t, msg.Text, "125.50 GHS")
+}
+
+func TestRenderEscapesHTMLAndKeepsSubjectOnOneLine(t *testing.T) {
+	msg, err := defaultTemplates().Render(TemplateNotification, DefaultLocale, map[string]interface{}{
+		"Name":    "<b>ama</b>",
+		"Title":   "Hello\r\nBcc: someone@example.com",
+		"Message": "<script>alert(1)</script>",
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, msg.HTML, "<script>")
+	assert.Contains(t, msg.HTML, "&lt;b&gt;ama&lt;/b&gt;")
+	assert.Equal(t, "Hello Bcc: someone@example.com", msg.Subject)
+	assert.Contains(t, msg.Text, "<script>alert(1)</script>")
+}
+
+func TestRenderRequiresVariables(t *testing.T) {
+	_, err := defaultTemplates().Render(TemplateVerification, DefaultLocale, map[string]interface{}{"Name": "ama"})
+	assert.Error(t, err)
+}
+
+func TestRenderLocaleFallback(t *testing.T) {
+	layout := `{{define "layout"}}{{template "content" .}}{{end}}`
+	templates, err := NewTemplateService(fstest.MapFS{
+		"templates/layout.html":           {Data: []byte(layout)},
+		"templates/en/welcome.subject":    {Data: []byte("Welcome {{.Name}}")},
+		"templates/en/welcome.html":       {Data: []byte(`{{define "content"}}Hello {{.Name}}{{end}}`)},
+		"templates/en/welcome.txt":        {Data: []byte("Hello {{.Name}}")},
+		"templates/en/goodbye.subject":    {Data: []byte("Goodbye")},
+		"templates/en/goodbye.html":       {Data: []byte(`{{define "content"}}Bye{{end}}`)},
+		"templates/en/goodbye.txt":        {Data: []byte("Bye")},
+		"templates/fr/welcome.subject":    {Data: []byte("Bienvenue {{.Name}}")},
+		"templates/fr/welcome.html":       {Data: []byte(`{{define "content"}}Bonjour {{.Name}}{{end}}`)},
+		"templates/fr/welcome.txt":        {Data: []byte("Bonjour {{.Name}}")},
+		"templates/fr-CA/welcome.subject": {Data: []byte("Salut {{.Name}}")},
+		"templates/fr-CA/welcome.html":    {Data: []byte(`{{define "content"}}Salut {{.Name}}{{end}}`)},
+		"templates/fr-CA/welcome.txt":     {Data: []byte("Salut {{.Name}}")},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name, locale, wantSubject string
+	}{
+		{"welcome", "fr_CA", "Salut Kofi"},
+		{"welcome", "fr-GH", "Bienvenue Kofi"},
+		{"welcome", "FR", "Bienvenue Kofi"},
+		{"welcome", "de", "Welcome Kofi"},
+		{"welcome", "", "Welcome Kofi"},
+		{"goodbye", "fr", "Goodbye"},
+	}
+	for _, tt := range tests {
+		msg, err := templates.Render(tt.name, tt.locale, map[string]interface{}{"Name": "Kofi"})
+		require.NoError(t, err, tt.locale)
+		assert.Equal(t, tt.wantSubject, msg.Subject, tt.locale)
+		assert.Equal(t, msg.Text, msg.HTML, tt.locale)
+	}
+
+	_, err = templates.Render("missing", "fr", nil)
+	assert.True(t, errors.Is(err, ErrTemplateNotFound))
+}
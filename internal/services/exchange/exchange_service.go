@@ -6,6 +6,9 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/money"
 )
 
 // ExchangeRateService provides real-time currency exchange rates
@@ -91,7 +94,8 @@ func (s *ExchangeRateService) GetExchangeRate(fromCurrency, toCurrency string) (
 	return rate, nil
 }
 
-// ConvertAmount converts an amount from one currency to another
+// ConvertAmount converts an amount from one currency to another, rounded to the target
+// currency's precision with the conversion rounding mode
 func (s *ExchangeRateService) ConvertAmount(amount float64, fromCurrency, toCurrency string) (float64, error) {
 	if fromCurrency == toCurrency {
 		return amount, nil
@@ -102,7 +106,7 @@ func (s *ExchangeRateService) ConvertAmount(amount float64, fromCurrency, toCurr
 		return 0, err
 	}
 
-	return amount * rate, nil
+	return money.Round(amount*rate, models.Currency(toCurrency), money.CurrentPolicy().Conversion), nil
 }
 
 // GetAllRates gets exchange rates for a base currency against all supported currencies
@@ -0,0 +1,225 @@
+// Package featureflags decides whether features that are still being built are switched on,
+// so they can ship dark and be enabled per environment or at runtime
+package featureflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Flag keys
+const (
+	CryptoWallets = "crypto_wallets"
+	MoMo          = "momo"
+	Referrals     = "referrals"
+)
+
+// Definition describes a feature flag
+type Definition struct {
+	Key         string `json:"key"`
+	Description string `json:"description"`
+}
+
+// Definitions lists every flag that can be toggled
+var Definitions = []Definition{
+	{Key: CryptoWallets, Description: "Custodial crypto wallets on Base"},
+	{Key: MoMo, Description: "MTN Mobile Money collections and disbursements"},
+	{Key: Referrals, Description: "Referral listings and stats"},
+}
+
+// ErrUnknownFlag is returned when toggling a flag that isn't in Definitions
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+// refreshInterval is how long runtime overrides are cached, so toggles made through another
+// instance apply here within this long
+const refreshInterval = 30 * time.Second
+
+// Flag is a flag's current state
+type Flag struct {
+	Definition
+	Enabled    bool       `json:"enabled"`
+	Default    bool       `json:"default"`    // The configured value, used without an override
+	Overridden bool       `json:"overridden"` // An admin set the flag at runtime
+	UpdatedBy  *uuid.UUID `json:"updated_by,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// Service resolves feature flags: an admin's runtime override if there is one, otherwise the
+// configured default. Flags that are neither overridden nor configured are off.
+type Service struct {
+	db          *gorm.DB
+	defaults    map[string]bool
+	auditLogger *utils.AuditLogger
+
+	mu        sync.RWMutex
+	overrides map[string]bool
+	loadedAt  time.Time
+}
+
+// NewService creates a feature flag service with the configured defaults, keyed by flag
+func NewService(db *gorm.DB, defaults map[string]bool) *Service {
+	return &Service{
+		db:          db,
+		defaults:    defaults,
+		auditLogger: utils.NewAuditLogger(db),
+	}
+}
+
+// IsEnabled reports whether a feature is switched on
+func (s *Service) IsEnabled(key string) bool {
+	return resolve(key, s.currentOverrides(), s.defaults)
+}
+
+// resolve returns a flag's override if it has one, otherwise its default
+func resolve(key string, overrides, defaults map[string]bool) bool {
+	if enabled, ok := overrides[key]; ok {
+		return enabled
+	}
+	return defaults[key]
+}
+
+// currentOverrides returns the runtime overrides, reloading them once they're older than
+// refreshInterval. If reloading fails the previous overrides are kept.
+func (s *Service) currentOverrides() map[string]bool {
+	s.mu.RLock()
+	if time.Since(s.loadedAt) < refreshInterval {
+		overrides := s.overrides
+		s.mu.RUnlock()
+		return overrides
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.loadedAt) < refreshInterval {
+		return s.overrides
+	}
+	s.loadedAt = time.Now()
+
+	var rows []models.FeatureFlag
+	if err := s.db.Find(&rows).Error; err != nil {
+		log.Printf("Failed to load feature flags, using previous values: %v", err)
+		return s.overrides
+	}
+	overrides := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		overrides[row.Key] = row.Enabled
+	}
+	s.overrides = overrides
+	return overrides
+}
+
+// List returns the state of every flag
+func (s *Service) List() ([]Flag, error) {
+	var rows []models.FeatureFlag
+	if err := s.db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("error listing feature flags: %w", err)
+	}
+	overrides := make(map[string]models.FeatureFlag, len(rows))
+	for _, row := range rows {
+		overrides[row.Key] = row
+	}
+
+	flags := make([]Flag, 0, len(Definitions))
+	for _, definition := range Definitions {
+		flag := Flag{
+			Definition: definition,
+			Default:    s.defaults[definition.Key],
+		}
+		flag.Enabled = flag.Default
+		if row, ok := overrides[definition.Key]; ok {
+			updatedAt := row.UpdatedAt
+			flag.Enabled = row.Enabled
+			flag.Overridden = true
+			flag.UpdatedBy = row.UpdatedBy
+			flag.UpdatedAt = &updatedAt
+		}
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
+
+// SetEnabled overrides a flag at runtime. Other instances pick the change up within
+// refreshInterval.
+func (s *Service) SetEnabled(ctx context.Context, adminID uuid.UUID, key string, enabled bool) error {
+	if !isKnown(key) {
+		return ErrUnknownFlag
+	}
+
+	row := models.FeatureFlag{
+		Key:       key,
+		Enabled:   enabled,
+		UpdatedBy: &adminID,
+		UpdatedAt: time.Now(),
+	}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_by", "updated_at"}),
+	}).Create(&row).Error
+	if err != nil {
+		return fmt.Errorf("error saving feature flag: %w", err)
+	}
+	s.setOverride(key, &enabled)
+
+	s.auditLogger.LogAdminAction(ctx, adminID, nil, "", "", "set_feature_flag", true, map[string]interface{}{
+		"flag":    key,
+		"enabled": enabled,
+	})
+	return nil
+}
+
+// Reset removes a flag's runtime override, so its configured default applies again
+func (s *Service) Reset(ctx context.Context, adminID uuid.UUID, key string) error {
+	if !isKnown(key) {
+		return ErrUnknownFlag
+	}
+
+	if err := s.db.Where("key = ?", key).Delete(&models.FeatureFlag{}).Error; err != nil {
+		return fmt.Errorf("error resetting feature flag: %w", err)
+	}
+	s.setOverride(key, nil)
+
+	s.auditLogger.LogAdminAction(ctx, adminID, nil, "", "", "reset_feature_flag", true, map[string]interface{}{
+		"flag":    key,
+		"enabled": s.defaults[key],
+	})
+	return nil
+}
+
+// setOverride applies a change to this instance's cached overrides straight away; a nil value
+// removes the override
+func (s *Service) setOverride(key string, enabled *bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	overrides := make(map[string]bool, len(s.overrides)+1)
+	for k, v := range s.overrides {
+		overrides[k] = v
+	}
+	if enabled == nil {
+		delete(overrides, key)
+	} else {
+		overrides[key] = *enabled
+	}
+	s.overrides = overrides
+}
+
+// isKnown reports whether key is in Definitions
+func isKnown(key string) bool {
+	for _, definition := range Definitions {
+		if definition.Key == key {
+			return true
+		}
+	}
+	return false
+}
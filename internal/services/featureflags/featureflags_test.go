@@ -0,0 +1,39 @@
+package featureflags
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	defaults := map[string]bool{MoMo: true, Referrals: false}
+	overrides := map[string]bool{MoMo: false, CryptoWallets: true}
+
+	assert.False(t, resolve(MoMo, overrides, defaults), "override beats default")
+	assert.True(t, resolve(CryptoWallets, overrides, defaults), "override without default")
+	assert.False(t, resolve(Referrals, overrides, defaults), "default without override")
+	assert.False(t, resolve("unconfigured", nil, nil), "unconfigured flags are off")
+}
+
+func TestSetOverrideUpdatesCache(t *testing.T) {
+	s := &Service{defaults: map[string]bool{MoMo: true}, loadedAt: time.Now()}
+
+	enabled := false
+	s.setOverride(MoMo, &enabled)
+	assert.False(t, s.IsEnabled(MoMo))
+
+	s.setOverride(MoMo, nil)
+	assert.True(t, s.IsEnabled(MoMo))
+}
+
+func TestDefinitionsAreUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, definition := range Definitions {
+		assert.False(t, seen[definition.Key], "duplicate flag %s", definition.Key)
+		seen[definition.Key] = true
+		assert.True(t, isKnown(definition.Key))
+	}
+	assert.False(t, isKnown("unknown"))
+}
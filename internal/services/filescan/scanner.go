@@ -0,0 +1,113 @@
+package filescan
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInfected is returned when a scanner finds malware in a file
+	ErrInfected = errors.New("file failed malware scan")
+)
+
+// Scanner checks file contents for malware. Scan returns ErrInfected (wrapped with the
+// signature name) when the file is infected, and any other error if the scan couldn't run.
+type Scanner interface {
+	Scan(data []byte) error
+}
+
+// DefaultScanner returns a ClamAV scanner when CLAMAV_ADDRESS is set, or nil when scanning is disabled
+func DefaultScanner() Scanner {
+	address := os.Getenv("CLAMAV_ADDRESS")
+	if address == "" {
+		return nil
+	}
+	return NewClamAVScanner(address)
+}
+
+// ClamAVScanner scans files with a clamd daemon over its INSTREAM protocol
+type ClamAVScanner struct {
+	address string
+	timeout time.Duration
+}
+
+// NewClamAVScanner creates a scanner for the clamd daemon at address (host:port)
+func NewClamAVScanner(address string) *ClamAVScanner {
+	return &ClamAVScanner{
+		address: address,
+		timeout: 30 * time.Second,
+	}
+}
+
+// clamAVChunkSize is the size of each INSTREAM chunk, well under clamd's StreamMaxLength
+const clamAVChunkSize = 64 * 1024
+
+// Scan streams data to clamd and reports whether it was found to be infected
+func (s *ClamAVScanner) Scan(data []byte) error {
+	conn, err := net.DialTimeout("tcp", s.address, s.timeout)
+	if err != nil {
+		return fmt.Errorf("error connecting to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return fmt.Errorf("error setting clamd deadline: %w", err)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("error starting clamd scan: %w", err)
+	}
+
+	// Each chunk is prefixed with its length; a zero length ends the stream
+	reader := bytes.NewReader(data)
+	chunk := make([]byte, clamAVChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := reader.Read(chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return fmt.Errorf("error streaming file to clamd: %w", err)
+			}
+			if _, err := conn.Write(chunk[:n]); err != nil {
+				return fmt.Errorf("error streaming file to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return fmt.Errorf("error finishing clamd scan: %w", err)
+	}
+
+	response, err := io.ReadAll(conn)
+	if err != nil {
+		return fmt.Errorf("error reading clamd response: %w", err)
+	}
+
+	return parseClamAVResponse(string(response))
+}
+
+// parseClamAVResponse interprets replies such as "stream: OK" and "stream: Eicar-Signature FOUND"
+func parseClamAVResponse(response string) error {
+	result := strings.TrimSpace(strings.TrimRight(response, "\x00"))
+	result = strings.TrimPrefix(result, "stream: ")
+
+	switch {
+	case result == "OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		return fmt.Errorf("%w: %s", ErrInfected, strings.TrimSuffix(result, " FOUND"))
+	default:
+		return fmt.Errorf("unexpected clamd response: %s", result)
+	}
+}
@@ -0,0 +1,78 @@
+package filescan
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeClamd accepts one INSTREAM scan and replies infected if the stream contains "EICAR"
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		command := make([]byte, len("zINSTREAM\x00"))
+		if _, err := io.ReadFull(conn, command); err != nil {
+			return
+		}
+
+		var received strings.Builder
+		size := make([]byte, 4)
+		for {
+			if _, err := io.ReadFull(conn, size); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size)
+			if n == 0 {
+				break
+			}
+			chunk := make([]byte, n)
+			if _, err := io.ReadFull(conn, chunk); err != nil {
+				return
+			}
+			received.Write(chunk)
+		}
+
+		if strings.Contains(received.String(), "EICAR") {
+			conn.Write([]byte("stream: Eicar-Signature FOUND\x00"))
+		} else {
+			conn.Write([]byte("stream: OK\x00"))
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestClamAVScanner(t *testing.T) {
+	if err := NewClamAVScanner(fakeClamd(t)).Scan([]byte("%PDF-1.7 clean document")); err != nil {
+		t.Errorf("expected clean file to pass, got %v", err)
+	}
+
+	err := NewClamAVScanner(fakeClamd(t)).Scan([]byte("X5O!P%@AP EICAR test file"))
+	if !errors.Is(err, ErrInfected) || !strings.Contains(err.Error(), "Eicar-Signature") {
+		t.Errorf("expected ErrInfected naming the signature, got %v", err)
+	}
+}
+
+func TestParseClamAVResponse(t *testing.T) {
+	if err := parseClamAVResponse("stream: OK\x00"); err != nil {
+		t.Errorf("expected OK, got %v", err)
+	}
+	if err := parseClamAVResponse("INSTREAM size limit exceeded. ERROR\x00"); err == nil || errors.Is(err, ErrInfected) {
+		t.Errorf("expected scan error that isn't an infection, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidIP is returned when the address cannot be parsed
+	ErrInvalidIP = errors.New("invalid IP address")
+
+	// ErrPrivateIP is returned for loopback and private addresses, which have no public location
+	ErrPrivateIP = errors.New("IP address is private")
+
+	// ErrLocationNotFound is returned when the provider has no location for the address
+	ErrLocationNotFound = errors.New("location not found")
+
+	// ErrNoProvider is returned when no geolocation database has been configured
+	ErrNoProvider = errors.New("geolocation is not configured")
+)
+
+const (
+	// DefaultCacheTTL is how long a lookup is reused before the provider is asked again
+	DefaultCacheTTL = 6 * time.Hour
+
+	// maxCacheEntries bounds the cache so a flood of distinct IPs cannot grow it without limit
+	maxCacheEntries = 10000
+)
+
+// Location is the resolved geographic location of an IP address
+type Location struct {
+	IP          string  `json:"ip"`
+	City        string  `json:"city,omitempty"`
+	Region      string  `json:"region,omitempty"`
+	Country     string  `json:"country,omitempty"`
+	CountryCode string  `json:"country_code,omitempty"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+}
+
+// Provider resolves an IP address to a location
+type Provider interface {
+	Lookup(ip net.IP) (*Location, error)
+}
+
+type cacheEntry struct {
+	location  *Location
+	err       error
+	expiresAt time.Time
+}
+
+// Service resolves IP addresses to locations, caching results so repeated
+// requests from the same address don't hit the provider every time
+type Service struct {
+	provider Provider
+	ttl      time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// NewService creates a geolocation service backed by the given provider.
+// A nil provider is allowed; every lookup then returns ErrNoProvider.
+func NewService(provider Provider, ttl time.Duration) *Service {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &Service{
+		provider: provider,
+		ttl:      ttl,
+		cache:    make(map[string]cacheEntry),
+	}
+}
+
+var (
+	defaultService     *Service
+	defaultServiceOnce sync.Once
+)
+
+// DefaultService returns the process-wide geolocation service. It reads the MaxMind
+// City database from GEOIP_DB_PATH; if that is unset or unreadable, lookups return ErrNoProvider.
+func DefaultService() *Service {
+	defaultServiceOnce.Do(func() {
+		var provider Provider
+		if path := os.Getenv("GEOIP_DB_PATH"); path != "" {
+			maxmind, err := NewMaxMindProvider(path)
+			if err != nil {
+				log.Printf("Failed to open GeoIP database %s: %v", path, err)
+			} else {
+				provider = maxmind
+			}
+		} else {
+			log.Println("GEOIP_DB_PATH is not set, session geolocation is disabled")
+		}
+		defaultService = NewService(provider, DefaultCacheTTL)
+	})
+	return defaultService
+}
+
+// Lookup resolves an IP address to a location. Results, including misses, are cached.
+func (s *Service) Lookup(ipAddress string) (*Location, error) {
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return nil, ErrInvalidIP
+	}
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return nil, ErrPrivateIP
+	}
+	if s.provider == nil {
+		return nil, ErrNoProvider
+	}
+
+	key := ip.String()
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.location, entry.err
+	}
+
+	location, err := s.provider.Lookup(ip)
+	if err != nil && !errors.Is(err, ErrLocationNotFound) {
+		// Don't cache provider failures, the next request should try again
+		return nil, fmt.Errorf("error looking up IP location: %w", err)
+	}
+	if location != nil {
+		location.IP = key
+	}
+
+	s.mu.Lock()
+	if len(s.cache) >= maxCacheEntries {
+		s.evictExpired(now)
+	}
+	s.cache[key] = cacheEntry{location: location, err: err, expiresAt: now.Add(s.ttl)}
+	s.mu.Unlock()
+
+	return location, err
+}
+
+// evictExpired drops expired entries, and clears the cache entirely if it is still full.
+// Callers must hold s.mu.
+func (s *Service) evictExpired(now time.Time) {
+	for key, entry := range s.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(s.cache, key)
+		}
+	}
+	if len(s.cache) >= maxCacheEntries {
+		s.cache = make(map[string]cacheEntry)
+	}
+}
@@ -0,0 +1,114 @@
+package geoip
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	calls     int
+	locations map[string]*Location
+	err       error
+}
+
+func (p *fakeProvider) Lookup(ip net.IP) (*Location, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	location, ok := p.locations[ip.String()]
+	if !ok {
+		return nil, ErrLocationNotFound
+	}
+	copied := *location
+	return &copied, nil
+}
+
+func TestLookupCachesResults(t *testing.T) {
+	provider := &fakeProvider{locations: map[string]*Location{
+		"41.66.1.1": {City: "Accra", Region: "Greater Accra", Country: "Ghana", CountryCode: "GH"},
+	}}
+	svc := NewService(provider, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		location, err := svc.Lookup("41.66.1.1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if location.City != "Accra" || location.IP != "41.66.1.1" {
+			t.Fatalf("unexpected location: %+v", location)
+		}
+	}
+	if provider.calls != 1 {
+		t.Errorf("expected 1 provider call, got %d", provider.calls)
+	}
+
+	// Misses are cached too
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Lookup("8.8.8.8"); !errors.Is(err, ErrLocationNotFound) {
+			t.Fatalf("expected ErrLocationNotFound, got %v", err)
+		}
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected 2 provider calls, got %d", provider.calls)
+	}
+}
+
+func TestLookupExpiresEntries(t *testing.T) {
+	provider := &fakeProvider{locations: map[string]*Location{
+		"41.66.1.1": {CountryCode: "GH"},
+	}}
+	svc := NewService(provider, time.Millisecond)
+
+	if _, err := svc.Lookup("41.66.1.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := svc.Lookup("41.66.1.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected expired entry to be looked up again, got %d calls", provider.calls)
+	}
+}
+
+func TestLookupDoesNotCacheProviderErrors(t *testing.T) {
+	provider := &fakeProvider{err: errors.New("database unavailable")}
+	svc := NewService(provider, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Lookup("41.66.1.1"); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected provider errors not to be cached, got %d calls", provider.calls)
+	}
+}
+
+func TestLookupSkipsUnroutableAddresses(t *testing.T) {
+	provider := &fakeProvider{}
+	svc := NewService(provider, time.Hour)
+
+	tests := map[string]error{
+		"not-an-ip":   ErrInvalidIP,
+		"127.0.0.1":   ErrPrivateIP,
+		"10.0.0.5":    ErrPrivateIP,
+		"192.168.1.1": ErrPrivateIP,
+		"::1":         ErrPrivateIP,
+	}
+	for ip, want := range tests {
+		if _, err := svc.Lookup(ip); !errors.Is(err, want) {
+			t.Errorf("Lookup(%q) error = %v, want %v", ip, err, want)
+		}
+	}
+	if provider.calls != 0 {
+		t.Errorf("expected no provider calls, got %d", provider.calls)
+	}
+
+	if _, err := NewService(nil, time.Hour).Lookup("41.66.1.1"); !errors.Is(err, ErrNoProvider) {
+		t.Errorf("expected ErrNoProvider, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// MaxMindProvider resolves locations from a local MaxMind GeoIP2/GeoLite2 City database
+type MaxMindProvider struct {
+	reader *geoip2.Reader
+}
+
+// NewMaxMindProvider opens the MaxMind City database at path
+func NewMaxMindProvider(path string) (*MaxMindProvider, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening GeoIP database: %w", err)
+	}
+	return &MaxMindProvider{reader: reader}, nil
+}
+
+// Lookup resolves an IP address using the City database
+func (p *MaxMindProvider) Lookup(ip net.IP) (*Location, error) {
+	record, err := p.reader.City(ip)
+	if err != nil {
+		return nil, err
+	}
+	if record.Country.IsoCode == "" {
+		return nil, ErrLocationNotFound
+	}
+
+	location := &Location{
+		City:        record.City.Names["en"],
+		Country:     record.Country.Names["en"],
+		CountryCode: record.Country.IsoCode,
+		Latitude:    record.Location.Latitude,
+		Longitude:   record.Location.Longitude,
+	}
+	if len(record.Subdivisions) > 0 {
+		location.Region = record.Subdivisions[0].Names["en"]
+	}
+
+	return location, nil
+}
+
+// Close releases the database
+func (p *MaxMindProvider) Close() error {
+	return p.reader.Close()
+}
@@ -2,23 +2,30 @@ package kyc
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/storage"
+	"github.com/revaspay/backend/internal/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // DiditService handles integration with the Didit API for KYC verification
 type DiditService struct {
-	db            *gorm.DB
+	verificationStore
 	apiKey        string
 	apiBaseURL    string
 	webhookSecret string
@@ -34,6 +41,7 @@ type DiditSessionResponse struct {
 
 // DiditWebhookPayload represents the payload received from Didit webhooks
 type DiditWebhookPayload struct {
+	EventID      string                 `json:"event_id"`
 	EventType    string                 `json:"event_type"`
 	SessionID    string                 `json:"session_id"`
 	Status       string                 `json:"status"`
@@ -59,8 +67,8 @@ type DiditErrorDetails struct {
 	Message string `json:"message"`
 }
 
-// NewDiditService creates a new instance of DiditService
-func NewDiditService(db *gorm.DB) (*DiditService, error) {
+// NewDiditService creates a new instance of DiditService that keeps uploaded documents in store
+func NewDiditService(db *gorm.DB, store storage.Backend) (*DiditService, error) {
 	apiKey := os.Getenv("DIDIT_API_KEY")
 	if apiKey == "" {
 		return nil, errors.New("DIDIT_API_KEY environment variable is not set")
@@ -77,7 +85,7 @@ func NewDiditService(db *gorm.DB) (*DiditService, error) {
 	}
 
 	return &DiditService{
-		db:            db,
+		verificationStore: verificationStore{db: db, storage: store, provider: models.KYCProviderDidit},
 		apiKey:        apiKey,
 		apiBaseURL:    "https://api.didit.me/v2",
 		webhookSecret: webhookSecret,
@@ -85,14 +93,31 @@ func NewDiditService(db *gorm.DB) (*DiditService, error) {
 	}, nil
 }
 
-// CreateVerificationSession creates a new KYC verification session for a user
-func (s *DiditService) CreateVerificationSession(userID uuid.UUID) (*models.KYCVerification, error) {
+// Name returns the provider identifier
+func (s *DiditService) Name() string {
+	return models.KYCProviderDidit
+}
+
+// CreateSession starts a hosted Didit verification. Didit collects the user's details
+// itself, so the request is not used.
+func (s *DiditService) CreateSession(ctx context.Context, userID uuid.UUID, req SessionRequest) (*models.KYCVerification, error) {
+	return s.CreateVerificationSession(ctx, userID)
+}
+
+// CreateVerificationSession creates a new KYC verification session for a user. When the user
+// already has a pending session, that session is returned instead, so retried requests don't
+// start another; ErrVerificationActive is returned when their verification is further along.
+func (s *DiditService) CreateVerificationSession(ctx context.Context, userID uuid.UUID) (*models.KYCVerification, error) {
 	// Check if user exists
 	var user models.User
 	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
+	if existing, err := s.pendingSession(userID); err != nil || existing != nil {
+		return existing, err
+	}
+
 	// Create payload for Didit API
 	payload := map[string]interface{}{
 		"workflow_id": s.workflowID,
@@ -108,7 +133,9 @@ func (s *DiditService) CreateVerificationSession(userID uuid.UUID) (*models.KYCV
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequest("POST", s.apiBaseURL+"/session/", bytes.NewBuffer(jsonPayload))
+	ctx, cancel := utils.ProviderContext(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", s.apiBaseURL+"/session/", bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -118,8 +145,7 @@ func (s *DiditService) CreateVerificationSession(userID uuid.UUID) (*models.KYCV
 	req.Header.Set("Authorization", "Bearer "+s.apiKey)
 
 	// Send request
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -151,67 +177,118 @@ func (s *DiditService) CreateVerificationSession(userID uuid.UUID) (*models.KYCV
 		VerificationURL: sessionResp.URL,
 	}
 
-	// Save to database
-	if err := s.db.Create(verification).Error; err != nil {
-		return nil, fmt.Errorf("failed to create verification record: %w", err)
+	// Save to database. If a concurrent request saved its session first, the user is given
+	// that one and the session just created is left unused.
+	if err := s.createVerification(verification); err != nil {
+		if errors.Is(err, ErrVerificationActive) {
+			if existing, err := s.pendingSession(userID); err != nil || existing != nil {
+				return existing, err
+			}
+		}
+		return nil, err
 	}
 
 	return verification, nil
 }
 
-// UploadDocument uploads a document for KYC verification
-func (s *DiditService) UploadDocument(verificationID uuid.UUID, docType models.DocumentType, filePath string) (*models.KYCDocument, error) {
-	// Check if verification exists
-	var verification models.KYCVerification
-	if err := s.db.First(&verification, "id = ?", verificationID).Error; err != nil {
-		return nil, fmt.Errorf("verification not found: %w", err)
-	}
-
-	// Get file info
-	fileInfo, err := os.Stat(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file info: %w", err)
+// pendingSession returns the user's pending Didit session, or nil if they have no active
+// verification. It returns ErrVerificationActive when their active verification can't be
+// resumed: it's in progress, approved or with another provider.
+func (s *DiditService) pendingSession(userID uuid.UUID) (*models.KYCVerification, error) {
+	existing, err := GetActiveVerification(s.db, userID)
+	if err != nil || existing == nil {
+		return nil, err
 	}
-
-	// Create document record
-	document := &models.KYCDocument{
-		VerificationID: verificationID,
-		Type:           docType,
-		FilePath:       filePath,
-		FileName:       filepath.Base(filePath),
-		FileSize:       fileInfo.Size(),
-		UploadedAt:     time.Now(),
+	if existing.Status != models.KYCStatusPending || existing.Provider != s.provider || existing.VerificationURL == "" {
+		return nil, ErrVerificationActive
 	}
+	return existing, nil
+}
 
-	// Save to database
-	if err := s.db.Create(document).Error; err != nil {
-		return nil, fmt.Errorf("failed to create document record: %w", err)
+// Submit returns the Didit session ID. The user completes the hosted session themselves,
+// so there is nothing further to send.
+func (s *DiditService) Submit(_ context.Context, verificationID uuid.UUID) (string, error) {
+	var verification models.KYCVerification
+	if err := s.db.First(&verification, "id = ?", verificationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrVerificationNotFound
+		}
+		return "", fmt.Errorf("error getting verification: %w", err)
 	}
-
-	return document, nil
+	return verification.SessionID, nil
 }
 
-// ProcessWebhook processes webhook notifications from Didit
+// ProcessWebhook applies a Didit webhook. It returns ErrInvalidWebhookSignature when the
+// signature doesn't match, ErrInvalidWebhookPayload when the body can't be parsed and
+// ErrVerificationNotFound for unknown sessions; any other error is transient and the
+// delivery should be retried. Each event is applied once: redeliveries return nil without
+// changing the verification again.
 func (s *DiditService) ProcessWebhook(payload []byte, signature string) error {
-	// Verify webhook signature
-	// Note: Implement signature verification based on Didit's documentation
+	if !s.verifySignature(payload, signature) {
+		return ErrInvalidWebhookSignature
+	}
 
-	// Parse webhook payload
 	var webhookPayload DiditWebhookPayload
 	if err := json.Unmarshal(payload, &webhookPayload); err != nil {
-		return fmt.Errorf("failed to unmarshal webhook payload: %w", err)
+		return fmt.Errorf("%w: %v", ErrInvalidWebhookPayload, err)
+	}
+	if webhookPayload.SessionID == "" {
+		return fmt.Errorf("%w: missing session_id", ErrInvalidWebhookPayload)
 	}
 
-	// Find the verification by session ID
-	var verification models.KYCVerification
-	if err := s.db.First(&verification, "session_id = ?", webhookPayload.SessionID).Error; err != nil {
-		return fmt.Errorf("verification not found for session ID %s: %w", webhookPayload.SessionID, err)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the verification so concurrent deliveries for the session apply one at a time
+		var verification models.KYCVerification
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&verification, "session_id = ? AND provider = ?", webhookPayload.SessionID, s.provider).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrVerificationNotFound
+			}
+			return fmt.Errorf("error getting verification: %w", err)
+		}
+
+		event := models.KYCWebhookEvent{
+			Provider:       s.provider,
+			EventKey:       webhookPayload.eventKey(),
+			VerificationID: verification.ID,
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+		if result.Error != nil {
+			return fmt.Errorf("error recording webhook event: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil // Already applied
+		}
+
+		previousStatus := verification.Status
+		if !applyDiditWebhook(&verification, &webhookPayload) {
+			return nil
+		}
+
+		notes := fmt.Sprintf("Didit webhook %s: %s", webhookPayload.EventType, webhookPayload.Status)
+		return saveStatusChange(tx, &verification, previousStatus, nil, notes)
+	})
+}
+
+// eventKey identifies a webhook event across redeliveries. Didit's event ID is used when
+// present; otherwise the session, status and event time together identify it.
+func (p *DiditWebhookPayload) eventKey() string {
+	if p.EventID != "" {
+		return p.EventID
 	}
+	return fmt.Sprintf("%s:%s:%s:%d", p.SessionID, p.EventType, p.Status, p.Timestamp.UnixNano())
+}
 
-	// Record previous status for history
-	previousStatus := verification.Status
+// applyDiditWebhook updates a verification from a webhook and reports whether anything should
+// be saved. Unknown statuses, and in-progress updates arriving after the verification has
+// already finished, are ignored.
+func applyDiditWebhook(verification *models.KYCVerification, webhookPayload *DiditWebhookPayload) bool {
+	// A verification that timed out waiting has to be resubmitted; a late result doesn't revive it
+	if verification.Status == models.KYCStatusTimedOut {
+		return false
+	}
 
-	// Update verification status based on webhook event
 	switch webhookPayload.Status {
 	case "completed":
 		verification.Status = models.KYCStatusApproved
@@ -270,32 +347,99 @@ func (s *DiditService) ProcessWebhook(payload []byte, signature string) error {
 		verification.Status = models.KYCStatusExpired
 		
 	case "in_progress":
+		// A late progress update mustn't reopen a finished verification
+		if isFinalStatus(verification.Status) {
+			return false
+		}
 		verification.Status = models.KYCStatusInProgress
 		
 	default:
 		// Keep status as is for unknown webhook events
+		return false
 	}
+	return true
+}
 
-	// Update verification record
-	if err := s.db.Save(&verification).Error; err != nil {
-		return fmt.Errorf("failed to update verification: %w", err)
+// PollStatus fetches the decision for a verification's Didit session and applies it as a
+// webhook with the same status would be, in case Didit's webhook never arrives
+func (s *DiditService) PollStatus(ctx context.Context, verification *models.KYCVerification) (bool, error) {
+	if verification.SessionID == "" {
+		return false, errors.New("verification has no Didit session")
 	}
 
-	// Create history record if status changed
-	if previousStatus != verification.Status {
-		history := models.KYCVerificationHistory{
-			VerificationID: verification.ID,
-			PreviousStatus: previousStatus,
-			NewStatus:      verification.Status,
-			CreatedAt:      time.Now(),
-		}
-		
-		if err := s.db.Create(&history).Error; err != nil {
-			return fmt.Errorf("failed to create history record: %w", err)
-		}
+	ctx, cancel := utils.ProviderContext(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.apiBaseURL+"/session/"+verification.SessionID+"/decision/", nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var decision DiditWebhookPayload
+	if err := json.Unmarshal(body, &decision); err != nil {
+		return false, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	decision.Status = diditDecisionStatus(decision.Status)
+	if decision.Status == "in_progress" {
+		return false, nil
+	}
+
+	notes := fmt.Sprintf("Didit session decision: %s", decision.Status)
+	var finished bool
+	err = applyProviderResult(s.db, verification.ID, notes, func(verification *models.KYCVerification) bool {
+		changed := applyDiditWebhook(verification, &decision)
+		finished = isFinalStatus(verification.Status)
+		return changed
+	})
+	return finished, err
+}
+
+// diditDecisionStatus maps the status of a Didit session decision to the matching webhook status
+func diditDecisionStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "approved", "completed":
+		return "completed"
+	case "declined", "rejected":
+		return "rejected"
+	case "expired", "abandoned":
+		return "expired"
+	}
+	return "in_progress"
+}
+
+// isFinalStatus reports whether a verification has finished
+func isFinalStatus(status models.KYCStatus) bool {
+	switch status {
+	case models.KYCStatusApproved, models.KYCStatusRejected, models.KYCStatusExpired, models.KYCStatusTimedOut:
+		return true
+	}
+	return false
+}
+
+// verifySignature checks a Didit signature: hex(HMAC-SHA256(webhook secret, raw body))
+func (s *DiditService) verifySignature(payload []byte, signature string) bool {
+	if s.webhookSecret == "" || signature == "" {
+		return false
 	}
 
-	return nil
+	mac := hmac.New(sha256.New, []byte(s.webhookSecret))
+	mac.Write(payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
 }
 
 // GetVerificationStatus retrieves the current status of a verification
@@ -0,0 +1,86 @@
+package kyc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+)
+
+func TestCreateVerificationSessionIsIdempotent(t *testing.T) {
+	db := testutil.NewDB(t)
+
+	// concurrent is called before Didit responds, standing in for another request saving its
+	// session first
+	var concurrent func(userID uuid.UUID)
+	sessions := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessions++
+		var payload struct {
+			Metadata map[string]string `json:"metadata"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if concurrent != nil {
+			concurrent(uuid.MustParse(payload.Metadata["user_id"]))
+		}
+		json.NewEncoder(w).Encode(DiditSessionResponse{SessionID: uuid.NewString(), URL: "https://verify.didit.me/new"})
+	}))
+	t.Cleanup(server.Close)
+
+	service := &DiditService{
+		verificationStore: verificationStore{db: db, provider: models.KYCProviderDidit},
+		apiBaseURL:        server.URL,
+	}
+	ctx := context.Background()
+
+	// A retry gets the pending session back without starting another
+	user := testutil.CreateUser(t, db)
+	first, err := service.CreateVerificationSession(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("CreateVerificationSession: %v", err)
+	}
+	retry, err := service.CreateVerificationSession(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("retried CreateVerificationSession: %v", err)
+	}
+	if retry.ID != first.ID || retry.VerificationURL != first.VerificationURL || sessions != 1 {
+		t.Errorf("retry got verification %s (%d sessions), want %s from one session", retry.ID, sessions, first.ID)
+	}
+
+	// When a concurrent request saves first, its session is returned
+	raced := testutil.CreateUser(t, db)
+	winner := &models.KYCVerification{ID: uuid.New(), UserID: raced.ID, Provider: models.KYCProviderDidit, Status: models.KYCStatusPending, VerificationURL: "https://verify.didit.me/winner"}
+	concurrent = func(userID uuid.UUID) {
+		if err := db.Create(winner).Error; err != nil {
+			t.Errorf("failed to create concurrent verification: %v", err)
+		}
+	}
+	got, err := service.CreateVerificationSession(ctx, raced.ID)
+	if err != nil {
+		t.Fatalf("CreateVerificationSession after losing the race: %v", err)
+	}
+	if got.ID != winner.ID || got.VerificationURL != winner.VerificationURL {
+		t.Errorf("got verification %s at %q, want the concurrent one %s", got.ID, got.VerificationURL, winner.ID)
+	}
+	var active int64
+	db.Model(&models.KYCVerification{}).Where("user_id = ?", raced.ID).Count(&active)
+	if active != 1 {
+		t.Errorf("user has %d verifications, want 1", active)
+	}
+	concurrent = nil
+
+	// Verifications that are further along still conflict
+	submitted := testutil.CreateUser(t, db)
+	if err := db.Create(&models.KYCVerification{ID: uuid.New(), UserID: submitted.ID, Provider: models.KYCProviderDidit, Status: models.KYCStatusInProgress}).Error; err != nil {
+		t.Fatalf("failed to create verification: %v", err)
+	}
+	if _, err := service.CreateVerificationSession(ctx, submitted.ID); !errors.Is(err, ErrVerificationActive) {
+		t.Errorf("expected ErrVerificationActive, got %v", err)
+	}
+}
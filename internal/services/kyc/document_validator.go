@@ -0,0 +1,159 @@
+package kyc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/revaspay/backend/internal/services/filescan"
+	"github.com/revaspay/backend/internal/services/storage"
+)
+
+// DefaultMaxDocumentSize is the largest KYC document accepted when KYC_MAX_DOCUMENT_MB isn't set
+const DefaultMaxDocumentSize = 5 << 20
+
+var (
+	// ErrDocumentTooLarge is returned when a document exceeds the per-file size limit
+	ErrDocumentTooLarge = errors.New("document is too large")
+
+	// ErrUnsupportedDocument is returned when a document isn't a JPEG, PNG or PDF
+	ErrUnsupportedDocument = errors.New("document must be a JPEG, PNG or PDF file")
+
+	// ErrDocumentInfected is returned when a document fails the malware scan
+	ErrDocumentInfected = errors.New("document failed malware scan")
+)
+
+// documentExtensions maps the accepted content types to the extension files are stored with
+var documentExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+// DocumentValidator checks uploaded KYC documents before they are stored. The type is
+// taken from the file's contents rather than its name, images are re-encoded to strip
+// EXIF and other metadata, and files are malware-scanned when a scanner is configured.
+type DocumentValidator struct {
+	maxSize int64
+	scanner filescan.Scanner
+}
+
+// NewDocumentValidator creates a validator. A nil scanner disables malware scanning.
+func NewDocumentValidator(maxSize int64, scanner filescan.Scanner) *DocumentValidator {
+	return &DocumentValidator{
+		maxSize: maxSize,
+		scanner: scanner,
+	}
+}
+
+// DefaultDocumentValidator creates a validator from KYC_MAX_DOCUMENT_MB and CLAMAV_ADDRESS
+func DefaultDocumentValidator() *DocumentValidator {
+	maxSize := int64(DefaultMaxDocumentSize)
+	if value := os.Getenv("KYC_MAX_DOCUMENT_MB"); value != "" {
+		if mb, err := strconv.Atoi(value); err == nil && mb > 0 {
+			maxSize = int64(mb) << 20
+		}
+	}
+
+	scanner := filescan.DefaultScanner()
+	if scanner == nil {
+		log.Printf("CLAMAV_ADDRESS not set, KYC documents will not be malware scanned")
+	}
+
+	return NewDocumentValidator(maxSize, scanner)
+}
+
+// Validate reads a document, checks its size and type, strips image metadata and scans it.
+// It returns the cleaned contents, their content type and the extension to store them with.
+func (v *DocumentValidator) Validate(r io.Reader) ([]byte, string, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, v.maxSize+1))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("error reading document: %w", err)
+	}
+	if int64(len(data)) > v.maxSize {
+		return nil, "", "", ErrDocumentTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	extension, ok := documentExtensions[contentType]
+	if !ok {
+		return nil, "", "", ErrUnsupportedDocument
+	}
+
+	if contentType != "application/pdf" {
+		if data, err = stripImageMetadata(data, contentType); err != nil {
+			return nil, "", "", err
+		}
+	}
+
+	if v.scanner != nil {
+		if err := v.scanner.Scan(data); err != nil {
+			if errors.Is(err, filescan.ErrInfected) {
+				return nil, "", "", fmt.Errorf("%w: %v", ErrDocumentInfected, err)
+			}
+			return nil, "", "", fmt.Errorf("error scanning document: %w", err)
+		}
+	}
+
+	return data, contentType, extension, nil
+}
+
+// StoredDocument describes a validated document written to the storage backend
+type StoredDocument struct {
+	Key         string
+	FileName    string
+	Size        int64
+	ContentType string
+}
+
+// Store validates a document and writes it to store under key plus the extension for its
+// type. Nothing is stored if validation fails.
+func (v *DocumentValidator) Store(ctx context.Context, store storage.Backend, r io.Reader, key string) (*StoredDocument, error) {
+	data, contentType, extension, err := v.Validate(r)
+	if err != nil {
+		return nil, err
+	}
+
+	key += extension
+	if err := store.Put(ctx, key, data, contentType); err != nil {
+		return nil, fmt.Errorf("error saving document: %w", err)
+	}
+
+	return &StoredDocument{
+		Key:         key,
+		FileName:    path.Base(key),
+		Size:        int64(len(data)),
+		ContentType: contentType,
+	}, nil
+}
+
+// stripImageMetadata re-encodes an image, which drops EXIF data such as location and device details
+func stripImageMetadata(data []byte, contentType string) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedDocument
+	}
+
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/png":
+		err = png.Encode(&buf, img)
+	default:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 92})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error re-encoding image: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,110 @@
+package kyc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/revaspay/backend/internal/services/filescan"
+	"github.com/revaspay/backend/internal/services/storage"
+)
+
+type fakeScanner struct {
+	err error
+}
+
+func (s *fakeScanner) Scan(data []byte) error {
+	return s.err
+}
+
+func testJPEG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+
+	// Insert an APP1 (EXIF) segment after the SOI marker
+	exif := append([]byte{0xFF, 0xE1, 0x00, 0x10}, []byte("Exif\x00\x00GPSDATA!")...)
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), exif...), data[2:]...)
+}
+
+func TestDocumentValidatorAcceptsImagesAndStripsMetadata(t *testing.T) {
+	validator := NewDocumentValidator(1<<20, &fakeScanner{})
+	original := testJPEG(t)
+
+	data, contentType, extension, err := validator.Validate(bytes.NewReader(original))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if contentType != "image/jpeg" || extension != ".jpg" {
+		t.Errorf("unexpected type %s %s", contentType, extension)
+	}
+	if bytes.Contains(data, []byte("GPSDATA")) {
+		t.Error("expected EXIF data to be stripped")
+	}
+}
+
+func TestDocumentValidatorRejectsBadDocuments(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		maxSize  int64
+		scanner  filescan.Scanner
+		expected error
+	}{
+		{"disguised executable", []byte("MZ\x90\x00 this is not an image"), 1 << 20, nil, ErrUnsupportedDocument},
+		{"truncated image", testJPEG(t)[:40], 1 << 20, nil, ErrUnsupportedDocument},
+		{"too large", bytes.Repeat([]byte("%PDF-1.7 "), 20), 64, nil, ErrDocumentTooLarge},
+		{"infected", []byte("%PDF-1.7\n%EICAR"), 1 << 20, &fakeScanner{err: fmt.Errorf("%w: Eicar-Signature", filescan.ErrInfected)}, ErrDocumentInfected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewDocumentValidator(tt.maxSize, tt.scanner)
+			if _, _, _, err := validator.Validate(bytes.NewReader(tt.data)); !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestDocumentValidatorStoreLeavesNothingOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.NewLocalBackend(storage.LocalConfig{Root: dir})
+	if err != nil {
+		t.Fatalf("failed to create storage backend: %v", err)
+	}
+	validator := NewDocumentValidator(1<<20, nil)
+
+	if _, err := validator.Store(context.Background(), store, bytes.NewReader([]byte("not a document")), "kyc/user/id_front"); !errors.Is(err, ErrUnsupportedDocument) {
+		t.Fatalf("expected ErrUnsupportedDocument, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "kyc")); !os.IsNotExist(err) {
+		t.Error("expected nothing to be stored for a rejected document")
+	}
+
+	stored, err := validator.Store(context.Background(), store, bytes.NewReader([]byte("%PDF-1.7\n")), "kyc/user/id_front")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored.Key != "kyc/user/id_front.pdf" || stored.FileName != "id_front.pdf" || stored.ContentType != "application/pdf" {
+		t.Errorf("unexpected stored document %+v", stored)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(dir, "kyc", "user"))
+	if len(entries) != 1 {
+		t.Errorf("expected only the stored document in the directory, found %d files", len(entries))
+	}
+}
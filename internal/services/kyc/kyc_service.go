@@ -56,14 +56,14 @@ func (s *KYCService) SubmitKYCVerification(userID uuid.UUID, documentType string
 	for _, file := range documentFiles {
 		documents = append(documents, models.KYCDocument{
 			Type:     models.DocumentTypeID,
-			FilePath: file,
+			StorageKey: file,
 		})
 	}
 
 	// Add selfie document
 	documents = append(documents, models.KYCDocument{
 		Type:     models.DocumentTypeSelfie,
-		FilePath: selfieFile,
+		StorageKey: selfieFile,
 	})
 
 	// Start transaction
@@ -0,0 +1,169 @@
+package kyc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Defaults for polling providers when a PollSchedule leaves them unset
+const (
+	DefaultPollInterval    = 5 * time.Minute
+	DefaultPollMaxDuration = 48 * time.Hour
+)
+
+// maxPollBackoff caps how far apart polls of one verification get, as a multiple of the interval
+const maxPollBackoff = 16
+
+// pollBatchSize bounds how many verifications one PollDue call works through
+const pollBatchSize = 100
+
+// StatusPoller is implemented by providers that can be asked for a verification's result, so
+// it isn't left waiting on a webhook that never arrives
+type StatusPoller interface {
+	// PollStatus fetches a submitted verification's result from the provider and applies it,
+	// reporting whether the provider has given its result, so polling can stop. Calls to the
+	// provider stop at ctx's deadline.
+	PollStatus(ctx context.Context, verification *models.KYCVerification) (bool, error)
+}
+
+// PollSchedule says how often submitted verifications are polled and how long to wait for a
+// result before giving up
+type PollSchedule struct {
+	Interval    time.Duration // Between the first polls, doubling after each one
+	MaxDuration time.Duration // After submission, when a verification without a result times out
+}
+
+// backoff is how long to wait after a verification's nth poll before the next
+func (s PollSchedule) backoff(attempts int) time.Duration {
+	multiplier := 1
+	for i := 1; i < attempts && multiplier < maxPollBackoff; i++ {
+		multiplier *= 2
+	}
+	return s.Interval * time.Duration(multiplier)
+}
+
+// Poller asks providers for the results of verifications still in progress, and times out the
+// ones that never get one
+type Poller struct {
+	db        *gorm.DB
+	schedule  PollSchedule
+	providers map[string]Provider
+}
+
+// NewPoller creates a poller for the providers' verifications
+func NewPoller(db *gorm.DB, schedule PollSchedule, providers ...Provider) *Poller {
+	if schedule.Interval <= 0 {
+		schedule.Interval = DefaultPollInterval
+	}
+	if schedule.MaxDuration <= 0 {
+		schedule.MaxDuration = DefaultPollMaxDuration
+	}
+
+	byName := make(map[string]Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+	return &Poller{db: db, schedule: schedule, providers: byName}
+}
+
+// Interval returns how often the poller should run
+func (p *Poller) Interval() time.Duration {
+	return p.schedule.Interval
+}
+
+// PollDue polls the provider of each in-progress verification due a poll, and times out those
+// submitted longer than the schedule's maximum duration ago. A failed poll is logged and tried
+// again at the next backoff. Verifications the provider has given a result for, including ones
+// left for an admin to review, aren't polled again. It returns how many verifications were
+// polled and timed out.
+func (p *Poller) PollDue(ctx context.Context, now time.Time) (int, int, error) {
+	var verifications []models.KYCVerification
+	if err := p.db.Where("status = ? AND next_poll_at <= ?", models.KYCStatusInProgress, now).
+		Order("next_poll_at").
+		Limit(pollBatchSize).
+		Find(&verifications).Error; err != nil {
+		return 0, 0, fmt.Errorf("error finding verifications to poll: %w", err)
+	}
+
+	polled, timedOut := 0, 0
+	for i := range verifications {
+		if err := ctx.Err(); err != nil {
+			return polled, timedOut, err
+		}
+		verification := &verifications[i]
+
+		submittedAt := verification.UpdatedAt
+		if verification.SubmittedAt != nil {
+			submittedAt = *verification.SubmittedAt
+		}
+		if now.Sub(submittedAt) >= p.schedule.MaxDuration {
+			if err := p.timeOut(verification.ID); err != nil {
+				log.Printf("Failed to time out KYC verification %s: %v", verification.ID, err)
+				continue
+			}
+			timedOut++
+			continue
+		}
+
+		if poller, ok := p.providers[verification.Provider].(StatusPoller); ok {
+			polled++
+			finished, err := poller.PollStatus(ctx, verification)
+			if err != nil {
+				log.Printf("Failed to poll %s for KYC verification %s: %v", verification.Provider, verification.ID, err)
+			} else if finished {
+				continue
+			}
+		}
+
+		attempts := verification.PollAttempts + 1
+		if err := p.db.Model(&models.KYCVerification{}).Where("id = ?", verification.ID).Updates(map[string]interface{}{
+			"poll_attempts": attempts,
+			"next_poll_at":  now.Add(p.schedule.backoff(attempts)),
+		}).Error; err != nil {
+			return polled, timedOut, fmt.Errorf("error scheduling next poll: %w", err)
+		}
+	}
+	return polled, timedOut, nil
+}
+
+// timeOut marks an in-progress verification timed out, so the user must resubmit
+func (p *Poller) timeOut(verificationID uuid.UUID) error {
+	notes := fmt.Sprintf("No result from the provider within %s", p.schedule.MaxDuration)
+	return applyProviderResult(p.db, verificationID, notes, func(verification *models.KYCVerification) bool {
+		verification.Status = models.KYCStatusTimedOut
+		return true
+	})
+}
+
+// applyProviderResult applies a result fetched from a provider to a verification still in
+// progress, saving it if apply reports a change. The verification is locked so a webhook
+// arriving at the same time is applied before or after it, not mixed in.
+func applyProviderResult(db *gorm.DB, verificationID uuid.UUID, notes string, apply func(*models.KYCVerification) bool) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var verification models.KYCVerification
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&verification, "id = ?", verificationID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVerificationNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("error getting verification: %w", err)
+		}
+		if verification.Status != models.KYCStatusInProgress {
+			return nil // Settled since it was loaded
+		}
+
+		previousStatus := verification.Status
+		if !apply(&verification) {
+			return nil
+		}
+		return saveStatusChange(tx, &verification, previousStatus, nil, notes)
+	})
+}
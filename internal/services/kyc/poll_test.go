@@ -0,0 +1,123 @@
+package kyc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// fakePollingProvider is a provider whose verifications are approved once their ID is in approve
+type fakePollingProvider struct {
+	Provider
+	db      *gorm.DB
+	approve map[uuid.UUID]bool
+	polls   int
+}
+
+func (p *fakePollingProvider) Name() string { return "fake" }
+
+func (p *fakePollingProvider) PollStatus(_ context.Context, verification *models.KYCVerification) (bool, error) {
+	p.polls++
+	if !p.approve[verification.ID] {
+		return false, nil
+	}
+	err := applyProviderResult(p.db, verification.ID, "approved", func(verification *models.KYCVerification) bool {
+		verification.Status = models.KYCStatusApproved
+		return true
+	})
+	return err == nil, err
+}
+
+func createSubmittedVerification(t *testing.T, db *gorm.DB, submittedAt time.Time) *models.KYCVerification {
+	t.Helper()
+
+	verification := &models.KYCVerification{ID: uuid.New(), UserID: uuid.New(), Provider: "fake", Status: models.KYCStatusPending}
+	if err := db.Create(verification).Error; err != nil {
+		t.Fatalf("failed to create verification: %v", err)
+	}
+	if err := RecordSubmission(db, verification, "job-1"); err != nil {
+		t.Fatalf("failed to record submission: %v", err)
+	}
+	if err := db.Model(verification).Updates(map[string]interface{}{"submitted_at": submittedAt, "next_poll_at": submittedAt}).Error; err != nil {
+		t.Fatalf("failed to backdate submission: %v", err)
+	}
+	return verification
+}
+
+func TestPollScheduleBackoff(t *testing.T) {
+	schedule := PollSchedule{Interval: time.Minute}
+	tests := map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 3: 4 * time.Minute, 5: 16 * time.Minute, 20: 16 * time.Minute}
+	for attempts, want := range tests {
+		if got := schedule.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestPollerPollsWithBackoffAndTimesOut(t *testing.T) {
+	db := testutil.NewDB(t)
+	now := time.Now()
+	provider := &fakePollingProvider{db: db, approve: map[uuid.UUID]bool{}}
+	poller := NewPoller(db, PollSchedule{Interval: time.Minute, MaxDuration: time.Hour}, provider)
+
+	waiting := createSubmittedVerification(t, db, now.Add(-10*time.Minute))
+	approved := createSubmittedVerification(t, db, now.Add(-10*time.Minute))
+	stale := createSubmittedVerification(t, db, now.Add(-2*time.Hour))
+	provider.approve[approved.ID] = true
+
+	polled, timedOut, err := poller.PollDue(context.Background(), now)
+	if err != nil || polled != 2 || timedOut != 1 {
+		t.Fatalf("PollDue = %d, %d, %v; want 2 polled and 1 timed out", polled, timedOut, err)
+	}
+
+	load := func(id uuid.UUID) models.KYCVerification {
+		var verification models.KYCVerification
+		if err := db.First(&verification, "id = ?", id).Error; err != nil {
+			t.Fatalf("failed to load verification: %v", err)
+		}
+		return verification
+	}
+
+	if got := load(approved.ID); got.Status != models.KYCStatusApproved {
+		t.Errorf("approved verification has status %s", got.Status)
+	}
+	if got := load(stale.ID); got.Status != models.KYCStatusTimedOut {
+		t.Errorf("stale verification has status %s, want timed_out", got.Status)
+	}
+	var history int64
+	db.Model(&models.KYCVerificationHistory{}).Where("verification_id = ? AND new_status = ?", stale.ID, models.KYCStatusTimedOut).Count(&history)
+	if history != 1 {
+		t.Errorf("expected the time out in the verification's history")
+	}
+
+	// The verification still waiting isn't polled again until its backoff has passed, which
+	// doubles after each poll
+	got := load(waiting.ID)
+	if got.Status != models.KYCStatusInProgress || got.PollAttempts != 1 || got.NextPollAt == nil || !got.NextPollAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("unexpected waiting verification %+v", got)
+	}
+	if polled, _, _ := poller.PollDue(context.Background(), now.Add(30*time.Second)); polled != 0 {
+		t.Errorf("polled %d verifications before they were due", polled)
+	}
+	if polled, _, _ := poller.PollDue(context.Background(), now.Add(time.Minute)); polled != 1 {
+		t.Errorf("polled %d verifications once due, want 1", polled)
+	}
+	got = load(waiting.ID)
+	if got.PollAttempts != 2 || !got.NextPollAt.Equal(now.Add(3*time.Minute)) {
+		t.Errorf("unexpected backoff: %d attempts, next poll at %v", got.PollAttempts, got.NextPollAt)
+	}
+	if provider.polls != 3 {
+		t.Errorf("provider polled %d times, want 3", provider.polls)
+	}
+
+	// A late result doesn't revive a timed out verification
+	got = load(stale.ID)
+	if applyDiditWebhook(&got, &DiditWebhookPayload{Status: "completed"}) {
+		t.Errorf("late webhook changed a timed out verification")
+	}
+}
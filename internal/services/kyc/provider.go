@@ -0,0 +1,396 @@
+package kyc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/storage"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrVerificationNotFound is returned when a verification doesn't exist
+	ErrVerificationNotFound = errors.New("verification not found")
+
+	// ErrVerificationActive is returned when the user already has a pending, in-progress or approved verification
+	ErrVerificationActive = errors.New("KYC verification already in progress or approved")
+
+	// ErrInvalidDocumentType is returned for unsupported document types
+	ErrInvalidDocumentType = errors.New("invalid document type")
+
+	// ErrInvalidReviewStatus is returned when an admin review sets a status other than approved or rejected
+	ErrInvalidReviewStatus = errors.New("status must be approved or rejected")
+
+	// ErrRejectionReasonRequired is returned when rejecting without a reason
+	ErrRejectionReasonRequired = errors.New("rejection reason is required")
+
+	// ErrStatusUnchanged is returned when a review sets the status the verification already has
+	ErrStatusUnchanged = errors.New("verification is already in the requested status")
+
+	// ErrInvalidWebhookSignature is returned when a provider webhook fails signature verification
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+	// ErrInvalidWebhookPayload is returned when a provider webhook can't be parsed. Redelivering
+	// it won't help.
+	ErrInvalidWebhookPayload = errors.New("invalid webhook payload")
+
+	// ErrDocumentNotFound is returned when a verification has no document of the requested type
+	ErrDocumentNotFound = errors.New("document not found")
+)
+
+// activeStatuses are the statuses that block a new verification from being started
+var activeStatuses = []models.KYCStatus{models.KYCStatusPending, models.KYCStatusInProgress, models.KYCStatusApproved}
+
+// Provider is a KYC verification provider. Every provider records its verifications as
+// models.KYCVerification rows, so status, documents and history look the same whichever
+// provider ran the check.
+type Provider interface {
+	// Name returns the provider identifier stored on each verification
+	Name() string
+
+	// CreateSession starts a verification for the user
+	CreateSession(ctx context.Context, userID uuid.UUID, req SessionRequest) (*models.KYCVerification, error)
+
+	// UploadDocument attaches a document already written to the storage backend to a verification
+	UploadDocument(verificationID uuid.UUID, docType models.DocumentType, document *StoredDocument) (*models.KYCDocument, error)
+
+	// Submit sends a verification's details and documents to the provider for checking
+	// and returns the provider's job ID. The result arrives later through ProcessWebhook.
+	// Calls to the provider stop at ctx's deadline.
+	Submit(ctx context.Context, verificationID uuid.UUID) (string, error)
+
+	// ProcessWebhook applies a provider callback to the matching verification
+	ProcessWebhook(payload []byte, signature string) error
+
+	// GetStatus returns the user's latest verification with this provider
+	GetStatus(userID uuid.UUID) (*Status, error)
+}
+
+var (
+	_ Provider = (*DiditService)(nil)
+	_ Provider = (*SmileProvider)(nil)
+
+	_ StatusPoller = (*DiditService)(nil)
+	_ StatusPoller = (*SmileProvider)(nil)
+)
+
+// DefaultProviders creates every configured provider, reading documents from store.
+// Smile Identity is always available; Didit is skipped when its credentials aren't set.
+func DefaultProviders(db *gorm.DB, store storage.Backend) []Provider {
+	providers := []Provider{NewSmileProvider(db, store)}
+
+	diditService, err := NewDiditService(db, store)
+	if err != nil {
+		log.Printf("Didit KYC provider not configured: %v", err)
+	} else {
+		providers = append(providers, diditService)
+	}
+
+	return providers
+}
+
+// SessionRequest holds the details a user submits when starting a verification.
+// Hosted-flow providers such as Didit collect these themselves and ignore them.
+type SessionRequest struct {
+	FullName    string
+	DateOfBirth *time.Time
+	Address     string
+	Country     string
+	IDDocType   *models.DocumentType
+	IDDocNumber string
+}
+
+// Status is the provider-independent view of a user's KYC verification
+type Status struct {
+	VerificationID  *uuid.UUID           `json:"verification_id,omitempty"`
+	Provider        string               `json:"provider,omitempty"`
+	Status          models.KYCStatus     `json:"status"`
+	VerificationURL string               `json:"verification_url,omitempty"`
+	FullName        *string              `json:"full_name,omitempty"`
+	IDDocType       *models.DocumentType `json:"id_doc_type,omitempty"`
+	IDDocCountry    *string              `json:"id_doc_country,omitempty"`
+	IDDocExpiry     *time.Time           `json:"id_doc_expiry,omitempty"`
+	RejectionReason *string              `json:"rejection_reason,omitempty"`
+	SubmittedAt     *time.Time           `json:"submitted_at,omitempty"`
+	UpdatedAt       *time.Time           `json:"updated_at,omitempty"`
+	VerifiedAt      *time.Time           `json:"verified_at,omitempty"`
+}
+
+// NewStatus builds the status view for a verification. Identity details are only included
+// once approved, and the verification URL only while the user can still complete it.
+func NewStatus(verification *models.KYCVerification) *Status {
+	status := &Status{
+		VerificationID: &verification.ID,
+		Provider:       verification.Provider,
+		Status:         verification.Status,
+		SubmittedAt:    &verification.CreatedAt,
+		UpdatedAt:      &verification.UpdatedAt,
+		VerifiedAt:     verification.VerifiedAt,
+	}
+
+	switch verification.Status {
+	case models.KYCStatusApproved:
+		status.FullName = verification.FullName
+		status.IDDocType = verification.IDDocType
+		status.IDDocCountry = verification.IDDocCountry
+		status.IDDocExpiry = verification.IDDocExpiry
+	case models.KYCStatusRejected:
+		status.RejectionReason = verification.RejectionReason
+	case models.KYCStatusPending, models.KYCStatusInProgress:
+		status.VerificationURL = verification.VerificationURL
+	}
+
+	return status
+}
+
+// GetUserStatus returns the status of the user's latest verification with any provider,
+// or a not_submitted status if they have never started one
+func GetUserStatus(db *gorm.DB, userID uuid.UUID) (*Status, error) {
+	return latestStatus(db.Where("user_id = ?", userID))
+}
+
+func latestStatus(query *gorm.DB) (*Status, error) {
+	var verification models.KYCVerification
+	if err := query.Order("created_at DESC").First(&verification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &Status{Status: models.KYCStatusNotSubmitted}, nil
+		}
+		return nil, fmt.Errorf("error getting KYC verification: %w", err)
+	}
+	return NewStatus(&verification), nil
+}
+
+// GetActiveVerification returns the user's pending, in-progress or approved verification, or nil if there isn't one
+func GetActiveVerification(db *gorm.DB, userID uuid.UUID) (*models.KYCVerification, error) {
+	var verification models.KYCVerification
+	err := db.Where("user_id = ? AND status IN ?", userID, activeStatuses).Order("created_at DESC").First(&verification).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error checking existing verifications: %w", err)
+	}
+	return &verification, nil
+}
+
+// ParseDocumentType accepts both the Didit ("id", "license") and legacy
+// ("id_card", "drivers_license") document type names
+func ParseDocumentType(value string) (models.DocumentType, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "id", "id_card", "national_id":
+		return models.DocumentTypeID, nil
+	case "passport":
+		return models.DocumentTypePassport, nil
+	case "license", "drivers_license":
+		return models.DocumentTypeLicense, nil
+	case "selfie":
+		return models.DocumentTypeSelfie, nil
+	case "proof_of_address", "address_proof":
+		return models.DocumentTypeProofOfAddress, nil
+	default:
+		return "", ErrInvalidDocumentType
+	}
+}
+
+// Document names accepted by FindDocument besides the document types themselves
+const (
+	DocumentIDFront = "id_front"
+	DocumentIDBack  = "id_back"
+)
+
+// FindDocument returns a verification's document by name. "id_front" and "id_back" are the
+// first and second ID document uploaded (the ID type is stored on both); any other name is
+// parsed as a document type and returns the first document of that type.
+func FindDocument(db *gorm.DB, verificationID uuid.UUID, name string) (*models.KYCDocument, error) {
+	var documents []models.KYCDocument
+	if err := db.Where("verification_id = ?", verificationID).Order("uploaded_at").Find(&documents).Error; err != nil {
+		return nil, fmt.Errorf("error getting verification documents: %w", err)
+	}
+
+	var idDocuments []models.KYCDocument
+	for _, document := range documents {
+		if document.Type != models.DocumentTypeSelfie && document.Type != models.DocumentTypeProofOfAddress {
+			idDocuments = append(idDocuments, document)
+		}
+	}
+
+	switch name {
+	case DocumentIDFront:
+		if len(idDocuments) > 0 {
+			return &idDocuments[0], nil
+		}
+	case DocumentIDBack:
+		if len(idDocuments) > 1 {
+			return &idDocuments[1], nil
+		}
+	default:
+		docType, err := ParseDocumentType(name)
+		if err != nil {
+			return nil, ErrDocumentNotFound
+		}
+		for i := range documents {
+			if documents[i].Type == docType {
+				return &documents[i], nil
+			}
+		}
+	}
+
+	return nil, ErrDocumentNotFound
+}
+
+// ReviewVerification records an admin's approval or rejection of a verification
+func ReviewVerification(db *gorm.DB, verificationID uuid.UUID, status models.KYCStatus, adminID uuid.UUID, rejectionReason, notes string) (*models.KYCVerification, error) {
+	if status != models.KYCStatusApproved && status != models.KYCStatusRejected {
+		return nil, ErrInvalidReviewStatus
+	}
+	if status == models.KYCStatusRejected && rejectionReason == "" {
+		return nil, ErrRejectionReasonRequired
+	}
+
+	var verification models.KYCVerification
+	if err := db.First(&verification, "id = ?", verificationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVerificationNotFound
+		}
+		return nil, fmt.Errorf("error getting verification: %w", err)
+	}
+
+	if verification.Status == status {
+		return nil, ErrStatusUnchanged
+	}
+
+	previousStatus := verification.Status
+	verification.Status = status
+	if status == models.KYCStatusRejected {
+		verification.RejectionReason = &rejectionReason
+	}
+	if notes != "" {
+		verification.AdminNotes = &notes
+	}
+
+	if err := saveStatusChange(db, &verification, previousStatus, &adminID, notes); err != nil {
+		return nil, err
+	}
+
+	return &verification, nil
+}
+
+// RecordSubmission stores the provider's job ID on a verification and moves it to in progress.
+// The poller starts asking the provider for the result straight away.
+func RecordSubmission(db *gorm.DB, verification *models.KYCVerification, providerJobID string) error {
+	previousStatus := verification.Status
+	if providerJobID != "" {
+		verification.SessionID = providerJobID
+	}
+	verification.Status = models.KYCStatusInProgress
+	now := time.Now()
+	verification.SubmittedAt = &now
+	verification.PollAttempts = 0
+	verification.NextPollAt = &now
+
+	return saveStatusChange(db, verification, previousStatus, nil, fmt.Sprintf("Submitted to %s", verification.Provider))
+}
+
+// saveStatusChange saves a verification and records the status change in its history.
+// Approvals are stamped with the verification time.
+func saveStatusChange(db *gorm.DB, verification *models.KYCVerification, previousStatus models.KYCStatus, changedBy *uuid.UUID, notes string) error {
+	if verification.Status == models.KYCStatusApproved && previousStatus != models.KYCStatusApproved {
+		now := time.Now()
+		verification.VerifiedAt = &now
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(verification).Error; err != nil {
+			return fmt.Errorf("error updating verification: %w", err)
+		}
+
+		if previousStatus == verification.Status {
+			return nil
+		}
+
+		history := models.KYCVerificationHistory{
+			VerificationID: verification.ID,
+			PreviousStatus: previousStatus,
+			NewStatus:      verification.Status,
+			ChangedBy:      changedBy,
+			CreatedAt:      time.Now(),
+		}
+		if notes != "" {
+			history.Notes = &notes
+		}
+
+		if err := tx.Create(&history).Error; err != nil {
+			return fmt.Errorf("error creating history record: %w", err)
+		}
+		return nil
+	})
+}
+
+// verificationStore holds the storage shared by all providers
+type verificationStore struct {
+	db       *gorm.DB
+	storage  storage.Backend
+	provider string
+}
+
+// createVerification stores a new verification for the provider. It returns ErrVerificationActive
+// when the user already has an active verification, including one a concurrent request has just
+// created.
+func (s *verificationStore) createVerification(verification *models.KYCVerification) error {
+	verification.Provider = s.provider
+	if verification.Status == "" {
+		verification.Status = models.KYCStatusPending
+	}
+	if err := s.db.Create(verification).Error; err != nil {
+		if isDuplicateKey(err) {
+			return ErrVerificationActive
+		}
+		return fmt.Errorf("failed to create verification record: %w", err)
+	}
+	return nil
+}
+
+// isDuplicateKey reports whether err is a unique constraint violation
+func isDuplicateKey(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey) ||
+		strings.Contains(err.Error(), "duplicate key") ||
+		strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// UploadDocument records a stored document against a verification
+func (s *verificationStore) UploadDocument(verificationID uuid.UUID, docType models.DocumentType, stored *StoredDocument) (*models.KYCDocument, error) {
+	var verification models.KYCVerification
+	if err := s.db.First(&verification, "id = ?", verificationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVerificationNotFound
+		}
+		return nil, fmt.Errorf("error getting verification: %w", err)
+	}
+
+	document := &models.KYCDocument{
+		VerificationID: verificationID,
+		Type:           docType,
+		StorageKey:     stored.Key,
+		FileName:       stored.FileName,
+		FileSize:       stored.Size,
+		MimeType:       stored.ContentType,
+		UploadedAt:     time.Now(),
+	}
+
+	if err := s.db.Create(document).Error; err != nil {
+		return nil, fmt.Errorf("failed to create document record: %w", err)
+	}
+
+	return document, nil
+}
+
+// GetStatus returns the user's latest verification with this provider
+func (s *verificationStore) GetStatus(userID uuid.UUID) (*Status, error) {
+	return latestStatus(s.db.Where("user_id = ? AND provider = ?", userID, s.provider))
+}
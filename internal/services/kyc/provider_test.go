@@ -0,0 +1,113 @@
+package kyc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+)
+
+func TestParseDocumentType(t *testing.T) {
+	tests := map[string]models.DocumentType{
+		"id":              models.DocumentTypeID,
+		"id_card":         models.DocumentTypeID,
+		"passport":        models.DocumentTypePassport,
+		"drivers_license": models.DocumentTypeLicense,
+		"license":         models.DocumentTypeLicense,
+		" Selfie ":        models.DocumentTypeSelfie,
+	}
+	for value, want := range tests {
+		got, err := ParseDocumentType(value)
+		if err != nil || got != want {
+			t.Errorf("ParseDocumentType(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+
+	if _, err := ParseDocumentType("library_card"); !errors.Is(err, ErrInvalidDocumentType) {
+		t.Errorf("expected ErrInvalidDocumentType, got %v", err)
+	}
+}
+
+func TestNewStatusHasSameShapeForEveryProvider(t *testing.T) {
+	name := "Ama Mensah"
+	reason := "Document expired"
+
+	for _, provider := range []string{models.KYCProviderDidit, models.KYCProviderSmileIdentity} {
+		approved := NewStatus(&models.KYCVerification{ID: uuid.New(), Provider: provider, Status: models.KYCStatusApproved, FullName: &name, VerificationURL: "https://verify.example"})
+		if approved.Provider != provider || approved.FullName == nil || approved.VerificationURL != "" {
+			t.Errorf("%s: unexpected approved status %+v", provider, approved)
+		}
+
+		rejected := NewStatus(&models.KYCVerification{ID: uuid.New(), Provider: provider, Status: models.KYCStatusRejected, FullName: &name, RejectionReason: &reason})
+		if rejected.RejectionReason == nil || rejected.FullName != nil {
+			t.Errorf("%s: unexpected rejected status %+v", provider, rejected)
+		}
+	}
+}
+
+func TestSmileProviderVerifySignature(t *testing.T) {
+	provider := &SmileProvider{partnerID: "2048", apiKey: "smile-api-key"}
+	timestamp := "2026-10-16T09:30:00.000Z"
+
+	mac := hmac.New(sha256.New, []byte("smile-api-key"))
+	mac.Write([]byte(timestamp + "2048" + "sid_request"))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	if !provider.verifySignature(timestamp, signature) {
+		t.Error("expected valid signature to be accepted")
+	}
+	if provider.verifySignature("2026-10-16T09:31:00.000Z", signature) {
+		t.Error("expected signature for a different timestamp to be rejected")
+	}
+	if (&SmileProvider{partnerID: "2048"}).verifySignature(timestamp, signature) {
+		t.Error("expected signatures to be rejected when no API key is configured")
+	}
+	if err := provider.ProcessWebhook([]byte(`{"timestamp":"x","signature":"bad"}`), ""); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("expected ErrInvalidWebhookSignature, got %v", err)
+	}
+}
+
+func TestDiditProcessWebhookRejectsBadSignature(t *testing.T) {
+	service := &DiditService{webhookSecret: "didit-secret"}
+	payload := []byte(`{"session_id":"sess_1","status":"completed"}`)
+
+	mac := hmac.New(sha256.New, []byte("didit-secret"))
+	mac.Write(payload)
+	if !service.verifySignature(payload, hex.EncodeToString(mac.Sum(nil))) {
+		t.Error("expected valid signature to be accepted")
+	}
+
+	if err := service.ProcessWebhook(payload, "bad"); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("expected ErrInvalidWebhookSignature, got %v", err)
+	}
+	if err := (&DiditService{}).ProcessWebhook(payload, "anything"); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("expected signatures to be rejected when no secret is configured, got %v", err)
+	}
+}
+
+func TestApplyDiditWebhookKeepsFinishedVerifications(t *testing.T) {
+	verification := &models.KYCVerification{Status: models.KYCStatusApproved}
+	if applyDiditWebhook(verification, &DiditWebhookPayload{Status: "in_progress"}) {
+		t.Error("expected a late in_progress update to be ignored")
+	}
+	if verification.Status != models.KYCStatusApproved {
+		t.Errorf("status changed to %s", verification.Status)
+	}
+
+	verification = &models.KYCVerification{Status: models.KYCStatusPending}
+	if !applyDiditWebhook(verification, &DiditWebhookPayload{Status: "rejected", ErrorDetails: &DiditErrorDetails{Message: "Blurry document"}}) {
+		t.Error("expected rejection to be applied")
+	}
+	if verification.Status != models.KYCStatusRejected || verification.RejectionReason == nil {
+		t.Errorf("unexpected verification after rejection: %+v", verification)
+	}
+
+	if applyDiditWebhook(verification, &DiditWebhookPayload{Status: "something_new"}) {
+		t.Error("expected unknown statuses to be ignored")
+	}
+}
@@ -0,0 +1,466 @@
+package kyc
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/storage"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultSmileAPIURL is the Smile Identity sandbox API
+	DefaultSmileAPIURL = "https://testapi.smileidentity.com/v1"
+
+	// smileDocumentVerificationJobType is Smile's job type for document verification
+	smileDocumentVerificationJobType = 6
+)
+
+// Smile image type IDs for uploaded files
+const (
+	smileImageSelfie  = 0
+	smileImageIDFront = 1
+	smileImageIDBack  = 5
+)
+
+// Smile Identity result codes that settle a verification
+var (
+	smileApprovedCodes = map[string]bool{"0810": true, "1012": true, "1020": true}
+	smileRejectedCodes = map[string]bool{"0811": true, "1013": true, "1022": true}
+)
+
+// SmileProvider runs document verifications with Smile Identity. Users upload their
+// documents to us and Smile reports the result through its callback; results Smile
+// can't settle stay in progress for an admin to review.
+type SmileProvider struct {
+	verificationStore
+	partnerID   string
+	apiKey      string
+	apiURL      string
+	callbackURL string
+	httpClient  *http.Client
+}
+
+// SmileCallbackPayload is the job result Smile Identity posts to the callback URL
+type SmileCallbackPayload struct {
+	ResultCode    string `json:"ResultCode"`
+	ResultText    string `json:"ResultText"`
+	SmileJobID    string `json:"SmileJobID"`
+	PartnerParams struct {
+		JobID   string `json:"job_id"`
+		UserID  string `json:"user_id"`
+		JobType string `json:"job_type"`
+	} `json:"PartnerParams"`
+	Timestamp string `json:"timestamp"`
+	Signature string `json:"signature"`
+}
+
+// NewSmileProvider creates a Smile Identity provider from the SMILE_IDENTITY_* environment.
+// Documents are read from store when a verification is submitted.
+func NewSmileProvider(db *gorm.DB, store storage.Backend) *SmileProvider {
+	apiURL := os.Getenv("SMILE_IDENTITY_API_URL")
+	if apiURL == "" {
+		apiURL = DefaultSmileAPIURL
+	}
+
+	return &SmileProvider{
+		verificationStore: verificationStore{db: db, storage: store, provider: models.KYCProviderSmileIdentity},
+		partnerID:         os.Getenv("SMILE_IDENTITY_PARTNER_ID"),
+		apiKey:            os.Getenv("SMILE_IDENTITY_API_KEY"),
+		apiURL:            apiURL,
+		callbackURL:       os.Getenv("SMILE_IDENTITY_CALLBACK_URL"),
+		httpClient:        &http.Client{},
+	}
+}
+
+// Name returns the provider identifier
+func (p *SmileProvider) Name() string {
+	return models.KYCProviderSmileIdentity
+}
+
+// CreateSession records a pending verification with the details the user submitted.
+// The verification ID is sent to Smile as the job ID so callbacks can be matched to it.
+func (p *SmileProvider) CreateSession(_ context.Context, userID uuid.UUID, req SessionRequest) (*models.KYCVerification, error) {
+	verification := &models.KYCVerification{
+		UserID:      userID,
+		DateOfBirth: req.DateOfBirth,
+		IDDocType:   req.IDDocType,
+	}
+	if req.FullName != "" {
+		verification.FullName = &req.FullName
+	}
+	if req.Address != "" {
+		verification.Address = &req.Address
+	}
+	if req.IDDocNumber != "" {
+		verification.IDDocNumber = &req.IDDocNumber
+	}
+	// Only ISO country codes fit the id_doc_country column
+	if country := strings.ToUpper(strings.TrimSpace(req.Country)); len(country) == 2 {
+		verification.IDDocCountry = &country
+	}
+
+	if err := p.createVerification(verification); err != nil {
+		return nil, err
+	}
+	return verification, nil
+}
+
+// Submit starts a Smile Identity document verification job: it requests an upload slot,
+// then uploads a zip with the ID document, selfie and job details. It returns Smile's job ID.
+func (p *SmileProvider) Submit(ctx context.Context, verificationID uuid.UUID) (string, error) {
+	if p.apiKey == "" || p.partnerID == "" {
+		return "", errors.New("smile identity credentials are not configured")
+	}
+
+	var verification models.KYCVerification
+	if err := p.db.First(&verification, "id = ? AND provider = ?", verificationID, p.provider).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrVerificationNotFound
+		}
+		return "", fmt.Errorf("error getting verification: %w", err)
+	}
+
+	var documents []models.KYCDocument
+	if err := p.db.Where("verification_id = ?", verificationID).Order("uploaded_at").Find(&documents).Error; err != nil {
+		return "", fmt.Errorf("error getting verification documents: %w", err)
+	}
+
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	partnerParams := map[string]interface{}{
+		"job_id":   verification.ID.String(),
+		"user_id":  verification.UserID.String(),
+		"job_type": smileDocumentVerificationJobType,
+	}
+	uploadRequest := map[string]interface{}{
+		"source_sdk":         "rest_api",
+		"source_sdk_version": "1.0.0",
+		"file_name":          "documents.zip",
+		"smile_client_id":    p.partnerID,
+		"signature":          p.sign(timestamp),
+		"timestamp":          timestamp,
+		"partner_params":     partnerParams,
+		"model_parameters":   map[string]interface{}{},
+		"callback_url":       p.callbackURL,
+	}
+
+	body, err := json.Marshal(uploadRequest)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal upload request: %w", err)
+	}
+
+	ctx, cancel := utils.ProviderContext(ctx)
+	defer cancel()
+
+	uploadReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.apiURL+"/upload", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload request: %w", err)
+	}
+	uploadReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(uploadReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to send upload request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read upload response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("smile identity upload request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var upload struct {
+		UploadURL  string `json:"upload_url"`
+		SmileJobID string `json:"smile_job_id"`
+	}
+	if err := json.Unmarshal(respBody, &upload); err != nil {
+		return "", fmt.Errorf("failed to unmarshal upload response: %w", err)
+	}
+
+	archive, err := p.buildJobArchive(&verification, documents, uploadRequest)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, upload.UploadURL, bytes.NewReader(archive))
+	if err != nil {
+		return "", fmt.Errorf("failed to create document upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/zip")
+
+	uploadResp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload documents: %w", err)
+	}
+	defer uploadResp.Body.Close()
+
+	if uploadResp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("smile identity document upload failed with status %d", uploadResp.StatusCode)
+	}
+
+	return upload.SmileJobID, nil
+}
+
+// buildJobArchive packages the job details and document images the way Smile expects
+func (p *SmileProvider) buildJobArchive(verification *models.KYCVerification, documents []models.KYCDocument, uploadRequest map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	idInfo := map[string]interface{}{
+		"entered": "true",
+		"id_type": smileIDType(verification.IDDocType),
+	}
+	if verification.IDDocCountry != nil {
+		idInfo["country"] = *verification.IDDocCountry
+	}
+	if verification.IDDocNumber != nil {
+		idInfo["id_number"] = *verification.IDDocNumber
+	}
+
+	var images []map[string]interface{}
+	idFrontAdded := false
+	for _, document := range documents {
+		var imageType int
+		switch {
+		case document.Type == models.DocumentTypeSelfie:
+			imageType = smileImageSelfie
+		case document.Type == models.DocumentTypeProofOfAddress:
+			continue
+		case !idFrontAdded:
+			imageType = smileImageIDFront
+			idFrontAdded = true
+		default:
+			imageType = smileImageIDBack
+		}
+
+		fileName := document.ID.String() + filepath.Ext(document.FileName)
+		if err := p.addDocumentToArchive(archive, fileName, document.StorageKey); err != nil {
+			return nil, err
+		}
+		images = append(images, map[string]interface{}{
+			"image_type_id": imageType,
+			"file_name":     fileName,
+		})
+	}
+
+	info := map[string]interface{}{
+		"package_information": map[string]interface{}{
+			"apiVersion": map[string]int{"buildNumber": 0, "majorVersion": 2, "minorVersion": 0},
+			"language":   "golang",
+		},
+		"misc_information": uploadRequest,
+		"id_info":          idInfo,
+		"images":           images,
+	}
+	infoJSON, err := json.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job info: %w", err)
+	}
+
+	infoFile, err := archive.Create("info.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to add job info: %w", err)
+	}
+	if _, err := infoFile.Write(infoJSON); err != nil {
+		return nil, fmt.Errorf("failed to add job info: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build document archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// addDocumentToArchive copies a document from the storage backend into the archive
+func (p *SmileProvider) addDocumentToArchive(archive *zip.Writer, name, key string) error {
+	object, _, err := p.storage.Get(context.Background(), key)
+	if err != nil {
+		return fmt.Errorf("failed to read document %s: %w", name, err)
+	}
+	defer object.Close()
+
+	entry, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add document to archive: %w", err)
+	}
+	if _, err := io.Copy(entry, object); err != nil {
+		return fmt.Errorf("failed to add document to archive: %w", err)
+	}
+	return nil
+}
+
+// smileIDType maps a document type to Smile's ID type names
+func smileIDType(docType *models.DocumentType) string {
+	if docType == nil {
+		return "NATIONAL_ID"
+	}
+	switch *docType {
+	case models.DocumentTypePassport:
+		return "PASSPORT"
+	case models.DocumentTypeLicense:
+		return "DRIVERS_LICENSE"
+	default:
+		return "NATIONAL_ID"
+	}
+}
+
+// ProcessWebhook applies a Smile Identity job result. The signature is normally carried in
+// the payload; a signature passed in separately takes precedence.
+func (p *SmileProvider) ProcessWebhook(payload []byte, signature string) error {
+	var callback SmileCallbackPayload
+	if err := json.Unmarshal(payload, &callback); err != nil {
+		return fmt.Errorf("failed to unmarshal webhook payload: %w", err)
+	}
+
+	if signature == "" {
+		signature = callback.Signature
+	}
+	if !p.verifySignature(callback.Timestamp, signature) {
+		return ErrInvalidWebhookSignature
+	}
+
+	verificationID, err := uuid.Parse(callback.PartnerParams.JobID)
+	if err != nil {
+		return fmt.Errorf("invalid job ID %q: %w", callback.PartnerParams.JobID, err)
+	}
+
+	var verification models.KYCVerification
+	if err := p.db.First(&verification, "id = ? AND provider = ?", verificationID, p.provider).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVerificationNotFound
+		}
+		return fmt.Errorf("error getting verification: %w", err)
+	}
+
+	// A verification that timed out waiting has to be resubmitted; a late result doesn't revive it
+	if verification.Status == models.KYCStatusTimedOut {
+		return nil
+	}
+
+	previousStatus := verification.Status
+	if callback.SmileJobID != "" {
+		verification.SessionID = callback.SmileJobID
+	}
+	applySmileResult(&verification, callback.ResultCode, callback.ResultText)
+
+	notes := fmt.Sprintf("Smile Identity result %s: %s", callback.ResultCode, callback.ResultText)
+	return saveStatusChange(p.db, &verification, previousStatus, nil, notes)
+}
+
+// applySmileResult sets a verification's status from a Smile Identity result code. Codes that
+// don't settle the verification leave it in progress for an admin to review. Either way Smile
+// has given its result, so the verification isn't polled again.
+func applySmileResult(verification *models.KYCVerification, resultCode, resultText string) {
+	verification.NextPollAt = nil
+	switch {
+	case smileApprovedCodes[resultCode]:
+		verification.Status = models.KYCStatusApproved
+	case smileRejectedCodes[resultCode]:
+		verification.Status = models.KYCStatusRejected
+		reason := resultText
+		verification.RejectionReason = &reason
+	default:
+		verification.Status = models.KYCStatusInProgress
+	}
+}
+
+// PollStatus asks Smile Identity for the result of a verification's job and applies it, in
+// case Smile's callback never arrives
+func (p *SmileProvider) PollStatus(ctx context.Context, verification *models.KYCVerification) (bool, error) {
+	if p.apiKey == "" || p.partnerID == "" {
+		return false, errors.New("smile identity credentials are not configured")
+	}
+
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	body, err := json.Marshal(map[string]interface{}{
+		"partner_id":  p.partnerID,
+		"timestamp":   timestamp,
+		"signature":   p.sign(timestamp),
+		"user_id":     verification.UserID.String(),
+		"job_id":      verification.ID.String(),
+		"image_links": false,
+		"history":     false,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal job status request: %w", err)
+	}
+
+	ctx, cancel := utils.ProviderContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.apiURL+"/job_status", bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create job status request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send job status request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read job status response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("smile identity job status request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var status struct {
+		JobComplete bool `json:"job_complete"`
+		Result      struct {
+			ResultCode string `json:"ResultCode"`
+			ResultText string `json:"ResultText"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &status); err != nil {
+		return false, fmt.Errorf("failed to unmarshal job status response: %w", err)
+	}
+	if !status.JobComplete {
+		return false, nil
+	}
+
+	notes := fmt.Sprintf("Smile Identity job status %s: %s", status.Result.ResultCode, status.Result.ResultText)
+	err = applyProviderResult(p.db, verification.ID, notes, func(verification *models.KYCVerification) bool {
+		applySmileResult(verification, status.Result.ResultCode, status.Result.ResultText)
+		return true
+	})
+	return err == nil, err
+}
+
+// verifySignature checks a Smile Identity signature: base64(HMAC-SHA256(api key, timestamp + partner ID + "sid_request"))
+func (p *SmileProvider) verifySignature(timestamp, signature string) bool {
+	if p.apiKey == "" || signature == "" {
+		return false
+	}
+
+	return hmac.Equal([]byte(p.sign(timestamp)), []byte(signature))
+}
+
+// sign creates the Smile Identity request signature for a timestamp
+func (p *SmileProvider) sign(timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(p.apiKey))
+	mac.Write([]byte(timestamp + p.partnerID + "sid_request"))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"fmt"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/email"
+	"gorm.io/gorm"
+)
+
+// EmailChannel delivers notifications by email
+type EmailChannel struct {
+	emailService *email.EmailService
+}
+
+// NewEmailChannel creates a channel that sends notifications through the email service
+func NewEmailChannel(emailService *email.EmailService) *EmailChannel {
+	return &EmailChannel{emailService: emailService}
+}
+
+// Name returns the channel name
+func (c *EmailChannel) Name() string {
+	return "email"
+}
+
+// Send emails the message to the user
+func (c *EmailChannel) Send(user *models.User, msg Message) error {
+	name := user.FirstName
+	if name == "" {
+		name = user.Username
+	}
+	return c.emailService.SendNotificationEmail(user.Email, name, msg.Title, msg.Body)
+}
+
+// InAppChannel stores notifications for the user to read in the app
+type InAppChannel struct {
+	db *gorm.DB
+}
+
+// NewInAppChannel creates a channel that stores notifications in the database
+func NewInAppChannel(db *gorm.DB) *InAppChannel {
+	return &InAppChannel{db: db}
+}
+
+// Name returns the channel name
+func (c *InAppChannel) Name() string {
+	return "in_app"
+}
+
+// Send stores the message as an unread notification
+func (c *InAppChannel) Send(user *models.User, msg Message) error {
+	notification := models.Notification{
+		UserID:  user.ID,
+		Type:    msg.Type,
+		Title:   msg.Title,
+		Message: msg.Body,
+		Data:    models.JSON(msg.Data),
+	}
+	if err := c.db.Create(&notification).Error; err != nil {
+		return fmt.Errorf("error storing notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotificationNotFound is returned when a notification does not exist or belongs to another user
+	ErrNotificationNotFound = errors.New("notification not found")
+
+	// ErrDeliveryFailed is returned when a message could not be delivered on any channel
+	ErrDeliveryFailed = errors.New("notification could not be delivered")
+)
+
+// Message is a notification rendered for delivery
+type Message struct {
+	Type  models.NotificationType
+	Title string
+	Body  string
+	Data  map[string]interface{}
+}
+
+// Channel delivers a message to a user, e.g. by email or as an in-app notification
+type Channel interface {
+	Name() string
+	Send(user *models.User, msg Message) error
+}
+
+// Service renders user notifications and delivers them on every configured channel
+type Service struct {
+	db       *gorm.DB
+	channels []Channel
+}
+
+// NewService creates a new notification service that delivers on the given channels
+func NewService(db *gorm.DB, channels ...Channel) *Service {
+	return &Service{
+		db:       db,
+		channels: channels,
+	}
+}
+
+// Notify delivers a message to a user on every channel. A channel failing does not stop the
+// others; an error is only returned when no channel delivered, so retries don't duplicate messages.
+func (s *Service) Notify(userID uuid.UUID, msg Message) error {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	delivered := 0
+	for _, channel := range s.channels {
+		if err := channel.Send(&user, msg); err != nil {
+			log.Printf("Failed to send %s notification to user %s via %s: %v", msg.Type, userID, channel.Name(), err)
+			continue
+		}
+		delivered++
+	}
+
+	if delivered == 0 && len(s.channels) > 0 {
+		return ErrDeliveryFailed
+	}
+	return nil
+}
+
+// NotifyPaymentStatus tells the sender of an international payment that its status changed
+func (s *Service) NotifyPaymentStatus(paymentID uuid.UUID, status string) error {
+	var payment database.InternationalPayment
+	if err := s.db.First(&payment, "id = ?", paymentID).Error; err != nil {
+		return fmt.Errorf("error getting international payment: %w", err)
+	}
+	if status == "" {
+		status = payment.Status
+	}
+
+	return s.Notify(payment.UserID, renderPaymentStatus(&payment, status))
+}
+
+// NotifyWithdrawalStatus tells a user that their withdrawal's status changed
+func (s *Service) NotifyWithdrawalStatus(withdrawalID uuid.UUID, status string) error {
+	var withdrawal models.Withdrawal
+	if err := s.db.First(&withdrawal, "id = ?", withdrawalID).Error; err != nil {
+		return fmt.Errorf("error getting withdrawal: %w", err)
+	}
+	if status == "" {
+		status = withdrawal.Status
+	}
+
+	return s.Notify(withdrawal.UserID, renderWithdrawalStatus(&withdrawal, status))
+}
+
+// NotifySubscriptionStatus tells a subscriber that their subscription renewed, a renewal
+// failed, or the subscription was cancelled
+func (s *Service) NotifySubscriptionStatus(subscriptionID uuid.UUID, status string) error {
+	var subscription models.Subscription
+	if err := s.db.First(&subscription, "id = ?", subscriptionID).Error; err != nil {
+		return fmt.Errorf("error getting subscription: %w", err)
+	}
+
+	// The plan may have been deleted since; its subscribers are still billed for it
+	var plan models.SubscriptionPlan
+	if err := s.db.Unscoped().First(&plan, "id = ?", subscription.PlanID).Error; err != nil {
+		return fmt.Errorf("error getting subscription plan: %w", err)
+	}
+
+	return s.Notify(subscription.SubscriberID, renderSubscriptionStatus(&subscription, &plan, status))
+}
+
+// ListNotifications returns a page of a user's in-app notifications, newest first
+func (s *Service) ListNotifications(userID uuid.UUID, unreadOnly bool, page, pageSize int) ([]models.Notification, int64, error) {
+	query := s.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error counting notifications: %w", err)
+	}
+
+	var notifications []models.Notification
+	offset := (page - 1) * pageSize
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&notifications).Error; err != nil {
+		return nil, 0, fmt.Errorf("error getting notifications: %w", err)
+	}
+
+	return notifications, total, nil
+}
+
+// UnreadCount returns how many in-app notifications the user has not read
+func (s *Service) UnreadCount(userID uuid.UUID) (int64, error) {
+	var count int64
+	if err := s.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error counting unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks one of the user's notifications as read
+func (s *Service) MarkRead(userID, notificationID uuid.UUID) (*models.Notification, error) {
+	var notification models.Notification
+	if err := s.db.Where("id = ? AND user_id = ?", notificationID, userID).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotificationNotFound
+		}
+		return nil, fmt.Errorf("error getting notification: %w", err)
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := s.db.Model(&notification).Update("read_at", now).Error; err != nil {
+			return nil, fmt.Errorf("error marking notification read: %w", err)
+		}
+	}
+
+	return &notification, nil
+}
+
+// MarkAllRead marks all of the user's unread notifications as read and returns how many changed
+func (s *Service) MarkAllRead(userID uuid.UUID) (int64, error) {
+	result := s.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return 0, fmt.Errorf("error marking notifications read: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// renderPaymentStatus builds the message for an international payment status change
+func renderPaymentStatus(payment *database.InternationalPayment, status string) Message {
+	msg := Message{
+		Type: models.NotificationTypePaymentStatus,
+		Data: map[string]interface{}{
+			"payment_id":   payment.ID.String(),
+			"reference":    payment.Reference,
+			"status":       status,
+			"amount_cedis": payment.AmountCedis,
+			"vendor_name":  payment.VendorName,
+		},
+	}
+
+	switch status {
+	case "completed":
+		msg.Title = "Payment completed"
+		msg.Body = fmt.Sprintf("Your payment of GHS %.2f to %s has been completed.", payment.AmountCedis, payment.VendorName)
+	case "failed":
+		msg.Title = "Payment failed"
+		msg.Body = fmt.Sprintf("Your payment of GHS %.2f to %s could not be completed.", payment.AmountCedis, payment.VendorName)
+		if payment.Error != "" {
+			msg.Body += " Reason: " + payment.Error
+		}
+	case "blocked":
+		msg.Title = "Payment blocked"
+		msg.Body = fmt.Sprintf("Your payment of GHS %.2f to %s was blocked by our compliance checks. Please contact support.", payment.AmountCedis, payment.VendorName)
+	default:
+		msg.Title = "Payment update"
+		msg.Body = fmt.Sprintf("Your payment of GHS %.2f to %s is now %s.", payment.AmountCedis, payment.VendorName, status)
+	}
+
+	return msg
+}
+
+// renderWithdrawalStatus builds the message for a withdrawal status change
+func renderWithdrawalStatus(withdrawal *models.Withdrawal, status string) Message {
+	msg := Message{
+		Type: models.NotificationTypeWithdrawalStatus,
+		Data: map[string]interface{}{
+			"withdrawal_id": withdrawal.ID.String(),
+			"reference":     withdrawal.Reference,
+			"status":        status,
+			"amount":        withdrawal.Amount,
+			"currency":      string(withdrawal.Currency),
+		},
+	}
+
+	amount := fmt.Sprintf("%s %.2f", withdrawal.Currency, withdrawal.Amount)
+	switch status {
+	case "completed":
+		msg.Title = "Withdrawal completed"
+		msg.Body = fmt.Sprintf("Your withdrawal of %s has been paid out.", amount)
+	case "failed", "blocked":
+		msg.Title = "Withdrawal failed"
+		msg.Body = fmt.Sprintf("Your withdrawal of %s could not be completed and the funds are back in your wallet.", amount)
+	default:
+		msg.Title = "Withdrawal update"
+		msg.Body = fmt.Sprintf("Your withdrawal of %s is now %s.", amount, status)
+	}
+
+	return msg
+}
+
+// renderSubscriptionStatus builds the message for a subscription renewal or failed charge
+func renderSubscriptionStatus(subscription *models.Subscription, plan *models.SubscriptionPlan, status string) Message {
+	msg := Message{
+		Type: models.NotificationTypeSubscription,
+		Data: map[string]interface{}{
+			"subscription_id": subscription.ID.String(),
+			"plan_id":         plan.ID.String(),
+			"status":          status,
+			"amount":          plan.Amount,
+			"currency":        string(plan.Currency),
+		},
+	}
+
+	amount := fmt.Sprintf("%s %.2f", plan.Currency, plan.Amount)
+	switch status {
+	case "renewed":
+		msg.Title = "Subscription renewed"
+		msg.Body = fmt.Sprintf("You were charged %s for %s. Your subscription now runs until %s.", amount, plan.Name, subscription.CurrentPeriodEnd.Format("2 January 2006"))
+	case "failed", "past_due":
+		msg.Title = "Subscription payment failed"
+		msg.Body = fmt.Sprintf("We couldn't charge %s for %s.", amount, plan.Name)
+		if subscription.NextPaymentDate != nil {
+			msg.Body += fmt.Sprintf(" We'll try again on %s; please make sure your card can be charged.", subscription.NextPaymentDate.Format("2 January 2006"))
+		}
+	case "cancelled":
+		msg.Title = "Subscription cancelled"
+		msg.Body = fmt.Sprintf("Your subscription to %s was cancelled because we couldn't charge your card.", plan.Name)
+	default:
+		msg.Title = "Subscription update"
+		msg.Body = fmt.Sprintf("Your subscription to %s is now %s.", plan.Name, status)
+	}
+
+	return msg
+}
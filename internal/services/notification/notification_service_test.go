@@ -0,0 +1,68 @@
+package notification
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingChannel is a channel that can't deliver anything
+type failingChannel struct{}
+
+func (failingChannel) Name() string { return "failing" }
+
+func (failingChannel) Send(user *models.User, msg Message) error {
+	return errors.New("provider unavailable")
+}
+
+// A withdrawal status change is stored as an unread in-app notification for its owner, even
+// when another channel fails, and only that owner can read or mark it
+func TestNotifyWithdrawalStatusStoresInAppNotification(t *testing.T) {
+	db := testutil.NewDB(t)
+	owner := testutil.CreateUser(t, db)
+	other := testutil.CreateUser(t, db)
+	service := NewService(db, failingChannel{}, NewInAppChannel(db))
+
+	withdrawal := models.Withdrawal{
+		UserID:      owner.ID,
+		Amount:      40,
+		Currency:    models.CurrencyGHS,
+		Status:      "failed",
+		Reference:   "WD-1",
+		InitiatedAt: time.Now(),
+	}
+	require.NoError(t, db.Create(&withdrawal).Error)
+	require.NoError(t, service.NotifyWithdrawalStatus(withdrawal.ID, ""))
+
+	notifications, total, err := service.ListNotifications(owner.ID, true, 1, 20)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	notification := notifications[0]
+	assert.Equal(t, models.NotificationTypeWithdrawalStatus, notification.Type)
+	assert.Equal(t, "Withdrawal failed", notification.Title)
+	assert.Equal(t, "Your withdrawal of GHS 40.00 could not be completed and the funds are back in your wallet.", notification.Message)
+	assert.Equal(t, "WD-1", notification.Data["reference"])
+
+	_, err = service.MarkRead(other.ID, notification.ID)
+	assert.ErrorIs(t, err, ErrNotificationNotFound)
+	read, err := service.MarkRead(owner.ID, notification.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, read.ReadAt)
+	unread, err := service.UnreadCount(owner.ID)
+	require.NoError(t, err)
+	assert.Zero(t, unread)
+}
+
+// Notify only fails when no channel delivered the message
+func TestNotifyFailsWhenNoChannelDelivers(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+
+	assert.ErrorIs(t, NewService(db, failingChannel{}).Notify(user.ID, Message{Type: models.NotificationTypePaymentStatus, Title: "Payment update"}), ErrDeliveryFailed)
+	assert.NoError(t, NewService(db).Notify(user.ID, Message{Type: models.NotificationTypePaymentStatus, Title: "Payment update"}))
+}
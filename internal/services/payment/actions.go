@@ -0,0 +1,71 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrNoActionRequired is returned when confirming a payment that isn't waiting on the customer
+var ErrNoActionRequired = errors.New("payment does not require action")
+
+// ConfirmPayment completes a payment that was waiting on the customer, such as for a 3D Secure
+// challenge or an OTP, by submitting actionData from the action to the provider. If the
+// provider needs another action it's returned with the payment, which stays pending; otherwise
+// the payment is verified, and credited if the provider completed it.
+func (s *PaymentService) ConfirmPayment(ctx context.Context, reference string, actionData map[string]string) (*models.Payment, *models.PaymentAction, error) {
+	var payment models.Payment
+	err := s.db.First(&payment, "reference = ?", reference).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrPaymentReferenceNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error finding payment: %w", err)
+	}
+	if payment.Status != models.PaymentStatusPending || payment.RequiredAction == "" {
+		return nil, nil, ErrNoActionRequired
+	}
+	// Too late to complete the action; verifying settles whether it was paid in time
+	if isExpired(&payment, time.Now()) {
+		verified, err := s.VerifyPayment(ctx, reference)
+		return verified, nil, err
+	}
+
+	paymentProvider, _, err := s.providerFor(payment.Provider, payment.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	provider, ok := paymentProvider.(ActionPaymentProvider)
+	if !ok {
+		return nil, nil, fmt.Errorf("provider %s does not support payment actions", payment.Provider)
+	}
+
+	call, err := s.allowProviderCall(payment.Provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	next, err := provider.ConfirmPayment(ctx, &payment, actionData)
+	call.Done(err)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error confirming payment: %w", err)
+	}
+
+	var requiredAction models.PaymentActionType
+	if next != nil {
+		requiredAction = next.Type
+	}
+	if err := s.db.Model(&payment).Update("required_action", requiredAction).Error; err != nil {
+		return nil, nil, fmt.Errorf("error updating payment record: %w", err)
+	}
+	if next != nil {
+		payment.RequiredAction = requiredAction
+		return &payment, next, nil
+	}
+
+	verified, err := s.VerifyPayment(ctx, reference)
+	return verified, nil, err
+}
@@ -0,0 +1,134 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/currency"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAmountBelowMinimum is returned when a payment amount is under the currency's minimum
+	ErrAmountBelowMinimum = errors.New("amount is below the minimum")
+
+	// ErrAmountAboveMaximum is returned when a payment amount is over the currency's maximum
+	ErrAmountAboveMaximum = errors.New("amount is above the maximum")
+
+	// ErrInvalidAmountLimit is returned when setting a limit whose minimum isn't positive or exceeds its maximum
+	ErrInvalidAmountLimit = errors.New("minimum amount must be positive and no more than the maximum")
+
+	// ErrAmountLimitNotFound is returned when a merchant has no limit override for a currency
+	ErrAmountLimitNotFound = errors.New("amount limit override not found")
+)
+
+// SetAmountLimits sets the default per-currency payment amount limits, keyed by currency code.
+// Payments in currencies without limits aren't bounded.
+func (s *PaymentService) SetAmountLimits(limits map[string]config.AmountLimit) {
+	s.amountLimits = limits
+}
+
+// AmountLimitFor returns the amount limits for a merchant's payments in a currency: the
+// merchant's override if an admin set one, otherwise the configured default. ok is false when
+// the currency isn't bounded.
+func (s *PaymentService) AmountLimitFor(userID uuid.UUID, currency models.Currency) (limit config.AmountLimit, ok bool, err error) {
+	var override models.PaymentAmountLimit
+	err = s.db.Where("user_id = ? AND currency = ?", userID, currency).First(&override).Error
+	if err == nil {
+		return config.AmountLimit{Min: override.MinAmount, Max: override.MaxAmount}, true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return config.AmountLimit{}, false, fmt.Errorf("error finding amount limit: %w", err)
+	}
+
+	limit, ok = s.amountLimits[string(currency)]
+	return limit, ok, nil
+}
+
+// normalizeAmount checks a payment's currency is supported, rounds the amount to the
+// currency's precision and checks it against the merchant's limits, returning the rounded amount
+func (s *PaymentService) normalizeAmount(userID uuid.UUID, amount float64, code models.Currency) (float64, error) {
+	if err := currency.Validate(code); err != nil {
+		return 0, err
+	}
+	amount = currency.Round(code, amount)
+
+	limit, ok, err := s.AmountLimitFor(userID, code)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return amount, nil
+	}
+	if amount < limit.Min {
+		return 0, fmt.Errorf("%w of %.2f %s", ErrAmountBelowMinimum, limit.Min, code)
+	}
+	if amount > limit.Max {
+		return 0, fmt.Errorf("%w of %.2f %s", ErrAmountAboveMaximum, limit.Max, code)
+	}
+	return amount, nil
+}
+
+// ListMerchantAmountLimits returns the limit overrides set for a merchant
+func (s *PaymentService) ListMerchantAmountLimits(userID uuid.UUID) ([]models.PaymentAmountLimit, error) {
+	var limits []models.PaymentAmountLimit
+	if err := s.db.Where("user_id = ?", userID).Order("currency").Find(&limits).Error; err != nil {
+		return nil, fmt.Errorf("error listing amount limits: %w", err)
+	}
+	return limits, nil
+}
+
+// SetMerchantAmountLimit overrides a merchant's payment amount limits in a currency
+func (s *PaymentService) SetMerchantAmountLimit(ctx context.Context, adminID, userID uuid.UUID, code models.Currency, limit config.AmountLimit) (*models.PaymentAmountLimit, error) {
+	if err := currency.Validate(code); err != nil {
+		return nil, err
+	}
+	if limit.Min <= 0 || limit.Min > limit.Max {
+		return nil, ErrInvalidAmountLimit
+	}
+
+	var override models.PaymentAmountLimit
+	err := s.db.Where("user_id = ? AND currency = ?", userID, code).First(&override).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("error finding amount limit: %w", err)
+	}
+
+	override.UserID = userID
+	override.Currency = code
+	override.MinAmount = limit.Min
+	override.MaxAmount = limit.Max
+	override.SetBy = &adminID
+	if err := s.db.Save(&override).Error; err != nil {
+		return nil, fmt.Errorf("error saving amount limit: %w", err)
+	}
+
+	s.auditLogger.LogAdminAction(ctx, adminID, &userID, "", "", "set_payment_amount_limit", true, map[string]interface{}{
+		"currency":   code,
+		"min_amount": limit.Min,
+		"max_amount": limit.Max,
+	})
+
+	return &override, nil
+}
+
+// DeleteMerchantAmountLimit removes a merchant's override in a currency, so the configured
+// default applies again
+func (s *PaymentService) DeleteMerchantAmountLimit(ctx context.Context, adminID, userID uuid.UUID, currency models.Currency) error {
+	result := s.db.Where("user_id = ? AND currency = ?", userID, currency).Delete(&models.PaymentAmountLimit{})
+	if result.Error != nil {
+		return fmt.Errorf("error deleting amount limit: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAmountLimitNotFound
+	}
+
+	s.auditLogger.LogAdminAction(ctx, adminID, &userID, "", "", "delete_payment_amount_limit", true, map[string]interface{}{
+		"currency": currency,
+	})
+
+	return nil
+}
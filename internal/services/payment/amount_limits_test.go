@@ -0,0 +1,60 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Payments outside their currency's limits are refused, a merchant's override replaces the
+// default until it is deleted, and currencies without limits aren't bounded
+func TestPaymentAmountLimits(t *testing.T) {
+	service, _, db := newTestPaymentService(t)
+	service.SetAmountLimits(map[string]config.AmountLimit{"GHS": {Min: 1, Max: 500}})
+	merchant := testutil.CreateUser(t, db)
+	other := testutil.CreateUser(t, db)
+	adminID := uuid.New()
+	ctx := context.Background()
+
+	initiate := func(user *models.User, amount float64, code models.Currency) error {
+		_, _, err := service.InitiatePayment(ctx, user.ID, fakeProvider, amount, code, "buyer@example.com", "Buyer", nil, nil)
+		return err
+	}
+
+	assert.ErrorIs(t, initiate(merchant, 0.5, models.CurrencyGHS), ErrAmountBelowMinimum)
+	assert.ErrorIs(t, initiate(merchant, 500.01, models.CurrencyGHS), ErrAmountAboveMaximum)
+	assert.NoError(t, initiate(merchant, 500, models.CurrencyGHS))
+	assert.NoError(t, initiate(merchant, 1000000, models.CurrencyUSD))
+	_, err := service.CreatePaymentLink(merchant.ID, "Big order", "", 600, models.CurrencyGHS, "", nil)
+	assert.ErrorIs(t, err, ErrAmountAboveMaximum)
+
+	// Overrides must be a valid range
+	_, err = service.SetMerchantAmountLimit(ctx, adminID, merchant.ID, models.CurrencyGHS, config.AmountLimit{Min: 0, Max: 100})
+	assert.ErrorIs(t, err, ErrInvalidAmountLimit)
+	_, err = service.SetMerchantAmountLimit(ctx, adminID, merchant.ID, models.CurrencyGHS, config.AmountLimit{Min: 10, Max: 5})
+	assert.ErrorIs(t, err, ErrInvalidAmountLimit)
+
+	override, err := service.SetMerchantAmountLimit(ctx, adminID, merchant.ID, models.CurrencyGHS, config.AmountLimit{Min: 5, Max: 2000})
+	require.NoError(t, err)
+	assert.Equal(t, adminID, *override.SetBy)
+	_, err = service.SetMerchantAmountLimit(ctx, adminID, merchant.ID, models.CurrencyGHS, config.AmountLimit{Min: 5, Max: 1500})
+	require.NoError(t, err)
+	limits, err := service.ListMerchantAmountLimits(merchant.ID)
+	require.NoError(t, err)
+	require.Len(t, limits, 1, "setting an override again updates it")
+	assert.Equal(t, 1500.0, limits[0].MaxAmount)
+
+	assert.NoError(t, initiate(merchant, 1500, models.CurrencyGHS))
+	assert.ErrorIs(t, initiate(merchant, 2, models.CurrencyGHS), ErrAmountBelowMinimum)
+	assert.ErrorIs(t, initiate(other, 1500, models.CurrencyGHS), ErrAmountAboveMaximum, "overrides are per merchant")
+
+	require.NoError(t, service.DeleteMerchantAmountLimit(ctx, adminID, merchant.ID, models.CurrencyGHS))
+	assert.ErrorIs(t, service.DeleteMerchantAmountLimit(ctx, adminID, merchant.ID, models.CurrencyGHS), ErrAmountLimitNotFound)
+	assert.ErrorIs(t, initiate(merchant, 1500, models.CurrencyGHS), ErrAmountAboveMaximum)
+}
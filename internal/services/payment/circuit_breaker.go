@@ -0,0 +1,37 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/revaspay/backend/internal/circuitbreaker"
+	"github.com/revaspay/backend/internal/models"
+)
+
+// ErrProviderUnavailable is returned without calling the provider while its circuit breaker is
+// open. It wraps the breaker's *circuitbreaker.OpenError, which says when to try again.
+var ErrProviderUnavailable = errors.New("payment provider is temporarily unavailable")
+
+// SetCircuitBreakerSettings sets when provider circuit breakers open and how long they stay
+// open, resetting the breakers of providers already registered. Until it's called the service
+// uses circuitbreaker.DefaultSettings.
+func (s *PaymentService) SetCircuitBreakerSettings(settings circuitbreaker.Settings) {
+	s.breakerSettings = settings
+	for name := range s.providers {
+		s.breakers[name] = newProviderBreaker(name, settings)
+	}
+}
+
+func newProviderBreaker(name models.PaymentProvider, settings circuitbreaker.Settings) *circuitbreaker.Breaker {
+	return circuitbreaker.New("payment:"+string(name), settings)
+}
+
+// allowProviderCall reserves a call to provider through its circuit breaker, returning an error
+// wrapping ErrProviderUnavailable if the breaker is open
+func (s *PaymentService) allowProviderCall(provider models.PaymentProvider) (*circuitbreaker.Call, error) {
+	call, err := s.breakers[provider].Allow()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProviderUnavailable, err)
+	}
+	return call, nil
+}
@@ -0,0 +1,271 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidWebhookSignature is returned when a provider webhook isn't signed with the key of
+	// the account it's for
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+	// ErrProviderCredentialNotFound is returned when a merchant has no credentials for a provider
+	ErrProviderCredentialNotFound = errors.New("provider credential not found")
+
+	// ErrSecretKeyRequired is returned when storing provider credentials without a secret key
+	ErrSecretKeyRequired = errors.New("secret key is required")
+
+	// ErrAccountIDInUse is returned when another merchant's credentials have the same provider account ID
+	ErrAccountIDInUse = errors.New("account ID is already used by another merchant")
+
+	// ErrMerchantAccountsUnsupported is returned when storing credentials for a provider that
+	// can't act on merchants' own accounts
+	ErrMerchantAccountsUnsupported = errors.New("provider does not support merchant accounts")
+
+	// ErrMerchantAccountSplits is returned when splitting a payment collected into the
+	// merchant's own provider account, since the platform never holds the funds to split
+	ErrMerchantAccountSplits = errors.New("payments collected into a merchant's own account can't be split")
+)
+
+// ProviderCredentials are the keys to a merchant's own account with a payment provider
+type ProviderCredentials struct {
+	SecretKey string
+	PublicKey string
+}
+
+// AccountProviderFunc creates a provider that acts on the account credentials are for
+type AccountProviderFunc func(credentials ProviderCredentials) PaymentProvider
+
+// SignedWebhookProvider is implemented by providers that sign webhooks with the secret key of
+// the account the event is for
+type SignedWebhookProvider interface {
+	// WebhookAccount returns the provider's ID for the account a webhook is for, if the webhook
+	// says, and the reference of the payment it's about
+	WebhookAccount(data []byte) (accountID, reference string, err error)
+
+	// VerifyWebhookSignature reports whether a webhook was signed with the provider's secret key
+	VerifyWebhookSignature(data []byte, signature string) bool
+}
+
+// SetCredentialBox sets the box merchants' provider secret keys are encrypted with. Without
+// one, merchant credentials can't be stored or used.
+func (s *PaymentService) SetCredentialBox(box *utils.SecretBox) {
+	s.credentialBox = box
+}
+
+// RegisterAccountProvider lets merchants with credentials for a registered provider have
+// their payments made through their own account with it, rather than the platform's
+func (s *PaymentService) RegisterAccountProvider(name models.PaymentProvider, newProvider AccountProviderFunc) {
+	s.accountProviders[name] = newProvider
+}
+
+// providerFor returns the provider to make a merchant's payments with: one acting on the
+// merchant's own account when they have credentials for it, otherwise the platform's. It also
+// reports whether the provider is the merchant's own account.
+func (s *PaymentService) providerFor(name models.PaymentProvider, userID uuid.UUID) (PaymentProvider, bool, error) {
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, false, fmt.Errorf("unsupported payment provider: %s", name)
+	}
+	if _, ok := s.accountProviders[name]; !ok {
+		return provider, false, nil
+	}
+
+	credential, err := s.findCredential(s.db.Where("user_id = ? AND provider = ?", userID, name))
+	if err != nil || credential == nil {
+		return provider, false, err
+	}
+	accountProvider, err := s.accountProvider(credential)
+	return accountProvider, err == nil, err
+}
+
+// accountProvider creates a provider acting on the account a stored credential is for
+func (s *PaymentService) accountProvider(credential *models.ProviderCredential) (PaymentProvider, error) {
+	newProvider, ok := s.accountProviders[credential.Provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMerchantAccountsUnsupported, credential.Provider)
+	}
+	secretKey, err := s.credentialBox.Open(credential.EncryptedSecretKey, credentialContext(credential.UserID, credential.Provider))
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s credentials: %w", credential.Provider, err)
+	}
+	return newProvider(ProviderCredentials{SecretKey: secretKey, PublicKey: credential.PublicKey}), nil
+}
+
+// findCredential returns the credential matching query, or nil if there isn't one
+func (s *PaymentService) findCredential(query *gorm.DB) (*models.ProviderCredential, error) {
+	var credential models.ProviderCredential
+	err := query.First(&credential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error finding provider credentials: %w", err)
+	}
+	return &credential, nil
+}
+
+// VerifyWebhook checks a provider webhook's signature with the secret key of the account it's
+// for. A webhook about a known payment is checked with the key of the merchant who owns the
+// payment, and rejected if it names a provider account that isn't theirs; other webhooks are
+// checked with the key of the merchant whose provider account ID they name. Webhooks for
+// merchants without their own credentials are checked with the platform's key. Providers that
+// don't sign webhooks aren't checked.
+func (s *PaymentService) VerifyWebhook(name models.PaymentProvider, data []byte, signature string) error {
+	provider, ok := s.providers[name]
+	if !ok {
+		return fmt.Errorf("unsupported payment provider: %s", name)
+	}
+	verifier, ok := provider.(SignedWebhookProvider)
+	if !ok {
+		return nil
+	}
+
+	accountID, reference, err := verifier.WebhookAccount(data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhookSignature, err)
+	}
+	credential, err := s.webhookCredential(name, accountID, reference)
+	if err != nil {
+		return err
+	}
+	if credential != nil {
+		accountProvider, err := s.accountProvider(credential)
+		if err != nil {
+			return err
+		}
+		if accountVerifier, ok := accountProvider.(SignedWebhookProvider); ok {
+			verifier = accountVerifier
+		}
+	}
+
+	if !verifier.VerifyWebhookSignature(data, signature) {
+		return ErrInvalidWebhookSignature
+	}
+	return nil
+}
+
+// webhookCredential finds the merchant credential a webhook for an account or payment
+// reference must be signed with, or nil if it must be signed with the platform's key. The
+// payment's owner decides the credential, so one merchant's key can't sign webhooks about
+// another merchant's payments by naming their own account.
+func (s *PaymentService) webhookCredential(name models.PaymentProvider, accountID, reference string) (*models.ProviderCredential, error) {
+	var payment models.Payment
+	err := s.db.Select("user_id").Where("reference = ? AND provider = ?", reference, name).First(&payment).Error
+	if reference == "" || errors.Is(err, gorm.ErrRecordNotFound) {
+		if accountID == "" {
+			return nil, nil
+		}
+		return s.findCredential(s.db.Where("provider = ? AND account_id = ?", name, accountID))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error finding payment: %w", err)
+	}
+
+	credential, err := s.findCredential(s.db.Where("user_id = ? AND provider = ?", payment.UserID, name))
+	if err != nil || accountID == "" {
+		return credential, err
+	}
+	if credential != nil && credential.AccountID != nil {
+		if *credential.AccountID != accountID {
+			return nil, fmt.Errorf("%w: account %s is not the payment's merchant's", ErrInvalidWebhookSignature, accountID)
+		}
+		return credential, nil
+	}
+
+	// The merchant has no account ID of their own, so the account named mustn't be anyone else's
+	accountCredential, err := s.findCredential(s.db.Where("provider = ? AND account_id = ?", name, accountID))
+	if err != nil {
+		return nil, err
+	}
+	if accountCredential != nil && accountCredential.UserID != payment.UserID {
+		return nil, fmt.Errorf("%w: account %s is not the payment's merchant's", ErrInvalidWebhookSignature, accountID)
+	}
+	return credential, nil
+}
+
+// ListProviderCredentials returns the provider credentials stored for a merchant. Secret keys
+// aren't included.
+func (s *PaymentService) ListProviderCredentials(userID uuid.UUID) ([]models.ProviderCredential, error) {
+	var credentials []models.ProviderCredential
+	if err := s.db.Where("user_id = ?", userID).Order("provider").Find(&credentials).Error; err != nil {
+		return nil, fmt.Errorf("error listing provider credentials: %w", err)
+	}
+	return credentials, nil
+}
+
+// SetProviderCredential stores a merchant's credentials for their own account with a provider,
+// replacing any they had. The secret key is encrypted before it's stored.
+func (s *PaymentService) SetProviderCredential(ctx context.Context, adminID, userID uuid.UUID, name models.PaymentProvider, accountID string, credentials ProviderCredentials) (*models.ProviderCredential, error) {
+	if _, ok := s.accountProviders[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMerchantAccountsUnsupported, name)
+	}
+	credentials.SecretKey = strings.TrimSpace(credentials.SecretKey)
+	if credentials.SecretKey == "" {
+		return nil, ErrSecretKeyRequired
+	}
+	encrypted, err := s.credentialBox.Seal(credentials.SecretKey, credentialContext(userID, name))
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting %s credentials: %w", name, err)
+	}
+
+	credential, err := s.findCredential(s.db.Where("user_id = ? AND provider = ?", userID, name))
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		credential = &models.ProviderCredential{UserID: userID, Provider: name}
+	}
+	credential.AccountID = nil
+	if accountID = strings.TrimSpace(accountID); accountID != "" {
+		credential.AccountID = &accountID
+	}
+	credential.EncryptedSecretKey = encrypted
+	credential.PublicKey = strings.TrimSpace(credentials.PublicKey)
+	credential.SetBy = &adminID
+	if err := s.db.Save(credential).Error; err != nil {
+		if isDuplicateKey(err) {
+			return nil, ErrAccountIDInUse
+		}
+		return nil, fmt.Errorf("error saving provider credentials: %w", err)
+	}
+
+	s.auditLogger.LogAdminAction(ctx, adminID, &userID, "", "", "set_provider_credentials", true, map[string]interface{}{
+		"provider":   name,
+		"account_id": accountID,
+	})
+
+	return credential, nil
+}
+
+// DeleteProviderCredential removes a merchant's credentials for a provider, so their payments
+// go through the platform's account again
+func (s *PaymentService) DeleteProviderCredential(ctx context.Context, adminID, userID uuid.UUID, name models.PaymentProvider) error {
+	result := s.db.Where("user_id = ? AND provider = ?", userID, name).Delete(&models.ProviderCredential{})
+	if result.Error != nil {
+		return fmt.Errorf("error deleting provider credentials: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrProviderCredentialNotFound
+	}
+
+	s.auditLogger.LogAdminAction(ctx, adminID, &userID, "", "", "delete_provider_credentials", true, map[string]interface{}{
+		"provider": name,
+	})
+
+	return nil
+}
+
+// credentialContext binds a merchant's encrypted secret key to them and the provider, so it
+// can't be used for another merchant's account
+func credentialContext(userID uuid.UUID, name models.PaymentProvider) string {
+	return userID.String() + ":" + string(name)
+}
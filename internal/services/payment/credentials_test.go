@@ -0,0 +1,199 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/payment/providers/paystack"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/revaspay/backend/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signPaystack signs a webhook body the way Paystack does
+func signPaystack(body []byte, secretKey string) string {
+	mac := hmac.New(sha512.New, []byte(secretKey))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newTestSecretBox returns a secret box with a random key
+func newTestSecretBox(t *testing.T) *utils.SecretBox {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	box, err := utils.NewSecretBox(key)
+	require.NoError(t, err)
+	return box
+}
+
+// paystackWebhook returns a Paystack charge.success webhook body for a payment reference,
+// naming a subaccount if one is given
+func paystackWebhook(t *testing.T, reference, subaccount string) []byte {
+	body, err := json.Marshal(map[string]interface{}{
+		"event": "charge.success",
+		"data": map[string]interface{}{
+			"reference":  reference,
+			"subaccount": map[string]interface{}{"subaccount_code": subaccount},
+		},
+	})
+	require.NoError(t, err)
+	return body
+}
+
+func TestMerchantProviderCredentials(t *testing.T) {
+	var mu sync.Mutex
+	var authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": true,
+			"data":   map[string]interface{}{"authorization_url": "https://checkout.paystack.com/abc"},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	box := newTestSecretBox(t)
+	db := testutil.NewDB(t)
+	platform := paystack.NewPaystackProvider(paystack.PaystackConfig{SecretKey: "sk_platform", BaseURL: server.URL})
+	service := NewPaymentService(db, wallet.NewWalletService(db))
+	service.RegisterProvider(models.PaymentProviderPaystack, platform)
+	service.RegisterAccountProvider(models.PaymentProviderPaystack, func(credentials ProviderCredentials) PaymentProvider {
+		return platform.WithKeys(credentials.SecretKey, credentials.PublicKey)
+	})
+	service.SetCredentialBox(box)
+
+	admin := testutil.CreateUser(t, db)
+	merchant := testutil.CreateUser(t, db)
+	other := testutil.CreateUser(t, db)
+	ctx := context.Background()
+
+	_, err := service.SetProviderCredential(ctx, admin.ID, merchant.ID, models.PaymentProviderPaystack, "ACCT_merchant", ProviderCredentials{})
+	assert.ErrorIs(t, err, ErrSecretKeyRequired)
+	_, err = service.SetProviderCredential(ctx, admin.ID, merchant.ID, models.PaymentProviderStripe, "", ProviderCredentials{SecretKey: "sk_stripe"})
+	assert.ErrorIs(t, err, ErrMerchantAccountsUnsupported)
+
+	credential, err := service.SetProviderCredential(ctx, admin.ID, merchant.ID, models.PaymentProviderPaystack, "ACCT_merchant", ProviderCredentials{SecretKey: "sk_merchant"})
+	require.NoError(t, err)
+	assert.NotContains(t, credential.EncryptedSecretKey, "sk_merchant")
+	_, err = service.SetProviderCredential(ctx, admin.ID, other.ID, models.PaymentProviderPaystack, "ACCT_merchant", ProviderCredentials{SecretKey: "sk_other"})
+	assert.ErrorIs(t, err, ErrAccountIDInUse)
+
+	// Each merchant's payments are made through their own account, if they have one
+	merchantPayment, _, err := service.InitiatePayment(ctx, merchant.ID, models.PaymentProviderPaystack, 100, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	otherPayment, _, err := service.InitiatePayment(ctx, other.ID, models.PaymentProviderPaystack, 100, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer sk_merchant", "Bearer sk_platform"}, authorizations)
+
+	// Webhooks are verified with the key of the subaccount they name, or of the merchant whose
+	// payment they're about
+	bySubaccount := paystackWebhook(t, "REV-unknown", "ACCT_merchant")
+	assert.NoError(t, service.VerifyWebhook(models.PaymentProviderPaystack, bySubaccount, signPaystack(bySubaccount, "sk_merchant")))
+	assert.ErrorIs(t, service.VerifyWebhook(models.PaymentProviderPaystack, bySubaccount, signPaystack(bySubaccount, "sk_platform")), ErrInvalidWebhookSignature)
+
+	byReference := paystackWebhook(t, merchantPayment.Reference, "")
+	assert.NoError(t, service.VerifyWebhook(models.PaymentProviderPaystack, byReference, signPaystack(byReference, "sk_merchant")))
+	assert.ErrorIs(t, service.VerifyWebhook(models.PaymentProviderPaystack, byReference, ""), ErrInvalidWebhookSignature)
+
+	platformWebhook := paystackWebhook(t, otherPayment.Reference, "")
+	assert.NoError(t, service.VerifyWebhook(models.PaymentProviderPaystack, platformWebhook, signPaystack(platformWebhook, "sk_platform")))
+	assert.ErrorIs(t, service.VerifyWebhook(models.PaymentProviderPaystack, platformWebhook, signPaystack(platformWebhook, "sk_merchant")), ErrInvalidWebhookSignature)
+
+	// Without their credentials, the merchant is back on the platform account
+	require.NoError(t, service.DeleteProviderCredential(ctx, admin.ID, merchant.ID, models.PaymentProviderPaystack))
+	assert.ErrorIs(t, service.DeleteProviderCredential(ctx, admin.ID, merchant.ID, models.PaymentProviderPaystack), ErrProviderCredentialNotFound)
+	assert.NoError(t, service.VerifyWebhook(models.PaymentProviderPaystack, byReference, signPaystack(byReference, "sk_platform")))
+}
+
+func TestWebhookForAnotherMerchantsPaymentIsRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": true,
+			"data":   map[string]interface{}{"authorization_url": "https://checkout.paystack.com/abc"},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	db := testutil.NewDB(t)
+	platform := paystack.NewPaystackProvider(paystack.PaystackConfig{SecretKey: "sk_platform", BaseURL: server.URL})
+	service := NewPaymentService(db, wallet.NewWalletService(db))
+	service.RegisterProvider(models.PaymentProviderPaystack, platform)
+	service.RegisterAccountProvider(models.PaymentProviderPaystack, func(credentials ProviderCredentials) PaymentProvider {
+		return platform.WithKeys(credentials.SecretKey, credentials.PublicKey)
+	})
+	service.SetCredentialBox(newTestSecretBox(t))
+
+	admin := testutil.CreateUser(t, db)
+	merchantA := testutil.CreateUser(t, db)
+	merchantB := testutil.CreateUser(t, db)
+	platformMerchant := testutil.CreateUser(t, db)
+	ctx := context.Background()
+
+	_, err := service.SetProviderCredential(ctx, admin.ID, merchantA.ID, models.PaymentProviderPaystack, "ACCT_a", ProviderCredentials{SecretKey: "sk_a"})
+	require.NoError(t, err)
+	_, err = service.SetProviderCredential(ctx, admin.ID, merchantB.ID, models.PaymentProviderPaystack, "ACCT_b", ProviderCredentials{SecretKey: "sk_b"})
+	require.NoError(t, err)
+
+	paymentB, _, err := service.InitiatePayment(ctx, merchantB.ID, models.PaymentProviderPaystack, 100, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	platformPayment, _, err := service.InitiatePayment(ctx, platformMerchant.ID, models.PaymentProviderPaystack, 100, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+
+	// A's key can't sign a webhook about B's payment, whether or not it names A's account
+	namingA := paystackWebhook(t, paymentB.Reference, "ACCT_a")
+	assert.ErrorIs(t, service.VerifyWebhook(models.PaymentProviderPaystack, namingA, signPaystack(namingA, "sk_a")), ErrInvalidWebhookSignature)
+	unnamed := paystackWebhook(t, paymentB.Reference, "")
+	assert.ErrorIs(t, service.VerifyWebhook(models.PaymentProviderPaystack, unnamed, signPaystack(unnamed, "sk_a")), ErrInvalidWebhookSignature)
+
+	// Nor can it sign one about a payment collected into the platform's account
+	forPlatform := paystackWebhook(t, platformPayment.Reference, "ACCT_a")
+	assert.ErrorIs(t, service.VerifyWebhook(models.PaymentProviderPaystack, forPlatform, signPaystack(forPlatform, "sk_a")), ErrInvalidWebhookSignature)
+
+	// B's own webhooks are still accepted
+	namingB := paystackWebhook(t, paymentB.Reference, "ACCT_b")
+	assert.NoError(t, service.VerifyWebhook(models.PaymentProviderPaystack, namingB, signPaystack(namingB, "sk_b")))
+}
+
+func TestMerchantAccountPaymentsAreNotCredited(t *testing.T) {
+	service, provider, db := newTestPaymentService(t)
+	service.RegisterAccountProvider(fakeProvider, func(ProviderCredentials) PaymentProvider { return provider })
+	service.SetCredentialBox(newTestSecretBox(t))
+
+	admin := testutil.CreateUser(t, db)
+	merchant := testutil.CreateUser(t, db)
+	ctx := context.Background()
+	_, err := service.SetProviderCredential(ctx, admin.ID, merchant.ID, fakeProvider, "", ProviderCredentials{SecretKey: "sk_merchant"})
+	require.NoError(t, err)
+
+	_, _, err = service.InitiatePayment(ctx, merchant.ID, fakeProvider, 100, models.CurrencyGHS, "buyer@example.com", "Buyer", nil,
+		[]SplitInput{{PlatformFee: true, Amount: 10}})
+	assert.ErrorIs(t, err, ErrMerchantAccountSplits)
+
+	payment, _, err := service.InitiatePayment(ctx, merchant.ID, fakeProvider, 100, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	assert.True(t, payment.MerchantAccount)
+
+	provider.SetStatus(payment.Reference, models.PaymentStatusCompleted)
+	verified, err := service.VerifyPayment(ctx, payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusCompleted, verified.Status)
+	assert.Zero(t, walletBalance(t, db, merchant, models.CurrencyGHS))
+
+	var ledgerEntries int64
+	require.NoError(t, db.Model(&models.WalletLedgerEntry{}).Where("reference = ?", payment.Reference).Count(&ledgerEntries).Error)
+	assert.Zero(t, ledgerEntries)
+}
@@ -0,0 +1,105 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/utils"
+)
+
+// DefaultPaymentExpiry is how long a pending payment can be paid until SetPaymentExpiry is called
+const DefaultPaymentExpiry = 30 * time.Minute
+
+// expireBatchSize bounds how many payments one ExpirePayments call expires
+const expireBatchSize = 500
+
+// SetPaymentExpiry sets how long a pending payment can be paid before it expires, overridden
+// per provider by byProvider. A duration of zero or less means payments never expire.
+func (s *PaymentService) SetPaymentExpiry(expiry time.Duration, byProvider map[string]time.Duration) {
+	s.paymentExpiry = expiry
+	s.providerExpiry = byProvider
+}
+
+// expiresAt returns when a payment through provider started at from expires, or nil if it doesn't
+func (s *PaymentService) expiresAt(provider models.PaymentProvider, from time.Time) *time.Time {
+	expiry := s.paymentExpiry
+	if override, ok := s.providerExpiry[string(provider)]; ok {
+		expiry = override
+	}
+	if expiry <= 0 {
+		return nil
+	}
+	expiresAt := from.Add(expiry)
+	return &expiresAt
+}
+
+// isExpired reports whether a payment has expired, or is pending past its expiry and
+// waiting for ExpirePayments to mark it
+func isExpired(payment *models.Payment, now time.Time) bool {
+	if payment.Status == models.PaymentStatusExpired {
+		return true
+	}
+	return payment.Status == models.PaymentStatusPending && payment.ExpiresAt != nil && !now.Before(*payment.ExpiresAt)
+}
+
+// paidBeforeExpiry reports whether the provider says an expired payment was paid while it was
+// still payable. Providers that don't say when a payment was paid are never trusted with one.
+func paidBeforeExpiry(payment, verified *models.Payment) bool {
+	return payment.ExpiresAt != nil && verified.PaidAt != nil && !verified.PaidAt.After(*payment.ExpiresAt)
+}
+
+// ExpirePayments marks pending payments whose expiry is before now as expired and notifies
+// their merchants. It returns how many payments were expired; more may be left when that's
+// the batch size.
+func (s *PaymentService) ExpirePayments(ctx context.Context, now time.Time) (int, error) {
+	var payments []models.Payment
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND expires_at <= ?", models.PaymentStatusPending, now).
+		Order("expires_at").
+		Limit(expireBatchSize).
+		Find(&payments).Error; err != nil {
+		return 0, fmt.Errorf("error finding expired payments: %w", err)
+	}
+
+	expired := 0
+	for i := range payments {
+		payment := &payments[i]
+		// Verification may have completed the payment since it was read
+		result := s.db.WithContext(ctx).Model(&models.Payment{}).
+			Where("id = ? AND status = ?", payment.ID, models.PaymentStatusPending).
+			Update("status", models.PaymentStatusExpired)
+		if result.Error != nil {
+			return expired, fmt.Errorf("error expiring payment %s: %w", payment.Reference, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		expired++
+
+		payment.Status = models.PaymentStatusExpired
+		s.auditStatusChange(payment, models.PaymentStatusPending, utils.AuditActorSystem, nil, "not paid before expiring")
+		s.dispatchPaymentExpired(payment)
+	}
+	return expired, nil
+}
+
+// dispatchPaymentExpired notifies the merchant's webhook endpoints that a payment expired
+func (s *PaymentService) dispatchPaymentExpired(payment *models.Payment) {
+	if s.webhookService == nil {
+		return
+	}
+	if err := s.webhookService.Dispatch(payment.UserID, models.WebhookEventPaymentExpired, map[string]interface{}{
+		"payment_id": payment.ID.String(),
+		"reference":  payment.Reference,
+		"amount":     payment.Amount,
+		"currency":   string(payment.Currency),
+		"provider":   string(payment.Provider),
+		"expires_at": payment.ExpiresAt,
+		"status":     string(payment.Status),
+	}); err != nil {
+		log.Printf("Failed to dispatch payment expired webhook for %s: %v", payment.Reference, err)
+	}
+}
@@ -0,0 +1,74 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpirePayments(t *testing.T) {
+	service, provider, db := newTestPaymentService(t)
+	service.SetPaymentExpiry(time.Hour, map[string]time.Duration{string(fakeProvider): 10 * time.Minute})
+	user := testutil.CreateUser(t, db)
+	ctx := context.Background()
+
+	payment, _, err := service.InitiatePayment(ctx, user.ID, fakeProvider, 100, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, payment.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), *payment.ExpiresAt, time.Minute)
+
+	expired, err := service.ExpirePayments(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+
+	// Past its expiry the public status shows it expired before the job marks it
+	expiresAt := time.Now().Add(-time.Minute)
+	require.NoError(t, db.Model(payment).Update("expires_at", expiresAt).Error)
+	summary, err := service.GetPaymentStatusSummary(payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusExpired, summary.Status)
+
+	expired, err = service.ExpirePayments(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	stored, err := service.GetPaymentByReference(payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusExpired, stored.Status)
+
+	// Still unpaid at the provider, it stays expired
+	verified, err := service.VerifyPayment(ctx, payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusExpired, verified.Status)
+
+	// Paid after it expired: not credited
+	paidAt := expiresAt.Add(time.Second)
+	provider.VerifyFunc = func(_ context.Context, reference string) (*models.Payment, error) {
+		return &models.Payment{Reference: reference, Status: models.PaymentStatusCompleted, PaidAt: &paidAt}, nil
+	}
+	verified, err = service.VerifyPayment(ctx, payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusExpired, verified.Status)
+	assert.Zero(t, walletBalance(t, db, user, models.CurrencyGHS))
+
+	// Paid before it expired, with the provider confirming late: credited
+	paidAt = expiresAt.Add(-time.Second)
+	verified, err = service.VerifyPayment(ctx, payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusCompleted, verified.Status)
+	assert.Equal(t, 100.0, walletBalance(t, db, user, models.CurrencyGHS))
+}
+
+func TestSetPaymentExpiryDisabled(t *testing.T) {
+	service, _, db := newTestPaymentService(t)
+	service.SetPaymentExpiry(0, nil)
+	user := testutil.CreateUser(t, db)
+
+	payment, _, err := service.InitiatePayment(context.Background(), user.ID, fakeProvider, 100, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, payment.ExpiresAt)
+}
@@ -1,7 +1,10 @@
 package payment
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,122 +12,177 @@ import (
 	"github.com/revaspay/backend/internal/queue"
 	"github.com/revaspay/backend/internal/services/crypto"
 	"github.com/revaspay/backend/internal/services/exchange"
+	"github.com/revaspay/backend/internal/services/screening"
 	"github.com/revaspay/backend/internal/utils"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrPaymentNotFound is returned when a payment does not exist or is not owned by the user
+	ErrPaymentNotFound = errors.New("international payment not found")
+
+	// ErrNoBankAccount is returned when the user has no active Ghanaian bank account to debit
+	ErrNoBankAccount = errors.New("no active Ghanaian bank account found")
+
+	// ErrNoCryptoWallet is returned when the user has no active wallet on the payout network
+	ErrNoCryptoWallet = errors.New("no active crypto wallet found")
+)
+
+// payoutWalletTypes maps the networks we can pay vendors on to the wallet type that sends the funds
+var payoutWalletTypes = map[string]string{
+	crypto.NetworkBase: "BASE",
+}
+
 // InternationalPaymentService handles international payments using Base blockchain
 type InternationalPaymentService struct {
 	db              *gorm.DB
-	baseService     *crypto.BaseService
 	exchangeService *exchange.ExchangeRateService
+	screeningSvc    *screening.Service
 	queue           *queue.Queue
 }
 
 // NewInternationalPaymentService creates a new international payment service
-func NewInternationalPaymentService(db *gorm.DB) *InternationalPaymentService {
+func NewInternationalPaymentService(db *gorm.DB, jobQueue *queue.Queue, screeningSvc *screening.Service) *InternationalPaymentService {
 	return &InternationalPaymentService{
 		db:              db,
-		baseService:     crypto.NewBaseService(db),
 		exchangeService: exchange.NewExchangeRateService(), // Using free ExchangeRate-API (no API key needed)
-		queue:           queue.NewQueue(db),
+		screeningSvc:    screeningSvc,
+		queue:           jobQueue,
 	}
 }
 
-// PaymentRequest represents a request to make an international payment
-type PaymentRequest struct {
+// CreatePaymentRequest represents a request to make an international payment
+type CreatePaymentRequest struct {
 	VendorName    string  `json:"vendor_name"`
 	VendorAddress string  `json:"vendor_address"`
-	Amount        float64 `json:"amount"`
+	Network       string  `json:"network"`
+	AmountCedis   float64 `json:"amount_cedis"`
 	Description   string  `json:"description"`
 }
 
-// ProcessPayment handles the full payment flow from bank to blockchain
-func (s *InternationalPaymentService) ProcessPayment(userID uuid.UUID, req PaymentRequest) (*database.InternationalPayment, error) {
-	// Start database transaction
+// Create records an international payment together with its bank debit and on-chain
+// transfer, then enqueues it for processing. All three records are written in one
+// transaction so a payment never exists without its linked transactions.
+// If the vendor address fails screening, the payment is recorded as blocked and
+// screening.ErrAddressBlocked is returned along with it.
+func (s *InternationalPaymentService) Create(ctx context.Context, userID uuid.UUID, req CreatePaymentRequest) (*database.InternationalPayment, error) {
+	network := strings.ToLower(req.Network)
+	if network == "" {
+		network = crypto.NetworkBase
+	}
+
+	if err := crypto.ValidateAddress(network, req.VendorAddress); err != nil {
+		return nil, err
+	}
+
+	walletType, ok := payoutWalletTypes[network]
+	if !ok {
+		return nil, fmt.Errorf("%w: payouts are not available on %s", crypto.ErrUnsupportedNetwork, network)
+	}
+
+	verdict, err := s.screeningSvc.ScreenAddress(network, req.VendorAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to screen vendor address: %w", err)
+	}
+	if verdict.Flagged {
+		return s.recordBlockedPayment(ctx, userID, network, req, verdict)
+	}
+
+	// Get real-time exchange rate from GHS to USDC (using USD as proxy)
+	exchangeRate, err := s.exchangeService.GetExchangeRate("GHS", "USD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+	amountCrypto := fmt.Sprintf("%.6f", req.AmountCedis*exchangeRate)
+
+	reference := utils.GenerateReference("IP")
+
 	tx := s.db.Begin()
 
 	// Get user's bank account
 	var bankAccount database.BankAccount
 	if err := tx.Where("user_id = ? AND is_active = ? AND country = ?", userID, true, "Ghana").First(&bankAccount).Error; err != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("no active Ghanaian bank account found: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoBankAccount
+		}
+		return nil, fmt.Errorf("error getting bank account: %w", err)
 	}
 
-	// Get user's Base wallet
+	// Get user's wallet on the payout network
 	var wallet database.CryptoWallet
-	if err := tx.Where("user_id = ? AND wallet_type = ? AND is_active = ?", userID, "BASE", true).First(&wallet).Error; err != nil {
+	if err := tx.Where("user_id = ? AND wallet_type = ? AND is_active = ?", userID, walletType, true).First(&wallet).Error; err != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("no active Base wallet found: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoCryptoWallet
+		}
+		return nil, fmt.Errorf("error getting crypto wallet: %w", err)
 	}
 
-	// Create a unique reference for this payment
-	reference := utils.GenerateReference("IP")
-
 	// Create bank transaction record
 	bankTx := database.GhanaBankTransaction{
 		UserID:          userID,
 		BankAccountID:   bankAccount.ID,
 		TransactionType: "international_payment",
-		Amount:          req.Amount,
+		Type:            "debit",
+		Amount:          req.AmountCedis,
 		Fee:             0, // No conversion fee as per requirements
 		Currency:        "GHS",
 		Status:          "pending",
 		Reference:       reference,
+		Description:     req.Description,
 	}
-
 	if err := tx.Create(&bankTx).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, fmt.Errorf("error creating bank transaction: %w", err)
 	}
 
-	// Create crypto transaction record (will be updated with actual hash later)
+	// Create crypto transaction record (the hash is filled in once it is broadcast)
 	cryptoTx := database.CryptoTransaction{
-		UserID:      userID,
-		WalletID:    wallet.ID,
-		FromAddress: wallet.Address,
-		ToAddress:   req.VendorAddress,
-		Amount:      fmt.Sprintf("%f", req.Amount), // This would be converted to crypto amount in production
-		Currency:    "USDC",                        // Assuming USDC stablecoin
-		Status:      "pending",
+		UserID:           userID,
+		WalletID:         wallet.ID,
+		FromAddress:      wallet.Address,
+		ToAddress:        req.VendorAddress,
+		RecipientAddress: req.VendorAddress,
+		Amount:           amountCrypto,
+		Currency:         "USDC",
+		TokenSymbol:      "USDC",
+		Type:             "send",
+		Status:           "created",
 	}
-
 	if err := tx.Create(&cryptoTx).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, fmt.Errorf("error creating crypto transaction: %w", err)
 	}
 
-	// Get real-time exchange rate from GHS to USDC (using USD as proxy)
-	exchangeRate, err := s.exchangeService.GetExchangeRate("GHS", "USD")
-	if err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to get exchange rate: %w", err)
-	}
-
-	// Convert amount from GHS to USDC
-	amountCrypto, err := s.exchangeService.ConvertAmount(req.Amount, "GHS", "USD")
-	if err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to convert amount: %w", err)
-	}
-
-	// Create international payment record
 	payment := database.InternationalPayment{
 		UserID:            userID,
 		BankTransactionID: bankTx.ID,
 		CryptoTxID:        cryptoTx.ID,
 		VendorName:        req.VendorName,
 		VendorAddress:     req.VendorAddress,
-		AmountCedis:       req.Amount,
-		AmountCrypto:      fmt.Sprintf("%f", amountCrypto),
+		Network:           network,
+		ScreeningResult:   "clear",
+		AmountCedis:       req.AmountCedis,
+		AmountCrypto:      amountCrypto,
 		ExchangeRate:      exchangeRate,
+		Reference:         reference,
 		Status:            "initiated",
 		Description:       req.Description,
 	}
-
 	if err := tx.Create(&payment).Error; err != nil {
 		tx.Rollback()
-		return nil, err
+		return nil, fmt.Errorf("error creating international payment: %w", err)
+	}
+
+	// Link the crypto transaction back to the payment
+	if err := tx.Model(&cryptoTx).Update("international_payment_id", payment.ID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("error linking crypto transaction: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("error committing international payment: %w", err)
 	}
 
 	// Queue payment processing job
@@ -133,60 +191,83 @@ func (s *InternationalPaymentService) ProcessPayment(userID uuid.UUID, req Payme
 		UserID:           userID,
 		BankAccountID:    bankAccount.ID,
 		WalletID:         wallet.ID,
+		VendorName:       req.VendorName,
 		RecipientAddress: req.VendorAddress,
-		Amount:           req.Amount,
-		Currency:         "USDC",
+		Amount:           req.AmountCedis,
+		Currency:         "GHS",
 		Description:      req.Description,
 		Reference:        reference,
 	}
 
-	jobID, err := s.queue.EnqueueJob(queue.JobTypeProcessPayment, payload)
-	if err != nil {
-		// Update payment status to failed if we couldn't queue the job
+	if _, err := s.queue.EnqueueJob(queue.JobTypeProcessPayment, payload); err != nil {
+		// Mark the payment failed if we couldn't queue the job
 		s.updatePaymentStatus(payment.ID, "failed", fmt.Sprintf("Failed to queue payment job: %v", err))
 		return nil, fmt.Errorf("failed to queue payment job: %w", err)
 	}
 
-	// Update payment with job ID
-	if err := s.db.Model(&payment).Updates(map[string]interface{}{
-		"status":     "queued",
-		"updated_at": time.Now(),
-		"reference":  fmt.Sprintf("%s:job:%s", reference, jobID),
-	}).Error; err != nil {
-		return nil, fmt.Errorf("failed to update payment with job ID: %w", err)
-	}
+	s.updatePaymentStatus(payment.ID, "queued", "")
+	payment.Status = "queued"
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		return nil, err
+	return &payment, nil
+}
+
+// recordBlockedPayment stores a refused payment without any bank or crypto transactions and audits it
+func (s *InternationalPaymentService) recordBlockedPayment(ctx context.Context, userID uuid.UUID, network string, req CreatePaymentRequest, verdict *screening.Verdict) (*database.InternationalPayment, error) {
+	payment := database.InternationalPayment{
+		UserID:          userID,
+		VendorName:      req.VendorName,
+		VendorAddress:   req.VendorAddress,
+		Network:         network,
+		ScreeningResult: "flagged",
+		AmountCedis:     req.AmountCedis,
+		Reference:       utils.GenerateReference("IP"),
+		Status:          "blocked",
+		Description:     req.Description,
+		Error:           fmt.Sprintf("vendor address flagged by %s: %s", verdict.Source, verdict.Reason),
+	}
+	if err := s.db.Create(&payment).Error; err != nil {
+		return nil, fmt.Errorf("error recording blocked payment: %w", err)
 	}
 
-	return &payment, nil
+	s.screeningSvc.RecordBlocked(ctx, userID, "international_payment", payment.ID, verdict)
+
+	return &payment, screening.ErrAddressBlocked
 }
 
 // updatePaymentStatus updates the status of a payment
 func (s *InternationalPaymentService) updatePaymentStatus(paymentID uuid.UUID, status string, reason string) {
 	s.db.Model(&database.InternationalPayment{}).Where("id = ?", paymentID).Updates(map[string]interface{}{
 		"status":     status,
-		"reason":     reason,
+		"error":      reason,
 		"updated_at": time.Now(),
 	})
 }
 
-// GetPayment retrieves a specific international payment
-func (s *InternationalPaymentService) GetPayment(paymentID uuid.UUID) (*database.InternationalPayment, error) {
+// GetPayment retrieves a specific international payment owned by the user
+func (s *InternationalPaymentService) GetPayment(paymentID, userID uuid.UUID) (*database.InternationalPayment, error) {
 	var payment database.InternationalPayment
-	if err := s.db.First(&payment, paymentID).Error; err != nil {
-		return nil, err
+	if err := s.db.Where("id = ? AND user_id = ?", paymentID, userID).First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, fmt.Errorf("error getting international payment: %w", err)
 	}
 	return &payment, nil
 }
 
-// GetPayments retrieves all international payments for a user
-func (s *InternationalPaymentService) GetPayments(userID uuid.UUID) ([]database.InternationalPayment, error) {
+// GetPayments retrieves a page of international payments for a user
+func (s *InternationalPaymentService) GetPayments(userID uuid.UUID, page, pageSize int) ([]database.InternationalPayment, int64, error) {
 	var payments []database.InternationalPayment
-	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&payments).Error; err != nil {
-		return nil, err
+	var total int64
+
+	query := s.db.Model(&database.InternationalPayment{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error counting international payments: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&payments).Error; err != nil {
+		return nil, 0, fmt.Errorf("error getting international payments: %w", err)
 	}
-	return payments, nil
+	return payments, total, nil
 }
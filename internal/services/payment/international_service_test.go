@@ -0,0 +1,100 @@
+package payment
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/screening"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const vendorAddress = "0x1234567890abcdef1234567890abcdef12345678"
+
+// roundTripFunc serves HTTP requests from a function
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// newTestInternationalService returns an international payment service whose exchange rate
+// requests are answered with 1 GHS = 0.08 USD
+func newTestInternationalService(t *testing.T) (*InternationalPaymentService, *gorm.DB) {
+	transport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"result":"success","base":"GHS","rates":{"USD":0.08}}`)),
+			Request:    r,
+		}, nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = transport })
+
+	db := testutil.NewDB(t)
+	require.NoError(t, db.AutoMigrate(&queue.Job{}))
+	return NewInternationalPaymentService(db, queue.NewQueue(db), screening.NewService(db, nil)), db
+}
+
+// A payment is recorded with its bank debit and on-chain transfer linked to it, and a payment
+// to a blocked address is recorded without either
+func TestCreateInternationalPayment(t *testing.T) {
+	service, db := newTestInternationalService(t)
+	user := testutil.CreateUser(t, db)
+	ctx := context.Background()
+	req := CreatePaymentRequest{VendorName: "Acme Ltd", VendorAddress: vendorAddress, AmountCedis: 500, Description: "Invoice 42"}
+
+	_, err := service.Create(ctx, user.ID, req)
+	assert.ErrorIs(t, err, ErrNoBankAccount)
+
+	account := database.BankAccount{UserID: user.ID, AccountNumber: "0123456789", BankCode: "GCB", Country: "Ghana", IsActive: true}
+	require.NoError(t, db.Create(&account).Error)
+	_, err = service.Create(ctx, user.ID, req)
+	assert.ErrorIs(t, err, ErrNoCryptoWallet)
+
+	wallet := database.CryptoWallet{UserID: user.ID, Address: "0xaaaabbbbccccddddeeeeffff0000111122223333", WalletType: "BASE", IsActive: true}
+	require.NoError(t, db.Create(&wallet).Error)
+	payment, err := service.Create(ctx, user.ID, req)
+	require.NoError(t, err)
+	assert.Equal(t, "queued", payment.Status)
+	assert.Equal(t, "base", payment.Network)
+	assert.Equal(t, "clear", payment.ScreeningResult)
+	assert.Equal(t, "40.000000", payment.AmountCrypto)
+
+	var bankTx database.GhanaBankTransaction
+	require.NoError(t, db.First(&bankTx, "id = ?", payment.BankTransactionID).Error)
+	assert.Equal(t, account.ID, bankTx.BankAccountID)
+	assert.Equal(t, payment.Reference, bankTx.Reference)
+	assert.Equal(t, 500.0, bankTx.Amount)
+
+	var cryptoTx database.CryptoTransaction
+	require.NoError(t, db.First(&cryptoTx, "id = ?", payment.CryptoTxID).Error)
+	assert.Equal(t, payment.ID, cryptoTx.InternationalPaymentID)
+	assert.Equal(t, vendorAddress, cryptoTx.ToAddress)
+
+	stored, err := service.GetPayment(payment.ID, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "queued", stored.Status)
+	_, err = service.GetPayment(payment.ID, testutil.CreateUser(t, db).ID)
+	assert.ErrorIs(t, err, ErrPaymentNotFound)
+
+	require.NoError(t, db.Create(&models.BlockedAddress{Network: "base", Address: screening.NormalizeAddress("base", vendorAddress), Reason: "sanctioned"}).Error)
+	blocked, err := service.Create(ctx, user.ID, req)
+	assert.ErrorIs(t, err, screening.ErrAddressBlocked)
+	require.NotNil(t, blocked)
+	assert.Equal(t, "blocked", blocked.Status)
+	assert.Equal(t, "flagged", blocked.ScreeningResult)
+
+	var bankTxs, cryptoTxs int64
+	require.NoError(t, db.Model(&database.GhanaBankTransaction{}).Count(&bankTxs).Error)
+	require.NoError(t, db.Model(&database.CryptoTransaction{}).Count(&cryptoTxs).Error)
+	assert.Equal(t, int64(1), bankTxs, "blocked payments aren't debited")
+	assert.Equal(t, int64(1), cryptoTxs, "blocked payments aren't sent")
+}
@@ -0,0 +1,73 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/revaspay/backend/internal/config"
+)
+
+// ErrMetadataTooLarge is returned when payment or payment link metadata exceeds the configured
+// size, key count or nesting depth
+var ErrMetadataTooLarge = errors.New("metadata is too large")
+
+// SetMetadataLimits sets the bounds on merchant-supplied metadata. Until it's called the
+// service uses config.DefaultMetadataLimits.
+func (s *PaymentService) SetMetadataLimits(limits config.MetadataLimits) {
+	s.metadataLimits = limits
+}
+
+// validateMetadata checks merchant-supplied metadata against the service's limits. A limit of
+// zero or less isn't enforced.
+func (s *PaymentService) validateMetadata(metadata map[string]interface{}) error {
+	if metadata == nil {
+		return nil
+	}
+	limits := s.metadataLimits
+
+	if limits.MaxDepth > 0 || limits.MaxKeys > 0 {
+		depth, keys := metadataShape(metadata)
+		if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+			return fmt.Errorf("%w: nested %d levels deep, the maximum is %d", ErrMetadataTooLarge, depth, limits.MaxDepth)
+		}
+		if limits.MaxKeys > 0 && keys > limits.MaxKeys {
+			return fmt.Errorf("%w: %d keys, the maximum is %d", ErrMetadataTooLarge, keys, limits.MaxKeys)
+		}
+	}
+
+	if limits.MaxBytes > 0 {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("error encoding metadata: %w", err)
+		}
+		if len(encoded) > limits.MaxBytes {
+			return fmt.Errorf("%w: %d bytes, the maximum is %d", ErrMetadataTooLarge, len(encoded), limits.MaxBytes)
+		}
+	}
+
+	return nil
+}
+
+// metadataShape returns how deeply a decoded JSON value nests objects and arrays, and how many
+// object keys it holds at every level
+func metadataShape(value interface{}) (depth, keys int) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			childDepth, childKeys := metadataShape(child)
+			depth = max(depth, childDepth)
+			keys += childKeys
+		}
+		return depth + 1, keys + len(v)
+	case []interface{}:
+		for _, child := range v {
+			childDepth, childKeys := metadataShape(child)
+			depth = max(depth, childDepth)
+			keys += childKeys
+		}
+		return depth + 1, keys
+	default:
+		return 0, 0
+	}
+}
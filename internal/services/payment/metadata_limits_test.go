@@ -0,0 +1,43 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/revaspay/backend/internal/config"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	s := &PaymentService{metadataLimits: config.MetadataLimits{MaxBytes: 200, MaxKeys: 5, MaxDepth: 2}}
+
+	manyKeys := map[string]interface{}{}
+	for i := 0; i < 6; i++ {
+		manyKeys[fmt.Sprintf("k%d", i)] = i
+	}
+
+	tests := []struct {
+		name     string
+		metadata map[string]interface{}
+		valid    bool
+	}{
+		{"nil", nil, true},
+		{"flat", map[string]interface{}{"order_id": "123", "note": "gift"}, true},
+		{"one level nested", map[string]interface{}{"customer": map[string]interface{}{"id": 1}}, true},
+		{"too deep", map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": 1}}}, false},
+		{"too deep through arrays", map[string]interface{}{"items": []interface{}{[]interface{}{1}}}, false},
+		{"too many keys", manyKeys, false},
+		{"nested keys count", map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2, "d": 3, "e": 4, "f": 5}}, false},
+		{"too large", map[string]interface{}{"note": strings.Repeat("x", 300)}, false},
+	}
+	for _, tt := range tests {
+		err := s.validateMetadata(tt.metadata)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrMetadataTooLarge) {
+			t.Errorf("%s: expected ErrMetadataTooLarge, got %v", tt.name, err)
+		}
+	}
+}
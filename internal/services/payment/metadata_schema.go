@@ -0,0 +1,184 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrReservedMetadataKey is returned when merchant metadata sets a key the platform writes itself
+	ErrReservedMetadataKey = errors.New("metadata key is reserved")
+
+	// ErrMetadataSchemaViolation is returned when metadata doesn't conform to the merchant's schema
+	ErrMetadataSchemaViolation = errors.New("metadata does not match schema")
+
+	// ErrInvalidMetadataSchema is returned when setting a schema with a blank, duplicate or
+	// reserved key or an unknown type
+	ErrInvalidMetadataSchema = errors.New("invalid metadata schema")
+
+	// ErrMetadataSchemaNotFound is returned when a merchant has no metadata schema
+	ErrMetadataSchemaNotFound = errors.New("metadata schema not found")
+)
+
+// ReservedMetadataKeys are set on payment metadata by the platform: payment link details when
+// paying through a link and subscription details on renewal charges. Merchants can't set them.
+var ReservedMetadataKeys = []string{
+	"payment_link_id",
+	"payment_link_slug",
+	"payment_link_title",
+	"subscription_id",
+	"plan_id",
+	"subscriber_id",
+	"period_start",
+	"period_end",
+	"recurring",
+}
+
+// checkMetadata validates merchant-supplied metadata: its size, that it doesn't set reserved
+// keys and, when the merchant has one, that it conforms to their schema
+func (s *PaymentService) checkMetadata(userID uuid.UUID, metadata map[string]interface{}) error {
+	if err := s.validateMetadata(metadata); err != nil {
+		return err
+	}
+	if err := checkReservedMetadataKeys(metadata); err != nil {
+		return err
+	}
+
+	schema, err := s.GetMetadataSchema(userID)
+	if errors.Is(err, ErrMetadataSchemaNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return validateMetadataSchema(schema, metadata)
+}
+
+// checkReservedMetadataKeys rejects metadata that sets a reserved key
+func checkReservedMetadataKeys(metadata map[string]interface{}) error {
+	for key := range metadata {
+		if isReservedMetadataKey(key) {
+			return fmt.Errorf("%w: %q", ErrReservedMetadataKey, key)
+		}
+	}
+	return nil
+}
+
+// isReservedMetadataKey reports whether key is one of ReservedMetadataKeys
+func isReservedMetadataKey(key string) bool {
+	for _, reserved := range ReservedMetadataKeys {
+		if key == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMetadataSchema checks metadata has the schema's required keys, that every value has
+// its field's type and, unless the schema allows unknown keys, that it has no other keys
+func validateMetadataSchema(schema *models.PaymentMetadataSchema, metadata map[string]interface{}) error {
+	fields := make(map[string]models.MetadataField, len(schema.Fields))
+	for _, field := range schema.Fields {
+		fields[field.Key] = field
+		value, ok := metadata[field.Key]
+		if !ok {
+			if field.Required {
+				return fmt.Errorf("%w: %q is required", ErrMetadataSchemaViolation, field.Key)
+			}
+			continue
+		}
+		if !metadataValueHasType(value, field.Type) {
+			return fmt.Errorf("%w: %q must be a %s", ErrMetadataSchemaViolation, field.Key, field.Type)
+		}
+	}
+
+	if !schema.AllowUnknownKeys {
+		for key := range metadata {
+			if _, ok := fields[key]; !ok {
+				return fmt.Errorf("%w: %q is not an allowed key", ErrMetadataSchemaViolation, key)
+			}
+		}
+	}
+	return nil
+}
+
+// metadataValueHasType reports whether a decoded JSON value has the given type. Null only
+// matches optional fields that are left out, never a declared type.
+func metadataValueHasType(value interface{}, fieldType models.MetadataFieldType) bool {
+	switch value.(type) {
+	case string:
+		return fieldType == models.MetadataFieldString
+	case float64, float32, int, int32, int64:
+		return fieldType == models.MetadataFieldNumber
+	case bool:
+		return fieldType == models.MetadataFieldBoolean
+	case map[string]interface{}:
+		return fieldType == models.MetadataFieldObject
+	case []interface{}:
+		return fieldType == models.MetadataFieldArray
+	default:
+		return false
+	}
+}
+
+// GetMetadataSchema returns a merchant's metadata schema
+func (s *PaymentService) GetMetadataSchema(userID uuid.UUID) (*models.PaymentMetadataSchema, error) {
+	var schema models.PaymentMetadataSchema
+	if err := s.db.Where("user_id = ?", userID).First(&schema).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMetadataSchemaNotFound
+		}
+		return nil, fmt.Errorf("error finding metadata schema: %w", err)
+	}
+	return &schema, nil
+}
+
+// SetMetadataSchema creates or replaces a merchant's metadata schema. It applies to payments
+// and payment links created or updated afterwards; existing metadata isn't revalidated.
+func (s *PaymentService) SetMetadataSchema(userID uuid.UUID, fields []models.MetadataField, allowUnknownKeys bool) (*models.PaymentMetadataSchema, error) {
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		switch {
+		case field.Key == "":
+			return nil, fmt.Errorf("%w: field keys can't be blank", ErrInvalidMetadataSchema)
+		case seen[field.Key]:
+			return nil, fmt.Errorf("%w: %q is listed more than once", ErrInvalidMetadataSchema, field.Key)
+		case isReservedMetadataKey(field.Key):
+			return nil, fmt.Errorf("%w: %q is reserved", ErrInvalidMetadataSchema, field.Key)
+		case !field.Type.IsValid():
+			return nil, fmt.Errorf("%w: %q has unknown type %q", ErrInvalidMetadataSchema, field.Key, field.Type)
+		}
+		seen[field.Key] = true
+	}
+
+	var schema models.PaymentMetadataSchema
+	err := s.db.Where("user_id = ?", userID).First(&schema).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("error finding metadata schema: %w", err)
+	}
+
+	schema.UserID = userID
+	schema.Fields = fields
+	schema.AllowUnknownKeys = allowUnknownKeys
+	if err := s.db.Save(&schema).Error; err != nil {
+		return nil, fmt.Errorf("error saving metadata schema: %w", err)
+	}
+	return &schema, nil
+}
+
+// DeleteMetadataSchema removes a merchant's metadata schema, so any metadata within the size
+// limits is accepted again
+func (s *PaymentService) DeleteMetadataSchema(userID uuid.UUID) error {
+	result := s.db.Where("user_id = ?", userID).Delete(&models.PaymentMetadataSchema{})
+	if result.Error != nil {
+		return fmt.Errorf("error deleting metadata schema: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrMetadataSchemaNotFound
+	}
+	return nil
+}
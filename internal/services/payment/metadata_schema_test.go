@@ -0,0 +1,55 @@
+package payment
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/revaspay/backend/internal/models"
+)
+
+func TestCheckReservedMetadataKeys(t *testing.T) {
+	if err := checkReservedMetadataKeys(map[string]interface{}{"order_id": "123"}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	err := checkReservedMetadataKeys(map[string]interface{}{"order_id": "123", "payment_link_id": "x"})
+	if !errors.Is(err, ErrReservedMetadataKey) {
+		t.Errorf("expected ErrReservedMetadataKey, got %v", err)
+	}
+}
+
+func TestValidateMetadataSchema(t *testing.T) {
+	schema := &models.PaymentMetadataSchema{
+		Fields: models.MetadataFields{
+			{Key: "order_id", Type: models.MetadataFieldString, Required: true},
+			{Key: "quantity", Type: models.MetadataFieldNumber},
+			{Key: "gift", Type: models.MetadataFieldBoolean},
+			{Key: "items", Type: models.MetadataFieldArray},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		metadata map[string]interface{}
+		unknown  bool
+		valid    bool
+	}{
+		{"required only", map[string]interface{}{"order_id": "123"}, false, true},
+		{"all fields", map[string]interface{}{"order_id": "123", "quantity": 2.0, "gift": true, "items": []interface{}{"a"}}, false, true},
+		{"missing required", map[string]interface{}{"quantity": 2.0}, false, false},
+		{"no metadata", nil, false, false},
+		{"wrong type", map[string]interface{}{"order_id": 123.0}, false, false},
+		{"null value", map[string]interface{}{"order_id": "123", "gift": nil}, false, false},
+		{"unknown key", map[string]interface{}{"order_id": "123", "note": "x"}, false, false},
+		{"unknown key allowed", map[string]interface{}{"order_id": "123", "note": "x"}, true, true},
+	}
+	for _, tt := range tests {
+		schema.AllowUnknownKeys = tt.unknown
+		err := validateMetadataSchema(schema, tt.metadata)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrMetadataSchemaViolation) {
+			t.Errorf("%s: expected ErrMetadataSchemaViolation, got %v", tt.name, err)
+		}
+	}
+}
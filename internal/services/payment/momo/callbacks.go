@@ -0,0 +1,296 @@
+package momo
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/revaspay/backend/internal/currency"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidCallbackToken is returned when a callback doesn't carry the configured secret
+	ErrInvalidCallbackToken = errors.New("invalid callback token")
+
+	// ErrCallbackTransactionNotFound is returned when a callback doesn't match any MoMo transaction
+	ErrCallbackTransactionNotFound = errors.New("momo transaction not found")
+
+	// ErrCallbackMismatch is returned when a successful callback's amount or currency differs
+	// from the transaction's
+	ErrCallbackMismatch = errors.New("callback does not match the transaction")
+)
+
+// CallbackProcessor applies MTN MoMo collection and disbursement callbacks to the
+// MoMo transactions they report on. Successful collections credit the user's wallet and
+// finished disbursements settle the withdrawal they pay out.
+type CallbackProcessor struct {
+	db          *gorm.DB
+	walletSvc   *wallet.WalletService
+	secret      string
+	auditLogger *utils.AuditLogger
+}
+
+// NewCallbackProcessor creates a new callback processor. Callbacks must carry secret as
+// their token; with no secret every callback is rejected.
+func NewCallbackProcessor(db *gorm.DB, walletSvc *wallet.WalletService, secret string) *CallbackProcessor {
+	return &CallbackProcessor{
+		db:          db,
+		walletSvc:   walletSvc,
+		secret:      secret,
+		auditLogger: utils.NewAuditLogger(db),
+	}
+}
+
+// VerifyToken checks a callback's token against the configured secret
+func (p *CallbackProcessor) VerifyToken(token string) error {
+	if p.secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(p.secret)) != 1 {
+		return ErrInvalidCallbackToken
+	}
+	return nil
+}
+
+// ProcessCollection applies a collection callback. Callbacks for transactions that already
+// reached a final status are ignored, so retried callbacks can't credit twice.
+func (p *CallbackProcessor) ProcessCollection(notification PaymentNotification) error {
+	return p.process(models.MoMoTransactionTypeCollection, notification.ExternalID, notification.ReferenceID,
+		notification.Status, notification.Amount, notification.Currency, notification, p.settleCollection)
+}
+
+// ProcessDisbursement applies a disbursement callback, completing or failing the linked
+// withdrawal. Callbacks for transactions that already reached a final status are ignored.
+func (p *CallbackProcessor) ProcessDisbursement(notification DisbursementNotification) error {
+	return p.process(models.MoMoTransactionTypeDisbursement, notification.ExternalID, notification.ReferenceID,
+		notification.Status, notification.Amount, notification.Currency, notification, p.settleDisbursement)
+}
+
+// process locks the matching transaction, records the callback and hands final statuses to settle
+func (p *CallbackProcessor) process(txType models.MoMoTransactionType, externalID, referenceID, status, amount, code string,
+	payload interface{}, settle func(tx *gorm.DB, momoTx *models.MoMoTransaction) ([]statusAudit, error)) error {
+	newStatus := callbackStatus(status)
+
+	var audits []statusAudit
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		momoTx, err := p.lockTransaction(tx, txType, externalID, referenceID)
+		if err != nil {
+			return err
+		}
+		if momoTx.Status != models.MoMoTransactionStatusPending {
+			log.Printf("MoMo %s %s is already %s, ignoring %s callback", txType, momoTx.Reference, momoTx.Status, status)
+			return nil
+		}
+		if newStatus == models.MoMoTransactionStatusPending {
+			return nil
+		}
+		if newStatus == models.MoMoTransactionStatusSucceeded {
+			if err := checkAmount(momoTx, amount, code); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		momoTx.Status = newStatus
+		momoTx.WebhookReceived = true
+		momoTx.WebhookData = toJSON(payload)
+		if newStatus == models.MoMoTransactionStatusSucceeded {
+			momoTx.CompletedAt = &now
+		} else {
+			momoTx.FailedAt = &now
+			momoTx.Reason = status
+		}
+		if err := tx.Save(momoTx).Error; err != nil {
+			return fmt.Errorf("error updating momo transaction: %w", err)
+		}
+
+		audits, err = settle(tx, momoTx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, audit := range audits {
+		p.auditTransition(audit.eventType, audit.transition)
+	}
+	return nil
+}
+
+// statusAudit is a status change to audit once the callback's changes are committed
+type statusAudit struct {
+	eventType  utils.AuditEventType
+	transition utils.StatusTransition
+}
+
+// lockTransaction finds the transaction a callback is for, by our reference (sent to MoMo as
+// the external ID) or by MoMo's reference ID, and locks it until the callback is applied
+func (p *CallbackProcessor) lockTransaction(tx *gorm.DB, txType models.MoMoTransactionType, externalID, referenceID string) (*models.MoMoTransaction, error) {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("type = ?", txType)
+	switch {
+	case externalID != "":
+		query = query.Where("reference = ?", externalID)
+	case referenceID != "":
+		query = query.Where("external_id = ?", referenceID)
+	default:
+		return nil, ErrCallbackTransactionNotFound
+	}
+
+	var momoTx models.MoMoTransaction
+	if err := query.First(&momoTx).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCallbackTransactionNotFound
+		}
+		return nil, fmt.Errorf("error finding momo transaction: %w", err)
+	}
+	return &momoTx, nil
+}
+
+// settleCollection credits the user's wallet for a successful collection. Collections for a
+// payment complete the payment and credit it under the payment's reference, so verifying the
+// payment later can't credit it again.
+func (p *CallbackProcessor) settleCollection(tx *gorm.DB, momoTx *models.MoMoTransaction) ([]statusAudit, error) {
+	if momoTx.Status != models.MoMoTransactionStatusSucceeded {
+		return nil, nil
+	}
+
+	var audits []statusAudit
+	txType, category, reference := "momo_collection", models.LedgerCategoryDeposit, momoTx.Reference
+	if momoTx.PaymentID != nil {
+		var payment models.Payment
+		if err := tx.First(&payment, "id = ?", *momoTx.PaymentID).Error; err != nil {
+			return nil, fmt.Errorf("error finding payment: %w", err)
+		}
+		if payment.Status != models.PaymentStatusCompleted {
+			previousStatus := payment.Status
+			if err := tx.Model(&payment).Update("status", models.PaymentStatusCompleted).Error; err != nil {
+				return nil, fmt.Errorf("error updating payment: %w", err)
+			}
+			audits = append(audits, statusAudit{utils.AuditEventPaymentStatus, utils.StatusTransition{
+				EntityID:  payment.ID,
+				OwnerID:   payment.UserID,
+				Reference: payment.Reference,
+				From:      string(previousStatus),
+				To:        string(models.PaymentStatusCompleted),
+				Amount:    payment.Amount,
+				Currency:  string(payment.Currency),
+			}})
+		}
+		txType, category, reference = "payment", models.LedgerCategorySales, payment.Reference
+	}
+
+	userWallet, err := wallet.NewWalletService(tx).GetOrCreateWallet(momoTx.UserID, momoTx.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("error getting wallet: %w", err)
+	}
+	_, err = p.walletSvc.CreditOnceWithTx(tx, userWallet.ID, momoTx.Amount-momoTx.Fee, txType, category, reference,
+		fmt.Sprintf("Mobile money payment from %s", momoTx.PhoneNumber), map[string]interface{}{
+			"momo_transaction_id": momoTx.ID.String(),
+			"phone_number":        momoTx.PhoneNumber,
+		})
+	if errors.Is(err, wallet.ErrDuplicateCredit) {
+		log.Printf("MoMo collection %s was already credited, skipping", momoTx.Reference)
+		return audits, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return audits, nil
+}
+
+// settleDisbursement completes the withdrawal a successful disbursement paid out, or fails it
+// and returns the funds when the disbursement failed
+func (p *CallbackProcessor) settleDisbursement(tx *gorm.DB, momoTx *models.MoMoTransaction) ([]statusAudit, error) {
+	if momoTx.WithdrawalID == nil {
+		return nil, nil
+	}
+
+	var withdrawal models.Withdrawal
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&withdrawal, "id = ?", *momoTx.WithdrawalID).Error; err != nil {
+		return nil, fmt.Errorf("error finding withdrawal: %w", err)
+	}
+	if withdrawal.Status != "pending" && withdrawal.Status != "processing" {
+		log.Printf("Withdrawal %s is already %s, not settling from MoMo callback", withdrawal.ID, withdrawal.Status)
+		return nil, nil
+	}
+
+	previousStatus := withdrawal.Status
+	var err error
+	if momoTx.Status == models.MoMoTransactionStatusSucceeded {
+		err = p.walletSvc.CompleteWithdrawalWithTx(tx, &withdrawal)
+	} else {
+		err = p.walletSvc.FailWithdrawalWithTx(tx, &withdrawal, fmt.Sprintf("mobile money disbursement %s", momoTx.Status))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return []statusAudit{{utils.AuditEventWithdrawalStatus, utils.StatusTransition{
+		EntityID:  withdrawal.ID,
+		OwnerID:   withdrawal.UserID,
+		Reference: withdrawal.Reference,
+		From:      previousStatus,
+		To:        withdrawal.Status,
+		Amount:    withdrawal.Amount,
+		Currency:  string(withdrawal.Currency),
+	}}}, nil
+}
+
+// auditTransition records a status change made by a MoMo callback
+func (p *CallbackProcessor) auditTransition(eventType utils.AuditEventType, transition utils.StatusTransition) {
+	transition.Actor = utils.AuditActorSystem
+	transition.Reason = "momo_callback"
+	if err := p.auditLogger.LogStatusTransition(context.Background(), eventType, transition); err != nil {
+		log.Printf("Failed to write status audit event for %s: %v", transition.Reference, err)
+	}
+}
+
+// callbackStatus maps a MoMo callback status to a transaction status. Statuses MoMo is still
+// working on map to pending.
+func callbackStatus(status string) models.MoMoTransactionStatus {
+	switch status {
+	case "SUCCESSFUL":
+		return models.MoMoTransactionStatusSucceeded
+	case "FAILED", "REJECTED":
+		return models.MoMoTransactionStatusFailed
+	case "TIMEOUT":
+		return models.MoMoTransactionStatusExpired
+	default:
+		return models.MoMoTransactionStatusPending
+	}
+}
+
+// checkAmount checks a successful callback reports the amount and currency we asked for
+func checkAmount(momoTx *models.MoMoTransaction, amount, code string) error {
+	if code != "" && models.Currency(code) != momoTx.Currency {
+		return fmt.Errorf("%w: currency %s, expected %s", ErrCallbackMismatch, code, momoTx.Currency)
+	}
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid amount %q", ErrCallbackMismatch, amount)
+	}
+	if currency.Round(momoTx.Currency, value) != currency.Round(momoTx.Currency, momoTx.Amount) {
+		return fmt.Errorf("%w: amount %s, expected %.2f", ErrCallbackMismatch, amount, momoTx.Amount)
+	}
+	return nil
+}
+
+// toJSON converts a callback payload for storage
+func toJSON(payload interface{}) models.JSON {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	var result models.JSON
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	return result
+}
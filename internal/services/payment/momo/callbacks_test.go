@@ -0,0 +1,212 @@
+package momo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeMoMo is a MoMo API that accepts every payment and transfer, reports statuses from
+// statuses by reference ID, and records the callback URL each request asked for
+type fakeMoMo struct {
+	mu        sync.Mutex
+	callbacks map[string]string
+	statuses  map[string]TransactionStatus
+}
+
+func (f *fakeMoMo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/token/"):
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "token", TokenType: "access_token", ExpiresIn: 3600})
+	case r.Method == http.MethodPost:
+		f.callbacks[r.URL.Path] = r.Header.Get("X-Callback-Url")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodGet:
+		referenceID := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		json.NewEncoder(w).Encode(f.statuses[referenceID])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestMoMoService returns a MoMo service talking to a fake MoMo API, configured to ask for
+// callbacks to https://api.example.com
+func newTestMoMoService(t *testing.T) (*MoMoService, *fakeMoMo, *gorm.DB) {
+	fake := &fakeMoMo{callbacks: map[string]string{}, statuses: map[string]TransactionStatus{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	db := testutil.NewDB(t)
+	service := InitMoMoService(db, &config.Config{MoMo: config.MoMoConfig{
+		CallbackSecret:  "s3cret&",
+		CallbackBaseURL: "https://api.example.com/",
+		UseSandbox:      true,
+	}})
+	service.client.BaseURL = server.URL
+	return service, fake, db
+}
+
+// A collection asks MoMo to call back with the secret, and is credited once however many
+// times its callback arrives
+func TestCollectionCallbackCreditsOnce(t *testing.T) {
+	service, fake, db := newTestMoMoService(t)
+	user := testutil.CreateUser(t, db)
+	processor := NewCallbackProcessor(db, wallet.NewWalletService(db), "s3cret&")
+
+	payment, err := service.RequestPayment(PaymentRequest{UserID: user.ID, PhoneNumber: "0241234567", Amount: 50, Description: "Top up"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/webhooks/momo/payment?token=s3cret%26", fake.callbacks[requestToPayEndpoint])
+
+	var momoTx models.MoMoTransaction
+	require.NoError(t, db.First(&momoTx, "reference = ?", payment.ReferenceID).Error)
+	assert.Equal(t, models.MoMoTransactionTypeCollection, momoTx.Type)
+	assert.Equal(t, models.MoMoTransactionStatusPending, momoTx.Status)
+	assert.Equal(t, payment.TransactionID, momoTx.ExternalID)
+	assert.Equal(t, "233241234567", momoTx.PhoneNumber)
+
+	callback := PaymentNotification{ExternalID: payment.ReferenceID, Status: "SUCCESSFUL", Amount: "50", Currency: "GHS"}
+	require.NoError(t, processor.ProcessCollection(callback))
+	require.NoError(t, processor.ProcessCollection(callback))
+
+	// Polling a collection its callback settled doesn't credit it again
+	status, err := service.CheckPaymentStatus(payment.TransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, MoMoStatusSuccessful, status.Status)
+
+	var userWallet models.Wallet
+	require.NoError(t, db.First(&userWallet, "user_id = ? AND currency = ?", user.ID, models.CurrencyGHS).Error)
+	assert.Equal(t, 50.0, userWallet.Balance)
+	var credits int64
+	require.NoError(t, db.Model(&models.WalletLedgerEntry{}).Where("reference = ?", payment.ReferenceID).Count(&credits).Error)
+	assert.Equal(t, int64(1), credits)
+}
+
+// Polling settles a collection the same way its callback would, so a later callback is ignored
+func TestCheckPaymentStatusSettlesOnce(t *testing.T) {
+	service, fake, db := newTestMoMoService(t)
+	user := testutil.CreateUser(t, db)
+	processor := NewCallbackProcessor(db, wallet.NewWalletService(db), "s3cret&")
+
+	payment, err := service.RequestPayment(PaymentRequest{UserID: user.ID, PhoneNumber: "0241234567", Amount: 20, Description: "Top up"})
+	require.NoError(t, err)
+	fake.statuses[payment.TransactionID] = TransactionStatus{Amount: "20", Currency: "GHS", Status: "SUCCESSFUL", FinancialTransactionID: "FIN-1"}
+
+	status, err := service.CheckPaymentStatus(payment.TransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, MoMoStatusSuccessful, status.Status)
+	require.NoError(t, processor.ProcessCollection(PaymentNotification{ReferenceID: payment.TransactionID, Status: "SUCCESSFUL", Amount: "20", Currency: "GHS"}))
+
+	var momoTx models.MoMoTransaction
+	require.NoError(t, db.First(&momoTx, "reference = ?", payment.ReferenceID).Error)
+	assert.Equal(t, models.MoMoTransactionStatusSucceeded, momoTx.Status)
+	assert.Equal(t, "FIN-1", momoTx.FinancialID)
+	var userWallet models.Wallet
+	require.NoError(t, db.First(&userWallet, "user_id = ?", user.ID).Error)
+	assert.Equal(t, 20.0, userWallet.Balance)
+}
+
+// Callbacks that don't match what was asked for are rejected without crediting
+func TestCollectionCallbackMismatch(t *testing.T) {
+	service, _, db := newTestMoMoService(t)
+	user := testutil.CreateUser(t, db)
+	processor := NewCallbackProcessor(db, wallet.NewWalletService(db), "s3cret&")
+
+	payment, err := service.RequestPayment(PaymentRequest{UserID: user.ID, PhoneNumber: "0241234567", Amount: 50, Description: "Top up"})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, processor.ProcessCollection(PaymentNotification{ExternalID: payment.ReferenceID, Status: "SUCCESSFUL", Amount: "49.99", Currency: "GHS"}), ErrCallbackMismatch)
+	assert.ErrorIs(t, processor.ProcessCollection(PaymentNotification{ExternalID: payment.ReferenceID, Status: "SUCCESSFUL", Amount: "50", Currency: "EUR"}), ErrCallbackMismatch)
+	assert.ErrorIs(t, processor.ProcessCollection(PaymentNotification{ExternalID: "RP-unknown", Status: "SUCCESSFUL", Amount: "50"}), ErrCallbackTransactionNotFound)
+
+	var credits int64
+	require.NoError(t, db.Model(&models.WalletLedgerEntry{}).Where("reference = ?", payment.ReferenceID).Count(&credits).Error)
+	assert.Zero(t, credits)
+}
+
+// A disbursement's callback settles the withdrawal it pays out once: a success captures the
+// held funds and a failure releases them
+func TestDisbursementCallbackSettlesOnce(t *testing.T) {
+	service, fake, db := newTestMoMoService(t)
+	user := testutil.CreateUser(t, db)
+	walletSvc := wallet.NewWalletService(db)
+	processor := NewCallbackProcessor(db, walletSvc, "s3cret&")
+
+	_, err := service.DisbursePayment(DisbursementRequest{UserID: user.ID, PhoneNumber: "0241234567", Amount: 5, Description: "Payout"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/webhooks/momo/disbursement?token=s3cret%26", fake.callbacks[transferEndpoint])
+
+	userWallet, err := walletSvc.GetOrCreateWallet(user.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	_, err = walletSvc.Credit(userWallet.ID, 100, "deposit", models.LedgerCategoryDeposit, "DEP-1", "Deposit", nil)
+	require.NoError(t, err)
+
+	disburse := func(amount float64) *models.Withdrawal {
+		withdrawal, err := walletSvc.CreateWithdrawal(user.ID, wallet.CreateWithdrawalRequest{
+			Currency: models.CurrencyGHS,
+			Amount:   amount,
+			Method:   wallet.WithdrawalMethodMobileMoney,
+			Metadata: map[string]interface{}{"mobile_number": "233241234567"},
+		})
+		require.NoError(t, err)
+		require.NoError(t, db.Create(&models.MoMoTransaction{
+			UserID:       user.ID,
+			Type:         models.MoMoTransactionTypeDisbursement,
+			Status:       models.MoMoTransactionStatusPending,
+			Amount:       amount,
+			Currency:     models.CurrencyGHS,
+			PhoneNumber:  "233241234567",
+			Reference:    withdrawal.Reference,
+			WithdrawalID: &withdrawal.ID,
+		}).Error)
+		return withdrawal
+	}
+
+	paid := disburse(40)
+	callback := DisbursementNotification{ExternalID: paid.Reference, Status: "SUCCESSFUL", Amount: "40", Currency: "GHS"}
+	require.NoError(t, processor.ProcessDisbursement(callback))
+	require.NoError(t, processor.ProcessDisbursement(callback))
+
+	failed := disburse(30)
+	callback = DisbursementNotification{ExternalID: failed.Reference, Status: "FAILED"}
+	require.NoError(t, processor.ProcessDisbursement(callback))
+	require.NoError(t, processor.ProcessDisbursement(callback))
+
+	var paidWithdrawal, failedWithdrawal models.Withdrawal
+	require.NoError(t, db.First(&paidWithdrawal, "id = ?", paid.ID).Error)
+	assert.Equal(t, "completed", paidWithdrawal.Status)
+	require.NoError(t, db.First(&failedWithdrawal, "id = ?", failed.ID).Error)
+	assert.Equal(t, "failed", failedWithdrawal.Status)
+
+	settled, err := walletSvc.GetWallet(userWallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, settled.Balance)
+	assert.Equal(t, 60.0, settled.Available)
+	var debits int64
+	require.NoError(t, db.Model(&models.WalletLedgerEntry{}).Where("reference = ?", paid.Reference).Count(&debits).Error)
+	assert.Equal(t, int64(1), debits)
+}
+
+func TestVerifyToken(t *testing.T) {
+	processor := &CallbackProcessor{secret: "s3cret"}
+	assert.NoError(t, processor.VerifyToken("s3cret"))
+	assert.ErrorIs(t, processor.VerifyToken("wrong"), ErrInvalidCallbackToken)
+	assert.ErrorIs(t, processor.VerifyToken(""), ErrInvalidCallbackToken)
+
+	// Without a configured secret every callback is rejected
+	processor = &CallbackProcessor{}
+	assert.ErrorIs(t, processor.VerifyToken(""), ErrInvalidCallbackToken)
+}
@@ -1,13 +1,19 @@
 package momo
 
 import (
+	"log"
+	"net/url"
+	"strings"
+
 	"github.com/revaspay/backend/internal/config"
 	"gorm.io/gorm"
 )
 
-// InitMoMoService initializes the MTN Mobile Money service with configuration
+// InitMoMoService initializes the MTN Mobile Money service with configuration. Payments and
+// disbursements ask MoMo to call back to this API's webhook routes, with the callback secret
+// as the token.
 func InitMoMoService(db *gorm.DB, cfg *config.Config) *MoMoService {
-	return NewMoMoService(
+	service := NewMoMoService(
 		db,
 		cfg.MoMo.SubscriptionKey,
 		cfg.MoMo.CollectionAPIUser,
@@ -16,4 +22,17 @@ func InitMoMoService(db *gorm.DB, cfg *config.Config) *MoMoService {
 		cfg.MoMo.DisbursementAPIKey,
 		cfg.MoMo.UseSandbox,
 	)
+
+	if cfg.MoMo.CallbackBaseURL == "" {
+		log.Println("MTN_MOMO_CALLBACK_BASE_URL is not set; MoMo callbacks won't be requested")
+		return service
+	}
+	service.client.CollectionCallbackURL = callbackURL(cfg.MoMo.CallbackBaseURL, "/webhooks/momo/payment", cfg.MoMo.CallbackSecret)
+	service.client.DisbursementCallbackURL = callbackURL(cfg.MoMo.CallbackBaseURL, "/webhooks/momo/disbursement", cfg.MoMo.CallbackSecret)
+	return service
+}
+
+// callbackURL returns the URL MoMo calls back to for a webhook route, carrying secret as its token
+func callbackURL(baseURL, path, secret string) string {
+	return strings.TrimSuffix(baseURL, "/") + path + "?token=" + url.QueryEscape(secret)
 }
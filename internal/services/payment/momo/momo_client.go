@@ -7,9 +7,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"time"
 
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/utils"
 )
 
 const (
@@ -40,6 +40,10 @@ type MoMoClient struct {
 	DisbursementAPIKey  string
 	HTTPClient       *http.Client
 	UseSandbox       bool
+	// Where MoMo sends the outcome of collections and disbursements; callbacks aren't
+	// requested when these are empty
+	CollectionCallbackURL   string
+	DisbursementCallbackURL string
 }
 
 // NewMoMoClient creates a new MTN Mobile Money API client
@@ -57,7 +61,7 @@ func NewMoMoClient(subscriptionKey, collectionAPIUser, collectionAPIKey,
 		CollectionAPIKey:  collectionAPIKey,
 		DisbursementAPIUser: disbursementAPIUser,
 		DisbursementAPIKey:  disbursementAPIKey,
-		HTTPClient:       &http.Client{Timeout: 30 * time.Second},
+		HTTPClient:       &http.Client{Timeout: utils.ProviderTimeout()},
 		UseSandbox:       useSandbox,
 	}
 }
@@ -175,6 +179,9 @@ func (c *MoMoClient) RequestToPay(request RequestToPayRequest) (string, error) {
 	req.Header.Set("X-Target-Environment", c.getEnvironment())
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Ocp-Apim-Subscription-Key", c.SubscriptionKey)
+	if c.CollectionCallbackURL != "" {
+		req.Header.Set("X-Callback-Url", c.CollectionCallbackURL)
+	}
 	
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -313,6 +320,9 @@ func (c *MoMoClient) Transfer(request TransferRequest) (string, error) {
 	req.Header.Set("X-Target-Environment", c.getEnvironment())
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Ocp-Apim-Subscription-Key", c.SubscriptionKey)
+	if c.DisbursementCallbackURL != "" {
+		req.Header.Set("X-Callback-Url", c.DisbursementCallbackURL)
+	}
 	
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
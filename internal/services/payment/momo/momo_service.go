@@ -3,10 +3,10 @@ package momo
 import (
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/google/uuid"
-	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/wallet"
 	"gorm.io/gorm"
 )
 
@@ -24,16 +24,18 @@ const (
 
 // MoMoService handles MTN Mobile Money payments
 type MoMoService struct {
-	db     *gorm.DB
-	client *MoMoClient
+	db        *gorm.DB
+	client    *MoMoClient
+	callbacks *CallbackProcessor
 }
 
 // NewMoMoService creates a new MoMo service
 func NewMoMoService(db *gorm.DB, subscriptionKey, collectionAPIUser, collectionAPIKey,
 	disbursementAPIUser, disbursementAPIKey string, useSandbox bool) *MoMoService {
 	return &MoMoService{
-		db:     db,
-		client: NewMoMoClient(subscriptionKey, collectionAPIUser, collectionAPIKey, disbursementAPIUser, disbursementAPIKey, useSandbox),
+		db:        db,
+		client:    NewMoMoClient(subscriptionKey, collectionAPIUser, collectionAPIKey, disbursementAPIUser, disbursementAPIKey, useSandbox),
+		callbacks: NewCallbackProcessor(db, wallet.NewWalletService(db), ""),
 	}
 }
 
@@ -43,7 +45,6 @@ type PaymentRequest struct {
 	PhoneNumber  string
 	Amount       float64
 	Description  string
-	CallbackURL  string
 	ReferenceID  string
 	CurrencyCode string
 }
@@ -56,7 +57,8 @@ type PaymentResponse struct {
 	Message       string
 }
 
-// RequestPayment initiates a payment request to a mobile money user
+// RequestPayment initiates a payment request to a mobile money user. The collection is
+// recorded as a pending MoMo transaction, which the collection callback credits.
 func (s *MoMoService) RequestPayment(req PaymentRequest) (*PaymentResponse, error) {
 	// Format phone number to international format if needed
 	phoneNumber := formatPhoneNumber(req.PhoneNumber)
@@ -92,22 +94,22 @@ func (s *MoMoService) RequestPayment(req PaymentRequest) (*PaymentResponse, erro
 		return nil, fmt.Errorf("failed to initiate MoMo payment: %w", err)
 	}
 
-	// Create transaction record in database
-	tx := database.MoMoTransaction{
-		UserID:        req.UserID,
-		TransactionID: transactionID,
-		ReferenceID:   referenceID,
-		PhoneNumber:   phoneNumber,
-		Amount:        req.Amount,
-		Currency:      currency,
-		Description:   req.Description,
-		Status:        string(MoMoStatusPending),
-		CallbackURL:   req.CallbackURL,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+	// Record the collection under our reference, which MoMo echoes back as the external ID
+	momoTx := models.MoMoTransaction{
+		UserID:       req.UserID,
+		Type:         models.MoMoTransactionTypeCollection,
+		Status:       models.MoMoTransactionStatusPending,
+		Amount:       req.Amount,
+		Currency:     models.Currency(currency),
+		PhoneNumber:  phoneNumber,
+		Reference:    referenceID,
+		ExternalID:   transactionID,
+		PayerMessage: momoRequest.PayerMessage,
+		PayeeNote:    momoRequest.PayeeNote,
+		Description:  req.Description,
 	}
 
-	if err := s.db.Create(&tx).Error; err != nil {
+	if err := s.db.Create(&momoTx).Error; err != nil {
 		return nil, fmt.Errorf("failed to save MoMo transaction: %w", err)
 	}
 
@@ -119,15 +121,21 @@ func (s *MoMoService) RequestPayment(req PaymentRequest) (*PaymentResponse, erro
 	}, nil
 }
 
-// CheckPaymentStatus checks the status of a MoMo payment
+// CheckPaymentStatus checks the status of a MoMo payment. A final status is applied the same
+// way as a collection callback, so a payment is credited once whichever arrives first.
 func (s *MoMoService) CheckPaymentStatus(transactionID string) (*PaymentResponse, error) {
 	// Get transaction from database
-	var tx database.MoMoTransaction
-	if err := s.db.Where("transaction_id = ?", transactionID).First(&tx).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("transaction not found: %s", transactionID)
-		}
-		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	momoTx, err := s.findTransaction(models.MoMoTransactionTypeCollection, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if momoTx.Status != models.MoMoTransactionStatusPending {
+		return &PaymentResponse{
+			TransactionID: transactionID,
+			ReferenceID:   momoTx.Reference,
+			Status:        paymentStatus(momoTx.Status),
+			Message:       momoTx.Reason,
+		}, nil
 	}
 
 	// Check status from MoMo API
@@ -136,21 +144,25 @@ func (s *MoMoService) CheckPaymentStatus(transactionID string) (*PaymentResponse
 		return nil, fmt.Errorf("failed to check payment status: %w", err)
 	}
 
-	// Update transaction status in database
-	momoStatus := mapMoMoStatus(status.Status)
-	if err := s.db.Model(&tx).Updates(map[string]interface{}{
-		"status":       string(momoStatus),
-		"updated_at":   time.Now(),
+	if err := s.db.Model(momoTx).Updates(map[string]interface{}{
 		"financial_id": status.FinancialTransactionID,
 		"reason":       status.Reason,
 	}).Error; err != nil {
 		return nil, fmt.Errorf("failed to update transaction status: %w", err)
 	}
+	if err := s.callbacks.ProcessCollection(PaymentNotification{
+		ReferenceID: transactionID,
+		Status:      status.Status,
+		Amount:      status.Amount,
+		Currency:    status.Currency,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to update transaction status: %w", err)
+	}
 
 	return &PaymentResponse{
 		TransactionID: transactionID,
-		ReferenceID:   tx.ReferenceID,
-		Status:        momoStatus,
+		ReferenceID:   momoTx.Reference,
+		Status:        mapMoMoStatus(status.Status),
 		Message:       status.Reason,
 	}, nil
 }
@@ -208,60 +220,74 @@ func (s *MoMoService) DisbursePayment(req DisbursementRequest) (*DisbursementRes
 		return nil, fmt.Errorf("failed to initiate MoMo disbursement: %w", err)
 	}
 
-	// Create a new disbursement record
-	disbursement := database.MoMoDisbursement{
-		ID:            uuid.New(),
-		UserID:        req.UserID,
-		TransactionID: transactionID,
-		ReferenceID:   referenceID,
-		Amount:        req.Amount,
-		Currency:      "GHS",
-		PhoneNumber:   phoneNumber,
-		Status:        "PENDING",
-		Description:   req.Description,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+	// Record the disbursement, which the disbursement callback settles
+	momoTx := models.MoMoTransaction{
+		UserID:       req.UserID,
+		Type:         models.MoMoTransactionTypeDisbursement,
+		Status:       models.MoMoTransactionStatusPending,
+		Amount:       req.Amount,
+		Currency:     models.Currency(currency),
+		PhoneNumber:  phoneNumber,
+		Reference:    referenceID,
+		ExternalID:   transactionID,
+		PayerMessage: transferRequest.PayerMessage,
+		PayeeNote:    transferRequest.PayeeNote,
+		Description:  req.Description,
 	}
 
-	if err := s.db.Create(&disbursement).Error; err != nil {
+	if err := s.db.Create(&momoTx).Error; err != nil {
 		return nil, fmt.Errorf("failed to save MoMo disbursement: %w", err)
 	}
 
 	return &DisbursementResponse{
 		TransactionID: transactionID,
 		ReferenceID:   referenceID,
-		Status:        "PENDING",
+		Status:        string(MoMoStatusPending),
 	}, nil
 }
 
-// CheckDisbursementStatus checks the status of a disbursement
+// CheckDisbursementStatus checks the status of a disbursement. A final status is applied the
+// same way as a disbursement callback.
 func (s *MoMoService) CheckDisbursementStatus(transactionID string) (string, error) {
 	// Find the disbursement in the database
-	var disbursement database.MoMoDisbursement
-	result := s.db.Where("transaction_id = ?", transactionID).First(&disbursement)
-	if result.Error != nil {
-		return "", result.Error
+	momoTx, err := s.findTransaction(models.MoMoTransactionTypeDisbursement, transactionID)
+	if err != nil {
+		return "", err
 	}
 
 	// If the status is not pending, return the current status
-	if disbursement.Status != "PENDING" {
-		return disbursement.Status, nil
+	if momoTx.Status != models.MoMoTransactionStatusPending {
+		return string(paymentStatus(momoTx.Status)), nil
 	}
 
 	// Check the status with the MoMo API
-	status, err := s.client.GetTransferStatus(disbursement.ReferenceID)
+	status, err := s.client.GetTransferStatus(transactionID)
 	if err != nil {
 		return "", fmt.Errorf("failed to check disbursement status: %w", err)
 	}
 
-	// Update disbursement status in database
-	disbursement.Status = s.mapMoMoStatusToDisbursementStatus(status.Status)
-	disbursement.UpdatedAt = time.Now()
-	if err := s.db.Save(&disbursement).Error; err != nil {
+	if err := s.callbacks.ProcessDisbursement(DisbursementNotification{
+		ReferenceID: transactionID,
+		Status:      status.Status,
+		Amount:      status.Amount,
+		Currency:    status.Currency,
+	}); err != nil {
 		return "", fmt.Errorf("failed to update disbursement status: %w", err)
 	}
 
-	return disbursement.Status, nil
+	return string(mapMoMoStatus(status.Status)), nil
+}
+
+// findTransaction finds a MoMo transaction by the reference ID MoMo gave it
+func (s *MoMoService) findTransaction(txType models.MoMoTransactionType, transactionID string) (*models.MoMoTransaction, error) {
+	var momoTx models.MoMoTransaction
+	if err := s.db.Where("type = ? AND external_id = ?", txType, transactionID).First(&momoTx).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("transaction not found: %s", transactionID)
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	return &momoTx, nil
 }
 
 // GetBalance gets the account balance for both collection and disbursement
@@ -319,3 +345,19 @@ func mapMoMoStatus(status string) MoMoPaymentStatus {
 		return MoMoStatusPending
 	}
 }
+
+// paymentStatus maps a recorded transaction status to the MoMo status reported to clients
+func paymentStatus(status models.MoMoTransactionStatus) MoMoPaymentStatus {
+	switch status {
+	case models.MoMoTransactionStatusSucceeded:
+		return MoMoStatusSuccessful
+	case models.MoMoTransactionStatusFailed:
+		return MoMoStatusFailed
+	case models.MoMoTransactionStatusExpired:
+		return MoMoStatusExpired
+	case models.MoMoTransactionStatusCancelled:
+		return MoMoStatusCancelled
+	default:
+		return MoMoStatusPending
+	}
+}
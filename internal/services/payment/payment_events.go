@@ -0,0 +1,87 @@
+package payment
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+)
+
+// paymentEventSummaryKeys are the provider event fields shown to merchants. Anything else in
+// a webhook, such as customer details and card authorizations, is left out.
+var paymentEventSummaryKeys = []string{
+	"status",
+	"gateway_response",
+	"message",
+	"channel",
+	"currency",
+	"paid_at",
+	"failure_code",
+	"failure_message",
+}
+
+// PaymentEvent is a provider event that affected a payment, as shown to the merchant
+type PaymentEvent struct {
+	ID          uuid.UUID              `json:"id"`
+	Provider    models.PaymentProvider `json:"provider"`
+	Event       string                 `json:"event"`
+	ReceivedAt  time.Time              `json:"received_at"`
+	Processed   bool                   `json:"processed"`
+	ProcessedAt *time.Time             `json:"processed_at,omitempty"`
+	Summary     map[string]interface{} `json:"summary"`
+}
+
+// GetPaymentEvents returns the provider webhooks received for a payment, oldest first. Webhooks
+// are matched by payment ID, or by reference for ones stored before the payment was linked.
+func (s *PaymentService) GetPaymentEvents(payment *models.Payment) ([]PaymentEvent, error) {
+	var webhooks []models.PaymentWebhook
+	query := s.db.Where("payment_id = ?", payment.ID)
+	if payment.Reference != "" {
+		query = query.Or("provider = ? AND reference = ?", payment.Provider, payment.Reference)
+	}
+	if err := query.Order("created_at ASC").Find(&webhooks).Error; err != nil {
+		return nil, fmt.Errorf("error getting payment events: %w", err)
+	}
+
+	events := make([]PaymentEvent, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		events = append(events, PaymentEvent{
+			ID:          webhook.ID,
+			Provider:    webhook.Provider,
+			Event:       webhook.Event,
+			ReceivedAt:  webhook.CreatedAt,
+			Processed:   webhook.Processed,
+			ProcessedAt: webhook.ProcessedAt,
+			Summary:     summarizeWebhook(webhook.RawData),
+		})
+	}
+	return events, nil
+}
+
+// summarizeWebhook picks the allowed summary fields out of a webhook's event data. Providers
+// put the data under "data" (Paystack) or "data.object" (Stripe).
+func summarizeWebhook(raw models.JSON) map[string]interface{} {
+	summary := make(map[string]interface{})
+
+	data, _ := raw["data"].(map[string]interface{})
+	if object, ok := data["object"].(map[string]interface{}); ok {
+		data = object
+	}
+	if data == nil {
+		return summary
+	}
+
+	for _, key := range paymentEventSummaryKeys {
+		value, ok := data[key]
+		if !ok || value == nil {
+			continue
+		}
+		// Only plain values; nested objects may carry customer or card details
+		switch value.(type) {
+		case string, float64, bool:
+			summary[key] = value
+		}
+	}
+	return summary
+}
@@ -0,0 +1,45 @@
+package payment
+
+import (
+	"testing"
+
+	"github.com/revaspay/backend/internal/models"
+)
+
+func TestSummarizeWebhook(t *testing.T) {
+	paystack := models.JSON{
+		"event": "charge.success",
+		"data": map[string]interface{}{
+			"status":           "success",
+			"gateway_response": "Approved",
+			"channel":          "card",
+			"amount":           float64(50000),
+			"customer":         map[string]interface{}{"email": "buyer@example.com"},
+			"authorization":    map[string]interface{}{"authorization_code": "AUTH_secret", "last4": "4081"},
+		},
+	}
+	summary := summarizeWebhook(paystack)
+	if summary["status"] != "success" || summary["gateway_response"] != "Approved" || summary["channel"] != "card" {
+		t.Errorf("missing summary fields: %v", summary)
+	}
+	for _, key := range []string{"amount", "customer", "authorization"} {
+		if _, ok := summary[key]; ok {
+			t.Errorf("summary should not include %s: %v", key, summary)
+		}
+	}
+
+	stripe := models.JSON{
+		"type": "payment_intent.payment_failed",
+		"data": map[string]interface{}{
+			"object": map[string]interface{}{"status": "requires_payment_method", "client_secret": "pi_secret"},
+		},
+	}
+	summary = summarizeWebhook(stripe)
+	if summary["status"] != "requires_payment_method" || len(summary) != 1 {
+		t.Errorf("unexpected stripe summary: %v", summary)
+	}
+
+	if summary := summarizeWebhook(models.JSON{"data": "unexpected"}); len(summary) != 0 {
+		t.Errorf("expected an empty summary, got %v", summary)
+	}
+}
@@ -0,0 +1,108 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// MaxPaymentLinkBatch bounds how many payment links one bulk create can make
+const MaxPaymentLinkBatch = 100
+
+var (
+	// ErrPaymentLinkBatchEmpty is returned when a bulk create has no links
+	ErrPaymentLinkBatchEmpty = errors.New("no payment links to create")
+
+	// ErrPaymentLinkBatchTooLarge is returned when a bulk create has more than MaxPaymentLinkBatch links
+	ErrPaymentLinkBatchTooLarge = fmt.Errorf("at most %d payment links can be created at once", MaxPaymentLinkBatch)
+)
+
+// PaymentLinkInput defines a payment link to create
+type PaymentLinkInput struct {
+	Title       string
+	Description string
+	Amount      float64
+	Currency    models.Currency
+	Slug        string // Optional; generated from the title when empty
+	Metadata    map[string]interface{}
+}
+
+// PaymentLinkItemError is why one link in a bulk create was rejected
+type PaymentLinkItemError struct {
+	Index int // Position of the link in the batch
+	Err   error
+}
+
+// PaymentLinkBatchError is returned when links in a bulk create are rejected. No links are
+// created when any are rejected.
+type PaymentLinkBatchError struct {
+	Items []PaymentLinkItemError
+}
+
+func (e *PaymentLinkBatchError) Error() string {
+	messages := make([]string, 0, len(e.Items))
+	for _, item := range e.Items {
+		messages = append(messages, fmt.Sprintf("link %d: %v", item.Index, item.Err))
+	}
+	return "invalid payment links: " + strings.Join(messages, "; ")
+}
+
+// CreatePaymentLinks creates a batch of payment links. Every link is validated as
+// CreatePaymentLink would; if any is rejected, a *PaymentLinkBatchError lists each rejected
+// link and nothing is created. The links are created in one transaction, so a failure part
+// way through leaves none behind.
+func (s *PaymentService) CreatePaymentLinks(userID uuid.UUID, inputs []PaymentLinkInput) ([]*models.PaymentLink, error) {
+	if len(inputs) == 0 {
+		return nil, ErrPaymentLinkBatchEmpty
+	}
+	if len(inputs) > MaxPaymentLinkBatch {
+		return nil, ErrPaymentLinkBatchTooLarge
+	}
+
+	links := make([]*models.PaymentLink, len(inputs))
+	var rejected []PaymentLinkItemError
+	customSlugs := make(map[string]int)
+	for i, input := range inputs {
+		if input.Slug != "" {
+			if first, ok := customSlugs[input.Slug]; ok {
+				rejected = append(rejected, PaymentLinkItemError{i, fmt.Errorf("%w: also used by link %d", ErrSlugTaken, first)})
+				continue
+			}
+			customSlugs[input.Slug] = i
+		}
+
+		link, err := s.newPaymentLink(userID, input)
+		if err != nil {
+			rejected = append(rejected, PaymentLinkItemError{i, err})
+			continue
+		}
+		links[i] = link
+	}
+	if len(rejected) > 0 {
+		return nil, &PaymentLinkBatchError{Items: rejected}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i, link := range links {
+			if err := insertPaymentLink(tx, link, inputs[i].Slug); err != nil {
+				if errors.Is(err, ErrSlugTaken) {
+					return &PaymentLinkBatchError{Items: []PaymentLinkItemError{{i, err}}}
+				}
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, link := range links {
+		s.auditPaymentLinkCreated(link)
+	}
+	return links, nil
+}
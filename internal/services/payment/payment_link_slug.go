@@ -0,0 +1,99 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gosimple/slug"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxSlugLength matches the payment_links.slug column
+	maxSlugLength = 100
+
+	// minCustomSlugLength keeps merchants from claiming very short slugs
+	minCustomSlugLength = 3
+
+	// slugSuffixLength is the number of random hex characters added to generated slugs
+	slugSuffixLength = 12
+
+	// maxSlugAttempts bounds how many generated slugs are tried before giving up
+	maxSlugAttempts = 5
+)
+
+var (
+	// ErrInvalidSlug is returned when a custom payment link slug isn't lower case letters,
+	// digits and single hyphens of a valid length
+	ErrInvalidSlug = errors.New("invalid payment link slug")
+
+	// ErrSlugTaken is returned when a custom payment link slug is already used by another link
+	ErrSlugTaken = errors.New("payment link slug is already taken")
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// validateCustomSlug checks a slug proposed by a merchant
+func validateCustomSlug(s string) error {
+	if len(s) < minCustomSlugLength || len(s) > maxSlugLength {
+		return fmt.Errorf("%w: must be %d to %d characters", ErrInvalidSlug, minCustomSlugLength, maxSlugLength)
+	}
+	if !slugPattern.MatchString(s) {
+		return fmt.Errorf("%w: use lower case letters, digits and single hyphens", ErrInvalidSlug)
+	}
+	return nil
+}
+
+// generateSlug makes a slug from the link's title with a random suffix
+func generateSlug(title string) string {
+	suffix := strings.ReplaceAll(uuid.New().String(), "-", "")[:slugSuffixLength]
+
+	base := slug.Make(title)
+	if maxBase := maxSlugLength - slugSuffixLength - 1; len(base) > maxBase {
+		base = strings.TrimRight(base[:maxBase], "-")
+	}
+	if base == "" {
+		return suffix
+	}
+	return base + "-" + suffix
+}
+
+// isDuplicateKey reports whether err is a unique constraint violation
+func isDuplicateKey(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey) ||
+		strings.Contains(err.Error(), "duplicate key") ||
+		strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// insertPaymentLink creates the link with customSlug, or with a generated slug that is
+// regenerated while it collides with another link's. The unique index on slug settles races
+// between concurrent creates. Each attempt runs in its own transaction, or savepoint when db
+// is already in one, so a collision doesn't abort the caller's transaction.
+func insertPaymentLink(db *gorm.DB, paymentLink *models.PaymentLink, customSlug string) error {
+	for attempt := 1; ; attempt++ {
+		paymentLink.Slug = customSlug
+		if customSlug == "" {
+			paymentLink.Slug = generateSlug(paymentLink.Title)
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return tx.Create(paymentLink).Error
+		})
+		if err == nil {
+			return nil
+		}
+		if !isDuplicateKey(err) {
+			return fmt.Errorf("error creating payment link: %w", err)
+		}
+		if customSlug != "" {
+			return ErrSlugTaken
+		}
+		if attempt == maxSlugAttempts {
+			return fmt.Errorf("error creating payment link: no free slug after %d attempts: %w", attempt, err)
+		}
+	}
+}
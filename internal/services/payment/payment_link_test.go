@@ -0,0 +1,86 @@
+package payment
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestCheckPaymentLinkActive(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		link   models.PaymentLink
+		active bool
+	}{
+		{"active", models.PaymentLink{Active: true}, true},
+		{"not yet expired", models.PaymentLink{Active: true, ExpiresAt: &future}, true},
+		{"deactivated", models.PaymentLink{Active: false}, false},
+		{"expired", models.PaymentLink{Active: true, ExpiresAt: &past}, false},
+		{"deleted", models.PaymentLink{Active: true, DeletedAt: gorm.DeletedAt{Time: past, Valid: true}}, false},
+	}
+	for _, tt := range tests {
+		err := checkPaymentLinkActive(&tt.link)
+		if tt.active && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !tt.active && err != ErrPaymentLinkInactive {
+			t.Errorf("%s: expected ErrPaymentLinkInactive, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidateCustomSlug(t *testing.T) {
+	tests := []struct {
+		slug  string
+		valid bool
+	}{
+		{"summer-sale", true},
+		{"abc", true},
+		{"sale2026", true},
+		{"ab", false},
+		{"Summer-Sale", false},
+		{"summer--sale", false},
+		{"-summer", false},
+		{"summer sale", false},
+		{strings.Repeat("a", maxSlugLength+1), false},
+	}
+	for _, tt := range tests {
+		err := validateCustomSlug(tt.slug)
+		if tt.valid && err != nil {
+			t.Errorf("%q: unexpected error %v", tt.slug, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidSlug) {
+			t.Errorf("%q: expected ErrInvalidSlug, got %v", tt.slug, err)
+		}
+	}
+}
+
+func TestGenerateSlug(t *testing.T) {
+	for _, title := range []string{"Summer Sale", strings.Repeat("long title ", 20), "!!!"} {
+		s := generateSlug(title)
+		if err := validateCustomSlug(s); err != nil {
+			t.Errorf("%q: generated invalid slug %q: %v", title, s, err)
+		}
+	}
+	if generateSlug("Summer Sale") == generateSlug("Summer Sale") {
+		t.Error("expected generated slugs to differ")
+	}
+}
+
+func TestCreatePaymentLinksBatchSize(t *testing.T) {
+	s := &PaymentService{}
+	if _, err := s.CreatePaymentLinks(uuid.New(), nil); !errors.Is(err, ErrPaymentLinkBatchEmpty) {
+		t.Errorf("expected ErrPaymentLinkBatchEmpty, got %v", err)
+	}
+	if _, err := s.CreatePaymentLinks(uuid.New(), make([]PaymentLinkInput, MaxPaymentLinkBatch+1)); !errors.Is(err, ErrPaymentLinkBatchTooLarge) {
+		t.Errorf("expected ErrPaymentLinkBatchTooLarge, got %v", err)
+	}
+}
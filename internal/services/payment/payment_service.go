@@ -1,38 +1,99 @@
 package payment
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/gosimple/slug"
+	"github.com/revaspay/backend/internal/circuitbreaker"
+	"github.com/revaspay/backend/internal/config"
 	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/money"
 	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/services/webhook"
+	"github.com/revaspay/backend/internal/utils"
 	"gorm.io/gorm"
 )
 
 // PaymentService handles payment operations
 type PaymentService struct {
 	db            *gorm.DB
-	walletService *wallet.WalletService
-	providers     map[models.PaymentProvider]PaymentProvider
+	walletService  *wallet.WalletService
+	webhookService *webhook.WebhookService
+	receiptQueue   JobEnqueuer
+	auditLogger    *utils.AuditLogger
+	providers      map[models.PaymentProvider]PaymentProvider
+	accountProviders map[models.PaymentProvider]AccountProviderFunc
+	credentialBox  *utils.SecretBox
+	amountLimits   map[string]config.AmountLimit
+	metadataLimits config.MetadataLimits
+	breakers       map[models.PaymentProvider]*circuitbreaker.Breaker
+	breakerSettings circuitbreaker.Settings
+	router         *PaymentRouter
+	paymentExpiry  time.Duration
+	providerExpiry map[string]time.Duration
 }
 
-// PaymentProvider interface for different payment providers
+// PaymentProvider interface for different payment providers. Calls to the provider's API
+// must use ctx, so they stop at the caller's deadline.
 type PaymentProvider interface {
-	InitiatePayment(payment *models.Payment) (string, error)
-	VerifyPayment(reference string) (*models.Payment, error)
+	InitiatePayment(ctx context.Context, payment *models.Payment) (*models.PaymentInitiation, error)
+	VerifyPayment(ctx context.Context, reference string) (*models.Payment, error)
 	ProcessWebhook(webhookData []byte) (*models.PaymentWebhook, error)
 }
 
+// RecurringPaymentProvider is implemented by providers that can charge a payment method
+// saved from an earlier payment without the customer present
+type RecurringPaymentProvider interface {
+	ChargeAuthorization(ctx context.Context, payment *models.Payment, authorizationCode string) error
+}
+
+// ActionPaymentProvider is implemented by providers whose payments can need the customer to
+// complete an action, such as a 3D Secure challenge or an OTP, before they're confirmed
+type ActionPaymentProvider interface {
+	// ConfirmPayment submits the result of the customer's action. It returns the next action
+	// when the provider needs another, or nil once the payment can be verified.
+	ConfirmPayment(ctx context.Context, payment *models.Payment, actionData map[string]string) (*models.PaymentAction, error)
+}
+
+var (
+	// ErrRecurringChargeUnsupported is returned when a provider can't charge saved payment methods
+	ErrRecurringChargeUnsupported = errors.New("provider does not support recurring charges")
+
+	// ErrChargeFailed is returned when a provider declines a charge on a saved payment method
+	ErrChargeFailed = errors.New("charge failed")
+
+	// ErrProviderTimeout is returned when a provider doesn't respond in time. The provider
+	// may still have taken the payment, so it's left pending until it's verified.
+	ErrProviderTimeout = errors.New("payment provider timed out")
+
+	// ErrPaymentLinkNotFound is returned when a payment link doesn't exist or belongs to another user
+	ErrPaymentLinkNotFound = errors.New("payment link not found")
+
+	// ErrPaymentLinkInactive is returned when paying through a link that was deactivated, deleted or has expired
+	ErrPaymentLinkInactive = errors.New("payment link is no longer active")
+
+	// ErrInvalidPaymentStatus is returned when filtering payments by a status they can't have
+	ErrInvalidPaymentStatus = errors.New("invalid payment status")
+)
+
 // NewPaymentService creates a new payment service
 func NewPaymentService(db *gorm.DB, walletService *wallet.WalletService) *PaymentService {
 	service := &PaymentService{
-		db:            db,
-		walletService: walletService,
-		providers:     make(map[models.PaymentProvider]PaymentProvider),
+		db:             db,
+		walletService:  walletService,
+		auditLogger:    utils.NewAuditLogger(db),
+		providers:      make(map[models.PaymentProvider]PaymentProvider),
+		accountProviders: make(map[models.PaymentProvider]AccountProviderFunc),
+		metadataLimits: config.DefaultMetadataLimits,
+		breakers:       make(map[models.PaymentProvider]*circuitbreaker.Breaker),
+		breakerSettings: circuitbreaker.DefaultSettings,
+		paymentExpiry:  DefaultPaymentExpiry,
 	}
 	
 	// Register providers here when they're implemented
@@ -44,33 +105,75 @@ func NewPaymentService(db *gorm.DB, walletService *wallet.WalletService) *Paymen
 	return service
 }
 
-// RegisterProvider registers a payment provider
+// RegisterProvider registers a payment provider. Calls to it go through a circuit breaker
+// named after it.
 func (s *PaymentService) RegisterProvider(name models.PaymentProvider, provider PaymentProvider) {
 	s.providers[name] = provider
+	s.breakers[name] = newProviderBreaker(name, s.breakerSettings)
 }
 
-// CreatePaymentLink creates a new payment link
-func (s *PaymentService) CreatePaymentLink(userID uuid.UUID, title, description string, amount float64, currency models.Currency, metadata map[string]interface{}) (*models.PaymentLink, error) {
-	// Generate a unique slug
-	baseSlug := slug.Make(title)
-	uniqueSlug := fmt.Sprintf("%s-%s", baseSlug, uuid.New().String()[:8])
-	
-	paymentLink := models.PaymentLink{
-		UserID:      userID,
+// SetWebhookService sets the service used to notify merchants of payment events
+func (s *PaymentService) SetWebhookService(webhookService *webhook.WebhookService) {
+	s.webhookService = webhookService
+}
+
+// CreatePaymentLink creates a new payment link. customSlug is the merchant's proposed slug;
+// when it's empty a slug is generated from the title, and regenerated if it's already taken.
+func (s *PaymentService) CreatePaymentLink(userID uuid.UUID, title, description string, amount float64, currency models.Currency, customSlug string, metadata map[string]interface{}) (*models.PaymentLink, error) {
+	paymentLink, err := s.newPaymentLink(userID, PaymentLinkInput{
 		Title:       title,
 		Description: description,
 		Amount:      amount,
 		Currency:    currency,
-		Slug:        uniqueSlug,
-		Active:      true,
-		Metadata:    models.JSON(metadata),
+		Slug:        customSlug,
+		Metadata:    metadata,
+	})
+	if err != nil {
+		return nil, err
 	}
 	
-	if err := s.db.Create(&paymentLink).Error; err != nil {
-		return nil, fmt.Errorf("error creating payment link: %w", err)
+	if err := insertPaymentLink(s.db, paymentLink, customSlug); err != nil {
+		return nil, err
 	}
 	
-	return &paymentLink, nil
+	s.auditPaymentLinkCreated(paymentLink)
+	
+	return paymentLink, nil
+}
+
+// newPaymentLink validates a payment link definition and builds the link, without a slug
+func (s *PaymentService) newPaymentLink(userID uuid.UUID, input PaymentLinkInput) (*models.PaymentLink, error) {
+	if input.Slug != "" {
+		if err := validateCustomSlug(input.Slug); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.checkMetadata(userID, input.Metadata); err != nil {
+		return nil, err
+	}
+	amount, err := s.normalizeAmount(userID, input.Amount, input.Currency)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &models.PaymentLink{
+		UserID:      userID,
+		Title:       input.Title,
+		Description: input.Description,
+		Amount:      amount,
+		Currency:    input.Currency,
+		Active:      true,
+		Metadata:    models.JSON(input.Metadata),
+	}, nil
+}
+
+// auditPaymentLinkCreated records a new payment link
+func (s *PaymentService) auditPaymentLinkCreated(paymentLink *models.PaymentLink) {
+	s.auditPaymentLink(utils.AuditEventPaymentLinkCreated, "Payment link created", paymentLink, map[string]interface{}{
+		"amount":   paymentLink.Amount,
+		"currency": paymentLink.Currency,
+		"title":    paymentLink.Title,
+	})
 }
 
 // GetPaymentLink gets a payment link by ID
@@ -82,15 +185,34 @@ func (s *PaymentService) GetPaymentLink(id uuid.UUID) (*models.PaymentLink, erro
 	return &paymentLink, nil
 }
 
-// GetPaymentLinkBySlug gets a payment link by slug
+// GetPaymentLinkBySlug gets a payment link that can still be paid by its slug. Links that
+// were deactivated, deleted or have expired return ErrPaymentLinkInactive.
 func (s *PaymentService) GetPaymentLinkBySlug(slug string) (*models.PaymentLink, error) {
 	var paymentLink models.PaymentLink
-	if err := s.db.First(&paymentLink, "slug = ? AND active = true", slug).Error; err != nil {
+	// Deleted links are looked up too, so payers are told the link is inactive rather than missing
+	if err := s.db.Unscoped().First(&paymentLink, "slug = ?", slug).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentLinkNotFound
+		}
 		return nil, fmt.Errorf("error finding payment link: %w", err)
 	}
+	if err := checkPaymentLinkActive(&paymentLink); err != nil {
+		return nil, err
+	}
 	return &paymentLink, nil
 }
 
+// checkPaymentLinkActive returns ErrPaymentLinkInactive unless the link can be paid
+func checkPaymentLinkActive(paymentLink *models.PaymentLink) error {
+	if paymentLink.DeletedAt.Valid || !paymentLink.Active {
+		return ErrPaymentLinkInactive
+	}
+	if paymentLink.ExpiresAt != nil && !paymentLink.ExpiresAt.After(time.Now()) {
+		return ErrPaymentLinkInactive
+	}
+	return nil
+}
+
 // GetUserPaymentLinks gets all payment links for a user
 func (s *PaymentService) GetUserPaymentLinks(userID uuid.UUID) ([]models.PaymentLink, error) {
 	var links []models.PaymentLink
@@ -100,39 +222,181 @@ func (s *PaymentService) GetUserPaymentLinks(userID uuid.UUID) ([]models.Payment
 	return links, nil
 }
 
-// UpdatePaymentLink updates a payment link
+// UpdatePaymentLink updates a payment link, recording the old and new value of each
+// changed field in the audit log
 func (s *PaymentService) UpdatePaymentLink(id uuid.UUID, userID uuid.UUID, updates map[string]interface{}) (*models.PaymentLink, error) {
 	var paymentLink models.PaymentLink
 	if err := s.db.First(&paymentLink, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentLinkNotFound
+		}
 		return nil, fmt.Errorf("error finding payment link: %w", err)
 	}
+	before := paymentLinkFields(&paymentLink)
+	
+	if metadata, ok := updates["metadata"].(map[string]interface{}); ok {
+		if err := s.checkMetadata(userID, metadata); err != nil {
+			return nil, err
+		}
+	}
+	
+	// A new amount or currency is bounded like a new link
+	amount, currency := paymentLink.Amount, paymentLink.Currency
+	if value, ok := updates["amount"].(float64); ok {
+		amount = value
+	}
+	if value, ok := updates["currency"].(models.Currency); ok {
+		currency = value
+	}
+	if amount != paymentLink.Amount || currency != paymentLink.Currency {
+		normalized, err := s.normalizeAmount(userID, amount, currency)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := updates["amount"]; ok {
+			updates["amount"] = normalized
+		}
+	}
 	
 	if err := s.db.Model(&paymentLink).Updates(updates).Error; err != nil {
 		return nil, fmt.Errorf("error updating payment link: %w", err)
 	}
 	
+	changes := make(map[string]interface{}, len(updates))
+	for field, value := range updates {
+		changes[field] = map[string]interface{}{"from": before[field], "to": value}
+	}
+	s.auditPaymentLink(utils.AuditEventPaymentLinkUpdated, "Payment link updated", &paymentLink, map[string]interface{}{
+		"changes": changes,
+	})
+	
 	return &paymentLink, nil
 }
 
-// DeletePaymentLink deletes a payment link
+// DeletePaymentLink soft-deletes a payment link so it can no longer be paid. Payments made
+// through the link are kept and still reference it.
 func (s *PaymentService) DeletePaymentLink(id uuid.UUID, userID uuid.UUID) error {
-	result := s.db.Delete(&models.PaymentLink{}, "id = ? AND user_id = ?", id, userID)
-	if result.Error != nil {
-		return fmt.Errorf("error deleting payment link: %w", result.Error)
+	var paymentLink models.PaymentLink
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&paymentLink, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+			return err
+		}
+		// Deactivate as well, so queries that include deleted rows still see the link as inactive
+		if err := tx.Model(&paymentLink).Update("active", false).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&paymentLink).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrPaymentLinkNotFound
 	}
-	if result.RowsAffected == 0 {
-		return errors.New("payment link not found or not owned by user")
+	if err != nil {
+		return fmt.Errorf("error deleting payment link: %w", err)
 	}
+	
+	s.auditPaymentLink(utils.AuditEventPaymentLinkDeleted, "Payment link deleted", &paymentLink, map[string]interface{}{
+		"title": paymentLink.Title,
+	})
 	return nil
 }
 
-// InitiatePayment initiates a payment using the specified provider
-func (s *PaymentService) InitiatePayment(userID uuid.UUID, provider models.PaymentProvider, amount float64, currency models.Currency, customerEmail, customerName string, metadata map[string]interface{}) (*models.Payment, string, error) {
-	// Check if provider is supported
-	paymentProvider, ok := s.providers[provider]
-	if !ok {
-		return nil, "", fmt.Errorf("unsupported payment provider: %s", provider)
+// auditStatusChange records the payment's move from previousStatus to its current status.
+// actorID is set when a user or admin caused the change.
+func (s *PaymentService) auditStatusChange(payment *models.Payment, previousStatus models.PaymentStatus, actor utils.AuditActor, actorID *uuid.UUID, reason string) {
+	if payment.Status == previousStatus {
+		return
+	}
+	if err := s.auditLogger.LogStatusTransition(context.Background(), utils.AuditEventPaymentStatus, utils.StatusTransition{
+		EntityID:  payment.ID,
+		OwnerID:   payment.UserID,
+		Reference: payment.Reference,
+		From:      string(previousStatus),
+		To:        string(payment.Status),
+		Amount:    payment.Amount,
+		Currency:  string(payment.Currency),
+		Actor:     actor,
+		ActorID:   actorID,
+		Reason:    reason,
+	}); err != nil {
+		log.Printf("Failed to write payment status audit event for %s: %v", payment.Reference, err)
+	}
+}
+
+// paymentLinkFields returns a link's editable fields by column name
+func paymentLinkFields(paymentLink *models.PaymentLink) map[string]interface{} {
+	return map[string]interface{}{
+		"title":       paymentLink.Title,
+		"description": paymentLink.Description,
+		"amount":      paymentLink.Amount,
+		"currency":    paymentLink.Currency,
+		"active":      paymentLink.Active,
+		"expires_at":  paymentLink.ExpiresAt,
+		"metadata":    paymentLink.Metadata,
+	}
+}
+
+// auditPaymentLink records a change to a payment link made by its owner
+func (s *PaymentService) auditPaymentLink(eventType utils.AuditEventType, description string, paymentLink *models.PaymentLink, details map[string]interface{}) {
+	details["payment_link_id"] = paymentLink.ID.String()
+	details["slug"] = paymentLink.Slug
+	userID := paymentLink.UserID
+	if err := s.auditLogger.LogEvent(context.Background(), eventType, utils.AuditSeverityInfo, description, &userID, nil, "", "", true, details); err != nil {
+		// The change is already saved, so a missing audit entry is logged rather than returned
+		log.Printf("Failed to write payment link audit event: %v", err)
+	}
+}
+
+// InitiatePayment initiates a payment using the specified provider. The initiation has the
+// checkout URL and, when the provider needs one first, the action the customer must complete
+// before the payment is confirmed with ConfirmPayment.
+//
+// splits may be empty. Otherwise they share the payment with other merchants' wallets, and
+// optionally a platform fee, when it completes; the merchant is credited what they leave.
+func (s *PaymentService) InitiatePayment(ctx context.Context, userID uuid.UUID, provider models.PaymentProvider, amount float64, currency models.Currency, customerEmail, customerName string, metadata map[string]interface{}, splits []SplitInput) (*models.Payment, *models.PaymentInitiation, error) {
+	if err := s.checkMetadata(userID, metadata); err != nil {
+		return nil, nil, err
+	}
+	return s.initiatePayment(ctx, nil, userID, provider, amount, currency, customerEmail, customerName, metadata, splits)
+}
+
+// InitiateRoutedPayment initiates a payment with the provider the router picks for it,
+// failing over to the next provider while one's circuit breaker is open. country is the
+// customer's ISO 3166 code, and may be empty. splits are as for InitiatePayment.
+func (s *PaymentService) InitiateRoutedPayment(ctx context.Context, userID uuid.UUID, country string, amount float64, currency models.Currency, customerEmail, customerName string, metadata map[string]interface{}, splits []SplitInput) (*models.Payment, *models.PaymentInitiation, error) {
+	if err := s.checkMetadata(userID, metadata); err != nil {
+		return nil, nil, err
+	}
+	return s.initiateRoutedPayment(ctx, nil, userID, country, amount, currency, customerEmail, customerName, metadata, splits)
+}
+
+// initiatePayment initiates a payment, linking it to the payment link it was made through if any.
+// If the provider times out the payment stays pending and is returned with an error wrapping
+// ErrProviderTimeout; verifying it later settles it. While the provider's circuit breaker is
+// open no payment is created and the error wraps ErrProviderUnavailable.
+func (s *PaymentService) initiatePayment(ctx context.Context, paymentLinkID *uuid.UUID, userID uuid.UUID, provider models.PaymentProvider, amount float64, currency models.Currency, customerEmail, customerName string, metadata map[string]interface{}, splits []SplitInput) (*models.Payment, *models.PaymentInitiation, error) {
+	// Check if provider is supported, and whether the merchant has their own account with it
+	paymentProvider, merchantAccount, err := s.providerFor(provider, userID)
+	if err != nil {
+		return nil, nil, err
 	}
+	if merchantAccount && len(splits) > 0 {
+		return nil, nil, ErrMerchantAccountSplits
+	}
+	amount, err = s.normalizeAmount(userID, amount, currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkSplits(amount, currency, splits); err != nil {
+		return nil, nil, err
+	}
+	
+	// Reserve the provider call before creating the payment, so an outage doesn't leave
+	// pending payments behind that were never sent
+	call, err := s.allowProviderCall(provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer call.Release()
 	
 	// Generate a unique reference
 	reference := fmt.Sprintf("REV-%s", uuid.New().String()[:12])
@@ -140,6 +404,7 @@ func (s *PaymentService) InitiatePayment(userID uuid.UUID, provider models.Payme
 	// Create payment record
 	payment := models.Payment{
 		UserID:        userID,
+		PaymentLinkID: paymentLinkID,
 		Amount:        amount,
 		Currency:      currency,
 		Provider:      provider,
@@ -148,51 +413,195 @@ func (s *PaymentService) InitiatePayment(userID uuid.UUID, provider models.Payme
 		CustomerEmail: customerEmail,
 		CustomerName:  customerName,
 		Metadata:      models.JSON(metadata),
+		ExpiresAt:     s.expiresAt(provider, time.Now()),
+		MerchantAccount: merchantAccount,
 	}
 	
-	if err := s.db.Create(&payment).Error; err != nil {
-		return nil, "", fmt.Errorf("error creating payment record: %w", err)
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&payment).Error; err != nil {
+			return fmt.Errorf("error creating payment record: %w", err)
+		}
+		return createSplits(tx, &payment, splits)
+	}); err != nil {
+		return nil, nil, err
+	}
+	// Payments through a link are started by an anonymous payer rather than the merchant
+	if paymentLinkID != nil {
+		s.auditStatusChange(&payment, "", utils.AuditActorUser, nil, "paid through payment link")
+	} else {
+		s.auditStatusChange(&payment, "", utils.AuditActorUser, &userID, "")
 	}
 	
 	// Initiate payment with provider
-	checkoutURL, err := paymentProvider.InitiatePayment(&payment)
+	initiation, err := paymentProvider.InitiatePayment(ctx, &payment)
+	call.Done(err)
+	if utils.IsTimeout(err) {
+		if dbErr := s.db.Model(&payment).Update("error", err.Error()).Error; dbErr != nil {
+			log.Printf("Failed to record timeout on payment %s: %v", payment.Reference, dbErr)
+		}
+		return &payment, nil, fmt.Errorf("%w: %v", ErrProviderTimeout, err)
+	}
 	if err != nil {
 		// Update payment status to failed
-		s.db.Model(&payment).Updates(map[string]interface{}{
+		if dbErr := s.db.Model(&payment).Updates(map[string]interface{}{
 			"status": models.PaymentStatusFailed,
 			"error":  err.Error(),
-		})
-		return nil, "", fmt.Errorf("error initiating payment: %w", err)
+		}).Error; dbErr != nil {
+			log.Printf("Failed to mark payment %s as failed: %v", payment.Reference, dbErr)
+		}
+		payment.Status = models.PaymentStatusFailed
+		s.auditStatusChange(&payment, models.PaymentStatusPending, utils.AuditActorSystem, nil, err.Error())
+		return nil, nil, fmt.Errorf("error initiating payment: %w", err)
+	}
+	
+	if initiation.Action != nil {
+		payment.RequiredAction = initiation.Action.Type
+		if err := s.db.Model(&payment).Update("required_action", payment.RequiredAction).Error; err != nil {
+			log.Printf("Failed to record required action on payment %s: %v", payment.Reference, err)
+		}
 	}
 	
-	return &payment, checkoutURL, nil
+	return &payment, initiation, nil
 }
 
-// InitiatePaymentFromLink initiates a payment from a payment link
-func (s *PaymentService) InitiatePaymentFromLink(paymentLinkID uuid.UUID, provider models.PaymentProvider, customerEmail, customerName string) (*models.Payment, string, error) {
-	// Get payment link
-	var paymentLink models.PaymentLink
-	if err := s.db.First(&paymentLink, "id = ? AND active = true", paymentLinkID).Error; err != nil {
-		return nil, "", fmt.Errorf("error finding payment link: %w", err)
+// ChargeSavedPaymentMethod charges a payment method saved from an earlier payment, such as a
+// Paystack card authorization, and credits the user's wallet when the charge succeeds. The
+// payment record is returned even when the charge fails, along with an error wrapping
+// ErrChargeFailed.
+//
+// reference may be empty to generate one. Callers that retry a charge pass the same
+// reference, and an earlier payment with it is returned instead of charging again. A charge
+// the provider didn't answer in time stays pending, with an error wrapping ErrProviderTimeout,
+// and is verified when it's retried. Nothing is charged while the provider's circuit breaker is
+// open; the error then wraps ErrProviderUnavailable.
+func (s *PaymentService) ChargeSavedPaymentMethod(ctx context.Context, userID uuid.UUID, provider models.PaymentProvider, amount float64, currency models.Currency, customerEmail, customerName, authorizationCode, reference string, metadata map[string]interface{}) (*models.Payment, error) {
+	paymentProvider, merchantAccount, err := s.providerFor(provider, userID)
+	if err != nil {
+		return nil, err
+	}
+	recurringProvider, ok := paymentProvider.(RecurringPaymentProvider)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRecurringChargeUnsupported, provider)
 	}
 	
-	// Create metadata with payment link info
-	metadata := map[string]interface{}{
-		"payment_link_id":   paymentLink.ID.String(),
-		"payment_link_slug": paymentLink.Slug,
-		"payment_link_title": paymentLink.Title,
+	if reference == "" {
+		reference = fmt.Sprintf("REV-%s", uuid.New().String()[:12])
+	} else if existing, err := s.existingCharge(ctx, reference); existing != nil || err != nil {
+		return existing, err
+	}
+	
+	call, err := s.allowProviderCall(provider)
+	if err != nil {
+		return nil, err
+	}
+	defer call.Release()
+	
+	payment := models.Payment{
+		UserID:        userID,
+		Amount:        amount,
+		Currency:      currency,
+		Provider:      provider,
+		Status:        models.PaymentStatusPending,
+		Reference:     reference,
+		CustomerEmail: customerEmail,
+		CustomerName:  customerName,
+		Metadata:      models.JSON(metadata),
+		MerchantAccount: merchantAccount,
+	}
+	
+	if err := s.db.Create(&payment).Error; err != nil {
+		return nil, fmt.Errorf("error creating payment record: %w", err)
+	}
+	s.auditStatusChange(&payment, "", utils.AuditActorSystem, nil, "charge on saved payment method")
+	
+	chargeErr := recurringProvider.ChargeAuthorization(ctx, &payment, authorizationCode)
+	call.Done(chargeErr)
+	if utils.IsTimeout(chargeErr) {
+		return &payment, fmt.Errorf("%w: %v", ErrProviderTimeout, chargeErr)
+	}
+	if chargeErr != nil {
+		if err := s.db.Model(&payment).Updates(map[string]interface{}{
+			"status":         models.PaymentStatusFailed,
+			"provider_ref":   payment.ProviderRef,
+			"payment_method": payment.PaymentMethod,
+		}).Error; err != nil {
+			log.Printf("Failed to mark payment %s as failed: %v", payment.Reference, err)
+		}
+		payment.Status = models.PaymentStatusFailed
+		s.auditStatusChange(&payment, models.PaymentStatusPending, utils.AuditActorSystem, nil, chargeErr.Error())
+		return &payment, fmt.Errorf("%w: %v", ErrChargeFailed, chargeErr)
+	}
+	
+	if err := s.processSuccessfulPayment(&payment, models.PaymentStatusPending, "charge on saved payment method"); err != nil {
+		return &payment, fmt.Errorf("error processing successful payment: %w", err)
+	}
+	
+	return &payment, nil
+}
+
+// existingCharge returns the outcome of an earlier charge with the reference, or nil if there
+// wasn't one. A charge left pending, e.g. by a crash while it was in flight, is verified
+// with the provider.
+func (s *PaymentService) existingCharge(ctx context.Context, reference string) (*models.Payment, error) {
+	var payment models.Payment
+	if err := s.db.First(&payment, "reference = ?", reference).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error finding payment: %w", err)
 	}
 	
-	// Add original metadata if any
-	if paymentLink.Metadata != nil {
-		originalMetadata := map[string]interface{}(paymentLink.Metadata)
-		for k, v := range originalMetadata {
-			metadata[k] = v
+	if payment.Status == models.PaymentStatusPending {
+		if _, err := s.VerifyPayment(ctx, reference); err != nil {
+			return nil, err
+		}
+		if err := s.db.First(&payment, "reference = ?", reference).Error; err != nil {
+			return nil, fmt.Errorf("error finding payment: %w", err)
 		}
 	}
 	
+	switch payment.Status {
+	case models.PaymentStatusCompleted:
+		return &payment, nil
+	case models.PaymentStatusFailed:
+		return &payment, fmt.Errorf("%w: payment %s already failed", ErrChargeFailed, reference)
+	default:
+		return nil, fmt.Errorf("payment %s is still %s", reference, payment.Status)
+	}
+}
+
+// InitiatePaymentFromLink initiates a payment from a payment link. When provider is empty the
+// router picks one, using the customer's country if it's known.
+func (s *PaymentService) InitiatePaymentFromLink(ctx context.Context, paymentLinkID uuid.UUID, provider models.PaymentProvider, country, customerEmail, customerName string) (*models.Payment, *models.PaymentInitiation, error) {
+	// Get payment link
+	var paymentLink models.PaymentLink
+	if err := s.db.Unscoped().First(&paymentLink, "id = ?", paymentLinkID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrPaymentLinkNotFound
+		}
+		return nil, nil, fmt.Errorf("error finding payment link: %w", err)
+	}
+	if err := checkPaymentLinkActive(&paymentLink); err != nil {
+		return nil, nil, err
+	}
+	
+	// Start from the link's metadata; the link details are set after it so they can't be
+	// overwritten by links created before reserved keys were rejected
+	metadata := make(map[string]interface{}, len(paymentLink.Metadata)+3)
+	for k, v := range paymentLink.Metadata {
+		metadata[k] = v
+	}
+	metadata["payment_link_id"] = paymentLink.ID.String()
+	metadata["payment_link_slug"] = paymentLink.Slug
+	metadata["payment_link_title"] = paymentLink.Title
+	
 	// Initiate payment
-	return s.InitiatePayment(
+	if provider == "" {
+		return s.initiateRoutedPayment(ctx, &paymentLink.ID, paymentLink.UserID, country, paymentLink.Amount, paymentLink.Currency, customerEmail, customerName, metadata, nil)
+	}
+	return s.initiatePayment(
+		ctx,
+		&paymentLink.ID,
 		paymentLink.UserID,
 		provider,
 		paymentLink.Amount,
@@ -200,46 +609,81 @@ func (s *PaymentService) InitiatePaymentFromLink(paymentLinkID uuid.UUID, provid
 		customerEmail,
 		customerName,
 		metadata,
+		nil,
 	)
 }
 
-// VerifyPayment verifies a payment using the specified provider
-func (s *PaymentService) VerifyPayment(reference string) (*models.Payment, error) {
+// VerifyPayment verifies a payment using the specified provider. An expired payment is only
+// completed if the provider says it was paid before it expired; otherwise it stays expired, and
+// a payment the provider took after expiry is recorded for refunding rather than credited.
+func (s *PaymentService) VerifyPayment(ctx context.Context, reference string) (*models.Payment, error) {
 	// Find payment by reference
 	var payment models.Payment
 	if err := s.db.First(&payment, "reference = ?", reference).Error; err != nil {
 		return nil, fmt.Errorf("error finding payment: %w", err)
 	}
 	
-	// Get provider
-	provider, ok := s.providers[payment.Provider]
-	if !ok {
-		return nil, fmt.Errorf("unsupported payment provider: %s", payment.Provider)
+	// Get provider, acting on the merchant's own account if the payment was made through it
+	provider, _, err := s.providerFor(payment.Provider, payment.UserID)
+	if err != nil {
+		return nil, err
 	}
 	
 	// Verify payment with provider
-	updatedPayment, err := provider.VerifyPayment(reference)
+	call, err := s.allowProviderCall(payment.Provider)
+	if err != nil {
+		return nil, err
+	}
+	updatedPayment, err := provider.VerifyPayment(ctx, reference)
+	call.Done(err)
 	if err != nil {
 		return nil, fmt.Errorf("error verifying payment: %w", err)
 	}
 	
 	// Update payment record
-	if err := s.db.Model(&payment).Updates(map[string]interface{}{
-		"status":        updatedPayment.Status,
+	previousStatus := payment.Status
+	status := updatedPayment.Status
+	reason := "verified with provider"
+	var paymentError string
+	if isExpired(&payment, time.Now()) {
+		switch {
+		case status == models.PaymentStatusCompleted && !paidBeforeExpiry(&payment, updatedPayment):
+			log.Printf("Payment %s was paid after it expired and needs refunding", payment.Reference)
+			status = models.PaymentStatusExpired
+			reason = "paid after expiring, not credited"
+			paymentError = "paid after the payment expired"
+		case status == models.PaymentStatusPending:
+			status = models.PaymentStatusExpired
+			reason = "not paid before expiring"
+		}
+	}
+	fields := map[string]interface{}{
+		"status":        status,
 		"provider_ref":  updatedPayment.ProviderRef,
 		"provider_fee":  updatedPayment.ProviderFee,
 		"payment_method": updatedPayment.PaymentMethod,
 		"payment_details": updatedPayment.PaymentDetails,
 		"receipt_url":   updatedPayment.ReceiptURL,
-	}).Error; err != nil {
+		"paid_at":       updatedPayment.PaidAt,
+	}
+	if paymentError != "" {
+		fields["error"] = paymentError
+	}
+	if err := s.db.Model(&payment).Updates(fields).Error; err != nil {
 		return nil, fmt.Errorf("error updating payment record: %w", err)
 	}
 	
 	// If payment is completed, credit user's wallet
-	if updatedPayment.Status == models.PaymentStatusCompleted {
-		if err := s.processSuccessfulPayment(&payment); err != nil {
+	if status == models.PaymentStatusCompleted {
+		if err := s.processSuccessfulPayment(&payment, previousStatus, reason); err != nil {
 			return nil, fmt.Errorf("error processing successful payment: %w", err)
 		}
+	} else {
+		payment.Status = status
+		s.auditStatusChange(&payment, previousStatus, utils.AuditActorSystem, nil, reason)
+		if status == models.PaymentStatusExpired && previousStatus != models.PaymentStatusExpired {
+			s.dispatchPaymentExpired(&payment)
+		}
 	}
 	
 	return &payment, nil
@@ -259,52 +703,96 @@ func (s *PaymentService) ProcessWebhook(provider models.PaymentProvider, data []
 		return nil, fmt.Errorf("error processing webhook: %w", err)
 	}
 	
-	// Save webhook
-	if err := s.db.Create(webhook).Error; err != nil {
+	// Providers retry deliveries, so return the stored result if we've already processed this event.
+	// An event that was stored but failed processing is picked up again using the existing row.
+	var existing *models.PaymentWebhook
+	if webhook.ProviderEventID != nil {
+		existing, err = s.findWebhookByEventID(provider, *webhook.ProviderEventID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.Processed {
+			return existing, nil
+		}
+	}
+	
+	if existing != nil {
+		webhook = existing
+	} else if err := s.db.Create(webhook).Error; err != nil {
+		// A concurrent delivery of the same event may have won the unique constraint
+		if webhook.ProviderEventID != nil {
+			if existing, findErr := s.findWebhookByEventID(provider, *webhook.ProviderEventID); findErr == nil && existing != nil {
+				return existing, nil
+			}
+		}
 		return nil, fmt.Errorf("error saving webhook: %w", err)
 	}
 	
 	// If webhook has a payment reference, update the payment
 	if webhook.Reference != "" {
 		var payment models.Payment
-		if err := s.db.First(&payment, "reference = ?", webhook.Reference).Error; err == nil {
+		if err := s.db.First(&payment, "reference = ? AND provider = ?", webhook.Reference, provider).Error; err == nil {
 			// Update payment with webhook data
-			payment.WebhookReceived = true
-			payment.WebhookData = webhook.RawData
+			if err := s.db.Model(&payment).Updates(map[string]interface{}{
+				"webhook_received": true,
+				"webhook_data":     webhook.RawData,
+			}).Error; err != nil {
+				return nil, fmt.Errorf("error updating payment: %w", err)
+			}
 			
-			// If webhook indicates payment is completed, update status
+			// A webhook saying the payment completed is only a prompt to verify it: the
+			// provider's verify API decides whether it's credited, and VerifyPayment also
+			// settles payments that expired before the webhook arrived
 			if strings.Contains(strings.ToLower(webhook.Event), "success") || 
 			   strings.Contains(strings.ToLower(webhook.Event), "complete") {
-				payment.Status = models.PaymentStatusCompleted
-				
-				// Process successful payment
-				if err := s.processSuccessfulPayment(&payment); err != nil {
-					return nil, fmt.Errorf("error processing successful payment: %w", err)
+				if _, err := s.VerifyPayment(context.Background(), payment.Reference); err != nil {
+					return nil, fmt.Errorf("error verifying payment: %w", err)
 				}
 			}
 			
-			// Save payment
-			s.db.Save(&payment)
-			
 			// Update webhook with payment ID
 			webhook.PaymentID = &payment.ID
-			s.db.Save(webhook)
 		}
 	}
 	
+	// Mark webhook as processed
+	now := time.Now()
+	webhook.Processed = true
+	webhook.ProcessedAt = &now
+	if err := s.db.Save(webhook).Error; err != nil {
+		// The webhook stays unprocessed, so the provider's retry processes it again
+		return nil, fmt.Errorf("error marking webhook processed: %w", err)
+	}
+	
 	return webhook, nil
 }
 
-// processSuccessfulPayment handles a successful payment by crediting the user's wallet
-func (s *PaymentService) processSuccessfulPayment(payment *models.Payment) error {
+// findWebhookByEventID returns a previously stored webhook for a provider event, or nil if none exists
+func (s *PaymentService) findWebhookByEventID(provider models.PaymentProvider, eventID string) (*models.PaymentWebhook, error) {
+	var webhook models.PaymentWebhook
+	if err := s.db.First(&webhook, "provider = ? AND provider_event_id = ?", provider, eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error finding webhook: %w", err)
+	}
+	return &webhook, nil
+}
+
+// processSuccessfulPayment handles a successful payment by crediting the user's wallet, and
+// the recipients of a split payment, and auditing its move from previousStatus to completed
+func (s *PaymentService) processSuccessfulPayment(payment *models.Payment, previousStatus models.PaymentStatus, reason string) error {
 	// Get or create wallet for user
-	wallet, err := s.walletService.GetOrCreateWallet(payment.UserID, payment.Currency)
+	userWallet, err := s.walletService.GetOrCreateWallet(payment.UserID, payment.Currency)
 	if err != nil {
 		return fmt.Errorf("error getting wallet: %w", err)
 	}
 	
-	// Calculate net amount (after fees)
-	netAmount := payment.Amount - payment.Fee - payment.ProviderFee
+	// Calculate net amount (after fees), rounding the fees and the credit with their own modes
+	// so float error isn't credited
+	rounding := money.CurrentPolicy()
+	fees := money.Round(payment.Fee+payment.ProviderFee, payment.Currency, rounding.Fee)
+	netAmount := money.Round(payment.Amount-fees, payment.Currency, rounding.Customer)
 	
 	// Credit wallet
 	metadata := map[string]interface{}{
@@ -314,15 +802,41 @@ func (s *PaymentService) processSuccessfulPayment(payment *models.Payment) error
 		"provider_ref":    payment.ProviderRef,
 	}
 	
-	_, err = s.walletService.Credit(
-		wallet.ID,
-		netAmount,
-		"payment",
-		payment.Reference,
-		fmt.Sprintf("Payment from %s", payment.CustomerEmail),
-		metadata,
-	)
+	splits, err := s.GetPaymentSplits(payment.ID)
+	if err != nil {
+		return err
+	}
 	
+	// Verification, webhooks and replays can all complete the same payment, so only the
+	// first one credits the wallet. With batching on the credit is applied with the wallet's
+	// next batch; split payments credit every party together, so they aren't batched.
+	description := fmt.Sprintf("Payment from %s", payment.CustomerEmail)
+	if payment.MerchantAccount {
+		// The provider paid the merchant's own account, so the platform holds nothing to credit
+		if previousStatus == models.PaymentStatusCompleted {
+			return nil
+		}
+		if err := s.db.Model(payment).Update("status", models.PaymentStatusCompleted).Error; err != nil {
+			return fmt.Errorf("error updating payment status: %w", err)
+		}
+	} else if len(splits) > 0 {
+		err = s.creditSplits(payment, userWallet.ID, netAmount, splits, description, metadata)
+	} else {
+		err = s.walletService.CreditOnceBatched(
+			userWallet.ID,
+			netAmount,
+			"payment",
+			models.LedgerCategorySales,
+			payment.Reference,
+			description,
+			metadata,
+		)
+	}
+	if errors.Is(err, wallet.ErrDuplicateCredit) {
+		log.Printf("Payment %s was already credited, skipping", payment.Reference)
+		payment.Status = models.PaymentStatusCompleted
+		return s.db.Model(payment).Update("status", models.PaymentStatusCompleted).Error
+	}
 	if err != nil {
 		return fmt.Errorf("error crediting wallet: %w", err)
 	}
@@ -330,6 +844,25 @@ func (s *PaymentService) processSuccessfulPayment(payment *models.Payment) error
 	// Mark payment as processed
 	payment.Status = models.PaymentStatusCompleted
 	s.db.Save(payment)
+	s.auditStatusChange(payment, previousStatus, utils.AuditActorSystem, nil, reason)
+	
+	// Notify the merchant's webhook endpoints
+	if s.webhookService != nil {
+		if err := s.webhookService.Dispatch(payment.UserID, models.WebhookEventPaymentCompleted, map[string]interface{}{
+			"payment_id":     payment.ID.String(),
+			"reference":      payment.Reference,
+			"amount":         payment.Amount,
+			"fee":            fees,
+			"net_amount":     netAmount,
+			"currency":       string(payment.Currency),
+			"provider":       string(payment.Provider),
+			"customer_email": payment.CustomerEmail,
+			"status":         string(payment.Status),
+		}); err != nil {
+			log.Printf("Failed to dispatch payment webhook for %s: %v", payment.Reference, err)
+		}
+	}
+	s.enqueueReceipt(payment)
 	
 	return nil
 }
@@ -352,19 +885,57 @@ func (s *PaymentService) GetPaymentByReference(reference string) (*models.Paymen
 	return &payment, nil
 }
 
-// GetUserPayments gets all payments for a user
-func (s *PaymentService) GetUserPayments(userID uuid.UUID, page, pageSize int) ([]models.Payment, int64, error) {
+// PaymentFilter narrows a merchant's payments. Empty fields match everything.
+type PaymentFilter struct {
+	Status    models.PaymentStatus
+	Provider  models.PaymentProvider
+	Currency  models.Currency
+	Reference string    // Matches references containing it, ignoring case
+	From      time.Time // Created at or after
+	To        time.Time // Created before
+}
+
+// likeEscaper escapes the LIKE wildcards in a value matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetUserPayments gets a user's payments matching filter, newest first. It returns
+// ErrInvalidPaymentStatus if the filter's status isn't one payments have.
+func (s *PaymentService) GetUserPayments(userID uuid.UUID, filter PaymentFilter, page, pageSize int) ([]models.Payment, int64, error) {
 	var payments []models.Payment
 	var total int64
 	
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, 0, fmt.Errorf("%w: %q", ErrInvalidPaymentStatus, filter.Status)
+	}
+	
+	query := s.db.Model(&models.Payment{}).Where("user_id = ?", userID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Provider != "" {
+		query = query.Where("provider = ?", filter.Provider)
+	}
+	if filter.Currency != "" {
+		query = query.Where("currency = ?", filter.Currency)
+	}
+	if filter.Reference != "" {
+		query = query.Where(`LOWER(reference) LIKE ? ESCAPE '\'`, "%"+likeEscaper.Replace(strings.ToLower(filter.Reference))+"%")
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	
 	// Count total records
-	if err := s.db.Model(&models.Payment{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, fmt.Errorf("error counting payments: %w", err)
 	}
 	
 	// Get paginated records
 	offset := (page - 1) * pageSize
-	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&payments).Error; err != nil {
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&payments).Error; err != nil {
 		return nil, 0, fmt.Errorf("error finding payments: %w", err)
 	}
 	
@@ -372,7 +943,15 @@ func (s *PaymentService) GetUserPayments(userID uuid.UUID, page, pageSize int) (
 }
 
 // InitiateCryptoPayment initiates a cryptocurrency payment
-func (s *PaymentService) InitiateCryptoPayment(userID uuid.UUID, amount float64, currency models.Currency, network, cryptoCurrency string, metadata map[string]interface{}) (*models.Payment, *models.CryptoPayment, error) {
+func (s *PaymentService) InitiateCryptoPayment(ctx context.Context, userID uuid.UUID, amount float64, currency models.Currency, network, cryptoCurrency string, metadata map[string]interface{}) (*models.Payment, *models.CryptoPayment, error) {
+	if err := s.checkMetadata(userID, metadata); err != nil {
+		return nil, nil, err
+	}
+	amount, err := s.normalizeAmount(userID, amount, currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	
 	// Generate a unique reference
 	reference := fmt.Sprintf("CRYPTO-%s", uuid.New().String()[:12])
 	
@@ -386,6 +965,7 @@ func (s *PaymentService) InitiateCryptoPayment(userID uuid.UUID, amount float64,
 		Reference:     reference,
 		PaymentMethod: "crypto",
 		Metadata:      models.JSON(metadata),
+		ExpiresAt:     s.expiresAt(models.PaymentProviderCrypto, time.Now()),
 	}
 	
 	// Get crypto provider
@@ -412,7 +992,13 @@ func (s *PaymentService) InitiateCryptoPayment(userID uuid.UUID, amount float64,
 	}
 	
 	// Call provider to get address
-	_, err := provider.InitiatePayment(&payment)
+	call, err := s.allowProviderCall(models.PaymentProviderCrypto)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	_, err = provider.InitiatePayment(ctx, &payment)
+	call.Done(err)
 	if err != nil {
 		tx.Rollback()
 		return nil, nil, fmt.Errorf("error initiating crypto payment: %w", err)
@@ -468,6 +1054,7 @@ func (s *PaymentService) UpdateCryptoPayment(cryptoPaymentID uuid.UUID, txHash s
 			return fmt.Errorf("error finding payment: %w", err)
 		}
 		
+		previousStatus := payment.Status
 		payment.Status = models.PaymentStatusCompleted
 		payment.PaymentDetails = models.JSON(map[string]interface{}{
 			"tx_hash":      txHash,
@@ -483,7 +1070,7 @@ func (s *PaymentService) UpdateCryptoPayment(cryptoPaymentID uuid.UUID, txHash s
 		}
 		
 		// Process successful payment
-		if err := s.processSuccessfulPayment(&payment); err != nil {
+		if err := s.processSuccessfulPayment(&payment, previousStatus, fmt.Sprintf("%d confirmations of %s", confirmations, txHash)); err != nil {
 			return fmt.Errorf("error processing successful payment: %w", err)
 		}
 	}
@@ -0,0 +1,319 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/services/payment/paymenttest"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const fakeProvider models.PaymentProvider = "fake"
+
+var (
+	_ PaymentProvider       = (*paymenttest.FakePaymentProvider)(nil)
+	_ ActionPaymentProvider = (*paymenttest.FakePaymentProvider)(nil)
+)
+
+// newTestPaymentService returns a payment service backed by a test database with a fake
+// provider registered
+func newTestPaymentService(t *testing.T) (*PaymentService, *paymenttest.FakePaymentProvider, *gorm.DB) {
+	db := testutil.NewDB(t)
+	provider := paymenttest.NewFakePaymentProvider(fakeProvider)
+	service := NewPaymentService(db, wallet.NewWalletService(db))
+	service.RegisterProvider(fakeProvider, provider)
+	return service, provider, db
+}
+
+// walletBalance returns the user's balance in a currency, or zero if they have no wallet
+func walletBalance(t *testing.T, db *gorm.DB, user *models.User, code models.Currency) float64 {
+	var w models.Wallet
+	err := db.Where("user_id = ? AND currency = ?", user.ID, code).First(&w).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0
+	}
+	require.NoError(t, err)
+	return w.Balance
+}
+
+func TestInitiateAndVerifyPayment(t *testing.T) {
+	service, provider, db := newTestPaymentService(t)
+	user := testutil.CreateUser(t, db)
+	ctx := context.Background()
+
+	payment, initiation, err := service.InitiatePayment(ctx, user.ID, fakeProvider, 100, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://checkout.test/"+payment.Reference, initiation.CheckoutURL)
+	assert.Nil(t, initiation.Action)
+	assert.Equal(t, models.PaymentStatusPending, payment.Status)
+	assert.Equal(t, []string{payment.Reference}, provider.Initiated())
+
+	// Still pending at the provider: nothing is credited
+	verified, err := service.VerifyPayment(ctx, payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusPending, verified.Status)
+	assert.Zero(t, walletBalance(t, db, user, models.CurrencyGHS))
+
+	provider.SetStatus(payment.Reference, models.PaymentStatusCompleted)
+	verified, err = service.VerifyPayment(ctx, payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusCompleted, verified.Status)
+	assert.Equal(t, 100.0, walletBalance(t, db, user, models.CurrencyGHS))
+
+	// Verifying again doesn't credit twice
+	_, err = service.VerifyPayment(ctx, payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, walletBalance(t, db, user, models.CurrencyGHS))
+
+	var ledgerEntries int64
+	require.NoError(t, db.Model(&models.WalletLedgerEntry{}).Where("reference = ?", payment.Reference).Count(&ledgerEntries).Error)
+	assert.EqualValues(t, 1, ledgerEntries)
+}
+
+func TestInitiatePaymentProviderFailure(t *testing.T) {
+	service, provider, db := newTestPaymentService(t)
+	user := testutil.CreateUser(t, db)
+	provider.InitiateFunc = func(context.Context, *models.Payment) (*models.PaymentInitiation, error) {
+		return nil, errors.New("card declined")
+	}
+
+	_, _, err := service.InitiatePayment(context.Background(), user.ID, fakeProvider, 50, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.Error(t, err)
+
+	var payment models.Payment
+	require.NoError(t, db.First(&payment, "user_id = ?", user.ID).Error)
+	assert.Equal(t, models.PaymentStatusFailed, payment.Status)
+	assert.Equal(t, "card declined", payment.Error)
+}
+
+func TestProcessWebhookCompletesPaymentOnce(t *testing.T) {
+	service, provider, db := newTestPaymentService(t)
+	user := testutil.CreateUser(t, db)
+
+	payment, _, err := service.InitiatePayment(context.Background(), user.ID, fakeProvider, 75, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+
+	// The webhook alone isn't trusted: the provider doesn't say the payment completed yet
+	early := []byte(`{"id":"evt_0","event":"charge.success","reference":"` + payment.Reference + `"}`)
+	_, err = service.ProcessWebhook(fakeProvider, early)
+	require.NoError(t, err)
+	assert.Zero(t, walletBalance(t, db, user, models.CurrencyGHS))
+
+	provider.SetStatus(payment.Reference, models.PaymentStatusCompleted)
+	body := []byte(`{"id":"evt_1","event":"charge.success","reference":"` + payment.Reference + `"}`)
+	webhook, err := service.ProcessWebhook(fakeProvider, body)
+	require.NoError(t, err)
+	assert.True(t, webhook.Processed)
+	require.NotNil(t, webhook.PaymentID)
+	assert.Equal(t, payment.ID, *webhook.PaymentID)
+
+	// A redelivery returns the stored webhook without crediting again
+	again, err := service.ProcessWebhook(fakeProvider, body)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.ID, again.ID)
+	assert.Equal(t, 3, provider.WebhookCount())
+
+	var stored models.Payment
+	require.NoError(t, db.First(&stored, "id = ?", payment.ID).Error)
+	assert.Equal(t, models.PaymentStatusCompleted, stored.Status)
+	assert.True(t, stored.WebhookReceived)
+	assert.Equal(t, 75.0, walletBalance(t, db, user, models.CurrencyGHS))
+
+	var webhooks int64
+	require.NoError(t, db.Model(&models.PaymentWebhook{}).Count(&webhooks).Error)
+	assert.EqualValues(t, 2, webhooks)
+}
+
+// A webhook that can't be marked processed returns an error, so the provider redelivers it, and
+// the redelivery is processed without crediting twice
+func TestProcessWebhookReportsFailedSave(t *testing.T) {
+	service, provider, db := newTestPaymentService(t)
+	user := testutil.CreateUser(t, db)
+
+	failSaves := true
+	require.NoError(t, db.Callback().Update().Before("gorm:update").Register("test:fail_webhook_save", func(tx *gorm.DB) {
+		if failSaves && tx.Statement.Table == "payment_webhooks" {
+			tx.AddError(errors.New("database unavailable"))
+		}
+	}))
+
+	payment, _, err := service.InitiatePayment(context.Background(), user.ID, fakeProvider, 30, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	provider.SetStatus(payment.Reference, models.PaymentStatusCompleted)
+
+	body := []byte(`{"id":"evt_1","event":"charge.success","reference":"` + payment.Reference + `"}`)
+	_, err = service.ProcessWebhook(fakeProvider, body)
+	require.Error(t, err)
+
+	var stored models.PaymentWebhook
+	require.NoError(t, db.First(&stored, "provider_event_id = ?", "evt_1").Error)
+	assert.False(t, stored.Processed)
+
+	failSaves = false
+	webhook, err := service.ProcessWebhook(fakeProvider, body)
+	require.NoError(t, err)
+	assert.True(t, webhook.Processed)
+	assert.Equal(t, stored.ID, webhook.ID)
+	assert.Equal(t, 30.0, walletBalance(t, db, user, models.CurrencyGHS))
+}
+
+// recordingQueue records the jobs enqueued on it
+type recordingQueue struct {
+	jobs []SendPaymentReceiptPayload
+}
+
+func (q *recordingQueue) EnqueueJob(jobType queue.JobType, payload interface{}) (string, error) {
+	if jobType == SendPaymentReceiptJobType {
+		q.jobs = append(q.jobs, payload.(SendPaymentReceiptPayload))
+	}
+	return "job", nil
+}
+
+func TestCompletedPaymentQueuesReceipt(t *testing.T) {
+	service, provider, db := newTestPaymentService(t)
+	receipts := &recordingQueue{}
+	service.SetReceiptQueue(receipts)
+	user := testutil.CreateUser(t, db)
+	ctx := context.Background()
+
+	payment, _, err := service.InitiatePayment(ctx, user.ID, fakeProvider, 20, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	provider.SetStatus(payment.Reference, models.PaymentStatusCompleted)
+
+	for i := 0; i < 2; i++ {
+		_, err = service.VerifyPayment(ctx, payment.Reference)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []SendPaymentReceiptPayload{{PaymentID: payment.ID}}, receipts.jobs)
+
+	// Payments without a customer email have nobody to send a receipt to
+	anonymous, _, err := service.InitiatePayment(ctx, user.ID, fakeProvider, 20, models.CurrencyGHS, "", "", nil, nil)
+	require.NoError(t, err)
+	provider.SetStatus(anonymous.Reference, models.PaymentStatusCompleted)
+	_, err = service.VerifyPayment(ctx, anonymous.Reference)
+	require.NoError(t, err)
+	assert.Len(t, receipts.jobs, 1)
+}
+
+func TestGetPaymentStatusSummary(t *testing.T) {
+	service, _, db := newTestPaymentService(t)
+	user := testutil.CreateUser(t, db)
+	require.NoError(t, db.Table("users").Where("id = ?", user.ID).Update("business_name", "Kente Co").Error)
+
+	payment, _, err := service.InitiatePayment(context.Background(), user.ID, fakeProvider, 30, models.CurrencyGHS, "buyer@example.com", "Ama Buyer", nil, nil)
+	require.NoError(t, err)
+
+	summary, err := service.GetPaymentStatusSummary(payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, payment.Reference, summary.Reference)
+	assert.Equal(t, 30.0, summary.Amount)
+	assert.Equal(t, models.PaymentStatusPending, summary.Status)
+	assert.Equal(t, "Kente Co", summary.MerchantName)
+
+	body, err := json.Marshal(summary)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "buyer@example.com")
+	assert.NotContains(t, string(body), "Ama Buyer")
+
+	_, err = service.GetPaymentStatusSummary("REV-missing")
+	assert.ErrorIs(t, err, ErrPaymentReferenceNotFound)
+}
+
+func TestConfirmPaymentAfterActions(t *testing.T) {
+	service, provider, db := newTestPaymentService(t)
+	user := testutil.CreateUser(t, db)
+	ctx := context.Background()
+
+	provider.InitiateFunc = func(_ context.Context, payment *models.Payment) (*models.PaymentInitiation, error) {
+		return &models.PaymentInitiation{Action: &models.PaymentAction{
+			Type: models.PaymentActionRedirect, URL: "https://acs.test/" + payment.Reference,
+		}}, nil
+	}
+	var submitted []map[string]string
+	provider.ConfirmFunc = func(_ context.Context, payment *models.Payment, actionData map[string]string) (*models.PaymentAction, error) {
+		submitted = append(submitted, actionData)
+		// The issuer asks for an OTP after the 3D Secure challenge
+		if payment.RequiredAction == models.PaymentActionRedirect {
+			return &models.PaymentAction{Type: models.PaymentActionOTP, Message: "Enter the code sent to your phone"}, nil
+		}
+		provider.SetStatus(payment.Reference, models.PaymentStatusCompleted)
+		return nil, nil
+	}
+
+	payment, initiation, err := service.InitiatePayment(ctx, user.ID, fakeProvider, 40, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, initiation.Action)
+	assert.Equal(t, models.PaymentActionRedirect, initiation.Action.Type)
+	assert.Equal(t, models.PaymentActionRedirect, payment.RequiredAction)
+
+	confirmed, next, err := service.ConfirmPayment(ctx, payment.Reference, nil)
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, models.PaymentActionOTP, next.Type)
+	assert.Equal(t, models.PaymentStatusPending, confirmed.Status)
+	assert.Zero(t, walletBalance(t, db, user, models.CurrencyGHS))
+
+	confirmed, next, err = service.ConfirmPayment(ctx, payment.Reference, map[string]string{"otp": "123456"})
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	assert.Equal(t, models.PaymentStatusCompleted, confirmed.Status)
+	assert.Empty(t, confirmed.RequiredAction)
+	assert.Equal(t, 40.0, walletBalance(t, db, user, models.CurrencyGHS))
+	assert.Equal(t, map[string]string{"otp": "123456"}, submitted[1])
+
+	_, _, err = service.ConfirmPayment(ctx, payment.Reference, nil)
+	assert.ErrorIs(t, err, ErrNoActionRequired)
+	_, _, err = service.ConfirmPayment(ctx, "REV-missing", nil)
+	assert.ErrorIs(t, err, ErrPaymentReferenceNotFound)
+}
+
+func TestGetUserPaymentsFilters(t *testing.T) {
+	service, provider, db := newTestPaymentService(t)
+	user := testutil.CreateUser(t, db)
+	other := testutil.CreateUser(t, db)
+	ctx := context.Background()
+
+	ghs, _, err := service.InitiatePayment(ctx, user.ID, fakeProvider, 10, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	ngn, _, err := service.InitiatePayment(ctx, user.ID, fakeProvider, 20, models.CurrencyNGN, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	_, _, err = service.InitiatePayment(ctx, other.ID, fakeProvider, 30, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+
+	provider.SetStatus(ngn.Reference, models.PaymentStatusCompleted)
+	_, err = service.VerifyPayment(ctx, ngn.Reference)
+	require.NoError(t, err)
+
+	list := func(filter PaymentFilter) []string {
+		payments, total, err := service.GetUserPayments(user.ID, filter, 1, 10)
+		require.NoError(t, err)
+		assert.EqualValues(t, len(payments), total)
+		references := make([]string, 0, len(payments))
+		for _, p := range payments {
+			references = append(references, p.Reference)
+		}
+		return references
+	}
+
+	assert.ElementsMatch(t, []string{ghs.Reference, ngn.Reference}, list(PaymentFilter{}))
+	assert.Equal(t, []string{ngn.Reference}, list(PaymentFilter{Status: models.PaymentStatusCompleted}))
+	assert.Equal(t, []string{ghs.Reference}, list(PaymentFilter{Currency: models.CurrencyGHS}))
+	assert.Equal(t, []string{ghs.Reference}, list(PaymentFilter{Reference: strings.ToLower(ghs.Reference[4:10])}))
+	assert.Empty(t, list(PaymentFilter{Provider: models.PaymentProviderPaystack}))
+	assert.Empty(t, list(PaymentFilter{Reference: "%"}))
+	assert.Empty(t, list(PaymentFilter{From: time.Now().Add(time.Hour)}))
+	assert.Len(t, list(PaymentFilter{From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour)}), 2)
+
+	_, _, err = service.GetUserPayments(user.ID, PaymentFilter{Status: "settled"}, 1, 10)
+	assert.ErrorIs(t, err, ErrInvalidPaymentStatus)
+}
@@ -0,0 +1,146 @@
+// Package paymenttest provides a payment provider for tests that run payments end to end
+// without calling a real provider
+package paymenttest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/revaspay/backend/internal/models"
+)
+
+// FakeWebhook is the webhook body FakePaymentProvider understands
+type FakeWebhook struct {
+	ID        string `json:"id"`
+	Event     string `json:"event"`
+	Reference string `json:"reference"`
+}
+
+// FakePaymentProvider is a PaymentProvider whose responses are programmed by the test.
+// By default payments start at a fake checkout URL, verify with the status set by
+// SetStatus (pending until then), are confirmed without needing another action and
+// webhooks are parsed as FakeWebhook. Setting one of the Func fields replaces that default.
+// It is safe for concurrent use.
+type FakePaymentProvider struct {
+	Name models.PaymentProvider
+
+	InitiateFunc func(ctx context.Context, payment *models.Payment) (*models.PaymentInitiation, error)
+	VerifyFunc   func(ctx context.Context, reference string) (*models.Payment, error)
+	ConfirmFunc  func(ctx context.Context, payment *models.Payment, actionData map[string]string) (*models.PaymentAction, error)
+	WebhookFunc  func(data []byte) (*models.PaymentWebhook, error)
+
+	mu        sync.Mutex
+	statuses  map[string]models.PaymentStatus
+	initiated []string
+	verified  []string
+	webhooks  int
+}
+
+// NewFakePaymentProvider creates a fake provider that reports itself as name on webhooks
+func NewFakePaymentProvider(name models.PaymentProvider) *FakePaymentProvider {
+	return &FakePaymentProvider{
+		Name:     name,
+		statuses: make(map[string]models.PaymentStatus),
+	}
+}
+
+// SetStatus sets the status the provider reports when the payment is verified
+func (p *FakePaymentProvider) SetStatus(reference string, status models.PaymentStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statuses[reference] = status
+}
+
+// InitiatePayment records the payment and returns a checkout URL for it
+func (p *FakePaymentProvider) InitiatePayment(ctx context.Context, payment *models.Payment) (*models.PaymentInitiation, error) {
+	p.mu.Lock()
+	p.initiated = append(p.initiated, payment.Reference)
+	p.mu.Unlock()
+
+	if p.InitiateFunc != nil {
+		return p.InitiateFunc(ctx, payment)
+	}
+	return &models.PaymentInitiation{CheckoutURL: "https://checkout.test/" + payment.Reference}, nil
+}
+
+// VerifyPayment returns the status set for the reference
+func (p *FakePaymentProvider) VerifyPayment(ctx context.Context, reference string) (*models.Payment, error) {
+	p.mu.Lock()
+	p.verified = append(p.verified, reference)
+	status, ok := p.statuses[reference]
+	p.mu.Unlock()
+
+	if p.VerifyFunc != nil {
+		return p.VerifyFunc(ctx, reference)
+	}
+	if !ok {
+		status = models.PaymentStatusPending
+	}
+	return &models.Payment{
+		Reference:   reference,
+		Status:      status,
+		ProviderRef: "fake_" + reference,
+	}, nil
+}
+
+// ConfirmPayment accepts the customer's action without needing another
+func (p *FakePaymentProvider) ConfirmPayment(ctx context.Context, payment *models.Payment, actionData map[string]string) (*models.PaymentAction, error) {
+	if p.ConfirmFunc != nil {
+		return p.ConfirmFunc(ctx, payment, actionData)
+	}
+	return nil, nil
+}
+
+// ProcessWebhook parses a FakeWebhook body
+func (p *FakePaymentProvider) ProcessWebhook(data []byte) (*models.PaymentWebhook, error) {
+	p.mu.Lock()
+	p.webhooks++
+	p.mu.Unlock()
+
+	if p.WebhookFunc != nil {
+		return p.WebhookFunc(data)
+	}
+
+	var body FakeWebhook
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("invalid fake webhook: %w", err)
+	}
+	var raw models.JSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid fake webhook: %w", err)
+	}
+
+	webhook := &models.PaymentWebhook{
+		Provider:  p.Name,
+		Event:     body.Event,
+		Reference: body.Reference,
+		RawData:   raw,
+	}
+	if body.ID != "" {
+		webhook.ProviderEventID = &body.ID
+	}
+	return webhook, nil
+}
+
+// Initiated returns the references of the payments started, in order
+func (p *FakePaymentProvider) Initiated() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.initiated...)
+}
+
+// Verified returns the references verified, in order
+func (p *FakePaymentProvider) Verified() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.verified...)
+}
+
+// WebhookCount returns how many webhooks the provider has parsed
+func (p *FakePaymentProvider) WebhookCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.webhooks
+}
@@ -2,14 +2,22 @@ package paystack
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/circuitbreaker"
 	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/utils"
 )
 
 // PaystackProvider implements the payment.PaymentProvider interface for Paystack
@@ -40,6 +48,16 @@ func NewPaystackProvider(config PaystackConfig) *PaystackProvider {
 	}
 }
 
+// WithKeys returns a provider for another Paystack account, such as a merchant's own, that
+// uses the same API as p
+func (p *PaystackProvider) WithKeys(secretKey, publicKey string) *PaystackProvider {
+	return &PaystackProvider{
+		secretKey: secretKey,
+		publicKey: publicKey,
+		baseURL:   p.baseURL,
+	}
+}
+
 // InitiatePaymentRequest represents a request to initiate a payment
 type InitiatePaymentRequest struct {
 	Amount      int64  `json:"amount"`       // Amount in kobo (for NGN) or cents (for other currencies)
@@ -135,6 +153,60 @@ type VerifyPaymentResponse struct {
 	} `json:"data"`
 }
 
+// ChargeAuthorizationRequest represents a request to charge a saved card authorization
+type ChargeAuthorizationRequest struct {
+	Amount            int64  `json:"amount"` // Amount in kobo (for NGN) or cents (for other currencies)
+	Email             string `json:"email"`
+	Currency          string `json:"currency"`
+	Reference         string `json:"reference"`
+	AuthorizationCode string `json:"authorization_code"`
+}
+
+// ChargeAuthorizationResponse represents a response from the Paystack charge authorization endpoint
+type ChargeAuthorizationResponse struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		Amount          int64  `json:"amount"`
+		Currency        string `json:"currency"`
+		Status          string `json:"status"`
+		Reference       string `json:"reference"`
+		GatewayResponse string `json:"gateway_response"`
+		Channel         string `json:"channel"`
+		Fees            int64  `json:"fees"`
+	} `json:"data"`
+}
+
+// Bank represents a bank returned by the Paystack bank list endpoint
+type Bank struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	Code     string `json:"code"`
+	Country  string `json:"country"`
+	Currency string `json:"currency"`
+	Type     string `json:"type"`
+	Active   bool   `json:"active"`
+}
+
+// ListBanksResponse represents a response from the Paystack bank list endpoint
+type ListBanksResponse struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Data    []Bank `json:"data"`
+}
+
+// ResolveAccountResponse represents a response from the Paystack account resolve endpoint
+type ResolveAccountResponse struct {
+	Status  bool   `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		AccountNumber string `json:"account_number"`
+		AccountName   string `json:"account_name"`
+		BankID        int    `json:"bank_id"`
+	} `json:"data"`
+}
+
 // WebhookPayload represents a Paystack webhook payload
 type WebhookPayload struct {
 	Event string `json:"event"`
@@ -179,12 +251,15 @@ type WebhookPayload struct {
 			Reusable          bool   `json:"reusable"`
 			CountryCode       string `json:"country_code"`
 		} `json:"authorization"`
-		Fees int64 `json:"fees"`
+		Fees       int64 `json:"fees"`
+		Subaccount struct {
+			SubaccountCode string `json:"subaccount_code"`
+		} `json:"subaccount"`
 	} `json:"data"`
 }
 
 // InitiatePayment initiates a payment with Paystack
-func (p *PaystackProvider) InitiatePayment(payment *models.Payment) (string, error) {
+func (p *PaystackProvider) InitiatePayment(ctx context.Context, payment *models.Payment) (*models.PaymentInitiation, error) {
 	// Convert amount to the smallest currency unit (kobo for NGN, cents for USD, etc.)
 	amount := int64(payment.Amount * 100)
 	
@@ -233,84 +308,30 @@ func (p *PaystackProvider) InitiatePayment(payment *models.Payment) (string, err
 		req.Metadata.CustomFields = customFields
 	}
 	
-	// Convert request to JSON
-	reqBody, err := json.Marshal(req)
-	if err != nil {
-		return "", fmt.Errorf("error marshaling request: %w", err)
-	}
-	
-	// Create HTTP request
-	httpReq, err := http.NewRequest("POST", p.baseURL+"/transaction/initialize", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return "", fmt.Errorf("error creating request: %w", err)
-	}
-	
-	// Add headers
-	httpReq.Header.Set("Authorization", "Bearer "+p.secretKey)
-	httpReq.Header.Set("Content-Type", "application/json")
-	
 	// Send request
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return "", fmt.Errorf("error sending request: %w", err)
-	}
-	defer resp.Body.Close()
-	
-	// Read response
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("error reading response: %w", err)
-	}
-	
-	// Parse response
 	var paystackResp InitiatePaymentResponse
-	if err := json.Unmarshal(respBody, &paystackResp); err != nil {
-		return "", fmt.Errorf("error parsing response: %w", err)
+	if err := p.post(ctx, "/transaction/initialize", req, &paystackResp); err != nil {
+		return nil, err
 	}
 	
 	// Check if successful
 	if !paystackResp.Status {
-		return "", fmt.Errorf("paystack error: %s", paystackResp.Message)
+		return nil, fmt.Errorf("paystack error: %s", paystackResp.Message)
 	}
 	
 	// Update payment with provider reference
 	payment.ProviderRef = paystackResp.Data.Reference
 	
-	// Return authorization URL
-	return paystackResp.Data.AuthorizationURL, nil
+	// Return authorization URL. Paystack's hosted checkout handles 3D Secure and OTPs itself.
+	return &models.PaymentInitiation{CheckoutURL: paystackResp.Data.AuthorizationURL}, nil
 }
 
 // VerifyPayment verifies a payment with Paystack
-func (p *PaystackProvider) VerifyPayment(reference string) (*models.Payment, error) {
-	// Create HTTP request
-	httpReq, err := http.NewRequest("GET", p.baseURL+"/transaction/verify/"+reference, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-	
-	// Add headers
-	httpReq.Header.Set("Authorization", "Bearer "+p.secretKey)
-	httpReq.Header.Set("Content-Type", "application/json")
-	
+func (p *PaystackProvider) VerifyPayment(ctx context.Context, reference string) (*models.Payment, error) {
 	// Send request
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("error sending request: %w", err)
-	}
-	defer resp.Body.Close()
-	
-	// Read response
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response: %w", err)
-	}
-	
-	// Parse response
 	var paystackResp VerifyPaymentResponse
-	if err := json.Unmarshal(respBody, &paystackResp); err != nil {
-		return nil, fmt.Errorf("error parsing response: %w", err)
+	if err := p.get(ctx, "/transaction/verify/"+reference, &paystackResp); err != nil {
+		return nil, err
 	}
 	
 	// Check if successful
@@ -352,6 +373,9 @@ func (p *PaystackProvider) VerifyPayment(reference string) (*models.Payment, err
 	default:
 		payment.Status = models.PaymentStatusPending
 	}
+	if paidAt, err := time.Parse(time.RFC3339, paystackResp.Data.PaidAt); err == nil {
+		payment.PaidAt = &paidAt
+	}
 	
 	return payment, nil
 }
@@ -380,5 +404,158 @@ func (p *PaystackProvider) ProcessWebhook(data []byte) (*models.PaymentWebhook,
 		Processed: false,
 	}
 	
+	// Paystack has no event ID, but the event name and transaction ID identify a delivery
+	if payload.Data.ID != 0 {
+		eventID := fmt.Sprintf("%s:%d", payload.Event, payload.Data.ID)
+		webhook.ProviderEventID = &eventID
+	}
+	
 	return webhook, nil
 }
+
+// WebhookAccount returns the subaccount code and payment reference of a Paystack webhook, so
+// the key it was signed with can be found
+func (p *PaystackProvider) WebhookAccount(data []byte) (string, string, error) {
+	var payload WebhookPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return "", "", fmt.Errorf("error parsing webhook payload: %w", err)
+	}
+	return payload.Data.Subaccount.SubaccountCode, payload.Data.Reference, nil
+}
+
+// VerifyWebhookSignature checks a Paystack webhook signature: hex(HMAC-SHA512(secret key, raw body))
+func (p *PaystackProvider) VerifyWebhookSignature(data []byte, signature string) bool {
+	if p.secretKey == "" || signature == "" {
+		return false
+	}
+
+	mac := hmac.New(sha512.New, []byte(p.secretKey))
+	mac.Write(data)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+// ChargeAuthorization charges a card authorization saved from an earlier Paystack payment.
+// It updates the payment's status, provider reference and fee, and returns an error when
+// the charge doesn't succeed.
+func (p *PaystackProvider) ChargeAuthorization(ctx context.Context, payment *models.Payment, authorizationCode string) error {
+	req := ChargeAuthorizationRequest{
+		Amount:            int64(payment.Amount * 100),
+		Email:             payment.CustomerEmail,
+		Currency:          string(payment.Currency),
+		Reference:         payment.Reference,
+		AuthorizationCode: authorizationCode,
+	}
+	
+	var paystackResp ChargeAuthorizationResponse
+	if err := p.post(ctx, "/transaction/charge_authorization", req, &paystackResp); err != nil {
+		return err
+	}
+	
+	if !paystackResp.Status {
+		payment.Status = models.PaymentStatusFailed
+		return fmt.Errorf("paystack error: %s", paystackResp.Message)
+	}
+	
+	payment.ProviderRef = paystackResp.Data.Reference
+	payment.ProviderFee = float64(paystackResp.Data.Fees) / 100
+	if paystackResp.Data.Channel != "" {
+		payment.PaymentMethod = paystackResp.Data.Channel
+	}
+	
+	// Anything short of success (failed, or a charge needing the customer's input) is a failed charge
+	if paystackResp.Data.Status != "success" {
+		payment.Status = models.PaymentStatusFailed
+		return fmt.Errorf("charge %s: %s", paystackResp.Data.Status, paystackResp.Data.GatewayResponse)
+	}
+	
+	payment.Status = models.PaymentStatusCompleted
+	return nil
+}
+
+// ListBanks gets the banks Paystack supports for a country (e.g. "ghana")
+func (p *PaystackProvider) ListBanks(country string) ([]Bank, error) {
+	query := url.Values{}
+	query.Set("country", country)
+	query.Set("perPage", "100")
+	
+	var paystackResp ListBanksResponse
+	if err := p.get(context.Background(), "/bank?"+query.Encode(), &paystackResp); err != nil {
+		return nil, err
+	}
+	
+	if !paystackResp.Status {
+		return nil, fmt.Errorf("paystack error: %s", paystackResp.Message)
+	}
+	
+	return paystackResp.Data, nil
+}
+
+// ResolveAccount confirms an account number at a bank and returns the account holder's name
+func (p *PaystackProvider) ResolveAccount(accountNumber, bankCode string) (*ResolveAccountResponse, error) {
+	query := url.Values{}
+	query.Set("account_number", accountNumber)
+	query.Set("bank_code", bankCode)
+	
+	var paystackResp ResolveAccountResponse
+	if err := p.get(context.Background(), "/bank/resolve?"+query.Encode(), &paystackResp); err != nil {
+		return nil, err
+	}
+	
+	if !paystackResp.Status {
+		return nil, fmt.Errorf("paystack error: %s", paystackResp.Message)
+	}
+	
+	return &paystackResp, nil
+}
+
+// get sends an authenticated GET request to Paystack and decodes the JSON response into out
+func (p *PaystackProvider) get(ctx context.Context, path string, out interface{}) error {
+	return p.request(ctx, "GET", path, nil, out)
+}
+
+// post sends body as JSON in an authenticated POST request and decodes the response into out
+func (p *PaystackProvider) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error marshaling request: %w", err)
+	}
+	return p.request(ctx, "POST", path, bytes.NewReader(reqBody), out)
+}
+
+// request sends an authenticated request to Paystack and decodes the JSON response into out.
+// The request is bounded by the provider timeout as well as any deadline on ctx.
+func (p *PaystackProvider) request(ctx context.Context, method, path string, body io.Reader, out interface{}) error {
+	ctx, cancel := utils.ProviderContext(ctx)
+	defer cancel()
+	
+	httpReq, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	
+	httpReq.Header.Set("Authorization", "Bearer "+p.secretKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	
+	// Server errors mean Paystack itself is struggling, so they count towards its circuit breaker
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: paystack returned status %d", circuitbreaker.ErrUnavailable, resp.StatusCode)
+	}
+	
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("error parsing response: %w", err)
+	}
+	
+	return nil
+}
@@ -0,0 +1,108 @@
+package paystack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/revaspay/backend/internal/circuitbreaker"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestProvider returns a provider talking to a server that waits delay before answering
+// every request with response
+func newTestProvider(t *testing.T, delay time.Duration, response interface{}) *PaystackProvider {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewPaystackProvider(PaystackConfig{SecretKey: "sk_test", BaseURL: server.URL})
+}
+
+func TestInitiatePaymentTimeout(t *testing.T) {
+	utils.SetProviderTimeout(20 * time.Millisecond)
+	t.Cleanup(func() { utils.SetProviderTimeout(0) })
+
+	provider := newTestProvider(t, time.Second, map[string]interface{}{"status": true})
+	payment := &models.Payment{Amount: 10, Currency: "NGN", Reference: "REV-test", Status: models.PaymentStatusPending}
+
+	start := time.Now()
+	_, err := provider.InitiatePayment(context.Background(), payment)
+	require.Error(t, err)
+	assert.True(t, utils.IsTimeout(err), "expected a timeout, got %v", err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, models.PaymentStatusPending, payment.Status)
+	assert.Empty(t, payment.ProviderRef)
+}
+
+func TestVerifyPaymentStopsAtCallerDeadline(t *testing.T) {
+	provider := newTestProvider(t, time.Second, map[string]interface{}{"status": true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := provider.VerifyPayment(ctx, "REV-test")
+	require.Error(t, err)
+	assert.True(t, utils.IsTimeout(err), "expected a timeout, got %v", err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestChargeAuthorizationTimeoutLeavesPaymentPending(t *testing.T) {
+	utils.SetProviderTimeout(20 * time.Millisecond)
+	t.Cleanup(func() { utils.SetProviderTimeout(0) })
+
+	provider := newTestProvider(t, time.Second, map[string]interface{}{"status": true})
+	payment := &models.Payment{Amount: 10, Currency: "NGN", Reference: "SUB-test", Status: models.PaymentStatusPending}
+
+	err := provider.ChargeAuthorization(context.Background(), payment, "AUTH_test")
+	require.Error(t, err)
+	assert.True(t, utils.IsTimeout(err), "expected a timeout, got %v", err)
+	assert.Equal(t, models.PaymentStatusPending, payment.Status)
+}
+
+func TestInitiatePaymentWithinTimeout(t *testing.T) {
+	utils.SetProviderTimeout(time.Second)
+	t.Cleanup(func() { utils.SetProviderTimeout(0) })
+
+	provider := newTestProvider(t, 0, map[string]interface{}{
+		"status": true,
+		"data": map[string]interface{}{
+			"authorization_url": "https://checkout.paystack.com/abc",
+			"reference":         "REV-test",
+		},
+	})
+	payment := &models.Payment{Amount: 10, Currency: "NGN", Reference: "REV-test"}
+
+	initiation, err := provider.InitiatePayment(context.Background(), payment)
+	require.NoError(t, err)
+	assert.Equal(t, "https://checkout.paystack.com/abc", initiation.CheckoutURL)
+	assert.Equal(t, "REV-test", payment.ProviderRef)
+}
+
+func TestServerErrorCountsAsUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("<html>Bad Gateway</html>"))
+	}))
+	t.Cleanup(server.Close)
+	provider := NewPaystackProvider(PaystackConfig{SecretKey: "sk_test", BaseURL: server.URL})
+
+	_, err := provider.VerifyPayment(context.Background(), "REV-test")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, circuitbreaker.ErrUnavailable)
+	assert.True(t, circuitbreaker.IsFailure(err))
+}
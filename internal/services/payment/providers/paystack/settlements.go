@@ -0,0 +1,109 @@
+package paystack
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// listPageSize is how many records are requested per page when listing transactions and transfers
+const listPageSize = 100
+
+// Transaction is a payment from Paystack's transaction history
+type Transaction struct {
+	ID        int64     `json:"id"`
+	Reference string    `json:"reference"`
+	Amount    int64     `json:"amount"` // In kobo/cents
+	Fees      int64     `json:"fees"`   // In kobo/cents
+	Currency  string    `json:"currency"`
+	Status    string    `json:"status"` // success, failed, abandoned, reversed, ongoing, pending
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Transfer is a payout from Paystack's transfer history
+type Transfer struct {
+	ID           int64     `json:"id"`
+	Reference    string    `json:"reference"`
+	TransferCode string    `json:"transfer_code"`
+	Amount       int64     `json:"amount"`      // In kobo/cents
+	FeeCharged   int64     `json:"fee_charged"` // In kobo/cents
+	Currency     string    `json:"currency"`
+	Status       string    `json:"status"` // success, failed, reversed, pending, otp, processing
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// listMeta is the pagination metadata of Paystack list responses
+type listMeta struct {
+	Page      int `json:"page"`
+	PageCount int `json:"pageCount"`
+}
+
+// ListTransactions returns every transaction created between from and to
+func (p *PaystackProvider) ListTransactions(ctx context.Context, from, to time.Time) ([]Transaction, error) {
+	var transactions []Transaction
+	err := p.list(ctx, "/transaction", from, to, func(query string) (listMeta, error) {
+		var resp struct {
+			Status  bool          `json:"status"`
+			Message string        `json:"message"`
+			Data    []Transaction `json:"data"`
+			Meta    listMeta      `json:"meta"`
+		}
+		if err := p.get(ctx, query, &resp); err != nil {
+			return listMeta{}, err
+		}
+		if !resp.Status {
+			return listMeta{}, fmt.Errorf("paystack error: %s", resp.Message)
+		}
+		transactions = append(transactions, resp.Data...)
+		return resp.Meta, nil
+	})
+	return transactions, err
+}
+
+// ListTransfers returns every transfer created between from and to
+func (p *PaystackProvider) ListTransfers(ctx context.Context, from, to time.Time) ([]Transfer, error) {
+	var transfers []Transfer
+	err := p.list(ctx, "/transfer", from, to, func(query string) (listMeta, error) {
+		var resp struct {
+			Status  bool       `json:"status"`
+			Message string     `json:"message"`
+			Data    []Transfer `json:"data"`
+			Meta    listMeta   `json:"meta"`
+		}
+		if err := p.get(ctx, query, &resp); err != nil {
+			return listMeta{}, err
+		}
+		if !resp.Status {
+			return listMeta{}, fmt.Errorf("paystack error: %s", resp.Message)
+		}
+		transfers = append(transfers, resp.Data...)
+		return resp.Meta, nil
+	})
+	return transfers, err
+}
+
+// list walks the pages of a Paystack list endpoint filtered to records created between from
+// and to, calling fetch with the path and query of each page until the last one
+func (p *PaystackProvider) list(ctx context.Context, path string, from, to time.Time, fetch func(query string) (listMeta, error)) error {
+	for page := 1; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		params := url.Values{}
+		params.Set("from", from.UTC().Format(time.RFC3339))
+		params.Set("to", to.UTC().Format(time.RFC3339))
+		params.Set("perPage", strconv.Itoa(listPageSize))
+		params.Set("page", strconv.Itoa(page))
+
+		meta, err := fetch(path + "?" + params.Encode())
+		if err != nil {
+			return fmt.Errorf("error listing %s page %d: %w", path, page, err)
+		}
+		if page >= meta.PageCount {
+			return nil
+		}
+	}
+}
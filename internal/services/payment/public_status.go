@@ -0,0 +1,63 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrPaymentReferenceNotFound is returned when no payment has the given reference
+var ErrPaymentReferenceNotFound = errors.New("payment not found")
+
+// PaymentStatusSummary is the part of a payment that is safe to show anyone holding its
+// reference, such as the hosted checkout page polling after the customer returns from the
+// provider. It must never include the customer's details.
+type PaymentStatusSummary struct {
+	Reference    string               `json:"reference"`
+	Amount       float64              `json:"amount"`
+	Currency     models.Currency      `json:"currency"`
+	Status       models.PaymentStatus `json:"status"`
+	MerchantName string               `json:"merchant_name"`
+	ExpiresAt    *time.Time           `json:"expires_at,omitempty"`
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+}
+
+// GetPaymentStatusSummary returns the public status of the payment with the given reference. A
+// pending payment past its expiry is reported as expired before it's marked.
+func (s *PaymentService) GetPaymentStatusSummary(reference string) (*PaymentStatusSummary, error) {
+	var payment models.Payment
+	err := s.db.Select("id", "user_id", "reference", "amount", "currency", "status", "expires_at", "created_at", "updated_at").
+		First(&payment, "reference = ?", reference).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPaymentReferenceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error finding payment: %w", err)
+	}
+
+	var merchant database.User
+	if err := s.db.Select(database.PublicNameColumns).First(&merchant, "id = ?", payment.UserID).Error; err != nil {
+		return nil, fmt.Errorf("error finding merchant: %w", err)
+	}
+
+	status := payment.Status
+	if isExpired(&payment, time.Now()) {
+		status = models.PaymentStatusExpired
+	}
+
+	return &PaymentStatusSummary{
+		Reference:    payment.Reference,
+		Amount:       payment.Amount,
+		Currency:     payment.Currency,
+		Status:       status,
+		MerchantName: merchant.PublicName(),
+		ExpiresAt:    payment.ExpiresAt,
+		CreatedAt:    payment.CreatedAt,
+		UpdatedAt:    payment.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package payment
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+)
+
+// SendPaymentReceiptJobType is the job type for emailing a customer the receipt for a completed payment
+const SendPaymentReceiptJobType queue.JobType = "send_payment_receipt"
+
+// SendPaymentReceiptPayload represents the payload for a payment receipt job
+type SendPaymentReceiptPayload struct {
+	PaymentID uuid.UUID `json:"payment_id"`
+}
+
+// JobEnqueuer enqueues background jobs
+type JobEnqueuer interface {
+	EnqueueJob(jobType queue.JobType, payload interface{}) (string, error)
+}
+
+// SetReceiptQueue sets the queue receipt emails are sent through. Without one, customers
+// aren't sent receipts.
+func (s *PaymentService) SetReceiptQueue(q JobEnqueuer) {
+	s.receiptQueue = q
+}
+
+// enqueueReceipt queues the customer's receipt for a payment that was just completed. The
+// email is sent in the background, so a failure here never affects the payment.
+func (s *PaymentService) enqueueReceipt(payment *models.Payment) {
+	if s.receiptQueue == nil || payment.CustomerEmail == "" {
+		return
+	}
+	if _, err := s.receiptQueue.EnqueueJob(SendPaymentReceiptJobType, SendPaymentReceiptPayload{PaymentID: payment.ID}); err != nil {
+		log.Printf("Failed to enqueue receipt for payment %s: %v", payment.Reference, err)
+	}
+}
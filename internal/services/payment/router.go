@@ -0,0 +1,140 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/models"
+)
+
+// ErrNoProviderRoute is returned when no provider is configured for a payment
+var ErrNoProviderRoute = errors.New("no payment provider is available for this payment")
+
+// PaymentRouter picks the providers for payments that don't name one, from config-driven
+// routes. Routes are tried in order and the first that matches a payment picks its
+// providers; payments no route matches use the default providers.
+type PaymentRouter struct {
+	routes   []config.PaymentRoute
+	defaults []models.PaymentProvider
+	healthy  func(models.PaymentProvider) bool
+}
+
+// NewPaymentRouter creates a router. healthy reports whether a provider can take payments
+// now; unhealthy providers are tried last.
+func NewPaymentRouter(routes []config.PaymentRoute, defaults []string, healthy func(models.PaymentProvider) bool) *PaymentRouter {
+	return &PaymentRouter{
+		routes:   routes,
+		defaults: toProviders(defaults),
+		healthy:  healthy,
+	}
+}
+
+// SelectProvider returns the providers for a payment in order of preference: those of the
+// first matching route, or the defaults, with healthy providers first. country is the
+// customer's ISO 3166 code and may be empty, in which case routes limited to countries don't
+// match.
+func (r *PaymentRouter) SelectProvider(code models.Currency, country string, amount float64) ([]models.PaymentProvider, error) {
+	candidates := r.defaults
+	for _, route := range r.routes {
+		if routeMatches(route, code, country, amount) {
+			candidates = toProviders(route.Providers)
+			break
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoProviderRoute
+	}
+
+	ordered := make([]models.PaymentProvider, 0, len(candidates))
+	var unhealthy []models.PaymentProvider
+	for _, provider := range candidates {
+		if r.healthy == nil || r.healthy(provider) {
+			ordered = append(ordered, provider)
+		} else {
+			unhealthy = append(unhealthy, provider)
+		}
+	}
+	return append(ordered, unhealthy...), nil
+}
+
+// routeMatches reports whether a payment falls within a route
+func routeMatches(route config.PaymentRoute, code models.Currency, country string, amount float64) bool {
+	if len(route.Currencies) > 0 && !containsFold(route.Currencies, string(code)) {
+		return false
+	}
+	if len(route.Countries) > 0 && !containsFold(route.Countries, country) {
+		return false
+	}
+	if route.MinAmount > 0 && amount < route.MinAmount {
+		return false
+	}
+	if route.MaxAmount > 0 && amount > route.MaxAmount {
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func toProviders(names []string) []models.PaymentProvider {
+	providers := make([]models.PaymentProvider, 0, len(names))
+	for _, name := range names {
+		providers = append(providers, models.PaymentProvider(strings.ToLower(name)))
+	}
+	return providers
+}
+
+// SetRouter sets the router that picks providers for payments that don't name one. Without
+// one, payments must name their provider.
+func (s *PaymentService) SetRouter(router *PaymentRouter) {
+	s.router = router
+}
+
+// ProviderHealthy reports whether a provider is registered and its circuit breaker would let
+// a call through now
+func (s *PaymentService) ProviderHealthy(provider models.PaymentProvider) bool {
+	if _, ok := s.providers[provider]; !ok {
+		return false
+	}
+	return s.breakers[provider].Ready() == nil
+}
+
+// initiateRoutedPayment initiates a payment with the first of the router's providers for it
+// that takes it, failing over to the next while a provider's circuit breaker is open. No
+// payment is created for a provider that isn't called.
+func (s *PaymentService) initiateRoutedPayment(ctx context.Context, paymentLinkID *uuid.UUID, userID uuid.UUID, country string, amount float64, code models.Currency, customerEmail, customerName string, metadata map[string]interface{}, splits []SplitInput) (*models.Payment, *models.PaymentInitiation, error) {
+	if s.router == nil {
+		return nil, nil, ErrNoProviderRoute
+	}
+	candidates, err := s.router.SelectProvider(code, country, amount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lastErr := ErrNoProviderRoute
+	for _, provider := range candidates {
+		if _, ok := s.providers[provider]; !ok {
+			continue
+		}
+		payment, initiation, err := s.initiatePayment(ctx, paymentLinkID, userID, provider, amount, code, customerEmail, customerName, metadata, splits)
+		if errors.Is(err, ErrProviderUnavailable) {
+			log.Printf("Payment provider %s is unavailable, trying the next: %v", provider, err)
+			lastErr = err
+			continue
+		}
+		return payment, initiation, err
+	}
+	return nil, nil, fmt.Errorf("no provider took the payment: %w", lastErr)
+}
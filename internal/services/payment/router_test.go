@@ -0,0 +1,79 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/revaspay/backend/internal/circuitbreaker"
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/payment/paymenttest"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRoutes = []config.PaymentRoute{
+	{Name: "10_nigeria", Currencies: []string{"NGN"}, Countries: []string{"NG"}, MaxAmount: 1000, Providers: []string{"paystack", "flutterwave"}},
+	{Name: "20_naira", Currencies: []string{"NGN"}, Providers: []string{"flutterwave"}},
+}
+
+func TestSelectProvider(t *testing.T) {
+	router := NewPaymentRouter(testRoutes, []string{"stripe"}, nil)
+
+	tests := []struct {
+		name     string
+		currency models.Currency
+		country  string
+		amount   float64
+		want     []models.PaymentProvider
+	}{
+		{"first matching route", models.CurrencyNGN, "ng", 500, []models.PaymentProvider{"paystack", "flutterwave"}},
+		{"above the first route's maximum", models.CurrencyNGN, "NG", 5000, []models.PaymentProvider{"flutterwave"}},
+		{"unknown country", models.CurrencyNGN, "", 500, []models.PaymentProvider{"flutterwave"}},
+		{"no route matches", models.CurrencyUSD, "US", 500, []models.PaymentProvider{"stripe"}},
+	}
+	for _, tt := range tests {
+		got, err := router.SelectProvider(tt.currency, tt.country, tt.amount)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+
+	// Unhealthy providers are tried last
+	router = NewPaymentRouter(testRoutes, nil, func(p models.PaymentProvider) bool { return p != "paystack" })
+	got, err := router.SelectProvider(models.CurrencyNGN, "NG", 500)
+	require.NoError(t, err)
+	assert.Equal(t, []models.PaymentProvider{"flutterwave", "paystack"}, got)
+
+	_, err = router.SelectProvider(models.CurrencyUSD, "US", 500)
+	assert.ErrorIs(t, err, ErrNoProviderRoute)
+}
+
+func TestInitiateRoutedPaymentFailsOver(t *testing.T) {
+	service, primary, db := newTestPaymentService(t)
+	user := testutil.CreateUser(t, db)
+	backup := paymenttest.NewFakePaymentProvider("backup")
+	service.RegisterProvider("backup", backup)
+	service.SetRouter(NewPaymentRouter(nil, []string{string(fakeProvider), "backup"}, nil))
+
+	payment, _, err := service.InitiateRoutedPayment(context.Background(), user.ID, "", 10, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, fakeProvider, payment.Provider)
+
+	// Open the primary's breaker: the next payment goes to the backup without calling it
+	service.SetCircuitBreakerSettings(circuitbreaker.Settings{FailureRate: 0.5, MinRequests: 1, Window: time.Minute, CoolDown: time.Minute})
+	primary.InitiateFunc = func(context.Context, *models.Payment) (*models.PaymentInitiation, error) {
+		return nil, circuitbreaker.ErrUnavailable
+	}
+	_, _, err = service.InitiateRoutedPayment(context.Background(), user.ID, "", 10, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	// A provider that fails once it was called doesn't fail over: its payment was created
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrProviderUnavailable)
+	initiated := len(primary.Initiated())
+
+	payment, _, err = service.InitiateRoutedPayment(context.Background(), user.ID, "", 10, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentProvider("backup"), payment.Provider)
+	assert.Len(t, primary.Initiated(), initiated)
+}
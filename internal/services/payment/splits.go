@@ -0,0 +1,198 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/money"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidSplit is returned when a payment split is malformed
+	ErrInvalidSplit = errors.New("invalid payment split")
+
+	// ErrSplitsExceedNetAmount is returned when a payment's splits add up to more than it nets
+	ErrSplitsExceedNetAmount = errors.New("splits add up to more than the payment's net amount")
+
+	// ErrSplitRecipientNotFound is returned when a split's recipient wallet doesn't exist
+	ErrSplitRecipientNotFound = errors.New("split recipient wallet not found")
+)
+
+// splitTxType is the wallet transaction type of a recipient's share of a split payment
+const splitTxType = "payment_split"
+
+// SplitInput is a share of a payment for another merchant's wallet or, with PlatformFee set,
+// for the platform. A share is either a fixed Amount or a Percentage of the net amount.
+type SplitInput struct {
+	WalletID    *uuid.UUID
+	PlatformFee bool
+	Amount      float64
+	Percentage  float64
+}
+
+// checkSplits validates a payment's splits before it's created. Each split is a fixed amount
+// or a percentage, each recipient's wallet exists in the payment's currency and has one split,
+// there is at most one platform fee, and the shares fit in the amount. The provider's fee
+// isn't known until the payment completes, when creditSplits allows for it.
+func (s *PaymentService) checkSplits(amount float64, code models.Currency, splits []SplitInput) error {
+	total := 0.0
+	hasPlatformFee := false
+	recipients := make(map[uuid.UUID]bool, len(splits))
+	for _, split := range splits {
+		if (split.Amount > 0) == (split.Percentage > 0) || split.Amount < 0 || split.Percentage < 0 || split.Percentage > 100 {
+			return fmt.Errorf("%w: needs either an amount or a percentage of up to 100", ErrInvalidSplit)
+		}
+
+		if split.PlatformFee {
+			if split.WalletID != nil {
+				return fmt.Errorf("%w: the platform fee has no recipient wallet", ErrInvalidSplit)
+			}
+			if hasPlatformFee {
+				return fmt.Errorf("%w: only one platform fee is allowed", ErrInvalidSplit)
+			}
+			hasPlatformFee = true
+		} else {
+			if split.WalletID == nil {
+				return fmt.Errorf("%w: a recipient wallet is required", ErrInvalidSplit)
+			}
+			if recipients[*split.WalletID] {
+				return fmt.Errorf("%w: wallet %s has more than one split", ErrInvalidSplit, split.WalletID)
+			}
+			recipients[*split.WalletID] = true
+
+			var recipient models.Wallet
+			err := s.db.Select("id", "currency").First(&recipient, "id = ?", *split.WalletID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %s", ErrSplitRecipientNotFound, split.WalletID)
+			}
+			if err != nil {
+				return fmt.Errorf("error finding split recipient: %w", err)
+			}
+			if recipient.Currency != code {
+				return fmt.Errorf("%w: wallet %s is not in %s", ErrInvalidSplit, split.WalletID, code)
+			}
+		}
+
+		total += splitShare(split.Amount, split.Percentage, amount, code)
+	}
+
+	if money.Round(total, code, money.HalfUp) > money.Round(amount, code, money.HalfUp) {
+		return ErrSplitsExceedNetAmount
+	}
+	return nil
+}
+
+// createSplits saves a new payment's splits
+func createSplits(tx *gorm.DB, payment *models.Payment, splits []SplitInput) error {
+	if len(splits) == 0 {
+		return nil
+	}
+	rows := make([]models.PaymentSplit, 0, len(splits))
+	for _, split := range splits {
+		splitType := models.PaymentSplitRecipient
+		if split.PlatformFee {
+			splitType = models.PaymentSplitPlatformFee
+		}
+		rows = append(rows, models.PaymentSplit{
+			PaymentID:  payment.ID,
+			Type:       splitType,
+			WalletID:   split.WalletID,
+			Amount:     split.Amount,
+			Percentage: split.Percentage,
+		})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return fmt.Errorf("error saving payment splits: %w", err)
+	}
+	return nil
+}
+
+// GetPaymentSplits returns a payment's splits, which are empty unless it was split
+func (s *PaymentService) GetPaymentSplits(paymentID uuid.UUID) ([]models.PaymentSplit, error) {
+	var splits []models.PaymentSplit
+	if err := s.db.Where("payment_id = ?", paymentID).Order("created_at, id").Find(&splits).Error; err != nil {
+		return nil, fmt.Errorf("error finding payment splits: %w", err)
+	}
+	return splits, nil
+}
+
+// creditSplits settles a split payment: each recipient is credited its share and the merchant
+// what's left of net, all in one transaction so a payment is never part-credited. The platform
+// fee is kept rather than credited. If the provider's fee leaves less than the shares add up
+// to, every share is cut in proportion so no more is paid out than was received. It returns
+// wallet.ErrDuplicateCredit if the payment was already settled.
+func (s *PaymentService) creditSplits(payment *models.Payment, merchantWalletID uuid.UUID, net float64, splits []models.PaymentSplit, description string, metadata map[string]interface{}) error {
+	shares := splitShares(net, payment.Currency, splits)
+	remainder := net
+	for _, share := range shares {
+		remainder -= share
+	}
+	remainder = money.Round(remainder, payment.Currency, money.CurrentPolicy().Customer)
+
+	now := time.Now()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for i, split := range splits {
+			var transactionID *uuid.UUID
+			if split.Type == models.PaymentSplitRecipient && split.WalletID != nil && shares[i] > 0 {
+				transaction, err := s.walletService.CreditOnceWithTx(tx, *split.WalletID, shares[i], splitTxType, models.LedgerCategorySales,
+					payment.Reference, fmt.Sprintf("Split of payment %s", payment.Reference), metadata)
+				if err != nil {
+					return err
+				}
+				transactionID = &transaction.ID
+			}
+
+			// Only the first settlement of a split counts, so replays can't pay it twice
+			result := tx.Model(&models.PaymentSplit{}).
+				Where("id = ? AND settled_at IS NULL", split.ID).
+				Updates(map[string]interface{}{
+					"settled_amount": shares[i],
+					"transaction_id": transactionID,
+					"settled_at":     now,
+				})
+			if result.Error != nil {
+				return fmt.Errorf("error settling payment split: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return wallet.ErrDuplicateCredit
+			}
+		}
+
+		if remainder <= 0 {
+			return nil
+		}
+		_, err := s.walletService.CreditOnceWithTx(tx, merchantWalletID, remainder, "payment", models.LedgerCategorySales,
+			payment.Reference, description, metadata)
+		return err
+	})
+}
+
+// splitShares works out each split's share of net, cutting them in proportion when they add
+// up to more than it
+func splitShares(net float64, code models.Currency, splits []models.PaymentSplit) []float64 {
+	shares := make([]float64, len(splits))
+	total := 0.0
+	for i, split := range splits {
+		shares[i] = splitShare(split.Amount, split.Percentage, net, code)
+		total += shares[i]
+	}
+	if total > net {
+		for i := range shares {
+			shares[i] = money.Round(shares[i]*net/total, code, money.Down)
+		}
+	}
+	return shares
+}
+
+// splitShare is a split's share of net: its fixed amount, or its percentage of net
+func splitShare(amount, percentage, net float64, code models.Currency) float64 {
+	if percentage > 0 {
+		return money.Round(net*percentage/100, code, money.CurrentPolicy().Customer)
+	}
+	return amount
+}
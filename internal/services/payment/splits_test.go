@@ -0,0 +1,95 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitPayment(t *testing.T) {
+	service, provider, db := newTestPaymentService(t)
+	merchant := testutil.CreateUser(t, db)
+	seller := testutil.CreateUser(t, db)
+	ctx := context.Background()
+
+	sellerWallet, err := service.walletService.GetOrCreateWallet(seller.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+
+	splits := []SplitInput{
+		{WalletID: &sellerWallet.ID, Percentage: 70},
+		{PlatformFee: true, Amount: 5},
+	}
+	payment, _, err := service.InitiatePayment(ctx, merchant.ID, fakeProvider, 100, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, splits)
+	require.NoError(t, err)
+
+	provider.SetStatus(payment.Reference, models.PaymentStatusCompleted)
+	_, err = service.VerifyPayment(ctx, payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, 70.0, walletBalance(t, db, seller, models.CurrencyGHS))
+	assert.Equal(t, 25.0, walletBalance(t, db, merchant, models.CurrencyGHS))
+
+	settled, err := service.GetPaymentSplits(payment.ID)
+	require.NoError(t, err)
+	require.Len(t, settled, 2)
+	for _, split := range settled {
+		require.NotNil(t, split.SettledAt)
+		if split.Type == models.PaymentSplitRecipient {
+			assert.Equal(t, 70.0, split.SettledAmount)
+			assert.NotNil(t, split.TransactionID)
+		} else {
+			assert.Equal(t, 5.0, split.SettledAmount)
+			assert.Nil(t, split.TransactionID)
+		}
+	}
+
+	// Verifying again doesn't pay anyone twice
+	_, err = service.VerifyPayment(ctx, payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, 70.0, walletBalance(t, db, seller, models.CurrencyGHS))
+	assert.Equal(t, 25.0, walletBalance(t, db, merchant, models.CurrencyGHS))
+}
+
+func TestSplitPaymentValidation(t *testing.T) {
+	service, _, db := newTestPaymentService(t)
+	merchant := testutil.CreateUser(t, db)
+	seller := testutil.CreateUser(t, db)
+
+	sellerWallet, err := service.walletService.GetOrCreateWallet(seller.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	missing := uuid.New()
+
+	tests := []struct {
+		name   string
+		splits []SplitInput
+		want   error
+	}{
+		{"amount and percentage", []SplitInput{{WalletID: &sellerWallet.ID, Amount: 10, Percentage: 10}}, ErrInvalidSplit},
+		{"no recipient", []SplitInput{{Amount: 10}}, ErrInvalidSplit},
+		{"two platform fees", []SplitInput{{PlatformFee: true, Amount: 1}, {PlatformFee: true, Amount: 1}}, ErrInvalidSplit},
+		{"unknown recipient", []SplitInput{{WalletID: &missing, Amount: 10}}, ErrSplitRecipientNotFound},
+		{"more than the amount", []SplitInput{{WalletID: &sellerWallet.ID, Percentage: 90}, {PlatformFee: true, Amount: 15}}, ErrSplitsExceedNetAmount},
+	}
+	for _, tt := range tests {
+		_, _, err := service.InitiatePayment(context.Background(), merchant.ID, fakeProvider, 100, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, tt.splits)
+		assert.ErrorIs(t, err, tt.want, tt.name)
+	}
+
+	var count int64
+	require.NoError(t, db.Model(&models.Payment{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestSplitSharesAllowForProviderFee(t *testing.T) {
+	splits := []models.PaymentSplit{
+		{Type: models.PaymentSplitRecipient, Amount: 60},
+		{Type: models.PaymentSplitPlatformFee, Amount: 40},
+	}
+	// The provider's fee left 90 of the 100 the splits were checked against
+	shares := splitShares(90, models.CurrencyGHS, splits)
+	assert.Equal(t, []float64{54, 36}, shares)
+}
@@ -0,0 +1,134 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidSummaryQuery is returned for an unsupported grouping or period
+var ErrInvalidSummaryQuery = errors.New("invalid summary query")
+
+// SummaryGroupBy is the size of the time buckets in a payment summary
+type SummaryGroupBy string
+
+const (
+	SummaryGroupByDay   SummaryGroupBy = "day"
+	SummaryGroupByWeek  SummaryGroupBy = "week"
+	SummaryGroupByMonth SummaryGroupBy = "month"
+)
+
+// maxSummaryBuckets bounds how many time buckets one summary can span
+const maxSummaryBuckets = 366
+
+// SummaryQuery selects the payments in a summary. From is inclusive and To exclusive.
+type SummaryQuery struct {
+	GroupBy SummaryGroupBy
+	From    time.Time
+	To      time.Time
+}
+
+// SummaryBucket aggregates a merchant's payments in one currency and provider over one
+// period. Volume and Fees only count completed payments.
+type SummaryBucket struct {
+	Period       time.Time              `json:"period"`
+	Currency     models.Currency        `json:"currency"`
+	Provider     models.PaymentProvider `json:"provider"`
+	Count        int64                  `json:"count"`
+	SuccessCount int64                  `json:"success_count"`
+	FailedCount  int64                  `json:"failed_count"`
+	Volume       float64                `json:"volume"`
+	Fees         float64                `json:"fees"`
+}
+
+// SummaryTotal aggregates a merchant's payments in one currency over the whole query period
+type SummaryTotal struct {
+	Currency     models.Currency `json:"currency"`
+	Count        int64           `json:"count"`
+	SuccessCount int64           `json:"success_count"`
+	FailedCount  int64           `json:"failed_count"`
+	Volume       float64         `json:"volume"`
+	Fees         float64         `json:"fees"`
+}
+
+// Summary is a merchant's time-bucketed payment aggregates
+type Summary struct {
+	GroupBy SummaryGroupBy  `json:"group_by"`
+	From    time.Time       `json:"from"`
+	To      time.Time       `json:"to"`
+	Buckets []SummaryBucket `json:"buckets"`
+	Totals  []SummaryTotal  `json:"totals"`
+}
+
+// summaryAggregates are the aggregate columns shared by buckets and totals
+const summaryAggregates = `COUNT(*) AS count,
+	COUNT(*) FILTER (WHERE status = 'completed') AS success_count,
+	COUNT(*) FILTER (WHERE status = 'failed') AS failed_count,
+	COALESCE(SUM(amount) FILTER (WHERE status = 'completed'), 0) AS volume,
+	COALESCE(SUM(fee) FILTER (WHERE status = 'completed'), 0) AS fees`
+
+// GetSummary aggregates the merchant's payments into UTC day, week (starting Monday) or
+// month buckets per currency and provider. The aggregation runs in the database.
+func (s *PaymentService) GetSummary(userID uuid.UUID, query SummaryQuery) (*Summary, error) {
+	if err := validateSummaryQuery(query); err != nil {
+		return nil, err
+	}
+
+	base := s.db.Model(&models.Payment{}).
+		Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, query.From, query.To)
+
+	summary := &Summary{
+		GroupBy: query.GroupBy,
+		From:    query.From,
+		To:      query.To,
+		Buckets: []SummaryBucket{},
+		Totals:  []SummaryTotal{},
+	}
+
+	// GroupBy is validated above, so it's safe to put in the query
+	period := fmt.Sprintf("date_trunc('%s', created_at AT TIME ZONE 'UTC')", query.GroupBy)
+	if err := base.Session(&gorm.Session{}).
+		Select(period + " AS period, currency, provider, " + summaryAggregates).
+		Group("1, 2, 3").
+		Order("1, 2, 3").
+		Scan(&summary.Buckets).Error; err != nil {
+		return nil, fmt.Errorf("error summarizing payments: %w", err)
+	}
+
+	if err := base.Session(&gorm.Session{}).
+		Select("currency, " + summaryAggregates).
+		Group("currency").
+		Order("currency").
+		Scan(&summary.Totals).Error; err != nil {
+		return nil, fmt.Errorf("error totalling payments: %w", err)
+	}
+
+	return summary, nil
+}
+
+// validateSummaryQuery checks the grouping and that the period doesn't span too many buckets
+func validateSummaryQuery(query SummaryQuery) error {
+	var bucket time.Duration
+	switch query.GroupBy {
+	case SummaryGroupByDay:
+		bucket = 24 * time.Hour
+	case SummaryGroupByWeek:
+		bucket = 7 * 24 * time.Hour
+	case SummaryGroupByMonth:
+		bucket = 28 * 24 * time.Hour
+	default:
+		return fmt.Errorf("%w: group_by must be day, week or month", ErrInvalidSummaryQuery)
+	}
+
+	if !query.From.Before(query.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidSummaryQuery)
+	}
+	if query.To.Sub(query.From) > bucket*maxSummaryBuckets {
+		return fmt.Errorf("%w: period spans more than %d %ss", ErrInvalidSummaryQuery, maxSummaryBuckets, query.GroupBy)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package payment
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateSummaryQuery(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query SummaryQuery
+		valid bool
+	}{
+		{"day", SummaryQuery{GroupBy: SummaryGroupByDay, From: from, To: from.AddDate(0, 1, 0)}, true},
+		{"week", SummaryQuery{GroupBy: SummaryGroupByWeek, From: from, To: from.AddDate(2, 0, 0)}, true},
+		{"unknown grouping", SummaryQuery{GroupBy: "hour", From: from, To: from.AddDate(0, 0, 1)}, false},
+		{"reversed period", SummaryQuery{GroupBy: SummaryGroupByDay, From: from, To: from}, false},
+		{"too many days", SummaryQuery{GroupBy: SummaryGroupByDay, From: from, To: from.AddDate(2, 0, 0)}, false},
+	}
+	for _, tt := range tests {
+		err := validateSummaryQuery(tt.query)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidSummaryQuery) {
+			t.Errorf("%s: expected ErrInvalidSummaryQuery, got %v", tt.name, err)
+		}
+	}
+}
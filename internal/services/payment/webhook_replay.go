@@ -0,0 +1,84 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// MaxWebhookReplayBatch bounds how many stored webhooks one bulk replay can reset
+const MaxWebhookReplayBatch = 1000
+
+var (
+	// ErrWebhookNotFound is returned when a stored webhook does not exist
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrWebhookReplayTooLarge is returned when a bulk replay matches more than MaxWebhookReplayBatch webhooks
+	ErrWebhookReplayTooLarge = errors.New("too many webhooks to replay at once")
+)
+
+// WebhookReplayFilter selects stored webhooks to replay. From is inclusive and To exclusive.
+type WebhookReplayFilter struct {
+	Provider models.PaymentProvider
+	From     time.Time
+	To       time.Time
+}
+
+// ResetWebhookForReplay clears a stored webhook's processed flag so the webhook job will
+// process it again. Wallet credits are idempotent per payment, so a replay can't pay twice.
+func (s *PaymentService) ResetWebhookForReplay(id uuid.UUID) (*models.PaymentWebhook, error) {
+	var webhook models.PaymentWebhook
+	if err := s.db.First(&webhook, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("error finding webhook: %w", err)
+	}
+
+	if err := s.db.Model(&webhook).Updates(map[string]interface{}{
+		"processed":    false,
+		"processed_at": nil,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("error resetting webhook: %w", err)
+	}
+	webhook.Processed = false
+	webhook.ProcessedAt = nil
+	return &webhook, nil
+}
+
+// ResetWebhooksForReplay clears the processed flag of every stored webhook matching the
+// filter, returning their IDs oldest first
+func (s *PaymentService) ResetWebhooksForReplay(filter WebhookReplayFilter) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PaymentWebhook{}).
+			Where("provider = ? AND created_at >= ? AND created_at < ?", filter.Provider, filter.From, filter.To).
+			Order("created_at").
+			Limit(MaxWebhookReplayBatch+1).
+			Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("error finding webhooks: %w", err)
+		}
+		if len(ids) > MaxWebhookReplayBatch {
+			return fmt.Errorf("%w: narrow the date range to at most %d webhooks", ErrWebhookReplayTooLarge, MaxWebhookReplayBatch)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.PaymentWebhook{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"processed":    false,
+			"processed_at": nil,
+		}).Error; err != nil {
+			return fmt.Errorf("error resetting webhooks: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,68 @@
+package payment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Replaying a webhook resets only the webhooks asked for, and reprocessing a payment that was
+// already credited doesn't credit it again
+func TestWebhookReplayCreditsOnce(t *testing.T) {
+	service, provider, db := newTestPaymentService(t)
+	user := testutil.CreateUser(t, db)
+
+	payment, _, err := service.InitiatePayment(context.Background(), user.ID, fakeProvider, 60, models.CurrencyGHS, "buyer@example.com", "Buyer", nil, nil)
+	require.NoError(t, err)
+	provider.SetStatus(payment.Reference, models.PaymentStatusCompleted)
+	body := []byte(`{"id":"evt_1","event":"charge.success","reference":"` + payment.Reference + `"}`)
+	webhook, err := service.ProcessWebhook(fakeProvider, body)
+	require.NoError(t, err)
+	require.True(t, webhook.Processed)
+
+	replayed, err := service.ResetWebhookForReplay(webhook.ID)
+	require.NoError(t, err)
+	assert.False(t, replayed.Processed)
+	assert.Nil(t, replayed.ProcessedAt)
+	_, err = service.ResetWebhookForReplay(uuid.New())
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+
+	// A payment left pending by a bad handler is completed by the replay without a second credit
+	require.NoError(t, db.Model(&models.Payment{}).Where("id = ?", payment.ID).Update("status", models.PaymentStatusPending).Error)
+	verified, err := service.VerifyPayment(context.Background(), payment.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentStatusCompleted, verified.Status)
+	assert.Equal(t, 60.0, walletBalance(t, db, user, models.CurrencyGHS))
+
+	var credits int64
+	require.NoError(t, db.Model(&models.Transaction{}).Where("reference = ? AND type = ?", payment.Reference, "payment").Count(&credits).Error)
+	assert.EqualValues(t, 1, credits)
+
+	// Bulk replays match the provider and date range, oldest first
+	now := time.Now()
+	old := models.PaymentWebhook{Provider: fakeProvider, Event: "charge.success", Processed: true, CreatedAt: now.Add(-48 * time.Hour)}
+	other := models.PaymentWebhook{Provider: models.PaymentProvider("other"), Event: "charge.success", Processed: true, CreatedAt: now}
+	for _, w := range []*models.PaymentWebhook{&old, &other} {
+		w.ID = uuid.New()
+		require.NoError(t, db.Create(w).Error)
+	}
+	require.NoError(t, db.Model(&models.PaymentWebhook{}).Where("id = ?", webhook.ID).Update("processed", true).Error)
+
+	ids, err := service.ResetWebhooksForReplay(WebhookReplayFilter{Provider: fakeProvider, From: now.Add(-72 * time.Hour), To: now.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{old.ID, webhook.ID}, ids)
+
+	var stillProcessed models.PaymentWebhook
+	require.NoError(t, db.First(&stillProcessed, "id = ?", other.ID).Error)
+	assert.True(t, stillProcessed.Processed)
+
+	ids, err = service.ResetWebhooksForReplay(WebhookReplayFilter{Provider: fakeProvider, From: now.Add(time.Hour), To: now.Add(2 * time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
@@ -0,0 +1,332 @@
+// Package profileimage validates and stores the images users upload as their profile picture
+package profileimage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Registers GIF decoding
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/services/storage"
+	"github.com/revaspay/backend/internal/utils"
+)
+
+const (
+	// DefaultMaxSize is the largest upload accepted when PROFILE_IMAGE_MAX_MB isn't set
+	DefaultMaxSize = 2 << 20
+
+	// DefaultMaxDimension is the widest and tallest image accepted when PROFILE_IMAGE_MAX_DIMENSION isn't set
+	DefaultMaxDimension = 2048
+
+	// DefaultThumbnailSize is the longest side of thumbnails when PROFILE_IMAGE_THUMBNAIL_SIZE isn't set
+	DefaultThumbnailSize = 128
+
+	// keyPrefix is where profile images are kept in the storage backend
+	keyPrefix = "profile-images/"
+)
+
+var (
+	// ErrImageTooLarge is returned when an upload exceeds the size limit
+	ErrImageTooLarge = errors.New("image is too large")
+
+	// ErrUnsupportedImage is returned when an upload isn't an image of an allowed type
+	ErrUnsupportedImage = errors.New("file is not a supported image")
+
+	// ErrImageDimensions is returned when an image is wider or taller than allowed
+	ErrImageDimensions = errors.New("image dimensions are too large")
+)
+
+// imageTypes maps the type names used in PROFILE_IMAGE_TYPES to their content types
+var imageTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+}
+
+// Config limits what can be uploaded as a profile image
+type Config struct {
+	AllowedTypes  []string // Content types accepted, as sniffed from the file's contents
+	MaxSize       int64
+	MaxWidth      int
+	MaxHeight     int
+	ThumbnailSize int // Longest side of the thumbnail
+}
+
+// ConfigFromEnv reads the limits from PROFILE_IMAGE_TYPES (a comma-separated list of jpeg,
+// png and gif), PROFILE_IMAGE_MAX_MB, PROFILE_IMAGE_MAX_DIMENSION and PROFILE_IMAGE_THUMBNAIL_SIZE
+func ConfigFromEnv() Config {
+	config := Config{
+		AllowedTypes:  []string{"image/jpeg", "image/png", "image/gif"},
+		MaxSize:       DefaultMaxSize,
+		MaxWidth:      DefaultMaxDimension,
+		MaxHeight:     DefaultMaxDimension,
+		ThumbnailSize: DefaultThumbnailSize,
+	}
+
+	if value := os.Getenv("PROFILE_IMAGE_TYPES"); value != "" {
+		var allowed []string
+		for _, name := range strings.Split(value, ",") {
+			if contentType, ok := imageTypes[strings.ToLower(strings.TrimSpace(name))]; ok {
+				allowed = append(allowed, contentType)
+			}
+		}
+		if len(allowed) > 0 {
+			config.AllowedTypes = allowed
+		}
+	}
+	if mb := positiveEnvInt("PROFILE_IMAGE_MAX_MB"); mb > 0 {
+		config.MaxSize = int64(mb) << 20
+	}
+	if dimension := positiveEnvInt("PROFILE_IMAGE_MAX_DIMENSION"); dimension > 0 {
+		config.MaxWidth = dimension
+		config.MaxHeight = dimension
+	}
+	if size := positiveEnvInt("PROFILE_IMAGE_THUMBNAIL_SIZE"); size > 0 {
+		config.ThumbnailSize = size
+	}
+
+	return config
+}
+
+// DescribeTypes lists the accepted image types for error messages, e.g. "JPEG, PNG or GIF"
+func (c Config) DescribeTypes() string {
+	names := make([]string, len(c.AllowedTypes))
+	for i, contentType := range c.AllowedTypes {
+		names[i] = strings.ToUpper(strings.TrimPrefix(contentType, "image/"))
+	}
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// positiveEnvInt returns an environment variable as a positive integer, or 0 if it isn't one
+func positiveEnvInt(name string) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return 0
+	}
+	return value
+}
+
+// Image is a validated profile image, re-encoded without metadata, and its thumbnail
+type Image struct {
+	Data          []byte
+	Thumbnail     []byte
+	ContentType   string
+	Width, Height int
+}
+
+// Service validates profile images and keeps them in a storage backend
+type Service struct {
+	store  storage.Backend
+	config Config
+}
+
+// NewService creates a profile image service storing images in store
+func NewService(store storage.Backend, config Config) *Service {
+	return &Service{
+		store:  store,
+		config: config,
+	}
+}
+
+// Config returns the limits uploads are checked against
+func (s *Service) Config() Config {
+	return s.config
+}
+
+// Process reads an upload and checks its size, type and dimensions. The type is taken from
+// the file's contents rather than its name, and the dimensions are checked before the image
+// is decoded so oversized images aren't unpacked into memory. The image is re-encoded, which
+// drops EXIF and other metadata, and a thumbnail is made from it. GIFs are stored as PNGs of
+// their first frame.
+func (s *Service) Process(r io.Reader) (*Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.config.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading image: %w", err)
+	}
+	if int64(len(data)) > s.config.MaxSize {
+		return nil, ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !s.allowed(contentType) {
+		return nil, ErrUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width > s.config.MaxWidth || config.Height > s.config.MaxHeight {
+		return nil, ErrImageDimensions
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	if contentType == "image/gif" {
+		contentType = "image/png"
+	}
+	cleaned, err := encode(img, contentType)
+	if err != nil {
+		return nil, err
+	}
+	thumbnail, err := encode(resize(img, s.config.ThumbnailSize), contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		Data:        cleaned,
+		Thumbnail:   thumbnail,
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+	}, nil
+}
+
+// Store processes an upload and writes the image and its thumbnail to the storage backend,
+// returning the image's key. Nothing is stored if validation fails.
+func (s *Service) Store(ctx context.Context, userID uuid.UUID, r io.Reader) (string, *Image, error) {
+	img, err := s.Process(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	random, err := utils.GenerateRandomString(16)
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating image key: %w", err)
+	}
+	extension := ".jpg"
+	if img.ContentType == "image/png" {
+		extension = ".png"
+	}
+	key := keyPrefix + userID.String() + "/" + random + extension
+
+	if err := s.store.Put(ctx, key, img.Data, img.ContentType); err != nil {
+		return "", nil, fmt.Errorf("error saving image: %w", err)
+	}
+	if err := s.store.Put(ctx, ThumbnailKey(key), img.Thumbnail, img.ContentType); err != nil {
+		_ = s.store.Delete(ctx, key)
+		return "", nil, fmt.Errorf("error saving thumbnail: %w", err)
+	}
+
+	return key, img, nil
+}
+
+// Open reads a stored profile image, or its thumbnail
+func (s *Service) Open(ctx context.Context, key string, thumbnail bool) (io.ReadCloser, *storage.ObjectInfo, error) {
+	if !IsStoredKey(key) {
+		return nil, nil, storage.ErrNotFound
+	}
+	if thumbnail {
+		key = ThumbnailKey(key)
+	}
+	return s.store.Get(ctx, key)
+}
+
+// Delete removes a stored profile image and its thumbnail. Keys from before images were kept
+// in the storage backend are ignored.
+func (s *Service) Delete(ctx context.Context, key string) error {
+	if !IsStoredKey(key) {
+		return nil
+	}
+	for _, k := range []string{key, ThumbnailKey(key)} {
+		if err := s.store.Delete(ctx, k); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsStoredKey reports whether a user's profile image value is a key in the storage backend
+func IsStoredKey(key string) bool {
+	return strings.HasPrefix(key, keyPrefix)
+}
+
+// ThumbnailKey returns the key a profile image's thumbnail is stored under
+func ThumbnailKey(key string) string {
+	extension := path.Ext(key)
+	return strings.TrimSuffix(key, extension) + "-thumb" + extension
+}
+
+// allowed reports whether uploads of contentType are accepted
+func (s *Service) allowed(contentType string) bool {
+	for _, allowed := range s.config.AllowedTypes {
+		if allowed == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// encode writes an image as contentType, which is either PNG or JPEG
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error re-encoding image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// resize scales an image down so its longest side is at most size, averaging the pixels each
+// thumbnail pixel covers. Images already small enough are returned as they are.
+func resize(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	newWidth, newHeight := size, height*size/width
+	if height > width {
+		newWidth, newHeight = width*size/height, size
+	}
+	newWidth, newHeight = max(newWidth, 1), max(newHeight, 1)
+
+	thumbnail := image.NewNRGBA(image.Rect(0, 0, newWidth, newHeight))
+	for y := 0; y < newHeight; y++ {
+		y0, y1 := bounds.Min.Y+y*height/newHeight, bounds.Min.Y+max((y+1)*height/newHeight, y*height/newHeight+1)
+		for x := 0; x < newWidth; x++ {
+			x0, x1 := bounds.Min.X+x*width/newWidth, bounds.Min.X+max((x+1)*width/newWidth, x*width/newWidth+1)
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(img.At(sx, sy)).(color.NRGBA64)
+					r, g, b, a = r+uint64(c.R), g+uint64(c.G), b+uint64(c.B), a+uint64(c.A)
+					count++
+				}
+			}
+			thumbnail.Set(x, y, color.NRGBA64{
+				R: uint16(r / count),
+				G: uint16(g / count),
+				B: uint16(b / count),
+				A: uint16(a / count),
+			})
+		}
+	}
+	return thumbnail
+}
@@ -0,0 +1,141 @@
+package profileimage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/services/storage"
+)
+
+func testConfig() Config {
+	return Config{
+		AllowedTypes:  []string{"image/jpeg", "image/png"},
+		MaxSize:       1 << 20,
+		MaxWidth:      400,
+		MaxHeight:     400,
+		ThumbnailSize: 32,
+	}
+}
+
+func testJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+
+	// Insert an APP1 (EXIF) segment after the SOI marker
+	exif := append([]byte{0xFF, 0xE1, 0x00, 0x10}, []byte("Exif\x00\x00GPSDATA!")...)
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), exif...), data[2:]...)
+}
+
+func TestProcessStripsMetadataAndMakesThumbnail(t *testing.T) {
+	img, err := NewService(nil, testConfig()).Process(bytes.NewReader(testJPEG(t, 200, 100)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img.ContentType != "image/jpeg" || img.Width != 200 || img.Height != 100 {
+		t.Errorf("unexpected image %s %dx%d", img.ContentType, img.Width, img.Height)
+	}
+	if bytes.Contains(img.Data, []byte("GPSDATA")) {
+		t.Error("expected EXIF data to be stripped")
+	}
+
+	thumbnail, _, err := image.DecodeConfig(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatalf("failed to decode thumbnail: %v", err)
+	}
+	if thumbnail.Width != 32 || thumbnail.Height != 16 {
+		t.Errorf("expected a 32x16 thumbnail, got %dx%d", thumbnail.Width, thumbnail.Height)
+	}
+}
+
+func TestProcessRejectsBadImages(t *testing.T) {
+	var gifData bytes.Buffer
+	if err := gif.Encode(&gifData, image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.Black}), nil); err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"disguised executable", []byte("MZ\x90\x00 this is not an image"), ErrUnsupportedImage},
+		{"type not allowed", gifData.Bytes(), ErrUnsupportedImage},
+		{"truncated image", testJPEG(t, 8, 8)[:40], ErrUnsupportedImage},
+		{"too wide", testJPEG(t, 401, 10), ErrImageDimensions},
+		{"too large", append(testJPEG(t, 8, 8), make([]byte, 1<<20)...), ErrImageTooLarge},
+	}
+
+	service := NewService(nil, testConfig())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.Process(bytes.NewReader(tt.data)); !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestStoreAndDelete(t *testing.T) {
+	backend, err := storage.NewLocalBackend(storage.LocalConfig{
+		Root:          t.TempDir(),
+		EncryptionKey: bytes.Repeat([]byte{7}, 32),
+	})
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+	service := NewService(backend, testConfig())
+	ctx := context.Background()
+
+	key, _, err := service.Store(ctx, uuid.New(), bytes.NewReader(testJPEG(t, 64, 64)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !IsStoredKey(key) {
+		t.Errorf("unexpected key %q", key)
+	}
+
+	object, info, err := service.Open(ctx, key, true)
+	if err != nil {
+		t.Fatalf("expected thumbnail to be stored: %v", err)
+	}
+	data, _ := io.ReadAll(object)
+	object.Close()
+	if info.ContentType != "image/jpeg" || len(data) == 0 {
+		t.Errorf("unexpected thumbnail %+v", info)
+	}
+
+	if err := service.Delete(ctx, key); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, thumbnail := range []bool{false, true} {
+		if _, _, err := service.Open(ctx, key, thumbnail); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("expected ErrNotFound after delete, got %v", err)
+		}
+	}
+
+	// Files uploaded before images were kept in the storage backend aren't looked up
+	if _, _, err := service.Open(ctx, "legacy-avatar.png", false); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a legacy file name, got %v", err)
+	}
+}
+
+func TestDescribeTypes(t *testing.T) {
+	if got := (Config{AllowedTypes: []string{"image/jpeg", "image/png", "image/gif"}}).DescribeTypes(); got != "JPEG, PNG or GIF" {
+		t.Errorf("unexpected description %q", got)
+	}
+}
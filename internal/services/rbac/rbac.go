@@ -0,0 +1,138 @@
+// Package rbac manages staff roles and resolves the permissions they grant
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRoleNotFound is returned when no role has the given name
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrUserNotFound is returned when assigning a role to a user that doesn't exist
+	ErrUserNotFound = errors.New("user not found")
+	// ErrRoleNotAssigned is returned when removing a role the user doesn't have
+	ErrRoleNotAssigned = errors.New("role not assigned to user")
+)
+
+// UserPermissions returns the permissions granted by the user's roles. It doesn't include the
+// implicit permissions of admins.
+func UserPermissions(db *gorm.DB, userID uuid.UUID) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	err := db.Model(&models.RolePermission{}).
+		Distinct("role_permissions.permission").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Order("role_permissions.permission").
+		Pluck("role_permissions.permission", &permissions).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting user permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// Service assigns roles to users
+type Service struct {
+	db          *gorm.DB
+	auditLogger *utils.AuditLogger
+}
+
+// NewService creates a new role service
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		db:          db,
+		auditLogger: utils.NewAuditLogger(db),
+	}
+}
+
+// ListRoles returns every role with its permissions
+func (s *Service) ListRoles() ([]models.Role, error) {
+	var roles []models.Role
+	if err := s.db.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("error listing roles: %w", err)
+	}
+	return roles, nil
+}
+
+// UserRoles returns the roles assigned to a user
+func (s *Service) UserRoles(userID uuid.UUID) ([]models.UserRole, error) {
+	var roles []models.UserRole
+	if err := s.db.Preload("Role.Permissions").Where("user_id = ?", userID).Order("created_at").Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("error getting user roles: %w", err)
+	}
+	return roles, nil
+}
+
+// AssignRole grants a role to a user. Assigning a role the user already has is a no-op.
+// The new permissions apply from the user's next token refresh.
+func (s *Service) AssignRole(ctx context.Context, adminID, userID uuid.UUID, roleName string) (*models.UserRole, error) {
+	role, err := s.findRole(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := s.db.Select("id").First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("error finding user: %w", err)
+	}
+
+	assignment := models.UserRole{
+		UserID:    userID,
+		RoleID:    role.ID,
+		GrantedBy: &adminID,
+	}
+	if err := s.db.Where(models.UserRole{UserID: userID, RoleID: role.ID}).FirstOrCreate(&assignment).Error; err != nil {
+		return nil, fmt.Errorf("error assigning role: %w", err)
+	}
+	assignment.Role = *role
+
+	s.auditLogger.LogAdminAction(ctx, adminID, &userID, "", "", "assign_role", true, map[string]interface{}{
+		"role": role.Name,
+	})
+
+	return &assignment, nil
+}
+
+// RemoveRole takes a role away from a user. Permissions already in the user's access token
+// last until it expires.
+func (s *Service) RemoveRole(ctx context.Context, adminID, userID uuid.UUID, roleName string) error {
+	role, err := s.findRole(roleName)
+	if err != nil {
+		return err
+	}
+
+	result := s.db.Where("user_id = ? AND role_id = ?", userID, role.ID).Delete(&models.UserRole{})
+	if result.Error != nil {
+		return fmt.Errorf("error removing role: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRoleNotAssigned
+	}
+
+	s.auditLogger.LogAdminAction(ctx, adminID, &userID, "", "", "remove_role", true, map[string]interface{}{
+		"role": role.Name,
+	})
+
+	return nil
+}
+
+// findRole looks up a role by name
+func (s *Service) findRole(name string) (*models.Role, error) {
+	var role models.Role
+	if err := s.db.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, fmt.Errorf("error finding role: %w", err)
+	}
+	return &role, nil
+}
@@ -0,0 +1,180 @@
+package reconciliation
+
+import (
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+)
+
+// Record types
+const (
+	RecordTypePayment    = "payment"
+	RecordTypeWithdrawal = "withdrawal"
+)
+
+// Statuses records are normalised to so both sides can be compared
+const (
+	StatusCompleted = "completed"
+	StatusPending   = "pending"
+	StatusFailed    = "failed"
+)
+
+// amountTolerance absorbs rounding when amounts are converted from minor units
+const amountTolerance = 0.005
+
+// Record is a payment or payout as one side recorded it
+type Record struct {
+	Type         string // RecordTypePayment or RecordTypeWithdrawal
+	Reference    string
+	AltReference string // The provider's own reference, also tried when matching
+	Amount       float64
+	Fee          *float64 // Provider fee, nil when the side doesn't record one
+	Currency     models.Currency
+	Status       string // StatusCompleted, StatusPending or StatusFailed
+	PaymentID    *uuid.UUID
+	WithdrawalID *uuid.UUID
+}
+
+// Totals sums the settled records on each side for one record type and currency
+type Totals struct {
+	InternalCount  int     `json:"internal_count"`
+	InternalAmount float64 `json:"internal_amount"`
+	InternalFees   float64 `json:"internal_fees"`
+	ProviderCount  int     `json:"provider_count"`
+	ProviderAmount float64 `json:"provider_amount"`
+	ProviderFees   float64 `json:"provider_fees"`
+}
+
+// Comparison is the result of matching our records against a provider's
+type Comparison struct {
+	Discrepancies []models.ReconciliationDiscrepancy
+	Totals        map[string]map[models.Currency]*Totals // By record type, then currency
+}
+
+// Compare matches our records against the provider's by type and reference. Records the
+// provider settled or still has pending that we don't know about, and records we settled
+// that the provider doesn't have, are reported as missing; matched records are checked for
+// differences in amount, currency, status and provider fee.
+func Compare(internal, provider []Record) Comparison {
+	result := Comparison{Totals: make(map[string]map[models.Currency]*Totals)}
+
+	byReference := make(map[string]int, len(internal))
+	for i, rec := range internal {
+		if rec.Reference != "" {
+			byReference[matchKey(rec.Type, rec.Reference)] = i
+		}
+		result.totals(rec.Type, rec.Currency).addInternal(rec)
+	}
+
+	matched := make([]bool, len(internal))
+	for _, rec := range provider {
+		result.totals(rec.Type, rec.Currency).addProvider(rec)
+
+		i, ok := byReference[matchKey(rec.Type, rec.Reference)]
+		if !ok && rec.AltReference != "" {
+			i, ok = byReference[matchKey(rec.Type, rec.AltReference)]
+		}
+		if !ok || matched[i] {
+			// Failed attempts we never recorded don't affect either side's books
+			if rec.Status != StatusFailed {
+				result.add(models.ReconciliationMissingInternal, nil, &rec)
+			}
+			continue
+		}
+		matched[i] = true
+		ours := internal[i]
+
+		if ours.Currency != rec.Currency || math.Abs(ours.Amount-rec.Amount) > amountTolerance {
+			result.add(models.ReconciliationAmountMismatch, &ours, &rec)
+		}
+		if ours.Status != rec.Status {
+			result.add(models.ReconciliationStatusMismatch, &ours, &rec)
+		}
+		if ours.Fee != nil && rec.Fee != nil && rec.Status == StatusCompleted && math.Abs(*ours.Fee-*rec.Fee) > amountTolerance {
+			result.add(models.ReconciliationFeeMismatch, &ours, &rec)
+		}
+	}
+
+	for i, ours := range internal {
+		if !matched[i] && ours.Status == StatusCompleted {
+			ours := ours
+			result.add(models.ReconciliationMissingProvider, &ours, nil)
+		}
+	}
+
+	sort.SliceStable(result.Discrepancies, func(i, j int) bool {
+		return result.Discrepancies[i].Reference < result.Discrepancies[j].Reference
+	})
+	return result
+}
+
+func matchKey(recordType, reference string) string {
+	return recordType + ":" + reference
+}
+
+// totals returns the totals for a record type and currency, creating them if needed
+func (c *Comparison) totals(recordType string, currency models.Currency) *Totals {
+	byCurrency, ok := c.Totals[recordType]
+	if !ok {
+		byCurrency = make(map[models.Currency]*Totals)
+		c.Totals[recordType] = byCurrency
+	}
+	totals, ok := byCurrency[currency]
+	if !ok {
+		totals = &Totals{}
+		byCurrency[currency] = totals
+	}
+	return totals
+}
+
+// add records a discrepancy between ours and the provider's record, either of which may be nil
+func (c *Comparison) add(kind models.ReconciliationDiscrepancyKind, ours, theirs *Record) {
+	d := models.ReconciliationDiscrepancy{Kind: kind}
+	if ours != nil {
+		amount := ours.Amount
+		d.RecordType = ours.Type
+		d.Reference = ours.Reference
+		d.PaymentID = ours.PaymentID
+		d.WithdrawalID = ours.WithdrawalID
+		d.Currency = ours.Currency
+		d.InternalAmount = &amount
+		d.InternalFee = ours.Fee
+		d.InternalStatus = ours.Status
+	}
+	if theirs != nil {
+		amount := theirs.Amount
+		if ours == nil {
+			d.RecordType = theirs.Type
+			d.Reference = theirs.Reference
+			d.Currency = theirs.Currency
+		}
+		d.ProviderAmount = &amount
+		d.ProviderFee = theirs.Fee
+		d.ProviderStatus = theirs.Status
+	}
+	c.Discrepancies = append(c.Discrepancies, d)
+}
+
+func (t *Totals) addInternal(rec Record) {
+	if rec.Status != StatusCompleted {
+		return
+	}
+	t.InternalCount++
+	t.InternalAmount += rec.Amount
+	if rec.Fee != nil {
+		t.InternalFees += *rec.Fee
+	}
+}
+
+func (t *Totals) addProvider(rec Record) {
+	if rec.Status != StatusCompleted {
+		return
+	}
+	t.ProviderCount++
+	t.ProviderAmount += rec.Amount
+	if rec.Fee != nil {
+		t.ProviderFees += *rec.Fee
+	}
+}
@@ -0,0 +1,118 @@
+package reconciliation
+
+import (
+	"testing"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/payment/providers/paystack"
+)
+
+func fee(v float64) *float64 { return &v }
+
+func TestCompare(t *testing.T) {
+	payment := func(ref string, amount float64, status string) Record {
+		return Record{Type: RecordTypePayment, Reference: ref, Amount: amount, Fee: fee(1.5), Currency: models.CurrencyNGN, Status: status}
+	}
+
+	tests := []struct {
+		name     string
+		internal []Record
+		provider []Record
+		kinds    []models.ReconciliationDiscrepancyKind
+	}{
+		{
+			name:     "balanced",
+			internal: []Record{payment("a", 100, StatusCompleted), payment("b", 50, StatusFailed)},
+			provider: []Record{payment("a", 100, StatusCompleted), payment("b", 50, StatusFailed)},
+		},
+		{
+			name:     "missed webhook",
+			internal: []Record{payment("a", 100, StatusPending)},
+			provider: []Record{payment("a", 100, StatusCompleted)},
+			kinds:    []models.ReconciliationDiscrepancyKind{models.ReconciliationStatusMismatch},
+		},
+		{
+			name:     "unknown to us",
+			provider: []Record{payment("a", 100, StatusCompleted), payment("b", 100, StatusFailed)},
+			kinds:    []models.ReconciliationDiscrepancyKind{models.ReconciliationMissingInternal},
+		},
+		{
+			name:     "unknown to provider",
+			internal: []Record{payment("a", 100, StatusCompleted), payment("b", 100, StatusPending)},
+			kinds:    []models.ReconciliationDiscrepancyKind{models.ReconciliationMissingProvider},
+		},
+		{
+			name:     "amount differs",
+			internal: []Record{payment("a", 100, StatusCompleted)},
+			provider: []Record{payment("a", 99.5, StatusCompleted)},
+			kinds:    []models.ReconciliationDiscrepancyKind{models.ReconciliationAmountMismatch},
+		},
+		{
+			name:     "rounding",
+			internal: []Record{payment("a", 100.004, StatusCompleted)},
+			provider: []Record{payment("a", 100, StatusCompleted)},
+		},
+		{
+			name:     "fee differs",
+			internal: []Record{payment("a", 100, StatusCompleted)},
+			provider: []Record{{Type: RecordTypePayment, Reference: "a", Amount: 100, Fee: fee(2), Currency: models.CurrencyNGN, Status: StatusCompleted}},
+			kinds:    []models.ReconciliationDiscrepancyKind{models.ReconciliationFeeMismatch},
+		},
+		{
+			name:     "matched by provider reference",
+			internal: []Record{{Type: RecordTypeWithdrawal, Reference: "TRF_1", Amount: 20, Currency: models.CurrencyNGN, Status: StatusCompleted}},
+			provider: []Record{{Type: RecordTypeWithdrawal, Reference: "other", AltReference: "TRF_1", Amount: 20, Fee: fee(10), Currency: models.CurrencyNGN, Status: StatusCompleted}},
+		},
+		{
+			name:     "same reference, different type",
+			internal: []Record{payment("a", 100, StatusCompleted)},
+			provider: []Record{{Type: RecordTypeWithdrawal, Reference: "a", Amount: 100, Currency: models.CurrencyNGN, Status: StatusCompleted}},
+			kinds:    []models.ReconciliationDiscrepancyKind{models.ReconciliationMissingInternal, models.ReconciliationMissingProvider},
+		},
+	}
+	for _, tt := range tests {
+		result := Compare(tt.internal, tt.provider)
+		if len(result.Discrepancies) != len(tt.kinds) {
+			t.Errorf("%s: expected %d discrepancies, got %+v", tt.name, len(tt.kinds), result.Discrepancies)
+			continue
+		}
+		got := make(map[models.ReconciliationDiscrepancyKind]bool)
+		for _, d := range result.Discrepancies {
+			got[d.Kind] = true
+		}
+		for _, kind := range tt.kinds {
+			if !got[kind] {
+				t.Errorf("%s: expected a %s discrepancy, got %+v", tt.name, kind, result.Discrepancies)
+			}
+		}
+	}
+}
+
+func TestCompareTotals(t *testing.T) {
+	internal := []Record{
+		{Type: RecordTypePayment, Reference: "a", Amount: 100, Fee: fee(1.5), Currency: models.CurrencyNGN, Status: StatusCompleted},
+		{Type: RecordTypePayment, Reference: "b", Amount: 40, Fee: fee(0.5), Currency: models.CurrencyNGN, Status: StatusPending},
+	}
+	provider := []Record{
+		{Type: RecordTypePayment, Reference: "a", Amount: 100, Fee: fee(1.5), Currency: models.CurrencyNGN, Status: StatusCompleted},
+		{Type: RecordTypePayment, Reference: "b", Amount: 40, Fee: fee(0.5), Currency: models.CurrencyNGN, Status: StatusCompleted},
+	}
+
+	totals := Compare(internal, provider).Totals[RecordTypePayment][models.CurrencyNGN]
+	if totals == nil {
+		t.Fatal("expected NGN payment totals")
+	}
+	if totals.InternalCount != 1 || totals.InternalAmount != 100 || totals.InternalFees != 1.5 {
+		t.Errorf("unexpected internal totals %+v", totals)
+	}
+	if totals.ProviderCount != 2 || totals.ProviderAmount != 140 || totals.ProviderFees != 2 {
+		t.Errorf("unexpected provider totals %+v", totals)
+	}
+}
+
+func TestPaystackTransactionRecord(t *testing.T) {
+	rec := paystackTransactionRecord(paystack.Transaction{Reference: "ref", Amount: 150050, Fees: 2250, Currency: "ngn", Status: "abandoned"})
+	if rec.Amount != 1500.5 || *rec.Fee != 22.5 || rec.Currency != models.CurrencyNGN || rec.Status != StatusFailed {
+		t.Errorf("unexpected record %+v", rec)
+	}
+}
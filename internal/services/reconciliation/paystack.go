@@ -0,0 +1,94 @@
+package reconciliation
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/payment/providers/paystack"
+)
+
+// PaystackSource reconciles Paystack transactions against our payments and Paystack transfers
+// against our bank transfer withdrawals
+type PaystackSource struct {
+	provider *paystack.PaystackProvider
+}
+
+// NewPaystackSource creates a reconciliation source backed by the Paystack API
+func NewPaystackSource(provider *paystack.PaystackProvider) *PaystackSource {
+	return &PaystackSource{provider: provider}
+}
+
+// Provider returns the Paystack provider name
+func (s *PaystackSource) Provider() models.PaymentProvider {
+	return models.PaymentProviderPaystack
+}
+
+// PayoutMethods returns the withdrawal methods paid out through Paystack transfers
+func (s *PaystackSource) PayoutMethods() []string {
+	return []string{"bank_transfer"}
+}
+
+// ListRecords returns Paystack's transactions and transfers created between from and to
+func (s *PaystackSource) ListRecords(ctx context.Context, from, to time.Time) ([]Record, error) {
+	transactions, err := s.provider.ListTransactions(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	transfers, err := s.provider.ListTransfers(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(transactions)+len(transfers))
+	for _, t := range transactions {
+		records = append(records, paystackTransactionRecord(t))
+	}
+	for _, t := range transfers {
+		records = append(records, paystackTransferRecord(t))
+	}
+	return records, nil
+}
+
+// paystackTransactionRecord converts a Paystack transaction, whose amounts are in kobo/cents
+func paystackTransactionRecord(t paystack.Transaction) Record {
+	fee := float64(t.Fees) / 100
+	status := StatusPending
+	switch t.Status {
+	case "success", "reversed":
+		// A reversed transaction was settled and then refunded, as our refunded payments are
+		status = StatusCompleted
+	case "failed", "abandoned":
+		status = StatusFailed
+	}
+	return Record{
+		Type:      RecordTypePayment,
+		Reference: t.Reference,
+		Amount:    float64(t.Amount) / 100,
+		Fee:       &fee,
+		Currency:  models.Currency(strings.ToUpper(t.Currency)),
+		Status:    status,
+	}
+}
+
+// paystackTransferRecord converts a Paystack transfer, whose amounts are in kobo/cents
+func paystackTransferRecord(t paystack.Transfer) Record {
+	fee := float64(t.FeeCharged) / 100
+	status := StatusPending
+	switch t.Status {
+	case "success":
+		status = StatusCompleted
+	case "failed", "reversed":
+		status = StatusFailed
+	}
+	return Record{
+		Type:         RecordTypeWithdrawal,
+		Reference:    t.Reference,
+		AltReference: t.TransferCode,
+		Amount:       float64(t.Amount) / 100,
+		Fee:          &fee,
+		Currency:     models.Currency(strings.ToUpper(t.Currency)),
+		Status:       status,
+	}
+}
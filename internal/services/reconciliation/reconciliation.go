@@ -0,0 +1,325 @@
+// Package reconciliation compares the payments and payouts each provider reports with our
+// payment and withdrawal records, catching missed webhooks, payouts that never went out and
+// provider fee mismatches
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// discrepancyBatchSize is how many discrepancies are inserted at a time when saving a report
+const discrepancyBatchSize = 200
+
+// ErrReportNotFound is returned when a reconciliation report does not exist
+var ErrReportNotFound = errors.New("reconciliation report not found")
+
+// Source is a provider whose own records of payments and payouts are reconciled against ours
+type Source interface {
+	// Provider is the payment provider the records belong to
+	Provider() models.PaymentProvider
+	// PayoutMethods are the withdrawal methods paid out through the provider
+	PayoutMethods() []string
+	// ListRecords returns the provider's payments and payouts created between from and to
+	ListRecords(ctx context.Context, from, to time.Time) ([]Record, error)
+}
+
+// ReportFilter narrows the reconciliation reports returned to admins
+type ReportFilter struct {
+	Provider models.PaymentProvider
+	Status   models.ReconciliationReportStatus
+	From     *time.Time // Reports for periods starting at or after From
+	To       *time.Time // Reports for periods starting before To
+}
+
+// Service produces and stores reconciliation reports
+type Service struct {
+	db      *gorm.DB
+	sources []Source
+}
+
+// NewService creates a reconciliation service for the given providers
+func NewService(db *gorm.DB, sources ...Source) *Service {
+	return &Service{
+		db:      db,
+		sources: sources,
+	}
+}
+
+// ReconcileDay reconciles every provider for the UTC day containing day. The reports are
+// returned even when some providers fail, along with an error for each failure.
+func (s *Service) ReconcileDay(ctx context.Context, day time.Time) ([]models.ReconciliationReport, error) {
+	from := day.UTC().Truncate(24 * time.Hour)
+	to := from.AddDate(0, 0, 1)
+
+	var reports []models.ReconciliationReport
+	var errs []error
+	for _, source := range s.sources {
+		report, err := s.Reconcile(ctx, source, from, to)
+		if report != nil {
+			reports = append(reports, *report)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Provider(), err))
+		}
+	}
+	return reports, errors.Join(errs...)
+}
+
+// Reconcile compares a provider's records for the period with ours and stores the report. A
+// period that was already reconciled returns the stored report; one whose earlier run failed
+// is reconciled again. If the provider's records can't be fetched a failed report is stored
+// so the gap is visible.
+func (s *Service) Reconcile(ctx context.Context, source Source, from, to time.Time) (*models.ReconciliationReport, error) {
+	existing, err := s.findReport(source.Provider(), from)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Status != models.ReconciliationReportFailed {
+		return existing, nil
+	}
+
+	report := models.ReconciliationReport{
+		Provider:    source.Provider(),
+		PeriodStart: from,
+		PeriodEnd:   to,
+	}
+	if existing != nil {
+		report.ID = existing.ID
+		report.CreatedAt = existing.CreatedAt
+	}
+
+	providerRecords, err := source.ListRecords(ctx, from, to)
+	if err != nil {
+		report.Status = models.ReconciliationReportFailed
+		report.Error = err.Error()
+		if saveErr := s.saveReport(&report, nil); saveErr != nil {
+			return nil, saveErr
+		}
+		return &report, fmt.Errorf("error fetching provider records: %w", err)
+	}
+
+	internalRecords, err := s.internalRecords(source, from, to, providerRecords)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := Compare(internalRecords, providerRecords)
+	report.Status = models.ReconciliationReportBalanced
+	if len(comparison.Discrepancies) > 0 {
+		report.Status = models.ReconciliationReportDiscrepancies
+	}
+	report.InternalRecords = len(internalRecords)
+	report.ProviderRecords = len(providerRecords)
+	report.DiscrepancyCount = len(comparison.Discrepancies)
+	report.Totals = make(models.JSON, len(comparison.Totals))
+	for recordType, totals := range comparison.Totals {
+		report.Totals[recordType] = totals
+	}
+
+	if err := s.saveReport(&report, comparison.Discrepancies); err != nil {
+		return nil, err
+	}
+	report.Discrepancies = comparison.Discrepancies
+	return &report, nil
+}
+
+// internalRecords loads our payments through the provider and withdrawals paid out through it
+// that were created in the period, plus any records the provider listed for the period that
+// we created just outside it
+func (s *Service) internalRecords(source Source, from, to time.Time, providerRecords []Record) ([]Record, error) {
+	var payments []models.Payment
+	if err := s.db.Where("provider = ? AND created_at >= ? AND created_at < ?", source.Provider(), from, to).
+		Find(&payments).Error; err != nil {
+		return nil, fmt.Errorf("error getting payments: %w", err)
+	}
+
+	var withdrawals []models.Withdrawal
+	if methods := source.PayoutMethods(); len(methods) > 0 {
+		if err := s.db.Where("method IN ? AND status <> ? AND created_at >= ? AND created_at < ?", methods, "completed_dry_run", from, to).
+			Find(&withdrawals).Error; err != nil {
+			return nil, fmt.Errorf("error getting withdrawals: %w", err)
+		}
+	}
+
+	// Look up provider records we didn't load by reference, since each side may put a record
+	// created around midnight on a different day
+	known := make(map[string]bool, len(payments)+len(withdrawals))
+	for _, p := range payments {
+		known[matchKey(RecordTypePayment, p.Reference)] = true
+	}
+	for _, w := range withdrawals {
+		known[matchKey(RecordTypeWithdrawal, w.Reference)] = true
+	}
+	var paymentRefs, withdrawalRefs []string
+	for _, rec := range providerRecords {
+		if rec.Reference == "" || known[matchKey(rec.Type, rec.Reference)] {
+			continue
+		}
+		if rec.Type == RecordTypePayment {
+			paymentRefs = append(paymentRefs, rec.Reference)
+		} else {
+			withdrawalRefs = append(withdrawalRefs, rec.Reference, rec.AltReference)
+		}
+	}
+	if len(paymentRefs) > 0 {
+		var outside []models.Payment
+		if err := s.db.Where("provider = ? AND reference IN ?", source.Provider(), paymentRefs).
+			Find(&outside).Error; err != nil {
+			return nil, fmt.Errorf("error getting payments by reference: %w", err)
+		}
+		payments = append(payments, outside...)
+	}
+	if len(withdrawalRefs) > 0 {
+		var outside []models.Withdrawal
+		if err := s.db.Where("reference IN ? AND (created_at < ? OR created_at >= ?)", withdrawalRefs, from, to).
+			Find(&outside).Error; err != nil {
+			return nil, fmt.Errorf("error getting withdrawals by reference: %w", err)
+		}
+		withdrawals = append(withdrawals, outside...)
+	}
+
+	records := make([]Record, 0, len(payments)+len(withdrawals))
+	for _, p := range payments {
+		records = append(records, paymentRecord(p))
+	}
+	for _, w := range withdrawals {
+		records = append(records, withdrawalRecord(w))
+	}
+	return records, nil
+}
+
+// paymentRecord converts one of our payments for comparison
+func paymentRecord(p models.Payment) Record {
+	id := p.ID
+	fee := p.ProviderFee
+	status := StatusPending
+	switch p.Status {
+	case models.PaymentStatusCompleted, models.PaymentStatusRefunded:
+		status = StatusCompleted
+	case models.PaymentStatusFailed, models.PaymentStatusCancelled:
+		status = StatusFailed
+	}
+	return Record{
+		Type:      RecordTypePayment,
+		Reference: p.Reference,
+		Amount:    p.Amount,
+		Fee:       &fee,
+		Currency:  p.Currency,
+		Status:    status,
+		PaymentID: &id,
+	}
+}
+
+// withdrawalRecord converts one of our withdrawals for comparison. We don't record what
+// providers charge for payouts, so there's no fee to compare.
+func withdrawalRecord(w models.Withdrawal) Record {
+	id := w.ID
+	status := StatusPending
+	switch w.Status {
+	case "completed":
+		status = StatusCompleted
+	case "failed", "blocked":
+		status = StatusFailed
+	}
+	return Record{
+		Type:         RecordTypeWithdrawal,
+		Reference:    w.Reference,
+		Amount:       w.Amount,
+		Currency:     w.Currency,
+		Status:       status,
+		WithdrawalID: &id,
+	}
+}
+
+// saveReport stores a report and its discrepancies, replacing those of an earlier failed run
+func (s *Service) saveReport(report *models.ReconciliationReport, discrepancies []models.ReconciliationDiscrepancy) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if report.ID != uuid.Nil {
+			if err := tx.Where("report_id = ?", report.ID).Delete(&models.ReconciliationDiscrepancy{}).Error; err != nil {
+				return fmt.Errorf("error clearing reconciliation discrepancies: %w", err)
+			}
+			if err := tx.Omit("Discrepancies").Save(report).Error; err != nil {
+				return fmt.Errorf("error saving reconciliation report: %w", err)
+			}
+		} else if err := tx.Omit("Discrepancies").Create(report).Error; err != nil {
+			return fmt.Errorf("error creating reconciliation report: %w", err)
+		}
+
+		if len(discrepancies) == 0 {
+			return nil
+		}
+		for i := range discrepancies {
+			discrepancies[i].ReportID = report.ID
+		}
+		if err := tx.CreateInBatches(discrepancies, discrepancyBatchSize).Error; err != nil {
+			return fmt.Errorf("error creating reconciliation discrepancies: %w", err)
+		}
+		return nil
+	})
+}
+
+// findReport returns the report for a provider and period, or nil if there is none
+func (s *Service) findReport(provider models.PaymentProvider, periodStart time.Time) (*models.ReconciliationReport, error) {
+	var report models.ReconciliationReport
+	err := s.db.Where("provider = ? AND period_start = ?", provider, periodStart).First(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting reconciliation report: %w", err)
+	}
+	return &report, nil
+}
+
+// GetReport returns a reconciliation report with its discrepancies
+func (s *Service) GetReport(reportID uuid.UUID) (*models.ReconciliationReport, error) {
+	var report models.ReconciliationReport
+	err := s.db.Preload("Discrepancies", func(db *gorm.DB) *gorm.DB {
+		return db.Order("reference ASC")
+	}).First(&report, "id = ?", reportID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReportNotFound
+		}
+		return nil, fmt.Errorf("error getting reconciliation report: %w", err)
+	}
+	return &report, nil
+}
+
+// ListReports returns a page of reconciliation reports matching the filter, without their
+// discrepancies, latest period first
+func (s *Service) ListReports(filter ReportFilter, page, pageSize int) ([]models.ReconciliationReport, int64, error) {
+	query := s.db.Model(&models.ReconciliationReport{})
+	if filter.Provider != "" {
+		query = query.Where("provider = ?", filter.Provider)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("period_start >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("period_start < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error counting reconciliation reports: %w", err)
+	}
+
+	var reports []models.ReconciliationReport
+	offset := (page - 1) * pageSize
+	if err := query.Order("period_start DESC, provider ASC").Offset(offset).Limit(pageSize).Find(&reports).Error; err != nil {
+		return nil, 0, fmt.Errorf("error getting reconciliation reports: %w", err)
+	}
+
+	return reports, total, nil
+}
@@ -0,0 +1,248 @@
+package screening
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/crypto"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+// RiskLevel describes how risky it is to send funds to an address
+type RiskLevel string
+
+const (
+	RiskLow    RiskLevel = "low"
+	RiskMedium RiskLevel = "medium"
+	RiskHigh   RiskLevel = "high"
+	RiskSevere RiskLevel = "severe"
+)
+
+// Verdict sources
+const (
+	SourceBlocklist = "blocklist"
+	SourceManual    = "manual"
+)
+
+var (
+	// ErrAddressBlocked is returned by callers that refuse to send to a flagged address
+	ErrAddressBlocked = errors.New("destination address failed sanctions screening")
+
+	// ErrBlockedAddressNotFound is returned when a blocklist entry does not exist
+	ErrBlockedAddressNotFound = errors.New("blocked address not found")
+
+	// ErrAddressAlreadyBlocked is returned when an address is already on the blocklist
+	ErrAddressAlreadyBlocked = errors.New("address is already blocked")
+)
+
+// Verdict is the outcome of screening a destination address
+type Verdict struct {
+	Network string    `json:"network"`
+	Address string    `json:"address"`
+	Flagged bool      `json:"flagged"`
+	Risk    RiskLevel `json:"risk"`
+	Reason  string    `json:"reason,omitempty"`
+	Source  string    `json:"source,omitempty"`
+}
+
+// Provider is an external screening service, e.g. a chain-analytics API.
+// Implementations return a verdict for addresses they have an opinion on.
+type Provider interface {
+	Name() string
+	Screen(network, address string) (*Verdict, error)
+}
+
+// Service screens destination addresses against the internal blocklist and an optional provider
+type Service struct {
+	db          *gorm.DB
+	provider    Provider
+	auditLogger *utils.AuditLogger
+}
+
+// NewService creates a new screening service. provider may be nil to rely on the blocklist only.
+func NewService(db *gorm.DB, provider Provider) *Service {
+	return &Service{
+		db:          db,
+		provider:    provider,
+		auditLogger: utils.NewAuditLogger(db),
+	}
+}
+
+// ScreenAddress checks an address against the blocklist, then the external provider if one is configured.
+// Provider errors are returned so callers fail closed rather than sending unscreened funds.
+func (s *Service) ScreenAddress(network, address string) (*Verdict, error) {
+	network = strings.ToLower(network)
+	normalized := NormalizeAddress(network, address)
+
+	var entry models.BlockedAddress
+	err := s.db.Where("network IN ? AND address = ?", equivalentNetworks(network), normalized).First(&entry).Error
+	if err == nil {
+		return &Verdict{
+			Network: network,
+			Address: address,
+			Flagged: true,
+			Risk:    RiskSevere,
+			Reason:  entry.Reason,
+			Source:  SourceBlocklist,
+		}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("error checking address blocklist: %w", err)
+	}
+
+	if s.provider != nil {
+		verdict, err := s.provider.Screen(network, address)
+		if err != nil {
+			return nil, fmt.Errorf("error screening address with %s: %w", s.provider.Name(), err)
+		}
+		if verdict != nil {
+			if verdict.Source == "" {
+				verdict.Source = s.provider.Name()
+			}
+			return verdict, nil
+		}
+	}
+
+	return &Verdict{
+		Network: network,
+		Address: address,
+		Flagged: false,
+		Risk:    RiskLow,
+	}, nil
+}
+
+// RecordBlocked writes an audit event for a transaction refused because its destination was flagged
+func (s *Service) RecordBlocked(ctx context.Context, userID uuid.UUID, subjectType string, subjectID uuid.UUID, verdict *Verdict) {
+	details := map[string]interface{}{
+		"subject_type": subjectType,
+		"subject_id":   subjectID.String(),
+		"network":      verdict.Network,
+		"address":      verdict.Address,
+		"risk":         verdict.Risk,
+		"reason":       verdict.Reason,
+		"source":       verdict.Source,
+	}
+
+	description := fmt.Sprintf("Blocked %s to flagged address %s", subjectType, verdict.Address)
+	if err := s.auditLogger.LogEvent(ctx, utils.AuditEventAddressBlocked, utils.AuditSeverityCritical, description, &userID, nil, "", "", false, details); err != nil {
+		// The transaction is still refused; losing the audit entry must not let it through
+		log.Printf("Failed to write address screening audit event: %v", err)
+	}
+}
+
+// ListBlockedAddresses returns a page of blocklist entries, optionally filtered by network
+func (s *Service) ListBlockedAddresses(network string, page, pageSize int) ([]models.BlockedAddress, int64, error) {
+	query := s.db.Model(&models.BlockedAddress{})
+	if network != "" {
+		query = query.Where("network = ?", strings.ToLower(network))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error counting blocked addresses: %w", err)
+	}
+
+	var entries []models.BlockedAddress
+	offset := (page - 1) * pageSize
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&entries).Error; err != nil {
+		return nil, 0, fmt.Errorf("error getting blocked addresses: %w", err)
+	}
+
+	return entries, total, nil
+}
+
+// BlockAddress adds an address to the blocklist
+func (s *Service) BlockAddress(ctx context.Context, adminID uuid.UUID, network, address, reason, source string) (*models.BlockedAddress, error) {
+	network = strings.ToLower(network)
+	if err := crypto.ValidateAddress(network, address); err != nil {
+		return nil, err
+	}
+	if source == "" {
+		source = SourceManual
+	}
+
+	normalized := NormalizeAddress(network, address)
+
+	var count int64
+	if err := s.db.Model(&models.BlockedAddress{}).
+		Where("network = ? AND address = ?", network, normalized).
+		Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("error checking address blocklist: %w", err)
+	}
+	if count > 0 {
+		return nil, ErrAddressAlreadyBlocked
+	}
+
+	entry := models.BlockedAddress{
+		Network: network,
+		Address: normalized,
+		Reason:  reason,
+		Source:  source,
+		AddedBy: &adminID,
+	}
+	if err := s.db.Create(&entry).Error; err != nil {
+		return nil, fmt.Errorf("error blocking address: %w", err)
+	}
+
+	s.auditLogger.LogAdminAction(ctx, adminID, nil, "", "", "block_address", true, map[string]interface{}{
+		"network": network,
+		"address": normalized,
+		"reason":  reason,
+		"source":  source,
+	})
+
+	return &entry, nil
+}
+
+// UnblockAddress removes an address from the blocklist
+func (s *Service) UnblockAddress(ctx context.Context, adminID, id uuid.UUID) error {
+	var entry models.BlockedAddress
+	if err := s.db.First(&entry, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrBlockedAddressNotFound
+		}
+		return fmt.Errorf("error getting blocked address: %w", err)
+	}
+
+	if err := s.db.Delete(&entry).Error; err != nil {
+		return fmt.Errorf("error unblocking address: %w", err)
+	}
+
+	s.auditLogger.LogAdminAction(ctx, adminID, nil, "", "", "unblock_address", true, map[string]interface{}{
+		"network": entry.Network,
+		"address": entry.Address,
+	})
+
+	return nil
+}
+
+// NormalizeAddress returns the canonical form of an address used for blocklist lookups.
+// EVM and bech32 addresses are case-insensitive, so they are lowercased.
+func NormalizeAddress(network, address string) string {
+	address = strings.TrimSpace(address)
+	switch strings.ToLower(network) {
+	case crypto.NetworkBase, crypto.NetworkEthereum, crypto.NetworkPolygon:
+		return strings.ToLower(address)
+	case crypto.NetworkBitcoin:
+		if strings.HasPrefix(strings.ToLower(address), "bc1") {
+			return strings.ToLower(address)
+		}
+	}
+	return address
+}
+
+// equivalentNetworks returns the networks that share an address space with network.
+// An EVM address blocked on one chain is controlled by the same key on every EVM chain.
+func equivalentNetworks(network string) []string {
+	switch network {
+	case crypto.NetworkBase, crypto.NetworkEthereum, crypto.NetworkPolygon:
+		return []string{crypto.NetworkBase, crypto.NetworkEthereum, crypto.NetworkPolygon}
+	}
+	return []string{network}
+}
@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalObjectsPath is the route local signed URLs point at; the key follows it
+const LocalObjectsPath = "/api/storage/objects/"
+
+// encryptedPrefix marks files written encrypted, so files stored before encryption still read
+var encryptedPrefix = []byte("RVENC1\x00")
+
+// LocalConfig configures a LocalBackend
+type LocalConfig struct {
+	// Root is the directory objects are stored under
+	Root string
+	// EncryptionKey is a 32-byte AES-256 key. Objects are stored unencrypted without one.
+	EncryptionKey []byte
+	// PublicURL is the API's base URL used in signed URLs; they're relative when it's empty
+	PublicURL string
+}
+
+// LocalConfigFromEnv reads STORAGE_LOCAL_DIR, STORAGE_ENCRYPTION_KEY (32 bytes, base64
+// encoded) and STORAGE_PUBLIC_URL
+func LocalConfigFromEnv() LocalConfig {
+	cfg := LocalConfig{
+		Root:      os.Getenv("STORAGE_LOCAL_DIR"),
+		PublicURL: os.Getenv("STORAGE_PUBLIC_URL"),
+	}
+	if cfg.Root == "" {
+		cfg.Root = "uploads"
+	}
+	if value := os.Getenv("STORAGE_ENCRYPTION_KEY"); value != "" {
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			log.Printf("STORAGE_ENCRYPTION_KEY is not valid base64: %v", err)
+		} else {
+			cfg.EncryptionKey = key
+		}
+	}
+	return cfg
+}
+
+// LocalBackend stores objects on local disk, encrypted with AES-256-GCM. Signed URLs point
+// back at the API (LocalObjectsPath) and are checked with VerifySignature before serving.
+type LocalBackend struct {
+	root       string
+	aead       cipher.AEAD
+	signingKey []byte
+	publicURL  string
+}
+
+// NewLocalBackend creates a local backend, creating the root directory if needed
+func NewLocalBackend(cfg LocalConfig) (*LocalBackend, error) {
+	if err := os.MkdirAll(cfg.Root, 0700); err != nil {
+		return nil, fmt.Errorf("error creating storage directory: %w", err)
+	}
+
+	backend := &LocalBackend{
+		root:      cfg.Root,
+		publicURL: strings.TrimSuffix(cfg.PublicURL, "/"),
+	}
+
+	if len(cfg.EncryptionKey) > 0 {
+		block, err := aes.NewCipher(cfg.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage encryption key: %w", err)
+		}
+		if backend.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, fmt.Errorf("error creating storage cipher: %w", err)
+		}
+
+		// Sign URLs with a key derived from the encryption key so they survive restarts
+		mac := hmac.New(sha256.New, cfg.EncryptionKey)
+		mac.Write([]byte("storage-url-signing"))
+		backend.signingKey = mac.Sum(nil)
+	} else {
+		log.Printf("STORAGE_ENCRYPTION_KEY not set, objects in %s will not be encrypted", cfg.Root)
+
+		// Signed URLs are short-lived, so a per-process key is enough
+		backend.signingKey = make([]byte, 32)
+		if _, err := rand.Read(backend.signingKey); err != nil {
+			return nil, fmt.Errorf("error generating signing key: %w", err)
+		}
+	}
+
+	return backend, nil
+}
+
+// path maps a key to its file under the root
+func (b *LocalBackend) path(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(b.root, filepath.FromSlash(cleaned)), nil
+}
+
+// Put encrypts and writes an object. The file is written to a temporary name and renamed,
+// so a failed write never leaves a partial object behind.
+func (b *LocalBackend) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	objectPath, err := b.path(key)
+	if err != nil {
+		return err
+	}
+
+	if b.aead != nil {
+		nonce := make([]byte, b.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("error generating nonce: %w", err)
+		}
+		sealed := append(append([]byte{}, encryptedPrefix...), nonce...)
+		data = b.aead.Seal(sealed, nonce, data, []byte(key))
+	}
+
+	dir := filepath.Dir(objectPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating object directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, filepath.Base(objectPath)+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating object file: %w", err)
+	}
+	tempPath := file.Name()
+
+	_, writeErr := file.Write(data)
+	closeErr := file.Close()
+	if writeErr != nil || closeErr != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("error writing object: %w", errors.Join(writeErr, closeErr))
+	}
+
+	if err := os.Rename(tempPath, objectPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("error saving object: %w", err)
+	}
+	return nil
+}
+
+// Get reads and decrypts an object. The content type is sniffed from its contents.
+func (b *LocalBackend) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	objectPath, err := b.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := os.ReadFile(objectPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("error reading object: %w", err)
+	}
+
+	if bytes.HasPrefix(data, encryptedPrefix) {
+		if b.aead == nil {
+			return nil, nil, errors.New("object is encrypted but no storage encryption key is configured")
+		}
+		sealed := data[len(encryptedPrefix):]
+		if len(sealed) < b.aead.NonceSize() {
+			return nil, nil, errors.New("encrypted object is truncated")
+		}
+		nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+		if data, err = b.aead.Open(nil, nonce, ciphertext, []byte(key)); err != nil {
+			return nil, nil, fmt.Errorf("error decrypting object: %w", err)
+		}
+	}
+
+	info := &ObjectInfo{
+		Size:        int64(len(data)),
+		ContentType: http.DetectContentType(data),
+	}
+	return io.NopCloser(bytes.NewReader(data)), info, nil
+}
+
+// Delete removes an object. Deleting a missing object isn't an error.
+func (b *LocalBackend) Delete(ctx context.Context, key string) error {
+	objectPath, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(objectPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error deleting object: %w", err)
+	}
+	return nil
+}
+
+// SignedURL returns a URL under LocalObjectsPath carrying the expiry and an HMAC of the key
+func (b *LocalBackend) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	query := url.Values{
+		"expires":   {expires},
+		"signature": {b.sign(cleaned, expires)},
+	}
+	return b.publicURL + LocalObjectsPath + cleaned + "?" + query.Encode(), nil
+}
+
+// VerifySignature checks the expiry and signature from a URL created by SignedURL
+func (b *LocalBackend) VerifySignature(key, expires, signature string) error {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(b.sign(cleaned, expires))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (b *LocalBackend) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, b.signingKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalBackend(t *testing.T) (*LocalBackend, string) {
+	t.Helper()
+	dir := t.TempDir()
+	backend, err := NewLocalBackend(LocalConfig{
+		Root:          dir,
+		EncryptionKey: bytes.Repeat([]byte{7}, 32),
+	})
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+	return backend, dir
+}
+
+func TestLocalBackendEncryptsObjects(t *testing.T) {
+	backend, dir := newTestLocalBackend(t)
+	ctx := context.Background()
+	data := []byte("%PDF-1.7 passport scan")
+
+	if err := backend.Put(ctx, "kyc/user/id_front.pdf", data, "application/pdf"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	onDisk, err := os.ReadFile(filepath.Join(dir, "kyc", "user", "id_front.pdf"))
+	if err != nil {
+		t.Fatalf("expected object on disk: %v", err)
+	}
+	if bytes.Contains(onDisk, []byte("passport scan")) {
+		t.Error("expected object to be encrypted on disk")
+	}
+
+	object, info, err := backend.Get(ctx, "kyc/user/id_front.pdf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer object.Close()
+	read, _ := io.ReadAll(object)
+	if !bytes.Equal(read, data) || info.ContentType != "application/pdf" || info.Size != int64(len(data)) {
+		t.Errorf("unexpected object %q %+v", read, info)
+	}
+
+	if err := backend.Delete(ctx, "kyc/user/id_front.pdf"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := backend.Get(ctx, "kyc/user/id_front.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestLocalBackendReadsUnencryptedFiles(t *testing.T) {
+	backend, dir := newTestLocalBackend(t)
+	os.MkdirAll(filepath.Join(dir, "kyc"), 0700)
+	os.WriteFile(filepath.Join(dir, "kyc", "old.pdf"), []byte("%PDF-1.4 legacy"), 0600)
+
+	object, _, err := backend.Get(context.Background(), "kyc/old.pdf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer object.Close()
+	if read, _ := io.ReadAll(object); string(read) != "%PDF-1.4 legacy" {
+		t.Errorf("unexpected contents %q", read)
+	}
+}
+
+func TestLocalBackendRejectsKeysOutsideRoot(t *testing.T) {
+	backend, _ := newTestLocalBackend(t)
+	for _, key := range []string{"", "../secrets", "kyc/../../etc/passwd"} {
+		if err := backend.Put(context.Background(), key, []byte("x"), "text/plain"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey for %q, got %v", key, err)
+		}
+	}
+}
+
+func TestLocalBackendSignedURL(t *testing.T) {
+	backend, _ := newTestLocalBackend(t)
+
+	signed, err := backend.SignedURL(context.Background(), "kyc/user/selfie.jpg", time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("invalid URL %q: %v", signed, err)
+	}
+	key := strings.TrimPrefix(parsed.Path, LocalObjectsPath)
+	expires, signature := parsed.Query().Get("expires"), parsed.Query().Get("signature")
+
+	if err := backend.VerifySignature(key, expires, signature); err != nil {
+		t.Errorf("expected signature to verify, got %v", err)
+	}
+	if err := backend.VerifySignature("kyc/other/selfie.jpg", expires, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected signature for another key to fail, got %v", err)
+	}
+
+	expired, _ := backend.SignedURL(context.Background(), key, -time.Minute)
+	parsed, _ = url.Parse(expired)
+	if err := backend.VerifySignature(key, parsed.Query().Get("expires"), parsed.Query().Get("signature")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected expired signature to fail, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+)
+
+// GCSEndpoint is Google Cloud Storage's S3-compatible (XML API) endpoint
+const GCSEndpoint = "storage.googleapis.com"
+
+// S3Config configures an S3Backend
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	// ServerSideEncryption requests SSE-S3 (AES-256) on every upload. GCS always encrypts
+	// objects at rest and doesn't accept the SSE-S3 header, so it's off for GCS.
+	ServerSideEncryption bool
+}
+
+// S3ConfigFromEnv reads STORAGE_ENDPOINT, STORAGE_REGION, STORAGE_BUCKET, STORAGE_ACCESS_KEY,
+// STORAGE_SECRET_KEY and STORAGE_USE_SSL for the "s3" or "gcs" driver. GCS uses HMAC
+// interoperability keys and defaults to GCSEndpoint.
+func S3ConfigFromEnv(driver string) S3Config {
+	cfg := S3Config{
+		Endpoint:             os.Getenv("STORAGE_ENDPOINT"),
+		Region:               os.Getenv("STORAGE_REGION"),
+		Bucket:               os.Getenv("STORAGE_BUCKET"),
+		AccessKey:            os.Getenv("STORAGE_ACCESS_KEY"),
+		SecretKey:            os.Getenv("STORAGE_SECRET_KEY"),
+		UseSSL:               os.Getenv("STORAGE_USE_SSL") != "false",
+		ServerSideEncryption: driver != "gcs",
+	}
+	if cfg.Endpoint == "" {
+		if driver == "gcs" {
+			cfg.Endpoint = GCSEndpoint
+		} else {
+			cfg.Endpoint = "s3.amazonaws.com"
+		}
+	}
+	return cfg
+}
+
+// S3Backend stores objects in an S3-compatible bucket (AWS S3, GCS, MinIO). Signed URLs
+// are presigned GET requests.
+type S3Backend struct {
+	client *minio.Client
+	bucket string
+	sse    encrypt.ServerSide
+}
+
+// NewS3Backend creates an S3 backend for cfg.Bucket
+func NewS3Backend(cfg S3Config) (*S3Backend, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("STORAGE_BUCKET is required for object storage")
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating object storage client: %w", err)
+	}
+
+	backend := &S3Backend{
+		client: client,
+		bucket: cfg.Bucket,
+	}
+	if cfg.ServerSideEncryption {
+		backend.sse = encrypt.NewSSE()
+	}
+	return backend, nil
+}
+
+// Put uploads an object with server-side encryption
+func (b *S3Backend) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.client.PutObject(ctx, b.bucket, cleaned, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType:          contentType,
+		ServerSideEncryption: b.sse,
+	})
+	if err != nil {
+		return fmt.Errorf("error uploading object: %w", err)
+	}
+	return nil
+}
+
+// Get downloads an object
+func (b *S3Backend) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	object, err := b.client.GetObject(ctx, b.bucket, cleaned, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting object: %w", err)
+	}
+
+	// GetObject is lazy; Stat makes the request and reports missing objects
+	stat, err := object.Stat()
+	if err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("error getting object: %w", err)
+	}
+
+	return object, &ObjectInfo{Size: stat.Size, ContentType: stat.ContentType}, nil
+}
+
+// Delete removes an object. Deleting a missing object isn't an error.
+func (b *S3Backend) Delete(ctx context.Context, key string) error {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	if err := b.client.RemoveObject(ctx, b.bucket, cleaned, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("error deleting object: %w", err)
+	}
+	return nil
+}
+
+// SignedURL returns a presigned GET URL for the object
+func (b *S3Backend) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := b.client.PresignedGetObject(ctx, b.bucket, cleaned, expiry, url.Values{})
+	if err != nil {
+		return "", fmt.Errorf("error signing object URL: %w", err)
+	}
+	return signed.String(), nil
+}
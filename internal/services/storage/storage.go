@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultSignedURLExpiry is how long signed document URLs stay valid
+const DefaultSignedURLExpiry = 5 * time.Minute
+
+var (
+	// ErrNotFound is returned when no object is stored under a key
+	ErrNotFound = errors.New("object not found")
+
+	// ErrInvalidKey is returned for empty keys and keys that escape the storage root
+	ErrInvalidKey = errors.New("invalid storage key")
+
+	// ErrInvalidSignature is returned when a signed URL is invalid or has expired
+	ErrInvalidSignature = errors.New("invalid or expired signature")
+)
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// Backend stores objects under slash-separated keys. Objects are encrypted at rest, and
+// records should keep the key rather than a path or URL so the backend can be changed.
+type Backend interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that grants read access to the object until expiry passes
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// Both backends must satisfy Backend
+var (
+	_ Backend = (*LocalBackend)(nil)
+	_ Backend = (*S3Backend)(nil)
+)
+
+// DefaultBackend creates the backend selected by STORAGE_BACKEND: "local" (the default),
+// "s3" or "gcs". See LocalConfigFromEnv and S3ConfigFromEnv for the other settings.
+func DefaultBackend() (Backend, error) {
+	switch driver := strings.ToLower(os.Getenv("STORAGE_BACKEND")); driver {
+	case "", "local":
+		return NewLocalBackend(LocalConfigFromEnv())
+	case "s3", "gcs":
+		return NewS3Backend(S3ConfigFromEnv(driver))
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", driver)
+	}
+}
+
+// cleanKey normalises a key and rejects ones that are empty or point outside the storage root
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + strings.ReplaceAll(key, "\\", "/"))
+	cleaned = strings.TrimPrefix(cleaned, "/")
+	if cleaned == "" || cleaned == "." || cleaned != strings.TrimPrefix(key, "/") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return cleaned, nil
+}
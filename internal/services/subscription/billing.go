@@ -0,0 +1,366 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/payment"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPastDueAfter is how many renewals in a row must fail before a subscription is
+	// marked past due. Until then it stays active while the charge is retried.
+	DefaultPastDueAfter = 2
+
+	// ChargeLease is how long a renewal holds a subscription before another run may retry it
+	ChargeLease = 15 * time.Minute
+)
+
+// DefaultDunningSchedule retries a failed renewal after 1, 3 and 5 days before cancelling
+var DefaultDunningSchedule = []time.Duration{
+	24 * time.Hour,
+	3 * 24 * time.Hour,
+	5 * 24 * time.Hour,
+}
+
+// ValidInterval reports whether plans can bill on the interval
+func ValidInterval(interval models.BillingInterval) bool {
+	switch interval {
+	case models.BillingIntervalMonthly, models.BillingIntervalQuarterly, models.BillingIntervalYearly:
+		return true
+	}
+	return false
+}
+
+// AddInterval moves t forward by n billing intervals. Days past the end of a shorter month
+// are clamped to its last day, so a plan started on 31 January renews on 28 or 29 February
+// rather than in March.
+func AddInterval(t time.Time, interval models.BillingInterval, n int) time.Time {
+	months := 0
+	switch interval {
+	case models.BillingIntervalMonthly:
+		months = n
+	case models.BillingIntervalQuarterly:
+		months = 3 * n
+	case models.BillingIntervalYearly:
+		months = 12 * n
+	}
+
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// RetryDelay returns how long to wait before retrying a renewal that has failed
+// failedCount times in a row. It returns false once the schedule is exhausted.
+func RetryDelay(schedule []time.Duration, failedCount int) (time.Duration, bool) {
+	if failedCount < 1 || failedCount > len(schedule) {
+		return 0, false
+	}
+	return schedule[failedCount-1], true
+}
+
+// DueSubscriptions returns the IDs of subscriptions with a renewal or retry due at now
+func (s *SubscriptionService) DueSubscriptions(now time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := s.db.Model(&models.Subscription{}).
+		Where("status IN ? AND next_payment_date <= ?", liveStatuses, now).
+		Order("next_payment_date ASC").
+		Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("error finding due subscriptions: %w", err)
+	}
+	return ids, nil
+}
+
+// ChargeDue charges every subscription whose renewal or retry is due at now and ends
+// subscriptions cancelled at the end of their period. It returns how many subscriptions were
+// processed; an error on one subscription doesn't stop the others. Once ctx is done the
+// remaining subscriptions are left for the next run.
+func (s *SubscriptionService) ChargeDue(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.DueSubscriptions(now)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for i, id := range due {
+		if ctx.Err() != nil {
+			log.Printf("Stopped renewing subscriptions with %d left: %v", len(due)-i, ctx.Err())
+			break
+		}
+		if _, err := s.ChargeSubscription(ctx, id, now); err != nil {
+			log.Printf("Failed to renew subscription %s: %v", id, err)
+			continue
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+// ChargeSubscription renews a subscription if it's due at now. A declined charge isn't an
+// error: the subscription is retried on the dunning schedule, goes past due after
+// PastDueAfter failures and is cancelled once the schedule runs out. Errors are only
+// returned when the charge couldn't be attempted.
+//
+// Renewals are idempotent per billing period. The subscription is claimed before charging so
+// overlapping runs skip it, a period that was already paid for is never charged again, and
+// each attempt uses a reference derived from the period so a retried attempt reuses the
+// earlier charge instead of making a new one.
+func (s *SubscriptionService) ChargeSubscription(ctx context.Context, subscriptionID uuid.UUID, now time.Time) (*models.Subscription, error) {
+	var sub models.Subscription
+	if err := s.db.First(&sub, "id = ?", subscriptionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, fmt.Errorf("error getting subscription: %w", err)
+	}
+
+	if !isDue(&sub, now) {
+		return &sub, nil
+	}
+
+	// Cancelled at period end: the paid period is over, so stop without charging
+	if sub.CancelAtPeriodEnd {
+		if err := s.db.Model(&sub).Updates(map[string]interface{}{
+			"status":            models.SubscriptionStatusCancelled,
+			"next_payment_date": nil,
+		}).Error; err != nil {
+			return nil, fmt.Errorf("error ending subscription: %w", err)
+		}
+		return &sub, nil
+	}
+
+	claimed, err := s.claim(&sub, now)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return &sub, nil
+	}
+
+	// Plans that were deactivated or deleted keep billing the subscribers they already have
+	var plan models.SubscriptionPlan
+	if err := s.db.Unscoped().First(&plan, "id = ?", sub.PlanID).Error; err != nil {
+		return nil, fmt.Errorf("error getting subscription plan: %w", err)
+	}
+
+	var subscriber models.User
+	if err := s.db.First(&subscriber, "id = ?", sub.SubscriberID).Error; err != nil {
+		return nil, fmt.Errorf("error getting subscriber: %w", err)
+	}
+
+	periodStart := sub.CurrentPeriodEnd
+	periodEnd := AddInterval(periodStart, plan.Interval, 1)
+
+	// An earlier run may have paid for the period and stopped before moving the subscription on
+	var paidPeriod models.SubscriptionPayment
+	err = s.db.Where("subscription_id = ? AND period_start = ? AND status = ?", sub.ID, periodStart, models.PaymentStatusCompleted).
+		First(&paidPeriod).Error
+	if err == nil {
+		return s.recordRenewal(&sub, &paidPeriod, periodStart, periodEnd, now)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("error checking subscription payments: %w", err)
+	}
+
+	reference := RenewalReference(sub.ID, periodStart, sub.FailedPaymentCount)
+	paid, err := s.charge(ctx, &sub, &plan, &subscriber, periodStart, periodEnd, reference)
+	if err != nil && !errors.Is(err, payment.ErrChargeFailed) {
+		if paid == nil || paid.Status != models.PaymentStatusCompleted {
+			return nil, err
+		}
+		// The card was charged but crediting the merchant failed; the period is still paid for
+		log.Printf("Subscription %s renewed but payment %s needs attention: %v", sub.ID, paid.Reference, err)
+	}
+
+	if errors.Is(err, payment.ErrChargeFailed) {
+		return s.recordFailedRenewal(&sub, paid, periodStart, periodEnd, now)
+	}
+	return s.recordRenewal(&sub, &models.SubscriptionPayment{
+		SubscriptionID: sub.ID,
+		PaymentID:      paid.ID,
+		PeriodStart:    periodStart,
+		PeriodEnd:      periodEnd,
+		Status:         models.PaymentStatusCompleted,
+		RetryCount:     sub.FailedPaymentCount,
+	}, periodStart, periodEnd, now)
+}
+
+// RenewalReference is the payment reference for an attempt to charge a billing period.
+// attempt counts the failed charges before it, so retries after a decline get a new
+// reference while re-running the same attempt reuses the old one.
+func RenewalReference(subscriptionID uuid.UUID, periodStart time.Time, attempt int) string {
+	return fmt.Sprintf("SUB-%s-%s-%d", subscriptionID, periodStart.UTC().Format("20060102"), attempt)
+}
+
+// StatusAfterFailure returns the status of a subscription whose renewal has failed
+// failedCount times in a row, and how long to wait before retrying. A zero delay means the
+// dunning schedule is exhausted and the subscription is cancelled.
+func StatusAfterFailure(schedule []time.Duration, pastDueAfter, failedCount int) (models.SubscriptionStatus, time.Duration) {
+	delay, ok := RetryDelay(schedule, failedCount)
+	if !ok {
+		return models.SubscriptionStatusCancelled, 0
+	}
+	if failedCount >= pastDueAfter {
+		return models.SubscriptionStatusPastDue, delay
+	}
+	return models.SubscriptionStatusActive, delay
+}
+
+// claim pushes a due subscription's payment date past now for ChargeLease, so other runs
+// don't pick it up while it's being charged. It reports false if another run claimed it
+// first. If this run stops before recording the outcome, the renewal is retried once the
+// lease runs out.
+func (s *SubscriptionService) claim(sub *models.Subscription, now time.Time) (bool, error) {
+	result := s.db.Model(&models.Subscription{}).
+		Where("id = ? AND next_payment_date = ?", sub.ID, *sub.NextPaymentDate).
+		Update("next_payment_date", now.Add(ChargeLease))
+	if result.Error != nil {
+		return false, fmt.Errorf("error claiming subscription: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// charge bills the subscriber's saved card for one period of the plan
+func (s *SubscriptionService) charge(ctx context.Context, sub *models.Subscription, plan *models.SubscriptionPlan, subscriber *models.User, periodStart, periodEnd time.Time, reference string) (*models.Payment, error) {
+	metadata := map[string]interface{}{
+		"subscription_id": sub.ID.String(),
+		"plan_id":         plan.ID.String(),
+		"subscriber_id":   subscriber.ID.String(),
+		"period_start":    periodStart,
+		"period_end":      periodEnd,
+		"recurring":       true,
+	}
+
+	return s.paymentSvc.ChargeSavedPaymentMethod(
+		ctx,
+		plan.UserID,
+		models.PaymentProvider(sub.PaymentMethod),
+		plan.Amount,
+		plan.Currency,
+		subscriber.Email,
+		fmt.Sprintf("%s %s", subscriber.FirstName, subscriber.LastName),
+		sub.AuthorizationCode,
+		reference,
+		metadata,
+	)
+}
+
+// recordRenewal starts the period that was just paid for. record is saved unless it
+// already exists.
+func (s *SubscriptionService) recordRenewal(sub *models.Subscription, record *models.SubscriptionPayment, periodStart, periodEnd, now time.Time) (*models.Subscription, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(sub).Updates(map[string]interface{}{
+			"status":               models.SubscriptionStatusActive,
+			"current_period_start": periodStart,
+			"current_period_end":   periodEnd,
+			"last_payment_date":    now,
+			"next_payment_date":    periodEnd,
+			"failed_payment_count": 0,
+		}).Error; err != nil {
+			return err
+		}
+		if record.ID != uuid.Nil {
+			return nil
+		}
+		return tx.Create(record).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error recording renewal: %w", err)
+	}
+
+	sub.Status = models.SubscriptionStatusActive
+	sub.CurrentPeriodStart = periodStart
+	sub.CurrentPeriodEnd = periodEnd
+	sub.LastPaymentDate = &now
+	sub.NextPaymentDate = &periodEnd
+	sub.FailedPaymentCount = 0
+
+	s.notify(sub.ID, "renewed")
+	return sub, nil
+}
+
+// recordFailedRenewal schedules the next dunning retry, or cancels the subscription once
+// the retries are used up
+func (s *SubscriptionService) recordFailedRenewal(sub *models.Subscription, attempt *models.Payment, periodStart, periodEnd, now time.Time) (*models.Subscription, error) {
+	failedCount := sub.FailedPaymentCount + 1
+	status, delay := StatusAfterFailure(s.DunningSchedule, s.PastDueAfter, failedCount)
+	updates := map[string]interface{}{
+		"status":               status,
+		"failed_payment_count": failedCount,
+	}
+
+	var nextRetry *time.Time
+	if status == models.SubscriptionStatusCancelled {
+		updates["cancelled_at"] = now
+		updates["next_payment_date"] = nil
+	} else {
+		retryAt := now.Add(delay)
+		nextRetry = &retryAt
+		updates["next_payment_date"] = retryAt
+	}
+
+	record := models.SubscriptionPayment{
+		SubscriptionID: sub.ID,
+		PeriodStart:    periodStart,
+		PeriodEnd:      periodEnd,
+		Status:         models.PaymentStatusFailed,
+		RetryCount:     sub.FailedPaymentCount,
+		NextRetryDate:  nextRetry,
+	}
+	if attempt != nil {
+		record.PaymentID = attempt.ID
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(sub).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error recording failed renewal: %w", err)
+	}
+
+	sub.FailedPaymentCount = failedCount
+	sub.Status = status
+	sub.NextPaymentDate = nextRetry
+	if nextRetry == nil {
+		sub.CancelledAt = &now
+	}
+
+	if status == models.SubscriptionStatusActive {
+		s.notify(sub.ID, "failed")
+	} else {
+		s.notify(sub.ID, string(status))
+	}
+	return sub, nil
+}
+
+// notify tells the subscriber about a renewal outcome. Failing to notify doesn't undo it.
+func (s *SubscriptionService) notify(subscriptionID uuid.UUID, status string) {
+	if s.notificationSvc == nil {
+		return
+	}
+	if err := s.notificationSvc.NotifySubscriptionStatus(subscriptionID, status); err != nil {
+		log.Printf("Failed to send %s notification for subscription %s: %v", status, subscriptionID, err)
+	}
+}
+
+// isDue reports whether a subscription has a renewal or retry due at now
+func isDue(sub *models.Subscription, now time.Time) bool {
+	if sub.Status != models.SubscriptionStatusActive && sub.Status != models.SubscriptionStatusPastDue {
+		return false
+	}
+	return sub.NextPaymentDate != nil && !sub.NextPaymentDate.After(now)
+}
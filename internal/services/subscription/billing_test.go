@@ -0,0 +1,130 @@
+package subscription
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+)
+
+func TestAddInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    time.Time
+		interval models.BillingInterval
+		n        int
+		want     time.Time
+	}{
+		{"monthly", date(2024, 3, 15), models.BillingIntervalMonthly, 1, date(2024, 4, 15)},
+		{"monthly clamps to short month", date(2024, 1, 31), models.BillingIntervalMonthly, 1, date(2024, 2, 29)},
+		{"monthly clamps in non-leap year", date(2023, 1, 31), models.BillingIntervalMonthly, 1, date(2023, 2, 28)},
+		{"monthly across year end", date(2024, 12, 10), models.BillingIntervalMonthly, 1, date(2025, 1, 10)},
+		{"quarterly", date(2024, 11, 30), models.BillingIntervalQuarterly, 1, date(2025, 2, 28)},
+		{"yearly from leap day", date(2024, 2, 29), models.BillingIntervalYearly, 1, date(2025, 2, 28)},
+		{"several periods keep the anchor day", date(2024, 1, 31), models.BillingIntervalMonthly, 2, date(2024, 3, 31)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AddInterval(tt.start, tt.interval, tt.n); !got.Equal(tt.want) {
+				t.Errorf("AddInterval(%s, %s, %d) = %s, want %s", tt.start.Format("2006-01-02"), tt.interval, tt.n, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestAddIntervalKeepsTimeOfDay(t *testing.T) {
+	start := time.Date(2024, 5, 31, 14, 30, 0, 0, time.UTC)
+	want := time.Date(2024, 6, 30, 14, 30, 0, 0, time.UTC)
+	if got := AddInterval(start, models.BillingIntervalMonthly, 1); !got.Equal(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	schedule := []time.Duration{time.Hour, 2 * time.Hour}
+
+	if delay, ok := RetryDelay(schedule, 1); !ok || delay != time.Hour {
+		t.Errorf("expected first retry after an hour, got %s %v", delay, ok)
+	}
+	if delay, ok := RetryDelay(schedule, 2); !ok || delay != 2*time.Hour {
+		t.Errorf("expected second retry after two hours, got %s %v", delay, ok)
+	}
+	if _, ok := RetryDelay(schedule, 3); ok {
+		t.Error("expected the schedule to be exhausted after two retries")
+	}
+	if _, ok := RetryDelay(nil, 1); ok {
+		t.Error("expected no retries with an empty schedule")
+	}
+}
+
+func TestIsDue(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	tests := []struct {
+		name string
+		sub  models.Subscription
+		want bool
+	}{
+		{"active and due", models.Subscription{Status: models.SubscriptionStatusActive, NextPaymentDate: &past}, true},
+		{"past due retry", models.Subscription{Status: models.SubscriptionStatusPastDue, NextPaymentDate: &now}, true},
+		{"not yet due", models.Subscription{Status: models.SubscriptionStatusActive, NextPaymentDate: &future}, false},
+		{"cancelled", models.Subscription{Status: models.SubscriptionStatusCancelled, NextPaymentDate: &past}, false},
+		{"no payment date", models.Subscription{Status: models.SubscriptionStatusActive}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDue(&tt.sub, now); got != tt.want {
+				t.Errorf("isDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestStatusAfterFailure(t *testing.T) {
+	schedule := []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour}
+
+	tests := []struct {
+		failedCount int
+		wantStatus  models.SubscriptionStatus
+		wantDelay   time.Duration
+	}{
+		{1, models.SubscriptionStatusActive, time.Hour},
+		{2, models.SubscriptionStatusPastDue, 2 * time.Hour},
+		{3, models.SubscriptionStatusPastDue, 3 * time.Hour},
+		{4, models.SubscriptionStatusCancelled, 0},
+	}
+
+	for _, tt := range tests {
+		status, delay := StatusAfterFailure(schedule, 2, tt.failedCount)
+		if status != tt.wantStatus || delay != tt.wantDelay {
+			t.Errorf("after %d failures got %s/%s, want %s/%s", tt.failedCount, status, delay, tt.wantStatus, tt.wantDelay)
+		}
+	}
+}
+
+func TestRenewalReference(t *testing.T) {
+	id := uuid.MustParse("6f1c2d3e-4a5b-4c6d-8e7f-901234567890")
+	period := time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC)
+
+	first := RenewalReference(id, period, 0)
+	if first != RenewalReference(id, period, 0) {
+		t.Error("expected the same attempt to reuse its reference")
+	}
+	if first == RenewalReference(id, period, 1) {
+		t.Error("expected a retry to get a new reference")
+	}
+	if first == RenewalReference(id, AddInterval(period, models.BillingIntervalMonthly, 1), 0) {
+		t.Error("expected the next period to get a new reference")
+	}
+	if len(first) > 100 {
+		t.Errorf("reference %q is longer than the payments column", first)
+	}
+}
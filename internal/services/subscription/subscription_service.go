@@ -0,0 +1,519 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/notification"
+	"github.com/revaspay/backend/internal/services/payment"
+	"gorm.io/gorm"
+)
+
+// MaxTrialDays is the longest free trial a plan can offer
+const MaxTrialDays = 365
+
+var (
+	// ErrPlanNotFound is returned when a plan does not exist or belongs to another merchant
+	ErrPlanNotFound = errors.New("subscription plan not found")
+
+	// ErrPlanInactive is returned when subscribing to a plan that no longer accepts subscribers
+	ErrPlanInactive = errors.New("subscription plan is not accepting new subscribers")
+
+	// ErrPlanHasSubscribers is returned when deleting a plan that still has subscriptions to bill
+	ErrPlanHasSubscribers = errors.New("subscription plan has active subscriptions")
+
+	// ErrInvalidPlan is returned when a plan's amount, currency, interval or trial is invalid
+	ErrInvalidPlan = errors.New("invalid subscription plan")
+
+	// ErrSubscriptionNotFound is returned when a subscription does not exist or isn't visible to the user
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+
+	// ErrAlreadySubscribed is returned when the subscriber already has a live subscription to the plan
+	ErrAlreadySubscribed = errors.New("already subscribed to this plan")
+
+	// ErrSubscriptionCancelled is returned when cancelling a subscription that has already ended
+	ErrSubscriptionCancelled = errors.New("subscription is already cancelled")
+
+	// ErrInvalidPaymentMethod is returned when the payment used to save a card can't be reused
+	ErrInvalidPaymentMethod = errors.New("payment method cannot be used for recurring charges")
+)
+
+// supportedCurrencies are the currencies plans can be billed in
+var supportedCurrencies = map[models.Currency]bool{
+	models.CurrencyUSD: true,
+	models.CurrencyEUR: true,
+	models.CurrencyGBP: true,
+	models.CurrencyNGN: true,
+	models.CurrencyGHS: true,
+	models.CurrencyKES: true,
+	models.CurrencyZAR: true,
+}
+
+// liveStatuses are the statuses of subscriptions that will still be charged
+var liveStatuses = []models.SubscriptionStatus{
+	models.SubscriptionStatusActive,
+	models.SubscriptionStatusPastDue,
+}
+
+// PlanInput describes a new subscription plan
+type PlanInput struct {
+	Name        string
+	Description string
+	Amount      float64
+	Currency    models.Currency
+	Interval    models.BillingInterval
+	TrialDays   int
+	Features    map[string]interface{}
+	Metadata    map[string]interface{}
+}
+
+// PlanUpdate holds the plan fields to change. Nil fields are left as they are. A plan's
+// currency and interval can't change once it exists.
+type PlanUpdate struct {
+	Name        *string
+	Description *string
+	Amount      *float64
+	TrialDays   *int
+	Active      *bool
+	Features    map[string]interface{}
+	Metadata    map[string]interface{}
+}
+
+// SubscribeInput describes how a subscriber pays for a plan
+type SubscribeInput struct {
+	// PaymentReference is a completed Paystack card payment by the subscriber. Its card
+	// authorization is saved and charged for every period.
+	PaymentReference string
+	Metadata         map[string]interface{}
+}
+
+// SubscriptionService manages merchants' subscription plans and bills their subscribers
+type SubscriptionService struct {
+	db              *gorm.DB
+	paymentSvc      *payment.PaymentService
+	notificationSvc *notification.Service
+	// DunningSchedule is how long to wait before each retry of a failed charge. A subscription
+	// is cancelled when a charge fails again after the last retry.
+	DunningSchedule []time.Duration
+	// PastDueAfter is how many failed charges in a row mark a subscription past due
+	PastDueAfter int
+}
+
+// NewSubscriptionService creates a new subscription service
+func NewSubscriptionService(db *gorm.DB, paymentSvc *payment.PaymentService) *SubscriptionService {
+	return &SubscriptionService{
+		db:              db,
+		paymentSvc:      paymentSvc,
+		DunningSchedule: DefaultDunningSchedule,
+		PastDueAfter:    DefaultPastDueAfter,
+	}
+}
+
+// SetNotificationService sets the service used to tell subscribers about renewals and failed charges
+func (s *SubscriptionService) SetNotificationService(notificationSvc *notification.Service) {
+	s.notificationSvc = notificationSvc
+}
+
+// CreatePlan creates a plan for a merchant
+func (s *SubscriptionService) CreatePlan(merchantID uuid.UUID, input PlanInput) (*models.SubscriptionPlan, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	input.Currency = models.Currency(strings.ToUpper(string(input.Currency)))
+	input.Interval = models.BillingInterval(strings.ToLower(string(input.Interval)))
+
+	if input.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidPlan)
+	}
+	if err := validateAmount(input.Amount); err != nil {
+		return nil, err
+	}
+	if !supportedCurrencies[input.Currency] {
+		return nil, fmt.Errorf("%w: unsupported currency %q", ErrInvalidPlan, input.Currency)
+	}
+	if !ValidInterval(input.Interval) {
+		return nil, fmt.Errorf("%w: interval must be monthly, quarterly or yearly", ErrInvalidPlan)
+	}
+	if err := validateTrialDays(input.TrialDays); err != nil {
+		return nil, err
+	}
+
+	plan := models.SubscriptionPlan{
+		UserID:      merchantID,
+		Name:        input.Name,
+		Description: input.Description,
+		Amount:      input.Amount,
+		Currency:    input.Currency,
+		Interval:    input.Interval,
+		Active:      true,
+		TrialDays:   input.TrialDays,
+		Features:    models.JSON(input.Features),
+		Metadata:    models.JSON(input.Metadata),
+	}
+
+	if err := s.db.Create(&plan).Error; err != nil {
+		return nil, fmt.Errorf("error creating subscription plan: %w", err)
+	}
+
+	return &plan, nil
+}
+
+// GetPlan gets an active plan that a customer can subscribe to
+func (s *SubscriptionService) GetPlan(planID uuid.UUID) (*models.SubscriptionPlan, error) {
+	var plan models.SubscriptionPlan
+	if err := s.db.First(&plan, "id = ?", planID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlanNotFound
+		}
+		return nil, fmt.Errorf("error getting subscription plan: %w", err)
+	}
+	if !plan.Active {
+		return nil, ErrPlanInactive
+	}
+	return &plan, nil
+}
+
+// ListPlans returns a page of a merchant's plans with their live subscriber counts
+func (s *SubscriptionService) ListPlans(merchantID uuid.UUID, page, pageSize int) ([]models.SubscriptionPlan, int64, error) {
+	query := s.db.Model(&models.SubscriptionPlan{}).Where("user_id = ?", merchantID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error counting subscription plans: %w", err)
+	}
+
+	var plans []models.SubscriptionPlan
+	offset := (page - 1) * pageSize
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&plans).Error; err != nil {
+		return nil, 0, fmt.Errorf("error getting subscription plans: %w", err)
+	}
+
+	for i := range plans {
+		count, err := s.countLiveSubscriptions(plans[i].ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		plans[i].SubscriberCount = int(count)
+	}
+
+	return plans, total, nil
+}
+
+// UpdatePlan changes a merchant's plan. A new amount is charged from each subscriber's next renewal.
+func (s *SubscriptionService) UpdatePlan(merchantID, planID uuid.UUID, update PlanUpdate) (*models.SubscriptionPlan, error) {
+	plan, err := s.getMerchantPlan(merchantID, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: name is required", ErrInvalidPlan)
+		}
+		updates["name"] = name
+	}
+	if update.Description != nil {
+		updates["description"] = *update.Description
+	}
+	if update.Amount != nil {
+		if err := validateAmount(*update.Amount); err != nil {
+			return nil, err
+		}
+		updates["amount"] = *update.Amount
+	}
+	if update.TrialDays != nil {
+		if err := validateTrialDays(*update.TrialDays); err != nil {
+			return nil, err
+		}
+		updates["trial_days"] = *update.TrialDays
+	}
+	if update.Active != nil {
+		updates["active"] = *update.Active
+	}
+	if update.Features != nil {
+		updates["features"] = models.JSON(update.Features)
+	}
+	if update.Metadata != nil {
+		updates["metadata"] = models.JSON(update.Metadata)
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(plan).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("error updating subscription plan: %w", err)
+		}
+	}
+
+	return s.getMerchantPlan(merchantID, planID)
+}
+
+// DeletePlan deletes a merchant's plan. Plans with subscriptions still being billed can only
+// be deactivated, so their subscribers keep the plan they're paying for.
+func (s *SubscriptionService) DeletePlan(merchantID, planID uuid.UUID) error {
+	plan, err := s.getMerchantPlan(merchantID, planID)
+	if err != nil {
+		return err
+	}
+
+	count, err := s.countLiveSubscriptions(plan.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrPlanHasSubscribers
+	}
+
+	if err := s.db.Delete(plan).Error; err != nil {
+		return fmt.Errorf("error deleting subscription plan: %w", err)
+	}
+	return nil
+}
+
+// Subscribe subscribes a customer to a plan using the card they paid with in
+// input.PaymentReference. The first period is charged straight away unless the plan has a
+// trial, in which case the first charge is when the trial ends.
+func (s *SubscriptionService) Subscribe(ctx context.Context, subscriberID, planID uuid.UUID, input SubscribeInput) (*models.Subscription, error) {
+	plan, err := s.GetPlan(planID)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing int64
+	if err := s.db.Model(&models.Subscription{}).
+		Where("plan_id = ? AND subscriber_id = ? AND status IN ?", plan.ID, subscriberID, liveStatuses).
+		Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("error checking subscriptions: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrAlreadySubscribed
+	}
+
+	var subscriber models.User
+	if err := s.db.First(&subscriber, "id = ?", subscriberID).Error; err != nil {
+		return nil, fmt.Errorf("error getting subscriber: %w", err)
+	}
+
+	authorization, err := s.savedAuthorization(&subscriber, input.PaymentReference)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sub := models.Subscription{
+		UserID:             plan.UserID,
+		PlanID:             plan.ID,
+		SubscriberID:       subscriber.ID,
+		Status:             models.SubscriptionStatusActive,
+		CurrentPeriodStart: now,
+		PaymentMethod:      string(authorization.Provider),
+		PaymentMethodDetails: models.JSON{
+			"card_type": authorization.Details["card_type"],
+			"last4":     authorization.Details["last4"],
+			"exp_month": authorization.Details["exp_month"],
+			"exp_year":  authorization.Details["exp_year"],
+			"bank":      authorization.Details["bank"],
+		},
+		AuthorizationCode: authorization.Code,
+		Metadata:          models.JSON(input.Metadata),
+	}
+
+	if plan.TrialDays > 0 {
+		trialEnd := now.AddDate(0, 0, plan.TrialDays)
+		sub.TrialStart = &now
+		sub.TrialEnd = &trialEnd
+		sub.CurrentPeriodEnd = trialEnd
+		sub.NextPaymentDate = &trialEnd
+
+		if err := s.db.Create(&sub).Error; err != nil {
+			return nil, fmt.Errorf("error creating subscription: %w", err)
+		}
+		return &sub, nil
+	}
+
+	// Charge the first period before saving, so a declined card doesn't leave a subscription behind
+	periodEnd := AddInterval(now, plan.Interval, 1)
+	sub.ID = uuid.New()
+	paid, err := s.charge(ctx, &sub, plan, &subscriber, now, periodEnd, RenewalReference(sub.ID, now, 0))
+	if err != nil {
+		if errors.Is(err, payment.ErrChargeFailed) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error charging first period: %w", err)
+	}
+
+	sub.CurrentPeriodEnd = periodEnd
+	sub.LastPaymentDate = &now
+	sub.NextPaymentDate = &periodEnd
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&sub).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.SubscriptionPayment{
+			SubscriptionID: sub.ID,
+			PaymentID:      paid.ID,
+			PeriodStart:    now,
+			PeriodEnd:      periodEnd,
+			Status:         models.PaymentStatusCompleted,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating subscription: %w", err)
+	}
+
+	return &sub, nil
+}
+
+// ListSubscriptions returns a page of the subscriptions a customer has taken out
+func (s *SubscriptionService) ListSubscriptions(subscriberID uuid.UUID, page, pageSize int) ([]models.Subscription, int64, error) {
+	return s.listSubscriptions(s.db.Where("subscriber_id = ?", subscriberID), page, pageSize)
+}
+
+// ListPlanSubscriptions returns a page of the subscriptions to one of a merchant's plans
+func (s *SubscriptionService) ListPlanSubscriptions(merchantID, planID uuid.UUID, page, pageSize int) ([]models.Subscription, int64, error) {
+	plan, err := s.getMerchantPlan(merchantID, planID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.listSubscriptions(s.db.Where("plan_id = ?", plan.ID), page, pageSize)
+}
+
+// Cancel stops future charges on a subscription. The subscriber or the plan's merchant can
+// cancel it. By default the subscription runs to the end of the period already paid for;
+// immediately ends it now. A past-due subscription always ends now, since its current
+// period was never paid.
+func (s *SubscriptionService) Cancel(userID, subscriptionID uuid.UUID, immediately bool) (*models.Subscription, error) {
+	var sub models.Subscription
+	if err := s.db.First(&sub, "id = ? AND (subscriber_id = ? OR user_id = ?)", subscriptionID, userID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, fmt.Errorf("error getting subscription: %w", err)
+	}
+
+	if sub.Status != models.SubscriptionStatusActive && sub.Status != models.SubscriptionStatusPastDue {
+		return nil, ErrSubscriptionCancelled
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"cancel_at_period_end": true,
+		"cancelled_at":         now,
+	}
+	if immediately || sub.Status == models.SubscriptionStatusPastDue {
+		updates["status"] = models.SubscriptionStatusCancelled
+		updates["next_payment_date"] = nil
+	}
+
+	if err := s.db.Model(&sub).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("error cancelling subscription: %w", err)
+	}
+
+	if err := s.db.First(&sub, "id = ?", sub.ID).Error; err != nil {
+		return nil, fmt.Errorf("error getting subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// savedAuthorization is a reusable card authorization taken from a completed payment
+type savedAuthorization struct {
+	Provider models.PaymentProvider
+	Code     string
+	Details  map[string]interface{}
+}
+
+// savedAuthorization returns the card authorization from a completed payment the subscriber made
+func (s *SubscriptionService) savedAuthorization(subscriber *models.User, reference string) (*savedAuthorization, error) {
+	if reference == "" {
+		return nil, fmt.Errorf("%w: payment reference is required", ErrInvalidPaymentMethod)
+	}
+
+	var paid models.Payment
+	if err := s.db.First(&paid, "reference = ?", reference).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: payment not found", ErrInvalidPaymentMethod)
+		}
+		return nil, fmt.Errorf("error getting payment: %w", err)
+	}
+
+	// Only the customer who paid can reuse the card they paid with
+	if paid.Status != models.PaymentStatusCompleted || !strings.EqualFold(paid.CustomerEmail, subscriber.Email) {
+		return nil, fmt.Errorf("%w: payment not found", ErrInvalidPaymentMethod)
+	}
+	if paid.Provider != models.PaymentProviderPaystack {
+		return nil, fmt.Errorf("%w: %s payments can't be charged again", ErrInvalidPaymentMethod, paid.Provider)
+	}
+
+	details := map[string]interface{}(paid.PaymentDetails)
+	code, _ := details["authorization_code"].(string)
+	if code == "" {
+		return nil, fmt.Errorf("%w: payment has no reusable card", ErrInvalidPaymentMethod)
+	}
+
+	return &savedAuthorization{
+		Provider: paid.Provider,
+		Code:     code,
+		Details:  details,
+	}, nil
+}
+
+// getMerchantPlan gets a plan owned by the merchant, whether or not it's active
+func (s *SubscriptionService) getMerchantPlan(merchantID, planID uuid.UUID) (*models.SubscriptionPlan, error) {
+	var plan models.SubscriptionPlan
+	if err := s.db.First(&plan, "id = ? AND user_id = ?", planID, merchantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlanNotFound
+		}
+		return nil, fmt.Errorf("error getting subscription plan: %w", err)
+	}
+	return &plan, nil
+}
+
+// countLiveSubscriptions counts a plan's subscriptions that are still being billed
+func (s *SubscriptionService) countLiveSubscriptions(planID uuid.UUID) (int64, error) {
+	var count int64
+	if err := s.db.Model(&models.Subscription{}).
+		Where("plan_id = ? AND status IN ?", planID, liveStatuses).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error counting subscriptions: %w", err)
+	}
+	return count, nil
+}
+
+// listSubscriptions returns a page of the subscriptions matched by query, newest first
+func (s *SubscriptionService) listSubscriptions(query *gorm.DB, page, pageSize int) ([]models.Subscription, int64, error) {
+	query = query.Model(&models.Subscription{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error counting subscriptions: %w", err)
+	}
+
+	var subscriptions []models.Subscription
+	offset := (page - 1) * pageSize
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&subscriptions).Error; err != nil {
+		return nil, 0, fmt.Errorf("error getting subscriptions: %w", err)
+	}
+
+	return subscriptions, total, nil
+}
+
+// validateAmount checks a plan amount is positive
+func validateAmount(amount float64) error {
+	if amount <= 0 {
+		return fmt.Errorf("%w: amount must be greater than zero", ErrInvalidPlan)
+	}
+	return nil
+}
+
+// validateTrialDays checks a plan's trial length is within bounds
+func validateTrialDays(days int) error {
+	if days < 0 || days > MaxTrialDays {
+		return fmt.Errorf("%w: trial_days must be between 0 and %d", ErrInvalidPlan, MaxTrialDays)
+	}
+	return nil
+}
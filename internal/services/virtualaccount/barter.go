@@ -0,0 +1,107 @@
+package virtualaccount
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/models"
+)
+
+// DefaultBarterBaseURL is Barter's API
+const DefaultBarterBaseURL = "https://api.getbarter.co"
+
+// BarterProvider issues USD receiving accounts through Barter
+type BarterProvider struct {
+	secretKey string
+	baseURL   string
+}
+
+// NewBarterProvider creates a Barter provider
+func NewBarterProvider(cfg config.BarterConfig) *BarterProvider {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBarterBaseURL
+	}
+	return &BarterProvider{secretKey: cfg.SecretKey, baseURL: baseURL}
+}
+
+// barterAccountRequest is the body of POST /v1/virtual-accounts
+type barterAccountRequest struct {
+	Currency  string `json:"currency"`
+	Reference string `json:"reference"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Country   string `json:"country,omitempty"`
+}
+
+// barterAccountResponse is Barter's virtual account resource
+type barterAccountResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		ID            string `json:"id"`
+		AccountName   string `json:"account_name"`
+		AccountNumber string `json:"account_number"`
+		RoutingNumber string `json:"routing_number"`
+		SwiftCode     string `json:"swift_code"`
+		BankName      string `json:"bank_name"`
+		BankAddress   string `json:"bank_address"`
+	} `json:"data"`
+}
+
+// Name returns the provider identifier
+func (p *BarterProvider) Name() models.VirtualAccountProvider {
+	return models.VirtualAccountProviderBarter
+}
+
+// Currencies returns the currencies Barter issues accounts in
+func (p *BarterProvider) Currencies() []models.VirtualAccountCurrency {
+	return []models.VirtualAccountCurrency{models.VirtualAccountCurrencyUSD}
+}
+
+// CreateAccount opens a Barter account for the holder
+func (p *BarterProvider) CreateAccount(ctx context.Context, holder AccountHolder, currency models.VirtualAccountCurrency) (*ProviderAccount, error) {
+	var resp barterAccountResponse
+	raw, err := doJSON(ctx, http.MethodPost, p.baseURL+"/v1/virtual-accounts", p.headers(), barterAccountRequest{
+		Currency:  string(currency),
+		Reference: holder.Reference,
+		Name:      holder.FullName,
+		Email:     holder.Email,
+		Country:   holder.Country,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != "success" || resp.Data.ID == "" || resp.Data.AccountNumber == "" {
+		return nil, fmt.Errorf("%w: Barter: %s", ErrProvisioningFailed, resp.Message)
+	}
+
+	return &ProviderAccount{
+		ProviderAccountID: resp.Data.ID,
+		AccountNumber:     resp.Data.AccountNumber,
+		RoutingNumber:     resp.Data.RoutingNumber,
+		SwiftCode:         resp.Data.SwiftCode,
+		BankName:          resp.Data.BankName,
+		BankAddress:       resp.Data.BankAddress,
+		AccountName:       resp.Data.AccountName,
+		Raw:               raw,
+	}, nil
+}
+
+// CloseAccount closes a Barter account
+func (p *BarterProvider) CloseAccount(ctx context.Context, providerAccountID string) error {
+	_, err := doJSON(ctx, http.MethodDelete, p.baseURL+"/v1/virtual-accounts/"+url.PathEscape(providerAccountID), p.headers(), nil, nil)
+	return err
+}
+
+// ListTransactions is not implemented yet for Barter, so Barter accounts aren't reconciled
+func (p *BarterProvider) ListTransactions(ctx context.Context, providerAccountID, cursor string) (*TransactionPage, error) {
+	return nil, &NotImplementedError{Provider: p.Name(), Operation: "transaction history"}
+}
+
+func (p *BarterProvider) headers() map[string]string {
+	return map[string]string{"Authorization": "Bearer " + p.secretKey}
+}
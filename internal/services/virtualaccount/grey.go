@@ -0,0 +1,190 @@
+package virtualaccount
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/models"
+)
+
+// DefaultGreyBaseURL is Grey's business API
+const DefaultGreyBaseURL = "https://api.grey.co"
+
+// GreyProvider issues USD, EUR and GBP receiving accounts through Grey's business API
+type GreyProvider struct {
+	apiKey  string
+	baseURL string
+}
+
+// NewGreyProvider creates a Grey provider
+func NewGreyProvider(cfg config.GreyConfig) *GreyProvider {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultGreyBaseURL
+	}
+	return &GreyProvider{apiKey: cfg.APIKey, baseURL: baseURL}
+}
+
+// greyAccountRequest is the body of POST /v1/accounts
+type greyAccountRequest struct {
+	Currency          string `json:"currency"`
+	CustomerReference string `json:"customer_reference"`
+	CustomerName      string `json:"customer_name"`
+	CustomerEmail     string `json:"customer_email"`
+	Country           string `json:"country,omitempty"`
+}
+
+// greyAccountResponse is Grey's account resource
+type greyAccountResponse struct {
+	Data struct {
+		ID            string `json:"id"`
+		Status        string `json:"status"`
+		AccountName   string `json:"account_name"`
+		AccountNumber string `json:"account_number"`
+		RoutingNumber string `json:"routing_number"`
+		IBAN          string `json:"iban"`
+		SwiftCode     string `json:"swift_code"`
+		SortCode      string `json:"sort_code"`
+		BankName      string `json:"bank_name"`
+		BankAddress   string `json:"bank_address"`
+	} `json:"data"`
+}
+
+// greyTransactionsResponse is a page of GET /v1/accounts/{id}/transactions
+type greyTransactionsResponse struct {
+	Data []struct {
+		ID        string    `json:"id"`
+		Type      string    `json:"type"`
+		Status    string    `json:"status"`
+		Amount    float64   `json:"amount"`
+		Fee       float64   `json:"fee"`
+		Currency  string    `json:"currency"`
+		Reference string    `json:"reference"`
+		CreatedAt time.Time `json:"created_at"`
+		Sender    struct {
+			Name          string `json:"name"`
+			BankName      string `json:"bank_name"`
+			AccountNumber string `json:"account_number"`
+		} `json:"sender"`
+	} `json:"data"`
+	Meta struct {
+		NextCursor string `json:"next_cursor"`
+		HasMore    bool   `json:"has_more"`
+	} `json:"meta"`
+}
+
+// Name returns the provider identifier
+func (p *GreyProvider) Name() models.VirtualAccountProvider {
+	return models.VirtualAccountProviderGrey
+}
+
+// Currencies returns the currencies Grey issues accounts in
+func (p *GreyProvider) Currencies() []models.VirtualAccountCurrency {
+	return []models.VirtualAccountCurrency{
+		models.VirtualAccountCurrencyUSD,
+		models.VirtualAccountCurrencyEUR,
+		models.VirtualAccountCurrencyGBP,
+	}
+}
+
+// CreateAccount opens a Grey account for the holder
+func (p *GreyProvider) CreateAccount(ctx context.Context, holder AccountHolder, currency models.VirtualAccountCurrency) (*ProviderAccount, error) {
+	var resp greyAccountResponse
+	raw, err := doJSON(ctx, http.MethodPost, p.baseURL+"/v1/accounts", p.headers(), greyAccountRequest{
+		Currency:          string(currency),
+		CustomerReference: holder.Reference,
+		CustomerName:      holder.FullName,
+		CustomerEmail:     holder.Email,
+		Country:           holder.Country,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data.ID == "" || resp.Data.AccountNumber == "" && resp.Data.IBAN == "" {
+		return nil, fmt.Errorf("%w: Grey returned no account details", ErrProvisioningFailed)
+	}
+
+	// UK accounts are identified by sort code rather than routing number
+	routingNumber := resp.Data.RoutingNumber
+	if routingNumber == "" {
+		routingNumber = resp.Data.SortCode
+	}
+
+	return &ProviderAccount{
+		ProviderAccountID: resp.Data.ID,
+		AccountNumber:     resp.Data.AccountNumber,
+		RoutingNumber:     routingNumber,
+		IBAN:              resp.Data.IBAN,
+		SwiftCode:         resp.Data.SwiftCode,
+		BankName:          resp.Data.BankName,
+		BankAddress:       resp.Data.BankAddress,
+		AccountName:       resp.Data.AccountName,
+		Raw:               raw,
+	}, nil
+}
+
+// CloseAccount closes a Grey account
+func (p *GreyProvider) CloseAccount(ctx context.Context, providerAccountID string) error {
+	_, err := doJSON(ctx, http.MethodDelete, p.baseURL+"/v1/accounts/"+url.PathEscape(providerAccountID), p.headers(), nil, nil)
+	return err
+}
+
+// ListTransactions returns a page of a Grey account's transactions after cursor
+func (p *GreyProvider) ListTransactions(ctx context.Context, providerAccountID, cursor string) (*TransactionPage, error) {
+	query := url.Values{"limit": {"100"}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	var resp greyTransactionsResponse
+	endpoint := fmt.Sprintf("%s/v1/accounts/%s/transactions?%s", p.baseURL, url.PathEscape(providerAccountID), query.Encode())
+	if _, err := doJSON(ctx, http.MethodGet, endpoint, p.headers(), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	page := &TransactionPage{
+		NextCursor: resp.Meta.NextCursor,
+		HasMore:    resp.Meta.HasMore,
+	}
+	for _, tx := range resp.Data {
+		transaction := ProviderTransaction{
+			ID:                  tx.ID,
+			Type:                models.VirtualAccountTransactionInbound,
+			Status:              greyTransactionStatus(tx.Status),
+			Amount:              tx.Amount,
+			Fee:                 tx.Fee,
+			Currency:            strings.ToUpper(tx.Currency),
+			Reference:           tx.Reference,
+			SenderName:          tx.Sender.Name,
+			SenderBank:          tx.Sender.BankName,
+			SenderAccountNumber: tx.Sender.AccountNumber,
+			CreatedAt:           tx.CreatedAt,
+		}
+		if tx.Type == "debit" {
+			transaction.Type = models.VirtualAccountTransactionOutbound
+		}
+		page.Transactions = append(page.Transactions, transaction)
+	}
+	return page, nil
+}
+
+// greyTransactionStatus maps Grey's transaction statuses to settlement states
+func greyTransactionStatus(status string) ProviderTransactionStatus {
+	switch strings.ToLower(status) {
+	case "completed", "successful", "success":
+		return ProviderTransactionCompleted
+	case "failed", "reversed", "cancelled":
+		return ProviderTransactionFailed
+	default:
+		return ProviderTransactionPending
+	}
+}
+
+func (p *GreyProvider) headers() map[string]string {
+	return map[string]string{"Authorization": "Bearer " + p.apiKey}
+}
@@ -0,0 +1,234 @@
+package virtualaccount
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/utils"
+)
+
+var (
+	// ErrUnsupportedProvider is returned for a provider that isn't configured
+	ErrUnsupportedProvider = errors.New("virtual account provider not supported")
+
+	// ErrUnsupportedCurrency is returned when the provider can't issue accounts in the currency
+	ErrUnsupportedCurrency = errors.New("currency not supported by virtual account provider")
+
+	// ErrAccountExists is returned when the user already has an account with the provider in the currency
+	ErrAccountExists = errors.New("virtual account already exists for this provider and currency")
+
+	// ErrKYCRequired is returned when the user hasn't passed KYC
+	ErrKYCRequired = errors.New("approved KYC verification is required")
+
+	// ErrAccountNotFound is returned when a virtual account doesn't exist
+	ErrAccountNotFound = errors.New("virtual account not found")
+
+	// ErrAccountInactive is returned when deactivating an account that isn't active
+	ErrAccountInactive = errors.New("virtual account is not active")
+
+	// ErrProvisioningFailed is returned when the provider rejects or fails a request
+	ErrProvisioningFailed = errors.New("virtual account provider request failed")
+)
+
+// NotImplementedError is returned by providers for operations they don't support yet
+type NotImplementedError struct {
+	Provider  models.VirtualAccountProvider
+	Operation string
+}
+
+func (e *NotImplementedError) Error() string {
+	return fmt.Sprintf("%s is not implemented for %s virtual accounts", e.Operation, e.Provider)
+}
+
+// Provider issues receiving account details through a banking partner
+type Provider interface {
+	// Name returns the provider identifier stored on each account
+	Name() models.VirtualAccountProvider
+
+	// Currencies returns the currencies the provider can issue accounts in
+	Currencies() []models.VirtualAccountCurrency
+
+	// CreateAccount provisions an account for the holder in the currency
+	CreateAccount(ctx context.Context, holder AccountHolder, currency models.VirtualAccountCurrency) (*ProviderAccount, error)
+
+	// CloseAccount closes an account so it stops receiving funds
+	CloseAccount(ctx context.Context, providerAccountID string) error
+
+	// ListTransactions returns one page of the account's transactions after cursor, oldest
+	// first, with the cursor to resume from. An empty cursor starts from the beginning.
+	ListTransactions(ctx context.Context, providerAccountID, cursor string) (*TransactionPage, error)
+}
+
+var (
+	_ Provider = (*GreyProvider)(nil)
+	_ Provider = (*WiseProvider)(nil)
+	_ Provider = (*BarterProvider)(nil)
+)
+
+// DefaultProviders creates every provider whose credentials are set in cfg
+func DefaultProviders(cfg *config.Config) []Provider {
+	var providers []Provider
+
+	if cfg.Grey.APIKey != "" {
+		providers = append(providers, NewGreyProvider(cfg.Grey))
+	} else {
+		log.Printf("Grey virtual account provider not configured")
+	}
+
+	if cfg.Wise.APIToken != "" && cfg.Wise.ProfileID != "" {
+		providers = append(providers, NewWiseProvider(cfg.Wise))
+	} else {
+		log.Printf("Wise virtual account provider not configured")
+	}
+
+	if cfg.Barter.SecretKey != "" {
+		providers = append(providers, NewBarterProvider(cfg.Barter))
+	} else {
+		log.Printf("Barter virtual account provider not configured")
+	}
+
+	return providers
+}
+
+// AccountHolder is the verified customer an account is opened for
+type AccountHolder struct {
+	Reference string
+	FullName  string
+	Email     string
+	Country   string
+}
+
+// ProviderAccount holds the details a provider returns for a new account
+type ProviderAccount struct {
+	ProviderAccountID string
+	AccountNumber     string
+	RoutingNumber     string
+	IBAN              string
+	SwiftCode         string
+	BankName          string
+	BankAddress       string
+	AccountName       string
+
+	// Raw is the provider's response, kept on the account for support
+	Raw json.RawMessage
+}
+
+// ProviderTransactionStatus is a transaction's settlement state at the provider
+type ProviderTransactionStatus string
+
+const (
+	// ProviderTransactionPending is a transaction the provider hasn't settled yet
+	ProviderTransactionPending ProviderTransactionStatus = "pending"
+	// ProviderTransactionCompleted is a settled transaction
+	ProviderTransactionCompleted ProviderTransactionStatus = "completed"
+	// ProviderTransactionFailed is a transaction the provider failed or reversed
+	ProviderTransactionFailed ProviderTransactionStatus = "failed"
+)
+
+// ProviderTransaction is a transaction in a provider's account history
+type ProviderTransaction struct {
+	ID                  string
+	Type                models.VirtualAccountTransactionType
+	Status              ProviderTransactionStatus
+	Amount              float64
+	Fee                 float64
+	Currency            string
+	Reference           string
+	SenderName          string
+	SenderBank          string
+	SenderAccountNumber string
+	CreatedAt           time.Time
+}
+
+// TransactionPage is a page of a provider's account history
+type TransactionPage struct {
+	Transactions []ProviderTransaction
+
+	// NextCursor resumes the history after this page; it is empty if the provider has
+	// nothing to resume from yet
+	NextCursor string
+
+	// HasMore is set when further pages are already available
+	HasMore bool
+}
+
+// supportsCurrency reports whether the provider can issue accounts in currency
+func supportsCurrency(provider Provider, currency models.VirtualAccountCurrency) bool {
+	for _, supported := range provider.Currencies() {
+		if supported == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// httpClient is shared by the provider API clients. Requests are bounded by their context.
+var httpClient = &http.Client{}
+
+// doJSON sends body as JSON with the given headers and decodes a 2xx response into out.
+// Other statuses are returned as ErrProvisioningFailed with the provider's response body.
+// The request is bounded by the provider timeout as well as any deadline on ctx.
+func doJSON(ctx context.Context, method, url string, headers map[string]string, body, out interface{}) (json.RawMessage, error) {
+	ctx, cancel := utils.ProviderContext(ctx)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProvisioningFailed, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: status %d: %s", ErrProvisioningFailed, resp.StatusCode, truncate(respBody, 500))
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return nil, fmt.Errorf("error parsing response: %w", err)
+		}
+	}
+
+	return respBody, nil
+}
+
+func truncate(body []byte, max int) string {
+	if len(body) > max {
+		return string(body[:max]) + "..."
+	}
+	return string(body)
+}
@@ -0,0 +1,161 @@
+package virtualaccount
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/models"
+)
+
+func TestGreyProviderCreateAccount(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/accounts" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer grey-key" {
+			t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		var body greyAccountRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Currency != "GBP" || body.CustomerReference != "ref-1" || body.CustomerName != "Ama Mensah" {
+			t.Errorf("unexpected body %+v", body)
+		}
+		w.Write([]byte(`{"data":{"id":"acc_1","account_number":"12345678","sort_code":"04-00-04","bank_name":"Clear Junction","account_name":"Ama Mensah"}}`))
+	}))
+	defer server.Close()
+
+	provider := NewGreyProvider(config.GreyConfig{APIKey: "grey-key", BaseURL: server.URL})
+	account, err := provider.CreateAccount(context.Background(), AccountHolder{
+		Reference: "ref-1",
+		FullName:  "Ama Mensah",
+		Email:     "ama@example.com",
+	}, models.VirtualAccountCurrencyGBP)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if account.ProviderAccountID != "acc_1" || account.AccountNumber != "12345678" || account.RoutingNumber != "04-00-04" {
+		t.Errorf("unexpected account %+v", account)
+	}
+	if len(account.Raw) == 0 {
+		t.Error("expected the raw response to be kept")
+	}
+}
+
+func TestGreyProviderRejectedRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message":"customer not eligible"}`))
+	}))
+	defer server.Close()
+
+	provider := NewGreyProvider(config.GreyConfig{APIKey: "grey-key", BaseURL: server.URL})
+	_, err := provider.CreateAccount(context.Background(), AccountHolder{Reference: "ref-1"}, models.VirtualAccountCurrencyUSD)
+	if !errors.Is(err, ErrProvisioningFailed) {
+		t.Errorf("expected ErrProvisioningFailed, got %v", err)
+	}
+}
+
+func TestWiseProviderOpensBalanceAndReadsDetails(t *testing.T) {
+	var opened bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v4/profiles/42/balances":
+			w.Write([]byte(`[{"id":1,"currency":"USD","type":"STANDARD"}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v4/profiles/42/balances":
+			if r.Header.Get("X-idempotence-uuid") == "" {
+				t.Error("expected an idempotency key")
+			}
+			opened = true
+			w.Write([]byte(`{"id":7,"currency":"EUR","type":"STANDARD"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/profiles/42/account-details":
+			w.Write([]byte(`[
+				{"id":3,"status":"ACTIVE","currency":{"code":"USD"},"receiveOptions":[]},
+				{"id":4,"status":"ACTIVE","currency":{"code":"EUR"},"receiveOptions":[{"type":"LOCAL","details":[
+					{"type":"ACCOUNT_HOLDER","body":"RevasPay Ltd"},
+					{"type":"IBAN","body":"BE12 3456 7890 1234"},
+					{"type":"SWIFT_CODE","body":"TRWIBEB1XXX"},
+					{"type":"BANK_NAME_AND_ADDRESS","body":"Wise, Rue du Trone 100, Brussels"}
+				]}]}
+			]`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewWiseProvider(config.WiseConfig{APIToken: "wise-token", ProfileID: "42", BaseURL: server.URL})
+	account, err := provider.CreateAccount(context.Background(), AccountHolder{Reference: "ref-1"}, models.VirtualAccountCurrencyEUR)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !opened {
+		t.Error("expected a EUR balance to be opened")
+	}
+	if account.ProviderAccountID != "7" || account.IBAN != "BE12 3456 7890 1234" || account.SwiftCode != "TRWIBEB1XXX" || account.AccountName != "RevasPay Ltd" {
+		t.Errorf("unexpected account %+v", account)
+	}
+}
+
+func TestSupportsCurrency(t *testing.T) {
+	barter := NewBarterProvider(config.BarterConfig{SecretKey: "key"})
+	if !supportsCurrency(barter, models.VirtualAccountCurrencyUSD) {
+		t.Error("expected Barter to support USD")
+	}
+	if supportsCurrency(barter, models.VirtualAccountCurrencyEUR) {
+		t.Error("expected Barter not to support EUR")
+	}
+}
+
+func TestGreyProviderListTransactions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/accounts/acc_1/transactions" || r.URL.Query().Get("cursor") != "c1" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"data":[
+			{"id":"tx_1","type":"credit","status":"completed","amount":250.5,"currency":"usd","reference":"INV-7","sender":{"name":"Acme Inc","bank_name":"Chase"}},
+			{"id":"tx_2","type":"debit","status":"reversed","amount":10,"currency":"USD"},
+			{"id":"tx_3","type":"credit","status":"processing","amount":5,"currency":"USD"}
+		],"meta":{"next_cursor":"c2","has_more":true}}`))
+	}))
+	defer server.Close()
+
+	provider := NewGreyProvider(config.GreyConfig{APIKey: "grey-key", BaseURL: server.URL})
+	page, err := provider.ListTransactions(context.Background(), "acc_1", "c1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.NextCursor != "c2" || !page.HasMore || len(page.Transactions) != 3 {
+		t.Fatalf("unexpected page %+v", page)
+	}
+
+	credit, debit, pending := page.Transactions[0], page.Transactions[1], page.Transactions[2]
+	if credit.Type != models.VirtualAccountTransactionInbound || credit.Status != ProviderTransactionCompleted ||
+		credit.Currency != "USD" || credit.SenderName != "Acme Inc" {
+		t.Errorf("unexpected credit %+v", credit)
+	}
+	if debit.Type != models.VirtualAccountTransactionOutbound || debit.Status != ProviderTransactionFailed {
+		t.Errorf("unexpected debit %+v", debit)
+	}
+	if pending.Status != ProviderTransactionPending {
+		t.Errorf("expected processing to map to pending, got %s", pending.Status)
+	}
+}
+
+func TestUnimplementedProvidersReturnTypedError(t *testing.T) {
+	providers := []Provider{
+		NewWiseProvider(config.WiseConfig{APIToken: "token", ProfileID: "42"}),
+		NewBarterProvider(config.BarterConfig{SecretKey: "key"}),
+	}
+	for _, provider := range providers {
+		_, err := provider.ListTransactions(context.Background(), "acc", "")
+		var notImplemented *NotImplementedError
+		if !errors.As(err, &notImplemented) || notImplemented.Provider != provider.Name() {
+			t.Errorf("expected NotImplementedError from %s, got %v", provider.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package virtualaccount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReconciliationResult summarises the reconciliation of one account
+type ReconciliationResult struct {
+	// Fetched is the number of provider transactions examined
+	Fetched int
+
+	// Created holds the inbound transactions recorded for deposits we had missed.
+	// They are pending and still need to be processed to credit the user.
+	Created []uuid.UUID
+
+	// Flagged is the number of transactions newly flagged for review
+	Flagged int
+}
+
+// ActiveAccounts returns every active virtual account
+func (s *VirtualAccountService) ActiveAccounts() ([]models.VirtualAccount, error) {
+	var accounts []models.VirtualAccount
+	if err := s.db.Where("status = ?", models.VirtualAccountStatusActive).Find(&accounts).Error; err != nil {
+		return nil, fmt.Errorf("error getting active virtual accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// Reconcile matches the provider's history for the account against our transactions,
+// starting from the account's stored cursor. Deposits we never recorded are created as
+// pending inbound transactions and transactions that disagree with the provider are
+// flagged for review. The cursor only moves past pages whose transactions have all
+// settled, so pending deposits are fetched again on the next run.
+func (s *VirtualAccountService) Reconcile(ctx context.Context, account *models.VirtualAccount) (*ReconciliationResult, error) {
+	provider, err := s.Provider(account.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReconciliationResult{}
+	cursor := account.ReconciliationCursor
+	for {
+		page, err := provider.ListTransactions(ctx, account.ProviderAccountID, cursor)
+		if err != nil {
+			return result, err
+		}
+		result.Fetched += len(page.Transactions)
+
+		settled := true
+		for _, tx := range page.Transactions {
+			if tx.Status == ProviderTransactionPending {
+				settled = false
+				continue
+			}
+			if err := s.reconcileTransaction(account, tx, result); err != nil {
+				return result, err
+			}
+		}
+
+		if !settled || page.NextCursor == "" || page.NextCursor == cursor {
+			break
+		}
+		cursor = page.NextCursor
+		if err := s.db.Model(account).Update("reconciliation_cursor", cursor).Error; err != nil {
+			return result, fmt.Errorf("error saving reconciliation cursor: %w", err)
+		}
+		if !page.HasMore {
+			break
+		}
+	}
+
+	if err := s.db.Model(account).Update("last_synced_at", time.Now()).Error; err != nil {
+		return result, fmt.Errorf("error updating virtual account sync time: %w", err)
+	}
+	return result, nil
+}
+
+// reconcileTransaction records a settled provider transaction we're missing or flags the
+// matching transaction if it disagrees with the provider
+func (s *VirtualAccountService) reconcileTransaction(account *models.VirtualAccount, tx ProviderTransaction, result *ReconciliationResult) error {
+	var existing models.VirtualAccountTransaction
+	err := s.db.Where("virtual_account_id = ? AND transaction_id = ?", account.ID, tx.ID).First(&existing).Error
+	if err == nil {
+		reasons := compareTransaction(&existing, tx)
+		if len(reasons) == 0 {
+			return nil
+		}
+		reason := strings.Join(reasons, "; ")
+		if existing.NeedsReview && existing.ReviewReason == reason {
+			return nil
+		}
+		if err := s.db.Model(&existing).Updates(map[string]interface{}{
+			"needs_review":  true,
+			"review_reason": reason,
+		}).Error; err != nil {
+			return fmt.Errorf("error flagging virtual account transaction: %w", err)
+		}
+		result.Flagged++
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("error getting virtual account transaction: %w", err)
+	}
+
+	// A failed transaction we never saw moved no money
+	if tx.Status == ProviderTransactionFailed {
+		return nil
+	}
+
+	record := &models.VirtualAccountTransaction{
+		VirtualAccountID:    account.ID,
+		Amount:              tx.Amount,
+		Currency:            tx.Currency,
+		TransactionID:       tx.ID,
+		Reference:           tx.Reference,
+		Type:                tx.Type,
+		Status:              "pending",
+		Provider:            string(account.Provider),
+		SenderName:          tx.SenderName,
+		SenderBank:          tx.SenderBank,
+		SenderAccountNumber: tx.SenderAccountNumber,
+		Fee:                 tx.Fee,
+		Metadata: models.JSON{
+			"source":              "reconciliation",
+			"provider_created_at": tx.CreatedAt,
+		},
+	}
+	if tx.Type == models.VirtualAccountTransactionInbound {
+		record.RecipientUserID = account.UserID
+		record.RecipientName = account.AccountName
+		record.RecipientAccountNumber = account.AccountNumber
+		record.RecipientAccountID = account.ProviderAccountID
+		if !strings.EqualFold(tx.Currency, string(account.Currency)) {
+			record.NeedsReview = true
+			record.ReviewReason = fmt.Sprintf("deposit in %s received on a %s account", tx.Currency, account.Currency)
+		}
+	} else {
+		// Every debit should have been started by us, so an unknown one is left for review
+		// rather than processed
+		record.SenderUserID = account.UserID
+		record.NeedsReview = true
+		record.ReviewReason = "debit not initiated through RevasPay"
+	}
+
+	res := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if res.Error != nil {
+		return fmt.Errorf("error recording missed virtual account transaction: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil
+	}
+	if record.NeedsReview {
+		result.Flagged++
+		return nil
+	}
+	result.Created = append(result.Created, record.ID)
+	return nil
+}
+
+// compareTransaction returns the ways our record of a transaction disagrees with the provider's
+func compareTransaction(ours *models.VirtualAccountTransaction, theirs ProviderTransaction) []string {
+	var reasons []string
+	if ours.Type != theirs.Type {
+		reasons = append(reasons, fmt.Sprintf("type %s, provider reports %s", ours.Type, theirs.Type))
+	}
+	if math.Abs(ours.Amount-theirs.Amount) >= 0.005 {
+		reasons = append(reasons, fmt.Sprintf("amount %.2f, provider reports %.2f", ours.Amount, theirs.Amount))
+	}
+	if !strings.EqualFold(ours.Currency, theirs.Currency) {
+		reasons = append(reasons, fmt.Sprintf("currency %s, provider reports %s", ours.Currency, theirs.Currency))
+	}
+	switch {
+	case ours.Status == "completed" && theirs.Status == ProviderTransactionFailed:
+		reasons = append(reasons, "completed here but failed or reversed at the provider")
+	case ours.Status == "failed" && theirs.Status == ProviderTransactionCompleted:
+		reasons = append(reasons, "failed here but completed at the provider")
+	}
+	return reasons
+}
@@ -0,0 +1,41 @@
+package virtualaccount
+
+import (
+	"testing"
+
+	"github.com/revaspay/backend/internal/models"
+)
+
+func TestCompareTransaction(t *testing.T) {
+	ours := &models.VirtualAccountTransaction{
+		Type:     models.VirtualAccountTransactionInbound,
+		Amount:   100,
+		Currency: "USD",
+		Status:   "completed",
+	}
+
+	matching := ProviderTransaction{
+		Type:     models.VirtualAccountTransactionInbound,
+		Amount:   100.001,
+		Currency: "usd",
+		Status:   ProviderTransactionCompleted,
+	}
+	if reasons := compareTransaction(ours, matching); len(reasons) != 0 {
+		t.Errorf("expected a match, got %v", reasons)
+	}
+
+	tests := []struct {
+		name   string
+		theirs ProviderTransaction
+	}{
+		{"amount", ProviderTransaction{Type: ours.Type, Amount: 90, Currency: "USD", Status: ProviderTransactionCompleted}},
+		{"currency", ProviderTransaction{Type: ours.Type, Amount: 100, Currency: "EUR", Status: ProviderTransactionCompleted}},
+		{"type", ProviderTransaction{Type: models.VirtualAccountTransactionOutbound, Amount: 100, Currency: "USD", Status: ProviderTransactionCompleted}},
+		{"reversed", ProviderTransaction{Type: ours.Type, Amount: 100, Currency: "USD", Status: ProviderTransactionFailed}},
+	}
+	for _, tt := range tests {
+		if reasons := compareTransaction(ours, tt.theirs); len(reasons) != 1 {
+			t.Errorf("%s: expected one mismatch, got %v", tt.name, reasons)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package virtualaccount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/kyc"
+	"gorm.io/gorm"
+)
+
+// liveStatuses are the statuses that count towards the one-account-per-provider-and-currency limit
+var liveStatuses = []models.VirtualAccountStatus{models.VirtualAccountStatusPending, models.VirtualAccountStatusActive}
+
+// VirtualAccountService provisions receiving accounts with banking providers
+type VirtualAccountService struct {
+	db        *gorm.DB
+	providers map[models.VirtualAccountProvider]Provider
+}
+
+// NewVirtualAccountService creates a new virtual account service
+func NewVirtualAccountService(db *gorm.DB, providers ...Provider) *VirtualAccountService {
+	s := &VirtualAccountService{
+		db:        db,
+		providers: make(map[models.VirtualAccountProvider]Provider),
+	}
+	for _, provider := range providers {
+		s.providers[provider.Name()] = provider
+	}
+	return s
+}
+
+// Provider returns a configured provider by name
+func (s *VirtualAccountService) Provider(name models.VirtualAccountProvider) (Provider, error) {
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, ErrUnsupportedProvider
+	}
+	return provider, nil
+}
+
+// Create provisions an account for the user with the provider in the currency. The user must
+// have passed KYC and may hold one pending or active account per provider and currency.
+func (s *VirtualAccountService) Create(ctx context.Context, userID uuid.UUID, providerName models.VirtualAccountProvider, currency models.VirtualAccountCurrency) (*models.VirtualAccount, error) {
+	provider, err := s.Provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	currency = models.VirtualAccountCurrency(strings.ToUpper(string(currency)))
+	if !supportsCurrency(provider, currency) {
+		return nil, ErrUnsupportedCurrency
+	}
+
+	holder, err := s.accountHolder(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pending row reserves the slot; the partial unique index on live accounts stops a
+	// concurrent request from provisioning a second account
+	account := &models.VirtualAccount{
+		UserID:       userID,
+		Provider:     providerName,
+		Currency:     currency,
+		Status:       models.VirtualAccountStatusPending,
+		ProviderData: "{}",
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.VirtualAccount{}).
+			Where("user_id = ? AND provider = ? AND currency = ? AND status IN ?", userID, providerName, currency, liveStatuses).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("error checking existing virtual accounts: %w", err)
+		}
+		if count > 0 {
+			return ErrAccountExists
+		}
+		if err := tx.Create(account).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "duplicate key") {
+				return ErrAccountExists
+			}
+			return fmt.Errorf("error creating virtual account: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	holder.Reference = account.ID.String()
+	details, err := provider.CreateAccount(ctx, *holder, currency)
+	if err != nil {
+		s.db.Model(account).Update("status", models.VirtualAccountStatusFailed)
+		if errors.Is(err, ErrProvisioningFailed) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrProvisioningFailed, err)
+	}
+
+	providerData := "{}"
+	if len(details.Raw) > 0 {
+		providerData = string(details.Raw)
+	}
+	updates := map[string]interface{}{
+		"provider_account_id": details.ProviderAccountID,
+		"account_number":      details.AccountNumber,
+		"routing_number":      details.RoutingNumber,
+		"iban":                details.IBAN,
+		"swift_code":          details.SwiftCode,
+		"bank_name":           details.BankName,
+		"bank_address":        details.BankAddress,
+		"account_name":        details.AccountName,
+		"provider_data":       providerData,
+		"status":              models.VirtualAccountStatusActive,
+	}
+	if err := s.db.Model(account).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("error saving virtual account details: %w", err)
+	}
+
+	return s.Get(userID, account.ID)
+}
+
+// Get returns one of the user's virtual accounts
+func (s *VirtualAccountService) Get(userID, accountID uuid.UUID) (*models.VirtualAccount, error) {
+	var account models.VirtualAccount
+	if err := s.db.Where("id = ? AND user_id = ?", accountID, userID).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("error getting virtual account: %w", err)
+	}
+	return &account, nil
+}
+
+// List returns the user's virtual accounts, newest first. Failed provisioning attempts are left out.
+func (s *VirtualAccountService) List(userID uuid.UUID) ([]models.VirtualAccount, error) {
+	var accounts []models.VirtualAccount
+	if err := s.db.Where("user_id = ? AND status <> ?", userID, models.VirtualAccountStatusFailed).
+		Order("created_at DESC").
+		Find(&accounts).Error; err != nil {
+		return nil, fmt.Errorf("error listing virtual accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// Deactivate closes the account with its provider and marks it inactive, freeing the
+// provider and currency for a new account
+func (s *VirtualAccountService) Deactivate(ctx context.Context, userID, accountID uuid.UUID) (*models.VirtualAccount, error) {
+	account, err := s.Get(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status != models.VirtualAccountStatusActive {
+		return nil, ErrAccountInactive
+	}
+
+	provider, err := s.Provider(account.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if err := provider.CloseAccount(ctx, account.ProviderAccountID); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(account).Update("status", models.VirtualAccountStatusInactive).Error; err != nil {
+		return nil, fmt.Errorf("error deactivating virtual account: %w", err)
+	}
+	return account, nil
+}
+
+// accountHolder returns the user's verified identity, or ErrKYCRequired if they haven't passed KYC
+func (s *VirtualAccountService) accountHolder(userID uuid.UUID) (*AccountHolder, error) {
+	status, err := kyc.GetUserStatus(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if status.Status != models.KYCStatusApproved {
+		return nil, ErrKYCRequired
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	holder := &AccountHolder{
+		FullName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+		Email:    user.Email,
+	}
+	// Accounts are opened in the name on the verified ID document
+	if status.FullName != nil && *status.FullName != "" {
+		holder.FullName = *status.FullName
+	}
+	if status.IDDocCountry != nil {
+		holder.Country = *status.IDDocCountry
+	}
+	return holder, nil
+}
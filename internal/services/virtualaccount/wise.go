@@ -0,0 +1,161 @@
+package virtualaccount
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/config"
+	"github.com/revaspay/backend/internal/models"
+)
+
+// DefaultWiseBaseURL is Wise's production API
+const DefaultWiseBaseURL = "https://api.transferwise.com"
+
+// WiseProvider issues receiving details from the platform's Wise business profile. Wise
+// account details belong to a profile's currency balance rather than to a customer, so
+// every user receiving through Wise in a currency shares the same details and deposits
+// are matched by the reference the sender quotes.
+type WiseProvider struct {
+	apiToken  string
+	profileID string
+	baseURL   string
+}
+
+// NewWiseProvider creates a Wise provider
+func NewWiseProvider(cfg config.WiseConfig) *WiseProvider {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultWiseBaseURL
+	}
+	return &WiseProvider{apiToken: cfg.APIToken, profileID: cfg.ProfileID, baseURL: baseURL}
+}
+
+// wiseBalance is a profile's currency balance
+type wiseBalance struct {
+	ID       int64  `json:"id"`
+	Currency string `json:"currency"`
+	Type     string `json:"type"`
+}
+
+// wiseAccountDetails is one entry of GET /v1/profiles/{profileId}/account-details
+type wiseAccountDetails struct {
+	ID       int64  `json:"id"`
+	Status   string `json:"status"`
+	Currency struct {
+		Code string `json:"code"`
+	} `json:"currency"`
+	ReceiveOptions []struct {
+		Type    string `json:"type"`
+		Details []struct {
+			Type string `json:"type"`
+			Body string `json:"body"`
+		} `json:"details"`
+	} `json:"receiveOptions"`
+}
+
+// Name returns the provider identifier
+func (p *WiseProvider) Name() models.VirtualAccountProvider {
+	return models.VirtualAccountProviderWise
+}
+
+// Currencies returns the currencies Wise issues account details in
+func (p *WiseProvider) Currencies() []models.VirtualAccountCurrency {
+	return []models.VirtualAccountCurrency{
+		models.VirtualAccountCurrencyUSD,
+		models.VirtualAccountCurrencyEUR,
+		models.VirtualAccountCurrencyGBP,
+	}
+}
+
+// CreateAccount opens the profile's balance in the currency if it doesn't have one yet
+// and returns the balance's account details
+func (p *WiseProvider) CreateAccount(ctx context.Context, holder AccountHolder, currency models.VirtualAccountCurrency) (*ProviderAccount, error) {
+	balance, err := p.ensureBalance(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var details []wiseAccountDetails
+	raw, err := doJSON(ctx, http.MethodGet, fmt.Sprintf("%s/v1/profiles/%s/account-details", p.baseURL, p.profileID), p.headers(), nil, &details)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, detail := range details {
+		if detail.Currency.Code != string(currency) || detail.Status != "ACTIVE" {
+			continue
+		}
+
+		account := &ProviderAccount{
+			ProviderAccountID: strconv.FormatInt(balance.ID, 10),
+			Raw:               raw,
+		}
+		for _, option := range detail.ReceiveOptions {
+			for _, field := range option.Details {
+				switch field.Type {
+				case "ACCOUNT_HOLDER":
+					account.AccountName = field.Body
+				case "ACCOUNT_NUMBER":
+					account.AccountNumber = field.Body
+				case "ROUTING_NUMBER", "SORT_CODE":
+					account.RoutingNumber = field.Body
+				case "IBAN":
+					account.IBAN = field.Body
+				case "SWIFT_CODE", "BIC":
+					account.SwiftCode = field.Body
+				case "BANK_NAME":
+					account.BankName = field.Body
+				case "BANK_ADDRESS", "BANK_NAME_AND_ADDRESS":
+					account.BankAddress = field.Body
+				}
+			}
+		}
+		return account, nil
+	}
+
+	return nil, fmt.Errorf("%w: Wise profile has no active %s account details", ErrProvisioningFailed, currency)
+}
+
+// CloseAccount is a no-op: the balance and its details are shared by every user receiving
+// through Wise in the currency, so only the user's record is deactivated
+func (p *WiseProvider) CloseAccount(ctx context.Context, providerAccountID string) error {
+	return nil
+}
+
+// ensureBalance returns the profile's standard balance in the currency, opening it if needed
+func (p *WiseProvider) ensureBalance(ctx context.Context, currency models.VirtualAccountCurrency) (*wiseBalance, error) {
+	var balances []wiseBalance
+	if _, err := doJSON(ctx, http.MethodGet, fmt.Sprintf("%s/v4/profiles/%s/balances?types=STANDARD", p.baseURL, p.profileID), p.headers(), nil, &balances); err != nil {
+		return nil, err
+	}
+	for i := range balances {
+		if balances[i].Currency == string(currency) {
+			return &balances[i], nil
+		}
+	}
+
+	// Wise requires an idempotency key when opening balances
+	headers := p.headers()
+	headers["X-idempotence-uuid"] = uuid.New().String()
+
+	var balance wiseBalance
+	if _, err := doJSON(ctx, http.MethodPost, fmt.Sprintf("%s/v4/profiles/%s/balances", p.baseURL, p.profileID), headers, map[string]string{
+		"currency": string(currency),
+		"type":     "STANDARD",
+	}, &balance); err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+// ListTransactions is not implemented yet for Wise, so Wise accounts aren't reconciled
+func (p *WiseProvider) ListTransactions(ctx context.Context, providerAccountID, cursor string) (*TransactionPage, error) {
+	return nil, &NotImplementedError{Provider: p.Name(), Operation: "transaction history"}
+}
+
+func (p *WiseProvider) headers() map[string]string {
+	return map[string]string{"Authorization": "Bearer " + p.apiToken}
+}
@@ -0,0 +1,50 @@
+package wallet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recordBalanceChange adds a movement to the wallet's pending balance change when its owner
+// has an active webhook endpoint that opted in to balance events. It's written in the
+// movement's transaction, so a movement that is rolled back is never reported. Movements
+// made before the change is sent are coalesced into it.
+func recordBalanceChange(tx *gorm.DB, wallet *models.Wallet, amount float64, reference string) error {
+	var subscribed int64
+	if err := tx.Model(&models.WebhookEndpoint{}).
+		Where("user_id = ? AND active = ? AND balance_events = ?", wallet.UserID, true, true).
+		Count(&subscribed).Error; err != nil {
+		return fmt.Errorf("error checking balance webhook subscriptions: %w", err)
+	}
+	if subscribed == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	change := models.WalletBalanceChange{
+		ID:        uuid.New(),
+		WalletID:  wallet.ID,
+		Delta:     amount,
+		Reference: reference,
+		Changes:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "wallet_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"delta":      gorm.Expr("wallet_balance_changes.delta + ?", amount),
+			"reference":  reference,
+			"changes":    gorm.Expr("wallet_balance_changes.changes + 1"),
+			"updated_at": now,
+		}),
+	}).Create(&change).Error; err != nil {
+		return fmt.Errorf("error recording balance change: %w", err)
+	}
+	return nil
+}
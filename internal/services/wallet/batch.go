@@ -0,0 +1,178 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// creditBatchSize bounds how many pending credits one wallet applies in a single batch
+const creditBatchSize = 500
+
+// SetCreditBatchWindow turns on batched credits when window is positive. CreditOnceBatched
+// then saves credits as pending, and RunCreditFlusher applies each wallet's pending credits
+// together every window. Call it at startup.
+func (s *WalletService) SetCreditBatchWindow(window time.Duration) {
+	s.creditBatchWindow = window
+}
+
+// CreditOnceBatched credits a wallet once per txType and reference, like CreditOnce. With
+// batching on, the credit is saved as pending without locking the wallet and applied by the
+// next flush, in one balance update with every other credit pending for the wallet, so busy
+// wallets take their row lock once per batch rather than once per credit. With batching off
+// it's CreditOnce.
+//
+// The pending credit is the record persisted first, and survives a crash to be applied by the
+// next flush. The credit's transaction and ledger entry aren't written until then, in the same
+// database transaction as the balance update, so the ledger always sums to the balance and
+// reconciliation never sees an entry the balance doesn't include yet.
+func (s *WalletService) CreditOnceBatched(walletID uuid.UUID, amount float64, txType string, category models.LedgerCategory, reference string, description string, metadata map[string]interface{}) error {
+	if s.creditBatchWindow <= 0 {
+		_, err := s.CreditOnce(walletID, amount, txType, category, reference, description, metadata)
+		return err
+	}
+
+	var existing int64
+	if err := s.db.Model(&models.Transaction{}).
+		Where("wallet_id = ? AND type = ? AND reference = ?", walletID, txType, reference).
+		Count(&existing).Error; err != nil {
+		return fmt.Errorf("error checking for existing credit: %w", err)
+	}
+	if existing > 0 {
+		return ErrDuplicateCredit
+	}
+
+	credit := models.PendingWalletCredit{
+		WalletID:    walletID,
+		Type:        txType,
+		Category:    category,
+		Amount:      amount,
+		Reference:   reference,
+		Description: description,
+		MetaData:    metadata,
+	}
+	if err := s.db.Create(&credit).Error; err != nil {
+		if isDuplicateKey(err) {
+			return ErrDuplicateCredit
+		}
+		return fmt.Errorf("error saving pending credit: %w", err)
+	}
+	return nil
+}
+
+// RunCreditFlusher applies pending credits every batch window until ctx is done. It returns
+// straight away when batching is off. Credits still pending when it stops are applied by the
+// next flush, including one after a restart.
+func (s *WalletService) RunCreditFlusher(ctx context.Context) {
+	if s.creditBatchWindow <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.creditBatchWindow)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.FlushCredits(ctx); err != nil {
+				log.Printf("Failed to flush pending wallet credits: %v", err)
+			}
+		}
+	}
+}
+
+// FlushCredits applies the pending credits of every wallet that has any and returns how many
+// were applied. A wallet that fails is logged and left for the next flush.
+func (s *WalletService) FlushCredits(ctx context.Context) (int, error) {
+	var walletIDs []uuid.UUID
+	if err := s.db.WithContext(ctx).Model(&models.PendingWalletCredit{}).
+		Distinct("wallet_id").
+		Pluck("wallet_id", &walletIDs).Error; err != nil {
+		return 0, fmt.Errorf("error finding wallets with pending credits: %w", err)
+	}
+
+	applied := 0
+	for _, walletID := range walletIDs {
+		count, err := s.flushWallet(ctx, walletID)
+		if err != nil {
+			log.Printf("Failed to apply pending credits to wallet %s: %v", walletID, err)
+			continue
+		}
+		applied += count
+	}
+	return applied, nil
+}
+
+// flushWallet applies a wallet's pending credits in the order they were saved. Each gets its
+// own transaction and ledger entry, but the wallet is locked and saved once for the batch.
+func (s *WalletService) flushWallet(ctx context.Context, walletID uuid.UUID) (int, error) {
+	applied := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		wallet, err := s.lockWallet(tx, walletID)
+		if err != nil {
+			return err
+		}
+
+		// Read under the wallet's lock, so concurrent flushes can't apply the same credits
+		var credits []models.PendingWalletCredit
+		if err := tx.Where("wallet_id = ?", walletID).
+			Order("created_at, id").
+			Limit(creditBatchSize).
+			Find(&credits).Error; err != nil {
+			return fmt.Errorf("error finding pending credits: %w", err)
+		}
+		if len(credits) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, 0, len(credits))
+		for _, credit := range credits {
+			ids = append(ids, credit.ID)
+
+			// A credit applied directly by CreditOnce, or by an earlier batch while this one was
+			// being saved, isn't applied twice
+			var existing int64
+			if err := tx.Model(&models.Transaction{}).
+				Where("wallet_id = ? AND type = ? AND reference = ?", walletID, credit.Type, credit.Reference).
+				Count(&existing).Error; err != nil {
+				return fmt.Errorf("error checking for existing credit: %w", err)
+			}
+			if existing > 0 {
+				log.Printf("Pending credit %s to wallet %s was already applied, skipping", credit.Reference, walletID)
+				continue
+			}
+
+			if _, err := s.recordMovement(tx, wallet, credit.Amount, credit.Type, credit.Category, credit.Reference, credit.Description, credit.MetaData); err != nil {
+				return err
+			}
+			applied++
+		}
+
+		if err := tx.Save(wallet).Error; err != nil {
+			return fmt.Errorf("error updating wallet balance: %w", err)
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&models.PendingWalletCredit{}).Error; err != nil {
+			return fmt.Errorf("error removing applied credits: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+// isDuplicateKey reports whether err is a unique constraint violation
+func isDuplicateKey(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey) ||
+		strings.Contains(err.Error(), "duplicate key") ||
+		strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
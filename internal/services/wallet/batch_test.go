@@ -0,0 +1,81 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreditOnceBatched(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+	s := NewWalletService(db)
+	s.SetCreditBatchWindow(time.Second)
+	ctx := context.Background()
+
+	w, err := s.GetOrCreateWallet(user.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+
+	credit := func(amount float64, reference string) error {
+		return s.CreditOnceBatched(w.ID, amount, "payment", models.LedgerCategorySales, reference, "Payment", nil)
+	}
+	require.NoError(t, credit(10, "PAY-1"))
+	require.NoError(t, credit(20.5, "PAY-2"))
+	assert.ErrorIs(t, credit(10, "PAY-1"), ErrDuplicateCredit)
+
+	// Credited directly while the batched credit was pending, so the batch skips it
+	require.NoError(t, credit(5, "PAY-3"))
+	_, err = s.CreditOnce(w.ID, 5, "payment", models.LedgerCategorySales, "PAY-3", "Payment", nil)
+	require.NoError(t, err)
+
+	// Nothing is applied until the flush
+	pending, err := s.GetWallet(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, pending.Balance)
+
+	applied, err := s.FlushCredits(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+
+	flushed, err := s.GetWallet(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 35.5, flushed.Balance)
+	assert.Equal(t, 35.5, flushed.Available)
+
+	var entries []models.WalletLedgerEntry
+	require.NoError(t, db.Where("wallet_id = ?", w.ID).Order("balance_after").Find(&entries).Error)
+	require.Len(t, entries, 3)
+	// Each batched credit gets its own entry with the running balance
+	assert.Equal(t, 5.0, entries[0].BalanceAfter)
+	assert.Contains(t, []float64{15, 25.5}, entries[1].BalanceAfter)
+	assert.Equal(t, 35.5, entries[2].BalanceAfter)
+
+	var remaining int64
+	require.NoError(t, db.Model(&models.PendingWalletCredit{}).Count(&remaining).Error)
+	assert.Zero(t, remaining)
+
+	// Applied credits still count as duplicates
+	assert.ErrorIs(t, credit(10, "PAY-1"), ErrDuplicateCredit)
+	applied, err = s.FlushCredits(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, applied)
+}
+
+func TestCreditOnceBatchedOff(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+	s := NewWalletService(db)
+
+	w, err := s.GetOrCreateWallet(user.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	require.NoError(t, s.CreditOnceBatched(w.ID, 10, "payment", models.LedgerCategorySales, "PAY-1", "Payment", nil))
+
+	credited, err := s.GetWallet(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, credited.Balance)
+}
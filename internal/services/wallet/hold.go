@@ -0,0 +1,203 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/currency"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrHoldNotFound is returned when a wallet hold does not exist
+	ErrHoldNotFound = errors.New("wallet hold not found")
+
+	// ErrHoldNotActive is returned when a hold has already been captured or released
+	ErrHoldNotActive = errors.New("wallet hold is no longer active")
+)
+
+// WalletBalance breaks a wallet's balance into funds that can be spent and funds reserved by holds
+type WalletBalance struct {
+	WalletID    uuid.UUID       `json:"wallet_id"`
+	Currency    models.Currency `json:"currency"`
+	Balance     float64         `json:"balance"`
+	Available   float64         `json:"available"`
+	Held        float64         `json:"held"`
+	ActiveHolds int64           `json:"active_holds"`
+}
+
+// GetBalance returns a wallet's available and held balances
+func (s *WalletService) GetBalance(walletID uuid.UUID) (*WalletBalance, error) {
+	wallet, err := s.GetWallet(walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	var activeHolds int64
+	if err := s.db.Model(&models.WalletHold{}).
+		Where("wallet_id = ? AND status = ?", walletID, models.WalletHoldActive).
+		Count(&activeHolds).Error; err != nil {
+		return nil, fmt.Errorf("error counting wallet holds: %w", err)
+	}
+
+	return &WalletBalance{
+		WalletID:    wallet.ID,
+		Currency:    wallet.Currency,
+		Balance:     wallet.Balance,
+		Available:   wallet.Available,
+		Held:        wallet.Held,
+		ActiveHolds: activeHolds,
+	}, nil
+}
+
+// Hold reserves funds in a wallet without debiting them. The amount moves from
+// available to held, so it cannot be spent twice while the operation is pending.
+func (s *WalletService) Hold(walletID uuid.UUID, amount float64, reference string, description string) (*models.WalletHold, error) {
+	var hold *models.WalletHold
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		hold, err = s.HoldWithTx(tx, walletID, amount, reference, description)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// HoldWithTx reserves funds in a wallet using an existing transaction
+func (s *WalletService) HoldWithTx(tx *gorm.DB, walletID uuid.UUID, amount float64, reference string, description string) (*models.WalletHold, error) {
+	wallet, err := s.lockWallet(tx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	amount = currency.Round(wallet.Currency, amount)
+	if err := checkAvailable(wallet, amount); err != nil {
+		return nil, err
+	}
+
+	// Balance is unchanged, so no ledger entry is written until the hold is captured
+	wallet.Available -= amount
+	wallet.Held += amount
+	roundBalances(wallet)
+	if err := tx.Save(wallet).Error; err != nil {
+		return nil, fmt.Errorf("error updating wallet balance: %w", err)
+	}
+
+	hold := models.WalletHold{
+		WalletID:    wallet.ID,
+		Amount:      amount,
+		Currency:    wallet.Currency,
+		Status:      models.WalletHoldActive,
+		Reference:   reference,
+		Description: description,
+	}
+	if err := tx.Create(&hold).Error; err != nil {
+		return nil, fmt.Errorf("error creating wallet hold: %w", err)
+	}
+
+	return &hold, nil
+}
+
+// CaptureHold turns a hold into a final debit once the operation it backs has succeeded
+func (s *WalletService) CaptureHold(holdID uuid.UUID, txType string, category models.LedgerCategory, description string, metadata map[string]interface{}) (*models.Transaction, error) {
+	var transaction *models.Transaction
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = s.CaptureHoldWithTx(tx, holdID, txType, category, description, metadata)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// CaptureHoldWithTx captures a hold using an existing transaction
+func (s *WalletService) CaptureHoldWithTx(tx *gorm.DB, holdID uuid.UUID, txType string, category models.LedgerCategory, description string, metadata map[string]interface{}) (*models.Transaction, error) {
+	hold, err := s.lockActiveHold(tx, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.lockWallet(tx, hold.WalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Move the held funds back to available so the debit below draws them
+	// from there; the net effect is Balance and Held both drop by the amount
+	wallet.Held -= hold.Amount
+	wallet.Available += hold.Amount
+
+	transaction, err := s.applyMovement(tx, wallet, -hold.Amount, txType, category, hold.Reference, description, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	hold.Status = models.WalletHoldCaptured
+	hold.TransactionID = &transaction.ID
+	hold.SettledAt = &now
+	if err := tx.Save(hold).Error; err != nil {
+		return nil, fmt.Errorf("error updating wallet hold: %w", err)
+	}
+
+	return transaction, nil
+}
+
+// ReleaseHold returns held funds to the wallet's available balance when the operation is abandoned
+func (s *WalletService) ReleaseHold(holdID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.ReleaseHoldWithTx(tx, holdID)
+	})
+}
+
+// ReleaseHoldWithTx releases a hold using an existing transaction
+func (s *WalletService) ReleaseHoldWithTx(tx *gorm.DB, holdID uuid.UUID) error {
+	hold, err := s.lockActiveHold(tx, holdID)
+	if err != nil {
+		return err
+	}
+
+	wallet, err := s.lockWallet(tx, hold.WalletID)
+	if err != nil {
+		return err
+	}
+
+	wallet.Held -= hold.Amount
+	wallet.Available += hold.Amount
+	roundBalances(wallet)
+	if err := tx.Save(wallet).Error; err != nil {
+		return fmt.Errorf("error updating wallet balance: %w", err)
+	}
+
+	now := time.Now()
+	hold.Status = models.WalletHoldReleased
+	hold.SettledAt = &now
+	if err := tx.Save(hold).Error; err != nil {
+		return fmt.Errorf("error updating wallet hold: %w", err)
+	}
+
+	return nil
+}
+
+// lockActiveHold loads a hold with a row lock and checks it has not already been settled
+func (s *WalletService) lockActiveHold(tx *gorm.DB, holdID uuid.UUID) (*models.WalletHold, error) {
+	var hold models.WalletHold
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&hold, "id = ?", holdID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, fmt.Errorf("error finding wallet hold: %w", err)
+	}
+	if hold.Status != models.WalletHoldActive {
+		return nil, ErrHoldNotActive
+	}
+	return &hold, nil
+}
@@ -0,0 +1,60 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Debits and holds can only spend the available balance, so held funds can't be spent twice
+// and a wallet never goes below zero
+func TestDebitsAndHoldsSpendOnlyAvailableFunds(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+	s := NewWalletService(db)
+
+	w, err := s.GetOrCreateWallet(user.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	_, err = s.Credit(w.ID, 100, "deposit", models.LedgerCategoryDeposit, "DEP-1", "Deposit", nil)
+	require.NoError(t, err)
+
+	hold, err := s.Hold(w.ID, 30, "WD-1", "Withdrawal pending")
+	require.NoError(t, err)
+	balance, err := s.GetBalance(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, WalletBalance{WalletID: w.ID, Currency: models.CurrencyGHS, Balance: 100, Available: 70, Held: 30, ActiveHolds: 1}, *balance)
+
+	// The held 30 can't be debited or held again
+	_, err = s.Debit(w.ID, 80, "withdrawal", models.LedgerCategoryPayout, "WD-2", "Withdrawal", nil)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	_, err = s.Hold(w.ID, 71, "WD-3", "Withdrawal pending")
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	_, err = s.Debit(w.ID, 0, "withdrawal", models.LedgerCategoryPayout, "WD-4", "Withdrawal", nil)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	_, err = s.Debit(w.ID, 70, "withdrawal", models.LedgerCategoryPayout, "WD-5", "Withdrawal", nil)
+	require.NoError(t, err)
+
+	// Capturing the hold takes the reserved funds; it can only be settled once
+	_, err = s.CaptureHold(hold.ID, "withdrawal", models.LedgerCategoryPayout, "Withdrawal completed", nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.ReleaseHold(hold.ID), ErrHoldNotActive)
+
+	balance, err = s.GetBalance(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, WalletBalance{WalletID: w.ID, Currency: models.CurrencyGHS}, *balance)
+
+	// Releasing a hold returns its funds to available
+	_, err = s.Credit(w.ID, 50, "deposit", models.LedgerCategoryDeposit, "DEP-2", "Deposit", nil)
+	require.NoError(t, err)
+	released, err := s.Hold(w.ID, 50, "WD-6", "Withdrawal pending")
+	require.NoError(t, err)
+	require.NoError(t, s.ReleaseHold(released.ID))
+	balance, err = s.GetBalance(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, balance.Available)
+	assert.Zero(t, balance.Held)
+}
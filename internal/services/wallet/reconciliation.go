@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reconciliationTolerance absorbs rounding at the decimal(20,8) column precision
+const reconciliationTolerance = 0.000000005
+
+// reconciliationBatchSize is how many wallets are loaded at a time when reconciling all wallets
+const reconciliationBatchSize = 200
+
+// ReconciliationResult compares a wallet's stored balance with the sum of its ledger entries
+type ReconciliationResult struct {
+	WalletID      uuid.UUID       `json:"wallet_id"`
+	UserID        uuid.UUID       `json:"user_id"`
+	Currency      models.Currency `json:"currency"`
+	StoredBalance float64         `json:"stored_balance"`
+	LedgerBalance float64         `json:"ledger_balance"`
+	Difference    float64         `json:"difference"`
+	EntryCount    int64           `json:"entry_count"`
+	Balanced      bool            `json:"balanced"`
+}
+
+// Reconcile recomputes a wallet's balance from its ledger entries and reports any discrepancy
+func (s *WalletService) Reconcile(walletID uuid.UUID) (*ReconciliationResult, error) {
+	return s.reconcileWallet(walletID, false)
+}
+
+// ReconcileAll reconciles every wallet and returns the ones whose balance does not match
+// the ledger. Wallets that predate the ledger get an opening-balance entry the first time
+// they are seen, so only drift after that point is reported.
+func (s *WalletService) ReconcileAll() ([]ReconciliationResult, int, error) {
+	var mismatches []ReconciliationResult
+	scanned := 0
+
+	var wallets []models.Wallet
+	result := s.db.Model(&models.Wallet{}).Select("id").FindInBatches(&wallets, reconciliationBatchSize, func(_ *gorm.DB, _ int) error {
+		for _, w := range wallets {
+			res, err := s.reconcileWallet(w.ID, true)
+			if err != nil {
+				return err
+			}
+			scanned++
+			if !res.Balanced {
+				mismatches = append(mismatches, *res)
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return nil, scanned, fmt.Errorf("error reconciling wallets: %w", result.Error)
+	}
+
+	return mismatches, scanned, nil
+}
+
+// reconcileWallet locks a wallet so its balance and ledger are read consistently, optionally
+// seeds an opening-balance entry for a wallet with no ledger history, and compares the two
+func (s *WalletService) reconcileWallet(walletID uuid.UUID, seedOpening bool) (*ReconciliationResult, error) {
+	var result *ReconciliationResult
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var wallet models.Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&wallet, "id = ?", walletID).Error; err != nil {
+			return fmt.Errorf("error finding wallet: %w", err)
+		}
+
+		var totals struct {
+			Sum   float64
+			Count int64
+		}
+		if err := tx.Model(&models.WalletLedgerEntry{}).
+			Select("COALESCE(SUM(amount), 0) AS sum, COUNT(*) AS count").
+			Where("wallet_id = ?", wallet.ID).
+			Scan(&totals).Error; err != nil {
+			return fmt.Errorf("error summing wallet ledger: %w", err)
+		}
+
+		if seedOpening && totals.Count == 0 && wallet.Balance != 0 {
+			entry := models.WalletLedgerEntry{
+				WalletID:     wallet.ID,
+				EntryType:    models.LedgerEntryOpeningBalance,
+				Category:     models.LedgerCategoryAdjustment,
+				Amount:       wallet.Balance,
+				BalanceAfter: wallet.Balance,
+				Currency:     wallet.Currency,
+				Reference:    "opening_balance",
+			}
+			if err := tx.Create(&entry).Error; err != nil {
+				return fmt.Errorf("error creating opening balance entry: %w", err)
+			}
+			totals.Sum = wallet.Balance
+			totals.Count = 1
+		}
+
+		difference := wallet.Balance - totals.Sum
+		result = &ReconciliationResult{
+			WalletID:      wallet.ID,
+			UserID:        wallet.UserID,
+			Currency:      wallet.Currency,
+			StoredBalance: wallet.Balance,
+			LedgerBalance: totals.Sum,
+			Difference:    difference,
+			EntryCount:    totals.Count,
+			Balanced:      math.Abs(difference) < reconciliationTolerance,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
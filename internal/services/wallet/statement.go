@@ -0,0 +1,203 @@
+package wallet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/revaspay/backend/internal/models"
+)
+
+// statementDateFormat is how dates are written on statements
+const statementDateFormat = "2006-01-02 15:04:05"
+
+// statementColumns are the columns of every statement
+var statementColumns = []string{"Date", "Type", "Description", "Reference", "Amount", "Currency", "Balance"}
+
+// StatementFilter selects the ledger entries on a statement. A nil WalletID covers all of
+// the user's wallets; zero From or To leave that end of the period open.
+type StatementFilter struct {
+	UserID   uuid.UUID
+	WalletID *uuid.UUID
+	From     time.Time
+	To       time.Time
+}
+
+// StatementLine is one ledger entry on a statement. Balance is the wallet's running
+// balance after the entry.
+type StatementLine struct {
+	Date        time.Time       `gorm:"column:date"`
+	Type        string          `gorm:"column:type"`
+	Description string          `gorm:"column:description"`
+	Reference   string          `gorm:"column:reference"`
+	Amount      float64         `gorm:"column:amount"`
+	Currency    models.Currency `gorm:"column:currency"`
+	Balance     float64         `gorm:"column:balance"`
+}
+
+// StreamStatement calls fn with each of the user's ledger entries matching the filter,
+// grouped by currency and oldest first, reading them one row at a time
+func (s *WalletService) StreamStatement(filter StatementFilter, fn func(StatementLine) error) error {
+	query := s.db.Table("wallet_ledger AS l").
+		Select(`l.created_at AS date,
+			COALESCE(t.type, l.entry_type) AS type,
+			COALESCE(t.description, '') AS description,
+			l.reference AS reference,
+			l.amount AS amount,
+			l.currency AS currency,
+			l.balance_after AS balance`).
+		Joins("JOIN wallets w ON w.id = l.wallet_id").
+		Joins("LEFT JOIN transactions t ON t.id = l.transaction_id").
+		Where("w.user_id = ?", filter.UserID)
+	if filter.WalletID != nil {
+		query = query.Where("l.wallet_id = ?", *filter.WalletID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("l.created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("l.created_at < ?", filter.To)
+	}
+
+	rows, err := query.Order("l.currency, l.created_at, l.id").Rows()
+	if err != nil {
+		return fmt.Errorf("error querying statement: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line StatementLine
+		if err := s.db.ScanRows(rows, &line); err != nil {
+			return fmt.Errorf("error reading statement line: %w", err)
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// WriteStatementCSV streams the statement to w as CSV
+func (s *WalletService) WriteStatementCSV(w io.Writer, filter StatementFilter) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(statementColumns); err != nil {
+		return err
+	}
+
+	err := s.StreamStatement(filter, func(line StatementLine) error {
+		return writer.Write([]string{
+			line.Date.UTC().Format(statementDateFormat),
+			line.Type,
+			line.Description,
+			line.Reference,
+			strconv.FormatFloat(line.Amount, 'f', 2, 64),
+			string(line.Currency),
+			strconv.FormatFloat(line.Balance, 'f', 2, 64),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteStatementPDF renders the statement as a PDF headed with the merchant's name and the
+// statement period. PDFs are built in memory, so callers should bound the period.
+func (s *WalletService) WriteStatementPDF(w io.Writer, filter StatementFilter, merchantName string) error {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	widths := []float64{40, 30, 80, 45, 27, 18, 37}
+
+	header := func() {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(235, 235, 235)
+		for i, column := range statementColumns {
+			align := "L"
+			if column == "Amount" || column == "Balance" {
+				align = "R"
+			}
+			pdf.CellFormat(widths[i], 7, column, "1", 0, align, true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 8)
+	}
+
+	pdf.SetHeaderFunc(func() {
+		if pdf.PageNo() > 1 {
+			header()
+		}
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 8, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, "Account Statement", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.CellFormat(0, 7, tr(merchantName), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 7, "Period: "+statementPeriod(filter), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 7, "Generated: "+time.Now().UTC().Format(statementDateFormat)+" UTC", "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+	header()
+
+	lines := 0
+	err := s.StreamStatement(filter, func(line StatementLine) error {
+		lines++
+		cells := []string{
+			line.Date.UTC().Format(statementDateFormat),
+			line.Type,
+			truncateCell(line.Description, 55),
+			truncateCell(line.Reference, 30),
+			strconv.FormatFloat(line.Amount, 'f', 2, 64),
+			string(line.Currency),
+			strconv.FormatFloat(line.Balance, 'f', 2, 64),
+		}
+		for i, cell := range cells {
+			align := "L"
+			if i == 4 || i == 6 {
+				align = "R"
+			}
+			pdf.CellFormat(widths[i], 6, tr(cell), "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if lines == 0 {
+		pdf.CellFormat(0, 8, "No transactions in this period", "", 1, "L", false, 0, "")
+	}
+
+	return pdf.Output(w)
+}
+
+// statementPeriod describes the statement's period for its heading
+func statementPeriod(filter StatementFilter) string {
+	from, to := "account opening", "today"
+	if !filter.From.IsZero() {
+		from = filter.From.UTC().Format("2006-01-02")
+	}
+	if !filter.To.IsZero() {
+		// To is exclusive, so the last day covered is the one before it
+		to = filter.To.Add(-time.Nanosecond).UTC().Format("2006-01-02")
+	}
+	return from + " to " + to
+}
+
+func truncateCell(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) > max {
+		return string(runes[:max-3]) + "..."
+	}
+	return value
+}
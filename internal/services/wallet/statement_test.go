@@ -0,0 +1,34 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatementPeriod(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		filter StatementFilter
+		want   string
+	}{
+		{StatementFilter{From: from, To: to}, "2026-01-01 to 2026-01-31"},
+		{StatementFilter{From: from}, "2026-01-01 to today"},
+		{StatementFilter{To: to}, "account opening to 2026-01-31"},
+	}
+	for _, tt := range tests {
+		if got := statementPeriod(tt.filter); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestTruncateCell(t *testing.T) {
+	if got := truncateCell("Payment from Acme", 20); got != "Payment from Acme" {
+		t.Errorf("expected short values unchanged, got %q", got)
+	}
+	if got := truncateCell("Payment from Acme Incorporated", 12); got != "Payment f..." {
+		t.Errorf("unexpected truncation %q", got)
+	}
+}
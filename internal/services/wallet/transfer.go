@@ -0,0 +1,208 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/currency"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Wallet transaction types of the two sides of a transfer
+const (
+	transferOutTxType = "transfer_out"
+	transferInTxType  = "transfer_in"
+)
+
+// MaxIdempotencyKeyLength matches the transfers.idempotency_key column
+const MaxIdempotencyKeyLength = 100
+
+var (
+	// ErrRecipientNotFound is returned when no active user has the transfer recipient's email or username
+	ErrRecipientNotFound = errors.New("recipient not found")
+
+	// ErrTransferToSelf is returned when a user tries to transfer to themselves
+	ErrTransferToSelf = errors.New("cannot transfer to yourself")
+
+	// ErrIdempotencyKeyRequired is returned when a transfer has no idempotency key
+	ErrIdempotencyKeyRequired = fmt.Errorf("an idempotency key of up to %d characters is required", MaxIdempotencyKeyLength)
+
+	// ErrIdempotencyKeyReused is returned when an idempotency key was already used for a
+	// different transfer
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different transfer")
+)
+
+// Transfer sends funds from a user's wallet to another user's wallet in the same currency. The
+// recipient is found by email or username, and their wallet is opened if they don't have one.
+// The debit, the credit and the transfer record are written in one transaction. Retrying with
+// an idempotency key the sender already used returns the original transfer rather than sending
+// again, or ErrIdempotencyKeyReused if the retry asks for a different transfer.
+func (s *WalletService) Transfer(fromUserID uuid.UUID, toUserIdentifier string, amount float64, code models.Currency, idempotencyKey string) (*models.Transfer, error) {
+	idempotencyKey = strings.TrimSpace(idempotencyKey)
+	if idempotencyKey == "" || len(idempotencyKey) > MaxIdempotencyKeyLength {
+		return nil, ErrIdempotencyKeyRequired
+	}
+	if err := currency.Validate(code); err != nil {
+		return nil, err
+	}
+	amount = currency.Round(code, amount)
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	recipient, err := s.findRecipient(toUserIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	// A retry is answered before anything else is checked, so it gets the same transfer back
+	// even if the sender's balance has since changed
+	existing, err := s.findTransfer(fromUserID, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return matchTransfer(existing, recipient.ID, amount, code)
+	}
+
+	if recipient.ID == fromUserID {
+		return nil, ErrTransferToSelf
+	}
+	if err := s.CheckWithdrawalsAllowed(fromUserID); err != nil {
+		return nil, err
+	}
+
+	var senderWallet models.Wallet
+	if err := s.db.Where("user_id = ? AND currency = ?", fromUserID, code).First(&senderWallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, fmt.Errorf("error finding wallet: %w", err)
+	}
+	recipientWallet, err := s.GetOrCreateWallet(recipient.ID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := models.Transfer{
+		SenderUserID:      fromUserID,
+		RecipientUserID:   recipient.ID,
+		SenderWalletID:    senderWallet.ID,
+		RecipientWalletID: recipientWallet.ID,
+		Amount:            amount,
+		Currency:          code,
+		Reference:         "TRF-" + strings.ToUpper(uuid.New().String()[:12]),
+		IdempotencyKey:    idempotencyKey,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Created first so a concurrent retry with the same key waits on the unique index and
+		// then fails, rather than debiting the sender as well
+		if err := tx.Create(&transfer).Error; err != nil {
+			return fmt.Errorf("error creating transfer: %w", err)
+		}
+
+		// Both wallets are locked in ID order so opposite transfers between the same two users
+		// can't deadlock
+		first, second := senderWallet.ID, recipientWallet.ID
+		if second.String() < first.String() {
+			first, second = second, first
+		}
+		locked := make(map[uuid.UUID]*models.Wallet, 2)
+		for _, walletID := range []uuid.UUID{first, second} {
+			wallet, err := s.lockWallet(tx, walletID)
+			if err != nil {
+				return err
+			}
+			locked[walletID] = wallet
+		}
+
+		if err := checkAvailable(locked[senderWallet.ID], amount); err != nil {
+			return err
+		}
+		metadata := map[string]interface{}{
+			"transfer_id":       transfer.ID.String(),
+			"sender_user_id":    fromUserID.String(),
+			"recipient_user_id": recipient.ID.String(),
+		}
+		debit, err := s.applyMovement(tx, locked[senderWallet.ID], -amount, transferOutTxType, models.LedgerCategoryTransfer,
+			transfer.Reference, "Transfer sent", metadata)
+		if err != nil {
+			return err
+		}
+		credit, err := s.applyMovement(tx, locked[recipientWallet.ID], amount, transferInTxType, models.LedgerCategoryTransfer,
+			transfer.Reference, "Transfer received", metadata)
+		if err != nil {
+			return err
+		}
+
+		transfer.DebitTransactionID = &debit.ID
+		transfer.CreditTransactionID = &credit.ID
+		if err := tx.Model(&transfer).Updates(map[string]interface{}{
+			"debit_transaction_id":  debit.ID,
+			"credit_transaction_id": credit.ID,
+		}).Error; err != nil {
+			return fmt.Errorf("error linking transfer transactions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// A concurrent request with the same key won the race; answer as its retry
+		if isDuplicateKey(err) {
+			existing, findErr := s.findTransfer(fromUserID, idempotencyKey)
+			if findErr == nil && existing != nil {
+				return matchTransfer(existing, recipient.ID, amount, code)
+			}
+		}
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+// findRecipient finds the active user with an email or username, ignoring case
+func (s *WalletService) findRecipient(identifier string) (*models.User, error) {
+	identifier = strings.ToLower(strings.TrimSpace(identifier))
+	if identifier == "" {
+		return nil, ErrRecipientNotFound
+	}
+
+	var user models.User
+	err := s.db.Select("id", "is_active").
+		Where("LOWER(email) = ? OR LOWER(username) = ?", identifier, identifier).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRecipientNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error finding recipient: %w", err)
+	}
+	if !user.IsActive {
+		return nil, ErrRecipientNotFound
+	}
+	return &user, nil
+}
+
+// findTransfer returns the sender's transfer with an idempotency key, or nil if there isn't one
+func (s *WalletService) findTransfer(senderUserID uuid.UUID, idempotencyKey string) (*models.Transfer, error) {
+	var transfer models.Transfer
+	err := s.db.Where("sender_user_id = ? AND idempotency_key = ?", senderUserID, idempotencyKey).First(&transfer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error finding transfer: %w", err)
+	}
+	return &transfer, nil
+}
+
+// matchTransfer returns an existing transfer for a retried request, provided the retry asks for
+// the same transfer
+func matchTransfer(existing *models.Transfer, recipientID uuid.UUID, amount float64, code models.Currency) (*models.Transfer, error) {
+	if existing.RecipientUserID != recipientID || existing.Amount != amount || existing.Currency != code {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return existing, nil
+}
@@ -0,0 +1,66 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransfer(t *testing.T) {
+	db := testutil.NewDB(t)
+	sender := testutil.CreateUser(t, db)
+	recipient := testutil.CreateUser(t, db)
+	s := NewWalletService(db)
+
+	w, err := s.GetOrCreateWallet(sender.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	_, err = s.Credit(w.ID, 100, "deposit", models.LedgerCategoryDeposit, "DEP-1", "Deposit", nil)
+	require.NoError(t, err)
+
+	// The recipient is found by email regardless of case, and their wallet is opened
+	transfer, err := s.Transfer(sender.ID, strings.ToUpper(recipient.Email), 40, models.CurrencyGHS, "key-1")
+	require.NoError(t, err)
+	require.NotNil(t, transfer.DebitTransactionID)
+	require.NotNil(t, transfer.CreditTransactionID)
+
+	// Retrying with the same key doesn't send again
+	retried, err := s.Transfer(sender.ID, recipient.Username, 40, models.CurrencyGHS, "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, transfer.ID, retried.ID)
+	_, err = s.Transfer(sender.ID, recipient.Username, 50, models.CurrencyGHS, "key-1")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	senderWallet, err := s.GetWallet(w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, senderWallet.Balance)
+	recipientWallet, err := s.GetWallet(transfer.RecipientWalletID)
+	require.NoError(t, err)
+	assert.Equal(t, 40.0, recipientWallet.Balance)
+
+	// Both sides are in the ledger under the transfer's reference
+	var entries []models.WalletLedgerEntry
+	require.NoError(t, db.Where("reference = ?", transfer.Reference).Order("amount").Find(&entries).Error)
+	require.Len(t, entries, 2)
+	assert.Equal(t, -40.0, entries[0].Amount)
+	assert.Equal(t, 40.0, entries[1].Amount)
+
+	_, err = s.Transfer(sender.ID, recipient.Email, 61, models.CurrencyGHS, "key-2")
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	_, err = s.Transfer(sender.ID, sender.Email, 10, models.CurrencyGHS, "key-3")
+	assert.ErrorIs(t, err, ErrTransferToSelf)
+	_, err = s.Transfer(sender.ID, "nobody@example.com", 10, models.CurrencyGHS, "key-4")
+	assert.ErrorIs(t, err, ErrRecipientNotFound)
+	_, err = s.Transfer(sender.ID, recipient.Email, 10, models.CurrencyGHS, " ")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyRequired)
+
+	// The failed insufficient funds attempt left nothing behind, so its key can be used again
+	_, err = s.Transfer(sender.ID, recipient.Email, 10, models.CurrencyGHS, "key-2")
+	require.NoError(t, err)
+	var count int64
+	require.NoError(t, db.Model(&models.Transfer{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
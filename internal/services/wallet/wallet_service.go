@@ -3,15 +3,44 @@ package wallet
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/currency"
+	"github.com/revaspay/backend/internal/metrics"
 	"github.com/revaspay/backend/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	// ErrInsufficientFunds is returned when a debit or hold exceeds the wallet's available balance
+	ErrInsufficientFunds = errors.New("insufficient funds")
+
+	// ErrInvalidAmount is returned when a debit or hold is not for a positive amount
+	ErrInvalidAmount = errors.New("amount must be greater than zero")
+
+	// ErrWalletNotFound is returned when a wallet does not exist
+	ErrWalletNotFound = errors.New("wallet not found")
+
+	// ErrDuplicateCredit is returned by CreditOnce when the wallet was already credited for the reference
+	ErrDuplicateCredit = errors.New("wallet already credited for this reference")
+
+	// ErrTransactionNotFound is returned when a transaction doesn't exist or isn't the user's
+	ErrTransactionNotFound = errors.New("transaction not found")
+
+	// ErrTagTooLong is returned when a transaction tag is longer than MaxTagLength
+	ErrTagTooLong = fmt.Errorf("tag must be at most %d characters", MaxTagLength)
+)
+
+// MaxTagLength matches the transactions.tag column
+const MaxTagLength = 50
+
 // WalletService handles wallet operations
 type WalletService struct {
-	db *gorm.DB
+	db                *gorm.DB
+	creditBatchWindow time.Duration
 }
 
 // NewWalletService creates a new wallet service
@@ -20,11 +49,11 @@ func NewWalletService(db *gorm.DB) *WalletService {
 }
 
 // GetOrCreateWallet gets a user's wallet or creates one if it doesn't exist
-func (s *WalletService) GetOrCreateWallet(userID uuid.UUID, currency models.Currency) (*models.Wallet, error) {
+func (s *WalletService) GetOrCreateWallet(userID uuid.UUID, code models.Currency) (*models.Wallet, error) {
 	var wallet models.Wallet
 	
 	// Try to find existing wallet
-	err := s.db.Where("user_id = ? AND currency = ?", userID, currency).First(&wallet).Error
+	err := s.db.Where("user_id = ? AND currency = ?", userID, code).First(&wallet).Error
 	if err == nil {
 		return &wallet, nil
 	}
@@ -33,10 +62,15 @@ func (s *WalletService) GetOrCreateWallet(userID uuid.UUID, currency models.Curr
 		return nil, fmt.Errorf("error finding wallet: %w", err)
 	}
 	
+	// New wallets can only be opened in supported currencies
+	if err := currency.Validate(code); err != nil {
+		return nil, err
+	}
+	
 	// Create new wallet
 	wallet = models.Wallet{
 		UserID:    userID,
-		Currency:  currency,
+		Currency:  code,
 		Balance:   0,
 		Available: 0,
 	}
@@ -61,15 +95,16 @@ func (s *WalletService) GetWallets(userID uuid.UUID) ([]models.Wallet, error) {
 func (s *WalletService) GetWallet(walletID uuid.UUID) (*models.Wallet, error) {
 	var wallet models.Wallet
 	if err := s.db.First(&wallet, "id = ?", walletID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWalletNotFound
+		}
 		return nil, fmt.Errorf("error finding wallet: %w", err)
 	}
 	return &wallet, nil
 }
 
 // Credit adds funds to a wallet
-func (s *WalletService) Credit(walletID uuid.UUID, amount float64, txType string, reference string, description string, metadata map[string]interface{}) (*models.Transaction, error) {
-	var wallet models.Wallet
-	
+func (s *WalletService) Credit(walletID uuid.UUID, amount float64, txType string, category models.LedgerCategory, reference string, description string, metadata map[string]interface{}) (*models.Transaction, error) {
 	// Use a transaction to ensure atomicity
 	tx := s.db.Begin()
 	defer func() {
@@ -79,39 +114,16 @@ func (s *WalletService) Credit(walletID uuid.UUID, amount float64, txType string
 	}()
 	
 	// Get wallet with lock
-	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&wallet, "id = ?", walletID).Error; err != nil {
+	wallet, err := s.lockWallet(tx, walletID)
+	if err != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("error finding wallet: %w", err)
+		return nil, err
 	}
 	
-	// Record balance before
-	balanceBefore := wallet.Balance
-	
-	// Update wallet balance
-	wallet.Balance += amount
-	wallet.Available += amount
-	if err := tx.Save(&wallet).Error; err != nil {
+	transaction, err := s.applyMovement(tx, wallet, amount, txType, category, reference, description, metadata)
+	if err != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("error updating wallet balance: %w", err)
-	}
-	
-	// Create transaction record
-	transaction := models.Transaction{
-		WalletID:      walletID,
-		Type:          txType,
-		Amount:        amount,
-		Currency:      wallet.Currency,
-		Status:        "completed",
-		Reference:     reference,
-		Description:   description,
-		MetaData:      metadata, // models.JSON is already a map[string]interface{}
-		BalanceBefore: balanceBefore,
-		BalanceAfter:  wallet.Balance,
-	}
-	
-	if err := tx.Create(&transaction).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("error creating transaction record: %w", err)
+		return nil, err
 	}
 	
 	// Commit transaction
@@ -119,53 +131,59 @@ func (s *WalletService) Credit(walletID uuid.UUID, amount float64, txType string
 		return nil, fmt.Errorf("error committing transaction: %w", err)
 	}
 	
-	return &transaction, nil
+	return transaction, nil
 }
 
-// CreditWithTx adds funds to a wallet using an existing transaction
-func (s *WalletService) CreditWithTx(tx *gorm.DB, walletID uuid.UUID, amount float64, txType string, reference string, description string, metadata map[string]interface{}) error {
-	var wallet models.Wallet
-	
-	// Get wallet with lock
-	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&wallet, "id = ?", walletID).Error; err != nil {
-		return fmt.Errorf("error finding wallet: %w", err)
+// CreditOnce adds funds to a wallet unless it already has a txType transaction with the same
+// reference, in which case it returns ErrDuplicateCredit. The check runs under the wallet's
+// row lock, so redelivered or replayed events can't credit twice even when they race.
+func (s *WalletService) CreditOnce(walletID uuid.UUID, amount float64, txType string, category models.LedgerCategory, reference string, description string, metadata map[string]interface{}) (*models.Transaction, error) {
+	var transaction *models.Transaction
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = s.CreditOnceWithTx(tx, walletID, amount, txType, category, reference, description, metadata)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	
-	// Record balance before
-	balanceBefore := wallet.Balance
-	
-	// Update wallet balance
-	wallet.Balance += amount
-	wallet.Available += amount
-	if err := tx.Save(&wallet).Error; err != nil {
-		return fmt.Errorf("error updating wallet balance: %w", err)
+	return transaction, nil
+}
+
+// CreditOnceWithTx credits a wallet once per txType and reference using an existing transaction
+func (s *WalletService) CreditOnceWithTx(tx *gorm.DB, walletID uuid.UUID, amount float64, txType string, category models.LedgerCategory, reference string, description string, metadata map[string]interface{}) (*models.Transaction, error) {
+	wallet, err := s.lockWallet(tx, walletID)
+	if err != nil {
+		return nil, err
 	}
 	
-	// Create transaction record
-	transaction := models.Transaction{
-		WalletID:      walletID,
-		Type:          txType,
-		Amount:        amount,
-		Currency:      wallet.Currency,
-		Status:        "completed",
-		Reference:     reference,
-		Description:   description,
-		MetaData:      metadata, // models.JSON is already a map[string]interface{}
-		BalanceBefore: balanceBefore,
-		BalanceAfter:  wallet.Balance,
+	var existing int64
+	if err := tx.Model(&models.Transaction{}).
+		Where("wallet_id = ? AND type = ? AND reference = ?", walletID, txType, reference).
+		Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("error checking for existing credit: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrDuplicateCredit
 	}
 	
-	if err := tx.Create(&transaction).Error; err != nil {
-		return fmt.Errorf("error creating transaction record: %w", err)
+	return s.applyMovement(tx, wallet, amount, txType, category, reference, description, metadata)
+}
+
+// CreditWithTx adds funds to a wallet using an existing transaction
+func (s *WalletService) CreditWithTx(tx *gorm.DB, walletID uuid.UUID, amount float64, txType string, category models.LedgerCategory, reference string, description string, metadata map[string]interface{}) error {
+	// Get wallet with lock
+	wallet, err := s.lockWallet(tx, walletID)
+	if err != nil {
+		return err
 	}
 	
-	return nil
+	_, err = s.applyMovement(tx, wallet, amount, txType, category, reference, description, metadata)
+	return err
 }
 
 // Debit removes funds from a wallet
-func (s *WalletService) Debit(walletID uuid.UUID, amount float64, txType string, reference string, description string, metadata map[string]interface{}) (*models.Transaction, error) {
-	var wallet models.Wallet
-	
+func (s *WalletService) Debit(walletID uuid.UUID, amount float64, txType string, category models.LedgerCategory, reference string, description string, metadata map[string]interface{}) (*models.Transaction, error) {
 	// Use a transaction to ensure atomicity
 	tx := s.db.Begin()
 	defer func() {
@@ -175,33 +193,107 @@ func (s *WalletService) Debit(walletID uuid.UUID, amount float64, txType string,
 	}()
 	
 	// Get wallet with lock
-	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&wallet, "id = ?", walletID).Error; err != nil {
+	wallet, err := s.lockWallet(tx, walletID)
+	if err != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("error finding wallet: %w", err)
+		return nil, err
 	}
 	
-	// Check if sufficient funds
-	if wallet.Available < amount {
+	// Check if sufficient funds while the wallet is locked
+	amount = currency.Round(wallet.Currency, amount)
+	if err := checkAvailable(wallet, amount); err != nil {
 		tx.Rollback()
-		return nil, errors.New("insufficient funds")
+		return nil, err
+	}
+	
+	transaction, err := s.applyMovement(tx, wallet, -amount, txType, category, reference, description, metadata) // Negative for debit
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	
+	return transaction, nil
+}
+
+// DebitWithTx removes funds from a wallet using an existing transaction
+func (s *WalletService) DebitWithTx(tx *gorm.DB, walletID uuid.UUID, amount float64, txType string, category models.LedgerCategory, reference string, description string, metadata map[string]interface{}) (*models.Transaction, error) {
+	// Get wallet with lock
+	wallet, err := s.lockWallet(tx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Check if sufficient funds while the wallet is locked
+	amount = currency.Round(wallet.Currency, amount)
+	if err := checkAvailable(wallet, amount); err != nil {
+		return nil, err
+	}
+	
+	return s.applyMovement(tx, wallet, -amount, txType, category, reference, description, metadata)
+}
+
+// lockWallet loads a wallet with a row lock held until the surrounding transaction ends
+func (s *WalletService) lockWallet(tx *gorm.DB, walletID uuid.UUID) (*models.Wallet, error) {
+	var wallet models.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&wallet, "id = ?", walletID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, fmt.Errorf("error finding wallet: %w", err)
 	}
+	return &wallet, nil
+}
+
+// checkAvailable verifies a locked wallet can cover an outgoing amount
+func checkAvailable(wallet *models.Wallet, amount float64) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	if wallet.Available < amount {
+		return fmt.Errorf("%w: requested %.2f %s, available %.2f %s", ErrInsufficientFunds, amount, wallet.Currency, wallet.Available, wallet.Currency)
+	}
+	return nil
+}
+
+// applyMovement changes a locked wallet's balance by a signed amount and records the
+// matching transaction and ledger entry in the same database transaction. The amount is
+// rounded to the currency's precision so float error never reaches stored balances.
+func (s *WalletService) applyMovement(tx *gorm.DB, wallet *models.Wallet, amount float64, txType string, category models.LedgerCategory, reference string, description string, metadata map[string]interface{}) (*models.Transaction, error) {
+	transaction, err := s.recordMovement(tx, wallet, amount, txType, category, reference, description, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Save(wallet).Error; err != nil {
+		return nil, fmt.Errorf("error updating wallet balance: %w", err)
+	}
+	return transaction, nil
+}
+
+// recordMovement changes a locked wallet's balances in memory by a signed amount and records
+// the matching transaction and ledger entry, plus the balance change for any subscribed
+// webhook endpoints, leaving the caller to save the wallet
+func (s *WalletService) recordMovement(tx *gorm.DB, wallet *models.Wallet, amount float64, txType string, category models.LedgerCategory, reference string, description string, metadata map[string]interface{}) (*models.Transaction, error) {
+	amount = currency.Round(wallet.Currency, amount)
 	
 	// Record balance before
 	balanceBefore := wallet.Balance
 	
 	// Update wallet balance
-	wallet.Balance -= amount
-	wallet.Available -= amount
-	if err := tx.Save(&wallet).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("error updating wallet balance: %w", err)
-	}
+	wallet.Balance += amount
+	wallet.Available += amount
+	roundBalances(wallet)
 	
 	// Create transaction record
 	transaction := models.Transaction{
-		WalletID:      walletID,
+		WalletID:      wallet.ID,
 		Type:          txType,
-		Amount:        -amount, // Negative for debit
+		Category:      category,
+		Amount:        amount,
 		Currency:      wallet.Currency,
 		Status:        "completed",
 		Reference:     reference,
@@ -212,37 +304,105 @@ func (s *WalletService) Debit(walletID uuid.UUID, amount float64, txType string,
 	}
 	
 	if err := tx.Create(&transaction).Error; err != nil {
-		tx.Rollback()
 		return nil, fmt.Errorf("error creating transaction record: %w", err)
 	}
 	
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("error committing transaction: %w", err)
+	entryType := models.LedgerEntryCredit
+	if amount < 0 {
+		entryType = models.LedgerEntryDebit
 	}
+	entry := models.WalletLedgerEntry{
+		WalletID:      wallet.ID,
+		TransactionID: &transaction.ID,
+		EntryType:     entryType,
+		Category:      category,
+		Amount:        amount,
+		BalanceAfter:  wallet.Balance,
+		Currency:      wallet.Currency,
+		Reference:     reference,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, fmt.Errorf("error creating ledger entry: %w", err)
+	}
+	
+	if err := recordBalanceChange(tx, wallet, amount, reference); err != nil {
+		return nil, err
+	}
+	
+	// Counted when written rather than on commit, so a rolled back movement is still counted
+	metrics.ObserveWalletMovement(string(wallet.Currency), amount)
 	
 	return &transaction, nil
 }
 
+// roundBalances rounds a wallet's balances to its currency's precision after they change
+func roundBalances(wallet *models.Wallet) {
+	wallet.Balance = currency.Round(wallet.Currency, wallet.Balance)
+	wallet.Available = currency.Round(wallet.Currency, wallet.Available)
+	wallet.Held = currency.Round(wallet.Currency, wallet.Held)
+}
+
+// TransactionFilter narrows a wallet's transaction history. Empty fields match everything.
+type TransactionFilter struct {
+	Category models.LedgerCategory
+	Tag      string
+}
+
 // GetTransactionHistory gets transaction history for a wallet
-func (s *WalletService) GetTransactionHistory(walletID uuid.UUID, page, pageSize int) ([]models.Transaction, int64, error) {
+func (s *WalletService) GetTransactionHistory(walletID uuid.UUID, filter TransactionFilter, page, pageSize int) ([]models.Transaction, int64, error) {
 	var transactions []models.Transaction
 	var total int64
 	
+	query := s.db.Model(&models.Transaction{}).Where("wallet_id = ?", walletID)
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Tag != "" {
+		query = query.Where("tag = ?", filter.Tag)
+	}
+	
 	// Count total records
-	if err := s.db.Model(&models.Transaction{}).Where("wallet_id = ?", walletID).Count(&total).Error; err != nil {
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, fmt.Errorf("error counting transactions: %w", err)
 	}
 	
 	// Get paginated records
 	offset := (page - 1) * pageSize
-	if err := s.db.Where("wallet_id = ?", walletID).Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&transactions).Error; err != nil {
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&transactions).Error; err != nil {
 		return nil, 0, fmt.Errorf("error finding transactions: %w", err)
 	}
 	
 	return transactions, total, nil
 }
 
+// TagTransaction sets or, with an empty tag, clears the free-form tag on one of the user's
+// wallet transactions. Tags are bookkeeping labels only, so unlike the category they can
+// change after the transaction is written.
+func (s *WalletService) TagTransaction(userID, transactionID uuid.UUID, tag string) (*models.Transaction, error) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) > MaxTagLength {
+		return nil, ErrTagTooLong
+	}
+	
+	var transaction models.Transaction
+	err := s.db.Joins("JOIN wallets ON wallets.id = transactions.wallet_id").
+		Where("transactions.id = ? AND wallets.user_id = ?", transactionID, userID).
+		First(&transaction).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("error finding transaction: %w", err)
+	}
+	
+	if err := s.db.Model(&transaction).Update("tag", tag).Error; err != nil {
+		return nil, fmt.Errorf("error tagging transaction: %w", err)
+	}
+	transaction.Tag = tag
+	
+	return &transaction, nil
+}
+
 // GetAutoWithdrawConfig gets auto-withdraw configuration for a user
 func (s *WalletService) GetAutoWithdrawConfig(userID uuid.UUID) (*models.AutoWithdrawConfig, error) {
 	var config models.AutoWithdrawConfig
@@ -0,0 +1,259 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/currency"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/crypto"
+	"gorm.io/gorm"
+)
+
+// Withdrawal methods supported by the withdrawal processor
+const (
+	WithdrawalMethodBankTransfer = "bank_transfer"
+	WithdrawalMethodMobileMoney  = "mobile_money"
+	WithdrawalMethodCrypto       = "crypto"
+	WithdrawalMethodPayPal       = "paypal"
+)
+
+var (
+	// ErrWithdrawalNotFound is returned when a withdrawal does not exist or belongs to another user
+	ErrWithdrawalNotFound = errors.New("withdrawal not found")
+
+	// ErrUnsupportedWithdrawalMethod is returned for withdrawal methods we cannot pay out through
+	ErrUnsupportedWithdrawalMethod = errors.New("unsupported withdrawal method")
+
+	// ErrMissingWithdrawalDestination is returned when the details needed to pay out are absent
+	ErrMissingWithdrawalDestination = errors.New("withdrawal destination details are required")
+
+	// ErrAccountSuspended is returned when withdrawing from a suspended user's wallet, which
+	// stays frozen until the account is reactivated
+	ErrAccountSuspended = errors.New("account is suspended")
+)
+
+// CreateWithdrawalRequest describes a user-initiated withdrawal
+type CreateWithdrawalRequest struct {
+	Currency      models.Currency
+	Amount        float64
+	Method        string
+	DestinationID uuid.UUID
+	Description   string
+	Metadata      map[string]interface{}
+}
+
+// CreateWithdrawal validates a withdrawal and reserves its amount in the user's wallet.
+// The funds are held rather than debited so they are only taken once the payout succeeds.
+func (s *WalletService) CreateWithdrawal(userID uuid.UUID, req CreateWithdrawalRequest) (*models.Withdrawal, error) {
+	if err := currency.Validate(req.Currency); err != nil {
+		return nil, err
+	}
+	req.Amount = currency.Round(req.Currency, req.Amount)
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if err := validateWithdrawalDestination(req); err != nil {
+		return nil, err
+	}
+	if err := s.CheckWithdrawalsAllowed(userID); err != nil {
+		return nil, err
+	}
+
+	var wallet models.Wallet
+	if err := s.db.Where("user_id = ? AND currency = ?", userID, req.Currency).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, fmt.Errorf("error finding wallet: %w", err)
+	}
+
+	metadata := req.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+
+	withdrawal := models.Withdrawal{
+		UserID:        userID,
+		WalletID:      wallet.ID,
+		Amount:        req.Amount,
+		Currency:      wallet.Currency,
+		Method:        req.Method,
+		DestinationID: req.DestinationID,
+		Status:        "pending",
+		Reference:     "WD-" + strings.ToUpper(uuid.New().String()[:12]),
+		Description:   req.Description,
+		MetaData:      models.JSON(metadata),
+		InitiatedAt:   time.Now(),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		hold, err := s.HoldWithTx(tx, wallet.ID, req.Amount, withdrawal.Reference, "Withdrawal pending")
+		if err != nil {
+			return err
+		}
+		withdrawal.HoldID = &hold.ID
+
+		if err := tx.Create(&withdrawal).Error; err != nil {
+			return fmt.Errorf("error creating withdrawal: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &withdrawal, nil
+}
+
+// CheckWithdrawalsAllowed returns ErrAccountSuspended if the user's account is suspended
+func (s *WalletService) CheckWithdrawalsAllowed(userID uuid.UUID) error {
+	var user models.User
+	if err := s.db.Select("id", "is_active").First(&user, "id = ?", userID).Error; err != nil {
+		return fmt.Errorf("error finding user: %w", err)
+	}
+	if !user.IsActive {
+		return ErrAccountSuspended
+	}
+	return nil
+}
+
+// GetWithdrawal returns a withdrawal owned by the user
+func (s *WalletService) GetWithdrawal(withdrawalID, userID uuid.UUID) (*models.Withdrawal, error) {
+	var withdrawal models.Withdrawal
+	if err := s.db.Where("id = ? AND user_id = ?", withdrawalID, userID).First(&withdrawal).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWithdrawalNotFound
+		}
+		return nil, fmt.Errorf("error finding withdrawal: %w", err)
+	}
+	return &withdrawal, nil
+}
+
+// GetWithdrawals returns a page of the user's withdrawals, newest first
+func (s *WalletService) GetWithdrawals(userID uuid.UUID, page, pageSize int) ([]models.Withdrawal, int64, error) {
+	var total int64
+	if err := s.db.Model(&models.Withdrawal{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error counting withdrawals: %w", err)
+	}
+
+	var withdrawals []models.Withdrawal
+	offset := (page - 1) * pageSize
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&withdrawals).Error; err != nil {
+		return nil, 0, fmt.Errorf("error finding withdrawals: %w", err)
+	}
+
+	return withdrawals, total, nil
+}
+
+// FailWithdrawal marks a pending withdrawal as failed and releases its held funds
+func (s *WalletService) FailWithdrawal(withdrawalID uuid.UUID, reason string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var withdrawal models.Withdrawal
+		if err := tx.First(&withdrawal, "id = ?", withdrawalID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrWithdrawalNotFound
+			}
+			return fmt.Errorf("error finding withdrawal: %w", err)
+		}
+		return s.FailWithdrawalWithTx(tx, &withdrawal, reason)
+	})
+}
+
+// FailWithdrawalWithTx marks a withdrawal as failed and returns its funds using an existing
+// transaction
+func (s *WalletService) FailWithdrawalWithTx(tx *gorm.DB, withdrawal *models.Withdrawal, reason string) error {
+	if err := s.RefundWithdrawalWithTx(tx, withdrawal, reason); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	withdrawal.Status = "failed"
+	withdrawal.FailureReason = reason
+	withdrawal.FailedAt = &now
+	withdrawal.UpdatedAt = now
+	if err := tx.Save(withdrawal).Error; err != nil {
+		return fmt.Errorf("error updating withdrawal: %w", err)
+	}
+	return nil
+}
+
+// RefundWithdrawalWithTx returns a failed withdrawal's funds using an existing transaction.
+// Held funds are released; older withdrawals that debited the wallet up front are credited
+// back once, keyed on the withdrawal's ID, so retrying a failure can't refund it twice.
+func (s *WalletService) RefundWithdrawalWithTx(tx *gorm.DB, withdrawal *models.Withdrawal, reason string) error {
+	if withdrawal.HoldID != nil {
+		if err := s.ReleaseHoldWithTx(tx, *withdrawal.HoldID); err != nil && !errors.Is(err, ErrHoldNotActive) {
+			return err
+		}
+		return nil
+	}
+
+	_, err := s.CreditOnceWithTx(tx, withdrawal.WalletID, withdrawal.Amount, "refund", models.LedgerCategoryRefund,
+		fmt.Sprintf("Refund: %s", withdrawal.ID), "Withdrawal failed - amount refunded",
+		map[string]interface{}{
+			"withdrawal_id": withdrawal.ID.String(),
+			"reference":     withdrawal.Reference,
+			"refund_reason": "withdrawal_failed",
+			"error":         reason,
+		})
+	if err != nil && !errors.Is(err, ErrDuplicateCredit) {
+		return err
+	}
+	return nil
+}
+
+// CompleteWithdrawalWithTx marks a withdrawal completed and turns its hold into the final
+// debit using an existing transaction
+func (s *WalletService) CompleteWithdrawalWithTx(tx *gorm.DB, withdrawal *models.Withdrawal) error {
+	if withdrawal.HoldID != nil {
+		_, err := s.CaptureHoldWithTx(tx, *withdrawal.HoldID, "withdrawal", models.LedgerCategoryPayout, "Withdrawal completed", map[string]interface{}{
+			"withdrawal_id": withdrawal.ID.String(),
+			"method":        withdrawal.Method,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to capture wallet hold: %w", err)
+		}
+	}
+
+	now := time.Now()
+	withdrawal.Status = "completed"
+	withdrawal.CompletedAt = &now
+	withdrawal.UpdatedAt = now
+	if err := tx.Save(withdrawal).Error; err != nil {
+		return fmt.Errorf("failed to update withdrawal status: %w", err)
+	}
+	return nil
+}
+
+// validateWithdrawalDestination checks the method is supported and carries the details its processor needs
+func validateWithdrawalDestination(req CreateWithdrawalRequest) error {
+	metaString := func(key string) string {
+		v, _ := req.Metadata[key].(string)
+		return v
+	}
+
+	switch req.Method {
+	case WithdrawalMethodBankTransfer, WithdrawalMethodPayPal:
+		return nil
+	case WithdrawalMethodMobileMoney:
+		if metaString("mobile_number") == "" {
+			return fmt.Errorf("%w: mobile_number", ErrMissingWithdrawalDestination)
+		}
+		return nil
+	case WithdrawalMethodCrypto:
+		address, network := metaString("address"), metaString("network")
+		if address == "" {
+			return fmt.Errorf("%w: address", ErrMissingWithdrawalDestination)
+		}
+		if network == "" {
+			network = crypto.NetworkBase
+		}
+		return crypto.ValidateAddress(network, address)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedWithdrawalMethod, req.Method)
+	}
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// lookupTimeout bounds resolving an endpoint's host when it's registered
+const lookupTimeout = 5 * time.Second
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which isn't reachable from
+// the internet either
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP reports whether ip is a public internet address. Loopback, private (RFC 1918 and
+// unique local), link-local (which covers cloud metadata services on 169.254.169.254),
+// shared, multicast and unspecified addresses aren't.
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip) || ip.Equal(net.IPv4bcast))
+}
+
+// validateEndpointURL ensures the URL is absolute, uses http or https, and that its host
+// resolves only to addresses deliveries are allowed to reach
+func (s *WebhookService) validateEndpointURL(endpointURL string) error {
+	u, err := url.Parse(endpointURL)
+	if err != nil || u.Hostname() == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return ErrInvalidEndpointURL
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	ips, err := s.lookupIP(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: %s could not be resolved", ErrInvalidEndpointURL, u.Hostname())
+	}
+	for _, ip := range ips {
+		if !s.addressAllowed(ip) {
+			return ErrNonPublicEndpointURL
+		}
+	}
+	return nil
+}
+
+// newDeliveryClient returns the client deliveries are sent with. Each connection's address is
+// checked after the host is resolved, so a host that resolves to a public address when the
+// endpoint is registered can't later be pointed at an internal one, and redirects can't reach
+// one either. Proxies aren't used, since the checks would then apply to the proxy.
+func (s *WebhookService) newDeliveryClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !s.addressAllowed(ip) {
+				return fmt.Errorf("%w: %s", ErrNonPublicEndpointURL, host)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// lookupIPAddrs resolves a host with the default resolver
+func lookupIPAddrs(ctx context.Context, host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/currency"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DispatchBalanceChangesJobType is the job type for sending pending wallet balance changes
+	DispatchBalanceChangesJobType queue.JobType = "dispatch_wallet_balance_changes"
+
+	// DefaultBalanceChangeWindow is how long wallet balance changes are coalesced when
+	// SetBalanceChangeWindow isn't called
+	DefaultBalanceChangeWindow = 10 * time.Second
+)
+
+// SetBalanceChangeWindow sets how long a wallet's balance changes are coalesced before
+// they're sent as one wallet.balance_changed event. The sweep also runs this often.
+func (s *WebhookService) SetBalanceChangeWindow(window time.Duration) {
+	if window > 0 {
+		s.balanceChangeWindow = window
+	}
+}
+
+// BalanceChangeWindow returns how long a wallet's balance changes are coalesced
+func (s *WebhookService) BalanceChangeWindow() time.Duration {
+	return s.balanceChangeWindow
+}
+
+// DispatchBalanceChanges sends a wallet.balance_changed event for each wallet whose first
+// pending balance change is at least the coalescing window old as of now, and returns how
+// many were sent. A wallet that fails is logged and left for the next sweep.
+func (s *WebhookService) DispatchBalanceChanges(now time.Time) (int, error) {
+	var walletIDs []uuid.UUID
+	if err := s.db.Model(&models.WalletBalanceChange{}).
+		Where("created_at <= ?", now.Add(-s.balanceChangeWindow)).
+		Limit(500).
+		Pluck("wallet_id", &walletIDs).Error; err != nil {
+		return 0, fmt.Errorf("error finding wallet balance changes: %w", err)
+	}
+
+	dispatched := 0
+	for _, walletID := range walletIDs {
+		sent, err := s.dispatchBalanceChange(walletID)
+		if err != nil {
+			log.Printf("Failed to dispatch balance change for wallet %s: %v", walletID, err)
+			continue
+		}
+		if sent {
+			dispatched++
+		}
+	}
+	return dispatched, nil
+}
+
+// dispatchBalanceChange records the event for a wallet's pending balance change and removes
+// the change, in one transaction, then enqueues the deliveries. The wallet is locked first,
+// as movements do, so the balance sent matches the changes coalesced into the event.
+func (s *WebhookService) dispatchBalanceChange(walletID uuid.UUID) (bool, error) {
+	var deliveryIDs []uuid.UUID
+	sent := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var wallet models.Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&wallet, "id = ?", walletID).Error; err != nil {
+			return fmt.Errorf("error finding wallet: %w", err)
+		}
+
+		var change models.WalletBalanceChange
+		if err := tx.First(&change, "wallet_id = ?", walletID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil // Sent by a concurrent sweep
+			}
+			return fmt.Errorf("error finding wallet balance change: %w", err)
+		}
+		if err := tx.Delete(&change).Error; err != nil {
+			return fmt.Errorf("error removing wallet balance change: %w", err)
+		}
+
+		var err error
+		deliveryIDs, err = s.createDeliveries(tx, wallet.UserID, models.WebhookEventWalletBalanceChanged, map[string]interface{}{
+			"wallet_id":  wallet.ID,
+			"currency":   wallet.Currency,
+			"balance":    wallet.Balance,
+			"available":  wallet.Available,
+			"delta":      currency.Round(wallet.Currency, change.Delta),
+			"reference":  change.Reference,
+			"changes":    change.Changes,
+			"changed_at": change.UpdatedAt,
+		})
+		sent = true
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	s.enqueueDeliveries(deliveryIDs)
+	return sent, nil
+}
@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/services/wallet"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Wallet movements are coalesced into one wallet.balance_changed event, sent only to the
+// endpoints that opted in
+func TestDispatchBalanceChanges(t *testing.T) {
+	db := testutil.NewDB(t)
+	merchant := testutil.CreateUser(t, db)
+	other := testutil.CreateUser(t, db)
+
+	s := newTestService(db, nil)
+	subscribed, err := s.CreateEndpoint(merchant.ID, "https://merchant.example.com/webhooks", "", true)
+	require.NoError(t, err)
+	_, err = s.CreateEndpoint(merchant.ID, "https://merchant.example.com/payments", "", false)
+	require.NoError(t, err)
+	_, err = s.CreateEndpoint(other.ID, "https://other.example.com/webhooks", "", false)
+	require.NoError(t, err)
+
+	wallets := wallet.NewWalletService(db)
+	merchantWallet, err := wallets.GetOrCreateWallet(merchant.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+	otherWallet, err := wallets.GetOrCreateWallet(other.ID, models.CurrencyGHS)
+	require.NoError(t, err)
+
+	_, err = wallets.Credit(merchantWallet.ID, 10, "payment", models.LedgerCategorySales, "PAY-1", "Payment", nil)
+	require.NoError(t, err)
+	_, err = wallets.Debit(merchantWallet.ID, 2.5, "withdrawal", models.LedgerCategoryPayout, "WD-1", "Withdrawal", nil)
+	require.NoError(t, err)
+	_, err = wallets.Credit(otherWallet.ID, 10, "payment", models.LedgerCategorySales, "PAY-2", "Payment", nil)
+	require.NoError(t, err)
+
+	// Rolled back movements aren't reported
+	rollback := errors.New("rollback")
+	err = db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, wallets.CreditWithTx(tx, merchantWallet.ID, 100, "payment", models.LedgerCategorySales, "PAY-3", "Payment", nil))
+		return rollback
+	})
+	require.ErrorIs(t, err, rollback)
+
+	var changes []models.WalletBalanceChange
+	require.NoError(t, db.Find(&changes).Error)
+	require.Len(t, changes, 1, "only opted in merchants' wallets are tracked")
+	assert.Equal(t, 7.5, changes[0].Delta)
+	assert.Equal(t, 2, changes[0].Changes)
+	assert.Equal(t, "WD-1", changes[0].Reference)
+
+	// Changes are held for the coalescing window
+	sent, err := s.DispatchBalanceChanges(time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+
+	sent, err = s.DispatchBalanceChanges(time.Now().Add(DefaultBalanceChangeWindow))
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	var deliveries []models.WebhookDelivery
+	require.NoError(t, db.Find(&deliveries).Error)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, subscribed.ID, deliveries[0].EndpointID)
+	assert.Equal(t, models.WebhookEventWalletBalanceChanged, deliveries[0].EventType)
+	assert.Equal(t, 7.5, deliveries[0].Payload["balance"])
+	assert.Equal(t, 7.5, deliveries[0].Payload["delta"])
+	assert.Equal(t, "WD-1", deliveries[0].Payload["reference"])
+
+	var remaining int64
+	require.NoError(t, db.Model(&models.WalletBalanceChange{}).Count(&remaining).Error)
+	assert.Zero(t, remaining)
+}
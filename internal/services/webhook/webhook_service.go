@@ -0,0 +1,544 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// DeliverWebhookJobType is the job type for delivering a single merchant webhook
+	DeliverWebhookJobType queue.JobType = "deliver_merchant_webhook"
+
+	// RetryWebhookDeliveriesJobType is the job type for re-enqueueing deliveries that are due for retry
+	RetryWebhookDeliveriesJobType queue.JobType = "retry_merchant_webhook_deliveries"
+
+	// MaxDeliveryAttempts is the number of attempts made before a delivery is marked as failed
+	MaxDeliveryAttempts = 8
+
+	// Headers sent with every delivery. SignatureHeader is the base64 HMAC-SHA256 of
+	// "<timestamp>.<body>" (see Sign), and SignatureVersionHeader the version of the secret
+	// that signed it, e.g. "2". Versions start at 1 and go up by one with each rotation, so
+	// during a rotation merchants can pick which of their two secrets to verify with.
+	SignatureHeader        = "X-RevasPay-Signature"
+	SignatureVersionHeader = "X-RevasPay-Signature-Version"
+	TimestampHeader        = "X-RevasPay-Timestamp"
+	EventHeader            = "X-RevasPay-Event"
+	DeliveryHeader         = "X-RevasPay-Delivery"
+
+	maxResponseBodyLength = 1024
+
+	// claimTimeout is how long a delivery stays claimed by the worker sending it. One that
+	// isn't finished by then, because the worker died, is sent again by the retry sweep.
+	claimTimeout = 5 * time.Minute
+)
+
+var (
+	// ErrEndpointNotFound is returned when an endpoint does not exist or is not owned by the user
+	ErrEndpointNotFound = errors.New("webhook endpoint not found")
+
+	// ErrInvalidEndpointURL is returned when a webhook URL is not an absolute http(s) URL
+	ErrInvalidEndpointURL = errors.New("webhook URL must be an absolute http or https URL")
+
+	// ErrNonPublicEndpointURL is returned when a webhook URL's host resolves to a loopback,
+	// private, link-local or other internal address
+	ErrNonPublicEndpointURL = errors.New("webhook URL must resolve to a public address")
+
+	// ErrRotationInProgress is returned when starting a secret rotation while one is in progress
+	ErrRotationInProgress = errors.New("a secret rotation is already in progress")
+
+	// ErrNoRotationInProgress is returned when finalizing a secret rotation that wasn't started
+	ErrNoRotationInProgress = errors.New("no secret rotation is in progress")
+)
+
+// DeliverWebhookPayload represents the payload for a webhook delivery job
+type DeliverWebhookPayload struct {
+	DeliveryID uuid.UUID `json:"delivery_id"`
+}
+
+// Event is the JSON envelope POSTed to merchant endpoints
+type Event struct {
+	ID        uuid.UUID               `json:"id"`
+	Type      models.WebhookEventType `json:"type"`
+	CreatedAt time.Time               `json:"created_at"`
+	Data      map[string]interface{}  `json:"data"`
+}
+
+// WebhookService manages merchant webhook endpoints and outbound deliveries
+type WebhookService struct {
+	db                  *gorm.DB
+	queue               queue.QueueInterface
+	client              *http.Client
+	balanceChangeWindow time.Duration
+	lookupIP            func(ctx context.Context, host string) ([]net.IP, error)
+	addressAllowed      func(ip net.IP) bool // Whether endpoints may be registered at or delivered to an address
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(db *gorm.DB, q queue.QueueInterface) *WebhookService {
+	s := &WebhookService{
+		db:                  db,
+		queue:               q,
+		balanceChangeWindow: DefaultBalanceChangeWindow,
+		lookupIP:            lookupIPAddrs,
+		addressAllowed:      isPublicIP,
+	}
+	s.client = s.newDeliveryClient()
+	return s
+}
+
+// CreateEndpoint registers a new webhook endpoint for a user and generates its signing secret.
+// balanceEvents opts the endpoint in to wallet.balance_changed events.
+func (s *WebhookService) CreateEndpoint(userID uuid.UUID, endpointURL, description string, balanceEvents bool) (*models.WebhookEndpoint, error) {
+	if err := s.validateEndpointURL(endpointURL); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("error generating webhook secret: %w", err)
+	}
+
+	endpoint := models.WebhookEndpoint{
+		ID:            uuid.New(),
+		UserID:        userID,
+		URL:           endpointURL,
+		Secret:        secret,
+		SecretVersion: 1,
+		Description:   description,
+		Active:        true,
+		BalanceEvents: balanceEvents,
+	}
+
+	if err := s.db.Create(&endpoint).Error; err != nil {
+		return nil, fmt.Errorf("error creating webhook endpoint: %w", err)
+	}
+
+	return &endpoint, nil
+}
+
+// GetEndpoints gets all webhook endpoints for a user
+func (s *WebhookService) GetEndpoints(userID uuid.UUID) ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("error finding webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// GetEndpoint gets a webhook endpoint owned by a user
+func (s *WebhookService) GetEndpoint(id, userID uuid.UUID) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	if err := s.db.First(&endpoint, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEndpointNotFound
+		}
+		return nil, fmt.Errorf("error finding webhook endpoint: %w", err)
+	}
+	return &endpoint, nil
+}
+
+// UpdateEndpoint updates the URL, description, active flag or balance events opt-in of an endpoint
+func (s *WebhookService) UpdateEndpoint(id, userID uuid.UUID, endpointURL, description *string, active, balanceEvents *bool) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.GetEndpoint(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if endpointURL != nil {
+		if err := s.validateEndpointURL(*endpointURL); err != nil {
+			return nil, err
+		}
+		updates["url"] = *endpointURL
+	}
+	if description != nil {
+		updates["description"] = *description
+	}
+	if active != nil {
+		updates["active"] = *active
+	}
+	if balanceEvents != nil {
+		updates["balance_events"] = *balanceEvents
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(endpoint).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("error updating webhook endpoint: %w", err)
+		}
+	}
+
+	return endpoint, nil
+}
+
+// StartSecretRotation generates the next signing secret for an endpoint. Deliveries are
+// still signed with the current secret until FinalizeSecretRotation, so the merchant has time
+// to make their endpoint accept both. The returned endpoint's PendingSecret is the new secret.
+func (s *WebhookService) StartSecretRotation(id, userID uuid.UUID) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.GetEndpoint(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.PendingSecret != "" {
+		return nil, ErrRotationInProgress
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("error generating webhook secret: %w", err)
+	}
+
+	now := time.Now()
+	result := s.db.Model(&models.WebhookEndpoint{}).
+		Where("id = ? AND (pending_secret IS NULL OR pending_secret = '')", endpoint.ID).
+		Updates(map[string]interface{}{
+			"pending_secret":      secret,
+			"rotation_started_at": now,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("error starting webhook secret rotation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrRotationInProgress
+	}
+
+	endpoint.PendingSecret = secret
+	endpoint.RotationStartedAt = &now
+	return endpoint, nil
+}
+
+// FinalizeSecretRotation makes an endpoint's pending secret its current one, under the next
+// version. Later deliveries are signed with it, and the old secret is discarded.
+func (s *WebhookService) FinalizeSecretRotation(id, userID uuid.UUID) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.GetEndpoint(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.PendingSecret == "" {
+		return nil, ErrNoRotationInProgress
+	}
+
+	secret, version := endpoint.PendingSecret, endpoint.SecretVersion+1
+	result := s.db.Model(&models.WebhookEndpoint{}).
+		Where("id = ? AND pending_secret = ? AND secret_version = ?", endpoint.ID, secret, endpoint.SecretVersion).
+		Updates(map[string]interface{}{
+			"secret":              secret,
+			"secret_version":      version,
+			"pending_secret":      "",
+			"rotation_started_at": nil,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("error finalizing webhook secret rotation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNoRotationInProgress
+	}
+
+	endpoint.Secret = secret
+	endpoint.SecretVersion = version
+	endpoint.PendingSecret = ""
+	endpoint.RotationStartedAt = nil
+	return endpoint, nil
+}
+
+// DeleteEndpoint deletes a webhook endpoint owned by a user
+func (s *WebhookService) DeleteEndpoint(id, userID uuid.UUID) error {
+	result := s.db.Delete(&models.WebhookEndpoint{}, "id = ? AND user_id = ?", id, userID)
+	if result.Error != nil {
+		return fmt.Errorf("error deleting webhook endpoint: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrEndpointNotFound
+	}
+	return nil
+}
+
+// GetDeliveries gets the delivery log for an endpoint, including each attempt
+func (s *WebhookService) GetDeliveries(endpointID, userID uuid.UUID, page, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	if _, err := s.GetEndpoint(endpointID, userID); err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	var total int64
+
+	query := s.db.Model(&models.WebhookDelivery{}).Where("endpoint_id = ?", endpointID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error counting webhook deliveries: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Preload("AttemptLogs", func(db *gorm.DB) *gorm.DB {
+		return db.Order("attempt ASC")
+	}).Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, fmt.Errorf("error finding webhook deliveries: %w", err)
+	}
+
+	return deliveries, total, nil
+}
+
+// Dispatch records an event for every active endpoint of the user and enqueues delivery.
+// The delivery rows act as an outbox: if enqueueing fails they are picked up by the retry sweep.
+func (s *WebhookService) Dispatch(userID uuid.UUID, eventType models.WebhookEventType, data map[string]interface{}) error {
+	deliveryIDs, err := s.createDeliveries(s.db, userID, eventType, data)
+	s.enqueueDeliveries(deliveryIDs)
+	return err
+}
+
+// createDeliveries records an event for every active endpoint of the user that receives it,
+// returning the IDs of the deliveries created
+func (s *WebhookService) createDeliveries(tx *gorm.DB, userID uuid.UUID, eventType models.WebhookEventType, data map[string]interface{}) ([]uuid.UUID, error) {
+	query := tx.Where("user_id = ? AND active = ?", userID, true)
+	if eventType == models.WebhookEventWalletBalanceChanged {
+		query = query.Where("balance_events = ?", true)
+	}
+	var endpoints []models.WebhookEndpoint
+	if err := query.Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("error finding webhook endpoints: %w", err)
+	}
+
+	var deliveryIDs []uuid.UUID
+	for _, endpoint := range endpoints {
+		now := time.Now()
+		delivery := models.WebhookDelivery{
+			ID:          uuid.New(),
+			EndpointID:  endpoint.ID,
+			UserID:      userID,
+			EventType:   eventType,
+			Payload:     models.JSON(data),
+			Status:      models.WebhookDeliveryStatusPending,
+			NextRetryAt: &now,
+		}
+
+		if err := tx.Create(&delivery).Error; err != nil {
+			return deliveryIDs, fmt.Errorf("error creating webhook delivery: %w", err)
+		}
+		deliveryIDs = append(deliveryIDs, delivery.ID)
+	}
+
+	return deliveryIDs, nil
+}
+
+// enqueueDeliveries enqueues deliveries, leaving any that fail to the retry sweep
+func (s *WebhookService) enqueueDeliveries(deliveryIDs []uuid.UUID) {
+	for _, deliveryID := range deliveryIDs {
+		if err := s.enqueueDelivery(deliveryID); err != nil {
+			log.Printf("Failed to enqueue webhook delivery %s: %v", deliveryID, err)
+		}
+	}
+}
+
+// Deliver POSTs a pending delivery to its endpoint and records the attempt.
+// On a non-2xx response the next retry is scheduled with exponential backoff.
+// The delivery is claimed first, so when the same delivery is enqueued twice only one
+// worker sends it.
+func (s *WebhookService) Deliver(ctx context.Context, deliveryID uuid.UUID) error {
+	claimed, err := s.claimDelivery(deliveryID, time.Now())
+	if err != nil || !claimed {
+		return err
+	}
+
+	var delivery models.WebhookDelivery
+	if err := s.db.First(&delivery, "id = ?", deliveryID).Error; err != nil {
+		return fmt.Errorf("error finding webhook delivery: %w", err)
+	}
+
+	var endpoint models.WebhookEndpoint
+	if err := s.db.First(&endpoint, "id = ?", delivery.EndpointID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Endpoint was deleted after the event was recorded
+			return s.db.Model(&delivery).Updates(map[string]interface{}{
+				"status":        models.WebhookDeliveryStatusFailed,
+				"last_error":    "endpoint deleted",
+				"next_retry_at": nil,
+			}).Error
+		}
+		return fmt.Errorf("error finding webhook endpoint: %w", err)
+	}
+
+	body, err := json.Marshal(Event{
+		ID:        delivery.ID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshaling webhook event: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	attempt := models.WebhookDeliveryAttempt{
+		ID:         uuid.New(),
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts + 1,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(timestamp, body, endpoint.Secret))
+	req.Header.Set(SignatureVersionHeader, strconv.Itoa(endpoint.SecretVersion))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(EventHeader, string(delivery.EventType))
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+	} else {
+		// The response body isn't kept, since it's whatever the endpoint chose to return;
+		// a little is read so the connection can be reused
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBodyLength))
+		attempt.StatusCode = resp.StatusCode
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			attempt.Error = fmt.Sprintf("endpoint responded with status %d", resp.StatusCode)
+		}
+	}
+
+	return s.recordAttempt(&delivery, &attempt)
+}
+
+// claimDelivery marks a delivery as being sent, reporting false if it isn't pending or was
+// already claimed by another worker. A claim older than claimTimeout can be taken over.
+func (s *WebhookService) claimDelivery(deliveryID uuid.UUID, now time.Time) (bool, error) {
+	result := s.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND (status = ? OR (status = ? AND next_retry_at <= ?))",
+			deliveryID, models.WebhookDeliveryStatusPending, models.WebhookDeliveryStatusSending, now).
+		Updates(map[string]interface{}{
+			"status":        models.WebhookDeliveryStatusSending,
+			"next_retry_at": now.Add(claimTimeout),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("error claiming webhook delivery: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RetryDueDeliveries enqueues pending deliveries whose next retry time has passed, and those
+// whose worker didn't finish sending them in time
+func (s *WebhookService) RetryDueDeliveries() (int, error) {
+	var deliveries []models.WebhookDelivery
+	if err := s.db.Where("status IN ? AND next_retry_at <= ?",
+		[]models.WebhookDeliveryStatus{models.WebhookDeliveryStatusPending, models.WebhookDeliveryStatusSending}, time.Now()).
+		Limit(500).Find(&deliveries).Error; err != nil {
+		return 0, fmt.Errorf("error finding due webhook deliveries: %w", err)
+	}
+
+	enqueued := 0
+	for _, delivery := range deliveries {
+		if err := s.enqueueDelivery(delivery.ID); err != nil {
+			log.Printf("Failed to enqueue webhook delivery %s: %v", delivery.ID, err)
+			continue
+		}
+		enqueued++
+	}
+
+	return enqueued, nil
+}
+
+// Sign computes the signature merchants use to verify a delivery: HMAC-SHA256 over "timestamp.body"
+func Sign(timestamp string, body []byte, secret string) string {
+	return utils.SignHMAC(timestamp+"."+string(body), secret)
+}
+
+// VerifySignature reports whether signature is a valid Sign signature of the timestamp and
+// body with any of the secrets. Merchants rotating their secret verify against both the old
+// and the new one until the rotation is finalized.
+func VerifySignature(timestamp string, body []byte, signature string, secrets ...string) bool {
+	for _, secret := range secrets {
+		if secret != "" && hmac.Equal([]byte(signature), []byte(Sign(timestamp, body, secret))) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordAttempt stores an attempt and moves the delivery to its next state
+func (s *WebhookService) recordAttempt(delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"attempts":         attempt.Attempt,
+		"last_status_code": attempt.StatusCode,
+		"last_error":       attempt.Error,
+	}
+
+	switch {
+	case attempt.Error == "":
+		updates["status"] = models.WebhookDeliveryStatusSucceeded
+		updates["delivered_at"] = now
+		updates["next_retry_at"] = nil
+	case attempt.Attempt >= MaxDeliveryAttempts:
+		updates["status"] = models.WebhookDeliveryStatusFailed
+		updates["next_retry_at"] = nil
+	default:
+		updates["status"] = models.WebhookDeliveryStatusPending
+		updates["next_retry_at"] = now.Add(retryBackoff(attempt.Attempt))
+	}
+
+	tx := s.db.Begin()
+	if err := tx.Create(attempt).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error recording webhook attempt: %w", err)
+	}
+	if err := tx.Model(delivery).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error updating webhook delivery: %w", err)
+	}
+	return tx.Commit().Error
+}
+
+// enqueueDelivery enqueues a job to deliver a single webhook
+func (s *WebhookService) enqueueDelivery(deliveryID uuid.UUID) error {
+	if s.queue == nil {
+		return errors.New("no queue configured for webhook delivery")
+	}
+
+	payloadBytes, err := json.Marshal(DeliverWebhookPayload{DeliveryID: deliveryID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook delivery payload: %w", err)
+	}
+
+	return s.queue.Enqueue(&queue.Job{
+		ID:      uuid.New(),
+		Type:    DeliverWebhookJobType,
+		Payload: payloadBytes,
+	})
+}
+
+// retryBackoff returns the delay before the next attempt: 30s doubling up to 6 hours
+func retryBackoff(attempt int) time.Duration {
+	seconds := math.Min(6*3600, 30*math.Pow(2, float64(attempt-1)))
+	return time.Duration(seconds) * time.Second
+}
+
+// generateSecret creates a random signing secret for an endpoint
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,272 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/queue"
+	"github.com/revaspay/backend/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestService returns a webhook service that resolves every host name to a public address
+// and, unlike the real one, delivers to test servers on loopback
+func newTestService(db *gorm.DB, q queue.QueueInterface) *WebhookService {
+	s := NewWebhookService(db, q)
+	s.lookupIP = fakeLookup(map[string]string{})
+	s.addressAllowed = func(net.IP) bool { return true }
+	return s
+}
+
+// fakeLookup resolves the hosts in addresses to their address, IP literals to themselves and
+// any other host to a public address
+func fakeLookup(addresses map[string]string) func(context.Context, string) ([]net.IP, error) {
+	return func(_ context.Context, host string) ([]net.IP, error) {
+		if ip := net.ParseIP(host); ip != nil {
+			return []net.IP{ip}, nil
+		}
+		if address, ok := addresses[host]; ok {
+			if address == "" {
+				return nil, errors.New("no such host")
+			}
+			return []net.IP{net.ParseIP(address)}, nil
+		}
+		return []net.IP{net.ParseIP("93.184.216.34")}, nil
+	}
+}
+
+// recordingQueue records the jobs enqueued on it
+type recordingQueue struct {
+	queue.QueueInterface
+	jobs []*queue.Job
+}
+
+func (q *recordingQueue) Enqueue(job *queue.Job) error {
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+func TestValidateEndpointURL(t *testing.T) {
+	s := NewWebhookService(testutil.NewDB(t), nil)
+	s.lookupIP = fakeLookup(map[string]string{
+		"internal.example.com": "10.0.0.5",
+		"rebind.example.com":   "192.168.1.20",
+		"metadata.example.com": "169.254.169.254",
+		"cgnat.example.com":    "100.64.1.1",
+		"missing.example.com":  "",
+	})
+
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"https://merchant.example.com/webhooks", nil},
+		{"http://93.184.216.34:8080/hook", nil},
+		{"ftp://merchant.example.com/webhooks", ErrInvalidEndpointURL},
+		{"/webhooks", ErrInvalidEndpointURL},
+		{"https://missing.example.com/webhooks", ErrInvalidEndpointURL},
+		{"http://127.0.0.1/hook", ErrNonPublicEndpointURL},
+		{"http://localhost.:8080/hook", nil}, // Resolved by the fake to a public address
+		{"http://[::1]/hook", ErrNonPublicEndpointURL},
+		{"http://0.0.0.0/hook", ErrNonPublicEndpointURL},
+		{"http://169.254.169.254/latest/meta-data", ErrNonPublicEndpointURL},
+		{"https://internal.example.com/hook", ErrNonPublicEndpointURL},
+		{"https://rebind.example.com/hook", ErrNonPublicEndpointURL},
+		{"https://metadata.example.com/hook", ErrNonPublicEndpointURL},
+		{"https://cgnat.example.com/hook", ErrNonPublicEndpointURL},
+		{"http://[fd00::1]/hook", ErrNonPublicEndpointURL},
+	}
+	for _, tt := range tests {
+		err := s.validateEndpointURL(tt.url)
+		if tt.want == nil {
+			assert.NoError(t, err, tt.url)
+		} else {
+			assert.ErrorIs(t, err, tt.want, tt.url)
+		}
+	}
+}
+
+// A host that resolved to a public address when it was registered can't be delivered to once
+// it points somewhere internal, since addresses are checked again when connecting
+func TestDeliverRefusesInternalAddresses(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	s := NewWebhookService(db, nil)
+	endpoint := models.WebhookEndpoint{ID: uuid.New(), UserID: user.ID, URL: server.URL, Secret: "whsec_test", SecretVersion: 1, Active: true}
+	require.NoError(t, db.Create(&endpoint).Error)
+	delivery := models.WebhookDelivery{
+		ID: uuid.New(), EndpointID: endpoint.ID, UserID: user.ID,
+		EventType: models.WebhookEventPaymentCompleted, Status: models.WebhookDeliveryStatusPending,
+	}
+	require.NoError(t, db.Create(&delivery).Error)
+
+	require.NoError(t, s.Deliver(context.Background(), delivery.ID))
+	assert.Zero(t, hits)
+
+	var attempt models.WebhookDeliveryAttempt
+	require.NoError(t, db.First(&attempt, "delivery_id = ?", delivery.ID).Error)
+	assert.Contains(t, attempt.Error, ErrNonPublicEndpointURL.Error())
+	require.NoError(t, db.First(&delivery, "id = ?", delivery.ID).Error)
+	assert.Equal(t, models.WebhookDeliveryStatusPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1700000000." + string(body)))
+
+	signature := Sign("1700000000", body, "whsec_test")
+	assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), signature)
+	assert.True(t, VerifySignature("1700000000", body, signature, "whsec_old", "whsec_test"))
+	assert.False(t, VerifySignature("1700000001", body, signature, "whsec_test"), "timestamp is signed")
+	assert.False(t, VerifySignature("1700000000", []byte(`{"id":"evt_2"}`), signature, "whsec_test"), "body is signed")
+	assert.False(t, VerifySignature("1700000000", body, signature, "whsec_other", ""))
+}
+
+// A delivery enqueued twice is only sent by the worker that claims it; a claim its worker
+// never finished is picked up again by the retry sweep
+func TestDeliverClaimsDelivery(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+
+	status := http.StatusInternalServerError
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(status)
+		w.Write([]byte("internal details"))
+	}))
+	defer server.Close()
+
+	q := &recordingQueue{}
+	s := newTestService(db, q)
+	endpoint, err := s.CreateEndpoint(user.ID, server.URL, "", false)
+	require.NoError(t, err)
+	require.NoError(t, s.Dispatch(user.ID, models.WebhookEventPaymentCompleted, map[string]interface{}{"reference": "PAY-1"}))
+	require.Len(t, q.jobs, 1)
+
+	var delivery models.WebhookDelivery
+	require.NoError(t, db.First(&delivery, "endpoint_id = ?", endpoint.ID).Error)
+
+	// Another worker holds the claim, so this one sends nothing
+	claimed, err := s.claimDelivery(delivery.ID, time.Now())
+	require.NoError(t, err)
+	require.True(t, claimed)
+	require.NoError(t, s.Deliver(context.Background(), delivery.ID))
+	assert.Zero(t, hits)
+
+	// Nor is it retried while the claim is fresh
+	enqueued, err := s.RetryDueDeliveries()
+	require.NoError(t, err)
+	assert.Zero(t, enqueued)
+
+	// Once the claim has timed out the sweep sends it again
+	require.NoError(t, db.Model(&delivery).Update("next_retry_at", time.Now().Add(-time.Second)).Error)
+	enqueued, err = s.RetryDueDeliveries()
+	require.NoError(t, err)
+	assert.Equal(t, 1, enqueued)
+	require.NoError(t, s.Deliver(context.Background(), delivery.ID))
+	assert.Equal(t, 1, hits)
+
+	// The failure is scheduled for retry, without the response body being kept
+	require.NoError(t, db.First(&delivery, "id = ?", delivery.ID).Error)
+	assert.Equal(t, models.WebhookDeliveryStatusPending, delivery.Status)
+	assert.Equal(t, http.StatusInternalServerError, delivery.LastStatusCode)
+	require.NotNil(t, delivery.NextRetryAt)
+	assert.True(t, delivery.NextRetryAt.After(time.Now()))
+	deliveries, _, err := s.GetDeliveries(endpoint.ID, user.ID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries[0].AttemptLogs, 1)
+	attemptJSON, err := json.Marshal(deliveries[0].AttemptLogs[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(attemptJSON), "internal details")
+
+	// A delivery that succeeded isn't sent again
+	status = http.StatusOK
+	require.NoError(t, db.Model(&delivery).Update("next_retry_at", time.Now()).Error)
+	require.NoError(t, s.Deliver(context.Background(), delivery.ID))
+	require.NoError(t, s.Deliver(context.Background(), delivery.ID))
+	assert.Equal(t, 2, hits)
+	require.NoError(t, db.First(&delivery, "id = ?", delivery.ID).Error)
+	assert.Equal(t, models.WebhookDeliveryStatusSucceeded, delivery.Status)
+}
+
+func TestSecretRotation(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := testutil.CreateUser(t, db)
+
+	type received struct {
+		signature, version, timestamp string
+		body                          []byte
+	}
+	var got received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = received{r.Header.Get(SignatureHeader), r.Header.Get(SignatureVersionHeader), r.Header.Get(TimestampHeader), body}
+	}))
+	defer server.Close()
+
+	s := newTestService(db, nil)
+	endpoint, err := s.CreateEndpoint(user.ID, server.URL, "", false)
+	require.NoError(t, err)
+	oldSecret := endpoint.Secret
+
+	deliver := func() {
+		delivery := models.WebhookDelivery{
+			ID: uuid.New(), EndpointID: endpoint.ID, UserID: user.ID,
+			EventType: models.WebhookEventPaymentCompleted, Status: models.WebhookDeliveryStatusPending,
+		}
+		require.NoError(t, db.Create(&delivery).Error)
+		require.NoError(t, s.Deliver(context.Background(), delivery.ID))
+	}
+
+	_, err = s.FinalizeSecretRotation(endpoint.ID, user.ID)
+	assert.ErrorIs(t, err, ErrNoRotationInProgress)
+
+	rotating, err := s.StartSecretRotation(endpoint.ID, user.ID)
+	require.NoError(t, err)
+	newSecret := rotating.PendingSecret
+	require.NotEmpty(t, newSecret)
+	assert.NotEqual(t, oldSecret, newSecret)
+
+	_, err = s.StartSecretRotation(endpoint.ID, user.ID)
+	assert.ErrorIs(t, err, ErrRotationInProgress)
+
+	// Deliveries are signed with the current secret until the rotation is finalized
+	deliver()
+	assert.Equal(t, "1", got.version)
+	assert.True(t, VerifySignature(got.timestamp, got.body, got.signature, oldSecret))
+	assert.True(t, VerifySignature(got.timestamp, got.body, got.signature, oldSecret, newSecret))
+
+	finalized, err := s.FinalizeSecretRotation(endpoint.ID, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, finalized.SecretVersion)
+	assert.Empty(t, finalized.PendingSecret)
+
+	deliver()
+	assert.Equal(t, "2", got.version)
+	assert.True(t, VerifySignature(got.timestamp, got.body, got.signature, newSecret))
+	assert.False(t, VerifySignature(got.timestamp, got.body, got.signature, oldSecret))
+}
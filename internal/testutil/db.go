@@ -0,0 +1,100 @@
+// Package testutil sets up dependencies for tests that exercise services against a real database
+package testutil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/revaspay/backend/internal/database"
+	"github.com/revaspay/backend/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqliteUUID generates a random (version 4) UUID in SQLite, standing in for Postgres'
+// uuid_generate_v4() in column defaults
+const sqliteUUID = `(lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+	substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) ||
+	substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6))))`
+
+// NewDB opens a private in-memory SQLite database with the schema created by
+// database.Migrate, plus the profile columns database.User adds to users. The database is
+// closed when the test ends.
+//
+// SQLite stands in for Postgres, so code relying on Postgres-only SQL, such as jsonb
+// operators, needs a real Postgres database instead.
+func NewDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	// Each test gets its own named in-memory database, shared by the pool's connections
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_foreign_keys=off", strings.ReplaceAll(uuid.NewString(), "-", ""))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get test database connection: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.Callback().Raw().Before("gorm:raw").Register("testutil:sqlite_ddl", rewriteDDL); err != nil {
+		t.Fatalf("failed to register test database callback: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	for _, field := range legacyProfileFields {
+		if err := db.Migrator().AddColumn(&database.User{}, field); err != nil {
+			t.Fatalf("failed to add users.%s to test database: %v", field, err)
+		}
+	}
+	return db
+}
+
+// legacyProfileFields are the database.User profile fields that models.User doesn't migrate.
+// Its other extra columns, such as password, are left out because test users are created as
+// models.User.
+var legacyProfileFields = []string{"DisplayName", "BusinessName", "Bio", "Website"}
+
+// postgresUUIDDefaults are the Postgres UUID functions models use as column defaults
+var postgresUUIDDefaults = strings.NewReplacer(
+	"DEFAULT uuid_generate_v4()", "DEFAULT "+sqliteUUID,
+	"DEFAULT gen_random_uuid()", "DEFAULT "+sqliteUUID,
+)
+
+// rewriteDDL translates the Postgres-only parts of the DDL AutoMigrate generates from model
+// tags into SQLite
+func rewriteDDL(db *gorm.DB) {
+	sql := db.Statement.SQL.String()
+	if rewritten := postgresUUIDDefaults.Replace(sql); rewritten != sql {
+		db.Statement.SQL.Reset()
+		db.Statement.SQL.WriteString(rewritten)
+	}
+}
+
+// CreateUser stores an active, verified user with a unique email
+func CreateUser(t testing.TB, db *gorm.DB) *models.User {
+	t.Helper()
+
+	id := uuid.New()
+	user := models.User{
+		ID:         id,
+		Email:      id.String() + "@example.com",
+		Username:   strings.ReplaceAll(id.String(), "-", "")[:20],
+		FirstName:  "Test",
+		LastName:   "User",
+		IsActive:   true,
+		IsVerified: true,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return &user
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -40,6 +41,17 @@ const (
 	AuditEventUserDeleted          AuditEventType = "USER_DELETED"
 	AuditEventUserSuspended        AuditEventType = "USER_SUSPENDED"
 	AuditEventUserReinstated       AuditEventType = "USER_REINSTATED"
+	AuditEventAddressBlocked       AuditEventType = "ADDRESS_BLOCKED"
+	AuditEventLedgerMismatch       AuditEventType = "LEDGER_MISMATCH"
+	AuditEventPaymentLinkCreated   AuditEventType = "PAYMENT_LINK_CREATED"
+	AuditEventPaymentLinkUpdated   AuditEventType = "PAYMENT_LINK_UPDATED"
+	AuditEventPaymentLinkDeleted   AuditEventType = "PAYMENT_LINK_DELETED"
+	AuditEventPaymentStatus        AuditEventType = "PAYMENT_STATUS_CHANGED"
+	AuditEventWithdrawalStatus     AuditEventType = "WITHDRAWAL_STATUS_CHANGED"
+	AuditEventWithdrawalRefunded   AuditEventType = "WITHDRAWAL_REFUNDED"
+	AuditEventReconciliationIssue  AuditEventType = "RECONCILIATION_ISSUE"
+	AuditEventTransactionPINChange AuditEventType = "TRANSACTION_PIN_CHANGE"
+	AuditEventTransactionPINLocked AuditEventType = "TRANSACTION_PIN_LOCKED"
 )
 
 // AuditEventSeverity represents the severity level of an audit event
@@ -197,6 +209,67 @@ func (a *AuditLogger) LogSessionActivity(ctx context.Context, eventType AuditEve
 	return a.LogEvent(ctx, eventType, severity, description, &userID, sessionID, ipAddress, userAgent, success, details)
 }
 
+// AuditActor is who caused an audited change
+type AuditActor string
+
+// Define audit actors
+const (
+	AuditActorSystem AuditActor = "system"
+	AuditActorAdmin  AuditActor = "admin"
+	AuditActorUser   AuditActor = "user"
+)
+
+// StatusTransition describes a payment or withdrawal moving between statuses. From is
+// empty when the record was just created.
+type StatusTransition struct {
+	EntityID  uuid.UUID
+	OwnerID   uuid.UUID  // User the payment or withdrawal belongs to
+	Reference string
+	From      string
+	To        string
+	Amount    float64
+	Currency  string
+	Actor     AuditActor
+	ActorID   *uuid.UUID // Set when the actor is an admin or user
+	Reason    string
+}
+
+// LogStatusTransition logs a payment or withdrawal changing status against its owner.
+// Transitions into failed or blocked are logged as warnings and unsuccessful.
+func (a *AuditLogger) LogStatusTransition(ctx context.Context, eventType AuditEventType, transition StatusTransition) error {
+	severity := AuditSeverityInfo
+	success := true
+	if transition.To == "failed" || transition.To == "blocked" {
+		severity = AuditSeverityWarning
+		success = false
+	}
+
+	from := transition.From
+	if from == "" {
+		from = "new"
+	}
+	description := fmt.Sprintf("%s changed from %s to %s", transition.Reference, from, transition.To)
+
+	details := map[string]interface{}{
+		"entity_id":   transition.EntityID.String(),
+		"reference":   transition.Reference,
+		"from_status": transition.From,
+		"to_status":   transition.To,
+		"amount":      transition.Amount,
+		"currency":    transition.Currency,
+		"actor":       transition.Actor,
+	}
+	if transition.ActorID != nil {
+		details["actor_id"] = transition.ActorID.String()
+	}
+	if transition.Reason != "" {
+		details["reason"] = transition.Reason
+	}
+
+	ownerID := transition.OwnerID
+	return a.LogEvent(ctx, eventType, severity, description, &ownerID, nil, "", "", success, details)
+}
+
 // LogAdminAction logs administrative actions
 func (a *AuditLogger) LogAdminAction(ctx context.Context, adminID uuid.UUID, targetUserID *uuid.UUID, ipAddress, userAgent, action string, success bool, details map[string]interface{}) error {
 	eventType := AuditEventAdminAction
@@ -260,6 +333,109 @@ func (a *AuditLogger) QueryAuditLogs(userID *uuid.UUID, eventTypes []AuditEventT
 	return logs, count, nil
 }
 
+// AuditLogFilter selects audit logs for SearchAuditLogs. Nil and empty fields match everything.
+type AuditLogFilter struct {
+	UserID     *uuid.UUID
+	EventTypes []AuditEventType
+	Severity   AuditEventSeverity
+	From       *time.Time
+	To         *time.Time
+	Success    *bool
+}
+
+// SearchAuditLogs returns a page of audit logs matching the filter, newest first. Dates
+// are matched on created_at, which every writer to the table sets. Severity is matched
+// case-insensitively because the security audit logger stores it in lower case.
+func (a *AuditLogger) SearchAuditLogs(filter AuditLogFilter, limit, offset int) ([]AuditLog, int64, error) {
+	var logs []AuditLog
+	var count int64
+
+	query := a.db.Model(&AuditLog{})
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if len(filter.EventTypes) > 0 {
+		query = query.Where("event_type IN ?", filter.EventTypes)
+	}
+	if filter.Severity != "" {
+		query = query.Where("UPPER(severity) = UPPER(?)", filter.Severity)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.Success != nil {
+		query = query.Where("success = ?", *filter.Success)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&logs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit logs: %w", err)
+	}
+
+	return logs, count, nil
+}
+
+// RedactedValue replaces sensitive audit log detail values
+const RedactedValue = "[REDACTED]"
+
+// sensitiveAuditDetailKeys are fragments of detail keys whose values are personal data or secrets
+var sensitiveAuditDetailKeys = []string{"email", "phone", "password", "token", "secret", "account_number", "card", "otp", "backup_code"}
+
+// RedactAuditLog replaces the values of sensitive fields in the log's details, at any
+// depth, with RedactedValue. Details that aren't a JSON object are left as they are.
+func RedactAuditLog(log *AuditLog) {
+	if log.Details == "" {
+		return
+	}
+
+	var details map[string]interface{}
+	if err := json.Unmarshal([]byte(log.Details), &details); err != nil {
+		return
+	}
+	redactAuditDetails(details)
+
+	redacted, err := json.Marshal(details)
+	if err != nil {
+		return
+	}
+	log.Details = string(redacted)
+}
+
+// redactAuditDetails redacts sensitive fields of details in place
+func redactAuditDetails(details map[string]interface{}) {
+	for key, value := range details {
+		if isSensitiveAuditDetail(key) {
+			details[key] = RedactedValue
+			continue
+		}
+		switch nested := value.(type) {
+		case map[string]interface{}:
+			redactAuditDetails(nested)
+		case []interface{}:
+			for _, item := range nested {
+				if itemMap, ok := item.(map[string]interface{}); ok {
+					redactAuditDetails(itemMap)
+				}
+			}
+		}
+	}
+}
+
+func isSensitiveAuditDetail(key string) bool {
+	key = strings.ToLower(key)
+	for _, fragment := range sensitiveAuditDetailKeys {
+		if strings.Contains(key, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetUserAuditLogs gets audit logs for a specific user
 func (a *AuditLogger) GetUserAuditLogs(userID uuid.UUID, limit, offset int) ([]AuditLog, int64, error) {
 	return a.QueryAuditLogs(&userID, nil, nil, nil, "", limit, offset)
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRedactAuditLog(t *testing.T) {
+	log := AuditLog{Details: `{"email":"ama@example.com","reason":"bad password","reset_token":"abc","changes":{"phone_number":{"from":"1","to":"2"},"title":{"from":"a","to":"b"}}}`}
+	RedactAuditLog(&log)
+
+	var details map[string]interface{}
+	if err := json.Unmarshal([]byte(log.Details), &details); err != nil {
+		t.Fatalf("redacted details aren't JSON: %v", err)
+	}
+	if details["email"] != RedactedValue || details["reset_token"] != RedactedValue {
+		t.Errorf("expected top-level sensitive fields to be redacted, got %v", details)
+	}
+	if details["reason"] != "bad password" {
+		t.Errorf("expected reason to be kept, got %v", details["reason"])
+	}
+	changes := details["changes"].(map[string]interface{})
+	if changes["phone_number"] != RedactedValue {
+		t.Errorf("expected nested phone number to be redacted, got %v", changes["phone_number"])
+	}
+	if changes["title"] == RedactedValue {
+		t.Error("expected nested title to be kept")
+	}
+}
+
+func TestRedactAuditLogLeavesNonObjectDetails(t *testing.T) {
+	log := AuditLog{Details: "null"}
+	RedactAuditLog(&log)
+	if log.Details != "null" {
+		t.Errorf("expected details to be unchanged, got %q", log.Details)
+	}
+}
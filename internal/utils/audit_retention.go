@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// auditPruneBatchSize is how many audit logs are deleted or anonymized per statement, so
+// pruning a large backlog doesn't hold long locks on audit_logs
+const auditPruneBatchSize = 1000
+
+// AuditRetention is how long audit logs are kept, by severity, before they are pruned. A
+// retention of zero keeps logs of that severity forever. Logs with any other severity are
+// kept for the Info retention.
+type AuditRetention struct {
+	Info     time.Duration
+	Warning  time.Duration
+	Error    time.Duration
+	Critical time.Duration
+	// Anonymize keeps warning, error and critical logs past their retention with the user,
+	// session, IP address, user agent and details removed, rather than deleting them
+	Anonymize bool
+}
+
+// auditSeverities are the severities with their own retention, upper-cased since the two
+// audit loggers sharing audit_logs use different cases
+var auditSeverities = []string{string(AuditSeverityWarning), string(AuditSeverityError), string(AuditSeverityCritical)}
+
+// PruneAuditLogs deletes or anonymizes the audit logs older than their retention as of now,
+// in batches, returning how many were deleted and how many anonymized
+func PruneAuditLogs(db *gorm.DB, retention AuditRetention, now time.Time) (int64, int64, error) {
+	var deleted, anonymized int64
+
+	if retention.Info > 0 {
+		n, err := deleteAuditLogs(db.Where("created_at < ? AND (severity IS NULL OR UPPER(severity) NOT IN ?)",
+			now.Add(-retention.Info), auditSeverities))
+		deleted += n
+		if err != nil {
+			return deleted, anonymized, err
+		}
+	}
+
+	for severity, period := range map[string]time.Duration{
+		string(AuditSeverityWarning):  retention.Warning,
+		string(AuditSeverityError):    retention.Error,
+		string(AuditSeverityCritical): retention.Critical,
+	} {
+		if period <= 0 {
+			continue
+		}
+		scope := db.Where("created_at < ? AND UPPER(severity) = ?", now.Add(-period), severity)
+		if retention.Anonymize {
+			n, err := anonymizeAuditLogs(scope)
+			anonymized += n
+			if err != nil {
+				return deleted, anonymized, err
+			}
+			continue
+		}
+		n, err := deleteAuditLogs(scope)
+		deleted += n
+		if err != nil {
+			return deleted, anonymized, err
+		}
+	}
+
+	return deleted, anonymized, nil
+}
+
+// deleteAuditLogs deletes the audit logs matching scope a batch at a time
+func deleteAuditLogs(scope *gorm.DB) (int64, error) {
+	return inAuditBatches(scope, func(tx *gorm.DB, ids []uuid.UUID) (int64, error) {
+		result := tx.Where("id IN ?", ids).Delete(&AuditLog{})
+		return result.RowsAffected, result.Error
+	})
+}
+
+// anonymizeAuditLogs removes the personal data from the audit logs matching scope a batch at
+// a time. Logs already anonymized aren't matched again.
+func anonymizeAuditLogs(scope *gorm.DB) (int64, error) {
+	scope = scope.Where("user_id IS NOT NULL OR session_id IS NOT NULL OR target_id IS NOT NULL OR " +
+		"COALESCE(ip_address, '') <> '' OR COALESCE(user_agent, '') <> '' OR " +
+		"COALESCE(details, '') <> '' OR COALESCE(metadata, '') <> ''")
+
+	return inAuditBatches(scope, func(tx *gorm.DB, ids []uuid.UUID) (int64, error) {
+		result := tx.Table("audit_logs").Where("id IN ?", ids).Updates(map[string]interface{}{
+			"user_id":    nil,
+			"session_id": nil,
+			"target_id":  nil,
+			"ip_address": "",
+			"user_agent": "",
+			"details":    "",
+			"metadata":   "",
+		})
+		return result.RowsAffected, result.Error
+	})
+}
+
+// inAuditBatches runs apply on the IDs of the audit logs matching scope, a batch at a time,
+// until none are left
+func inAuditBatches(scope *gorm.DB, apply func(tx *gorm.DB, ids []uuid.UUID) (int64, error)) (int64, error) {
+	scope = scope.Session(&gorm.Session{})
+	var total int64
+	for {
+		var ids []uuid.UUID
+		if err := scope.Table("audit_logs").
+			Limit(auditPruneBatchSize).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+
+		n, err := apply(scope.Session(&gorm.Session{NewDB: true}), ids)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if len(ids) < auditPruneBatchSize {
+			return total, nil
+		}
+	}
+}
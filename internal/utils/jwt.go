@@ -2,18 +2,32 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
 )
 
+const (
+	// DefaultAccessTokenTTL is how long access tokens are valid when no lifetime is configured
+	DefaultAccessTokenTTL = 15 * time.Minute
+	// DefaultRefreshTokenTTL is how long refresh tokens are valid when no lifetime is configured
+	DefaultRefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// ErrUnknownSigningKey is returned when a token names a signing key that isn't configured
+var ErrUnknownSigningKey = errors.New("unknown token signing key")
+
 // Claims represents the JWT claims
 type Claims struct {
-	UserID  uuid.UUID `json:"user_id"`
-	Email   string    `json:"email"`
-	IsAdmin bool      `json:"is_admin"`
+	UserID      uuid.UUID `json:"user_id"`
+	Email       string    `json:"email"`
+	IsAdmin     bool      `json:"is_admin"`
+	Permissions []string  `json:"permissions,omitempty"` // Granted by the user's roles when the token was issued
+	SessionID   uuid.UUID `json:"session_id"`            // Session the tokens belong to; uuid.Nil for tokens issued without one
 	jwt.StandardClaims
 }
 
@@ -25,6 +39,72 @@ type TokenPair struct {
 	TokenType    string `json:"token_type"`
 }
 
+// JWTSettings configures how tokens are signed and how long they last.
+//
+// New tokens are signed with Secret and carry KeyID in their kid header. To rotate the secret
+// without signing everyone out, move the old secret into PreviousKeys under its key ID and set
+// a new KeyID and Secret. Tokens signed with a previous key stay valid until they expire, after
+// which the previous key can be removed.
+type JWTSettings struct {
+	KeyID           string
+	Secret          string
+	PreviousKeys    map[string]string // key ID -> secret
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+var (
+	jwtSettingsMu sync.RWMutex
+	jwtSettings   *JWTSettings
+)
+
+// ConfigureJWT sets the signing keys and token lifetimes used by GenerateTokenPair and
+// ValidateToken. Lifetimes left at zero use the defaults.
+func ConfigureJWT(settings JWTSettings) error {
+	if settings.Secret == "" {
+		return errors.New("JWT secret is required")
+	}
+	for keyID, secret := range settings.PreviousKeys {
+		if keyID == "" || secret == "" {
+			return errors.New("previous JWT keys need both a key ID and a secret")
+		}
+		if keyID == settings.KeyID {
+			return fmt.Errorf("previous JWT key %q has the same ID as the current key", keyID)
+		}
+	}
+	if settings.AccessTokenTTL <= 0 {
+		settings.AccessTokenTTL = DefaultAccessTokenTTL
+	}
+	if settings.RefreshTokenTTL <= 0 {
+		settings.RefreshTokenTTL = DefaultRefreshTokenTTL
+	}
+
+	jwtSettingsMu.Lock()
+	defer jwtSettingsMu.Unlock()
+	jwtSettings = &settings
+	return nil
+}
+
+// currentJWTSettings returns the configured settings, or settings built from JWT_SECRET if
+// ConfigureJWT hasn't been called
+func currentJWTSettings() JWTSettings {
+	jwtSettingsMu.RLock()
+	defer jwtSettingsMu.RUnlock()
+	if jwtSettings != nil {
+		return *jwtSettings
+	}
+	return JWTSettings{
+		Secret:          getJWTSecret(),
+		AccessTokenTTL:  DefaultAccessTokenTTL,
+		RefreshTokenTTL: DefaultRefreshTokenTTL,
+	}
+}
+
+// RefreshTokenTTL returns how long refresh tokens, and the sessions they belong to, are valid
+func RefreshTokenTTL() time.Duration {
+	return currentJWTSettings().RefreshTokenTTL
+}
+
 // getJWTSecret returns the JWT secret from environment variable or a default for development
 func getJWTSecret() string {
 	secret := os.Getenv("JWT_SECRET")
@@ -36,44 +116,40 @@ func getJWTSecret() string {
 	return secret
 }
 
-// GenerateTokenPair creates access and refresh tokens
-func GenerateTokenPair(userID uuid.UUID, email string, isAdmin bool) (TokenPair, error) {
+// GenerateTokenPair creates access and refresh tokens for a session, signed with the current
+// key. Role permissions are carried in the tokens, so role changes apply from the next refresh.
+func GenerateTokenPair(userID, sessionID uuid.UUID, email string, isAdmin bool, permissions []string) (TokenPair, error) {
+	settings := currentJWTSettings()
+
 	// Set expiration times
-	accessExpiration := time.Now().Add(15 * time.Minute)
-	refreshExpiration := time.Now().Add(7 * 24 * time.Hour)
-
-	// Create claims for access token
-	accessClaims := Claims{
-		UserID:  userID,
-		Email:   email,
-		IsAdmin: isAdmin,
+	now := time.Now()
+	accessExpiration := now.Add(settings.AccessTokenTTL)
+	refreshExpiration := now.Add(settings.RefreshTokenTTL)
+
+	accessTokenString, err := signToken(settings, Claims{
+		UserID:      userID,
+		Email:       email,
+		IsAdmin:     isAdmin,
+		Permissions: permissions,
+		SessionID:   sessionID,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: accessExpiration.Unix(),
 		},
+	})
+	if err != nil {
+		return TokenPair{}, err
 	}
 
-	// Create claims for refresh token
-	refreshClaims := Claims{
-		UserID:  userID,
-		Email:   email,
-		IsAdmin: isAdmin,
+	refreshTokenString, err := signToken(settings, Claims{
+		UserID:      userID,
+		Email:       email,
+		IsAdmin:     isAdmin,
+		Permissions: permissions,
+		SessionID:   sessionID,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: refreshExpiration.Unix(),
 		},
-	}
-
-	// Create tokens
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-
-	// Sign tokens
-	jwtSecret := getJWTSecret()
-	accessTokenString, err := accessToken.SignedString([]byte(jwtSecret))
-	if err != nil {
-		return TokenPair{}, err
-	}
-
-	refreshTokenString, err := refreshToken.SignedString([]byte(jwtSecret))
+	})
 	if err != nil {
 		return TokenPair{}, err
 	}
@@ -81,21 +157,89 @@ func GenerateTokenPair(userID uuid.UUID, email string, isAdmin bool) (TokenPair,
 	return TokenPair{
 		AccessToken:  accessTokenString,
 		RefreshToken: refreshTokenString,
-		ExpiresIn:    accessExpiration.Unix() - time.Now().Unix(),
+		ExpiresIn:    int64(settings.AccessTokenTTL / time.Second),
 		TokenType:    "Bearer",
 	}, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// signToken signs claims with the current key, naming it in the kid header
+func signToken(settings JWTSettings, claims Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if settings.KeyID != "" {
+		token.Header["kid"] = settings.KeyID
+	}
+	return token.SignedString([]byte(settings.Secret))
+}
+
+// ValidateToken validates a JWT token signed with the current or a previous key and returns
+// the claims. Tokens issued before key IDs were introduced carry no kid and are checked
+// against every configured key.
 func ValidateToken(tokenString string) (*Claims, error) {
-	// Get JWT secret
-	jwtSecret := getJWTSecret()
-	
-	// Parse token
+	settings := currentJWTSettings()
+
+	keyID, err := tokenKeyID(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []string
+	if keyID != "" {
+		secret, ok := settings.signingSecret(keyID)
+		if !ok {
+			return nil, ErrUnknownSigningKey
+		}
+		secrets = []string{secret}
+	} else {
+		secrets = append(secrets, settings.Secret)
+		for _, secret := range settings.PreviousKeys {
+			secrets = append(secrets, secret)
+		}
+	}
+
+	for _, secret := range secrets {
+		var claims *Claims
+		claims, err = parseToken(tokenString, secret)
+		if err == nil {
+			return claims, nil
+		}
+
+		// Only a bad signature is worth retrying with another key
+		var validationErr *jwt.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Errors&jwt.ValidationErrorSignatureInvalid == 0 {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+// signingSecret returns the secret of the current or a previous key
+func (s JWTSettings) signingSecret(keyID string) (string, bool) {
+	if keyID == s.KeyID {
+		return s.Secret, true
+	}
+	secret, ok := s.PreviousKeys[keyID]
+	return secret, ok
+}
+
+// tokenKeyID reads the kid header of a token without verifying it
+func tokenKeyID(tokenString string) (string, error) {
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, &Claims{})
+	if err != nil {
+		return "", err
+	}
+	keyID, _ := token.Header["kid"].(string)
+	return keyID, nil
+}
+
+// parseToken verifies a token's signature with secret and returns its claims
+func parseToken(tokenString, secret string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
 	})
-
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func configureTestJWT(t *testing.T, settings JWTSettings) {
+	t.Helper()
+	require.NoError(t, ConfigureJWT(settings))
+	t.Cleanup(func() {
+		jwtSettingsMu.Lock()
+		jwtSettings = nil
+		jwtSettingsMu.Unlock()
+	})
+}
+
+func TestGenerateTokenPairUsesCurrentKey(t *testing.T) {
+	configureTestJWT(t, JWTSettings{KeyID: "k2", Secret: "secret-2", AccessTokenTTL: 5 * time.Minute})
+
+	userID, sessionID := uuid.New(), uuid.New()
+	tokens, err := GenerateTokenPair(userID, sessionID, "user@example.com", false, []string{"kyc:view"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), tokens.ExpiresIn)
+
+	keyID, err := tokenKeyID(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", keyID)
+
+	claims, err := ValidateToken(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, userID, claims.UserID)
+	assert.Equal(t, []string{"kyc:view"}, claims.Permissions)
+	assert.Equal(t, sessionID, claims.SessionID)
+	assert.Equal(t, DefaultRefreshTokenTTL, RefreshTokenTTL())
+}
+
+func TestValidateTokenAcceptsPreviousKeys(t *testing.T) {
+	configureTestJWT(t, JWTSettings{KeyID: "k1", Secret: "secret-1"})
+	tokens, err := GenerateTokenPair(uuid.New(), uuid.Nil, "user@example.com", false, nil)
+	require.NoError(t, err)
+
+	// Rotate: the old key is still accepted, an unknown one isn't
+	configureTestJWT(t, JWTSettings{KeyID: "k2", Secret: "secret-2", PreviousKeys: map[string]string{"k1": "secret-1"}})
+	_, err = ValidateToken(tokens.RefreshToken)
+	assert.NoError(t, err)
+
+	configureTestJWT(t, JWTSettings{KeyID: "k3", Secret: "secret-3"})
+	_, err = ValidateToken(tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrUnknownSigningKey)
+}
+
+func TestValidateTokenWithoutKeyID(t *testing.T) {
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserID:         uuid.New(),
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()},
+	})
+	tokenString, err := legacy.SignedString([]byte("old-secret"))
+	require.NoError(t, err)
+
+	configureTestJWT(t, JWTSettings{KeyID: "k2", Secret: "secret-2", PreviousKeys: map[string]string{"k1": "old-secret"}})
+	_, err = ValidateToken(tokenString)
+	assert.NoError(t, err)
+
+	configureTestJWT(t, JWTSettings{KeyID: "k2", Secret: "secret-2"})
+	_, err = ValidateToken(tokenString)
+	assert.Error(t, err)
+}
+
+func TestValidateTokenRejectsExpiredAndUnsigned(t *testing.T) {
+	configureTestJWT(t, JWTSettings{KeyID: "k1", Secret: "secret-1"})
+
+	expired := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(-time.Minute).Unix()},
+	})
+	expired.Header["kid"] = "k1"
+	tokenString, err := expired.SignedString([]byte("secret-1"))
+	require.NoError(t, err)
+	_, err = ValidateToken(tokenString)
+	assert.Error(t, err)
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, Claims{})
+	tokenString, err = unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	_, err = ValidateToken(tokenString)
+	assert.Error(t, err)
+}
+
+func TestConfigureJWTValidatesKeys(t *testing.T) {
+	assert.Error(t, ConfigureJWT(JWTSettings{}))
+	assert.Error(t, ConfigureJWT(JWTSettings{KeyID: "k1", Secret: "s", PreviousKeys: map[string]string{"k1": "old"}}))
+	assert.Error(t, ConfigureJWT(JWTSettings{KeyID: "k1", Secret: "s", PreviousKeys: map[string]string{"k0": ""}}))
+}
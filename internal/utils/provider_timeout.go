@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultProviderTimeout bounds each outbound call to a payment, payout or KYC provider when
+// no timeout is configured
+const DefaultProviderTimeout = 30 * time.Second
+
+var providerTimeout atomic.Int64
+
+// SetProviderTimeout sets how long ProviderContext allows each provider call. A timeout of
+// zero or less restores DefaultProviderTimeout.
+func SetProviderTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultProviderTimeout
+	}
+	providerTimeout.Store(int64(timeout))
+}
+
+// ProviderTimeout returns how long each provider call is allowed
+func ProviderTimeout() time.Duration {
+	if timeout := time.Duration(providerTimeout.Load()); timeout > 0 {
+		return timeout
+	}
+	return DefaultProviderTimeout
+}
+
+// ProviderContext derives the context for one outbound provider call from ctx, bounded by
+// ProviderTimeout. An earlier deadline on ctx, such as a job's or a request's, still applies.
+func ProviderContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithTimeout(ctx, ProviderTimeout())
+}
+
+// IsTimeout reports whether err comes from a call that ran out of time. The provider may
+// still have acted on the request, so the outcome is unknown rather than failed.
+func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
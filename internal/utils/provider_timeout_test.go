@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowServer responds after delay, or as soon as the client gives up
+func slowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func callProvider(ctx context.Context, url string) error {
+	ctx, cancel := ProviderContext(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func TestProviderContext(t *testing.T) {
+	t.Cleanup(func() { SetProviderTimeout(0) })
+	server := slowServer(t, time.Second)
+
+	t.Run("provider timeout", func(t *testing.T) {
+		SetProviderTimeout(20 * time.Millisecond)
+
+		start := time.Now()
+		err := callProvider(context.Background(), server.URL)
+		require.Error(t, err)
+		assert.True(t, IsTimeout(err))
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("earlier caller deadline", func(t *testing.T) {
+		SetProviderTimeout(time.Minute)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := callProvider(ctx, server.URL)
+		require.Error(t, err)
+		assert.True(t, IsTimeout(err))
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("fast provider", func(t *testing.T) {
+		SetProviderTimeout(time.Second)
+		assert.NoError(t, callProvider(context.Background(), slowServer(t, 0).URL))
+	})
+}
+
+func TestSetProviderTimeout(t *testing.T) {
+	t.Cleanup(func() { SetProviderTimeout(0) })
+
+	SetProviderTimeout(5 * time.Second)
+	assert.Equal(t, 5*time.Second, ProviderTimeout())
+
+	SetProviderTimeout(0)
+	assert.Equal(t, DefaultProviderTimeout, ProviderTimeout())
+}
+
+func TestIsTimeout(t *testing.T) {
+	assert.False(t, IsTimeout(nil))
+	assert.False(t, IsTimeout(errors.New("connection refused")))
+	assert.False(t, IsTimeout(context.Canceled))
+	assert.True(t, IsTimeout(context.DeadlineExceeded))
+	assert.True(t, IsTimeout(errors.Join(errors.New("error sending request"), context.DeadlineExceeded)))
+}
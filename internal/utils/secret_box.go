@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrSecretKeyNotConfigured is returned when encrypting or decrypting secrets without a key
+var ErrSecretKeyNotConfigured = errors.New("no encryption key is configured for stored secrets")
+
+// SecretBox encrypts small secrets, such as provider API keys, for storing in the database.
+// Secrets are sealed with AES-256-GCM; the context they belong to, such as the row they're
+// stored on, is bound to the ciphertext so a secret copied to another row won't open.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a secret box from a 32-byte AES-256 key
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) == 0 {
+		return nil, ErrSecretKeyNotConfigured
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error creating secret cipher: %w", err)
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// NewSecretBoxFromBase64 creates a secret box from a base64 encoded 32-byte key. An empty key
+// returns ErrSecretKeyNotConfigured.
+func NewSecretBoxFromBase64(encoded string) (*SecretBox, error) {
+	if encoded == "" {
+		return nil, ErrSecretKeyNotConfigured
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("secret encryption key is not valid base64: %w", err)
+	}
+	return NewSecretBox(key)
+}
+
+// Seal encrypts a secret for context, returning it base64 encoded with its nonce
+func (b *SecretBox) Seal(secret, context string) (string, error) {
+	if b == nil {
+		return "", ErrSecretKeyNotConfigured
+	}
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error generating nonce: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(secret), []byte(context))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a secret sealed for context
+func (b *SecretBox) Open(sealed, context string) (string, error) {
+	if b == nil {
+		return "", ErrSecretKeyNotConfigured
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("error decoding secret: %w", err)
+	}
+	if len(data) < b.aead.NonceSize() {
+		return "", errors.New("encrypted secret is truncated")
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	secret, err := b.aead.Open(nil, nonce, ciphertext, []byte(context))
+	if err != nil {
+		return "", fmt.Errorf("error decrypting secret: %w", err)
+	}
+	return string(secret), nil
+}
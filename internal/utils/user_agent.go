@@ -2,8 +2,83 @@ package utils
 
 import (
 	"strings"
+
+	"github.com/mileusna/useragent"
+)
+
+// Device types reported by ParseUserAgent
+const (
+	DeviceTypeDesktop = "desktop"
+	DeviceTypeMobile  = "mobile"
+	DeviceTypeTablet  = "tablet"
+	DeviceTypeBot     = "bot"
+	DeviceTypeUnknown = "unknown"
 )
 
+// UserAgentInfo is the device, browser and OS described by a user agent string.
+// Names are lowercase with underscores, e.g. "chrome", "samsung_browser", "macos".
+type UserAgentInfo struct {
+	DeviceType     string
+	DeviceModel    string
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	Bot            bool
+}
+
+// ParseUserAgent parses a user agent string. Browsers that embed another engine's token
+// (Edge and Opera include "Chrome", Chrome on iOS includes "Safari") are reported as
+// themselves, and in-app webviews are reported by the app that hosts them.
+func ParseUserAgent(userAgent string) UserAgentInfo {
+	if strings.TrimSpace(userAgent) == "" {
+		return UserAgentInfo{DeviceType: DeviceTypeUnknown, Browser: "unknown", OS: "unknown"}
+	}
+
+	ua := useragent.Parse(userAgent)
+	info := UserAgentInfo{
+		DeviceModel:    ua.Device,
+		Browser:        normalizeUAName(ua.Name),
+		BrowserVersion: ua.Version,
+		OS:             normalizeUAName(ua.OS),
+		OSVersion:      ua.OSVersion,
+		Bot:            ua.Bot,
+	}
+
+	switch {
+	case ua.Bot:
+		info.DeviceType = DeviceTypeBot
+	case ua.Tablet:
+		info.DeviceType = DeviceTypeTablet
+	case ua.Mobile:
+		info.DeviceType = DeviceTypeMobile
+	default:
+		info.DeviceType = DeviceTypeDesktop
+	}
+
+	switch info.Browser {
+	case "mobile_safari":
+		info.Browser = "safari"
+	case "":
+		info.Browser = "unknown"
+	}
+	// Android system webviews carry "; wv)" and otherwise look like Chrome
+	if strings.Contains(userAgent, "; wv)") && info.Browser == "chrome" {
+		info.Browser = "android_webview"
+	}
+
+	if info.OS == "" {
+		info.OS = "unknown"
+	}
+
+	return info
+}
+
+// normalizeUAName turns a parser name like "Samsung Browser" or "macOS" into "samsung_browser" or "macos"
+func normalizeUAName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+}
+
 // Contains checks if a string contains a substring
 func Contains(s, substr string) bool {
 	return strings.Contains(s, substr)
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		name           string
+		userAgent      string
+		deviceType     string
+		deviceModel    string
+		browser        string
+		browserVersion string
+		os             string
+	}{
+		{
+			name:           "Edge on Windows is not Chrome",
+			userAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			deviceType:     DeviceTypeDesktop,
+			browser:        "edge",
+			browserVersion: "120.0.2210.91",
+			os:             "windows",
+		},
+		{
+			name:           "Chrome on iOS is not Safari",
+			userAgent:      "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			deviceType:     DeviceTypeMobile,
+			deviceModel:    "iPhone",
+			browser:        "chrome",
+			browserVersion: "120.0.6099.119",
+			os:             "ios",
+		},
+		{
+			name:           "Safari on macOS",
+			userAgent:      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+			deviceType:     DeviceTypeDesktop,
+			browser:        "safari",
+			browserVersion: "17.2",
+			os:             "macos",
+		},
+		{
+			name:        "Safari on iPad is a tablet",
+			userAgent:   "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+			deviceType:  DeviceTypeTablet,
+			deviceModel: "iPad",
+			browser:     "safari",
+			os:          "ios",
+		},
+		{
+			name:       "Facebook in-app browser",
+			userAgent:  "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 [FBAN/FBIOS;FBAV/442.0.0.34.106;FBBV/534165473;FBDV/iPhone14,5;FBMD/iPhone;FBSN/iOS;FBSV/17.1;FBSS/3;FBCR/;FBID/phone;FBLC/en_US;FBOP/80]",
+			deviceType: DeviceTypeMobile,
+			browser:    "facebook_app",
+			os:         "ios",
+		},
+		{
+			name:       "Android system webview",
+			userAgent:  "Mozilla/5.0 (Linux; Android 13; SM-S901B Build/TP1A.220624.014; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/119.0.6045.163 Mobile Safari/537.36",
+			deviceType: DeviceTypeMobile,
+			browser:    "android_webview",
+			os:         "android",
+		},
+		{
+			name:       "Empty user agent",
+			userAgent:  "",
+			deviceType: DeviceTypeUnknown,
+			browser:    "unknown",
+			os:         "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := ParseUserAgent(tt.userAgent)
+			assert.Equal(t, tt.deviceType, info.DeviceType)
+			assert.Equal(t, tt.browser, info.Browser)
+			assert.Equal(t, tt.os, info.OS)
+			if tt.browserVersion != "" {
+				assert.Equal(t, tt.browserVersion, info.BrowserVersion)
+			}
+			if tt.deviceModel != "" {
+				assert.Equal(t, tt.deviceModel, info.DeviceModel)
+			}
+		})
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/pquerna/otp/totp"
+	"github.com/revaspay/backend/internal/models"
+	"github.com/revaspay/backend/internal/money"
 )
 
 // GenerateRandomString creates a random string of specified length
@@ -66,6 +68,14 @@ func TruncateString(str string, length int) string {
 	return str[:length-3] + "..."
 }
 
+// MaskAccountNumber hides all but the last 4 characters of an account number
+func MaskAccountNumber(accountNumber string) string {
+	if len(accountNumber) <= 4 {
+		return accountNumber
+	}
+	return strings.Repeat("*", len(accountNumber)-4) + accountNumber[len(accountNumber)-4:]
+}
+
 // IsValidEmail checks if an email address is valid
 func IsValidEmail(email string) bool {
 	// Simple validation - contains @ and at least one dot after @
@@ -78,9 +88,10 @@ func IsValidEmail(email string) bool {
 	return len(domainParts) >= 2 && domainParts[len(domainParts)-1] != ""
 }
 
-// CalculateFee calculates the fee for a transaction
-func CalculateFee(amount float64, feePercentage float64) float64 {
-	return amount * (feePercentage / 100.0)
+// CalculateFee calculates the fee for a transaction, rounded to the currency's precision with
+// the fee rounding mode
+func CalculateFee(amount float64, feePercentage float64, code models.Currency) float64 {
+	return money.Round(amount*(feePercentage/100.0), code, money.CurrentPolicy().Fee)
 }
 
 // GenerateSecureToken generates a secure random token of specified length